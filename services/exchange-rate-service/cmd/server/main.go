@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,9 +15,12 @@ import (
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/handler"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/metrics"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/pb/exchangepb"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/tracing"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -46,19 +50,40 @@ func main() {
 	redisClient := setupRedis(cfg, logger)
 	defer redisClient.Close()
 
+	// Setup tracing
+	shutdownTracing := setupTracing(cfg, logger)
+	defer shutdownTracing()
+
+	// Setup metrics
+	appMetrics := metrics.NewMetrics("exchange_rate_service")
+
 	// Setup rate provider based on configuration
-	rateProvider := setupProvider(cfg, logger)
+	rateProvider := setupProvider(cfg, logger, appMetrics)
 	logger.Info("Rate provider configured", zap.String("provider", rateProvider.Name()))
+	loadRateCurrencies(rateProvider, logger)
 
 	// Setup repository
-	rateRepo := repository.NewRedisRepository(redisClient)
-
-	// Setup metrics
-	appMetrics := metrics.NewMetrics("exchange_rate_service")
-	_ = appMetrics // Will be used when instrumenting handlers
+	rateRepo := repository.NewRedisRepository(redisClient).
+		WithWriteFailureRecorder(appMetrics.RecordRedisWriteFailure).
+		WithRateHistoryLimit(cfg.RateHistoryMaxPoints)
 
 	// Create rate service with dependency injection
 	rateService := service.NewRateService(cfg, rateProvider, rateRepo, logger)
+	rateService.SetMarginRecorder(appMetrics.RecordCapturedMargin)
+	rateService.SetMetrics(appMetrics)
+	metrics.RegisterHealthGauges("exchange_rate_service", rateService.ProviderErrorRate, rateService.IsDegraded)
+
+	// Apply corridor pricing overrides made through the admin API
+	stopOverrides := watchCorridorOverrides(cfg, rateService, logger)
+	defer stopOverrides()
+
+	if cfg.WarmCacheOnStart {
+		warmCache(cfg, rateService)
+	}
+	stopRefresher := refreshCorridorRates(cfg, rateService)
+	defer stopRefresher()
+	stopAlerts := evaluateAlerts(cfg, rateService)
+	defer stopAlerts()
 
 	// Setup Gin router
 	router := setupRouter(cfg, logger, rateService, appMetrics)
@@ -72,7 +97,7 @@ func main() {
 	}
 
 	// Create gRPC server
-	grpcServer := setupGRPCServer(rateService, logger)
+	grpcServer, exchangeServer := setupGRPCServer(cfg, rateService, logger)
 
 	// Start servers
 	startServers(cfg, httpServer, grpcServer, logger)
@@ -85,7 +110,7 @@ func main() {
 	logger.Info("Shutting down servers...")
 
 	// Graceful shutdown
-	shutdownServers(httpServer, grpcServer, redisClient, logger)
+	shutdownServers(cfg, httpServer, grpcServer, exchangeServer, redisClient, logger)
 
 	logger.Info("Servers stopped")
 }
@@ -127,29 +152,223 @@ func setupRedis(cfg *config.Config, logger *zap.Logger) *redis.Client {
 	return redisClient
 }
 
-func setupProvider(cfg *config.Config, logger *zap.Logger) provider.RateProvider {
-	switch cfg.ProviderType {
+func setupProvider(cfg *config.Config, logger *zap.Logger, appMetrics *metrics.Metrics) provider.RateProvider {
+	primary := setupPrimaryProvider(cfg, logger, appMetrics)
+	if cfg.ProviderFallback == "" {
+		return primary
+	}
+	secondary := guarded(cfg, validated(cfg, newProvider(cfg, cfg.ProviderFallback, logger)), appMetrics)
+	return provider.NewFallbackProvider(primary, secondary)
+}
+
+func setupPrimaryProvider(cfg *config.Config, logger *zap.Logger, appMetrics *metrics.Metrics) provider.RateProvider {
+	if cfg.ProviderType == "chain" {
+		providers := make([]provider.RateProvider, 0, len(cfg.ProviderChain))
+		for _, providerType := range cfg.ProviderChain {
+			providers = append(providers, guarded(cfg, retrying(cfg, validated(cfg, newProvider(cfg, providerType, logger))), appMetrics))
+		}
+		return provider.NewChainProvider(providers...).
+			WithHedging(time.Duration(cfg.ProviderHedgeMs)*time.Millisecond, appMetrics.RecordProviderHedge)
+	}
+
+	return guarded(cfg, retrying(cfg, validated(cfg, newProvider(cfg, cfg.ProviderType, logger))), appMetrics)
+}
+
+// guarded wraps p in a circuit breaker, when enabled, so a failing provider
+// is skipped rather than tried on every request
+func guarded(cfg *config.Config, p provider.RateProvider, appMetrics *metrics.Metrics) provider.RateProvider {
+	if cfg.ProviderCircuitThreshold <= 0 {
+		return p
+	}
+	return provider.NewCircuitBreaker(p, provider.CircuitBreakerSettings{
+		FailureThreshold: cfg.ProviderCircuitThreshold,
+		OpenDuration:     time.Duration(cfg.ProviderCircuitOpenMs) * time.Millisecond,
+		CallTimeout:      time.Duration(cfg.ProviderTimeoutMs) * time.Millisecond,
+		OnReject: func(name string) {
+			appMetrics.RecordProviderError(name, "circuit_open")
+		},
+	})
+}
+
+// retrying wraps p so transient failures are retried with backoff, when
+// enabled. It sits inside the circuit breaker so a call counts as one failure
+// only once its retries are spent.
+func retrying(cfg *config.Config, p provider.RateProvider) provider.RateProvider {
+	if cfg.ProviderRetryAttempts <= 1 {
+		return p
+	}
+	return provider.NewRetrying(p, cfg.ProviderRetryAttempts, time.Duration(cfg.ProviderRetryBaseMs)*time.Millisecond)
+}
+
+// validated wraps p so non-positive rates count as provider failures, when
+// enabled
+func validated(cfg *config.Config, p provider.RateProvider) provider.RateProvider {
+	if !cfg.ValidateProviderRates {
+		return p
+	}
+	return provider.NewValidatingProvider(p)
+}
+
+func newProvider(cfg *config.Config, providerType string, logger *zap.Logger) provider.RateProvider {
+	switch providerType {
 	case "simulated":
 		providerCfg := provider.SimulatedProviderConfig{
 			BaseSpread:           cfg.ProviderSpread,
+			PairSpreads:          cfg.ProviderPairSpreads,
+			UnavailablePairs:     cfg.ProviderUnavailablePairs,
 			MaxDrift:             cfg.ProviderMaxDrift,
 			RateValidityDuration: time.Duration(cfg.RateCacheTTL) * time.Second,
 			DriftInterval:        5 * time.Second,
+			SmoothDrift:          cfg.ProviderSmoothDrift,
 		}
 		return provider.NewSimulatedProvider(providerCfg)
 
+	case "file":
+		fileProvider, err := provider.NewFileProvider(cfg.ProviderFile)
+		if err != nil {
+			logger.Fatal("Failed to load rate file", zap.String("path", cfg.ProviderFile), zap.Error(err))
+		}
+		return fileProvider.
+			WithSpread(cfg.ProviderSpread).
+			WithRateValidity(time.Duration(cfg.RateCacheTTL) * time.Second).
+			WithReloadHook(func(err error) {
+				if err != nil {
+					logger.Error("Rate file change rejected, keeping previous rates",
+						zap.String("path", cfg.ProviderFile),
+						zap.Error(err),
+					)
+					return
+				}
+				logger.Info("Reloaded rate file", zap.String("path", cfg.ProviderFile))
+			})
+
 	// Future: Add real provider implementations
 	// case "openexchangerates":
 	//     return provider.NewOpenExchangeRatesProvider(cfg.OXRAppID, cfg.OXRAPIUrl)
 
 	default:
 		logger.Info("Unknown provider type, defaulting to simulated",
-			zap.String("configured", cfg.ProviderType),
+			zap.String("configured", providerType),
 		)
 		return provider.NewSimulatedProvider(provider.DefaultSimulatedConfig())
 	}
 }
 
+// loadRateCurrencies replaces the built-in list of currencies the provider
+// quotes with those of the pairs it reports supporting
+func loadRateCurrencies(rateProvider provider.RateProvider, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pairs, err := rateProvider.GetSupportedPairs(ctx)
+	if err != nil || len(pairs) == 0 {
+		logger.Warn("Failed to load the provider's supported pairs, using built-in currencies", zap.Error(err))
+		return
+	}
+
+	var currencies []string
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		for _, currency := range []string{pair.Source, pair.Target} {
+			if !seen[currency] {
+				seen[currency] = true
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	model.RateCurrencies = currencies
+	logger.Info("Loaded supported currencies from provider", zap.Strings("currencies", currencies))
+}
+
+// watchCorridorOverrides loads the stored corridor pricing overrides and
+// keeps reloading them, returning a function that stops the reloads
+func watchCorridorOverrides(cfg *config.Config, rateService *service.RateService, logger *zap.Logger) func() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rateService.LoadCorridorOverrides(ctx); err != nil {
+		logger.Warn("Failed to load corridor overrides, using configured pricing", zap.Error(err))
+	}
+
+	if cfg.CorridorOverrideRefreshSeconds <= 0 {
+		return func() {}
+	}
+	watchCtx, stop := context.WithCancel(context.Background())
+	go rateService.WatchCorridorOverrides(watchCtx, time.Duration(cfg.CorridorOverrideRefreshSeconds)*time.Second)
+	return stop
+}
+
+// warmCache fetches the enabled corridors' rates before serving, waiting at
+// most WarmCacheTimeoutSeconds
+func warmCache(cfg *config.Config, rateService *service.RateService) {
+	ctx := context.Background()
+	if cfg.WarmCacheTimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.WarmCacheTimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	rateService.WarmCache(ctx)
+}
+
+// refreshCorridorRates keeps the enabled corridors' cached rates fresh in the
+// background, returning a function that stops the refresher and waits for it
+// to finish
+func refreshCorridorRates(cfg *config.Config, rateService *service.RateService) func() {
+	if cfg.RefreshInterval <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rateService.RefreshCorridorRates(ctx, time.Duration(cfg.RefreshInterval)*time.Second)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// evaluateAlerts checks rate alerts in the background, returning a function
+// that stops the checks and waits for any webhook call in flight to finish
+func evaluateAlerts(cfg *config.Config, rateService *service.RateService) func() {
+	if cfg.AlertCheckInterval <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rateService.EvaluateAlerts(ctx, time.Duration(cfg.AlertCheckInterval)*time.Second)
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// setupTracing exports spans to the Jaeger collector when tracing is enabled,
+// returning a function that flushes them on shutdown
+func setupTracing(cfg *config.Config, logger *zap.Logger) func() {
+	if !cfg.TracingEnabled {
+		return func() {}
+	}
+
+	shutdown, err := tracing.Setup("exchange-rate-service", cfg.JaegerURL)
+	if err != nil {
+		logger.Warn("Failed to set up tracing, continuing without it", zap.Error(err))
+		return func() {}
+	}
+	logger.Info("Tracing enabled", zap.String("collector", cfg.JaegerURL))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Warn("Failed to flush traces", zap.Error(err))
+		}
+	}
+}
+
 func setupRouter(cfg *config.Config, logger *zap.Logger, rateService *service.RateService, appMetrics *metrics.Metrics) *gin.Engine {
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -158,9 +377,11 @@ func setupRouter(cfg *config.Config, logger *zap.Logger, rateService *service.Ra
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(requestLogger(logger))
+	router.Use(tracing.GinMiddleware())
 
 	// Setup HTTP handler
-	httpHandler := handler.NewHTTPHandler(rateService, logger)
+	httpHandler := handler.NewHTTPHandler(cfg, rateService, logger)
+	httpHandler.SetMetrics(appMetrics)
 	httpHandler.SetupRoutes(router)
 
 	// Metrics endpoint
@@ -171,12 +392,15 @@ func setupRouter(cfg *config.Config, logger *zap.Logger, rateService *service.Ra
 	return router
 }
 
-func setupGRPCServer(rateService *service.RateService, logger *zap.Logger) *grpc.Server {
-	grpcServer := grpc.NewServer()
+func setupGRPCServer(cfg *config.Config, rateService *service.RateService, logger *zap.Logger) (*grpc.Server, *grpcserver.ExchangeRateServer) {
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(tracing.StreamServerInterceptor()),
+	)
 
 	// Register exchange rate service
-	exchangeServer := grpcserver.NewExchangeRateServer(rateService, logger)
-	grpcserver.RegisterExchangeRateServiceServer(grpcServer, exchangeServer)
+	exchangeServer := grpcserver.NewExchangeRateServer(cfg, rateService, logger)
+	exchangepb.RegisterExchangeRateServiceServer(grpcServer, exchangeServer)
 
 	// Register health check service
 	healthServer := health.NewServer()
@@ -186,7 +410,7 @@ func setupGRPCServer(rateService *service.RateService, logger *zap.Logger) *grpc
 	// Enable reflection for debugging (disable in production if needed)
 	reflection.Register(grpcServer)
 
-	return grpcServer
+	return grpcServer, exchangeServer
 }
 
 func startServers(cfg *config.Config, httpServer *http.Server, grpcServer *grpc.Server, logger *zap.Logger) {
@@ -212,7 +436,7 @@ func startServers(cfg *config.Config, httpServer *http.Server, grpcServer *grpc.
 	}()
 }
 
-func shutdownServers(httpServer *http.Server, grpcServer *grpc.Server, redisClient *redis.Client, logger *zap.Logger) {
+func shutdownServers(cfg *config.Config, httpServer *http.Server, grpcServer *grpc.Server, exchangeServer *grpcserver.ExchangeRateServer, redisClient *redis.Client, logger *zap.Logger) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -221,8 +445,20 @@ func shutdownServers(httpServer *http.Server, grpcServer *grpc.Server, redisClie
 		logger.Error("HTTP server shutdown error", zap.Error(err))
 	}
 
-	// Gracefully stop gRPC server
-	grpcServer.GracefulStop()
+	// End rate streams, which never finish on their own, then gracefully
+	// stop the gRPC server, stopping hard if calls are still in flight
+	exchangeServer.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Duration(cfg.GRPCShutdownTimeout) * time.Second):
+		logger.Warn("gRPC graceful stop timed out, stopping")
+		grpcServer.Stop()
+	}
 
 	// Close Redis connection
 	if err := redisClient.Close(); err != nil {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds all configuration for the exchange rate service
@@ -17,6 +18,7 @@ type Config struct {
 	RedisDB   int
 
 	// Observability
+	TracingEnabled  bool
 	JaegerURL       string
 	MetricsEnabled  bool
 	MetricsEndpoint string
@@ -29,11 +31,69 @@ type Config struct {
 	RateCacheTTL int // seconds
 	LockDuration int // seconds (default lock duration)
 	MaxLockDuration int // seconds (maximum allowed lock duration)
+	ExpiryGranularityMs int // lock and quote expiries are rounded to this many ms (0 disables)
+	CanonicalPairOrdering bool // fetch and cache rates in the market-convention direction, inverting for the other
+	FailOnCacheWriteError bool // fail rate requests when the rate cache can't be written instead of serving uncached
+	StaleGracePeriod      int  // seconds past expiry a cached rate is still served while it is refreshed in the background (0 disables)
+	SnapshotLockTerms     bool // quotes from a lock use the margin and fees in effect at lock time
+	RateSnapshotRetentionHours int // hours a lock's market snapshot is kept after the lock expires, for disputes (0 disables)
+	RateHistoryMaxPoints       int // rates kept per pair for the history endpoint, oldest dropped first (0 disables)
+	LockFallbackSize           int // locked rates kept in memory while Redis is unreachable (0 disables)
+	MaxActiveLocksPerPair      int // unexpired locks allowed per currency pair, so one client can't lock a pair without limit (0 disables)
+	WarmCacheOnStart           bool // fetch every enabled corridor's rate at startup, so first requests are served from cache
+	WarmCacheTimeoutSeconds    int  // longest startup waits for the cache to warm before serving anyway
+	RefreshInterval            int  // seconds between background refreshes of enabled corridors' rates, set just under the rate validity (0 disables)
+
+	// Quotes
+	MaxQuoteAmount          float64 // largest quote total in the source currency (0 disables)
+	MaxReverseFeePercentage float64 // corridors at or above this fee percentage can't be quoted by target amount (0 disables)
+	ReportingCurrency       string  // currency captured quote margin is reported in (empty disables the metric)
+	MaxQuoteLegs            int     // pairs without a corridor are quoted through up to this many corridors (1 or less disables)
+	AggregationBudgetMs     int     // endpoints covering many pairs return what they have after this many ms (0 waits for every pair)
+	FeeRounding             string  // how quote fees are rounded to the source currency's precision: "up" (default), "nearest" or "down"
 
 	// Provider configuration
-	ProviderType      string  // "simulated" or "openexchangerates"
-	ProviderSpread    float64 // Base spread percentage (e.g., 0.005 for 0.5%)
-	ProviderMaxDrift  float64 // Max drift percentage for simulated provider
+	ProviderType             string             // "simulated", "file", "chain" or "openexchangerates"
+	ProviderFile             string             // JSON or YAML file of mid rates, keyed "SGD/PHP", served when ProviderType is "file"
+	ProviderChain            []string           // Provider types tried in order when ProviderType is "chain"
+	ProviderFallback         string             // provider type serving rates while the primary is unavailable, e.g. "simulated" (empty disables)
+	ProviderHedgeMs          int                // Chain queries the next provider in parallel after this many ms (0 disables)
+	ProviderTimeoutMs        int                // provider calls fail as unavailable after this many ms (0 disables)
+	ProviderCircuitThreshold int                // consecutive failures that open a provider's circuit breaker (0 disables)
+	ProviderCircuitOpenMs    int                // ms an open circuit fails fast before probing the provider again
+	ProviderRetryAttempts    int                // attempts per provider call when it is unavailable (1 disables retries)
+	ProviderRetryBaseMs      int                // backoff before the first retry, doubled for each one after
+	ProviderSpread           float64            // Base spread percentage (e.g., 0.005 for 0.5%)
+	ProviderPairSpreads      map[string]float64 // per-pair spread overrides for the simulated provider, e.g. "SGD/IDR=0.012"
+	ProviderUnavailablePairs []string           // pairs the simulated provider always reports unavailable, e.g. "SGD/IDR", for testing outages
+	ProviderMaxDrift         float64            // Max drift percentage for simulated provider
+	ProviderSmoothDrift      bool               // simulated rates drift continuously instead of jumping every interval
+	ValidateProviderRates    bool               // treat zero, negative or non-finite provider rates as provider failures
+
+	// Health degradation
+	DegradedErrorRate   float64 // Provider error rate (0-1) above which /ready reports degraded (0 disables)
+	ErrorRateWindow     int     // seconds of provider calls included in the error rate
+	ErrorRateMinSamples int     // minimum provider calls in the window before degrading
+
+	// API deprecation warnings
+	DeprecationWarnings       bool // send a Warning header when deprecated fields are requested
+	DeprecationWarningsInBody bool // also add a _warnings field to the response body
+
+	// gRPC rate streaming
+	StreamMaxPairs          int // maximum currency pairs per StreamRates subscription
+	StreamSendBuffer        int // queued updates per stream before the oldest is dropped
+	StreamSlowClientTimeout int // seconds a stream may stay blocked with a full buffer before disconnecting
+	GRPCShutdownTimeout     int // seconds to wait for in-flight gRPC calls on shutdown before stopping hard
+
+	// Admin API
+	AdminToken                     string // bearer token required by admin endpoints that change configuration (empty disables them)
+	CorridorOverrideRefreshSeconds int    // how often corridor pricing overrides are reloaded from Redis (0 disables)
+
+	// Rate alerts
+	AlertCheckInterval int // seconds between checks of rate alerts against current rates, the simulated drift interval by default (0 disables)
+	MaxAlerts          int // rate alerts allowed in total (0 disables)
+	MaxAlertsPerClient int // rate alerts allowed per client (0 disables)
+	MaxAlertFailures   int // consecutive webhook failures after which an alert is disabled (0 disables)
 
 	// OpenExchangeRates API (for future use)
 	OXRAppID  string
@@ -53,6 +113,7 @@ func Load() *Config {
 		RedisDB:   getEnvInt("REDIS_DB", 0),
 
 		// Observability
+		TracingEnabled:  getEnvBool("TRACING_ENABLED", false),
 		JaegerURL:       getEnv("JAEGER_URL", "http://localhost:14268/api/traces"),
 		MetricsEnabled:  getEnvBool("METRICS_ENABLED", true),
 		MetricsEndpoint: getEnv("METRICS_ENDPOINT", "/metrics"),
@@ -65,11 +126,69 @@ func Load() *Config {
 		RateCacheTTL:    getEnvInt("RATE_CACHE_TTL", 60),
 		LockDuration:    getEnvInt("LOCK_DURATION", 30),
 		MaxLockDuration: getEnvInt("MAX_LOCK_DURATION", 120),
+		ExpiryGranularityMs: getEnvInt("EXPIRY_GRANULARITY_MS", 1000),
+		CanonicalPairOrdering: getEnvBool("CANONICAL_PAIR_ORDERING", true),
+		FailOnCacheWriteError: getEnvBool("FAIL_ON_CACHE_WRITE_ERROR", false),
+		StaleGracePeriod:      getEnvInt("STALE_GRACE_PERIOD", 0),
+		SnapshotLockTerms:     getEnvBool("SNAPSHOT_LOCK_TERMS", true),
+		RateSnapshotRetentionHours: getEnvInt("RATE_SNAPSHOT_RETENTION_HOURS", 90*24),
+		RateHistoryMaxPoints:       getEnvInt("RATE_HISTORY_MAX_POINTS", 1000),
+		LockFallbackSize:           getEnvInt("LOCK_FALLBACK_SIZE", 1000),
+		MaxActiveLocksPerPair:      getEnvInt("MAX_ACTIVE_LOCKS_PER_PAIR", 1000),
+		WarmCacheOnStart:           getEnvBool("WARM_CACHE_ON_START", false),
+		WarmCacheTimeoutSeconds:    getEnvInt("WARM_CACHE_TIMEOUT_SECONDS", 10),
+		RefreshInterval:            getEnvInt("REFRESH_INTERVAL", 0),
+
+		// Quotes
+		MaxQuoteAmount:          getEnvFloat("MAX_QUOTE_AMOUNT", 1000000),
+		MaxReverseFeePercentage: getEnvFloat("MAX_REVERSE_FEE_PERCENTAGE", 50),
+		ReportingCurrency:       getEnv("REPORTING_CURRENCY", "USD"),
+		MaxQuoteLegs:            getEnvInt("MAX_QUOTE_LEGS", 2),
+		AggregationBudgetMs:     getEnvInt("AGGREGATION_BUDGET_MS", 2000),
+		FeeRounding:             getEnv("FEE_ROUNDING", "up"),
 
 		// Provider configuration
-		ProviderType:     getEnv("PROVIDER_TYPE", "simulated"),
-		ProviderSpread:   getEnvFloat("PROVIDER_SPREAD", 0.005),
-		ProviderMaxDrift: getEnvFloat("PROVIDER_MAX_DRIFT", 0.02),
+		ProviderType:             getEnv("PROVIDER_TYPE", "simulated"),
+		ProviderFile:             getEnv("PROVIDER_FILE", "rates.yaml"),
+		ProviderChain:            getEnvList("PROVIDER_CHAIN", []string{"simulated"}),
+		ProviderFallback:         getEnv("PROVIDER_FALLBACK", ""),
+		ProviderHedgeMs:          getEnvInt("PROVIDER_HEDGE_MS", 0),
+		ProviderTimeoutMs:        getEnvInt("PROVIDER_TIMEOUT_MS", 5000),
+		ProviderCircuitThreshold: getEnvInt("PROVIDER_CIRCUIT_THRESHOLD", 5),
+		ProviderCircuitOpenMs:    getEnvInt("PROVIDER_CIRCUIT_OPEN_MS", 30000),
+		ProviderRetryAttempts:    getEnvInt("PROVIDER_RETRY_ATTEMPTS", 3),
+		ProviderRetryBaseMs:      getEnvInt("PROVIDER_RETRY_BASE_MS", 100),
+		ProviderSpread:           getEnvFloat("PROVIDER_SPREAD", 0.005),
+		ProviderPairSpreads:      getEnvFloatMap("PROVIDER_PAIR_SPREADS", nil),
+		ProviderUnavailablePairs: getEnvList("PROVIDER_UNAVAILABLE_PAIRS", nil),
+		ProviderMaxDrift:         getEnvFloat("PROVIDER_MAX_DRIFT", 0.02),
+		ProviderSmoothDrift:      getEnvBool("PROVIDER_SMOOTH_DRIFT", false),
+		ValidateProviderRates:    getEnvBool("VALIDATE_PROVIDER_RATES", true),
+
+		// Health degradation
+		DegradedErrorRate:   getEnvFloat("DEGRADED_ERROR_RATE", 0.5),
+		ErrorRateWindow:     getEnvInt("ERROR_RATE_WINDOW", 60),
+		ErrorRateMinSamples: getEnvInt("ERROR_RATE_MIN_SAMPLES", 10),
+
+		// API deprecation warnings
+		DeprecationWarnings:       getEnvBool("DEPRECATION_WARNINGS", true),
+		DeprecationWarningsInBody: getEnvBool("DEPRECATION_WARNINGS_BODY", false),
+
+		// gRPC rate streaming
+		StreamMaxPairs:          getEnvInt("STREAM_MAX_PAIRS", 50),
+		StreamSendBuffer:        getEnvInt("STREAM_SEND_BUFFER", 100),
+		StreamSlowClientTimeout: getEnvInt("STREAM_SLOW_CLIENT_TIMEOUT", 30),
+		GRPCShutdownTimeout:     getEnvInt("GRPC_SHUTDOWN_TIMEOUT", 10),
+
+		// Admin API
+		AdminToken:                     getEnv("ADMIN_TOKEN", ""),
+		CorridorOverrideRefreshSeconds: getEnvInt("CORRIDOR_OVERRIDE_REFRESH_SECONDS", 30),
+
+		// Rate alerts
+		AlertCheckInterval: getEnvInt("ALERT_CHECK_INTERVAL", 5),
+		MaxAlerts:          getEnvInt("MAX_ALERTS", 10000),
+		MaxAlertsPerClient: getEnvInt("MAX_ALERTS_PER_CLIENT", 50),
+		MaxAlertFailures:   getEnvInt("MAX_ALERT_FAILURES", 10),
 
 		// OpenExchangeRates API
 		OXRAppID:  getEnv("OXR_APP_ID", ""),
@@ -120,3 +239,39 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvFloatMap parses "key=number" pairs separated by commas,
+// e.g. "SGD/IDR=0.012,SGD/VND=0.015". Malformed entries are skipped.
+func getEnvFloatMap(key string, defaultValue map[string]float64) map[string]float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+			result[strings.TrimSpace(name)] = f
+		}
+	}
+	return result
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 0 {
+			return items
+		}
+	}
+	return defaultValue
+}
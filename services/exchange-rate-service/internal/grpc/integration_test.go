@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/pb/exchangepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// dialTestServer serves server on a local port and returns a client for it.
+// The generated client speaks the default proto codec, as the API gateway's
+// proto-loader client does.
+func dialTestServer(t *testing.T, server *ExchangeRateServer) exchangepb.ExchangeRateServiceClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	exchangepb.RegisterExchangeRateServiceServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return exchangepb.NewExchangeRateServiceClient(conn)
+}
+
+func TestIntegration_GetRateOverConnection(t *testing.T) {
+	client := dialTestServer(t, newQuoteTestServer(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.GetRate(ctx, &GetRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP"})
+	if err != nil {
+		t.Fatalf("GetRate call failed: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("unexpected error response: %+v", resp.Error)
+	}
+	if resp.Rate == nil || resp.Rate.SourceCurrency != "SGD" || resp.Rate.TargetCurrency != "PHP" || resp.Rate.Rate == "" {
+		t.Errorf("expected an SGD/PHP rate, got %+v", resp.Rate)
+	}
+	if resp.Rate.FetchedAt == nil || resp.Rate.FetchedAt.Seconds == 0 {
+		t.Errorf("expected the fetch time to survive the round trip, got %+v", resp.Rate.FetchedAt)
+	}
+
+	resp, err = client.GetRate(ctx, &GetRateRequest{SourceCurrency: "ZZZ", TargetCurrency: "PHP"})
+	if err != nil {
+		t.Fatalf("GetRate call failed: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != "UNSUPPORTED_CURRENCY" {
+		t.Errorf("expected UNSUPPORTED_CURRENCY, got %+v", resp.Error)
+	}
+}
+
+func TestIntegration_StreamRatesOverConnection(t *testing.T) {
+	client := dialTestServer(t, newStreamTestServer(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamRates(ctx, &StreamRatesRequest{CurrencyPairs: []string{"SGD:PHP", "USD:SGD"}})
+	if err != nil {
+		t.Fatalf("StreamRates call failed: %v", err)
+	}
+	for _, want := range []string{"SGD/PHP", "USD/SGD"} {
+		update, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive the initial snapshot: %v", err)
+		}
+		if got := update.Rate.SourceCurrency + "/" + update.Rate.TargetCurrency; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestIntegration_QuoteAndLockOverConnection(t *testing.T) {
+	client := dialTestServer(t, newLockTestServer(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	quoteResp, err := client.GetQuote(ctx, &GetQuoteRequest{SourceCurrency: "SGD", TargetCurrency: "PHP", SourceAmount: "100"})
+	if err != nil {
+		t.Fatalf("GetQuote call failed: %v", err)
+	}
+	if quoteResp.Error != nil {
+		t.Fatalf("unexpected error response: %+v", quoteResp.Error)
+	}
+	if fee := quoteResp.Quote.GetFee(); fee.GetAmount() != "3.00" || fee.GetCurrency() != "SGD" {
+		t.Errorf("expected a 3.00 SGD fee to survive the round trip, got %+v", fee)
+	}
+
+	lockResp, err := client.LockRate(ctx, &LockRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP", LockDurationSeconds: 30})
+	if err != nil {
+		t.Fatalf("LockRate call failed: %v", err)
+	}
+	if lockResp.Error != nil {
+		t.Fatalf("unexpected error response: %+v", lockResp.Error)
+	}
+	lockID := lockResp.LockedRate.GetLockId()
+
+	gotResp, err := client.GetLockedRate(ctx, &GetLockedRateRequest{LockId: lockID})
+	if err != nil {
+		t.Fatalf("GetLockedRate call failed: %v", err)
+	}
+	if gotResp.LockedRate.GetLockId() != lockID || gotResp.LockedRate.GetRate().GetBuyRate() != lockResp.LockedRate.GetRate().GetBuyRate() {
+		t.Errorf("expected lock %s back as locked, got %+v", lockID, gotResp.LockedRate)
+	}
+}
@@ -2,10 +2,19 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/pb/commonpb"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/pb/exchangepb"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -15,20 +24,38 @@ import (
 // ExchangeRateServer implements the gRPC ExchangeRateService
 type ExchangeRateServer struct {
 	UnimplementedExchangeRateServiceServer
+	config  *config.Config
 	service *service.RateService
 	logger  *zap.Logger
+
+	// shutdown is closed by Shutdown to end every active rate stream
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
 }
 
 // NewExchangeRateServer creates a new gRPC server instance
-func NewExchangeRateServer(svc *service.RateService, logger *zap.Logger) *ExchangeRateServer {
+func NewExchangeRateServer(cfg *config.Config, svc *service.RateService, logger *zap.Logger) *ExchangeRateServer {
 	return &ExchangeRateServer{
-		service: svc,
-		logger:  logger,
+		config:   cfg,
+		service:  svc,
+		logger:   logger,
+		shutdown: make(chan struct{}),
 	}
 }
 
+// Shutdown ends every active and future StreamRates call with an Unavailable
+// status, so clients reconnect to another instance and GracefulStop doesn't
+// wait on streams that would otherwise never finish
+func (s *ExchangeRateServer) Shutdown() {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+}
+
+// errShuttingDown ends rate streams when the server shuts down
+var errShuttingDown = status.Error(codes.Unavailable, "server shutting down, reconnect to continue streaming rates")
+
 // GetRate returns the current exchange rate for a currency pair
 func (s *ExchangeRateServer) GetRate(ctx context.Context, req *GetRateRequest) (*GetRateResponse, error) {
+	req.SourceCurrency, req.TargetCurrency = provider.NormalizeCurrency(req.SourceCurrency), provider.NormalizeCurrency(req.TargetCurrency)
 	if req.SourceCurrency == "" || req.TargetCurrency == "" {
 		return &GetRateResponse{
 			Error: &Error{
@@ -38,6 +65,10 @@ func (s *ExchangeRateServer) GetRate(ctx context.Context, req *GetRateRequest) (
 		}, nil
 	}
 
+	if unsupported := unsupportedCurrencyError(req.SourceCurrency, req.TargetCurrency); unsupported != nil {
+		return &GetRateResponse{Error: unsupported}, nil
+	}
+
 	rate, err := s.service.GetRate(ctx, req.SourceCurrency, req.TargetCurrency)
 	if err != nil {
 		s.logger.Error("Failed to get rate",
@@ -58,8 +89,64 @@ func (s *ExchangeRateServer) GetRate(ctx context.Context, req *GetRateRequest) (
 	}, nil
 }
 
+// GetQuote prices a transfer of a source amount, including fees
+func (s *ExchangeRateServer) GetQuote(ctx context.Context, req *GetQuoteRequest) (*GetQuoteResponse, error) {
+	req.SourceCurrency, req.TargetCurrency = provider.NormalizeCurrency(req.SourceCurrency), provider.NormalizeCurrency(req.TargetCurrency)
+	amount, err := strconv.ParseFloat(req.SourceAmount, 64)
+	if req.SourceCurrency == "" || req.TargetCurrency == "" || err != nil || !(amount > 0) || math.IsInf(amount, 1) {
+		return &GetQuoteResponse{
+			Error: &Error{
+				Code:    "INVALID_ARGUMENT",
+				Message: "source_currency, target_currency and a positive source_amount are required",
+			},
+		}, nil
+	}
+
+	if unsupported := unsupportedCurrencyError(req.SourceCurrency, req.TargetCurrency); unsupported != nil {
+		return &GetQuoteResponse{Error: unsupported}, nil
+	}
+
+	quote, err := s.service.GetQuote(ctx, req.SourceCurrency, req.TargetCurrency, amount)
+	if err != nil {
+		code := "QUOTE_FAILED"
+		switch err.(type) {
+		case service.ErrBelowMinTargetAmount:
+			code = "BELOW_MIN_TARGET_AMOUNT"
+		case service.ErrQuoteAmountTooLarge:
+			code = "AMOUNT_TOO_LARGE"
+		case service.ErrAmountOutOfRange:
+			code = "AMOUNT_OUT_OF_RANGE"
+		case service.ErrCorridorDisabled:
+			code = "CORRIDOR_DISABLED"
+		case service.ErrCorridorNotFound:
+			code = "CORRIDOR_NOT_FOUND"
+		default:
+			var writeErr repository.ErrWriteFailed
+			if errors.As(err, &writeErr) {
+				code = "UNAVAILABLE"
+			}
+			s.logger.Error("Failed to get quote",
+				zap.String("source", req.SourceCurrency),
+				zap.String("target", req.TargetCurrency),
+				zap.Error(err),
+			)
+		}
+		return &GetQuoteResponse{
+			Error: &Error{
+				Code:    code,
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	return &GetQuoteResponse{
+		Quote: modelQuoteToProto(quote),
+	}, nil
+}
+
 // LockRate locks a rate for a specified duration
 func (s *ExchangeRateServer) LockRate(ctx context.Context, req *LockRateRequest) (*LockRateResponse, error) {
+	req.SourceCurrency, req.TargetCurrency = provider.NormalizeCurrency(req.SourceCurrency), provider.NormalizeCurrency(req.TargetCurrency)
 	if req.SourceCurrency == "" || req.TargetCurrency == "" {
 		return &LockRateResponse{
 			Error: &Error{
@@ -69,21 +156,39 @@ func (s *ExchangeRateServer) LockRate(ctx context.Context, req *LockRateRequest)
 		}, nil
 	}
 
+	if unsupported := unsupportedCurrencyError(req.SourceCurrency, req.TargetCurrency); unsupported != nil {
+		return &LockRateResponse{Error: unsupported}, nil
+	}
+
 	durationSeconds := int(req.LockDurationSeconds)
-	if durationSeconds <= 0 {
-		durationSeconds = 30 // Default 30 seconds
+	if invalid := s.invalidLockError(req.SourceCurrency, req.TargetCurrency, durationSeconds); invalid != nil {
+		return &LockRateResponse{Error: invalid}, nil
 	}
 
-	locked, err := s.service.LockRate(ctx, req.SourceCurrency, req.TargetCurrency, durationSeconds)
+	locked, err := s.service.LockRateIdempotent(ctx, req.IdempotencyKey, req.SourceCurrency, req.TargetCurrency, durationSeconds)
 	if err != nil {
 		s.logger.Error("Failed to lock rate",
 			zap.String("source", req.SourceCurrency),
 			zap.String("target", req.TargetCurrency),
 			zap.Error(err),
 		)
+		code := "LOCK_FAILED"
+		var writeErr repository.ErrWriteFailed
+		switch err.(type) {
+		case service.ErrLockLimitExceeded:
+			code = "LOCK_LIMIT_EXCEEDED"
+		case service.ErrIdempotencyKeyConflict:
+			code = "IDEMPOTENCY_KEY_CONFLICT"
+		case service.ErrIdempotentRequestInProgress:
+			code = "REQUEST_IN_PROGRESS"
+		default:
+			if errors.As(err, &writeErr) {
+				code = "UNAVAILABLE"
+			}
+		}
 		return &LockRateResponse{
 			Error: &Error{
-				Code:    "LOCK_FAILED",
+				Code:    code,
 				Message: err.Error(),
 			},
 		}, nil
@@ -124,9 +229,99 @@ func (s *ExchangeRateServer) GetLockedRate(ctx context.Context, req *GetLockedRa
 	}, nil
 }
 
+// ExtendLockedRate extends a single rate lock
+func (s *ExchangeRateServer) ExtendLockedRate(ctx context.Context, req *ExtendLockedRateRequest) (*ExtendLockedRateResponse, error) {
+	if req.LockId == "" || req.AdditionalSeconds <= 0 {
+		return &ExtendLockedRateResponse{
+			Error: &Error{
+				Code:    "INVALID_ARGUMENT",
+				Message: "lock_id and a positive additional_seconds are required",
+			},
+		}, nil
+	}
+
+	locked, err := s.service.ExtendLockedRate(ctx, req.LockId, int(req.AdditionalSeconds))
+	if err != nil {
+		code := "EXTEND_FAILED"
+		switch err.(type) {
+		case repository.ErrNotFound:
+			code = "LOCK_NOT_FOUND"
+		case repository.ErrExpired:
+			code = "LOCK_EXPIRED"
+		case service.ErrLockDurationExceeded:
+			code = "MAX_LOCK_DURATION_EXCEEDED"
+		default:
+			var writeErr repository.ErrWriteFailed
+			if errors.As(err, &writeErr) {
+				code = "UNAVAILABLE"
+			}
+			s.logger.Error("Failed to extend locked rate",
+				zap.String("lockId", req.LockId),
+				zap.Error(err),
+			)
+		}
+		return &ExtendLockedRateResponse{
+			Error: &Error{
+				Code:    code,
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	return &ExtendLockedRateResponse{
+		LockedRate: modelLockedRateToProto(locked),
+	}, nil
+}
+
+// ExtendLockedRates extends several rate locks and reports the result per lock
+func (s *ExchangeRateServer) ExtendLockedRates(ctx context.Context, req *ExtendLockedRatesRequest) (*ExtendLockedRatesResponse, error) {
+	if len(req.LockIds) == 0 || req.AdditionalSeconds <= 0 {
+		return &ExtendLockedRatesResponse{
+			Error: &Error{
+				Code:    "INVALID_ARGUMENT",
+				Message: "lock_ids and a positive additional_seconds are required",
+			},
+		}, nil
+	}
+
+	results, err := s.service.ExtendLockedRates(ctx, req.LockIds, int(req.AdditionalSeconds))
+	if err != nil {
+		s.logger.Error("Failed to extend locked rates", zap.Error(err))
+		return &ExtendLockedRatesResponse{
+			Error: &Error{
+				Code:    "EXTEND_FAILED",
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	protoResults := make([]*LockExtensionResult, 0, len(results))
+	for _, r := range results {
+		result := &LockExtensionResult{
+			LockId:   r.LockID,
+			Extended: r.Extended,
+			Error:    r.Error,
+		}
+		if r.ExpiresAt != nil {
+			result.ExpiresAt = timeToProtoTimestamp(*r.ExpiresAt)
+		}
+		protoResults = append(protoResults, result)
+	}
+
+	return &ExtendLockedRatesResponse{
+		Results: protoResults,
+	}, nil
+}
+
 // GetCorridors returns available currency corridors
 func (s *ExchangeRateServer) GetCorridors(ctx context.Context, req *GetCorridorsRequest) (*GetCorridorsResponse, error) {
-	corridors := s.service.GetCorridors(req.SourceCurrency)
+	req.SourceCurrency, req.TargetCurrency = provider.NormalizeCurrency(req.SourceCurrency), provider.NormalizeCurrency(req.TargetCurrency)
+	corridors := s.service.GetCorridors(service.CorridorFilter{
+		SourceCurrency:  req.SourceCurrency,
+		TargetCurrency:  req.TargetCurrency,
+		PayoutMethod:    req.PayoutMethod,
+		IncludeDisabled: req.IncludeDisabled,
+	})
 
 	protoCorridors := make([]*Corridor, 0, len(corridors))
 	for _, c := range corridors {
@@ -138,11 +333,39 @@ func (s *ExchangeRateServer) GetCorridors(ctx context.Context, req *GetCorridors
 	}, nil
 }
 
-// StreamRates streams real-time rate updates
+// Bounds on how often StreamRates pushes updates; requests without an
+// interval get the default
+const (
+	defaultStreamInterval    = 5 * time.Second
+	minStreamIntervalSeconds = 1
+	maxStreamIntervalSeconds = 60
+
+	// streamChangeEpsilon is how far a mid rate must move for an OnlyChanges
+	// stream to send it again
+	streamChangeEpsilon = 1e-9
+)
+
+// StreamRates streams real-time rate updates every IntervalSeconds, starting
+// with a snapshot of every pair unless IncludeInitialSnapshot is false. With
+// OnlyChanges a pair is only sent again once its mid rate has moved. Updates
+// after a pair's first carry the change since it was last sent.
 func (s *ExchangeRateServer) StreamRates(req *StreamRatesRequest, stream ExchangeRateService_StreamRatesServer) error {
 	if len(req.CurrencyPairs) == 0 {
 		return status.Error(codes.InvalidArgument, "at least one currency pair is required")
 	}
+	if s.config.StreamMaxPairs > 0 && len(req.CurrencyPairs) > s.config.StreamMaxPairs {
+		return status.Errorf(codes.ResourceExhausted, "too many currency pairs: %d (maximum %d per stream)",
+			len(req.CurrencyPairs), s.config.StreamMaxPairs)
+	}
+
+	interval := defaultStreamInterval
+	if req.IntervalSeconds != 0 {
+		if req.IntervalSeconds < minStreamIntervalSeconds || req.IntervalSeconds > maxStreamIntervalSeconds {
+			return status.Errorf(codes.InvalidArgument, "interval_seconds must be between %d and %d, got %d",
+				minStreamIntervalSeconds, maxStreamIntervalSeconds, req.IntervalSeconds)
+		}
+		interval = time.Duration(req.IntervalSeconds) * time.Second
+	}
 
 	// Parse currency pairs
 	type pair struct {
@@ -158,31 +381,73 @@ func (s *ExchangeRateServer) StreamRates(req *StreamRatesRequest, stream Exchang
 				break
 			}
 		}
+		source, target = provider.NormalizeCurrency(source), provider.NormalizeCurrency(target)
 		if source == "" || target == "" {
 			return status.Errorf(codes.InvalidArgument, "invalid currency pair format: %s (expected 'XXX:YYY')", cp)
 		}
+		if unsupported := unsupportedCurrencyError(source, target); unsupported != nil {
+			return status.Error(codes.InvalidArgument, unsupported.Message)
+		}
 		pairs = append(pairs, pair{source, target})
 	}
 
-	// Stream rates every 5 seconds
-	ticker := time.NewTicker(5 * time.Second)
+	select {
+	case <-s.shutdown:
+		return errShuttingDown
+	default:
+	}
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	ctx := stream.Context()
+	ctx, cancel := context.WithCancel(stream.Context())
+
+	// Sends happen on their own goroutine so a slow client can't block this
+	// loop. It is stopped and waited for before returning, since the stream
+	// must not be sent to once the handler has returned.
+	sender := newStreamSender(stream, s.config.StreamSendBuffer, time.Duration(s.config.StreamSlowClientTimeout)*time.Second)
+	go sender.run(ctx)
+	defer func() {
+		cancel()
+		if !sender.wait(streamSenderStopTimeout) {
+			s.logger.Warn("Rate stream send still blocked on return", zap.Int("pairs", len(pairs)))
+		}
+	}()
+
+	sendRates := func() error {
+		for _, p := range pairs {
+			rate, err := s.service.GetRate(ctx, p.source, p.target)
+			if err != nil {
+				s.logger.Warn("Failed to get rate for stream",
+					zap.String("source", p.source),
+					zap.String("target", p.target),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			// With OnlyChanges, skip pairs that haven't moved since their last
+			// update was sent; a pair whose update was dropped is sent again
+			key := p.source + "/" + p.target
+			if previous, sent := sender.LastSent(key); sent && req.OnlyChanges && math.Abs(rate.MidRate-previous) <= streamChangeEpsilon {
+				continue
+			}
 
-	// Send initial rates immediately
-	for _, p := range pairs {
-		rate, err := s.service.GetRate(ctx, p.source, p.target)
-		if err != nil {
-			s.logger.Warn("Failed to get rate for stream",
-				zap.String("source", p.source),
-				zap.String("target", p.target),
-				zap.Error(err),
-			)
-			continue
+			if err := sender.enqueue(key, rate.MidRate, &RateUpdate{Rate: modelRateToProto(rate)}); err != nil {
+				s.logger.Warn("Closing rate stream",
+					zap.Int("pairs", len(pairs)),
+					zap.Int("dropped", sender.Dropped()),
+					zap.Error(err),
+				)
+				return err
+			}
 		}
+		return nil
+	}
 
-		if err := stream.Send(&RateUpdate{Rate: modelRateToProto(rate)}); err != nil {
+	// Send initial rates immediately unless the client already has them
+	if req.IncludeInitialSnapshot == nil || *req.IncludeInitialSnapshot {
+		if err := sendRates(); err != nil {
 			return err
 		}
 	}
@@ -192,19 +457,48 @@ func (s *ExchangeRateServer) StreamRates(req *StreamRatesRequest, stream Exchang
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-s.shutdown:
+			s.logger.Info("Closing rate stream for shutdown", zap.Int("pairs", len(pairs)))
+			return errShuttingDown
+		case <-sender.Done():
+			return sender.Err()
 		case <-ticker.C:
-			for _, p := range pairs {
-				rate, err := s.service.GetRate(ctx, p.source, p.target)
-				if err != nil {
-					continue
-				}
-
-				if err := stream.Send(&RateUpdate{Rate: modelRateToProto(rate)}); err != nil {
-					return err
-				}
+			if err := sendRates(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// invalidLockError returns an INVALID_ARGUMENT error unless durationSeconds
+// is between 0, for the default duration, and MaxLockDuration and the pair
+// has a corridor, as the HTTP API requires, or nil if the lock is valid
+func (s *ExchangeRateServer) invalidLockError(from, to string, durationSeconds int) *Error {
+	if durationSeconds < 0 {
+		return &Error{Code: "INVALID_ARGUMENT", Message: "lock_duration_seconds must not be negative"}
+	}
+	if max := s.config.MaxLockDuration; max > 0 && durationSeconds > max {
+		return &Error{Code: "INVALID_ARGUMENT", Message: fmt.Sprintf("lock_duration_seconds must be at most %d", max)}
+	}
+	corridors := s.service.GetCorridors(service.CorridorFilter{SourceCurrency: from, TargetCurrency: to, IncludeDisabled: true})
+	if len(corridors) == 0 {
+		return &Error{Code: "INVALID_ARGUMENT", Message: fmt.Sprintf("no corridor for %s/%s", from, to)}
+	}
+	return nil
+}
+
+// unsupportedCurrencyError returns an UNSUPPORTED_CURRENCY error for the
+// first of currencies that isn't supported, or nil if all are
+func unsupportedCurrencyError(currencies ...string) *Error {
+	for _, code := range currencies {
+		if !model.IsSupportedCurrency(code) {
+			return &Error{
+				Code:    "UNSUPPORTED_CURRENCY",
+				Message: fmt.Sprintf("unsupported currency: %s", code),
 			}
 		}
 	}
+	return nil
 }
 
 // Helper functions to convert between model and proto types
@@ -218,6 +512,24 @@ func modelRateToProto(rate *model.ExchangeRate) *ExchangeRate {
 		MarginPercentage: rate.MarginPercentage,
 		FetchedAt:        timeToProtoTimestamp(rate.FetchedAt),
 		ExpiresAt:        timeToProtoTimestamp(rate.ExpiresAt),
+		Age:              rate.Age,
+		FromCache:        rate.FromCache,
+	}
+}
+
+func modelQuoteToProto(q *model.RateQuote) *Quote {
+	return &Quote{
+		QuoteId:        q.QuoteID,
+		SourceCurrency: q.SourceCurrency,
+		TargetCurrency: q.TargetCurrency,
+		SourceAmount:   &Money{Currency: q.SourceCurrency, Amount: q.SourceAmount.String()},
+		TargetAmount:   &Money{Currency: q.TargetCurrency, Amount: q.TargetAmount.String()},
+		ExchangeRate:   q.ExchangeRate.StringFixed(model.RatePlaces),
+		MidMarketRate:  q.MidMarketRate.StringFixed(model.RatePlaces),
+		EffectiveRate:  q.EffectiveRate.StringFixed(model.RatePlaces),
+		Fee:            &Money{Currency: q.SourceCurrency, Amount: q.Fee.String()},
+		TotalCost:      &Money{Currency: q.SourceCurrency, Amount: q.TotalCost.String()},
+		ValidUntil:     timeToProtoTimestamp(q.ValidUntil),
 	}
 }
 
@@ -240,6 +552,9 @@ func modelCorridorToProto(c *model.Corridor) *Corridor {
 		FeeMinimum:       &Money{Currency: c.FeeMinimum.Currency, Amount: c.FeeMinimum.Amount},
 		MarginPercentage: c.MarginPercentage,
 		PayoutMethods:    c.PayoutMethods,
+		MinTargetAmount:  &Money{Currency: c.MinTargetAmount.Currency, Amount: c.MinTargetAmount.Amount},
+
+		RateValiditySeconds: int32(c.RateValiditySeconds),
 	}
 }
 
@@ -257,131 +572,70 @@ func protoTimestampToTime(ts *Timestamp) time.Time {
 	return time.Unix(ts.Seconds, int64(ts.Nanos))
 }
 
-// Placeholder types for generated proto code
-// These will be replaced by actual generated code from protoc
-
-// UnimplementedExchangeRateServiceServer provides forward compatibility
-type UnimplementedExchangeRateServiceServer struct{}
-
-func (UnimplementedExchangeRateServiceServer) GetRate(context.Context, *GetRateRequest) (*GetRateResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetRate not implemented")
-}
-func (UnimplementedExchangeRateServiceServer) LockRate(context.Context, *LockRateRequest) (*LockRateResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method LockRate not implemented")
-}
-func (UnimplementedExchangeRateServiceServer) GetLockedRate(context.Context, *GetLockedRateRequest) (*GetLockedRateResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetLockedRate not implemented")
-}
-func (UnimplementedExchangeRateServiceServer) GetCorridors(context.Context, *GetCorridorsRequest) (*GetCorridorsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetCorridors not implemented")
-}
-func (UnimplementedExchangeRateServiceServer) StreamRates(*StreamRatesRequest, ExchangeRateService_StreamRatesServer) error {
-	return status.Errorf(codes.Unimplemented, "method StreamRates not implemented")
-}
-func (UnimplementedExchangeRateServiceServer) mustEmbedUnimplementedExchangeRateServiceServer() {}
-
-// ExchangeRateService_StreamRatesServer is the server stream interface
-type ExchangeRateService_StreamRatesServer interface {
-	Send(*RateUpdate) error
-	Context() context.Context
-}
-
-// Proto message types (placeholders - will be replaced by generated code)
-
-type GetRateRequest struct {
-	SourceCurrency string
-	TargetCurrency string
-}
-
-type GetRateResponse struct {
-	Rate  *ExchangeRate
-	Error *Error
-}
-
-type LockRateRequest struct {
-	SourceCurrency      string
-	TargetCurrency      string
-	LockDurationSeconds int32
-}
-
-type LockRateResponse struct {
-	LockedRate *LockedRate
-	Error      *Error
-}
-
-type GetLockedRateRequest struct {
-	LockId string
-}
-
-type GetLockedRateResponse struct {
-	LockedRate *LockedRate
-	Error      *Error
-}
-
-type GetCorridorsRequest struct {
-	SourceCurrency string
-}
-
-type GetCorridorsResponse struct {
-	Corridors []*Corridor
-	Error     *Error
-}
-
-type StreamRatesRequest struct {
-	CurrencyPairs []string
-}
-
-type RateUpdate struct {
-	Rate *ExchangeRate
-}
-
-type ExchangeRate struct {
-	SourceCurrency   string
-	TargetCurrency   string
-	Rate             string
-	BuyRate          string
-	MarginPercentage string
-	FetchedAt        *Timestamp
-	ExpiresAt        *Timestamp
-}
-
-type LockedRate struct {
-	LockId    string
-	Rate      *ExchangeRate
-	LockedAt  *Timestamp
-	ExpiresAt *Timestamp
-	Expired   bool
-}
+// ReleaseLockedRate releases a rate lock before it expires. Releasing a lock
+// that is already gone succeeds.
+func (s *ExchangeRateServer) ReleaseLockedRate(ctx context.Context, req *ReleaseLockedRateRequest) (*ReleaseLockedRateResponse, error) {
+	if req.LockId == "" {
+		return &ReleaseLockedRateResponse{
+			Error: &Error{
+				Code:    "INVALID_ARGUMENT",
+				Message: "lock_id is required",
+			},
+		}, nil
+	}
 
-type Corridor struct {
-	SourceCurrency   string
-	TargetCurrency   string
-	Enabled          bool
-	FeePercentage    string
-	FeeMinimum       *Money
-	MarginPercentage string
-	PayoutMethods    []string
-}
+	if err := s.service.DeleteLockedRate(ctx, req.LockId); err != nil {
+		s.logger.Error("Failed to release locked rate",
+			zap.String("lockId", req.LockId),
+			zap.Error(err),
+		)
+		return &ReleaseLockedRateResponse{
+			Error: &Error{
+				Code:    "UNAVAILABLE",
+				Message: err.Error(),
+			},
+		}, nil
+	}
 
-type Money struct {
-	Currency string
-	Amount   string
+	return &ReleaseLockedRateResponse{}, nil
 }
 
-type Timestamp struct {
-	Seconds int64
-	Nanos   int32
-}
+// The types below are generated from proto/exchange.proto into internal/pb
+// (make proto); the server refers to them by their unqualified names.
 
-type Error struct {
-	Code    string
-	Message string
-	Details map[string]string
-}
+type (
+	UnimplementedExchangeRateServiceServer = exchangepb.UnimplementedExchangeRateServiceServer
+	ExchangeRateService_StreamRatesServer  = exchangepb.ExchangeRateService_StreamRatesServer
+)
 
-// RegisterExchangeRateServiceServer registers the server with a gRPC server
-// This is a placeholder - actual registration uses generated code
-func RegisterExchangeRateServiceServer(s interface{}, srv *ExchangeRateServer) {
-	// This will be replaced by actual generated registration code
-	fmt.Printf("Registered ExchangeRateServiceServer: %v\n", srv)
-}
+// Proto message types
+
+type (
+	GetRateRequest            = exchangepb.GetRateRequest
+	GetRateResponse           = exchangepb.GetRateResponse
+	GetQuoteRequest           = exchangepb.GetQuoteRequest
+	GetQuoteResponse          = exchangepb.GetQuoteResponse
+	LockRateRequest           = exchangepb.LockRateRequest
+	LockRateResponse          = exchangepb.LockRateResponse
+	GetLockedRateRequest      = exchangepb.GetLockedRateRequest
+	GetLockedRateResponse     = exchangepb.GetLockedRateResponse
+	ExtendLockedRateRequest   = exchangepb.ExtendLockedRateRequest
+	ExtendLockedRateResponse  = exchangepb.ExtendLockedRateResponse
+	ExtendLockedRatesRequest  = exchangepb.ExtendLockedRatesRequest
+	ExtendLockedRatesResponse = exchangepb.ExtendLockedRatesResponse
+	ReleaseLockedRateRequest  = exchangepb.ReleaseLockedRateRequest
+	ReleaseLockedRateResponse = exchangepb.ReleaseLockedRateResponse
+	LockExtensionResult       = exchangepb.LockExtensionResult
+	GetCorridorsRequest       = exchangepb.GetCorridorsRequest
+	GetCorridorsResponse      = exchangepb.GetCorridorsResponse
+	StreamRatesRequest        = exchangepb.StreamRatesRequest
+	RateUpdate                = exchangepb.RateUpdate
+	ExchangeRate              = exchangepb.ExchangeRate
+	Quote                     = exchangepb.Quote
+	LockedRate                = exchangepb.LockedRate
+	Corridor                  = exchangepb.Corridor
+
+	Money     = commonpb.Money
+	Timestamp = commonpb.Timestamp
+	Error     = commonpb.Error
+)
@@ -0,0 +1,526 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockingStream is a StreamRates server stream whose Send blocks until released
+type blockingStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	release chan struct{}
+	sent    chan *RateUpdate
+}
+
+func newBlockingStream(ctx context.Context) *blockingStream {
+	return &blockingStream{
+		ctx:     ctx,
+		release: make(chan struct{}),
+		sent:    make(chan *RateUpdate, 100),
+	}
+}
+
+func (s *blockingStream) Send(update *RateUpdate) error {
+	select {
+	case <-s.release:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	s.sent <- update
+	return nil
+}
+
+func (s *blockingStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamRates_RejectsTooManyPairs(t *testing.T) {
+	server := NewExchangeRateServer(&config.Config{StreamMaxPairs: 2}, nil, zap.NewNop())
+
+	err := server.StreamRates(&StreamRatesRequest{
+		CurrencyPairs: []string{"SGD:PHP", "SGD:INR", "SGD:IDR"},
+	}, newBlockingStream(context.Background()))
+
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
+
+func TestStreamRates_EndsOnShutdown(t *testing.T) {
+	server := newStreamTestServer(t)
+
+	stream := newBlockingStream(context.Background())
+	close(stream.release)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- server.StreamRates(&StreamRatesRequest{CurrencyPairs: []string{"SGD:PHP"}}, stream)
+	}()
+
+	select {
+	case <-stream.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected the initial rate update")
+	}
+
+	server.Shutdown()
+
+	select {
+	case err := <-result:
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("expected Unavailable, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to end promptly on shutdown")
+	}
+
+	// Streams opened after shutdown end immediately
+	err := server.StreamRates(&StreamRatesRequest{CurrencyPairs: []string{"SGD:PHP"}}, stream)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable for a new stream, got %v", err)
+	}
+}
+
+func TestStreamRates_WaitsForSendInFlight(t *testing.T) {
+	server := newStreamTestServer(t)
+	stream := newBlockingStream(context.Background())
+
+	result := make(chan error, 1)
+	go func() {
+		result <- server.StreamRates(&StreamRatesRequest{CurrencyPairs: []string{"SGD:PHP"}}, stream)
+	}()
+
+	// Let the initial update reach the blocked Send, then end the stream
+	time.Sleep(50 * time.Millisecond)
+	server.Shutdown()
+
+	select {
+	case <-result:
+		t.Fatal("expected the stream not to return while a send is in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(stream.release)
+	select {
+	case <-result:
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to return once the send finished")
+	}
+	if len(stream.sent) != 1 {
+		t.Errorf("expected only the update in flight to be sent, got %d", len(stream.sent))
+	}
+}
+
+func newStreamTestServer(t *testing.T) *ExchangeRateServer {
+	t.Helper()
+	server, _ := newStreamTestServerWithProvider(t, provider.DefaultSimulatedConfig())
+	return server
+}
+
+func newStreamTestServerWithProvider(t *testing.T, providerCfg provider.SimulatedProviderConfig) (*ExchangeRateServer, *provider.SimulatedProvider) {
+	t.Helper()
+	// Without Redis the rate cache misses and rates come from the provider
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0", MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	cfg := &config.Config{StreamSendBuffer: 10}
+	rateProvider := provider.NewSimulatedProvider(providerCfg)
+	svc := service.NewRateService(cfg, rateProvider, repository.NewRedisRepository(client), zap.NewNop())
+	return NewExchangeRateServer(cfg, svc, zap.NewNop()), rateProvider
+}
+
+func TestStreamRates_IntervalWithoutInitialSnapshot(t *testing.T) {
+	server := newStreamTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := newBlockingStream(ctx)
+	close(stream.release)
+
+	noSnapshot := false
+	start := time.Now()
+	go server.StreamRates(&StreamRatesRequest{
+		CurrencyPairs:          []string{"SGD:PHP"},
+		IntervalSeconds:        1,
+		IncludeInitialSnapshot: &noSnapshot,
+	}, stream)
+
+	// No snapshot, so the first update waits for the first tick
+	var arrivals []time.Duration
+	for len(arrivals) < 2 {
+		select {
+		case <-stream.sent:
+			arrivals = append(arrivals, time.Since(start))
+		case <-time.After(3 * time.Second):
+			t.Fatalf("expected an update every second, got %v", arrivals)
+		}
+	}
+	if arrivals[0] < 900*time.Millisecond || arrivals[0] > 1500*time.Millisecond {
+		t.Errorf("expected the first update after about 1s, got %v", arrivals[0])
+	}
+	if gap := arrivals[1] - arrivals[0]; gap < 900*time.Millisecond || gap > 1500*time.Millisecond {
+		t.Errorf("expected updates about 1s apart, got %v", gap)
+	}
+}
+
+func TestStreamRates_OnlyChanges(t *testing.T) {
+	providerCfg := provider.DefaultSimulatedConfig()
+	providerCfg.MaxDrift = 0
+	providerCfg.DriftInterval = time.Hour
+	server, rateProvider := newStreamTestServerWithProvider(t, providerCfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := newBlockingStream(ctx)
+	close(stream.release)
+	go server.StreamRates(&StreamRatesRequest{
+		CurrencyPairs:   []string{"SGD:PHP"},
+		IntervalSeconds: 1,
+		OnlyChanges:     true,
+	}, stream)
+
+	var initial *RateUpdate
+	select {
+	case initial = <-stream.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected the initial snapshot")
+	}
+
+	// With zero drift the rate never moves, so nothing follows the snapshot
+	select {
+	case update := <-stream.sent:
+		t.Fatalf("expected no update for an unchanged rate, got %+v", update.Rate)
+	case <-time.After(2500 * time.Millisecond):
+	}
+
+	rateProvider.SetDrift("SGD", "PHP", 0.01)
+	select {
+	case update := <-stream.sent:
+		previous, _ := strconv.ParseFloat(initial.Rate.Rate, 64)
+		if math.Abs(update.PreviousRate-previous) > 1e-6 || update.Change <= 0 || math.Abs(update.ChangePercent-1) > 1e-6 {
+			t.Errorf("expected a 1%% change from %f, got %+v", previous, update)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected an update once the rate moved")
+	}
+}
+
+func TestStreamRates_RejectsIntervalOutOfRange(t *testing.T) {
+	server := NewExchangeRateServer(&config.Config{}, nil, zap.NewNop())
+
+	for _, interval := range []int32{-1, 61} {
+		err := server.StreamRates(&StreamRatesRequest{
+			CurrencyPairs:   []string{"SGD:PHP"},
+			IntervalSeconds: interval,
+		}, newBlockingStream(context.Background()))
+
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("interval %d: expected InvalidArgument, got %v", interval, err)
+		}
+	}
+}
+
+func TestStreamSender_SlowConsumerDropsOldest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := newBlockingStream(ctx)
+	sender := newStreamSender(stream, 2, time.Minute)
+	go sender.run(ctx)
+
+	// The first update is picked up by the send loop and blocks there;
+	// the rest overflow the two-slot buffer without blocking the producer
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			if err := sender.enqueue("SGD/PHP", float64(i), &RateUpdate{Rate: &ExchangeRate{Rate: string(rune('0' + i))}}); err != nil {
+				t.Errorf("unexpected enqueue error: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enqueue blocked on a slow consumer")
+	}
+
+	if sender.Dropped() == 0 {
+		t.Error("expected oldest updates to be dropped")
+	}
+
+	// Once the client catches up it receives the newest update last
+	close(stream.release)
+	var last *RateUpdate
+	timeout := time.After(time.Second)
+	for last == nil || last.Rate.Rate != "9" {
+		select {
+		case last = <-stream.sent:
+		case <-timeout:
+			t.Fatalf("expected newest update to be delivered, last was %v", last)
+		}
+	}
+}
+
+func TestStreamSender_ChangeIsSinceLastDeliveredUpdate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := newBlockingStream(ctx)
+	sender := newStreamSender(stream, 1, time.Minute)
+	go sender.run(ctx)
+
+	// 42.0 blocks in the send loop, then 42.5 is dropped to make room for 43.0
+	if err := sender.enqueue("SGD/PHP", 42.0, &RateUpdate{Rate: &ExchangeRate{Rate: "42.0"}}); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	for deadline := time.Now().Add(time.Second); len(sender.queue) > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	for _, mid := range []float64{42.5, 43.0} {
+		if err := sender.enqueue("SGD/PHP", mid, &RateUpdate{Rate: &ExchangeRate{}}); err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+	}
+	if sender.Dropped() != 1 {
+		t.Fatalf("expected one dropped update, got %d", sender.Dropped())
+	}
+	if _, sent := sender.LastSent("SGD/PHP"); sent {
+		t.Error("expected nothing recorded as sent while the send is blocked")
+	}
+
+	close(stream.release)
+	var updates []*RateUpdate
+	for len(updates) < 2 {
+		select {
+		case update := <-stream.sent:
+			updates = append(updates, update)
+		case <-time.After(time.Second):
+			t.Fatalf("expected two updates delivered, got %d", len(updates))
+		}
+	}
+
+	if updates[0].PreviousRate != 0 || updates[0].Change != 0 {
+		t.Errorf("expected no change on the first update, got %+v", updates[0])
+	}
+	if updates[1].PreviousRate != 42.0 || math.Abs(updates[1].Change-1.0) > 1e-9 {
+		t.Errorf("expected the change from the delivered 42.0, not the dropped 42.5, got %+v", updates[1])
+	}
+}
+
+func TestStreamSender_DisconnectsOnSustainedBackpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := newBlockingStream(ctx)
+	sender := newStreamSender(stream, 1, 20*time.Millisecond)
+	go sender.run(ctx)
+
+	var err error
+	deadline := time.Now().Add(time.Second)
+	for err == nil && time.Now().Before(deadline) {
+		err = sender.enqueue("SGD/PHP", 42.5, &RateUpdate{Rate: &ExchangeRate{}})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted after sustained backpressure, got %v", err)
+	}
+}
+
+func newQuoteTestServer(t *testing.T) *ExchangeRateServer {
+	t.Helper()
+	// Without Redis the rate cache misses and rates come from the provider
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0", MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	cfg := &config.Config{}
+	svc := service.NewRateService(cfg, provider.NewSimulatedProvider(provider.DefaultSimulatedConfig()),
+		repository.NewRedisRepository(client), zap.NewNop())
+	return NewExchangeRateServer(cfg, svc, zap.NewNop())
+}
+
+func TestGetQuote_FeeMinimum(t *testing.T) {
+	server := newQuoteTestServer(t)
+
+	// SGD/PHP charges 0.5% with a 3.00 SGD minimum
+	for amount, wantFee := range map[string]string{"100": "3.00", "1000": "5.00"} {
+		resp, err := server.GetQuote(context.Background(), &GetQuoteRequest{
+			SourceCurrency: "SGD",
+			TargetCurrency: "PHP",
+			SourceAmount:   amount,
+		})
+		if err != nil || resp.Error != nil {
+			t.Fatalf("amount %s: unexpected error: %v %+v", amount, err, resp.Error)
+		}
+		quote := resp.Quote
+		if quote.Fee.Amount != wantFee || quote.Fee.Currency != "SGD" {
+			t.Errorf("amount %s: expected fee %s SGD, got %+v", amount, wantFee, quote.Fee)
+		}
+		if quote.QuoteId == "" || quote.TargetAmount.Currency != "PHP" || quote.ValidUntil.Seconds == 0 {
+			t.Errorf("amount %s: incomplete quote %+v", amount, quote)
+		}
+	}
+
+	resp, _ := server.GetQuote(context.Background(), &GetQuoteRequest{SourceCurrency: "SGD", TargetCurrency: "PHP", SourceAmount: "100"})
+	if resp.Quote.TotalCost.Amount != "103.00" {
+		t.Errorf("expected total cost 103.00, got %s", resp.Quote.TotalCost.Amount)
+	}
+}
+
+func TestGetQuote_InvalidArgument(t *testing.T) {
+	server := newQuoteTestServer(t)
+
+	for _, req := range []*GetQuoteRequest{
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", SourceAmount: "0"},
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", SourceAmount: "-5"},
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", SourceAmount: "abc"},
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", SourceAmount: "NaN"},
+		{SourceCurrency: "", TargetCurrency: "PHP", SourceAmount: "100"},
+		{SourceCurrency: "SGD", TargetCurrency: "", SourceAmount: "100"},
+	} {
+		resp, err := server.GetQuote(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Error == nil || resp.Error.Code != "INVALID_ARGUMENT" {
+			t.Errorf("expected INVALID_ARGUMENT for %+v, got %+v", req, resp.Error)
+		}
+	}
+}
+
+func TestGetRate_UnsupportedCurrency(t *testing.T) {
+	server := newQuoteTestServer(t)
+
+	resp, err := server.GetRate(context.Background(), &GetRateRequest{SourceCurrency: "ZZZ", TargetCurrency: "PHP"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != "UNSUPPORTED_CURRENCY" {
+		t.Errorf("expected UNSUPPORTED_CURRENCY, got %+v", resp.Error)
+	}
+
+	resp, err = server.GetRate(context.Background(), &GetRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP"})
+	if err != nil || resp.Error != nil {
+		t.Errorf("expected a rate for a supported pair, got %v %+v", err, resp.Error)
+	}
+}
+
+func TestGetCorridors_TargetAndMethodFilters(t *testing.T) {
+	server := newQuoteTestServer(t)
+
+	resp, err := server.GetCorridors(context.Background(), &GetCorridorsRequest{TargetCurrency: "PHP", PayoutMethod: "CASH_PICKUP"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Corridors) != 2 {
+		t.Fatalf("expected the 2 cash pickup corridors into PHP, got %d", len(resp.Corridors))
+	}
+	for _, c := range resp.Corridors {
+		if c.TargetCurrency != "PHP" {
+			t.Errorf("expected only PHP corridors, got %s/%s", c.SourceCurrency, c.TargetCurrency)
+		}
+	}
+}
+
+func newLockTestServer(t *testing.T) *ExchangeRateServer {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	cfg := &config.Config{LockDuration: 30, MaxLockDuration: 120}
+	svc := service.NewRateService(cfg, provider.NewSimulatedProvider(provider.DefaultSimulatedConfig()),
+		repository.NewRedisRepository(client), zap.NewNop())
+	return NewExchangeRateServer(cfg, svc, zap.NewNop())
+}
+
+func TestLockRate_ValidatesDurationAndCorridor(t *testing.T) {
+	server := newLockTestServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		req  *LockRateRequest
+	}{
+		{"negative duration", &LockRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP", LockDurationSeconds: -1}},
+		{"duration over max", &LockRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP", LockDurationSeconds: 121}},
+		{"unknown corridor", &LockRateRequest{SourceCurrency: "PHP", TargetCurrency: "SGD", LockDurationSeconds: 30}},
+	}
+	for _, tt := range tests {
+		resp, err := server.LockRate(ctx, tt.req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if resp.Error == nil || resp.Error.Code != "INVALID_ARGUMENT" {
+			t.Errorf("%s: expected INVALID_ARGUMENT, got %+v", tt.name, resp.Error)
+		}
+	}
+
+	// No duration takes the configured default rather than a fixed one
+	resp, err := server.LockRate(ctx, &LockRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP"})
+	if err != nil || resp.Error != nil {
+		t.Fatalf("unexpected error: %v %+v", err, resp.Error)
+	}
+	locked := resp.LockedRate
+	if d := locked.ExpiresAt.Seconds - locked.LockedAt.Seconds; d < 30 || d > 31 {
+		t.Errorf("expected the default 30s lock, got %ds", d)
+	}
+}
+
+func TestReleaseLockedRate(t *testing.T) {
+	server := newLockTestServer(t)
+	ctx := context.Background()
+
+	lockResp, err := server.LockRate(ctx, &LockRateRequest{SourceCurrency: "SGD", TargetCurrency: "PHP", LockDurationSeconds: 30})
+	if err != nil || lockResp.Error != nil {
+		t.Fatalf("unexpected error: %v %+v", err, lockResp.Error)
+	}
+	lockID := lockResp.LockedRate.LockId
+
+	resp, err := server.ReleaseLockedRate(ctx, &ReleaseLockedRateRequest{LockId: lockID})
+	if err != nil || resp.Error != nil {
+		t.Fatalf("unexpected error: %v %+v", err, resp.Error)
+	}
+
+	getResp, err := server.GetLockedRate(ctx, &GetLockedRateRequest{LockId: lockID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getResp.Error == nil && !getResp.LockedRate.Expired {
+		t.Error("expected the released lock to be gone")
+	}
+
+	// Releasing again, or releasing a lock that never existed, still succeeds
+	for _, id := range []string{lockID, "missing"} {
+		resp, err := server.ReleaseLockedRate(ctx, &ReleaseLockedRateRequest{LockId: id})
+		if err != nil || resp.Error != nil {
+			t.Errorf("%s: expected success, got %v %+v", id, err, resp.Error)
+		}
+	}
+}
+
+func TestReleaseLockedRate_RequiresLockID(t *testing.T) {
+	server := newLockTestServer(t)
+
+	resp, err := server.ReleaseLockedRate(context.Background(), &ReleaseLockedRateRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != "INVALID_ARGUMENT" {
+		t.Errorf("expected INVALID_ARGUMENT, got %+v", resp.Error)
+	}
+}
@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamSender decouples producing rate updates from sending them to a client.
+// Updates are queued in a bounded buffer and sent from a separate goroutine, so
+// a slow client never blocks the producer. When the buffer is full the oldest
+// update is dropped; if the client stays blocked for longer than slowTimeout
+// the stream is disconnected. The change each update carries is worked out
+// when it is sent, against the pair's last update the client actually got.
+type streamSender struct {
+	stream      ExchangeRateService_StreamRatesServer
+	queue       chan queuedUpdate
+	slowTimeout time.Duration
+
+	mu           sync.Mutex
+	lastProgress time.Time // last time the send loop picked up or finished an update
+	dropped      int
+	sent         map[string]float64 // mid rate last sent per pair
+
+	done    chan struct{} // closed when a send fails
+	err     error
+	stopped chan struct{} // closed when the send loop returns
+}
+
+// queuedUpdate is an update waiting to be sent, with its pair and mid rate
+type queuedUpdate struct {
+	pair    string
+	midRate float64
+	update  *RateUpdate
+}
+
+// streamSenderStopTimeout bounds how long a finishing stream waits for a send
+// in flight. A send blocked on a client that stopped reading only returns once
+// the stream itself ends, after the handler has returned.
+const streamSenderStopTimeout = 5 * time.Second
+
+func newStreamSender(stream ExchangeRateService_StreamRatesServer, bufferSize int, slowTimeout time.Duration) *streamSender {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &streamSender{
+		stream:       stream,
+		queue:        make(chan queuedUpdate, bufferSize),
+		slowTimeout:  slowTimeout,
+		lastProgress: time.Now(),
+		sent:         make(map[string]float64),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+// run sends queued updates until ctx is cancelled or a send fails
+func (s *streamSender) run(ctx context.Context) {
+	defer close(s.stopped)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case queued := <-s.queue:
+			if ctx.Err() != nil {
+				return
+			}
+			s.markProgress()
+			update := queued.update
+			if previous, sent := s.LastSent(queued.pair); sent {
+				update.PreviousRate = previous
+				update.Change = queued.midRate - previous
+				if previous != 0 {
+					update.ChangePercent = update.Change / previous * 100
+				}
+			}
+			if err := s.stream.Send(update); err != nil {
+				s.err = err
+				close(s.done)
+				return
+			}
+			s.mu.Lock()
+			s.sent[queued.pair] = queued.midRate
+			s.mu.Unlock()
+			s.markProgress()
+		}
+	}
+}
+
+// enqueue queues an update for pair at midRate, dropping the oldest queued
+// update if the buffer is full. It returns an error once the stream has
+// failed or the client has been blocked for longer than the slow client
+// timeout.
+func (s *streamSender) enqueue(pair string, midRate float64, update *RateUpdate) error {
+	queued := queuedUpdate{pair: pair, midRate: midRate, update: update}
+	for {
+		select {
+		case <-s.done:
+			return s.err
+		case s.queue <- queued:
+			return nil
+		default:
+		}
+
+		if s.slowTimeout > 0 && s.sinceProgress() > s.slowTimeout {
+			return status.Error(codes.ResourceExhausted, "client is not consuming rate updates fast enough")
+		}
+
+		// Buffer full: drop the oldest update to make room for the newest
+		select {
+		case <-s.queue:
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+		default:
+		}
+	}
+}
+
+// wait waits up to timeout for the send loop to return after its context has
+// been cancelled, reporting whether it did
+func (s *streamSender) wait(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.stopped:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Done is closed when the send loop stops because of a send error
+func (s *streamSender) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the send error that stopped the send loop
+func (s *streamSender) Err() error {
+	return s.err
+}
+
+// LastSent returns the mid rate of the last update sent for pair, if any
+func (s *streamSender) LastSent(pair string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	midRate, sent := s.sent[pair]
+	return midRate, sent
+}
+
+// Dropped returns the number of updates dropped because the buffer was full
+func (s *streamSender) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *streamSender) markProgress() {
+	s.mu.Lock()
+	s.lastProgress = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *streamSender) sinceProgress() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastProgress)
+}
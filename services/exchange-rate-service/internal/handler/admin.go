@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// requireAdminToken rejects requests that don't carry the configured admin
+// token as a bearer token. Without a configured token the endpoints it
+// guards are disabled.
+func (h *HTTPHandler) requireAdminToken(c *gin.Context) {
+	if h.config.AdminToken == "" {
+		respondError(c, newAPIError(http.StatusForbidden, CodeForbidden, "Admin endpoints are disabled"))
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.config.AdminToken)) != 1 {
+		c.Header("WWW-Authenticate", "Bearer")
+		respondError(c, newAPIError(http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid admin token"))
+		return
+	}
+	c.Next()
+}
+
+// UpdateCorridor overrides a corridor's margin and fee percentages, returning
+// the corridor as it is now priced
+func (h *HTTPHandler) UpdateCorridor(c *gin.Context) {
+	source := provider.NormalizeCurrency(c.Param("source"))
+	target := provider.NormalizeCurrency(c.Param("target"))
+
+	if !validCurrencyPair(c, source, target) {
+		return
+	}
+
+	var req model.UpdateCorridorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+
+	corridor, err := h.rateService.UpdateCorridorPricing(c.Request.Context(), source, target, req)
+	if err != nil {
+		h.logger.Warn("Failed to update corridor",
+			zap.String("source", source),
+			zap.String("target", target),
+			zap.Error(err),
+		)
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, corridor)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+// CreateAlert registers a webhook to be notified when a pair's rate crosses
+// a threshold. Alerts count against the calling client's limit, the client
+// being identified by its IP.
+func (h *HTTPHandler) CreateAlert(c *gin.Context) {
+	var req model.CreateAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+
+	pair := provider.CurrencyPair{
+		Source: provider.NormalizeCurrency(req.SourceCurrency),
+		Target: provider.NormalizeCurrency(req.TargetCurrency),
+	}
+	if !validCurrencyPair(c, pair.Source, pair.Target) {
+		return
+	}
+
+	alert, err := h.rateService.CreateAlert(c.Request.Context(), c.ClientIP(), pair, req.Direction, req.Threshold, req.WebhookURL)
+	if err != nil {
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+// DeleteAlert removes a rate alert
+func (h *HTTPHandler) DeleteAlert(c *gin.Context) {
+	if err := h.rateService.DeleteAlert(c.Param("alertId")); err != nil {
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Deprecated response fields are marked with a `deprecated` struct tag holding
+// the migration hint, e.g. `json:"rate" deprecated:"use midRate instead"`.
+
+// deprecationWarnings returns a warning for each deprecated field explicitly
+// requested via ?fields=. Full responses always include deprecated fields for
+// compatibility, so only an explicit request shows the client depends on one.
+func deprecationWarnings(value interface{}, fieldsParam string) []string {
+	if strings.TrimSpace(fieldsParam) == "" {
+		return nil
+	}
+
+	deprecated := deprecatedFields(reflect.TypeOf(value))
+	if len(deprecated) == 0 {
+		return nil
+	}
+
+	var warnings []string
+	for _, field := range strings.Split(fieldsParam, ",") {
+		field = strings.TrimSpace(field)
+		if hint, ok := deprecated[field]; ok {
+			warnings = append(warnings, fmt.Sprintf("field %q is deprecated: %s", field, hint))
+		}
+	}
+	return warnings
+}
+
+// deprecatedFields maps the JSON names of deprecated struct fields to their hint
+func deprecatedFields(t reflect.Type) map[string]string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	fields := make(map[string]string)
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		hint, ok := field.Tag.Lookup("deprecated")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = hint
+	}
+	return fields
+}
+
+// warningHeader formats a warning as an RFC 7234 Warning header value
+func warningHeader(warning string) string {
+	return fmt.Sprintf("299 - %q", warning)
+}
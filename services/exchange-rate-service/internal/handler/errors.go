@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
+)
+
+// Error codes returned in error responses. They are stable, so clients can
+// switch on them instead of on messages.
+const (
+	CodeInvalidRequest          = "INVALID_REQUEST"
+	CodeInvalidCurrency         = "INVALID_CURRENCY"
+	CodeUnsupportedCurrency     = "UNSUPPORTED_CURRENCY"
+	CodeUnsupportedPair         = "UNSUPPORTED_PAIR"
+	CodeInvalidAmount           = "INVALID_AMOUNT"
+	CodeInvalidDuration         = "INVALID_DURATION"
+	CodeInvalidTimeRange        = "INVALID_TIME_RANGE"
+	CodeInvalidFields           = "INVALID_FIELDS"
+	CodeInvalidPricing          = "INVALID_PRICING"
+	CodeCorridorNotFound        = "CORRIDOR_NOT_FOUND"
+	CodeCorridorDisabled        = "CORRIDOR_DISABLED"
+	CodeAmountOutOfRange        = "AMOUNT_OUT_OF_RANGE"
+	CodeBelowMinTargetAmount    = "BELOW_MIN_TARGET_AMOUNT"
+	CodeAmountTooLarge          = "AMOUNT_TOO_LARGE"
+	CodeReverseQuoteUnstable    = "REVERSE_QUOTE_UNSTABLE"
+	CodeQuoteExpired            = "QUOTE_EXPIRED"
+	CodeQuoteNotFound           = "QUOTE_NOT_FOUND"
+	CodeLockNotFound            = "LOCK_NOT_FOUND"
+	CodeLockExpired             = "LOCK_EXPIRED"
+	CodeMaxLockDurationExceeded = "MAX_LOCK_DURATION_EXCEEDED"
+	CodeLockLimitExceeded       = "LOCK_LIMIT_EXCEEDED"
+	CodeIdempotencyKeyConflict  = "IDEMPOTENCY_KEY_CONFLICT"
+	CodeRequestInProgress       = "REQUEST_IN_PROGRESS"
+	CodeSnapshotNotFound        = "SNAPSHOT_NOT_FOUND"
+	CodeInvalidAlert            = "INVALID_ALERT"
+	CodeAlertNotFound           = "ALERT_NOT_FOUND"
+	CodeAlertLimitExceeded      = "ALERT_LIMIT_EXCEEDED"
+	CodeProviderUnavailable     = "PROVIDER_UNAVAILABLE"
+	CodeStorageUnavailable      = "STORAGE_UNAVAILABLE"
+	CodeNotReady                = "NOT_READY"
+	CodeUnauthorized            = "UNAUTHORIZED"
+	CodeForbidden               = "FORBIDDEN"
+	CodeInternal                = "INTERNAL_ERROR"
+)
+
+// APIError is an error response. Every error is written as
+// {"error":{"code":...,"message":...}}, with details such as the allowed
+// amount range when the code has them.
+type APIError struct {
+	Code       string            `json:"code"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+	HTTPStatus int               `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// newAPIError creates an error response with the given status and code
+func newAPIError(status int, code, message string) *APIError {
+	return &APIError{Code: code, Message: message, HTTPStatus: status}
+}
+
+// respondError aborts the request with err as the response
+func respondError(c *gin.Context, err *APIError) {
+	c.AbortWithStatusJSON(err.HTTPStatus, gin.H{"error": err})
+}
+
+// mapServiceError maps an error from the rate service to its API error.
+// Provider and store errors may arrive wrapped. Errors without a specific
+// code are reported as internal errors, or as unavailable storage when a
+// store write was rejected (e.g. Redis out of memory) so clients retry later.
+func mapServiceError(err error) *APIError {
+	switch e := err.(type) {
+	case service.ErrCorridorNotFound:
+		return newAPIError(http.StatusNotFound, CodeCorridorNotFound, e.Error())
+	case service.ErrCorridorDisabled:
+		return newAPIError(http.StatusBadRequest, CodeCorridorDisabled, e.Error())
+	case service.ErrAmountOutOfRange:
+		apiErr := newAPIError(http.StatusBadRequest, CodeAmountOutOfRange, e.Error())
+		apiErr.Details = map[string]string{"minAmount": e.MinimumAmount, "maxAmount": e.MaximumAmount}
+		return apiErr
+	case service.ErrBelowMinTargetAmount:
+		apiErr := newAPIError(http.StatusBadRequest, CodeBelowMinTargetAmount, e.Error())
+		apiErr.Details = map[string]string{"minTargetAmount": e.MinimumAmount}
+		return apiErr
+	case service.ErrQuoteAmountTooLarge:
+		return newAPIError(http.StatusBadRequest, CodeAmountTooLarge, e.Error())
+	case service.ErrReverseQuoteUnstable:
+		return newAPIError(http.StatusBadRequest, CodeReverseQuoteUnstable, e.Error())
+	case service.ErrQuoteExpired:
+		return newAPIError(http.StatusGone, CodeQuoteExpired, "Quote expired")
+	case service.ErrQuoteNotFound:
+		return newAPIError(http.StatusNotFound, CodeQuoteNotFound, "Quote not found")
+	case service.ErrInvalidCorridorPricing:
+		return newAPIError(http.StatusBadRequest, CodeInvalidPricing, e.Error())
+	case service.ErrLockDurationExceeded:
+		return newAPIError(http.StatusUnprocessableEntity, CodeMaxLockDurationExceeded, e.Error())
+	case service.ErrLockLimitExceeded:
+		return newAPIError(http.StatusTooManyRequests, CodeLockLimitExceeded, e.Error())
+	case service.ErrIdempotencyKeyConflict:
+		return newAPIError(http.StatusUnprocessableEntity, CodeIdempotencyKeyConflict, e.Error())
+	case service.ErrIdempotentRequestInProgress:
+		return newAPIError(http.StatusConflict, CodeRequestInProgress, e.Error())
+	case service.ErrInvalidAlert:
+		return newAPIError(http.StatusBadRequest, CodeInvalidAlert, e.Error())
+	case service.ErrAlertNotFound:
+		return newAPIError(http.StatusNotFound, CodeAlertNotFound, "Rate alert not found")
+	case service.ErrAlertLimitExceeded:
+		return newAPIError(http.StatusTooManyRequests, CodeAlertLimitExceeded, e.Error())
+	case repository.ErrNotFound:
+		return newAPIError(http.StatusNotFound, CodeLockNotFound, "Rate lock not found")
+	case repository.ErrExpired:
+		return newAPIError(http.StatusGone, CodeLockExpired, "Rate lock expired")
+	}
+
+	var unsupported provider.ErrUnsupportedPair
+	if errors.As(err, &unsupported) {
+		return newAPIError(http.StatusNotFound, CodeUnsupportedPair, unsupported.Error())
+	}
+	var unavailable provider.ErrProviderUnavailable
+	if errors.As(err, &unavailable) {
+		return newAPIError(http.StatusServiceUnavailable, CodeProviderUnavailable, err.Error())
+	}
+	var writeErr repository.ErrWriteFailed
+	if errors.As(err, &writeErr) {
+		return newAPIError(http.StatusServiceUnavailable, CodeStorageUnavailable, err.Error())
+	}
+	return newAPIError(http.StatusInternalServerError, CodeInternal, err.Error())
+}
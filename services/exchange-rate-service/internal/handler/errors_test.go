@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
+)
+
+func TestMapServiceError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"unsupported pair", provider.ErrUnsupportedPair{Source: "SGD", Target: "XXX"}, http.StatusNotFound, CodeUnsupportedPair},
+		{"wrapped unsupported pair", fmt.Errorf("failed to get rate for SGD/XXX: %w", provider.ErrUnsupportedPair{Source: "SGD", Target: "XXX"}), http.StatusNotFound, CodeUnsupportedPair},
+		{"provider unavailable", fmt.Errorf("failed to get rate for SGD/PHP: %w", provider.ErrProviderUnavailable{Provider: "simulated", Reason: "down"}), http.StatusServiceUnavailable, CodeProviderUnavailable},
+		{"corridor not found", service.ErrCorridorNotFound{SourceCurrency: "SGD", TargetCurrency: "EUR"}, http.StatusNotFound, CodeCorridorNotFound},
+		{"corridor disabled", service.ErrCorridorDisabled{SourceCurrency: "SGD", TargetCurrency: "IDR"}, http.StatusBadRequest, CodeCorridorDisabled},
+		{"amount out of range", service.ErrAmountOutOfRange{SourceCurrency: "SGD", TargetCurrency: "PHP", MinimumAmount: "1.00"}, http.StatusBadRequest, CodeAmountOutOfRange},
+		{"below min target amount", service.ErrBelowMinTargetAmount{SourceCurrency: "SGD", TargetCurrency: "PHP", MinimumAmount: "50.00"}, http.StatusBadRequest, CodeBelowMinTargetAmount},
+		{"amount too large", service.ErrQuoteAmountTooLarge{SourceCurrency: "SGD"}, http.StatusBadRequest, CodeAmountTooLarge},
+		{"reverse quote unstable", service.ErrReverseQuoteUnstable{SourceCurrency: "SGD", TargetCurrency: "PHP"}, http.StatusBadRequest, CodeReverseQuoteUnstable},
+		{"quote expired", service.ErrQuoteExpired{QuoteID: "q1"}, http.StatusGone, CodeQuoteExpired},
+		{"quote not found", service.ErrQuoteNotFound{QuoteID: "q1"}, http.StatusNotFound, CodeQuoteNotFound},
+		{"lock not found", repository.ErrNotFound{Key: "lock1"}, http.StatusNotFound, CodeLockNotFound},
+		{"lock expired", repository.ErrExpired{LockID: "lock1"}, http.StatusGone, CodeLockExpired},
+		{"lock duration exceeded", service.ErrLockDurationExceeded{LockID: "lock1", MaxSeconds: 120}, http.StatusUnprocessableEntity, CodeMaxLockDurationExceeded},
+		{"store write rejected", fmt.Errorf("failed to lock rate: %w", repository.ErrWriteFailed{Operation: "save lock", Kind: repository.WriteFailureOOM}), http.StatusServiceUnavailable, CodeStorageUnavailable},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := mapServiceError(tt.err)
+			if apiErr.HTTPStatus != tt.status || apiErr.Code != tt.code {
+				t.Errorf("expected %d %s, got %d %s", tt.status, tt.code, apiErr.HTTPStatus, apiErr.Code)
+			}
+			if apiErr.Message == "" {
+				t.Error("expected a message")
+			}
+		})
+	}
+}
+
+func TestErrorResponseShape(t *testing.T) {
+	router, _ := newTestRouter()
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/rates/SG/PHP", http.StatusBadRequest, CodeInvalidCurrency},
+		{"/api/quote?from=SGD&to=PHP&amount=abc", http.StatusBadRequest, CodeInvalidAmount},
+		{"/api/quote?from=SGD&to=PHP", http.StatusBadRequest, CodeInvalidRequest},
+		{"/api/rates/locked/unknown", http.StatusGone, CodeLockExpired},
+		{"/api/quote/unknown", http.StatusNotFound, CodeQuoteNotFound},
+	}
+
+	for _, tt := range tests {
+		w := performRequest(router, http.MethodGet, tt.path)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d: %s", tt.path, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		body := decodeBody(t, w)
+		apiErr, _ := body["error"].(map[string]interface{})
+		if len(body) != 1 || apiErr["code"] != tt.code || apiErr["message"] == "" {
+			t.Errorf("%s: expected {\"error\":{\"code\":%q,...}}, got %s", tt.path, tt.code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// selectFields filters a JSON response down to the comma-separated fields
+// requested via ?fields=, so clients can minimize payload size. Field names
+// are validated against the response type's JSON field names. An empty
+// fields parameter returns the value unchanged.
+func selectFields(value interface{}, fieldsParam string) (interface{}, error) {
+	if strings.TrimSpace(fieldsParam) == "" {
+		return value, nil
+	}
+
+	allowed := jsonFieldNames(reflect.TypeOf(value))
+	requested := make([]string, 0)
+	for _, field := range strings.Split(fieldsParam, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !allowed[field] {
+			return nil, fmt.Errorf("unknown field: %s", field)
+		}
+		requested = append(requested, field)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string]json.RawMessage, len(requested))
+	for _, field := range requested {
+		if raw, ok := full[field]; ok {
+			filtered[field] = raw
+		}
+	}
+	return filtered, nil
+}
+
+// jsonFieldNames returns the JSON field names of a struct type
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	names := make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
@@ -1,29 +1,45 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/metrics"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
 	"go.uber.org/zap"
 )
 
 // HTTPHandler handles HTTP requests
 type HTTPHandler struct {
+	config      *config.Config
 	rateService *service.RateService
 	logger      *zap.Logger
+	metrics     *metrics.Metrics
 }
 
 // NewHTTPHandler creates a new HTTPHandler
-func NewHTTPHandler(rateService *service.RateService, logger *zap.Logger) *HTTPHandler {
+func NewHTTPHandler(cfg *config.Config, rateService *service.RateService, logger *zap.Logger) *HTTPHandler {
 	return &HTTPHandler{
+		config:      cfg,
 		rateService: rateService,
 		logger:      logger,
 	}
 }
 
+// SetMetrics records rate requests to m
+func (h *HTTPHandler) SetMetrics(m *metrics.Metrics) {
+	h.metrics = m
+}
+
 // SetupRoutes configures the HTTP routes
 func (h *HTTPHandler) SetupRoutes(r *gin.Engine) {
 	// Health check
@@ -35,12 +51,32 @@ func (h *HTTPHandler) SetupRoutes(r *gin.Engine) {
 	{
 		rates := api.Group("/rates")
 		{
+			rates.GET("/stream", h.StreamRates)
 			rates.GET("/:from/:to", h.GetRate)
+			rates.GET("/:from/:to/history", h.GetRateHistory)
+			rates.POST("/batch", h.GetRates)
 			rates.POST("/lock", h.LockRate)
+			rates.POST("/lock/batch", h.LockRates)
 			rates.GET("/locked/:lockId", h.GetLockedRate)
+			rates.DELETE("/locked/:lockId", h.ReleaseLockedRate)
+			rates.GET("/locked/:lockId/quote", h.GetLockedQuote)
+			rates.GET("/locked/:lockId/verify", h.VerifyLockedQuote)
+			rates.POST("/locked/extend", h.ExtendLockedRates)
+			rates.POST("/locked/:lockId/extend", h.ExtendLockedRate)
 		}
 		api.GET("/corridors", h.GetCorridors)
 		api.GET("/quote", h.GetQuote)
+		api.GET("/quote/:quoteId", h.GetQuoteByID)
+		api.POST("/alerts", h.requireAdminToken, h.CreateAlert)
+		api.DELETE("/alerts/:alertId", h.requireAdminToken, h.DeleteAlert)
+
+		admin := api.Group("/admin")
+		admin.Use(h.requireAdminToken)
+		{
+			admin.GET("/rates/:from/:to/compare", h.CompareProviders)
+			admin.GET("/locks/:lockId/snapshot", h.GetRateSnapshot)
+			admin.PUT("/corridors/:source/:target", h.UpdateCorridor)
+		}
 	}
 }
 
@@ -55,53 +91,341 @@ func (h *HTTPHandler) Health(c *gin.Context) {
 // Ready returns the readiness status
 func (h *HTTPHandler) Ready(c *gin.Context) {
 	// Check if service dependencies are healthy
-	if err := h.rateService.Health(c.Request.Context()); err != nil {
+	report := h.rateService.HealthReport(c.Request.Context())
+
+	switch report.State {
+	case model.HealthStateUnhealthy:
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"status":  "not ready",
 			"service": "exchange-rate-service",
-			"error":   err.Error(),
+			"error":   newAPIError(http.StatusServiceUnavailable, CodeNotReady, report.Error),
+			"health":  report,
+		})
+	case model.HealthStateDegraded:
+		// Degraded instances fail readiness so load balancers shed traffic
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "degraded",
+			"service": "exchange-rate-service",
+			"health":  report,
+		})
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ready",
+			"service": "exchange-rate-service",
+			"health":  report,
 		})
-		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "ready",
-		"service": "exchange-rate-service",
-	})
 }
 
 // GetRate retrieves the current exchange rate
 func (h *HTTPHandler) GetRate(c *gin.Context) {
-	from := c.Param("from")
-	to := c.Param("to")
+	from := provider.NormalizeCurrency(c.Param("from"))
+	to := provider.NormalizeCurrency(c.Param("to"))
 
-	if len(from) != 3 || len(to) != 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency code format"})
+	if !validCurrencyPair(c, from, to) {
 		return
 	}
 
-	rate, err := h.rateService.GetRate(c.Request.Context(), from, to)
+	start := time.Now()
+	rate, cacheHit, err := h.rateService.LookupRate(c.Request.Context(), from, to)
 	if err != nil {
+		h.recordRateRequest(from, to, "error", start, false)
 		h.logger.Error("Failed to get rate", zap.Error(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, mapServiceError(err))
+		return
+	}
+	h.recordRateRequest(from, to, "success", start, cacheHit)
+
+	h.respondWithFields(c, rate)
+}
+
+// GetRateHistory returns the rates recorded for a pair between the RFC 3339
+// "from" and "to" query times, defaulting to the last 24 hours
+func (h *HTTPHandler) GetRateHistory(c *gin.Context) {
+	from := provider.NormalizeCurrency(c.Param("from"))
+	to := provider.NormalizeCurrency(c.Param("to"))
+
+	if !validCurrencyPair(c, from, to) {
+		return
+	}
+
+	end := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidTimeRange, "Invalid 'to' time, expected RFC 3339"))
+			return
+		}
+		end = t
+	}
+	start := end.Add(-24 * time.Hour)
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidTimeRange, "Invalid 'from' time, expected RFC 3339"))
+			return
+		}
+		start = t
+	}
+	if start.After(end) {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidTimeRange, "'from' must not be after 'to'"))
+		return
+	}
+
+	history, err := h.rateService.GetRateHistory(c.Request.Context(), from, to, start, end)
+	if err != nil {
+		h.logger.Error("Failed to get rate history", zap.String("from", from), zap.String("to", to), zap.Error(err))
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// recordRateRequest records a rate lookup that began at start
+func (h *HTTPHandler) recordRateRequest(from, to, status string, start time.Time, cacheHit bool) {
+	if h.metrics == nil {
+		return
+	}
+	h.metrics.RecordRateRequest(from, to, status, time.Since(start).Seconds(), cacheHit)
+}
+
+// GetRates returns the rates for several pairs. Repeated pairs are looked up
+// once, and invalid or unsupported pairs are reported per pair rather than
+// failing the whole request.
+func (h *HTTPHandler) GetRates(c *gin.Context) {
+	var req model.BatchRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Pairs) == 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
 		return
 	}
 
-	c.JSON(http.StatusOK, rate)
+	var results []model.BatchRateResult
+	var pairs []provider.CurrencyPair
+	seen := make(map[model.BatchRatePair]bool, len(req.Pairs))
+	for _, pair := range req.Pairs {
+		pair.Source, pair.Target = provider.NormalizeCurrency(pair.Source), provider.NormalizeCurrency(pair.Target)
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+
+		result := model.BatchRateResult{SourceCurrency: pair.Source, TargetCurrency: pair.Target}
+		if len(pair.Source) != 3 || len(pair.Target) != 3 {
+			result.Error = "Invalid currency code format"
+		} else if unsupported := unsupportedCurrency(pair.Source, pair.Target); unsupported != "" {
+			result.Error = "Unsupported currency: " + unsupported
+		} else {
+			pairs = append(pairs, provider.CurrencyPair{Source: pair.Source, Target: pair.Target})
+		}
+		results = append(results, result)
+	}
+
+	if len(pairs) > 0 {
+		rates := h.rateService.GetRates(c.Request.Context(), pairs)
+
+		byPair := make(map[provider.CurrencyPair]*model.ExchangeRate, len(rates.Rates))
+		for _, rate := range rates.Rates {
+			byPair[provider.CurrencyPair{Source: rate.SourceCurrency, Target: rate.TargetCurrency}] = rate
+		}
+		failures := make(map[provider.CurrencyPair]string, len(rates.Errors))
+		for _, failure := range rates.Errors {
+			failures[provider.CurrencyPair{Source: failure.SourceCurrency, Target: failure.TargetCurrency}] = failure.Error
+		}
+		for i := range results {
+			if results[i].Error != "" {
+				continue
+			}
+			pair := provider.CurrencyPair{Source: results[i].SourceCurrency, Target: results[i].TargetCurrency}
+			if rate, ok := byPair[pair]; ok {
+				results[i].Rate = rate
+			} else {
+				results[i].Error = failures[pair]
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// CompareProviders returns each configured provider's rate for a pair
+func (h *HTTPHandler) CompareProviders(c *gin.Context) {
+	from := provider.NormalizeCurrency(c.Param("from"))
+	to := provider.NormalizeCurrency(c.Param("to"))
+
+	if !validCurrencyPair(c, from, to) {
+		return
+	}
+
+	comparison := h.rateService.CompareProviders(c.Request.Context(), from, to)
+	c.JSON(http.StatusOK, comparison)
+}
+
+// GetRateSnapshot returns the market conditions a rate was locked at, for
+// resolving rate disputes after the lock has expired
+func (h *HTTPHandler) GetRateSnapshot(c *gin.Context) {
+	lockID := c.Param("lockId")
+
+	snapshot, err := h.rateService.GetRateSnapshot(c.Request.Context(), lockID)
+	if err != nil {
+		if _, ok := err.(repository.ErrNotFound); ok {
+			respondError(c, newAPIError(http.StatusNotFound, CodeSnapshotNotFound, "Rate snapshot not found"))
+			return
+		}
+		h.logger.Error("Failed to get rate snapshot", zap.String("lockId", lockID), zap.Error(err))
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
 }
 
 // LockRate locks a rate for a transfer
 func (h *HTTPHandler) LockRate(c *gin.Context) {
 	var req model.RateLockRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+
+	if !h.validLockDuration(c, req.DurationSeconds) {
+		return
+	}
+
+	if req.QuoteID != "" {
+		h.lockQuotedRate(c, &req)
+		return
+	}
+
+	if req.SourceCurrency == "" || req.TargetCurrency == "" {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "sourceCurrency and targetCurrency, or quoteId, are required"))
+		return
+	}
+	req.SourceCurrency = provider.NormalizeCurrency(req.SourceCurrency)
+	req.TargetCurrency = provider.NormalizeCurrency(req.TargetCurrency)
+	if !h.validLockPair(c, req.SourceCurrency, req.TargetCurrency) {
 		return
 	}
 
-	locked, err := h.rateService.LockRate(c.Request.Context(), req.SourceCurrency, req.TargetCurrency, req.DurationSeconds)
+	locked, err := h.rateService.LockRateIdempotent(c.Request.Context(), req.IdempotencyKey, req.SourceCurrency, req.TargetCurrency, req.DurationSeconds)
 	if err != nil {
 		h.logger.Error("Failed to lock rate", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, locked)
+}
+
+// LockRates locks the rates of several pairs at once. If any pair can't be
+// locked none are, and the error names the pair that failed.
+func (h *HTTPHandler) LockRates(c *gin.Context) {
+	var req model.BatchLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Pairs) == 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+
+	reqs := make([]service.LockRequest, len(req.Pairs))
+	for i, pair := range req.Pairs {
+		source, target := provider.NormalizeCurrency(pair.SourceCurrency), provider.NormalizeCurrency(pair.TargetCurrency)
+		if !h.validLockDuration(c, pair.DurationSeconds) || !h.validLockPair(c, source, target) {
+			return
+		}
+		reqs[i] = service.LockRequest{
+			SourceCurrency:  source,
+			TargetCurrency:  target,
+			DurationSeconds: pair.DurationSeconds,
+		}
+	}
+
+	locks, err := h.rateService.LockRates(c.Request.Context(), reqs)
+	if err != nil {
+		var batchErr service.ErrBatchLockFailed
+		if !errors.As(err, &batchErr) {
+			h.logger.Error("Failed to lock rates", zap.Int("pairs", len(reqs)), zap.Error(err))
+			respondError(c, mapServiceError(err))
+			return
+		}
+		apiErr := mapServiceError(batchErr.Err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to lock rates", zap.Int("pairs", len(reqs)), zap.Error(err))
+		}
+		apiErr.Message = batchErr.Error()
+		if apiErr.Details == nil {
+			apiErr.Details = make(map[string]string)
+		}
+		apiErr.Details["sourceCurrency"] = batchErr.SourceCurrency
+		apiErr.Details["targetCurrency"] = batchErr.TargetCurrency
+		respondError(c, apiErr)
+		return
+	}
+
+	results := make([]model.BatchLockResult, len(locks))
+	for i, locked := range locks {
+		results[i] = model.BatchLockResult{
+			SourceCurrency: locked.Rate.SourceCurrency,
+			TargetCurrency: locked.Rate.TargetCurrency,
+			Lock:           locked,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// validLockDuration responds with 400 unless durationSeconds is between 0,
+// for the default duration, and MaxLockDuration
+func (h *HTTPHandler) validLockDuration(c *gin.Context, durationSeconds int) bool {
+	if durationSeconds < 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidDuration, "durationSeconds must not be negative"))
+		return false
+	}
+	if max := h.config.MaxLockDuration; max > 0 && durationSeconds > max {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidDuration, fmt.Sprintf("durationSeconds must be at most %d", max)))
+		return false
+	}
+	return true
+}
+
+// validLockPair responds with 400 unless from and to are three-letter
+// uppercase currency codes of a known corridor
+func (h *HTTPHandler) validLockPair(c *gin.Context, from, to string) bool {
+	for _, code := range []string{from, to} {
+		if !isCurrencyCode(code) {
+			respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidCurrency, fmt.Sprintf("Invalid currency code %q (expected 3 letters, e.g. SGD)", code)))
+			return false
+		}
+	}
+	corridors := h.rateService.GetCorridors(service.CorridorFilter{SourceCurrency: from, TargetCurrency: to, IncludeDisabled: true})
+	if len(corridors) == 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeCorridorNotFound, fmt.Sprintf("No corridor for %s/%s", from, to)))
+		return false
+	}
+	return true
+}
+
+// isCurrencyCode reports whether code is three uppercase letters
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// lockQuotedRate locks the rate of the request's quote, or responds with 410
+// once the quote has expired and 404 if it was never issued
+func (h *HTTPHandler) lockQuotedRate(c *gin.Context, req *model.RateLockRequest) {
+	locked, err := h.rateService.LockRateFromQuoteIdempotent(c.Request.Context(), req.IdempotencyKey, req.QuoteID, req.DurationSeconds)
+	if err != nil {
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to lock quoted rate", zap.String("quoteId", req.QuoteID), zap.Error(err))
+		}
+		respondError(c, apiErr)
 		return
 	}
 
@@ -115,60 +439,284 @@ func (h *HTTPHandler) GetLockedRate(c *gin.Context) {
 	locked, err := h.rateService.GetLockedRate(c.Request.Context(), lockID)
 	if err != nil {
 		h.logger.Error("Failed to get locked rate", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, mapServiceError(err))
 		return
 	}
 
 	if locked.Expired {
-		c.JSON(http.StatusGone, gin.H{"error": "Rate lock expired", "expired": true})
+		respondError(c, mapServiceError(repository.ErrExpired{LockID: lockID}))
 		return
 	}
 
 	c.JSON(http.StatusOK, locked)
 }
 
-// GetCorridors returns available corridors
+// ReleaseLockedRate releases a rate lock before it expires, e.g. when the
+// customer abandons the transfer. Releasing a lock that is already gone
+// succeeds, so clients can safely retry.
+func (h *HTTPHandler) ReleaseLockedRate(c *gin.Context) {
+	lockID := c.Param("lockId")
+
+	if err := h.rateService.DeleteLockedRate(c.Request.Context(), lockID); err != nil {
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to release locked rate", zap.String("lockId", lockID), zap.Error(err))
+		}
+		respondError(c, apiErr)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ExtendLockedRate extends a single rate lock. Failures carry a code so
+// clients can tell a missing lock from an expired one or one at its limit.
+func (h *HTTPHandler) ExtendLockedRate(c *gin.Context) {
+	lockID := c.Param("lockId")
+
+	var req model.ExtendLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+	if req.AdditionalSeconds <= 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "additionalSeconds must be positive"))
+		return
+	}
+
+	locked, err := h.rateService.ExtendLockedRate(c.Request.Context(), lockID, req.AdditionalSeconds)
+	if err != nil {
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to extend locked rate", zap.String("lockId", lockID), zap.Error(err))
+		}
+		respondError(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, locked)
+}
+
+// ExtendLockedRates extends several rate locks and reports the result per lock
+func (h *HTTPHandler) ExtendLockedRates(c *gin.Context) {
+	var req model.ExtendLocksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+	if req.AdditionalSeconds <= 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest, "additionalSeconds must be positive"))
+		return
+	}
+
+	results, err := h.rateService.ExtendLockedRates(c.Request.Context(), req.LockIDs, req.AdditionalSeconds)
+	if err != nil {
+		h.logger.Error("Failed to extend locked rates", zap.Error(err))
+		respondError(c, mapServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// GetCorridors returns available corridors, optionally filtered by ?source=,
+// ?target= and ?method= (a payout method such as CASH_PICKUP). Disabled
+// corridors are left out unless ?includeDisabled=true. With
+// ?includeQuotes=true each enabled corridor carries an illustrative quote for
+// its sample amount.
 func (h *HTTPHandler) GetCorridors(c *gin.Context) {
-	sourceCurrency := c.Query("source")
-	corridors := h.rateService.GetCorridors(sourceCurrency)
+	filter := service.CorridorFilter{
+		SourceCurrency:  provider.NormalizeCurrency(c.Query("source")),
+		TargetCurrency:  provider.NormalizeCurrency(c.Query("target")),
+		PayoutMethod:    c.Query("method"),
+		IncludeDisabled: c.Query("includeDisabled") == "true",
+	}
+	if c.Query("includeQuotes") == "true" {
+		corridors, warnings := h.rateService.GetCorridorQuotes(c.Request.Context(), filter)
+		response := gin.H{"corridors": corridors}
+		if len(warnings) > 0 {
+			response["warnings"] = warnings
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	corridors := h.rateService.GetCorridors(filter)
 	c.JSON(http.StatusOK, gin.H{"corridors": corridors})
 }
 
-// GetQuote generates a rate quote with fees
+// GetQuote generates a rate quote with fees. Either ?amount= (source amount)
+// or ?targetAmount= (amount the recipient should receive) must be given.
 func (h *HTTPHandler) GetQuote(c *gin.Context) {
-	from := c.Query("from")
-	to := c.Query("to")
+	from := provider.NormalizeCurrency(c.Query("from"))
+	to := provider.NormalizeCurrency(c.Query("to"))
 	amountStr := c.Query("amount")
+	targetAmountStr := c.Query("targetAmount")
 
-	if from == "" || to == "" || amountStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "from, to, and amount query parameters are required",
-		})
+	if from == "" || to == "" || (amountStr == "") == (targetAmountStr == "") {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidRequest,
+			"from, to, and exactly one of amount or targetAmount query parameters are required"))
 		return
 	}
 
-	if len(from) != 3 || len(to) != 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency code format"})
+	if !validCurrencyPair(c, from, to) {
 		return
 	}
 
+	quoteForTarget := targetAmountStr != ""
+	if quoteForTarget {
+		amountStr = targetAmountStr
+	}
+
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil || amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidAmount, "Invalid amount"))
+		return
+	}
+
+	var quote *model.RateQuote
+	if quoteForTarget {
+		quote, err = h.rateService.GetQuoteForTarget(c.Request.Context(), from, to, amount)
+	} else {
+		quote, err = h.rateService.GetQuote(c.Request.Context(), from, to, amount)
+	}
+	if err != nil {
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to get quote",
+				zap.String("from", from),
+				zap.String("to", to),
+				zap.Float64("amount", amount),
+				zap.Bool("targetAmount", quoteForTarget),
+				zap.Error(err),
+			)
+		}
+		respondError(c, apiErr)
+		return
+	}
+
+	h.respondWithFields(c, quote)
+}
+
+// GetQuoteByID returns a previously issued quote, or 410 once it has expired
+// and 404 if it was never issued
+func (h *HTTPHandler) GetQuoteByID(c *gin.Context) {
+	quoteID := c.Param("quoteId")
+
+	quote, err := h.rateService.GetQuoteByID(c.Request.Context(), quoteID)
+	if err != nil {
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to get quote", zap.String("quoteId", quoteID), zap.Error(err))
+		}
+		respondError(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// validCurrencyPair responds with 400 and returns false unless both
+// currency codes are well formed and supported
+func validCurrencyPair(c *gin.Context, from, to string) bool {
+	if len(from) != 3 || len(to) != 3 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidCurrency, "Invalid currency code format"))
+		return false
+	}
+	if unsupported := unsupportedCurrency(from, to); unsupported != "" {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeUnsupportedCurrency, "Unsupported currency: "+unsupported))
+		return false
+	}
+	return true
+}
+
+// unsupportedCurrency returns the first of currencies that isn't supported, or ""
+func unsupportedCurrency(currencies ...string) string {
+	for _, code := range currencies {
+		if !model.IsSupportedCurrency(code) {
+			return code
+		}
+	}
+	return ""
+}
+
+// GetLockedQuote generates a quote for ?amount= against a locked rate, using
+// the terms in effect when the rate was locked
+func (h *HTTPHandler) GetLockedQuote(c *gin.Context) {
+	lockID := c.Param("lockId")
+
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil || amount <= 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidAmount, "Invalid amount"))
 		return
 	}
 
-	quote, err := h.rateService.GetQuote(c.Request.Context(), from, to, amount)
+	quote, err := h.rateService.GetQuoteFromLock(c.Request.Context(), lockID, amount)
 	if err != nil {
-		h.logger.Error("Failed to get quote",
-			zap.String("from", from),
-			zap.String("to", to),
-			zap.Float64("amount", amount),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to get locked quote",
+				zap.String("lockId", lockID),
+				zap.Float64("amount", amount),
+				zap.Error(err),
+			)
+		}
+		respondError(c, apiErr)
+		return
+	}
+
+	h.respondWithFields(c, quote)
+}
+
+// VerifyLockedQuote prices an amount at a lock's terms for checking a
+// transfer against it, even after the lock has expired
+func (h *HTTPHandler) VerifyLockedQuote(c *gin.Context) {
+	lockID := c.Param("lockId")
+
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil || amount <= 0 {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidAmount, "Invalid amount"))
+		return
+	}
+
+	quote, err := h.rateService.VerifyQuoteFromLock(c.Request.Context(), lockID, amount)
+	if err != nil {
+		apiErr := mapServiceError(err)
+		if apiErr.HTTPStatus >= http.StatusInternalServerError {
+			h.logger.Error("Failed to verify locked quote",
+				zap.String("lockId", lockID),
+				zap.Float64("amount", amount),
+				zap.Error(err),
+			)
+		}
+		respondError(c, apiErr)
 		return
 	}
 
 	c.JSON(http.StatusOK, quote)
 }
+
+// respondWithFields writes the response, filtered to ?fields= when present.
+// Requests for deprecated fields get a Warning header and, when configured,
+// a _warnings field in the body.
+func (h *HTTPHandler) respondWithFields(c *gin.Context, value interface{}) {
+	fields := c.Query("fields")
+	response, err := selectFields(value, fields)
+	if err != nil {
+		respondError(c, newAPIError(http.StatusBadRequest, CodeInvalidFields, err.Error()))
+		return
+	}
+
+	if h.config.DeprecationWarnings {
+		warnings := deprecationWarnings(value, fields)
+		for _, warning := range warnings {
+			c.Header("Warning", warningHeader(warning))
+		}
+		if filtered, ok := response.(map[string]json.RawMessage); ok && len(warnings) > 0 && h.config.DeprecationWarningsInBody {
+			data, _ := json.Marshal(warnings)
+			filtered["_warnings"] = data
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
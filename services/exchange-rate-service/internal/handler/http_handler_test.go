@@ -0,0 +1,1316 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/metrics"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/service"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// memoryRepository is an in-memory repository.RateRepository for handler tests
+type memoryRepository struct {
+	mu          sync.Mutex // guards rates and quotes, which concurrent quotes write
+	rates       map[string]*provider.Rate
+	history     map[string][]model.RateHistoryPoint
+	lockedRates map[string]*model.LockedRate
+	lockKeys    map[string]repository.LockIdempotencyRecord
+	snapshots   map[string]*model.RateSnapshot
+	quotes      map[string]*model.RateQuote
+	overrides   map[string]model.CorridorOverride
+	writeErr    error // returned by every save when set
+	healthErr   error // returned by Health when set
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		rates:       make(map[string]*provider.Rate),
+		history:     make(map[string][]model.RateHistoryPoint),
+		lockedRates: make(map[string]*model.LockedRate),
+		lockKeys:    make(map[string]repository.LockIdempotencyRecord),
+		snapshots:   make(map[string]*model.RateSnapshot),
+		quotes:      make(map[string]*model.RateQuote),
+		overrides:   make(map[string]model.CorridorOverride),
+	}
+}
+
+func (m *memoryRepository) SaveRate(ctx context.Context, rate *provider.Rate, ttl time.Duration) error {
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := rate.SourceCurrency + ":" + rate.TargetCurrency
+	m.rates[key] = rate
+	m.history[key] = append(m.history[key], model.RateHistoryPoint{
+		Timestamp: rate.FetchedAt, MidRate: rate.MidRate, BidRate: rate.BidRate, AskRate: rate.AskRate,
+	})
+	return nil
+}
+
+func (m *memoryRepository) GetRateHistory(ctx context.Context, source, target string, from, to time.Time) ([]model.RateHistoryPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var points []model.RateHistoryPoint
+	for _, point := range m.history[source+":"+target] {
+		if !point.Timestamp.Before(from) && !point.Timestamp.After(to) {
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+func (m *memoryRepository) GetRate(ctx context.Context, source, target string) (*provider.Rate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rates[source+":"+target], nil
+}
+
+func (m *memoryRepository) GetStaleRate(ctx context.Context, source, target string) (*provider.Rate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rates[source+":"+target], nil
+}
+
+func (m *memoryRepository) SaveLockedRate(ctx context.Context, locked *model.LockedRate) error {
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	m.lockedRates[locked.LockID] = locked
+	return nil
+}
+
+func (m *memoryRepository) GetLockedRate(ctx context.Context, lockID string) (*model.LockedRate, error) {
+	return m.lockedRates[lockID], nil
+}
+
+func (m *memoryRepository) DeleteLockedRate(ctx context.Context, lockID string) error {
+	if _, ok := m.lockedRates[lockID]; !ok {
+		return repository.ErrNotFound{Key: lockID}
+	}
+	delete(m.lockedRates, lockID)
+	return nil
+}
+
+func (m *memoryRepository) CountActiveLocks(ctx context.Context, source, target string) (int, error) {
+	active := 0
+	for _, locked := range m.lockedRates {
+		if locked.Rate.SourceCurrency == source && locked.Rate.TargetCurrency == target && time.Now().Before(locked.ExpiresAt) {
+			active++
+		}
+	}
+	return active, nil
+}
+
+func (m *memoryRepository) ExtendLockedRate(ctx context.Context, lockID string, newExpiry time.Time) error {
+	if locked, ok := m.lockedRates[lockID]; ok {
+		locked.ExpiresAt = newExpiry
+	}
+	return nil
+}
+
+func (m *memoryRepository) SaveLockedRates(ctx context.Context, locked []*model.LockedRate) ([]string, error) {
+	if m.writeErr != nil {
+		return nil, m.writeErr
+	}
+	for _, l := range locked {
+		m.lockedRates[l.LockID] = l
+	}
+	return nil, nil
+}
+
+func (m *memoryRepository) GetLockIdempotencyKey(ctx context.Context, key string) (*repository.LockIdempotencyRecord, error) {
+	if record, ok := m.lockKeys[key]; ok {
+		return &record, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryRepository) ClaimLockIdempotencyKey(ctx context.Context, key string, record repository.LockIdempotencyRecord, ttl time.Duration) (*repository.LockIdempotencyRecord, error) {
+	if existing, ok := m.lockKeys[key]; ok {
+		return &existing, nil
+	}
+	m.lockKeys[key] = record
+	return &record, nil
+}
+
+func (m *memoryRepository) DeleteLockIdempotencyKey(ctx context.Context, key string) error {
+	delete(m.lockKeys, key)
+	return nil
+}
+
+func (m *memoryRepository) SaveRateSnapshot(ctx context.Context, snapshot *model.RateSnapshot, ttl time.Duration) error {
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	if _, ok := m.snapshots[snapshot.LockID]; !ok {
+		m.snapshots[snapshot.LockID] = snapshot
+	}
+	return nil
+}
+
+func (m *memoryRepository) GetRateSnapshot(ctx context.Context, lockID string) (*model.RateSnapshot, error) {
+	return m.snapshots[lockID], nil
+}
+
+func (m *memoryRepository) DeleteRateSnapshot(ctx context.Context, lockID string) error {
+	delete(m.snapshots, lockID)
+	return nil
+}
+
+func (m *memoryRepository) SaveQuote(ctx context.Context, quote *model.RateQuote) error {
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotes[quote.QuoteID] = quote
+	return nil
+}
+
+func (m *memoryRepository) GetQuote(ctx context.Context, quoteID string) (*model.RateQuote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quotes[quoteID], nil
+}
+
+func (m *memoryRepository) SaveCorridorOverride(ctx context.Context, override *model.CorridorOverride) error {
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overrides[override.SourceCurrency+":"+override.TargetCurrency] = *override
+	return nil
+}
+
+func (m *memoryRepository) GetCorridorOverrides(ctx context.Context) ([]model.CorridorOverride, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	overrides := make([]model.CorridorOverride, 0, len(m.overrides))
+	for _, override := range m.overrides {
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+func (m *memoryRepository) Health(ctx context.Context) error {
+	return m.healthErr
+}
+
+func newTestRouter() (*gin.Engine, *service.RateService) {
+	return newTestRouterWithConfig(&config.Config{
+		RateCacheTTL:    30,
+		LockDuration:    30,
+		MaxLockDuration: 120,
+	})
+}
+
+func newTestRouterWithConfig(cfg *config.Config) (*gin.Engine, *service.RateService) {
+	return newTestRouterWithRepository(cfg, newMemoryRepository())
+}
+
+func newTestRouterWithRepository(cfg *config.Config, repo *memoryRepository) (*gin.Engine, *service.RateService) {
+	gin.SetMode(gin.TestMode)
+
+	providerCfg := provider.DefaultSimulatedConfig()
+	providerCfg.Seed = 42
+	rateService := service.NewRateService(cfg, provider.NewSimulatedProvider(providerCfg), repo, zap.NewNop())
+
+	router := gin.New()
+	NewHTTPHandler(cfg, rateService, zap.NewNop()).SetupRoutes(router)
+	return router, rateService
+}
+
+func performRequest(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v (%s)", err, w.Body.String())
+	}
+	return body
+}
+
+// decodeError returns the error object of an error response
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	apiErr, ok := decodeBody(t, w)["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an error object, got %s", w.Body.String())
+	}
+	return apiErr
+}
+
+func TestReady_ReportsWhichDependencyIsDown(t *testing.T) {
+	repo := newMemoryRepository()
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30, LockDuration: 30}, repo)
+
+	w := performRequest(router, http.MethodGet, "/ready")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with healthy dependencies, got %d: %s", w.Code, w.Body.String())
+	}
+
+	repo.healthErr = errors.New("connection refused")
+	w = performRequest(router, http.MethodGet, "/ready")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with redis down, got %d", w.Code)
+	}
+	health, _ := decodeBody(t, w)["health"].(map[string]interface{})
+	redis, _ := health["redis"].(map[string]interface{})
+	prov, _ := health["provider"].(map[string]interface{})
+	if redis["healthy"] != false || prov["healthy"] != true {
+		t.Errorf("expected only redis to be reported down, got %v", health)
+	}
+}
+
+func TestGetRate_UnsupportedCurrency(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/ZZZ")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unsupported currency, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := decodeError(t, w)["code"]; code != "UNSUPPORTED_CURRENCY" {
+		t.Errorf("expected code UNSUPPORTED_CURRENCY, got %v", code)
+	}
+
+	// A supported currency without a corridor of its own is still quoted
+	if w := performRequest(router, http.MethodGet, "/api/rates/USD/GBP"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a supported currency, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetRate_ReportsFromCache(t *testing.T) {
+	router, _ := newTestRouter()
+
+	for i, wantCached := range []bool{false, true} {
+		w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d: %s", i+1, w.Code, w.Body.String())
+		}
+		body := decodeBody(t, w)
+		if body["fromCache"] != wantCached {
+			t.Errorf("request %d: expected fromCache %v, got %v", i+1, wantCached, body["fromCache"])
+		}
+		if age, ok := body["age"].(float64); !ok || age < 0 {
+			t.Errorf("request %d: expected a non-negative age, got %v", i+1, body["age"])
+		}
+	}
+}
+
+func TestGetRate_CurrencyCodesIgnoreCase(t *testing.T) {
+	router, _ := newTestRouter()
+
+	for i, path := range []string{"/api/rates/sgd/php", "/api/rates/SGD/PHP", "/api/rates/Sgd/pHp"} {
+		w := performRequest(router, http.MethodGet, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		body := decodeBody(t, w)
+		if body["sourceCurrency"] != "SGD" || body["targetCurrency"] != "PHP" {
+			t.Errorf("%s: expected the SGD/PHP rate, got %v/%v", path, body["sourceCurrency"], body["targetCurrency"])
+		}
+		// Every spelling shares one cache entry
+		if wantCached := i > 0; body["fromCache"] != wantCached {
+			t.Errorf("%s: expected fromCache %v, got %v", path, wantCached, body["fromCache"])
+		}
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=sgd&to=php&amount=1000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a lowercase quote, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeBody(t, w); body["sourceCurrency"] != "SGD" {
+		t.Errorf("expected the quote in uppercase codes, got %v", body["sourceCurrency"])
+	}
+}
+
+func TestGetRate_FieldsFilter(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP?fields=midRate,expiresAt")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := decodeBody(t, w)
+	if len(body) != 2 {
+		t.Errorf("expected only 2 fields, got %v", body)
+	}
+	if _, ok := body["midRate"]; !ok {
+		t.Error("expected midRate in response")
+	}
+	if _, ok := body["expiresAt"]; !ok {
+		t.Error("expected expiresAt in response")
+	}
+}
+
+func TestGetRate_NoFieldsReturnsFullResponse(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP")
+
+	body := decodeBody(t, w)
+	for _, field := range []string{"sourceCurrency", "targetCurrency", "midRate", "buyRate", "expiresAt"} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("expected %s in full response", field)
+		}
+	}
+}
+
+func TestGetQuote_FieldsFilter(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=100&fields=targetAmount,fee")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := decodeBody(t, w)
+	if len(body) != 2 || body["targetAmount"] == nil || body["fee"] == nil {
+		t.Errorf("expected only targetAmount and fee, got %v", body)
+	}
+}
+
+func TestGetQuote_TargetAmount(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&targetAmount=5000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := decodeBody(t, w)
+	if body["targetAmount"] != "5000.00" {
+		t.Errorf("expected targetAmount 5000, got %v", body["targetAmount"])
+	}
+
+	w = performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=100&targetAmount=5000")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when both amount and targetAmount are given, got %d", w.Code)
+	}
+}
+
+func TestGetRate_UnknownFieldRejected(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP?fields=midRate,bogus")
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown field, got %d", w.Code)
+	}
+}
+
+func TestGetRate_DeprecatedFieldWarning(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{
+		RateCacheTTL:              30,
+		DeprecationWarnings:       true,
+		DeprecationWarningsInBody: true,
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP?fields=rate,midRate")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	warning := w.Header().Get("Warning")
+	if !strings.HasPrefix(warning, "299 - ") || !strings.Contains(warning, "midRate") {
+		t.Errorf("expected deprecation Warning header, got %q", warning)
+	}
+
+	body := decodeBody(t, w)
+	warnings, ok := body["_warnings"].([]interface{})
+	if !ok || len(warnings) != 1 {
+		t.Errorf("expected one _warnings entry, got %v", body["_warnings"])
+	}
+}
+
+func TestGetRate_NoWarningForCurrentFields(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{
+		RateCacheTTL:              30,
+		DeprecationWarnings:       true,
+		DeprecationWarningsInBody: true,
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP?fields=midRate")
+	if warning := w.Header().Get("Warning"); warning != "" {
+		t.Errorf("expected no Warning header, got %q", warning)
+	}
+	if _, ok := decodeBody(t, w)["_warnings"]; ok {
+		t.Error("expected no _warnings field")
+	}
+}
+
+func TestGetRate_DeprecationWarningsDisabled(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30})
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP?fields=rate")
+	if warning := w.Header().Get("Warning"); warning != "" {
+		t.Errorf("expected no Warning header when disabled, got %q", warning)
+	}
+}
+
+func TestRedisWriteFailure_LockReturns503RateDegrades(t *testing.T) {
+	repo := newMemoryRepository()
+	repo.writeErr = repository.ErrWriteFailed{Operation: "save", Kind: repository.WriteFailureOOM, Err: errors.New("OOM")}
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30, LockDuration: 30, MaxLockDuration: 120}, repo)
+
+	// The rate cache is best effort, so rates are still served
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for rate with a failing cache, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rates/lock", strings.NewReader(`{"sourceCurrency":"SGD","targetCurrency":"PHP"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for lock with a failing store, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLockRate_ValidatesBody(t *testing.T) {
+	router, _ := newTestRouter()
+
+	tests := []struct {
+		name    string
+		body    string
+		code    string
+		message string
+	}{
+		{"negative duration", `{"sourceCurrency":"SGD","targetCurrency":"PHP","durationSeconds":-1}`, CodeInvalidDuration, "durationSeconds must not be negative"},
+		{"duration over max", `{"sourceCurrency":"SGD","targetCurrency":"PHP","durationSeconds":121}`, CodeInvalidDuration, "durationSeconds must be at most 120"},
+		{"quote duration over max", `{"quoteId":"q-1","durationSeconds":500}`, CodeInvalidDuration, "durationSeconds must be at most 120"},
+		{"missing currency", `{"sourceCurrency":"SGD"}`, CodeInvalidRequest, "sourceCurrency and targetCurrency, or quoteId, are required"},
+		{"long code", `{"sourceCurrency":"SGDX","targetCurrency":"PHP"}`, CodeInvalidCurrency, `Invalid currency code "SGDX" (expected 3 letters, e.g. SGD)`},
+		{"non-letter code", `{"sourceCurrency":"SGD","targetCurrency":"PH1"}`, CodeInvalidCurrency, `Invalid currency code "PH1" (expected 3 letters, e.g. SGD)`},
+		{"unknown corridor", `{"sourceCurrency":"PHP","targetCurrency":"SGD"}`, CodeCorridorNotFound, "No corridor for PHP/SGD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/rates/lock", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+			apiErr := decodeError(t, w)
+			if apiErr["code"] != tt.code || apiErr["message"] != tt.message {
+				t.Errorf("expected %s %q, got %v %q", tt.code, tt.message, apiErr["code"], apiErr["message"])
+			}
+		})
+	}
+}
+
+func TestLockRate_NormalizesCurrencyCodes(t *testing.T) {
+	router, _ := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rates/lock", strings.NewReader(`{"sourceCurrency":"sgd","targetCurrency":" php "}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	rate, _ := decodeBody(t, w)["rate"].(map[string]interface{})
+	if rate["sourceCurrency"] != "SGD" || rate["targetCurrency"] != "PHP" {
+		t.Errorf("expected the SGD/PHP rate to be locked, got %v/%v", rate["sourceCurrency"], rate["targetCurrency"])
+	}
+}
+
+func TestLockRate_LimitExceeded(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, MaxActiveLocksPerPair: 1})
+
+	lockRate := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/rates/lock", strings.NewReader(`{"sourceCurrency":"SGD","targetCurrency":"PHP"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := lockRate()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	lockID, _ := decodeBody(t, w)["lockId"].(string)
+
+	w = lockRate()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the limit, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := decodeError(t, w)["code"]; code != CodeLockLimitExceeded {
+		t.Errorf("expected code %s, got %v", CodeLockLimitExceeded, code)
+	}
+
+	if w := performRequest(router, http.MethodDelete, "/api/rates/locked/"+lockID); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := lockRate(); w.Code != http.StatusOK {
+		t.Errorf("expected 200 once the lock is released, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLockRates_Batch(t *testing.T) {
+	repo := newMemoryRepository()
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30, LockDuration: 30}, repo)
+
+	lockRates := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/rates/lock/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := lockRates(`{"pairs":[{"sourceCurrency":"SGD","targetCurrency":"PHP"},{"sourceCurrency":"USD","targetCurrency":"PHP","durationSeconds":60}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Results []model.BatchLockResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(body.Results) != 2 || body.Results[0].TargetCurrency != "PHP" || body.Results[1].SourceCurrency != "USD" {
+		t.Fatalf("expected a result per pair in request order, got %s", w.Body.String())
+	}
+	for _, result := range body.Results {
+		if result.Lock == nil || repo.lockedRates[result.Lock.LockID] == nil {
+			t.Errorf("%s/%s: expected a stored lock, got %+v", result.SourceCurrency, result.TargetCurrency, result.Lock)
+		}
+	}
+
+	// An invalid pair fails the request before anything is locked
+	locks := len(repo.lockedRates)
+	w = lockRates(`{"pairs":[{"sourceCurrency":"SGD","targetCurrency":"PHP"},{"sourceCurrency":"SGD","targetCurrency":"XXX"}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported currency, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(repo.lockedRates) != locks {
+		t.Errorf("expected no new locks, got %d", len(repo.lockedRates)-locks)
+	}
+
+	if w := lockRates(`{"pairs":[]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty batch, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLockRates_ValidatesEachPair(t *testing.T) {
+	repo := newMemoryRepository()
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30, LockDuration: 30, MaxLockDuration: 120}, repo)
+
+	tests := []struct {
+		name    string
+		pair    string
+		code    string
+		message string
+	}{
+		{"negative duration", `{"sourceCurrency":"USD","targetCurrency":"PHP","durationSeconds":-1}`, CodeInvalidDuration, "durationSeconds must not be negative"},
+		{"duration over max", `{"sourceCurrency":"USD","targetCurrency":"PHP","durationSeconds":121}`, CodeInvalidDuration, "durationSeconds must be at most 120"},
+		{"unknown corridor", `{"sourceCurrency":"PHP","targetCurrency":"SGD"}`, CodeCorridorNotFound, "No corridor for PHP/SGD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"pairs":[{"sourceCurrency":"SGD","targetCurrency":"PHP"},` + tt.pair + `]}`
+			req := httptest.NewRequest(http.MethodPost, "/api/rates/lock/batch", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+			apiErr := decodeError(t, w)
+			if apiErr["code"] != tt.code || apiErr["message"] != tt.message {
+				t.Errorf("expected %s %q, got %v %q", tt.code, tt.message, apiErr["code"], apiErr["message"])
+			}
+			if len(repo.lockedRates) != 0 {
+				t.Errorf("expected nothing locked, got %d locks", len(repo.lockedRates))
+			}
+		})
+	}
+}
+
+func TestLockRates_BatchFailureNamesPair(t *testing.T) {
+	repo := newMemoryRepository()
+	repo.writeErr = repository.ErrWriteFailed{Operation: "save", Kind: repository.WriteFailureOOM, Err: errors.New("OOM")}
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30, LockDuration: 30}, repo)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rates/lock/batch", strings.NewReader(`{"pairs":[{"sourceCurrency":"SGD","targetCurrency":"PHP"},{"sourceCurrency":"USD","targetCurrency":"PHP"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	apiErr := decodeError(t, w)
+	if apiErr["code"] != CodeStorageUnavailable {
+		t.Errorf("expected code %s, got %v", CodeStorageUnavailable, apiErr["code"])
+	}
+	details, _ := apiErr["details"].(map[string]interface{})
+	if details["sourceCurrency"] != "SGD" || details["targetCurrency"] != "PHP" {
+		t.Errorf("expected the failed SGD/PHP pair in the details, got %v", apiErr["details"])
+	}
+}
+
+func TestLockRate_FromQuote(t *testing.T) {
+	repo := newMemoryRepository()
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30, LockDuration: 30}, repo)
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=100")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	quote := decodeBody(t, w)
+	quoteID, _ := quote["quoteId"].(string)
+
+	lock := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/rates/lock", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = lock(`{"quoteId":"` + quoteID + `"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a valid quote, got %d: %s", w.Code, w.Body.String())
+	}
+	rate, _ := decodeBody(t, w)["rate"].(map[string]interface{})
+	midRate, _ := rate["midRate"].(float64)
+	if strconv.FormatFloat(midRate, 'f', 6, 64) != quote["midMarketRate"] || rate["sourceCurrency"] != "SGD" || rate["targetCurrency"] != "PHP" {
+		t.Errorf("expected the quoted rate to be locked, got %v", rate)
+	}
+
+	if w := lock(`{"quoteId":"unknown"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown quote, got %d: %s", w.Code, w.Body.String())
+	}
+
+	repo.quotes[quoteID].ValidUntil = time.Now().Add(-time.Second)
+	if w := lock(`{"quoteId":"` + quoteID + `"}`); w.Code != http.StatusGone {
+		t.Errorf("expected 410 for an expired quote, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := lock(`{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without currencies or a quote, got %d", w.Code)
+	}
+}
+
+func TestGetLockedQuote(t *testing.T) {
+	router, rateService := newTestRouter()
+
+	locked, err := rateService.LockRate(context.Background(), "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/rates/locked/"+locked.LockID+"/quote?amount=1000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeBody(t, w); body["fee"] != "5.00" {
+		t.Errorf("expected fee 5.00 from the locked terms, got %v", body["fee"])
+	}
+
+	w = performRequest(router, http.MethodGet, "/api/rates/locked/missing/quote?amount=1000")
+	if w.Code != http.StatusGone {
+		t.Errorf("expected 410 for an unknown lock, got %d", w.Code)
+	}
+}
+
+func TestVerifyLockedQuote_AfterLockReleased(t *testing.T) {
+	router, rateService := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, RateSnapshotRetentionHours: 24})
+
+	locked, err := rateService.LockRate(context.Background(), "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w := performRequest(router, http.MethodDelete, "/api/rates/locked/"+locked.LockID); w.Code >= 300 {
+		t.Fatalf("expected the lock to be released, got %d", w.Code)
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/rates/locked/"+locked.LockID+"/verify?amount=1000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the lock's snapshot, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeBody(t, w); body["fee"] != "5.00" {
+		t.Errorf("expected fee 5.00 from the locked terms, got %v", body["fee"])
+	}
+
+	w = performRequest(router, http.MethodGet, "/api/rates/locked/"+locked.LockID+"/verify?amount=abc")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid amount, got %d", w.Code)
+	}
+}
+
+func TestGetCorridors_TargetAndMethodFilters(t *testing.T) {
+	router, _ := newTestRouter()
+
+	w := performRequest(router, http.MethodGet, "/api/corridors?target=PHP&method=CASH_PICKUP")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	corridors, _ := decodeBody(t, w)["corridors"].([]interface{})
+	if len(corridors) != 2 {
+		t.Fatalf("expected the 2 cash pickup corridors into PHP, got %d", len(corridors))
+	}
+	for _, entry := range corridors {
+		corridor := entry.(map[string]interface{})
+		if corridor["targetCurrency"] != "PHP" {
+			t.Errorf("expected only PHP corridors, got %v", corridor["targetCurrency"])
+		}
+		methods := fmt.Sprint(corridor["payoutMethods"])
+		if !strings.Contains(methods, "CASH_PICKUP") {
+			t.Errorf("expected only corridors offering CASH_PICKUP, got %s", methods)
+		}
+	}
+}
+
+func TestGetCorridors_DisabledCorridor(t *testing.T) {
+	original := model.Corridors
+	model.Corridors = append([]model.Corridor(nil), original...)
+	t.Cleanup(func() { model.Corridors = original })
+	for i := range model.Corridors {
+		if model.Corridors[i].SourceCurrency == "SGD" && model.Corridors[i].TargetCurrency == "IDR" {
+			model.Corridors[i].Enabled = false
+		}
+	}
+
+	router, _ := newTestRouter()
+	count := func(path string) int {
+		w := performRequest(router, http.MethodGet, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, w.Code)
+		}
+		corridors, _ := decodeBody(t, w)["corridors"].([]interface{})
+		return len(corridors)
+	}
+	if enabled, all := count("/api/corridors"), count("/api/corridors?includeDisabled=true"); enabled != all-1 || all != len(model.Corridors) {
+		t.Errorf("expected %d corridors with the disabled one and one fewer without, got %d and %d", len(model.Corridors), all, enabled)
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=IDR&amount=100")
+	if body := decodeError(t, w); w.Code != http.StatusBadRequest || body["code"] != "CORRIDOR_DISABLED" {
+		t.Errorf("expected 400 CORRIDOR_DISABLED, got %d: %v", w.Code, body)
+	}
+}
+
+func TestGetQuote_AmountOutOfRange(t *testing.T) {
+	router, _ := newTestRouter()
+
+	for _, amount := range []string{"0.01", "10000000"} {
+		w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount="+amount)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("amount %s: expected 400, got %d: %s", amount, w.Code, w.Body.String())
+		}
+		body := decodeError(t, w)
+		details, _ := body["details"].(map[string]interface{})
+		if body["code"] != "AMOUNT_OUT_OF_RANGE" || details["minAmount"] != "1.00" || details["maxAmount"] != "50000.00" {
+			t.Errorf("amount %s: unexpected error %v", amount, body)
+		}
+		if msg, _ := body["message"].(string); !strings.Contains(msg, "1.00 SGD") || !strings.Contains(msg, "50000.00 SGD") {
+			t.Errorf("amount %s: expected the limits in the message, got %q", amount, msg)
+		}
+	}
+
+	if w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=1000"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for an amount in range, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetQuoteByID(t *testing.T) {
+	repo := newMemoryRepository()
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30}, repo)
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=100")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	issued := decodeBody(t, w)
+	quoteID, _ := issued["quoteId"].(string)
+
+	w = performRequest(router, http.MethodGet, "/api/quote/"+quoteID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a stored quote, got %d: %s", w.Code, w.Body.String())
+	}
+	if stored := decodeBody(t, w); stored["fee"] != issued["fee"] || stored["exchangeRate"] != issued["exchangeRate"] {
+		t.Errorf("expected the issued fee and rate, got %v", stored)
+	}
+
+	repo.quotes[quoteID].ValidUntil = time.Now().Add(-time.Second)
+	w = performRequest(router, http.MethodGet, "/api/quote/"+quoteID)
+	if w.Code != http.StatusGone {
+		t.Errorf("expected 410 for an expired quote, got %d", w.Code)
+	}
+
+	w = performRequest(router, http.MethodGet, "/api/quote/unknown")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown quote, got %d", w.Code)
+	}
+}
+
+func TestGetQuote_StoreFailureIssuesNoQuote(t *testing.T) {
+	repo := newMemoryRepository()
+	repo.writeErr = repository.ErrWriteFailed{Operation: "save quote", Kind: repository.WriteFailureOOM, Err: errors.New("OOM")}
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30}, repo)
+
+	w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=100")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when the quote can't be stored, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(repo.quotes) != 0 {
+		t.Errorf("expected no stored quotes, got %d", len(repo.quotes))
+	}
+}
+
+func TestGetRateHistory_FiltersByTimeRange(t *testing.T) {
+	repo := newMemoryRepository()
+	router, _ := newTestRouterWithRepository(&config.Config{RateCacheTTL: 30}, repo)
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, mid := range []float64{42.1, 42.2, 42.3} {
+		_ = repo.SaveRate(context.Background(), &provider.Rate{
+			SourceCurrency: "SGD", TargetCurrency: "PHP",
+			MidRate: mid, BidRate: mid - 0.1, AskRate: mid + 0.1,
+			FetchedAt: base.Add(time.Duration(i) * time.Hour),
+		}, time.Minute)
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP/history?from=2024-03-01T12:30:00Z&to=2024-03-01T14:00:00Z")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var history model.RateHistory
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(history.Points) != 2 {
+		t.Fatalf("expected the 2 points in range, got %+v", history.Points)
+	}
+	if history.Points[0].MidRate != 42.2 || history.Points[1].MidRate != 42.3 ||
+		!history.Points[0].Timestamp.Equal(base.Add(time.Hour)) {
+		t.Errorf("expected the 13:00 and 14:00 points oldest first, got %+v", history.Points)
+	}
+
+	for _, query := range []string{"from=yesterday", "to=2024-03-01", "from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z"} {
+		w = performRequest(router, http.MethodGet, "/api/rates/SGD/PHP/history?"+query)
+		if w.Code != http.StatusBadRequest || decodeError(t, w)["code"] != "INVALID_TIME_RANGE" {
+			t.Errorf("%s: expected 400 INVALID_TIME_RANGE, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestExtendLockedRate(t *testing.T) {
+	router, rateService := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, MaxLockDuration: 90})
+
+	locked, err := rateService.LockRate(context.Background(), "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	extend := func(lockID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/rates/locked/"+lockID+"/extend", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := extend(locked.LockID, `{"additionalSeconds":30}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = extend(locked.LockID, `{"additionalSeconds":60}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 past the maximum lock duration, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := decodeError(t, w)["code"]; code != "MAX_LOCK_DURATION_EXCEEDED" {
+		t.Errorf("expected code MAX_LOCK_DURATION_EXCEEDED, got %v", code)
+	}
+
+	w = extend("missing", `{"additionalSeconds":30}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown lock, got %d", w.Code)
+	}
+
+	w = extend(locked.LockID, `{"additionalSeconds":0}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-positive extension, got %d", w.Code)
+	}
+}
+
+func TestReleaseLockedRate(t *testing.T) {
+	router, rateService := newTestRouter()
+
+	locked, err := rateService.LockRate(context.Background(), "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := performRequest(router, http.MethodDelete, "/api/rates/locked/"+locked.LockID)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = performRequest(router, http.MethodGet, "/api/rates/locked/"+locked.LockID)
+	if w.Code != http.StatusGone {
+		t.Errorf("expected a released lock to be gone, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Releasing again, or releasing a lock that never existed, still succeeds
+	for _, lockID := range []string{locked.LockID, "missing"} {
+		if w := performRequest(router, http.MethodDelete, "/api/rates/locked/"+lockID); w.Code != http.StatusNoContent {
+			t.Errorf("%s: expected 204, got %d: %s", lockID, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGetRateSnapshot_AfterLockExpired(t *testing.T) {
+	repo := newMemoryRepository()
+	router, rateService := newTestRouterWithRepository(&config.Config{RateSnapshotRetentionHours: 24, AdminToken: "secret"}, repo)
+
+	locked, err := rateService.LockRate(context.Background(), "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	delete(repo.lockedRates, locked.LockID)
+
+	w := performRequest(router, http.MethodGet, "/api/admin/locks/"+locked.LockID+"/snapshot")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the admin token, got %d", w.Code)
+	}
+
+	w = authorizedRequest(router, http.MethodGet, "/api/admin/locks/"+locked.LockID+"/snapshot", "", "Bearer secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := decodeBody(t, w)
+	if body["lockId"] != locked.LockID || body["provider"] == "" || body["midRate"] == 0.0 {
+		t.Errorf("unexpected snapshot: %v", body)
+	}
+
+	w = authorizedRequest(router, http.MethodGet, "/api/admin/locks/missing/snapshot", "", "Bearer secret")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown lock, got %d", w.Code)
+	}
+}
+
+func TestGetRates_BatchReportsPerPairErrors(t *testing.T) {
+	router, _ := newTestRouter()
+
+	body := `{"pairs":[
+		{"source":"SGD","target":"PHP"},
+		{"source":"SGD","target":"PHP"},
+		{"source":"SG","target":"PHP"},
+		{"source":"SGD","target":"XXX"},
+		{"source":"SGD","target":"USD"}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/rates/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []model.BatchRateResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+
+	// The repeated SGD/PHP is looked up and reported once
+	if len(response.Results) != 4 {
+		t.Fatalf("expected 4 results, got %+v", response.Results)
+	}
+
+	tests := []struct {
+		source, target string
+		wantRate       bool
+		wantError      string
+	}{
+		{"SGD", "PHP", true, ""},
+		{"SG", "PHP", false, "Invalid currency code format"},
+		{"SGD", "XXX", false, "Unsupported currency: XXX"},
+		{"SGD", "USD", true, ""},
+	}
+	for i, tt := range tests {
+		result := response.Results[i]
+		if result.SourceCurrency != tt.source || result.TargetCurrency != tt.target {
+			t.Errorf("result %d: expected %s/%s, got %s/%s", i, tt.source, tt.target, result.SourceCurrency, result.TargetCurrency)
+		}
+		if (result.Rate != nil) != tt.wantRate {
+			t.Errorf("%s/%s: expected rate present=%v, got %+v", tt.source, tt.target, tt.wantRate, result.Rate)
+		}
+		if result.Error != tt.wantError {
+			t.Errorf("%s/%s: expected error %q, got %q", tt.source, tt.target, tt.wantError, result.Error)
+		}
+	}
+}
+
+func TestGetRates_BatchRequiresPairs(t *testing.T) {
+	router, _ := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rates/batch", strings.NewReader(`{"pairs":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty batch, got %d", w.Code)
+	}
+}
+
+func TestMetrics_RecordedOnRequestPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 30, MaxLockDuration: 120}
+	providerCfg := provider.DefaultSimulatedConfig()
+	providerCfg.Seed = 42
+	rateService := service.NewRateService(cfg, provider.NewSimulatedProvider(providerCfg), newMemoryRepository(), zap.NewNop())
+
+	// Metrics register with the default registry, so they're created once here
+	m := metrics.NewMetrics("handler_test")
+	rateService.SetMetrics(m)
+	h := NewHTTPHandler(cfg, rateService, zap.NewNop())
+	h.SetMetrics(m)
+
+	router := gin.New()
+	h.SetupRoutes(router)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// The first lookup misses the cache and the second hits it
+	for i := 0; i < 2; i++ {
+		if w := performRequest(router, http.MethodGet, "/api/rates/SGD/PHP"); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if w := performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=100"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	scrape := performRequest(router, http.MethodGet, "/metrics").Body.String()
+	for _, want := range []string{
+		`handler_test_rate_requests_total{source_currency="SGD",status="success",target_currency="PHP"} 2`,
+		`handler_test_rate_request_duration_seconds_count{cache_hit="false",source_currency="SGD",target_currency="PHP"} 1`,
+		`handler_test_rate_request_duration_seconds_count{cache_hit="true",source_currency="SGD",target_currency="PHP"} 1`,
+		`handler_test_cache_misses_total{cache_type="rate"} 1`,
+		`handler_test_cache_hits_total{cache_type="rate"} 2`,
+		`handler_test_quotes_generated_total{source_currency="SGD",target_currency="PHP"} 1`,
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("expected scrape to contain %s", want)
+		}
+	}
+
+	// Locking raises the active locks gauge and releasing lowers it, once
+	locked, err := rateService.LockRate(context.Background(), "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scrape := performRequest(router, http.MethodGet, "/metrics").Body.String(); !strings.Contains(scrape, "handler_test_locked_rates_active 1") {
+		t.Error("expected 1 active lock after locking")
+	}
+	for i := 0; i < 2; i++ {
+		if w := performRequest(router, http.MethodDelete, "/api/rates/locked/"+locked.LockID); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	if scrape := performRequest(router, http.MethodGet, "/metrics").Body.String(); !strings.Contains(scrape, "handler_test_locked_rates_active 0") {
+		t.Error("expected no active locks once the lock is released")
+	}
+}
+
+func TestUpdateCorridor_RequiresAdminToken(t *testing.T) {
+	updateCorridor := func(router *gin.Engine, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/corridors/SGD/PHP", strings.NewReader(`{"marginPercentage":"0.8"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without a configured token the admin endpoints are disabled
+	router, _ := newTestRouter()
+	if w := updateCorridor(router, "Bearer anything"); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 with no admin token configured, got %d: %s", w.Code, w.Body.String())
+	}
+
+	router, _ = newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, AdminToken: "secret"})
+	for _, authorization := range []string{"", "Bearer wrong", "secret"} {
+		w := updateCorridor(router, authorization)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401, got %d: %s", authorization, w.Code, w.Body.String())
+			continue
+		}
+		if code := decodeError(t, w)["code"]; code != CodeUnauthorized {
+			t.Errorf("%q: expected code %s, got %v", authorization, CodeUnauthorized, code)
+		}
+	}
+}
+
+func TestUpdateCorridor_RepricesQuotes(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, AdminToken: "secret"})
+
+	updateCorridor := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := updateCorridor("/api/admin/corridors/SGD/PHP", `{"marginPercentage":"0.8","feePercentage":"1.0"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeBody(t, w); body["marginPercentage"] != "0.8" || body["feePercentage"] != "1.0" {
+		t.Errorf("expected the repriced corridor, got %v", body)
+	}
+
+	w = performRequest(router, http.MethodGet, "/api/quote?from=SGD&to=PHP&amount=1000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := decodeBody(t, w); body["fee"] != "10.00" {
+		t.Errorf("expected fee 10.00 at the new 1.0%% fee, got %v", body["fee"])
+	}
+
+	tests := []struct {
+		path, body string
+		status     int
+		code       string
+	}{
+		{"/api/admin/corridors/SGD/PHP", `{"marginPercentage":"-1"}`, http.StatusBadRequest, CodeInvalidPricing},
+		{"/api/admin/corridors/SGD/PHP", `{}`, http.StatusBadRequest, CodeInvalidPricing},
+		{"/api/admin/corridors/SGD/PHP", `not json`, http.StatusBadRequest, CodeInvalidRequest},
+		{"/api/admin/corridors/PHP/SGD", `{"feePercentage":"1.0"}`, http.StatusNotFound, CodeCorridorNotFound},
+	}
+	for _, tt := range tests {
+		w := updateCorridor(tt.path, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d: %s", tt.path, tt.body, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		if code := decodeError(t, w)["code"]; code != tt.code {
+			t.Errorf("%s %s: expected code %s, got %v", tt.path, tt.body, tt.code, code)
+		}
+	}
+}
+
+// authorizedRequest performs a request carrying authorization, when set
+func authorizedRequest(router *gin.Engine, method, path, body, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAlerts_CreateAndDelete(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, AdminToken: "secret"})
+
+	body := `{"sourceCurrency":"sgd","targetCurrency":"PHP","direction":"above","threshold":43.0,"webhookUrl":"http://203.0.113.10/hook"}`
+	w := authorizedRequest(router, http.MethodPost, "/api/alerts", body, "Bearer secret")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var alert model.RateAlert
+	if err := json.Unmarshal(w.Body.Bytes(), &alert); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if alert.AlertID == "" || alert.SourceCurrency != "SGD" || alert.Threshold != 43.0 {
+		t.Errorf("expected the created SGD/PHP alert, got %+v", alert)
+	}
+
+	if w := authorizedRequest(router, http.MethodDelete, "/api/alerts/"+alert.AlertID, "", "Bearer secret"); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204 deleting the alert, got %d", w.Code)
+	}
+	w = authorizedRequest(router, http.MethodDelete, "/api/alerts/"+alert.AlertID, "", "Bearer secret")
+	if w.Code != http.StatusNotFound || decodeError(t, w)["code"] != CodeAlertNotFound {
+		t.Errorf("expected 404 %s deleting it again, got %d: %s", CodeAlertNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestAlerts_RequireAdminToken(t *testing.T) {
+	body := `{"sourceCurrency":"SGD","targetCurrency":"PHP","direction":"above","threshold":43.0,"webhookUrl":"http://203.0.113.10/hook"}`
+
+	router, _ := newTestRouter()
+	if w := authorizedRequest(router, http.MethodPost, "/api/alerts", body, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without a configured admin token, got %d", w.Code)
+	}
+
+	router, _ = newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, AdminToken: "secret"})
+	for _, authorization := range []string{"", "Bearer wrong"} {
+		if w := authorizedRequest(router, http.MethodPost, "/api/alerts", body, authorization); w.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401 creating an alert, got %d", authorization, w.Code)
+		}
+		if w := authorizedRequest(router, http.MethodDelete, "/api/alerts/some-alert", "", authorization); w.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401 deleting an alert, got %d", authorization, w.Code)
+		}
+	}
+}
+
+func TestAlerts_CreateValidates(t *testing.T) {
+	router, _ := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, LockDuration: 30, AdminToken: "secret"})
+
+	tests := []struct {
+		name, body, code string
+	}{
+		{"bad direction", `{"sourceCurrency":"SGD","targetCurrency":"PHP","direction":"sideways","threshold":43.0,"webhookUrl":"http://203.0.113.10/hook"}`, CodeInvalidAlert},
+		{"bad webhook", `{"sourceCurrency":"SGD","targetCurrency":"PHP","direction":"below","threshold":43.0,"webhookUrl":"example.com"}`, CodeInvalidAlert},
+		{"internal webhook", `{"sourceCurrency":"SGD","targetCurrency":"PHP","direction":"below","threshold":43.0,"webhookUrl":"http://169.254.169.254/latest"}`, CodeInvalidAlert},
+		{"bad currency", `{"sourceCurrency":"SGD","targetCurrency":"XXX","direction":"below","threshold":43.0,"webhookUrl":"http://203.0.113.10/hook"}`, CodeUnsupportedCurrency},
+	}
+	for _, tt := range tests {
+		w := authorizedRequest(router, http.MethodPost, "/api/alerts", tt.body, "Bearer secret")
+		if w.Code != http.StatusBadRequest || decodeError(t, w)["code"] != tt.code {
+			t.Errorf("%s: expected 400 %s, got %d: %s", tt.name, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
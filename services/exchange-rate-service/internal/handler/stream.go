@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+const (
+	// rateStreamInterval is how often every pair is pushed, matching the
+	// gRPC StreamRates
+	rateStreamInterval = 5 * time.Second
+
+	// rateStreamWriteTimeout is how long a client may take to accept an
+	// update before the stream is closed
+	rateStreamWriteTimeout = 10 * time.Second
+)
+
+var rateStreamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// StreamRates upgrades to a WebSocket and pushes a RateUpdate for each pair
+// in ?pairs=SGD:PHP,SGD:INR immediately and then every few seconds, for
+// browsers that can't consume the gRPC stream. Invalid pairs close the
+// connection with a policy violation whose reason says what was wrong, since
+// browsers can't read the body of a rejected handshake.
+func (h *HTTPHandler) StreamRates(c *gin.Context) {
+	conn, err := rateStreamUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already responded
+		h.logger.Debug("Rate stream upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	pairs, err := h.parseStreamPairs(c.Query("pairs"))
+	if err != nil {
+		closeRateStream(conn, websocket.ClosePolicyViolation, err.Error())
+		return
+	}
+
+	// The connection is hijacked, so a disconnect only shows up as a failed
+	// read; cancel the request context when that happens
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sendRates := func() error {
+		for _, p := range pairs {
+			rate, err := h.rateService.GetRate(ctx, p.Source, p.Target)
+			if err != nil {
+				h.logger.Warn("Failed to get rate for stream",
+					zap.String("source", p.Source),
+					zap.String("target", p.Target),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			_ = conn.SetWriteDeadline(time.Now().Add(rateStreamWriteTimeout))
+			if err := conn.WriteJSON(model.RateUpdate{Rate: rate}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(rateStreamInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := sendRates(); err != nil {
+			h.logger.Debug("Closing rate stream", zap.Int("pairs", len(pairs)), zap.Error(err))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseStreamPairs parses a comma-separated list of XXX:YYY pairs, capped at
+// StreamMaxPairs
+func (h *HTTPHandler) parseStreamPairs(query string) ([]provider.CurrencyPair, error) {
+	if query == "" {
+		return nil, fmt.Errorf("at least one currency pair is required")
+	}
+
+	list := strings.Split(query, ",")
+	if max := h.config.StreamMaxPairs; max > 0 && len(list) > max {
+		return nil, fmt.Errorf("too many currency pairs: %d (maximum %d per stream)", len(list), max)
+	}
+
+	pairs := make([]provider.CurrencyPair, 0, len(list))
+	for _, cp := range list {
+		source, target, ok := strings.Cut(cp, ":")
+		source, target = provider.NormalizeCurrency(source), provider.NormalizeCurrency(target)
+		if !ok || len(source) != 3 || len(target) != 3 {
+			return nil, fmt.Errorf("invalid currency pair format: %s (expected 'XXX:YYY')", cp)
+		}
+		if unsupported := unsupportedCurrency(source, target); unsupported != "" {
+			return nil, fmt.Errorf("unsupported currency: %s", unsupported)
+		}
+		pairs = append(pairs, provider.CurrencyPair{Source: source, Target: target})
+	}
+	return pairs, nil
+}
+
+// closeRateStream sends a close frame with code and reason. Close reasons
+// are limited to 123 bytes.
+func closeRateStream(conn *websocket.Conn, code int, reason string) {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	deadline := time.Now().Add(time.Second)
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+)
+
+func dialRateStream(t *testing.T, query string) *websocket.Conn {
+	t.Helper()
+	router, _ := newTestRouterWithConfig(&config.Config{RateCacheTTL: 30, StreamMaxPairs: 2})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/rates/stream?" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial rate stream: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestStreamRates_SendsUpdates(t *testing.T) {
+	conn := dialRateStream(t, "pairs=SGD:PHP")
+
+	var update model.RateUpdate
+	if err := conn.ReadJSON(&update); err != nil {
+		t.Fatalf("expected a rate update, got: %v", err)
+	}
+	if update.Rate == nil || update.Rate.SourceCurrency != "SGD" || update.Rate.TargetCurrency != "PHP" || update.Rate.MidRate <= 0 {
+		t.Errorf("unexpected update %+v", update.Rate)
+	}
+}
+
+func TestStreamRates_ClosesOnInvalidPairs(t *testing.T) {
+	for _, query := range []string{"pairs=SGDPHP", "pairs=", "pairs=SGD:PHP,SGD:INR,SGD:IDR"} {
+		conn := dialRateStream(t, query)
+
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+			t.Errorf("%s: expected a policy violation close, got %v", query, err)
+		}
+	}
+}
@@ -23,9 +23,15 @@ type Metrics struct {
 	ProviderRequestsTotal   *prometheus.CounterVec
 	ProviderErrorsTotal     *prometheus.CounterVec
 	ProviderRequestDuration *prometheus.HistogramVec
+	ProviderHedgesTotal     *prometheus.CounterVec
+
+	// Storage metrics
+	RedisWriteFailuresTotal *prometheus.CounterVec
+	LockFallbackTotal       *prometheus.CounterVec
 
 	// Business metrics
 	QuotesGeneratedTotal *prometheus.CounterVec
+	CapturedMarginTotal  *prometheus.CounterVec
 }
 
 // NewMetrics creates and registers all metrics
@@ -118,6 +124,33 @@ func NewMetrics(namespace string) *Metrics {
 			[]string{"provider"},
 		),
 
+		ProviderHedgesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "provider_hedged_requests_total",
+				Help:      "Total number of requests hedged to a backup provider because the primary was slow",
+			},
+			[]string{"slow_provider", "winner"}, // winner is "none" if every provider failed
+		),
+
+		RedisWriteFailuresTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "redis_write_failures_total",
+				Help:      "Total number of Redis writes that failed",
+			},
+			[]string{"operation", "type"}, // type is "oom", "readonly" or "error"
+		),
+
+		LockFallbackTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "lock_fallback_total",
+				Help:      "Total number of locked rate operations served from memory because Redis was unreachable",
+			},
+			[]string{"operation"}, // "save", "get", "extend" or "claim_key"
+		),
+
 		QuotesGeneratedTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
@@ -126,9 +159,49 @@ func NewMetrics(namespace string) *Metrics {
 			},
 			[]string{"source_currency", "target_currency"},
 		),
+
+		CapturedMarginTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "captured_margin_total",
+				Help:      "Theoretical margin revenue captured by rate locks, in the reporting currency",
+			},
+			[]string{"corridor", "currency"},
+		),
 	}
 }
 
+// RegisterHealthGauges exposes the rolling provider error rate and degraded
+// state, evaluated on each scrape
+func RegisterHealthGauges(namespace string, errorRate func() float64, degraded func() bool) {
+	if namespace == "" {
+		namespace = "exchange_rate_service"
+	}
+
+	promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "provider_error_rate",
+			Help:      "Rolling provider error rate (0-1)",
+		},
+		errorRate,
+	)
+
+	promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "health_degraded",
+			Help:      "1 if the service is degraded due to provider errors, 0 otherwise",
+		},
+		func() float64 {
+			if degraded() {
+				return 1
+			}
+			return 0
+		},
+	)
+}
+
 // RecordRateRequest records metrics for a rate request
 func (m *Metrics) RecordRateRequest(source, target, status string, durationSeconds float64, cacheHit bool) {
 	m.RateRequestsTotal.WithLabelValues(source, target, status).Inc()
@@ -172,7 +245,31 @@ func (m *Metrics) RecordProviderError(provider, errorType string) {
 	m.ProviderErrorsTotal.WithLabelValues(provider, errorType).Inc()
 }
 
+// RecordProviderHedge records a request hedged away from a slow provider
+func (m *Metrics) RecordProviderHedge(slowProvider, winner string) {
+	if winner == "" {
+		winner = "none"
+	}
+	m.ProviderHedgesTotal.WithLabelValues(slowProvider, winner).Inc()
+}
+
+// RecordRedisWriteFailure records a failed Redis write
+func (m *Metrics) RecordRedisWriteFailure(operation, kind string) {
+	m.RedisWriteFailuresTotal.WithLabelValues(operation, kind).Inc()
+}
+
+// RecordLockFallback records a locked rate operation served from the
+// in-memory fallback
+func (m *Metrics) RecordLockFallback(operation string) {
+	m.LockFallbackTotal.WithLabelValues(operation).Inc()
+}
+
 // RecordQuoteGenerated records a quote generation
 func (m *Metrics) RecordQuoteGenerated(source, target string) {
 	m.QuotesGeneratedTotal.WithLabelValues(source, target).Inc()
 }
+
+// RecordCapturedMargin records the margin captured by a quote
+func (m *Metrics) RecordCapturedMargin(corridor, currency string, amount float64) {
+	m.CapturedMarginTotal.WithLabelValues(corridor, currency).Add(amount)
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var (
+	testMetricsOnce sync.Once
+	testMetrics     *Metrics
+)
+
+// newTestMetrics returns a shared Metrics, since metrics register with the
+// default registry and can only be created once per process
+func newTestMetrics() *Metrics {
+	testMetricsOnce.Do(func() {
+		testMetrics = NewMetrics("test")
+	})
+	return testMetrics
+}
+
+func TestRecordCapturedMargin(t *testing.T) {
+	m := newTestMetrics()
+	counter := m.CapturedMarginTotal.WithLabelValues("SGD/PHP", "USD")
+	before := testutil.ToFloat64(counter)
+
+	m.RecordCapturedMargin("SGD/PHP", "USD", 2.25)
+	m.RecordCapturedMargin("SGD/PHP", "USD", 0.75)
+
+	if got := testutil.ToFloat64(counter) - before; got != 3.0 {
+		t.Errorf("expected captured margin to increase by 3.0, got %f", got)
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
+
+// Decimal places quote rates are rounded to, and amounts in currencies
+// without an entry in CurrencyPrecision
+const (
+	AmountPlaces = 2
+	RatePlaces   = 6
+)
+
+// CurrencyPrecision is the number of decimal places amounts in each currency
+// are rounded to. Currencies whose smallest unit in use is the whole unit,
+// such as IDR and VND, have none.
+var CurrencyPrecision = map[string]int32{
+	"SGD": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"PHP": 2,
+	"INR": 2,
+	"MYR": 2,
+	"THB": 2,
+	"IDR": 0,
+	"VND": 0,
+	"JPY": 0,
+	"KRW": 0,
+}
+
+// Precision returns the decimal places amounts in currency are rounded to
+func Precision(currency string) int32 {
+	if places, ok := CurrencyPrecision[currency]; ok {
+		return places
+	}
+	return AmountPlaces
+}
+
+// Amount is a money amount in a quote. It keeps the scale it was rounded to
+// and is written to JSON as a string with that many decimals, so clients
+// never see float artifacts or "11650.000000 IDR".
+type Amount struct {
+	decimal.Decimal
+}
+
+// NewAmount rounds d half-up to currency's precision
+func NewAmount(d decimal.Decimal, currency string) Amount {
+	return Amount{d.Round(Precision(currency))}
+}
+
+// AmountFromFloat converts f by its shortest decimal representation, so
+// 100.005 stays 100.005 before rounding rather than 100.00499...
+func AmountFromFloat(f float64, currency string) Amount {
+	return NewAmount(decimal.NewFromFloat(f), currency)
+}
+
+// Places returns the number of decimals the amount is written with
+func (a Amount) Places() int32 {
+	if exp := a.Exponent(); exp < 0 {
+		return -exp
+	}
+	return 0
+}
+
+// String returns the amount with its fixed number of decimals
+func (a Amount) String() string {
+	return a.StringFixed(a.Places())
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// RateValue is an exchange rate in a quote. It is written to JSON as a
+// string with RatePlaces decimals.
+type RateValue struct {
+	decimal.Decimal
+}
+
+// NewRateValue rounds d half-up to RatePlaces decimals
+func NewRateValue(d decimal.Decimal) RateValue {
+	return RateValue{d.Round(RatePlaces)}
+}
+
+// RateValueFromFloat converts f by its shortest decimal representation
+func RateValueFromFloat(f float64) RateValue {
+	return NewRateValue(decimal.NewFromFloat(f))
+}
+
+func (r RateValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.StringFixed(RatePlaces))
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strconv"
 	"time"
 )
 
@@ -9,7 +10,7 @@ type ExchangeRate struct {
 	SourceCurrency   string    `json:"sourceCurrency"`
 	TargetCurrency   string    `json:"targetCurrency"`
 	MidRate          float64   `json:"midRate"`          // Mid-market rate (raw)
-	Rate             string    `json:"rate"`             // Mid-market rate (string for API)
+	Rate             string    `json:"rate" deprecated:"use midRate instead"` // Mid-market rate (string for API)
 	BuyRate          string    `json:"buyRate"`          // Rate we offer (includes margin)
 	BidRate          float64   `json:"bidRate"`          // Rate to buy target currency
 	AskRate          float64   `json:"askRate"`          // Rate to sell target currency
@@ -18,6 +19,25 @@ type ExchangeRate struct {
 	Source           string    `json:"source"`           // Provider name
 	FetchedAt        time.Time `json:"fetchedAt"`
 	ExpiresAt        time.Time `json:"expiresAt"`
+	Age              float64   `json:"age"`       // Seconds since the rate was fetched from the provider
+	FromCache        bool      `json:"fromCache"` // Served from the rate cache rather than fetched for this request
+}
+
+// RateHistoryPoint is a pair's rate as fetched from the provider at Timestamp
+type RateHistoryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	MidRate   float64   `json:"midRate"`
+	BidRate   float64   `json:"bidRate"`
+	AskRate   float64   `json:"askRate"`
+}
+
+// RateHistory is a pair's recorded rates between From and To, oldest first
+type RateHistory struct {
+	SourceCurrency string             `json:"sourceCurrency"`
+	TargetCurrency string             `json:"targetCurrency"`
+	From           time.Time          `json:"from"`
+	To             time.Time          `json:"to"`
+	Points         []RateHistoryPoint `json:"points"`
 }
 
 // LockedRate represents a rate that has been locked for a transfer
@@ -27,6 +47,35 @@ type LockedRate struct {
 	LockedAt  time.Time    `json:"lockedAt"`
 	ExpiresAt time.Time    `json:"expiresAt"`
 	Expired   bool         `json:"expired"`
+
+	// Corridor terms in effect when the rate was locked, so quotes from the
+	// lock are unaffected by later corridor config changes. A lock taken on
+	// a quote has the quote's terms instead, and QuoteID set.
+	MarginPercentage string `json:"marginPercentage,omitempty"`
+	FeePercentage    string `json:"feePercentage,omitempty"`
+	FeeMinimum       *Money `json:"feeMinimum,omitempty"`
+	QuoteID          string `json:"quoteId,omitempty"`
+}
+
+// RateSnapshot is an immutable record of the market conditions a rate was
+// locked at, kept beyond the lock's expiry for dispute resolution
+type RateSnapshot struct {
+	LockID           string    `json:"lockId"`
+	SourceCurrency   string    `json:"sourceCurrency"`
+	TargetCurrency   string    `json:"targetCurrency"`
+	MidRate          float64   `json:"midRate"`
+	BidRate          float64   `json:"bidRate"`
+	AskRate          float64   `json:"askRate"`
+	Spread           float64   `json:"spread"` // Spread percentage
+	Drift            float64   `json:"drift"`  // Provider drift from the base rate as a fraction
+	BuyRate          string    `json:"buyRate"`
+	MarginPercentage string    `json:"marginPercentage"`
+	FeePercentage    string    `json:"feePercentage,omitempty"`
+	FeeMinimum       *Money    `json:"feeMinimum,omitempty"`
+	Provider         string    `json:"provider"`
+	FetchedAt        time.Time `json:"fetchedAt"`
+	LockedAt         time.Time `json:"lockedAt"`
+	ExpiresAt        time.Time `json:"expiresAt"` // When the lock expired, not the snapshot
 }
 
 // Corridor represents a currency corridor configuration
@@ -38,6 +87,78 @@ type Corridor struct {
 	FeeMinimum       Money    `json:"feeMinimum"`
 	MarginPercentage string   `json:"marginPercentage"`
 	PayoutMethods    []string `json:"payoutMethods"`
+	MinTargetAmount  Money    `json:"minTargetAmount"` // Smallest amount payout networks accept in the target currency
+	MinAmount        Money    `json:"minAmount"`       // Smallest source amount that can be quoted
+	MaxAmount        Money    `json:"maxAmount"`       // Largest source amount that can be quoted
+
+	// ReverseQuotes lets the pair be quoted target to source too, at this
+	// corridor's fee and margin, when no corridor is defined that way round
+	ReverseQuotes bool `json:"reverseQuotes,omitempty"`
+
+	// RateValiditySeconds overrides the global rate cache TTL for this corridor (0 uses the global TTL)
+	RateValiditySeconds int `json:"rateValiditySeconds,omitempty"`
+
+	// SampleAmount is the typical source amount used for the illustrative
+	// quote in corridor listings (empty uses DefaultSampleAmount)
+	SampleAmount string `json:"sampleAmount,omitempty"`
+}
+
+// defaultSampleAmounts are typical transfer amounts for currencies whose unit
+// is worth much less than a dollar; other currencies default to 100
+var defaultSampleAmounts = map[string]float64{
+	"IDR": 1000000,
+	"VND": 2000000,
+	"KRW": 100000,
+	"JPY": 10000,
+	"INR": 5000,
+	"PHP": 5000,
+	"THB": 3000,
+}
+
+// DefaultSampleAmount returns a typical transfer amount in currency
+func DefaultSampleAmount(currency string) float64 {
+	if amount, ok := defaultSampleAmounts[currency]; ok {
+		return amount
+	}
+	return 100
+}
+
+// SampleSourceAmount returns the corridor's sample amount, or the default for
+// its source currency if none is configured
+func (c Corridor) SampleSourceAmount() float64 {
+	if amount, err := strconv.ParseFloat(c.SampleAmount, 64); err == nil && amount > 0 {
+		return amount
+	}
+	return DefaultSampleAmount(c.SourceCurrency)
+}
+
+// CorridorOverride replaces a corridor's compiled-in pricing at runtime.
+// Empty percentages keep the corridor's own.
+type CorridorOverride struct {
+	SourceCurrency   string    `json:"sourceCurrency"`
+	TargetCurrency   string    `json:"targetCurrency"`
+	MarginPercentage string    `json:"marginPercentage,omitempty"`
+	FeePercentage    string    `json:"feePercentage,omitempty"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Apply returns c with the override's pricing
+func (o CorridorOverride) Apply(c Corridor) Corridor {
+	if o.MarginPercentage != "" {
+		c.MarginPercentage = o.MarginPercentage
+	}
+	if o.FeePercentage != "" {
+		c.FeePercentage = o.FeePercentage
+	}
+	return c
+}
+
+// CorridorQuote is a corridor listing entry with an illustrative quote for
+// the corridor's sample amount
+type CorridorQuote struct {
+	Corridor
+	SampleQuote *RateQuote `json:"sampleQuote,omitempty"`
+	Error       string     `json:"error,omitempty"` // Why the sample quote is missing
 }
 
 // Money represents a monetary amount
@@ -46,25 +167,231 @@ type Money struct {
 	Amount   string `json:"amount"`
 }
 
-// RateLockRequest represents a request to lock a rate
+// RateLockRequest represents a request to lock a rate. With a quote ID the
+// quote's rate is locked and the currencies may be omitted.
 type RateLockRequest struct {
-	SourceCurrency  string `json:"sourceCurrency" binding:"required"`
-	TargetCurrency  string `json:"targetCurrency" binding:"required"`
+	SourceCurrency  string `json:"sourceCurrency"`
+	TargetCurrency  string `json:"targetCurrency"`
+	QuoteID         string `json:"quoteId,omitempty"`
 	DurationSeconds int    `json:"durationSeconds"`
+	IdempotencyKey  string `json:"idempotencyKey,omitempty"` // Retries with the same key return the same lock
+}
+
+// BatchLockRequest represents a request to lock the rates of several pairs
+// at once, for a transfer split across corridors
+type BatchLockRequest struct {
+	Pairs []BatchLockPair `json:"pairs" binding:"required"`
+}
+
+// BatchLockPair is one currency pair in a batch lock request
+type BatchLockPair struct {
+	SourceCurrency  string `json:"sourceCurrency"`
+	TargetCurrency  string `json:"targetCurrency"`
+	DurationSeconds int    `json:"durationSeconds"`
+}
+
+// BatchLockResult reports the lock taken for one pair in a batch
+type BatchLockResult struct {
+	SourceCurrency string      `json:"sourceCurrency"`
+	TargetCurrency string      `json:"targetCurrency"`
+	Lock           *LockedRate `json:"lock"`
+}
+
+// UpdateCorridorRequest represents an admin request to reprice a corridor.
+// Omitted percentages are left as they are.
+type UpdateCorridorRequest struct {
+	MarginPercentage string `json:"marginPercentage"`
+	FeePercentage    string `json:"feePercentage"`
+}
+
+// Rate alert directions: an alert triggers when the rate rises above, or
+// falls below, its threshold
+const (
+	AlertAbove = "above"
+	AlertBelow = "below"
+)
+
+// CreateAlertRequest represents a request to be notified when a pair's rate
+// crosses a threshold
+type CreateAlertRequest struct {
+	SourceCurrency string  `json:"sourceCurrency"`
+	TargetCurrency string  `json:"targetCurrency"`
+	Direction      string  `json:"direction"`
+	Threshold      float64 `json:"threshold"`
+	WebhookURL     string  `json:"webhookUrl"`
+}
+
+// RateAlert notifies its webhook once when a pair's mid rate crosses its
+// threshold in its direction, and again only after the rate has crossed back
+type RateAlert struct {
+	AlertID             string    `json:"alertId"`
+	ClientID            string    `json:"-"` // the client that created it, counted against its alert limit
+	SourceCurrency      string    `json:"sourceCurrency"`
+	TargetCurrency      string    `json:"targetCurrency"`
+	Direction           string    `json:"direction"`
+	Threshold           float64   `json:"threshold"`
+	WebhookURL          string    `json:"webhookUrl"`
+	Triggered           bool      `json:"triggered"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"` // webhook calls failed since it last fired
+	Disabled            bool      `json:"disabled"`            // set after too many consecutive webhook failures
+	RetryAt             time.Time `json:"-"`                   // a failed webhook isn't retried before this
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// RateAlertNotification is the payload POSTed to an alert's webhook
+type RateAlertNotification struct {
+	AlertID        string    `json:"alertId"`
+	SourceCurrency string    `json:"sourceCurrency"`
+	TargetCurrency string    `json:"targetCurrency"`
+	Direction      string    `json:"direction"`
+	Threshold      float64   `json:"threshold"`
+	Rate           float64   `json:"rate"`
+	TriggeredAt    time.Time `json:"triggeredAt"`
+}
+
+// ExtendLocksRequest represents a request to extend several rate locks at once
+type ExtendLocksRequest struct {
+	LockIDs           []string `json:"lockIds" binding:"required"`
+	AdditionalSeconds int      `json:"additionalSeconds" binding:"required"`
+}
+
+// ExtendLockRequest represents a request to extend a single rate lock
+type ExtendLockRequest struct {
+	AdditionalSeconds int `json:"additionalSeconds" binding:"required"`
+}
+
+// LockExtensionResult reports the outcome of extending one lock in a batch
+type LockExtensionResult struct {
+	LockID    string     `json:"lockId"`
+	Extended  bool       `json:"extended"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// BatchRatesRequest represents a request for the rates of several pairs at once
+type BatchRatesRequest struct {
+	Pairs []BatchRatePair `json:"pairs" binding:"required"`
+}
+
+// BatchRatePair is one currency pair in a batch rates request
+type BatchRatePair struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// BatchRateResult reports the rate, or why there is none, for one pair in a batch
+type BatchRateResult struct {
+	SourceCurrency string        `json:"sourceCurrency"`
+	TargetCurrency string        `json:"targetCurrency"`
+	Rate           *ExchangeRate `json:"rate,omitempty"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// BatchRates holds the rates found for a batch of pairs, and why there is
+// none for the others, so one failed pair doesn't fail the whole batch
+type BatchRates struct {
+	Rates  []*ExchangeRate `json:"rates"`
+	Errors []PairError     `json:"errors"`
+}
+
+// PairError reports why there is no rate for one pair in a batch
+type PairError struct {
+	SourceCurrency string `json:"sourceCurrency"`
+	TargetCurrency string `json:"targetCurrency"`
+	Error          string `json:"error"`
+}
+
+// RateUpdate is one rate pushed to a rate stream subscriber
+type RateUpdate struct {
+	Rate *ExchangeRate `json:"rate"`
 }
 
-// RateQuote represents a customer-facing rate quote with fees
+// RateQuote represents a customer-facing rate quote with fees. Amounts and
+// rates are exact decimals, written to JSON as fixed-scale strings.
 type RateQuote struct {
 	SourceCurrency   string    `json:"sourceCurrency"`
 	TargetCurrency   string    `json:"targetCurrency"`
-	SourceAmount     float64   `json:"sourceAmount"`     // Amount in source currency
-	TargetAmount     float64   `json:"targetAmount"`     // Amount in target currency after conversion
-	ExchangeRate     float64   `json:"exchangeRate"`     // Rate applied (includes margin)
-	MidMarketRate    float64   `json:"midMarketRate"`    // Transparent mid-market rate
-	Fee              float64   `json:"fee"`              // Fee in source currency
-	TotalCost        float64   `json:"totalCost"`        // SourceAmount + Fee
+	SourceAmount     Amount    `json:"sourceAmount"`     // Amount in source currency
+	TargetAmount     Amount    `json:"targetAmount"`     // Amount in target currency after conversion
+	ExchangeRate     RateValue `json:"exchangeRate"`     // Rate applied (includes margin)
+	MidMarketRate    RateValue `json:"midMarketRate"`    // Transparent mid-market rate
+	Fee              Amount    `json:"fee"`              // Fee in source currency
+	TotalCost        Amount    `json:"totalCost"`        // SourceAmount + Fee
+	EffectiveRate    RateValue `json:"effectiveRate"`    // All-in rate: TargetAmount / TotalCost
 	ValidUntil       time.Time `json:"validUntil"`       // When this quote expires
 	QuoteID          string    `json:"quoteId"`          // Unique identifier for this quote
+
+	// The margin the rate was priced with. For transfers routed through
+	// intermediate currencies it is the margin the legs capture together,
+	// with the per-leg breakdown in Legs.
+	Legs             []QuoteLeg `json:"legs,omitempty"`
+	MarginPercentage string     `json:"marginPercentage,omitempty"`
+
+	// The bid the rate was priced from and the corridor's fee terms, kept
+	// so a lock taken on the quote gets exactly the quoted terms. Multi-leg
+	// quotes have neither.
+	BidRate       float64 `json:"bidRate,omitempty"`
+	FeePercentage string  `json:"feePercentage,omitempty"`
+	FeeMinimum    *Money  `json:"feeMinimum,omitempty"`
+
+	// Decimal places of amounts in the source and target currencies, so
+	// clients can format amounts the way the quote does
+	SourcePrecision int32 `json:"sourcePrecision"`
+	TargetPrecision int32 `json:"targetPrecision"`
+}
+
+// QuoteLeg is one conversion in a multi-leg quote
+type QuoteLeg struct {
+	SourceCurrency   string  `json:"sourceCurrency"`
+	TargetCurrency   string  `json:"targetCurrency"`
+	SourceAmount     Amount    `json:"sourceAmount"`
+	TargetAmount     Amount    `json:"targetAmount"`
+	ExchangeRate     RateValue `json:"exchangeRate"`
+	MidMarketRate    RateValue `json:"midMarketRate"`
+	MarginPercentage string    `json:"marginPercentage"`
+	Fee              Amount    `json:"fee"` // Fee in the leg's source currency
+}
+
+// ProviderRateComparison shows the rate each configured provider returns for a pair
+type ProviderRateComparison struct {
+	SourceCurrency string              `json:"sourceCurrency"`
+	TargetCurrency string              `json:"targetCurrency"`
+	Providers      []ProviderRateEntry `json:"providers"`
+	Selected       string              `json:"selected,omitempty"` // Provider that would serve the rate
+	ComparedAt     time.Time           `json:"comparedAt"`
+}
+
+// ProviderRateEntry is a single provider's result within a comparison
+type ProviderRateEntry struct {
+	Provider  string  `json:"provider"`
+	MidRate   float64 `json:"midRate,omitempty"`
+	BidRate   float64 `json:"bidRate,omitempty"`
+	AskRate   float64 `json:"askRate,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Health states reported by the readiness check
+const (
+	HealthStateHealthy   = "healthy"
+	HealthStateDegraded  = "degraded"
+	HealthStateUnhealthy = "unhealthy"
+)
+
+// HealthReport describes the service health beyond a binary up/down
+type HealthReport struct {
+	State             string           `json:"state"`
+	ProviderErrorRate float64          `json:"providerErrorRate"`
+	ProviderRequests  int              `json:"providerRequests"`
+	Redis             DependencyHealth `json:"redis"`
+	Provider          DependencyHealth `json:"provider"`
+	Error             string           `json:"error,omitempty"`
+}
+
+// DependencyHealth is the result of checking one dependency
+type DependencyHealth struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
 }
 
 // Corridors is a list of all supported corridors
@@ -76,7 +403,12 @@ var Corridors = []Corridor{
 		FeePercentage:    "0.5",
 		FeeMinimum:       Money{Currency: "SGD", Amount: "3.00"},
 		MarginPercentage: "0.3",
+		MinTargetAmount:  Money{Currency: "PHP", Amount: "50.00"},
+		MinAmount:        Money{Currency: "SGD", Amount: "1.00"},
+		MaxAmount:        Money{Currency: "SGD", Amount: "50000.00"},
 		PayoutMethods:    []string{"BANK_ACCOUNT", "MOBILE_WALLET", "CASH_PICKUP"},
+		SampleAmount:     "500", // Typical monthly remittance
+		ReverseQuotes:    true,
 	},
 	{
 		SourceCurrency:   "SGD",
@@ -85,6 +417,9 @@ var Corridors = []Corridor{
 		FeePercentage:    "0.5",
 		FeeMinimum:       Money{Currency: "SGD", Amount: "3.00"},
 		MarginPercentage: "0.35",
+		MinTargetAmount:  Money{Currency: "INR", Amount: "100.00"},
+		MinAmount:        Money{Currency: "SGD", Amount: "1.00"},
+		MaxAmount:        Money{Currency: "SGD", Amount: "50000.00"},
 		PayoutMethods:    []string{"BANK_ACCOUNT", "MOBILE_WALLET"},
 	},
 	{
@@ -94,7 +429,12 @@ var Corridors = []Corridor{
 		FeePercentage:    "0.5",
 		FeeMinimum:       Money{Currency: "SGD", Amount: "3.00"},
 		MarginPercentage: "0.3",
+		MinTargetAmount:  Money{Currency: "IDR", Amount: "20000"},
+		MinAmount:        Money{Currency: "SGD", Amount: "1.00"},
+		MaxAmount:        Money{Currency: "SGD", Amount: "50000.00"},
 		PayoutMethods:    []string{"BANK_ACCOUNT", "MOBILE_WALLET"},
+
+		RateValiditySeconds: 15, // IDR moves quickly, refresh more often
 	},
 	{
 		SourceCurrency:   "USD",
@@ -103,6 +443,9 @@ var Corridors = []Corridor{
 		FeePercentage:    "0.4",
 		FeeMinimum:       Money{Currency: "USD", Amount: "2.00"},
 		MarginPercentage: "0.25",
+		MinTargetAmount:  Money{Currency: "PHP", Amount: "50.00"},
+		MinAmount:        Money{Currency: "USD", Amount: "1.00"},
+		MaxAmount:        Money{Currency: "USD", Amount: "35000.00"},
 		PayoutMethods:    []string{"BANK_ACCOUNT", "MOBILE_WALLET", "CASH_PICKUP"},
 	},
 	{
@@ -112,6 +455,34 @@ var Corridors = []Corridor{
 		FeePercentage:    "0.3",
 		FeeMinimum:       Money{Currency: "SGD", Amount: "2.00"},
 		MarginPercentage: "0.2",
+		MinTargetAmount:  Money{Currency: "USD", Amount: "1.00"},
+		MinAmount:        Money{Currency: "SGD", Amount: "1.00"},
+		MaxAmount:        Money{Currency: "SGD", Amount: "100000.00"},
 		PayoutMethods:    []string{"BANK_ACCOUNT"},
+		SampleAmount:     "1000",
+
+		RateValiditySeconds: 120, // Stable major pair, safe to cache longer
 	},
 }
+
+// RateCurrencies are the currencies the rate providers quote, including
+// ones without a corridor of their own. The server replaces them at startup
+// with the currencies of the provider's supported pairs; these, matching the
+// simulated provider's base rates, are used until then or if it can't say.
+var RateCurrencies = []string{"SGD", "USD", "EUR", "GBP", "PHP", "INR", "IDR", "MYR", "THB", "VND"}
+
+// IsSupportedCurrency reports whether code is a currency of a corridor or
+// one the rate providers quote
+func IsSupportedCurrency(code string) bool {
+	for _, c := range Corridors {
+		if c.SourceCurrency == code || c.TargetCurrency == code {
+			return true
+		}
+	}
+	for _, currency := range RateCurrencies {
+		if currency == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,451 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: exchange.proto
+
+package exchangepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ExchangeRateService_GetRate_FullMethodName           = "/movra.exchange.ExchangeRateService/GetRate"
+	ExchangeRateService_GetQuote_FullMethodName          = "/movra.exchange.ExchangeRateService/GetQuote"
+	ExchangeRateService_LockRate_FullMethodName          = "/movra.exchange.ExchangeRateService/LockRate"
+	ExchangeRateService_GetLockedRate_FullMethodName     = "/movra.exchange.ExchangeRateService/GetLockedRate"
+	ExchangeRateService_ExtendLockedRate_FullMethodName  = "/movra.exchange.ExchangeRateService/ExtendLockedRate"
+	ExchangeRateService_ExtendLockedRates_FullMethodName = "/movra.exchange.ExchangeRateService/ExtendLockedRates"
+	ExchangeRateService_ReleaseLockedRate_FullMethodName = "/movra.exchange.ExchangeRateService/ReleaseLockedRate"
+	ExchangeRateService_GetCorridors_FullMethodName      = "/movra.exchange.ExchangeRateService/GetCorridors"
+	ExchangeRateService_StreamRates_FullMethodName       = "/movra.exchange.ExchangeRateService/StreamRates"
+)
+
+// ExchangeRateServiceClient is the client API for ExchangeRateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ExchangeRateServiceClient interface {
+	// Get current rate
+	GetRate(ctx context.Context, in *GetRateRequest, opts ...grpc.CallOption) (*GetRateResponse, error)
+	// Quote a transfer of a source amount, including fees
+	GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*GetQuoteResponse, error)
+	// Lock a rate for a period (used during transfer confirmation)
+	LockRate(ctx context.Context, in *LockRateRequest, opts ...grpc.CallOption) (*LockRateResponse, error)
+	// Get a previously locked rate
+	GetLockedRate(ctx context.Context, in *GetLockedRateRequest, opts ...grpc.CallOption) (*GetLockedRateResponse, error)
+	// Extend a locked rate, up to the maximum lock duration
+	ExtendLockedRate(ctx context.Context, in *ExtendLockedRateRequest, opts ...grpc.CallOption) (*ExtendLockedRateResponse, error)
+	// Extend several locked rates at once
+	ExtendLockedRates(ctx context.Context, in *ExtendLockedRatesRequest, opts ...grpc.CallOption) (*ExtendLockedRatesResponse, error)
+	// Release a locked rate before it expires (succeeds if it's already gone)
+	ReleaseLockedRate(ctx context.Context, in *ReleaseLockedRateRequest, opts ...grpc.CallOption) (*ReleaseLockedRateResponse, error)
+	// Get available corridors
+	GetCorridors(ctx context.Context, in *GetCorridorsRequest, opts ...grpc.CallOption) (*GetCorridorsResponse, error)
+	// Stream rate updates (for real-time display)
+	StreamRates(ctx context.Context, in *StreamRatesRequest, opts ...grpc.CallOption) (ExchangeRateService_StreamRatesClient, error)
+}
+
+type exchangeRateServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewExchangeRateServiceClient(cc grpc.ClientConnInterface) ExchangeRateServiceClient {
+	return &exchangeRateServiceClient{cc}
+}
+
+func (c *exchangeRateServiceClient) GetRate(ctx context.Context, in *GetRateRequest, opts ...grpc.CallOption) (*GetRateResponse, error) {
+	out := new(GetRateResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_GetRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*GetQuoteResponse, error) {
+	out := new(GetQuoteResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_GetQuote_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) LockRate(ctx context.Context, in *LockRateRequest, opts ...grpc.CallOption) (*LockRateResponse, error) {
+	out := new(LockRateResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_LockRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) GetLockedRate(ctx context.Context, in *GetLockedRateRequest, opts ...grpc.CallOption) (*GetLockedRateResponse, error) {
+	out := new(GetLockedRateResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_GetLockedRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) ExtendLockedRate(ctx context.Context, in *ExtendLockedRateRequest, opts ...grpc.CallOption) (*ExtendLockedRateResponse, error) {
+	out := new(ExtendLockedRateResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_ExtendLockedRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) ExtendLockedRates(ctx context.Context, in *ExtendLockedRatesRequest, opts ...grpc.CallOption) (*ExtendLockedRatesResponse, error) {
+	out := new(ExtendLockedRatesResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_ExtendLockedRates_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) ReleaseLockedRate(ctx context.Context, in *ReleaseLockedRateRequest, opts ...grpc.CallOption) (*ReleaseLockedRateResponse, error) {
+	out := new(ReleaseLockedRateResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_ReleaseLockedRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) GetCorridors(ctx context.Context, in *GetCorridorsRequest, opts ...grpc.CallOption) (*GetCorridorsResponse, error) {
+	out := new(GetCorridorsResponse)
+	err := c.cc.Invoke(ctx, ExchangeRateService_GetCorridors_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeRateServiceClient) StreamRates(ctx context.Context, in *StreamRatesRequest, opts ...grpc.CallOption) (ExchangeRateService_StreamRatesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ExchangeRateService_ServiceDesc.Streams[0], ExchangeRateService_StreamRates_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeRateServiceStreamRatesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ExchangeRateService_StreamRatesClient interface {
+	Recv() (*RateUpdate, error)
+	grpc.ClientStream
+}
+
+type exchangeRateServiceStreamRatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeRateServiceStreamRatesClient) Recv() (*RateUpdate, error) {
+	m := new(RateUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ExchangeRateServiceServer is the server API for ExchangeRateService service.
+// All implementations must embed UnimplementedExchangeRateServiceServer
+// for forward compatibility
+type ExchangeRateServiceServer interface {
+	// Get current rate
+	GetRate(context.Context, *GetRateRequest) (*GetRateResponse, error)
+	// Quote a transfer of a source amount, including fees
+	GetQuote(context.Context, *GetQuoteRequest) (*GetQuoteResponse, error)
+	// Lock a rate for a period (used during transfer confirmation)
+	LockRate(context.Context, *LockRateRequest) (*LockRateResponse, error)
+	// Get a previously locked rate
+	GetLockedRate(context.Context, *GetLockedRateRequest) (*GetLockedRateResponse, error)
+	// Extend a locked rate, up to the maximum lock duration
+	ExtendLockedRate(context.Context, *ExtendLockedRateRequest) (*ExtendLockedRateResponse, error)
+	// Extend several locked rates at once
+	ExtendLockedRates(context.Context, *ExtendLockedRatesRequest) (*ExtendLockedRatesResponse, error)
+	// Release a locked rate before it expires (succeeds if it's already gone)
+	ReleaseLockedRate(context.Context, *ReleaseLockedRateRequest) (*ReleaseLockedRateResponse, error)
+	// Get available corridors
+	GetCorridors(context.Context, *GetCorridorsRequest) (*GetCorridorsResponse, error)
+	// Stream rate updates (for real-time display)
+	StreamRates(*StreamRatesRequest, ExchangeRateService_StreamRatesServer) error
+	mustEmbedUnimplementedExchangeRateServiceServer()
+}
+
+// UnimplementedExchangeRateServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedExchangeRateServiceServer struct {
+}
+
+func (UnimplementedExchangeRateServiceServer) GetRate(context.Context, *GetRateRequest) (*GetRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRate not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) GetQuote(context.Context, *GetQuoteRequest) (*GetQuoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuote not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) LockRate(context.Context, *LockRateRequest) (*LockRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockRate not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) GetLockedRate(context.Context, *GetLockedRateRequest) (*GetLockedRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLockedRate not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) ExtendLockedRate(context.Context, *ExtendLockedRateRequest) (*ExtendLockedRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExtendLockedRate not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) ExtendLockedRates(context.Context, *ExtendLockedRatesRequest) (*ExtendLockedRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExtendLockedRates not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) ReleaseLockedRate(context.Context, *ReleaseLockedRateRequest) (*ReleaseLockedRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseLockedRate not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) GetCorridors(context.Context, *GetCorridorsRequest) (*GetCorridorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCorridors not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) StreamRates(*StreamRatesRequest, ExchangeRateService_StreamRatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRates not implemented")
+}
+func (UnimplementedExchangeRateServiceServer) mustEmbedUnimplementedExchangeRateServiceServer() {}
+
+// UnsafeExchangeRateServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ExchangeRateServiceServer will
+// result in compilation errors.
+type UnsafeExchangeRateServiceServer interface {
+	mustEmbedUnimplementedExchangeRateServiceServer()
+}
+
+func RegisterExchangeRateServiceServer(s grpc.ServiceRegistrar, srv ExchangeRateServiceServer) {
+	s.RegisterService(&ExchangeRateService_ServiceDesc, srv)
+}
+
+func _ExchangeRateService_GetRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).GetRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_GetRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).GetRate(ctx, req.(*GetRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_GetQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).GetQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_GetQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).GetQuote(ctx, req.(*GetQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_LockRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).LockRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_LockRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).LockRate(ctx, req.(*LockRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_GetLockedRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLockedRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).GetLockedRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_GetLockedRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).GetLockedRate(ctx, req.(*GetLockedRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_ExtendLockedRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendLockedRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).ExtendLockedRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_ExtendLockedRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).ExtendLockedRate(ctx, req.(*ExtendLockedRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_ExtendLockedRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendLockedRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).ExtendLockedRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_ExtendLockedRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).ExtendLockedRates(ctx, req.(*ExtendLockedRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_ReleaseLockedRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseLockedRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).ReleaseLockedRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_ReleaseLockedRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).ReleaseLockedRate(ctx, req.(*ReleaseLockedRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_GetCorridors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCorridorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeRateServiceServer).GetCorridors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeRateService_GetCorridors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeRateServiceServer).GetCorridors(ctx, req.(*GetCorridorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeRateService_StreamRates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeRateServiceServer).StreamRates(m, &exchangeRateServiceStreamRatesServer{stream})
+}
+
+type ExchangeRateService_StreamRatesServer interface {
+	Send(*RateUpdate) error
+	grpc.ServerStream
+}
+
+type exchangeRateServiceStreamRatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeRateServiceStreamRatesServer) Send(m *RateUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ExchangeRateService_ServiceDesc is the grpc.ServiceDesc for ExchangeRateService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ExchangeRateService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "movra.exchange.ExchangeRateService",
+	HandlerType: (*ExchangeRateServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRate",
+			Handler:    _ExchangeRateService_GetRate_Handler,
+		},
+		{
+			MethodName: "GetQuote",
+			Handler:    _ExchangeRateService_GetQuote_Handler,
+		},
+		{
+			MethodName: "LockRate",
+			Handler:    _ExchangeRateService_LockRate_Handler,
+		},
+		{
+			MethodName: "GetLockedRate",
+			Handler:    _ExchangeRateService_GetLockedRate_Handler,
+		},
+		{
+			MethodName: "ExtendLockedRate",
+			Handler:    _ExchangeRateService_ExtendLockedRate_Handler,
+		},
+		{
+			MethodName: "ExtendLockedRates",
+			Handler:    _ExchangeRateService_ExtendLockedRates_Handler,
+		},
+		{
+			MethodName: "ReleaseLockedRate",
+			Handler:    _ExchangeRateService_ReleaseLockedRate_Handler,
+		},
+		{
+			MethodName: "GetCorridors",
+			Handler:    _ExchangeRateService_GetCorridors_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRates",
+			Handler:       _ExchangeRateService_StreamRates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "exchange.proto",
+}
@@ -0,0 +1,58 @@
+package provider
+
+// currencyPriority lists currencies in market quoting convention order: in a
+// pair of two listed currencies the earlier one is the base (EUR/USD, USD/SGD).
+// Unlisted currencies rank after every listed one, alphabetically.
+var currencyPriority = map[string]int{
+	"EUR": 0,
+	"GBP": 1,
+	"AUD": 2,
+	"NZD": 3,
+	"USD": 4,
+	"CAD": 5,
+	"CHF": 6,
+	"SGD": 7,
+}
+
+// CanonicalPair returns the market-convention direction for a currency pair
+// and whether it is the inverse of the requested direction. Rates fetched and
+// cached in the canonical direction guarantee that A/B and B/A are exact
+// inverses of each other.
+func CanonicalPair(source, target string) (CurrencyPair, bool) {
+	source, target = NormalizeCurrency(source), NormalizeCurrency(target)
+	if isCanonicalBase(source, target) {
+		return CurrencyPair{Source: source, Target: target}, false
+	}
+	return CurrencyPair{Source: target, Target: source}, true
+}
+
+// isCanonicalBase reports whether a quotes before b by convention
+func isCanonicalBase(a, b string) bool {
+	pa, aListed := currencyPriority[a]
+	pb, bListed := currencyPriority[b]
+	switch {
+	case aListed && bListed:
+		return pa < pb
+	case aListed != bListed:
+		return aListed
+	default:
+		return a < b
+	}
+}
+
+// Inverse returns the rate for the opposite direction. Bid and ask swap sides
+// when inverted: buying the target of A/B is selling the target of B/A.
+func (r *Rate) Inverse() *Rate {
+	inverse := *r
+	inverse.SourceCurrency = r.TargetCurrency
+	inverse.TargetCurrency = r.SourceCurrency
+	inverse.MidRate = 1 / r.MidRate
+	inverse.Drift = 1/(1+r.Drift) - 1
+	if r.AskRate != 0 {
+		inverse.BidRate = 1 / r.AskRate
+	}
+	if r.BidRate != 0 {
+		inverse.AskRate = 1 / r.BidRate
+	}
+	return &inverse
+}
@@ -0,0 +1,25 @@
+package provider
+
+import "testing"
+
+func TestCanonicalPair(t *testing.T) {
+	tests := []struct {
+		source, target string
+		want           CurrencyPair
+		inverted       bool
+	}{
+		{"USD", "SGD", CurrencyPair{"USD", "SGD"}, false},
+		{"SGD", "USD", CurrencyPair{"USD", "SGD"}, true},
+		{"EUR", "USD", CurrencyPair{"EUR", "USD"}, false},
+		{"PHP", "SGD", CurrencyPair{"SGD", "PHP"}, true},
+		{"PHP", "INR", CurrencyPair{"INR", "PHP"}, true},
+	}
+
+	for _, tt := range tests {
+		got, inverted := CanonicalPair(tt.source, tt.target)
+		if got != tt.want || inverted != tt.inverted {
+			t.Errorf("CanonicalPair(%s, %s) = %v, %v; want %v, %v",
+				tt.source, tt.target, got, inverted, tt.want, tt.inverted)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// CompositeProvider is implemented by providers that delegate to other providers
+type CompositeProvider interface {
+	// Providers returns the underlying providers in priority order
+	Providers() []RateProvider
+
+	// Selected returns the index of the provider whose rate GetRate would
+	// return, given how each provider answered when queried on its own, or
+	// -1 if GetRate would fail
+	Selected(outcomes []ProviderOutcome) int
+}
+
+// ProviderOutcome is how one of a composite provider's providers answered
+type ProviderOutcome struct {
+	Latency time.Duration
+	Err     error
+}
+
+// HedgeRecorder is notified when a hedged request completes. slow is the
+// provider that exceeded the latency threshold and winner is the provider
+// whose rate was returned (empty if every provider failed).
+type HedgeRecorder func(slow, winner string)
+
+// ChainProvider tries a list of providers in order and returns the first
+// successful rate. It lets operators configure several rate sources with
+// a clear priority between them.
+type ChainProvider struct {
+	providers []RateProvider
+
+	// hedgeAfter starts the next provider in parallel when the current one
+	// hasn't answered within this duration (0 disables hedging)
+	hedgeAfter time.Duration
+	onHedge    HedgeRecorder
+}
+
+// NewChainProvider creates a provider that delegates to providers in order
+func NewChainProvider(providers ...RateProvider) *ChainProvider {
+	return &ChainProvider{
+		providers: providers,
+	}
+}
+
+// WithHedging enables latency-based failover: when a provider takes longer
+// than threshold without failing, the next provider is queried in parallel
+// and whichever succeeds first is returned. recorder may be nil.
+func (p *ChainProvider) WithHedging(threshold time.Duration, recorder HedgeRecorder) *ChainProvider {
+	p.hedgeAfter = threshold
+	p.onHedge = recorder
+	return p
+}
+
+// Name returns the provider name, including the chained provider names
+func (p *ChainProvider) Name() string {
+	names := make([]string, 0, len(p.providers))
+	for _, prov := range p.providers {
+		names = append(names, prov.Name())
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+// SupportsInverse returns true only if every chained provider supports inverse rates
+func (p *ChainProvider) SupportsInverse() bool {
+	for _, prov := range p.providers {
+		if !prov.SupportsInverse() {
+			return false
+		}
+	}
+	return len(p.providers) > 0
+}
+
+// HealthCheck passes if any chained provider is healthy, since GetRate falls
+// back to it
+func (p *ChainProvider) HealthCheck(ctx context.Context) error {
+	if len(p.providers) == 0 {
+		return ErrProviderUnavailable{Provider: p.Name(), Reason: "no providers configured"}
+	}
+
+	var lastErr error
+	for _, prov := range p.providers {
+		if lastErr = prov.HealthCheck(ctx); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// GetSupportedPairs returns every pair a chained provider supports, since
+// GetRate falls back through them. Providers that fail are skipped; the
+// last error is returned only if every provider fails.
+func (p *ChainProvider) GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error) {
+	if len(p.providers) == 0 {
+		return nil, ErrProviderUnavailable{Provider: p.Name(), Reason: "no providers configured"}
+	}
+
+	var pairs []CurrencyPair
+	seen := make(map[CurrencyPair]bool)
+	var lastErr error
+	answered := false
+	for _, prov := range p.providers {
+		provPairs, err := prov.GetSupportedPairs(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		answered = true
+		for _, pair := range provPairs {
+			if !seen[pair] {
+				seen[pair] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	if !answered {
+		return nil, lastErr
+	}
+	return pairs, nil
+}
+
+// Providers returns the chained providers in priority order
+func (p *ChainProvider) Providers() []RateProvider {
+	return p.providers
+}
+
+// GetRate returns the rate from the first provider that succeeds
+func (p *ChainProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	if len(p.providers) == 0 {
+		return nil, ErrProviderUnavailable{Provider: p.Name(), Reason: "no providers configured"}
+	}
+
+	if p.hedgeAfter > 0 && len(p.providers) > 1 {
+		return p.getRateHedged(ctx, source, target)
+	}
+
+	var lastErr error
+	for _, prov := range p.providers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		rate, err := prov.GetRate(ctx, source, target)
+		if err == nil {
+			return rate, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// Selected returns the first provider to succeed in priority order. With
+// hedging the outcomes are replayed against the hedge threshold, so a
+// provider that answers first can win over a slower one ahead of it.
+func (p *ChainProvider) Selected(outcomes []ProviderOutcome) int {
+	if p.hedgeAfter <= 0 || len(outcomes) < 2 {
+		for i, outcome := range outcomes {
+			if outcome.Err == nil {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// When each started provider was started, as getRateHedged starts them
+	startedAt := []time.Duration{0}
+	answered := make([]bool, len(outcomes))
+	hedgeAt := p.hedgeAfter
+	for {
+		// The outstanding request that answers next
+		first := -1
+		for i, at := range startedAt {
+			if !answered[i] && (first < 0 || at+outcomes[i].Latency < startedAt[first]+outcomes[first].Latency) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return -1
+		}
+		answeredAt := startedAt[first] + outcomes[first].Latency
+
+		if len(startedAt) < len(outcomes) && hedgeAt < answeredAt {
+			startedAt = append(startedAt, hedgeAt)
+			hedgeAt += p.hedgeAfter
+			continue
+		}
+		if outcomes[first].Err == nil {
+			return first
+		}
+		answered[first] = true
+		if len(startedAt) < len(outcomes) {
+			startedAt = append(startedAt, answeredAt)
+			hedgeAt = answeredAt + p.hedgeAfter
+		}
+	}
+}
+
+// getRateHedged queries providers in priority order, starting the next one
+// early whenever the outstanding requests exceed the hedge threshold. A
+// failure moves on to the next provider immediately, as in the plain chain.
+func (p *ChainProvider) getRateHedged(ctx context.Context, source, target string) (*Rate, error) {
+	type result struct {
+		provider string
+		rate     *Rate
+		err      error
+	}
+
+	// Outstanding requests are cancelled once a winner is found
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(p.providers))
+	next, pending := 0, 0
+	start := func() {
+		prov := p.providers[next]
+		next++
+		pending++
+		go func() {
+			rate, err := prov.GetRate(ctx, source, target)
+			results <- result{provider: prov.Name(), rate: rate, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(p.hedgeAfter)
+	defer timer.Stop()
+
+	var slow string
+	var lastErr error
+	start()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				p.recordHedge(slow, r.provider)
+				return r.rate, nil
+			}
+			lastErr = r.err
+			if next < len(p.providers) {
+				start()
+				resetTimer(timer, p.hedgeAfter)
+			} else if pending == 0 {
+				p.recordHedge(slow, "")
+				return nil, lastErr
+			}
+
+		case <-timer.C:
+			if next < len(p.providers) {
+				if slow == "" {
+					slow = p.providers[next-1].Name()
+				}
+				start()
+				timer.Reset(p.hedgeAfter)
+			}
+		}
+	}
+}
+
+// recordHedge reports a hedged request; requests that never hedged are ignored
+func (p *ChainProvider) recordHedge(slow, winner string) {
+	if slow != "" && p.onHedge != nil {
+		p.onHedge(slow, winner)
+	}
+}
+
+// resetTimer stops, drains and restarts a timer whose channel may have fired
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+// GetRates returns exchange rates for multiple currency pairs
+func (p *ChainProvider) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	rates := make([]*Rate, 0, len(pairs))
+	for _, pair := range pairs {
+		rate, err := p.GetRate(ctx, pair.Source, pair.Target)
+		if err != nil {
+			// Skip unsupported pairs, continue with others
+			if _, ok := err.(ErrUnsupportedPair); ok {
+				continue
+			}
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubProvider is a minimal RateProvider for testing composite providers
+type stubProvider struct {
+	name    string
+	midRate float64
+	err     error
+	delay   time.Duration
+	pairs   []CurrencyPair
+	calls   atomic.Int32
+}
+
+func (s *stubProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	s.calls.Add(1)
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &Rate{SourceCurrency: source, TargetCurrency: target, MidRate: s.midRate, Source: s.name}, nil
+}
+
+func (s *stubProvider) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	rates := make([]*Rate, 0, len(pairs))
+	for _, pair := range pairs {
+		rate, err := s.GetRate(ctx, pair.Source, pair.Target)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+func (s *stubProvider) Name() string {
+	return s.name
+}
+
+func (s *stubProvider) SupportsInverse() bool {
+	return true
+}
+
+func (s *stubProvider) HealthCheck(ctx context.Context) error {
+	return s.err
+}
+
+func (s *stubProvider) GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.pairs, nil
+}
+
+func TestChainProvider_UsesFirstSuccessfulProvider(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: ErrProviderUnavailable{Provider: "primary", Reason: "down"}}
+	secondary := &stubProvider{name: "secondary", midRate: 42.80}
+	chain := NewChainProvider(primary, secondary)
+
+	rate, err := chain.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.Source != "secondary" {
+		t.Errorf("expected rate from secondary, got %s", rate.Source)
+	}
+}
+
+func TestChainProvider_StopsAtFirstSuccess(t *testing.T) {
+	primary := &stubProvider{name: "primary", midRate: 42.50}
+	secondary := &stubProvider{name: "secondary", midRate: 42.80}
+	chain := NewChainProvider(primary, secondary)
+
+	if _, err := chain.GetRate(context.Background(), "SGD", "PHP"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if calls := secondary.calls.Load(); calls != 0 {
+		t.Errorf("expected secondary not to be called, got %d calls", calls)
+	}
+}
+
+func TestChainProvider_AllFail(t *testing.T) {
+	chain := NewChainProvider(
+		&stubProvider{name: "a", err: ErrProviderUnavailable{Provider: "a", Reason: "down"}},
+		&stubProvider{name: "b", err: ErrUnsupportedPair{Source: "SGD", Target: "XXX"}},
+	)
+
+	_, err := chain.GetRate(context.Background(), "SGD", "XXX")
+	if _, ok := err.(ErrUnsupportedPair); !ok {
+		t.Fatalf("expected last provider's error, got %v", err)
+	}
+}
+
+func TestChainProvider_HealthCheck(t *testing.T) {
+	down := &stubProvider{name: "a", err: ErrProviderUnavailable{Provider: "a", Reason: "down"}}
+	up := &stubProvider{name: "b", midRate: 1.34}
+
+	if err := NewChainProvider(down, up).HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected a chain with a healthy fallback to pass, got %v", err)
+	}
+	if err := NewChainProvider(down).HealthCheck(context.Background()); err == nil {
+		t.Error("expected a chain whose providers are all down to fail")
+	}
+	if err := NewChainProvider().HealthCheck(context.Background()); err == nil {
+		t.Error("expected an empty chain to fail")
+	}
+}
+
+// hedgeLog collects HedgeRecorder calls
+type hedgeLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *hedgeLog) record(slow, winner string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, slow+"->"+winner)
+}
+
+func TestChainProvider_HedgesSlowPrimary(t *testing.T) {
+	primary := &stubProvider{name: "primary", midRate: 42.50, delay: time.Second}
+	secondary := &stubProvider{name: "secondary", midRate: 42.80}
+	hedges := &hedgeLog{}
+	chain := NewChainProvider(primary, secondary).WithHedging(20*time.Millisecond, hedges.record)
+
+	start := time.Now()
+	rate, err := chain.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.Source != "secondary" {
+		t.Errorf("expected rate from secondary, got %s", rate.Source)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected hedged request to return before the slow primary, took %v", elapsed)
+	}
+	if len(hedges.entries) != 1 || hedges.entries[0] != "primary->secondary" {
+		t.Errorf("expected one hedge recorded from primary to secondary, got %v", hedges.entries)
+	}
+}
+
+func TestChainProvider_FastPrimaryIsNotHedged(t *testing.T) {
+	primary := &stubProvider{name: "primary", midRate: 42.50}
+	secondary := &stubProvider{name: "secondary", midRate: 42.80}
+	hedges := &hedgeLog{}
+	chain := NewChainProvider(primary, secondary).WithHedging(100*time.Millisecond, hedges.record)
+
+	rate, err := chain.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.Source != "primary" {
+		t.Errorf("expected rate from primary, got %s", rate.Source)
+	}
+	if calls := secondary.calls.Load(); calls != 0 {
+		t.Errorf("expected secondary not to be called, got %d calls", calls)
+	}
+	if len(hedges.entries) != 0 {
+		t.Errorf("expected no hedges recorded, got %v", hedges.entries)
+	}
+}
+
+func TestChainProvider_HedgedPrimaryCanStillWin(t *testing.T) {
+	primary := &stubProvider{name: "primary", midRate: 42.50, delay: 30 * time.Millisecond}
+	secondary := &stubProvider{name: "secondary", midRate: 42.80, delay: time.Second}
+	hedges := &hedgeLog{}
+	chain := NewChainProvider(primary, secondary).WithHedging(10*time.Millisecond, hedges.record)
+
+	rate, err := chain.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.Source != "primary" {
+		t.Errorf("expected rate from primary, got %s", rate.Source)
+	}
+	if len(hedges.entries) != 1 || hedges.entries[0] != "primary->primary" {
+		t.Errorf("expected hedge recorded with primary winning, got %v", hedges.entries)
+	}
+}
+
+func TestChainProvider_Selected(t *testing.T) {
+	down := ErrProviderUnavailable{Provider: "down", Reason: "down"}
+	ms := time.Millisecond
+
+	tests := []struct {
+		name       string
+		hedgeAfter time.Duration
+		outcomes   []ProviderOutcome
+		want       int
+	}{
+		{"first success without hedging", 0, []ProviderOutcome{{Latency: 500 * ms}, {Latency: ms}}, 0},
+		{"skips failures without hedging", 0, []ProviderOutcome{{Err: down}, {Latency: ms}}, 1},
+		{"fast primary", 10 * ms, []ProviderOutcome{{Latency: 5 * ms}, {Latency: ms}}, 0},
+		{"slow primary loses to hedge", 10 * ms, []ProviderOutcome{{Latency: 500 * ms}, {Latency: 5 * ms}}, 1},
+		{"hedged primary still answers first", 10 * ms, []ProviderOutcome{{Latency: 30 * ms}, {Latency: time.Second}}, 0},
+		{"failure starts next immediately", 10 * ms, []ProviderOutcome{{Latency: ms, Err: down}, {Latency: 5 * ms}, {Latency: ms}}, 1},
+		{"hedges down the chain", 10 * ms, []ProviderOutcome{{Latency: time.Second}, {Latency: time.Second}, {Latency: 5 * ms}}, 2},
+		{"every provider fails", 10 * ms, []ProviderOutcome{{Err: down}, {Latency: 20 * ms, Err: down}}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]RateProvider, len(tt.outcomes))
+			for i := range providers {
+				providers[i] = &stubProvider{}
+			}
+			chain := NewChainProvider(providers...)
+			if tt.hedgeAfter > 0 {
+				chain.WithHedging(tt.hedgeAfter, nil)
+			}
+			if got := chain.Selected(tt.outcomes); got != tt.want {
+				t.Errorf("expected provider %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChainProvider_GetSupportedPairsMergesProviders(t *testing.T) {
+	down := &stubProvider{name: "down", err: ErrProviderUnavailable{Provider: "down", Reason: "down"}}
+	primary := &stubProvider{name: "primary", pairs: []CurrencyPair{{"SGD", "PHP"}, {"SGD", "USD"}}}
+	secondary := &stubProvider{name: "secondary", pairs: []CurrencyPair{{"SGD", "USD"}, {"USD", "PHP"}}}
+
+	pairs, err := NewChainProvider(down, primary, secondary).GetSupportedPairs(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []CurrencyPair{{"SGD", "PHP"}, {"SGD", "USD"}, {"USD", "PHP"}}
+	if len(pairs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, pairs)
+	}
+	for i := range want {
+		if pairs[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, pairs)
+			break
+		}
+	}
+
+	if _, err := NewChainProvider(down).GetSupportedPairs(context.Background()); err == nil {
+		t.Error("expected an error when every provider fails")
+	}
+}
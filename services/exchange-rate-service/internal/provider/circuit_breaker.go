@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // calls go through to the provider
+	CircuitOpen     CircuitState = "open"      // calls fail fast
+	CircuitHalfOpen CircuitState = "half-open" // one probe call goes through to test recovery
+)
+
+// CircuitBreakerSettings configures a CircuitBreaker
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit
+	FailureThreshold int
+
+	// OpenDuration is how long the circuit fails fast before letting a probe
+	// call through
+	OpenDuration time.Duration
+
+	// CallTimeout bounds each call let through (0 leaves it unbounded). A
+	// call that times out fails with ErrProviderUnavailable and counts as a
+	// failure.
+	CallTimeout time.Duration
+
+	// OnReject is called with the provider name for every call failed fast
+	// while the circuit is open (may be nil)
+	OnReject func(provider string)
+}
+
+// CircuitBreaker stops calling a provider that keeps failing. After
+// FailureThreshold consecutive failures the circuit opens and calls fail fast
+// with ErrProviderUnavailable. Once OpenDuration has passed, a single probe
+// call is let through: success closes the circuit, failure opens it again.
+// Unsupported pairs and calls cancelled by the caller don't count as
+// failures; calls that time out, whether after CallTimeout or the caller's
+// deadline, do.
+type CircuitBreaker struct {
+	inner    RateProvider
+	settings CircuitBreakerSettings
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the circuit last opened
+	probing  bool      // a half-open probe is in flight
+}
+
+// NewCircuitBreaker wraps inner with a circuit breaker
+func NewCircuitBreaker(inner RateProvider, settings CircuitBreakerSettings) *CircuitBreaker {
+	return &CircuitBreaker{
+		inner:    inner,
+		settings: settings,
+		now:      time.Now,
+		state:    CircuitClosed,
+	}
+}
+
+// Name returns the wrapped provider's name
+func (b *CircuitBreaker) Name() string {
+	return b.inner.Name()
+}
+
+// SupportsInverse returns whether the wrapped provider supports inverse rates
+func (b *CircuitBreaker) SupportsInverse() bool {
+	return b.inner.SupportsInverse()
+}
+
+// State returns the current circuit state
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.settings.OpenDuration {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// GetRate returns the wrapped provider's rate unless the circuit is open
+func (b *CircuitBreaker) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	callCtx, cancel := b.callContext(ctx)
+	defer cancel()
+	rate, err := b.inner.GetRate(callCtx, source, target)
+	err = b.record(ctx, callCtx, err)
+	return rate, err
+}
+
+// GetRates returns the wrapped provider's rates unless the circuit is open
+func (b *CircuitBreaker) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	callCtx, cancel := b.callContext(ctx)
+	defer cancel()
+	rates, err := b.inner.GetRates(callCtx, pairs)
+	err = b.record(ctx, callCtx, err)
+	return rates, err
+}
+
+// GetSupportedPairs returns the wrapped provider's pairs. Listing pairs
+// doesn't fetch rates, so it neither waits on nor counts towards the circuit.
+func (b *CircuitBreaker) GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error) {
+	return b.inner.GetSupportedPairs(ctx)
+}
+
+// HealthCheck checks the wrapped provider unless the circuit is open. A
+// passing check while half-open closes the circuit.
+func (b *CircuitBreaker) HealthCheck(ctx context.Context) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	callCtx, cancel := b.callContext(ctx)
+	defer cancel()
+	return b.record(ctx, callCtx, b.inner.HealthCheck(callCtx))
+}
+
+// callContext returns the context a call let through runs with, bounded by
+// CallTimeout
+func (b *CircuitBreaker) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.settings.CallTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, b.settings.CallTimeout)
+}
+
+// allow returns ErrProviderUnavailable if the call must fail fast. When the
+// open period is over it lets the first caller through as the probe.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.settings.OpenDuration {
+		b.state = CircuitHalfOpen
+	}
+
+	switch {
+	case b.state == CircuitClosed:
+		return nil
+	case b.state == CircuitHalfOpen && !b.probing:
+		b.probing = true
+		return nil
+	}
+
+	if b.settings.OnReject != nil {
+		b.settings.OnReject(b.inner.Name())
+	}
+	return ErrProviderUnavailable{Provider: b.inner.Name(), Reason: "circuit open"}
+}
+
+// record updates the circuit with the outcome of a call that was let through,
+// returning the call's error. ctx is the caller's context and callCtx the one
+// the call ran with. Only a call the caller cancelled goes unrecorded; one
+// that outran CallTimeout fails as unavailable.
+func (b *CircuitBreaker) record(ctx, callCtx context.Context, err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+		err = ErrProviderUnavailable{Provider: b.inner.Name(), Reason: "timeout"}
+	}
+	if err != nil {
+		if _, ok := err.(ErrUnsupportedPair); ok || errors.Is(ctx.Err(), context.Canceled) {
+			// Not the provider's fault; a probe ends without a verdict
+			if b.state == CircuitHalfOpen {
+				b.probing = false
+			}
+			return err
+		}
+	}
+
+	switch {
+	case err == nil:
+		b.state = CircuitClosed
+		b.failures = 0
+		b.probing = false
+	case b.state == CircuitOpen:
+		// A call started before the circuit opened; it's already open
+	case b.state == CircuitHalfOpen:
+		b.open()
+	default:
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.open()
+		}
+	}
+	return err
+}
+
+// open opens the circuit. Callers hold mu.
+func (b *CircuitBreaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.probing = false
+}
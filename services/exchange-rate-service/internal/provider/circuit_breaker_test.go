@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestBreaker wraps inner in a breaker whose clock only moves when the
+// returned function is called
+func newTestBreaker(inner RateProvider, rejected *int) (*CircuitBreaker, func(time.Duration)) {
+	breaker := NewCircuitBreaker(inner, CircuitBreakerSettings{
+		FailureThreshold: 3,
+		OpenDuration:     30 * time.Second,
+		OnReject:         func(string) { *rejected++ },
+	})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	flaky := &stubProvider{name: "flaky", midRate: 42.5, err: ErrProviderUnavailable{Provider: "flaky", Reason: "down"}}
+	rejected := 0
+	breaker, advance := newTestBreaker(flaky, &rejected)
+	ctx := context.Background()
+
+	// Closed: failures below the threshold still reach the provider
+	for i := 0; i < 3; i++ {
+		if breaker.State() != CircuitClosed {
+			t.Fatalf("call %d: expected closed circuit, got %s", i, breaker.State())
+		}
+		if _, err := breaker.GetRate(ctx, "SGD", "PHP"); err == nil {
+			t.Fatalf("call %d: expected the provider's error", i)
+		}
+	}
+
+	// Open: calls fail fast without reaching the provider
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected open circuit after 3 failures, got %s", breaker.State())
+	}
+	_, err := breaker.GetRate(ctx, "SGD", "PHP")
+	if unavailable, ok := err.(ErrProviderUnavailable); !ok || unavailable.Reason != "circuit open" {
+		t.Fatalf("expected a circuit open error, got %v", err)
+	}
+	if calls := flaky.calls.Load(); calls != 3 {
+		t.Errorf("expected the open circuit not to call the provider, got %d calls", calls)
+	}
+	if rejected != 1 {
+		t.Errorf("expected 1 rejected call, got %d", rejected)
+	}
+
+	// Half-open: a failed probe opens the circuit again
+	advance(30 * time.Second)
+	if breaker.State() != CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit after the open duration, got %s", breaker.State())
+	}
+	if _, err := breaker.GetRate(ctx, "SGD", "PHP"); err != flaky.err {
+		t.Fatalf("expected the probe to reach the provider, got %v", err)
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected a failed probe to reopen the circuit, got %s", breaker.State())
+	}
+
+	// Half-open: a successful probe closes the circuit
+	flaky.err = nil
+	advance(30 * time.Second)
+	rate, err := breaker.GetRate(ctx, "SGD", "PHP")
+	if err != nil || rate.MidRate != 42.5 {
+		t.Fatalf("expected the probe to return the provider's rate, got %v, %v", rate, err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("expected a successful probe to close the circuit, got %s", breaker.State())
+	}
+	if _, err := breaker.GetRate(ctx, "SGD", "PHP"); err != nil {
+		t.Errorf("expected calls to go through once closed, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailureCount(t *testing.T) {
+	flaky := &stubProvider{name: "flaky", midRate: 42.5}
+	rejected := 0
+	breaker, _ := newTestBreaker(flaky, &rejected)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		flaky.err = ErrProviderUnavailable{Provider: "flaky", Reason: "down"}
+		breaker.GetRate(ctx, "SGD", "PHP")
+		breaker.GetRate(ctx, "SGD", "PHP")
+		flaky.err = nil
+		breaker.GetRate(ctx, "SGD", "PHP")
+	}
+
+	if breaker.State() != CircuitClosed {
+		t.Errorf("expected failures that aren't consecutive to keep the circuit closed, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_IgnoresCallerErrors(t *testing.T) {
+	stub := &stubProvider{name: "stub", err: ErrUnsupportedPair{Source: "SGD", Target: "XXX"}}
+	rejected := 0
+	breaker, _ := newTestBreaker(stub, &rejected)
+
+	for i := 0; i < 5; i++ {
+		breaker.GetRate(context.Background(), "SGD", "XXX")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stub.err = nil
+	stub.delay = time.Second
+	for i := 0; i < 5; i++ {
+		breaker.GetRate(ctx, "SGD", "PHP")
+	}
+
+	if breaker.State() != CircuitClosed {
+		t.Errorf("expected unsupported pairs and cancelled calls not to open the circuit, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_CountsTimeouts(t *testing.T) {
+	slow := &stubProvider{name: "slow", midRate: 42.5, delay: time.Second}
+	breaker := NewCircuitBreaker(slow, CircuitBreakerSettings{
+		FailureThreshold: 3,
+		OpenDuration:     30 * time.Second,
+		CallTimeout:      10 * time.Millisecond,
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := breaker.GetRate(context.Background(), "SGD", "PHP")
+		if unavailable, ok := err.(ErrProviderUnavailable); !ok || unavailable.Reason != "timeout" {
+			t.Fatalf("call %d: expected a timeout error, got %v", i, err)
+		}
+	}
+	if breaker.State() != CircuitOpen {
+		t.Errorf("expected calls timing out after CallTimeout to open the circuit, got %s", breaker.State())
+	}
+
+	// The caller's own deadline running out counts too
+	breaker = NewCircuitBreaker(slow, CircuitBreakerSettings{FailureThreshold: 3, OpenDuration: 30 * time.Second})
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		breaker.GetRate(ctx, "SGD", "PHP")
+		cancel()
+	}
+	if breaker.State() != CircuitOpen {
+		t.Errorf("expected calls past the caller's deadline to open the circuit, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_OneProbeAtATime(t *testing.T) {
+	stub := &stubProvider{name: "stub", err: ErrProviderUnavailable{Provider: "stub", Reason: "down"}}
+	rejected := 0
+	breaker, advance := newTestBreaker(stub, &rejected)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		breaker.GetRate(ctx, "SGD", "PHP")
+	}
+	advance(30 * time.Second)
+
+	// The first caller becomes the probe; others fail fast until it finishes
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	if err := breaker.allow(); err == nil {
+		t.Error("expected a second call to fail fast while the probe is in flight")
+	}
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+)
+
+// FallbackProvider serves rates from a primary provider and falls back to a
+// secondary one, typically the simulated provider, while the primary is
+// unavailable. Rates served by the secondary have their Source tagged so
+// callers can tell they came from the fallback.
+type FallbackProvider struct {
+	primary   RateProvider
+	secondary RateProvider
+}
+
+// NewFallbackProvider creates a provider that falls back from primary to secondary
+func NewFallbackProvider(primary, secondary RateProvider) *FallbackProvider {
+	return &FallbackProvider{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+// Name returns the provider name, including the primary and secondary names
+func (p *FallbackProvider) Name() string {
+	return "fallback(" + p.primary.Name() + "," + p.secondary.Name() + ")"
+}
+
+// SupportsInverse returns true only if both providers support inverse rates
+func (p *FallbackProvider) SupportsInverse() bool {
+	return p.primary.SupportsInverse() && p.secondary.SupportsInverse()
+}
+
+// HealthCheck passes if either provider is healthy, since GetRate falls back
+// to the secondary
+func (p *FallbackProvider) HealthCheck(ctx context.Context) error {
+	if err := p.primary.HealthCheck(ctx); err == nil {
+		return nil
+	}
+	return p.secondary.HealthCheck(ctx)
+}
+
+// Providers returns the primary and secondary providers
+func (p *FallbackProvider) Providers() []RateProvider {
+	return []RateProvider{p.primary, p.secondary}
+}
+
+// Selected returns the primary, or the secondary if the primary was
+// unavailable and the secondary succeeded
+func (p *FallbackProvider) Selected(outcomes []ProviderOutcome) int {
+	if len(outcomes) == 0 {
+		return -1
+	}
+	if outcomes[0].Err == nil {
+		return 0
+	}
+	if _, ok := outcomes[0].Err.(ErrProviderUnavailable); ok && len(outcomes) > 1 && outcomes[1].Err == nil {
+		return 1
+	}
+	return -1
+}
+
+// GetRate returns the primary's rate, or the secondary's if the primary is
+// unavailable
+func (p *FallbackProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	rate, err := p.primary.GetRate(ctx, source, target)
+	if !p.shouldFallBack(ctx, err) {
+		return rate, err
+	}
+
+	rate, err = p.secondary.GetRate(ctx, source, target)
+	if err != nil {
+		return nil, err
+	}
+	return p.tag(rate), nil
+}
+
+// GetRates returns the primary's rates, or the secondary's if the primary is
+// unavailable
+func (p *FallbackProvider) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	rates, err := p.primary.GetRates(ctx, pairs)
+	if !p.shouldFallBack(ctx, err) {
+		return rates, err
+	}
+
+	rates, err = p.secondary.GetRates(ctx, pairs)
+	if err != nil {
+		return nil, err
+	}
+	tagged := make([]*Rate, len(rates))
+	for i, rate := range rates {
+		tagged[i] = p.tag(rate)
+	}
+	return tagged, nil
+}
+
+// GetSupportedPairs returns the primary's pairs, or the secondary's if the
+// primary is unavailable
+func (p *FallbackProvider) GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error) {
+	pairs, err := p.primary.GetSupportedPairs(ctx)
+	if !p.shouldFallBack(ctx, err) {
+		return pairs, err
+	}
+	return p.secondary.GetSupportedPairs(ctx)
+}
+
+// shouldFallBack reports whether err means the primary is unavailable. Other
+// errors, such as an unsupported pair, are returned to the caller as is.
+func (p *FallbackProvider) shouldFallBack(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	_, ok := err.(ErrProviderUnavailable)
+	return ok
+}
+
+// tag returns a copy of a secondary rate with its Source marked as a fallback
+func (p *FallbackProvider) tag(rate *Rate) *Rate {
+	tagged := *rate
+	tagged.Source = rate.Source + " (fallback)"
+	return &tagged
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFallbackProvider_FallsBackWhenPrimaryUnavailable(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: ErrProviderUnavailable{Provider: "primary", Reason: "down"}}
+	secondary := &stubProvider{name: "simulated", midRate: 42.80}
+	fallback := NewFallbackProvider(primary, secondary)
+
+	rate, err := fallback.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.MidRate != 42.80 {
+		t.Errorf("expected the secondary's rate 42.80, got %f", rate.MidRate)
+	}
+	if rate.Source != "simulated (fallback)" {
+		t.Errorf("expected the rate to be tagged as a fallback, got %s", rate.Source)
+	}
+	if calls := primary.calls.Load(); calls != 1 {
+		t.Errorf("expected the primary to be tried once, got %d calls", calls)
+	}
+}
+
+func TestFallbackProvider_PrimarySucceeds(t *testing.T) {
+	primary := &stubProvider{name: "primary", midRate: 42.50}
+	secondary := &stubProvider{name: "simulated", midRate: 42.80}
+	fallback := NewFallbackProvider(primary, secondary)
+
+	rate, err := fallback.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.Source != "primary" {
+		t.Errorf("expected rate from primary, got %s", rate.Source)
+	}
+	if calls := secondary.calls.Load(); calls != 0 {
+		t.Errorf("expected secondary not to be called, got %d calls", calls)
+	}
+}
+
+func TestFallbackProvider_DoesNotFallBackOnUnsupportedPair(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: ErrUnsupportedPair{Source: "SGD", Target: "XXX"}}
+	secondary := &stubProvider{name: "simulated", midRate: 42.80}
+	fallback := NewFallbackProvider(primary, secondary)
+
+	_, err := fallback.GetRate(context.Background(), "SGD", "XXX")
+	if _, ok := err.(ErrUnsupportedPair); !ok {
+		t.Fatalf("expected ErrUnsupportedPair, got %v", err)
+	}
+	if calls := secondary.calls.Load(); calls != 0 {
+		t.Errorf("expected secondary not to be called, got %d calls", calls)
+	}
+}
+
+func TestFallbackProvider_Selected(t *testing.T) {
+	fallback := NewFallbackProvider(&stubProvider{name: "primary"}, &stubProvider{name: "simulated"})
+	down := ErrProviderUnavailable{Provider: "primary", Reason: "down"}
+
+	tests := []struct {
+		name     string
+		outcomes []ProviderOutcome
+		want     int
+	}{
+		{"primary succeeds", []ProviderOutcome{{}, {}}, 0},
+		{"primary unavailable", []ProviderOutcome{{Err: down}, {}}, 1},
+		{"primary rejects the pair", []ProviderOutcome{{Err: ErrUnsupportedPair{Source: "SGD", Target: "XXX"}}, {}}, -1},
+		{"both unavailable", []ProviderOutcome{{Err: down}, {Err: down}}, -1},
+	}
+
+	for _, tt := range tests {
+		if got := fallback.Selected(tt.outcomes); got != tt.want {
+			t.Errorf("%s: expected provider %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestFallbackProvider_GetRatesTagsFallbackRates(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: ErrProviderUnavailable{Provider: "primary", Reason: "down"}}
+	secondary := &stubProvider{name: "simulated", midRate: 42.80}
+	fallback := NewFallbackProvider(primary, secondary)
+
+	rates, err := fallback.GetRates(context.Background(), []CurrencyPair{
+		{Source: "SGD", Target: "PHP"},
+		{Source: "USD", Target: "SGD"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates, got %d", len(rates))
+	}
+	for _, rate := range rates {
+		if rate.Source != "simulated (fallback)" {
+			t.Errorf("%s/%s: expected the rate to be tagged as a fallback, got %s", rate.SourceCurrency, rate.TargetCurrency, rate.Source)
+		}
+	}
+}
+
+func TestFallbackProvider_SupportsInverse(t *testing.T) {
+	inverse := &stubProvider{name: "primary"}
+	noInverse := &noInverseProvider{stubProvider{name: "secondary"}}
+
+	if !NewFallbackProvider(inverse, &stubProvider{name: "secondary"}).SupportsInverse() {
+		t.Error("expected inverse support when both providers support it")
+	}
+	if NewFallbackProvider(inverse, noInverse).SupportsInverse() {
+		t.Error("expected no inverse support when the secondary lacks it")
+	}
+	if NewFallbackProvider(noInverse, inverse).SupportsInverse() {
+		t.Error("expected no inverse support when the primary lacks it")
+	}
+}
+
+// noInverseProvider is a stubProvider that doesn't support inverse rates
+type noInverseProvider struct {
+	stubProvider
+}
+
+func (p *noInverseProvider) SupportsInverse() bool {
+	return false
+}
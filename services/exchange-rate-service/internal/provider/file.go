@@ -0,0 +1,268 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// FileProvider serves fixed mid rates read from a JSON or YAML file, so demos
+// and CI runs can pin exact rates without code changes. The file maps pairs,
+// keyed "SGD/PHP", to mid rates:
+//
+//	SGD/PHP: 42.50
+//	USD/SGD: 1.3423
+//
+// Pairs missing from the file are derived from their inverse, or crossed
+// through USD, as the simulated provider does. The file is watched and
+// reloaded when it changes; a change that fails to parse is reported to the
+// reload hook and the previous rates are kept.
+type FileProvider struct {
+	path     string
+	spread   float64
+	validity time.Duration
+	watcher  *fsnotify.Watcher
+
+	mu         sync.RWMutex
+	rates      map[string]float64
+	loadedAt   time.Time
+	onReload   func(err error)
+	closeOnce  sync.Once
+	watchEnded chan struct{}
+}
+
+// NewFileProvider loads the rates in path and starts watching it for changes
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{
+		path:       path,
+		spread:     0.005,
+		validity:   30 * time.Second,
+		watchEnded: make(chan struct{}),
+	}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch rate file: %w", err)
+	}
+	// Watch the directory rather than the file, so the file being replaced
+	// by a rename, as editors and config management do, is still seen
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch rate file: %w", err)
+	}
+	p.watcher = watcher
+	go p.watch()
+
+	return p, nil
+}
+
+// WithSpread sets the spread applied around the file's mid rates (default 0.5%)
+func (p *FileProvider) WithSpread(spread float64) *FileProvider {
+	p.spread = spread
+	return p
+}
+
+// WithRateValidity sets how long served rates are valid (default 30 seconds)
+func (p *FileProvider) WithRateValidity(validity time.Duration) *FileProvider {
+	p.validity = validity
+	return p
+}
+
+// WithReloadHook sets a function called after each reload triggered by the
+// file changing, with the error if the new contents were rejected
+func (p *FileProvider) WithReloadHook(hook func(err error)) *FileProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onReload = hook
+	return p
+}
+
+// Name returns the provider name
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// SupportsInverse returns true - pairs missing from the file are inverted
+func (p *FileProvider) SupportsInverse() bool {
+	return true
+}
+
+// HealthCheck fetches the health check pair
+func (p *FileProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.GetRate(ctx, HealthCheckPair.Source, HealthCheckPair.Target)
+	return err
+}
+
+// GetRate returns the file's rate for a currency pair
+func (p *FileProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	source, target = NormalizeCurrency(source), NormalizeCurrency(target)
+
+	p.mu.RLock()
+	midRate, ok := p.midRate(source, target)
+	p.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnsupportedPair{Source: source, Target: target}
+	}
+
+	now := time.Now()
+	return &Rate{
+		SourceCurrency: source,
+		TargetCurrency: target,
+		MidRate:        midRate,
+		BidRate:        midRate * (1 - p.spread/2),
+		AskRate:        midRate * (1 + p.spread/2),
+		Spread:         p.spread * 100, // Convert to percentage
+		Source:         p.Name(),
+		FetchedAt:      now,
+		ValidUntil:     now.Add(p.validity),
+	}, nil
+}
+
+// GetRates returns the file's rates for multiple currency pairs, skipping
+// unsupported ones
+func (p *FileProvider) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	rates := make([]*Rate, 0, len(pairs))
+	for _, pair := range pairs {
+		rate, err := p.GetRate(ctx, pair.Source, pair.Target)
+		if err != nil {
+			if _, ok := err.(ErrUnsupportedPair); ok {
+				continue
+			}
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// GetSupportedPairs returns the pairs listed in the file
+func (p *FileProvider) GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return pairsOf(p.rates), nil
+}
+
+// LoadedAt returns when the rates being served were read from the file
+func (p *FileProvider) LoadedAt() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loadedAt
+}
+
+// Reload reads the file again, replacing the rates being served. If the file
+// can't be read or is invalid the previous rates are kept.
+func (p *FileProvider) Reload() error {
+	rates, err := readRateFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rates = rates
+	p.loadedAt = time.Now()
+	return nil
+}
+
+// Close stops watching the file
+func (p *FileProvider) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		err = p.watcher.Close()
+		<-p.watchEnded
+	})
+	return err
+}
+
+// watch reloads the rates whenever the file is written or created, including
+// by being renamed into place, until the watcher is closed
+func (p *FileProvider) watch() {
+	defer close(p.watchEnded)
+	name := filepath.Clean(p.path)
+	for {
+		select {
+		case event, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != name || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			err := p.Reload()
+			p.mu.RLock()
+			hook := p.onReload
+			p.mu.RUnlock()
+			if hook != nil {
+				hook(err)
+			}
+		case _, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// midRate returns the mid rate for a pair, derived from its inverse or
+// crossed through USD when the file doesn't list it. Callers must hold p.mu.
+func (p *FileProvider) midRate(source, target string) (float64, bool) {
+	if rate, ok := p.rates[source+"/"+target]; ok {
+		return rate, true
+	}
+	if rate, ok := p.rates[target+"/"+source]; ok {
+		return 1 / rate, true
+	}
+	if source != "USD" && target != "USD" {
+		sourceToUSD, sourceOK := p.midRate(source, "USD")
+		usdToTarget, targetOK := p.midRate("USD", target)
+		if sourceOK && targetOK {
+			return sourceToUSD * usdToTarget, true
+		}
+	}
+	return 0, false
+}
+
+// readRateFile parses a rate file. YAML is a superset of JSON, so one parser
+// reads both.
+func readRateFile(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate file: %w", err)
+	}
+
+	var raw map[string]float64
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse rate file %s: %w", path, err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("rate file %s has no rates", path)
+	}
+
+	rates := make(map[string]float64, len(raw))
+	for pair, rate := range raw {
+		source, target, ok := strings.Cut(pair, "/")
+		source, target = NormalizeCurrency(source), NormalizeCurrency(target)
+		if !ok || source == "" || target == "" {
+			return nil, fmt.Errorf("rate file %s: invalid pair %q (expected 'XXX/YYY')", path, pair)
+		}
+		if !(rate > 0) || math.IsInf(rate, 1) {
+			return nil, fmt.Errorf("rate file %s: rate for %s must be positive, got %v", path, pair, rate)
+		}
+		rates[source+"/"+target] = rate
+	}
+	return rates, nil
+}
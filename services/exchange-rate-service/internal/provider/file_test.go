@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRateFile writes contents to the rate file at path
+func writeRateFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write rate file: %v", err)
+	}
+}
+
+func newTestFileProvider(t *testing.T, name, contents string) (*FileProvider, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	writeRateFile(t, path, contents)
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("failed to create file provider: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p, path
+}
+
+func TestFileProvider_ServesRatesFromFile(t *testing.T) {
+	p, _ := newTestFileProvider(t, "rates.yaml", "SGD/PHP: 42.50\nUSD/SGD: 1.25\nUSD/INR: 80\n")
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		source, target string
+		want           float64
+	}{
+		{"SGD", "PHP", 42.50},
+		{"PHP", "SGD", 1 / 42.50}, // inverse
+		{"SGD", "INR", 64},        // crossed through USD
+	} {
+		rate, err := p.GetRate(ctx, tc.source, tc.target)
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", tc.source, tc.target, err)
+		}
+		if math.Abs(rate.MidRate-tc.want) > 1e-9 {
+			t.Errorf("%s/%s: expected mid rate %v, got %v", tc.source, tc.target, tc.want, rate.MidRate)
+		}
+		if rate.Source != "file" || !(rate.BidRate < rate.MidRate && rate.MidRate < rate.AskRate) {
+			t.Errorf("%s/%s: unexpected rate %+v", tc.source, tc.target, rate)
+		}
+	}
+
+	if _, err := p.GetRate(ctx, "SGD", "XXX"); err == nil {
+		t.Error("expected an error for a pair the file can't derive")
+	} else if _, ok := err.(ErrUnsupportedPair); !ok {
+		t.Errorf("expected ErrUnsupportedPair, got %v", err)
+	}
+}
+
+func TestFileProvider_GetSupportedPairs(t *testing.T) {
+	p, _ := newTestFileProvider(t, "rates.yaml", "usd/sgd: 1.25\nSGD/PHP: 42.50\n")
+
+	pairs, err := p.GetSupportedPairs(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []CurrencyPair{{"SGD", "PHP"}, {"USD", "SGD"}}
+	if len(pairs) != len(want) || pairs[0] != want[0] || pairs[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, pairs)
+	}
+}
+
+func TestFileProvider_ReadsJSON(t *testing.T) {
+	p, _ := newTestFileProvider(t, "rates.json", `{"SGD/PHP": 41.75}`)
+
+	rate, err := p.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate.MidRate != 41.75 {
+		t.Errorf("expected mid rate 41.75, got %v", rate.MidRate)
+	}
+}
+
+func TestFileProvider_RejectsInvalidFile(t *testing.T) {
+	for name, contents := range map[string]string{
+		"empty":         "",
+		"bad pair":      "SGDPHP: 42.5\n",
+		"negative rate": "SGD/PHP: -1\n",
+		"not a number":  "SGD/PHP: lots\n",
+	} {
+		path := filepath.Join(t.TempDir(), "rates.yaml")
+		writeRateFile(t, path, contents)
+		if p, err := NewFileProvider(path); err == nil {
+			p.Close()
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFileProvider_ReloadsWhenFileChanges(t *testing.T) {
+	p, path := newTestFileProvider(t, "rates.yaml", "SGD/PHP: 42.50\n")
+	reloads := make(chan error, 10)
+	p.WithReloadHook(func(err error) { reloads <- err })
+	ctx := context.Background()
+
+	writeRateFile(t, path, "SGD/PHP: 43.10\n")
+	waitForMidRate(t, p, 43.10)
+
+	// An invalid change is reported and the previous rates are kept
+	writeRateFile(t, path, "SGD/PHP: not-a-rate\n")
+	deadline := time.After(5 * time.Second)
+	for rejected := false; !rejected; {
+		select {
+		case err := <-reloads:
+			rejected = err != nil
+		case <-deadline:
+			t.Fatal("expected the invalid change to be reported")
+		}
+	}
+	rate, err := p.GetRate(ctx, "SGD", "PHP")
+	if err != nil || rate.MidRate != 43.10 {
+		t.Errorf("expected the previous rate 43.10 to be kept, got %v, %v", rate, err)
+	}
+}
+
+func TestFileProvider_ReloadsWhenFileReplaced(t *testing.T) {
+	p, path := newTestFileProvider(t, "rates.yaml", "SGD/PHP: 42.50\n")
+
+	// Editors and config management replace files by renaming over them
+	replacement := filepath.Join(filepath.Dir(path), "rates.yaml.tmp")
+	writeRateFile(t, replacement, "SGD/PHP: 44.00\n")
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatalf("failed to replace rate file: %v", err)
+	}
+
+	waitForMidRate(t, p, 44.00)
+}
+
+// waitForMidRate waits for p to serve want as the SGD/PHP mid rate
+func waitForMidRate(t *testing.T, p *FileProvider, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rate, err := p.GetRate(context.Background(), "SGD", "PHP")
+		if err == nil && rate.MidRate == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected mid rate %v after reload, got %v, %v", want, rate, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
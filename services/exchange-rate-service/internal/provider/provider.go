@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -11,6 +13,12 @@ type CurrencyPair struct {
 	Target string
 }
 
+// NormalizeCurrency returns a currency code in canonical form, trimmed and
+// uppercase, so "sgd" and "SGD" name the same currency
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
 // Rate represents a raw exchange rate from a provider
 type Rate struct {
 	SourceCurrency string
@@ -19,6 +27,7 @@ type Rate struct {
 	BidRate        float64   // Rate to buy target currency (what we pay)
 	AskRate        float64   // Rate to sell target currency (what customer pays)
 	Spread         float64   // Spread percentage
+	Drift          float64   // Simulated drift from the base rate as a fraction (0 for real providers)
 	Source         string    // Provider name
 	FetchedAt      time.Time // When the rate was fetched
 	ValidUntil     time.Time // When the rate expires
@@ -39,8 +48,37 @@ type RateProvider interface {
 
 	// SupportsInverse returns true if the provider can calculate inverse rates
 	SupportsInverse() bool
+
+	// HealthCheck returns an error if the provider can't serve rates
+	HealthCheck(ctx context.Context) error
+
+	// GetSupportedPairs returns the currency pairs the provider quotes
+	// directly. Providers that support inverse rates also serve each pair
+	// the other way round.
+	GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error)
 }
 
+// pairsOf returns the pairs of rates keyed "SGD/PHP", sorted
+func pairsOf(rates map[string]float64) []CurrencyPair {
+	pairs := make([]CurrencyPair, 0, len(rates))
+	for key := range rates {
+		if source, target, ok := strings.Cut(key, "/"); ok && source != "" && target != "" {
+			pairs = append(pairs, CurrencyPair{Source: source, Target: target})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Source != pairs[j].Source {
+			return pairs[i].Source < pairs[j].Source
+		}
+		return pairs[i].Target < pairs[j].Target
+	})
+	return pairs
+}
+
+// HealthCheckPair is the pair providers fetch to check they can serve rates.
+// Every provider quotes it, so fetching it is cheap and always supported.
+var HealthCheckPair = CurrencyPair{Source: "USD", Target: "SGD"}
+
 // ProviderConfig holds common configuration for providers
 type ProviderConfig struct {
 	// DefaultSpread is the default spread percentage to apply
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryingProvider retries transient failures of the provider it wraps.
+// Only ErrProviderUnavailable is retried; any other error, such as an
+// unsupported pair, is returned straight away. Attempts back off
+// exponentially from baseDelay with jitter, and no attempt is started that
+// the context's deadline wouldn't leave time for.
+type RetryingProvider struct {
+	RateProvider
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// NewRetrying wraps inner so each call is tried up to maxAttempts times
+func NewRetrying(inner RateProvider, maxAttempts int, baseDelay time.Duration) *RetryingProvider {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &RetryingProvider{
+		RateProvider: inner,
+		maxAttempts:  maxAttempts,
+		baseDelay:    baseDelay,
+	}
+}
+
+// GetRate returns the wrapped provider's rate, retrying transient failures
+func (p *RetryingProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	var rate *Rate
+	err := p.retry(ctx, func() error {
+		var err error
+		rate, err = p.RateProvider.GetRate(ctx, source, target)
+		return err
+	})
+	return rate, err
+}
+
+// GetRates returns the wrapped provider's rates, retrying transient failures
+func (p *RetryingProvider) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	var rates []*Rate
+	err := p.retry(ctx, func() error {
+		var err error
+		rates, err = p.RateProvider.GetRates(ctx, pairs)
+		return err
+	})
+	return rates, err
+}
+
+// retry calls fn until it succeeds, fails with a non-transient error or runs
+// out of attempts, and returns its last error
+func (p *RetryingProvider) retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if _, ok := err.(ErrProviderUnavailable); !ok || attempt >= p.maxAttempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before the attempt after attempt: baseDelay
+// doubled per attempt, with the upper half jittered so callers that failed
+// together don't retry together
+func (p *RetryingProvider) backoff(attempt int) time.Duration {
+	delay := p.baseDelay << (attempt - 1)
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingProvider fails its first failures calls with err, then succeeds
+type failingProvider struct {
+	stubProvider
+	failures int
+	failErr  error
+}
+
+func (f *failingProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	if int(f.calls.Add(1)) <= f.failures {
+		return nil, f.failErr
+	}
+	return &Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.5, Source: f.name}, nil
+}
+
+func TestRetrying_RetriesTransientErrors(t *testing.T) {
+	inner := &failingProvider{
+		stubProvider: stubProvider{name: "flaky"},
+		failures:     2,
+		failErr:      ErrProviderUnavailable{Provider: "flaky", Reason: "down"},
+	}
+	retrying := NewRetrying(inner, 3, time.Millisecond)
+
+	rate, err := retrying.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if rate.MidRate != 42.5 {
+		t.Errorf("expected rate 42.5, got %f", rate.MidRate)
+	}
+	if calls := inner.calls.Load(); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetrying_GivesUpWithLastError(t *testing.T) {
+	down := ErrProviderUnavailable{Provider: "flaky", Reason: "down"}
+	inner := &failingProvider{stubProvider: stubProvider{name: "flaky"}, failures: 10, failErr: down}
+	retrying := NewRetrying(inner, 4, time.Millisecond)
+
+	_, err := retrying.GetRate(context.Background(), "SGD", "PHP")
+	if err != down {
+		t.Fatalf("expected the last provider error, got %v", err)
+	}
+	if calls := inner.calls.Load(); calls != 4 {
+		t.Errorf("expected 4 attempts, got %d", calls)
+	}
+}
+
+func TestRetrying_DoesNotRetryUnsupportedPair(t *testing.T) {
+	inner := &stubProvider{name: "stub", err: ErrUnsupportedPair{Source: "SGD", Target: "XXX"}}
+	retrying := NewRetrying(inner, 5, time.Millisecond)
+
+	_, err := retrying.GetRate(context.Background(), "SGD", "XXX")
+	if _, ok := err.(ErrUnsupportedPair); !ok {
+		t.Fatalf("expected ErrUnsupportedPair, got %v", err)
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRetrying_DoesNotRetryOtherErrors(t *testing.T) {
+	inner := &stubProvider{name: "stub", err: errors.New("malformed response")}
+	retrying := NewRetrying(inner, 5, time.Millisecond)
+
+	if _, err := retrying.GetRate(context.Background(), "SGD", "PHP"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRetrying_RespectsContextDeadline(t *testing.T) {
+	down := ErrProviderUnavailable{Provider: "flaky", Reason: "down"}
+	inner := &failingProvider{stubProvider: stubProvider{name: "flaky"}, failures: 10, failErr: down}
+	retrying := NewRetrying(inner, 5, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := retrying.GetRate(ctx, "SGD", "PHP")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected no backoff past the deadline, took %v", elapsed)
+	}
+	if err != down {
+		t.Errorf("expected the last provider error, got %v", err)
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("expected no retry the deadline leaves no time for, got %d attempts", calls)
+	}
+}
+
+func TestRetrying_BackoffGrowsExponentially(t *testing.T) {
+	retrying := NewRetrying(&stubProvider{}, 5, 100*time.Millisecond)
+
+	for attempt, max := 1, 100*time.Millisecond; attempt <= 4; attempt, max = attempt+1, max*2 {
+		for i := 0; i < 20; i++ {
+			if delay := retrying.backoff(attempt); delay < max/2 || delay > max {
+				t.Fatalf("attempt %d: expected a delay in [%v, %v], got %v", attempt, max/2, max, delay)
+			}
+		}
+	}
+}
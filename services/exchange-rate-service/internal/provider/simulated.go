@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"math"
 	"math/rand"
 	"sync"
 	"time"
@@ -46,6 +47,15 @@ type SimulatedProviderConfig struct {
 	// BaseSpread is the base spread percentage (default 0.5%)
 	BaseSpread float64
 
+	// PairSpreads overrides BaseSpread for specific pairs, keyed "SGD/IDR".
+	// An override also applies to the inverse pair.
+	PairSpreads map[string]float64
+
+	// UnavailablePairs are pairs, keyed "SGD/IDR", that always fail with
+	// ErrProviderUnavailable, so outages can be reproduced in tests and demos.
+	// Unlike PairSpreads, they don't apply to the inverse pair.
+	UnavailablePairs []string
+
 	// MaxDrift is the maximum random drift percentage (default 2%)
 	MaxDrift float64
 
@@ -55,6 +65,11 @@ type SimulatedProviderConfig struct {
 	// DriftInterval is how often rates drift (default 5 seconds)
 	DriftInterval time.Duration
 
+	// SmoothDrift moves drift toward each new random target over the drift
+	// interval instead of snapping to it, so rates follow a continuous random
+	// walk within MaxDrift (default false)
+	SmoothDrift bool
+
 	// Seed for random number generator (0 for current time)
 	Seed int64
 }
@@ -77,6 +92,8 @@ type SimulatedProvider struct {
 	rng          *rand.Rand
 	mu           sync.RWMutex
 	currentDrift map[string]float64 // Current drift per pair
+	driftFrom    map[string]float64 // Drift per pair when the interval began (smooth drift)
+	driftTarget  map[string]float64 // Drift per pair at the end of the interval (smooth drift)
 	lastDrift    time.Time          // When drift was last updated
 }
 
@@ -91,6 +108,8 @@ func NewSimulatedProvider(config SimulatedProviderConfig) *SimulatedProvider {
 		config:       config,
 		rng:          rand.New(rand.NewSource(seed)),
 		currentDrift: make(map[string]float64),
+		driftFrom:    make(map[string]float64),
+		driftTarget:  make(map[string]float64),
 		lastDrift:    time.Time{},
 	}
 }
@@ -105,21 +124,32 @@ func (p *SimulatedProvider) SupportsInverse() bool {
 	return true
 }
 
+// HealthCheck fetches the health check pair
+func (p *SimulatedProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.GetRate(ctx, HealthCheckPair.Source, HealthCheckPair.Target)
+	return err
+}
+
 // GetRate returns the exchange rate for a single currency pair
 func (p *SimulatedProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
+	source, target = NormalizeCurrency(source), NormalizeCurrency(target)
+
+	if p.isUnavailable(source, target) {
+		return nil, ErrProviderUnavailable{Provider: p.Name(), Reason: "simulated outage for " + source + "/" + target}
+	}
 
 	p.updateDriftIfNeeded()
 
-	midRate, err := p.getMidRate(source, target)
+	midRate, drift, err := p.getMidRate(source, target)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
-	spread := p.config.BaseSpread
+	spread := p.spreadFor(source, target)
 
 	// Calculate bid/ask with spread
 	// Bid = rate to buy target (lower)
@@ -134,6 +164,7 @@ func (p *SimulatedProvider) GetRate(ctx context.Context, source, target string)
 		BidRate:        bidRate,
 		AskRate:        askRate,
 		Spread:         spread * 100, // Convert to percentage
+		Drift:          drift,
 		Source:         p.Name(),
 		FetchedAt:      now,
 		ValidUntil:     now.Add(p.config.RateValidityDuration),
@@ -162,8 +193,31 @@ func (p *SimulatedProvider) GetRates(ctx context.Context, pairs []CurrencyPair)
 	return rates, nil
 }
 
-// getMidRate returns the mid-market rate with drift applied
-func (p *SimulatedProvider) getMidRate(source, target string) (float64, error) {
+// isUnavailable reports whether the pair is configured to fail
+func (p *SimulatedProvider) isUnavailable(source, target string) bool {
+	key := source + "/" + target
+	for _, pair := range p.config.UnavailablePairs {
+		if pair == key {
+			return true
+		}
+	}
+	return false
+}
+
+// spreadFor returns the pair's spread override, falling back to BaseSpread
+func (p *SimulatedProvider) spreadFor(source, target string) float64 {
+	if spread, ok := p.config.PairSpreads[source+"/"+target]; ok {
+		return spread
+	}
+	if spread, ok := p.config.PairSpreads[target+"/"+source]; ok {
+		return spread
+	}
+	return p.config.BaseSpread
+}
+
+// getMidRate returns the mid-market rate with drift applied, and the drift as
+// a fraction of the undrifted rate
+func (p *SimulatedProvider) getMidRate(source, target string) (float64, float64, error) {
 	directKey := source + "/" + target
 	inverseKey := target + "/" + source
 
@@ -173,7 +227,7 @@ func (p *SimulatedProvider) getMidRate(source, target string) (float64, error) {
 
 	// Check direct rate
 	if baseRate, ok := baseRates[directKey]; ok {
-		return baseRate * (1 + drift), nil
+		return baseRate * (1 + drift), drift, nil
 	}
 
 	// Check inverse rate
@@ -182,19 +236,19 @@ func (p *SimulatedProvider) getMidRate(source, target string) (float64, error) {
 		p.mu.RLock()
 		inverseDrift := p.currentDrift[inverseKey]
 		p.mu.RUnlock()
-		return 1.0 / (baseRate * (1 + inverseDrift)), nil
+		return 1.0 / (baseRate * (1 + inverseDrift)), 1/(1+inverseDrift) - 1, nil
 	}
 
 	// Try to calculate via USD as intermediate
 	if source != "USD" && target != "USD" {
-		sourceToUSD, errSource := p.getMidRate(source, "USD")
-		usdToTarget, errTarget := p.getMidRate("USD", target)
+		sourceToUSD, sourceDrift, errSource := p.getMidRate(source, "USD")
+		usdToTarget, targetDrift, errTarget := p.getMidRate("USD", target)
 		if errSource == nil && errTarget == nil {
-			return sourceToUSD * usdToTarget, nil
+			return sourceToUSD * usdToTarget, (1+sourceDrift)*(1+targetDrift) - 1, nil
 		}
 	}
 
-	return 0, ErrUnsupportedPair{Source: source, Target: target}
+	return 0, 0, ErrUnsupportedPair{Source: source, Target: target}
 }
 
 // updateDriftIfNeeded updates rate drift if enough time has passed
@@ -202,7 +256,12 @@ func (p *SimulatedProvider) updateDriftIfNeeded() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if time.Since(p.lastDrift) < p.config.DriftInterval {
+	now := time.Now()
+	if p.config.SmoothDrift {
+		p.interpolateDrift(now)
+	}
+
+	if now.Sub(p.lastDrift) < p.config.DriftInterval {
 		return
 	}
 
@@ -210,43 +269,55 @@ func (p *SimulatedProvider) updateDriftIfNeeded() {
 	for pair := range baseRates {
 		// Random drift between -MaxDrift and +MaxDrift
 		drift := (p.rng.Float64()*2 - 1) * p.config.MaxDrift
-		p.currentDrift[pair] = drift
+		if p.config.SmoothDrift {
+			// Head for the new drift from wherever the walk has got to
+			p.driftFrom[pair] = p.currentDrift[pair]
+			p.driftTarget[pair] = drift
+		} else {
+			p.currentDrift[pair] = drift
+		}
 	}
 
-	p.lastDrift = time.Now()
+	p.lastDrift = now
 }
 
-// GetSupportedPairs returns all supported currency pairs
-func (p *SimulatedProvider) GetSupportedPairs() []CurrencyPair {
-	pairs := make([]CurrencyPair, 0, len(baseRates))
-	for key := range baseRates {
-		// Parse "SGD/PHP" format
-		var source, target string
-		for i, c := range key {
-			if c == '/' {
-				source = key[:i]
-				target = key[i+1:]
-				break
-			}
-		}
-		if source != "" && target != "" {
-			pairs = append(pairs, CurrencyPair{Source: source, Target: target})
-		}
+// interpolateDrift moves each pair's drift along the line from its drift at
+// the start of the interval to its target, reaching the target as the
+// interval ends. Callers must hold p.mu.
+func (p *SimulatedProvider) interpolateDrift(now time.Time) {
+	progress := 1.0
+	if p.config.DriftInterval > 0 {
+		progress = math.Min(float64(now.Sub(p.lastDrift))/float64(p.config.DriftInterval), 1)
+	}
+	for pair, target := range p.driftTarget {
+		from := p.driftFrom[pair]
+		p.currentDrift[pair] = from + (target-from)*progress
 	}
-	return pairs
+}
+
+// GetSupportedPairs returns the pairs with a base rate
+func (p *SimulatedProvider) GetSupportedPairs(ctx context.Context) ([]CurrencyPair, error) {
+	return pairsOf(baseRates), nil
 }
 
 // SetDrift manually sets drift for a currency pair (useful for testing)
+// The drift is held until the next drift interval elapses.
 func (p *SimulatedProvider) SetDrift(source, target string, drift float64) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.currentDrift[source+"/"+target] = drift
+	key := source + "/" + target
+	p.currentDrift[key] = drift
+	p.driftFrom[key] = drift
+	p.driftTarget[key] = drift
+	p.lastDrift = time.Now()
 }
 
-// ResetDrift resets all drift to zero
+// ResetDrift resets all drift to zero until the next drift interval elapses
 func (p *SimulatedProvider) ResetDrift() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.currentDrift = make(map[string]float64)
-	p.lastDrift = time.Time{}
+	p.driftFrom = make(map[string]float64)
+	p.driftTarget = make(map[string]float64)
+	p.lastDrift = time.Now()
 }
@@ -3,8 +3,11 @@ package provider
 import (
 	"context"
 	"math"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
 )
 
 func TestNewSimulatedProvider(t *testing.T) {
@@ -124,6 +127,40 @@ func TestGetRate_CrossPair(t *testing.T) {
 	}
 }
 
+func TestGetRate_NormalizesCurrencyCodes(t *testing.T) {
+	config := DefaultSimulatedConfig()
+	config.Seed = 42
+	provider := NewSimulatedProvider(config)
+
+	ctx := context.Background()
+
+	// Direct, inverse and cross pairs all resolve with mixed-case codes
+	for _, pair := range [][2]string{{"usd", "sgd"}, {"Sgd", "usd"}, {" php", "inr "}} {
+		rate, err := provider.GetRate(ctx, pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", pair[0], pair[1], err)
+		}
+		want := NormalizeCurrency(pair[0]) + "/" + NormalizeCurrency(pair[1])
+		if got := rate.SourceCurrency + "/" + rate.TargetCurrency; got != want {
+			t.Errorf("%s/%s: expected the rate for %s, got %s", pair[0], pair[1], want, got)
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	provider := NewSimulatedProvider(DefaultSimulatedConfig())
+
+	if err := provider.HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected healthy provider, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := provider.HealthCheck(ctx); err == nil {
+		t.Error("expected health check to fail with a cancelled context")
+	}
+}
+
 func TestGetRate_UnsupportedPair(t *testing.T) {
 	config := DefaultSimulatedConfig()
 	provider := NewSimulatedProvider(config)
@@ -145,6 +182,27 @@ func TestGetRate_UnsupportedPair(t *testing.T) {
 	}
 }
 
+func TestGetRate_UnavailablePair(t *testing.T) {
+	config := DefaultSimulatedConfig()
+	config.UnavailablePairs = []string{"SGD/IDR"}
+	provider := NewSimulatedProvider(config)
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := provider.GetRate(ctx, "SGD", "IDR")
+		if _, ok := err.(ErrProviderUnavailable); !ok {
+			t.Fatalf("call %d: expected ErrProviderUnavailable, got %v", i, err)
+		}
+	}
+
+	// Other pairs, including the inverse, are unaffected
+	for _, pair := range []CurrencyPair{{Source: "SGD", Target: "PHP"}, {Source: "IDR", Target: "SGD"}} {
+		if _, err := provider.GetRate(ctx, pair.Source, pair.Target); err != nil {
+			t.Errorf("%s/%s: unexpected error: %v", pair.Source, pair.Target, err)
+		}
+	}
+}
+
 func TestGetRate_ContextCancelled(t *testing.T) {
 	config := DefaultSimulatedConfig()
 	provider := NewSimulatedProvider(config)
@@ -238,6 +296,49 @@ func TestDrift_UpdatesOverTime(t *testing.T) {
 	}
 }
 
+func TestDrift_SmoothDriftMovesInSmallIncrements(t *testing.T) {
+	config := DefaultSimulatedConfig()
+	config.DriftInterval = 50 * time.Millisecond
+	config.SmoothDrift = true
+	config.Seed = 42
+	provider := NewSimulatedProvider(config)
+
+	ctx := context.Background()
+
+	// Within an interval drift moves at most 2*MaxDrift per interval, so
+	// consecutive samples can differ by no more than that rate allows
+	maxRate := 2 * config.MaxDrift / float64(config.DriftInterval)
+
+	start := time.Now()
+	prev, _ := provider.GetRate(ctx, "SGD", "PHP")
+	prevAt := start
+	moved := false
+	for time.Since(start) < 4*config.DriftInterval {
+		time.Sleep(2 * time.Millisecond)
+		sampledAt := time.Now()
+		rate, err := provider.GetRate(ctx, "SGD", "PHP")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if math.Abs(rate.Drift) > config.MaxDrift+1e-9 {
+			t.Fatalf("drift %f exceeds max drift %f", rate.Drift, config.MaxDrift)
+		}
+		step := math.Abs(rate.Drift - prev.Drift)
+		if limit := maxRate*float64(time.Since(prevAt)) + 1e-9; step > limit {
+			t.Fatalf("drift jumped by %f between samples, expected at most %f", step, limit)
+		}
+		if step > 0 {
+			moved = true
+		}
+		prev, prevAt = rate, sampledAt
+	}
+
+	if !moved {
+		t.Error("expected smoothed drift to change over time")
+	}
+}
+
 func TestSetDrift_ManualControl(t *testing.T) {
 	config := DefaultSimulatedConfig()
 	provider := NewSimulatedProvider(config)
@@ -293,12 +394,20 @@ func TestResetDrift(t *testing.T) {
 
 func TestGetSupportedPairs(t *testing.T) {
 	config := DefaultSimulatedConfig()
-	provider := NewSimulatedProvider(config)
+	var provider RateProvider = NewSimulatedProvider(config)
 
-	pairs := provider.GetSupportedPairs()
+	pairs, err := provider.GetSupportedPairs(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
-	if len(pairs) == 0 {
-		t.Fatal("expected at least some supported pairs")
+	if len(pairs) != len(baseRates) {
+		t.Fatalf("expected a pair per base rate (%d), got %d", len(baseRates), len(pairs))
+	}
+	for _, pair := range pairs {
+		if _, ok := baseRates[pair.Source+"/"+pair.Target]; !ok {
+			t.Errorf("unexpected pair %s/%s without a base rate", pair.Source, pair.Target)
+		}
 	}
 
 	// Check that SGD/PHP is in the list
@@ -342,3 +451,47 @@ func TestSpreadCalculation(t *testing.T) {
 		t.Errorf("expected ask %f, got %f", expectedAsk, rate.AskRate)
 	}
 }
+
+func TestSpreadCalculation_PairOverride(t *testing.T) {
+	config := DefaultSimulatedConfig()
+	config.BaseSpread = 0.005
+	config.PairSpreads = map[string]float64{"SGD/IDR": 0.02}
+	config.Seed = 42
+	provider := NewSimulatedProvider(config)
+	provider.ResetDrift()
+
+	ctx := context.Background()
+	tests := []struct {
+		source, target string
+		spread         float64
+	}{
+		{"SGD", "IDR", 0.02},
+		{"IDR", "SGD", 0.02}, // the override covers the inverse pair
+		{"SGD", "USD", 0.005},
+	}
+
+	for _, tt := range tests {
+		rate, err := provider.GetRate(ctx, tt.source, tt.target)
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", tt.source, tt.target, err)
+		}
+		if math.Abs(rate.Spread-tt.spread*100) > 1e-9 {
+			t.Errorf("%s/%s: expected spread %f%%, got %f%%", tt.source, tt.target, tt.spread*100, rate.Spread)
+		}
+		if math.Abs(rate.BidRate-rate.MidRate*(1-tt.spread/2)) > 1e-9*rate.MidRate ||
+			math.Abs(rate.AskRate-rate.MidRate*(1+tt.spread/2)) > 1e-9*rate.MidRate {
+			t.Errorf("%s/%s: bid %f and ask %f don't reflect a %f spread around %f",
+				tt.source, tt.target, rate.BidRate, rate.AskRate, tt.spread, rate.MidRate)
+		}
+	}
+}
+
+func TestBaseRateCurrenciesAreSupported(t *testing.T) {
+	for pair := range baseRates {
+		for _, currency := range strings.Split(pair, "/") {
+			if !model.IsSupportedCurrency(currency) {
+				t.Errorf("base rate %s uses %s, which model.IsSupportedCurrency rejects", pair, currency)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// ValidatingProvider rejects zero, negative and non-finite rates from the
+// provider it wraps. Bad rates are reported as ErrProviderUnavailable, so a
+// chain falls back to its next provider instead of caching and serving them.
+type ValidatingProvider struct {
+	RateProvider
+}
+
+// NewValidatingProvider wraps a provider with rate validation
+func NewValidatingProvider(provider RateProvider) *ValidatingProvider {
+	return &ValidatingProvider{RateProvider: provider}
+}
+
+// GetRate returns the wrapped provider's rate if it is valid
+func (p *ValidatingProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	rate, err := p.RateProvider.GetRate(ctx, source, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.validate(rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// GetRates returns the wrapped provider's rates, failing the batch if any
+// rate is invalid
+func (p *ValidatingProvider) GetRates(ctx context.Context, pairs []CurrencyPair) ([]*Rate, error) {
+	rates, err := p.RateProvider.GetRates(ctx, pairs)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range rates {
+		if err := p.validate(rate); err != nil {
+			return nil, err
+		}
+	}
+	return rates, nil
+}
+
+// validate requires a positive, finite mid rate. Bid and ask may both be
+// left at zero by providers that only quote a mid rate, but otherwise must be
+// positive and finite too.
+func (p *ValidatingProvider) validate(rate *Rate) error {
+	names := []string{"mid"}
+	values := []float64{rate.MidRate}
+	if rate.BidRate != 0 || rate.AskRate != 0 {
+		names = append(names, "bid", "ask")
+		values = append(values, rate.BidRate, rate.AskRate)
+	}
+
+	for i, value := range values {
+		if !(value > 0) || math.IsInf(value, 0) {
+			return ErrProviderUnavailable{
+				Provider: p.Name(),
+				Reason: fmt.Sprintf("invalid %s rate %v for %s/%s",
+					names[i], value, rate.SourceCurrency, rate.TargetCurrency),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestValidatingProvider_RejectsNonPositiveRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		midRate float64
+	}{
+		{"zero", 0},
+		{"negative", -42.50},
+		{"NaN", math.NaN()},
+		{"infinite", math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewValidatingProvider(&stubProvider{name: "bad", midRate: tt.midRate})
+
+			_, err := p.GetRate(context.Background(), "SGD", "PHP")
+			unavailable, ok := err.(ErrProviderUnavailable)
+			if !ok {
+				t.Fatalf("expected ErrProviderUnavailable, got %v", err)
+			}
+			if unavailable.Provider != "bad" {
+				t.Errorf("expected provider name bad, got %s", unavailable.Provider)
+			}
+
+			if _, err := p.GetRates(context.Background(), []CurrencyPair{{Source: "SGD", Target: "PHP"}}); err == nil {
+				t.Error("expected GetRates to reject the rate")
+			}
+		})
+	}
+}
+
+// quotingProvider returns a fixed bid and ask around its mid rate
+type quotingProvider struct {
+	stubProvider
+	bid, ask float64
+}
+
+func (q *quotingProvider) GetRate(ctx context.Context, source, target string) (*Rate, error) {
+	rate, err := q.stubProvider.GetRate(ctx, source, target)
+	if err != nil {
+		return nil, err
+	}
+	rate.BidRate, rate.AskRate = q.bid, q.ask
+	return rate, nil
+}
+
+func TestValidatingProvider_BidAndAsk(t *testing.T) {
+	tests := []struct {
+		name     string
+		bid, ask float64
+		valid    bool
+	}{
+		{"mid only", 0, 0, true},
+		{"positive", 42.30, 42.70, true},
+		{"zero bid", 0, 42.70, false},
+		{"negative ask", 42.30, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewValidatingProvider(&quotingProvider{
+				stubProvider: stubProvider{name: "quoting", midRate: 42.50},
+				bid:          tt.bid,
+				ask:          tt.ask,
+			})
+
+			_, err := p.GetRate(context.Background(), "SGD", "PHP")
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected rate to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidatingProvider_ChainFallsBackPastInvalidRate(t *testing.T) {
+	primary := &stubProvider{name: "primary", midRate: 0}
+	secondary := &stubProvider{name: "secondary", midRate: 42.80}
+	chain := NewChainProvider(NewValidatingProvider(primary), NewValidatingProvider(secondary))
+
+	rate, err := chain.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rate.Source != "secondary" || rate.MidRate != 42.80 {
+		t.Errorf("expected secondary's rate, got %+v", rate)
+	}
+	if calls := primary.calls.Load(); calls != 1 {
+		t.Errorf("expected primary to be tried once, got %d calls", calls)
+	}
+}
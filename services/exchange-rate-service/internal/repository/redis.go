@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
@@ -13,25 +15,83 @@ import (
 
 const (
 	// Key prefixes for Redis
-	rateKeyPrefix   = "rate:"
-	lockedKeyPrefix = "locked:"
+	rateKeyPrefix         = "rate:"
+	lockedKeyPrefix       = "locked:"
+	lockIdempotencyPrefix = "lock_idempotency:"
+	rateSnapshotPrefix    = "rate_snapshot:"
+	quoteKeyPrefix        = "quote:"
+	rateHistoryPrefix     = "rate_history:"
+	activeLocksPrefix     = "active_locks:"
+	corridorOverridesKey  = "corridor_overrides"
+
+	// defaultRateHistoryPoints is how many rates are kept per pair unless
+	// configured otherwise
+	defaultRateHistoryPoints = 1000
+
+	// expiredQuoteRetention is how long a quote is kept after it stops
+	// being valid, so it is reported as expired rather than not found
+	expiredQuoteRetention = 24 * time.Hour
 )
 
 // RedisRepository implements RateRepository using Redis
 type RedisRepository struct {
-	client *redis.Client
+	client           *redis.Client
+	onWriteFailure   WriteFailureRecorder
+	rateHistoryLimit int
 }
 
 // NewRedisRepository creates a new Redis-backed repository
 func NewRedisRepository(client *redis.Client) *RedisRepository {
 	return &RedisRepository{
-		client: client,
+		client:           client,
+		rateHistoryLimit: defaultRateHistoryPoints,
 	}
 }
 
+// WithRateHistoryLimit keeps at most maxPoints rates in each pair's history,
+// dropping the oldest first. 0 stops rates being recorded.
+func (r *RedisRepository) WithRateHistoryLimit(maxPoints int) *RedisRepository {
+	r.rateHistoryLimit = maxPoints
+	return r
+}
+
+// WithWriteFailureRecorder reports failed writes to recorder, e.g. for metrics
+func (r *RedisRepository) WithWriteFailureRecorder(recorder WriteFailureRecorder) *RedisRepository {
+	r.onWriteFailure = recorder
+	return r
+}
+
+// writeError classifies a failed write as ErrWriteFailed and records it
+func (r *RedisRepository) writeError(operation string, err error) error {
+	kind := WriteFailureOther
+	switch msg := err.Error(); {
+	case strings.HasPrefix(msg, "OOM "):
+		kind = WriteFailureOOM
+	case strings.HasPrefix(msg, "READONLY "):
+		kind = WriteFailureReadOnly
+	}
+
+	if r.onWriteFailure != nil {
+		r.onWriteFailure(operation, kind)
+	}
+	return ErrWriteFailed{Operation: operation, Kind: kind, Err: err}
+}
+
+// pairKey generates the Redis key for a currency pair under prefix. Currency
+// codes are normalized, so "sgd"/"php" and "SGD"/"PHP" share a key.
+func pairKey(prefix, source, target string) string {
+	return fmt.Sprintf("%s%s:%s", prefix, provider.NormalizeCurrency(source), provider.NormalizeCurrency(target))
+}
+
 // rateKey generates the Redis key for an exchange rate
 func rateKey(source, target string) string {
-	return fmt.Sprintf("%s%s:%s", rateKeyPrefix, source, target)
+	return pairKey(rateKeyPrefix, source, target)
+}
+
+// rateHistoryKey generates the Redis key for a pair's rate history, a sorted
+// set of points scored by fetch time in milliseconds
+func rateHistoryKey(source, target string) string {
+	return pairKey(rateHistoryPrefix, source, target)
 }
 
 // lockedKey generates the Redis key for a locked rate
@@ -39,25 +99,94 @@ func lockedKey(lockID string) string {
 	return lockedKeyPrefix + lockID
 }
 
-// SaveRate stores an exchange rate with TTL
+// activeLocksKey generates the Redis key for a pair's active locks, a sorted
+// set of lock IDs scored by their expiry in Unix milliseconds
+func activeLocksKey(source, target string) string {
+	return pairKey(activeLocksPrefix, source, target)
+}
+
+// lockIdempotencyKey generates the Redis key for a lock idempotency key
+func lockIdempotencyKey(key string) string {
+	return lockIdempotencyPrefix + key
+}
+
+// SaveRate stores an exchange rate with TTL and appends it to the pair's
+// rate history, trimming the history to its limit
 func (r *RedisRepository) SaveRate(ctx context.Context, rate *provider.Rate, ttl time.Duration) error {
 	data, err := json.Marshal(rate)
 	if err != nil {
 		return fmt.Errorf("failed to marshal rate: %w", err)
 	}
 
-	key := rateKey(rate.SourceCurrency, rate.TargetCurrency)
-	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to save rate: %w", err)
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, rateKey(rate.SourceCurrency, rate.TargetCurrency), data, ttl)
+
+	if r.rateHistoryLimit > 0 {
+		point, err := json.Marshal(model.RateHistoryPoint{
+			Timestamp: rate.FetchedAt,
+			MidRate:   rate.MidRate,
+			BidRate:   rate.BidRate,
+			AskRate:   rate.AskRate,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal rate history point: %w", err)
+		}
+
+		historyKey := rateHistoryKey(rate.SourceCurrency, rate.TargetCurrency)
+		pipe.ZAdd(ctx, historyKey, redis.Z{Score: float64(rate.FetchedAt.UnixMilli()), Member: point})
+		pipe.ZRemRangeByRank(ctx, historyKey, 0, int64(-r.rateHistoryLimit-1))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return r.writeError("save rate", err)
 	}
 
 	return nil
 }
 
+// GetRateHistory returns the recorded rates for a pair fetched between from
+// and to inclusive, oldest first
+func (r *RedisRepository) GetRateHistory(ctx context.Context, source, target string, from, to time.Time) ([]model.RateHistoryPoint, error) {
+	members, err := r.client.ZRangeByScore(ctx, rateHistoryKey(source, target), &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixMilli(), 10),
+		Max: strconv.FormatInt(to.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate history: %w", err)
+	}
+
+	points := make([]model.RateHistoryPoint, 0, len(members))
+	for _, member := range members {
+		var point model.RateHistoryPoint
+		if err := json.Unmarshal([]byte(member), &point); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rate history point: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
 // GetRate retrieves a cached exchange rate
 func (r *RedisRepository) GetRate(ctx context.Context, source, target string) (*provider.Rate, error) {
-	key := rateKey(source, target)
-	data, err := r.client.Get(ctx, key).Bytes()
+	rate, err := r.GetStaleRate(ctx, source, target)
+	if err != nil || rate == nil {
+		return nil, err
+	}
+
+	// Rates may be stored past their validity so they can be served stale;
+	// Redis expires them once that window is over
+	if time.Now().After(rate.ValidUntil) {
+		return nil, nil
+	}
+
+	return rate, nil
+}
+
+// GetStaleRate retrieves a cached exchange rate whether or not it is still
+// valid
+func (r *RedisRepository) GetStaleRate(ctx context.Context, source, target string) (*provider.Rate, error) {
+	data, err := r.client.Get(ctx, rateKey(source, target)).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil // Cache miss
@@ -70,13 +199,6 @@ func (r *RedisRepository) GetRate(ctx context.Context, source, target string) (*
 		return nil, fmt.Errorf("failed to unmarshal rate: %w", err)
 	}
 
-	// Check if rate is still valid
-	if time.Now().After(rate.ValidUntil) {
-		// Rate has expired, delete it and return cache miss
-		_ = r.client.Del(ctx, key)
-		return nil, nil
-	}
-
 	return &rate, nil
 }
 
@@ -93,13 +215,26 @@ func (r *RedisRepository) SaveLockedRate(ctx context.Context, locked *model.Lock
 		return fmt.Errorf("locked rate has already expired")
 	}
 
-	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to save locked rate: %w", err)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, ttl)
+		trackActiveLock(ctx, pipe, locked)
+		return nil
+	})
+	if err != nil {
+		return r.writeError("save locked rate", err)
 	}
 
 	return nil
 }
 
+// trackActiveLock records locked in its pair's active locks, or updates its
+// expiry there, and drops the pair's locks that have since expired
+func trackActiveLock(ctx context.Context, pipe redis.Pipeliner, locked *model.LockedRate) {
+	key := activeLocksKey(locked.Rate.SourceCurrency, locked.Rate.TargetCurrency)
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(locked.ExpiresAt.UnixMilli()), Member: locked.LockID})
+}
+
 // GetLockedRate retrieves a locked rate by ID
 func (r *RedisRepository) GetLockedRate(ctx context.Context, lockID string) (*model.LockedRate, error) {
 	key := lockedKey(lockID)
@@ -120,28 +255,66 @@ func (r *RedisRepository) GetLockedRate(ctx context.Context, lockID string) (*mo
 	if time.Now().After(locked.ExpiresAt) {
 		locked.Expired = true
 		// Delete expired lock
-		_ = r.client.Del(ctx, key)
+		_, _ = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.ZRem(ctx, activeLocksKey(locked.Rate.SourceCurrency, locked.Rate.TargetCurrency), lockID)
+			return nil
+		})
 		return nil, ErrExpired{LockID: lockID}
 	}
 
 	return &locked, nil
 }
 
-// DeleteLockedRate removes a locked rate
+// DeleteLockedRate removes a locked rate, and drops it from its pair's
+// active locks
 func (r *RedisRepository) DeleteLockedRate(ctx context.Context, lockID string) error {
 	key := lockedKey(lockID)
-	result, err := r.client.Del(ctx, key).Result()
+	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
+		if err == redis.Nil {
+			return ErrNotFound{Key: lockID}
+		}
 		return fmt.Errorf("failed to delete locked rate: %w", err)
 	}
 
-	if result == 0 {
+	var del *redis.IntCmd
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		del = pipe.Del(ctx, key)
+		var locked model.LockedRate
+		if json.Unmarshal(data, &locked) == nil {
+			pipe.ZRem(ctx, activeLocksKey(locked.Rate.SourceCurrency, locked.Rate.TargetCurrency), lockID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete locked rate: %w", err)
+	}
+
+	if del.Val() == 0 {
 		return ErrNotFound{Key: lockID}
 	}
 
 	return nil
 }
 
+// CountActiveLocks returns how many unexpired locks a currency pair has.
+// Locks leave the count when deleted or, without a delete, once their
+// expiry passes.
+func (r *RedisRepository) CountActiveLocks(ctx context.Context, source, target string) (int, error) {
+	key := activeLocksKey(source, target)
+	var count *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10))
+		count = pipe.ZCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active locks: %w", err)
+	}
+	return int(count.Val()), nil
+}
+
 // ExtendLockedRate extends the expiration of a locked rate
 func (r *RedisRepository) ExtendLockedRate(ctx context.Context, lockID string, newExpiry time.Time) error {
 	// Get existing locked rate
@@ -160,6 +333,216 @@ func (r *RedisRepository) ExtendLockedRate(ctx context.Context, lockID string, n
 	return r.SaveLockedRate(ctx, locked)
 }
 
+// SaveLockedRates stores several locked rates, pipelining the writes. Locks
+// that have expired by the time they are written are skipped rather than
+// failing the others, and their IDs returned.
+func (r *RedisRepository) SaveLockedRates(ctx context.Context, locked []*model.LockedRate) ([]string, error) {
+	var expired []string
+	values := make(map[string][]byte, len(locked))
+	ttls := make(map[string]time.Duration, len(locked))
+	byID := make(map[string]*model.LockedRate, len(locked))
+	for _, l := range locked {
+		ttl := time.Until(l.ExpiresAt)
+		if ttl <= 0 {
+			expired = append(expired, l.LockID)
+			continue
+		}
+		data, err := json.Marshal(l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal locked rate: %w", err)
+		}
+		values[l.LockID] = data
+		ttls[l.LockID] = ttl
+		byID[l.LockID] = l
+	}
+	if len(values) == 0 {
+		return expired, nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for lockID, data := range values {
+			pipe.Set(ctx, lockedKey(lockID), data, ttls[lockID])
+			trackActiveLock(ctx, pipe, byID[lockID])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, r.writeError("save locked rates", err)
+	}
+
+	return expired, nil
+}
+
+// GetLockIdempotencyKey returns the lock an idempotency key is bound to
+func (r *RedisRepository) GetLockIdempotencyKey(ctx context.Context, key string) (*LockIdempotencyRecord, error) {
+	data, err := r.client.Get(ctx, lockIdempotencyKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get lock idempotency key: %w", err)
+	}
+
+	var record LockIdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		// Keys written before requests were hashed hold just the lock ID
+		return &LockIdempotencyRecord{LockID: string(data)}, nil
+	}
+	return &record, nil
+}
+
+// ClaimLockIdempotencyKey binds an idempotency key to record's lock if the key is unused
+func (r *RedisRepository) ClaimLockIdempotencyKey(ctx context.Context, key string, record LockIdempotencyRecord, ttl time.Duration) (*LockIdempotencyRecord, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock idempotency key: %w", err)
+	}
+
+	claimed, err := r.client.SetNX(ctx, lockIdempotencyKey(key), data, ttl).Result()
+	if err != nil {
+		return nil, r.writeError("claim lock idempotency key", err)
+	}
+	if claimed {
+		return &record, nil
+	}
+
+	existing, err := r.GetLockIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		// Key expired between SETNX and GET, treat as claimed by us
+		if err := r.client.Set(ctx, lockIdempotencyKey(key), data, ttl).Err(); err != nil {
+			return nil, r.writeError("claim lock idempotency key", err)
+		}
+		return &record, nil
+	}
+	return existing, nil
+}
+
+// DeleteLockIdempotencyKey removes a key whose lock no longer exists
+func (r *RedisRepository) DeleteLockIdempotencyKey(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, lockIdempotencyKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to delete lock idempotency key: %w", err)
+	}
+	return nil
+}
+
+// SaveRateSnapshot stores a lock's market snapshot, keeping the first one
+// written for the lock
+func (r *RedisRepository) SaveRateSnapshot(ctx context.Context, snapshot *model.RateSnapshot, ttl time.Duration) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate snapshot: %w", err)
+	}
+
+	if err := r.client.SetNX(ctx, rateSnapshotPrefix+snapshot.LockID, data, ttl).Err(); err != nil {
+		return r.writeError("save rate snapshot", err)
+	}
+
+	return nil
+}
+
+// GetRateSnapshot retrieves a lock's market snapshot
+func (r *RedisRepository) GetRateSnapshot(ctx context.Context, lockID string) (*model.RateSnapshot, error) {
+	data, err := r.client.Get(ctx, rateSnapshotPrefix+lockID).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get rate snapshot: %w", err)
+	}
+
+	var snapshot model.RateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rate snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+// DeleteRateSnapshot removes a discarded lock's market snapshot
+func (r *RedisRepository) DeleteRateSnapshot(ctx context.Context, lockID string) error {
+	if err := r.client.Del(ctx, rateSnapshotPrefix+lockID).Err(); err != nil {
+		return fmt.Errorf("failed to delete rate snapshot: %w", err)
+	}
+	return nil
+}
+
+// SaveQuote stores a quote, expiring it expiredQuoteRetention after it stops
+// being valid. Quotes that are already invalid are not stored.
+func (r *RedisRepository) SaveQuote(ctx context.Context, quote *model.RateQuote) error {
+	ttl := time.Until(quote.ValidUntil)
+	if ttl <= 0 {
+		return nil
+	}
+	ttl += expiredQuoteRetention
+
+	data, err := json.Marshal(quote)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quote: %w", err)
+	}
+
+	if err := r.client.Set(ctx, quoteKeyPrefix+quote.QuoteID, data, ttl).Err(); err != nil {
+		return r.writeError("save quote", err)
+	}
+
+	return nil
+}
+
+// GetQuote retrieves a stored quote
+func (r *RedisRepository) GetQuote(ctx context.Context, quoteID string) (*model.RateQuote, error) {
+	data, err := r.client.Get(ctx, quoteKeyPrefix+quoteID).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+
+	var quote model.RateQuote
+	if err := json.Unmarshal(data, &quote); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal quote: %w", err)
+	}
+
+	return &quote, nil
+}
+
+// SaveCorridorOverride stores a corridor's pricing override. Overrides are
+// kept in one hash, keyed by pair, and never expire.
+func (r *RedisRepository) SaveCorridorOverride(ctx context.Context, override *model.CorridorOverride) error {
+	data, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("failed to marshal corridor override: %w", err)
+	}
+
+	field := pairKey("", override.SourceCurrency, override.TargetCurrency)
+	if err := r.client.HSet(ctx, corridorOverridesKey, field, data).Err(); err != nil {
+		return r.writeError("save corridor override", err)
+	}
+
+	return nil
+}
+
+// GetCorridorOverrides returns every stored corridor pricing override
+func (r *RedisRepository) GetCorridorOverrides(ctx context.Context) ([]model.CorridorOverride, error) {
+	fields, err := r.client.HGetAll(ctx, corridorOverridesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get corridor overrides: %w", err)
+	}
+
+	overrides := make([]model.CorridorOverride, 0, len(fields))
+	for field, data := range fields {
+		var override model.CorridorOverride
+		if err := json.Unmarshal([]byte(data), &override); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal corridor override %s: %w", field, err)
+		}
+		overrides = append(overrides, override)
+	}
+
+	return overrides, nil
+}
+
 // Health checks if Redis is healthy
 func (r *RedisRepository) Health(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/redis/go-redis/v9"
+)
+
+// failingHook makes every command fail with err without reaching Redis,
+// simulating a server that rejects writes
+type failingHook struct {
+	err error
+}
+
+func (h failingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, h.err
+	}
+}
+
+func (h failingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		cmd.SetErr(h.err)
+		return h.err
+	}
+}
+
+func (h failingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			cmd.SetErr(h.err)
+		}
+		return h.err
+	}
+}
+
+// newFailingRepository returns a repository whose writes fail with err, and
+// the failures it recorded
+func newFailingRepository(t *testing.T, err error) (*RedisRepository, *[]string) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	client.AddHook(failingHook{err: err})
+	t.Cleanup(func() { _ = client.Close() })
+
+	var recorded []string
+	repo := NewRedisRepository(client).WithWriteFailureRecorder(func(operation, kind string) {
+		recorded = append(recorded, operation+"/"+kind)
+	})
+	return repo, &recorded
+}
+
+func TestRedisRepository_WriteFailuresAreClassified(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind string
+	}{
+		{"oom", errors.New("OOM command not allowed when used memory > 'maxmemory'."), WriteFailureOOM},
+		{"readonly", errors.New("READONLY You can't write against a read only replica."), WriteFailureReadOnly},
+		{"other", errors.New("connection refused"), WriteFailureOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, recorded := newFailingRepository(t, tt.err)
+
+			err := repo.SaveRate(context.Background(), &provider.Rate{SourceCurrency: "SGD", TargetCurrency: "PHP"}, time.Minute)
+
+			var writeErr ErrWriteFailed
+			if !errors.As(err, &writeErr) {
+				t.Fatalf("expected ErrWriteFailed, got %v", err)
+			}
+			if writeErr.Kind != tt.kind || writeErr.Operation != "save rate" {
+				t.Errorf("unexpected classification: %+v", writeErr)
+			}
+			if len(*recorded) != 1 || (*recorded)[0] != "save rate/"+tt.kind {
+				t.Errorf("expected one recorded failure, got %v", *recorded)
+			}
+		})
+	}
+}
+
+func TestRedisRepository_LockWritesReturnErrWriteFailed(t *testing.T) {
+	oom := errors.New("OOM command not allowed when used memory > 'maxmemory'.")
+	locked := &model.LockedRate{LockID: "lock-1", ExpiresAt: time.Now().Add(time.Minute)}
+	ctx := context.Background()
+
+	repo, recorded := newFailingRepository(t, oom)
+
+	var writeErr ErrWriteFailed
+	if err := repo.SaveLockedRate(ctx, locked); !errors.As(err, &writeErr) || writeErr.Kind != WriteFailureOOM {
+		t.Errorf("SaveLockedRate: expected OOM ErrWriteFailed, got %v", err)
+	}
+	if _, err := repo.SaveLockedRates(ctx, []*model.LockedRate{locked}); !errors.As(err, &writeErr) || writeErr.Kind != WriteFailureOOM {
+		t.Errorf("SaveLockedRates: expected OOM ErrWriteFailed, got %v", err)
+	}
+	if _, err := repo.ClaimLockIdempotencyKey(ctx, "key-1", LockIdempotencyRecord{LockID: "lock-1"}, time.Minute); !errors.As(err, &writeErr) || writeErr.Kind != WriteFailureOOM {
+		t.Errorf("ClaimLockIdempotencyKey: expected OOM ErrWriteFailed, got %v", err)
+	}
+
+	if len(*recorded) != 3 {
+		t.Errorf("expected 3 recorded failures, got %v", *recorded)
+	}
+}
+
+func newTestRepository(t *testing.T) *RedisRepository {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisRepository(client)
+}
+
+func saveHistoryRates(t *testing.T, repo *RedisRepository, base time.Time, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		rate := &provider.Rate{
+			SourceCurrency: "SGD",
+			TargetCurrency: "PHP",
+			MidRate:        42 + float64(i),
+			FetchedAt:      base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.SaveRate(context.Background(), rate, time.Minute); err != nil {
+			t.Fatalf("save rate: %v", err)
+		}
+	}
+}
+
+func TestRedisRepository_GetRateHistory_FiltersByTimeRange(t *testing.T) {
+	repo := newTestRepository(t)
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	saveHistoryRates(t, repo, base, 5)
+
+	points, err := repo.GetRateHistory(context.Background(), "SGD", "PHP", base.Add(time.Minute), base.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("get rate history: %v", err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("expected 3 points in range, got %d", len(points))
+	}
+	for i, point := range points {
+		if want := base.Add(time.Duration(i+1) * time.Minute); !point.Timestamp.Equal(want) || point.MidRate != 43+float64(i) {
+			t.Errorf("point %d: expected rate %v at %v, got %+v", i, 43+float64(i), want, point)
+		}
+	}
+
+	if points, _ := repo.GetRateHistory(context.Background(), "PHP", "SGD", base, base.Add(time.Hour)); len(points) != 0 {
+		t.Errorf("expected no history for the other direction, got %d points", len(points))
+	}
+}
+
+func TestRedisRepository_RateHistoryIsCapped(t *testing.T) {
+	repo := newTestRepository(t).WithRateHistoryLimit(3)
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	saveHistoryRates(t, repo, base, 5)
+
+	points, err := repo.GetRateHistory(context.Background(), "SGD", "PHP", base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("get rate history: %v", err)
+	}
+	if len(points) != 3 || points[0].MidRate != 44 || points[2].MidRate != 46 {
+		t.Errorf("expected only the newest 3 points, got %+v", points)
+	}
+}
+
+func TestRedisRepository_GetStaleRate(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	expired := &provider.Rate{
+		SourceCurrency: "SGD",
+		TargetCurrency: "PHP",
+		MidRate:        42,
+		FetchedAt:      time.Now().Add(-time.Minute),
+		ValidUntil:     time.Now().Add(-time.Second),
+	}
+	if err := repo.SaveRate(ctx, expired, time.Minute); err != nil {
+		t.Fatalf("save rate: %v", err)
+	}
+
+	if rate, err := repo.GetRate(ctx, "SGD", "PHP"); err != nil || rate != nil {
+		t.Errorf("expected a cache miss for an expired rate, got %+v (%v)", rate, err)
+	}
+	rate, err := repo.GetStaleRate(ctx, "SGD", "PHP")
+	if err != nil || rate == nil || rate.MidRate != 42 {
+		t.Errorf("expected the expired rate to still be stored, got %+v (%v)", rate, err)
+	}
+}
+
+func TestRedisRepository_PairKeysIgnoreCase(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	rate := &provider.Rate{
+		SourceCurrency: "sgd",
+		TargetCurrency: " php",
+		MidRate:        42,
+		FetchedAt:      time.Now(),
+		ValidUntil:     time.Now().Add(time.Minute),
+	}
+	if err := repo.SaveRate(ctx, rate, time.Minute); err != nil {
+		t.Fatalf("save rate: %v", err)
+	}
+
+	if key := rateKey("sgd", " php"); key != "rate:SGD:PHP" {
+		t.Errorf("expected the canonical key rate:SGD:PHP, got %s", key)
+	}
+	if cached, err := repo.GetRate(ctx, "SGD", "PHP"); err != nil || cached == nil || cached.MidRate != 42 {
+		t.Errorf("expected the rate saved in lowercase to be found in uppercase, got %+v (%v)", cached, err)
+	}
+}
+
+func TestRedisRepository_CorridorOverrides(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	if overrides, err := repo.GetCorridorOverrides(ctx); err != nil || len(overrides) != 0 {
+		t.Fatalf("expected no overrides, got %+v (%v)", overrides, err)
+	}
+
+	for _, override := range []*model.CorridorOverride{
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", MarginPercentage: "0.50"},
+		{SourceCurrency: "USD", TargetCurrency: "PHP", FeePercentage: "0.75"},
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", MarginPercentage: "0.40", FeePercentage: "0.45"},
+	} {
+		if err := repo.SaveCorridorOverride(ctx, override); err != nil {
+			t.Fatalf("save corridor override: %v", err)
+		}
+	}
+
+	overrides, err := repo.GetCorridorOverrides(ctx)
+	if err != nil {
+		t.Fatalf("get corridor overrides: %v", err)
+	}
+	if len(overrides) != 2 {
+		t.Fatalf("expected one override per corridor, got %+v", overrides)
+	}
+	for _, o := range overrides {
+		if o.SourceCurrency == "SGD" && (o.MarginPercentage != "0.40" || o.FeePercentage != "0.45") {
+			t.Errorf("expected the later SGD/PHP override to replace the earlier one, got %+v", o)
+		}
+	}
+}
+
+func TestRedisRepository_SaveLockedRates_SkipsExpired(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	expired, err := repo.SaveLockedRates(ctx, []*model.LockedRate{
+		{LockID: "lock-1", ExpiresAt: time.Now().Add(time.Minute)},
+		{LockID: "lock-2", ExpiresAt: time.Now().Add(-time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("expected an expired lock not to fail the batch, got: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != "lock-2" {
+		t.Errorf("expected lock-2 to be reported expired, got %v", expired)
+	}
+	if locked, err := repo.GetLockedRate(ctx, "lock-1"); err != nil || locked == nil {
+		t.Errorf("expected lock-1 to be saved, got %v, %v", locked, err)
+	}
+}
+
+func TestRedisRepository_CountActiveLocks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	lock := func(lockID string, ttl time.Duration) *model.LockedRate {
+		return &model.LockedRate{
+			LockID:    lockID,
+			Rate:      model.ExchangeRate{SourceCurrency: "SGD", TargetCurrency: "PHP"},
+			ExpiresAt: time.Now().Add(ttl),
+		}
+	}
+	for _, locked := range []*model.LockedRate{lock("lock-1", time.Minute), lock("lock-2", time.Minute), lock("lock-3", 50*time.Millisecond)} {
+		if err := repo.SaveLockedRate(ctx, locked); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := repo.SaveLockedRates(ctx, []*model.LockedRate{lock("lock-1", 2*time.Minute)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	count := func() int {
+		t.Helper()
+		active, err := repo.CountActiveLocks(ctx, "SGD", "PHP")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return active
+	}
+	if active := count(); active != 3 {
+		t.Errorf("expected 3 active locks, with the re-saved lock counted once, got %d", active)
+	}
+
+	if err := repo.DeleteLockedRate(ctx, "lock-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if active := count(); active != 2 {
+		t.Errorf("expected 2 active locks after a delete, got %d", active)
+	}
+
+	// Expiry drops a lock from the count without a delete
+	time.Sleep(100 * time.Millisecond)
+	if active := count(); active != 1 {
+		t.Errorf("expected 1 active lock after one expired, got %d", active)
+	}
+
+	if active, _ := repo.CountActiveLocks(ctx, "USD", "PHP"); active != 0 {
+		t.Errorf("expected no active locks for another pair, got %d", active)
+	}
+	if err := repo.DeleteLockedRate(ctx, "lock-2"); !errors.As(err, new(ErrNotFound)) {
+		t.Errorf("expected ErrNotFound deleting a deleted lock, got %v", err)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	repo := NewRedisRepository(client)
+	ctx := context.Background()
+
+	mr.SetError("READONLY You can't write against a read only replica.")
+	_, err := repo.GetLockedRate(ctx, "lock")
+	if err == nil || IsConnectionError(err) {
+		t.Errorf("expected a rejected command not to be a connection error, got %v", err)
+	}
+	mr.SetError("")
+
+	mr.Close()
+	if _, err := repo.GetLockedRate(ctx, "lock"); !IsConnectionError(err) {
+		t.Errorf("expected a connection error with Redis down, got %v", err)
+	}
+	err = repo.SaveLockedRate(ctx, &model.LockedRate{LockID: "lock", ExpiresAt: time.Now().Add(time.Minute)})
+	if !IsConnectionError(err) {
+		t.Errorf("expected a connection error saving with Redis down, got %v", err)
+	}
+}
@@ -2,6 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
 	"time"
 
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
@@ -10,13 +15,23 @@ import (
 
 // RateRepository defines the interface for rate storage operations
 type RateRepository interface {
-	// SaveRate stores an exchange rate with TTL
+	// SaveRate stores an exchange rate with TTL and records it in the pair's
+	// rate history
 	SaveRate(ctx context.Context, rate *provider.Rate, ttl time.Duration) error
 
+	// GetRateHistory returns the recorded rates for a pair fetched between
+	// from and to inclusive, oldest first
+	GetRateHistory(ctx context.Context, source, target string, from, to time.Time) ([]model.RateHistoryPoint, error)
+
 	// GetRate retrieves a cached exchange rate
 	// Returns nil, nil if not found (cache miss)
 	GetRate(ctx context.Context, source, target string) (*provider.Rate, error)
 
+	// GetStaleRate retrieves a cached exchange rate even if it is past its
+	// ValidUntil, for as long as it is still stored
+	// Returns nil, nil if not found
+	GetStaleRate(ctx context.Context, source, target string) (*provider.Rate, error)
+
 	// SaveLockedRate stores a locked rate for a transfer
 	SaveLockedRate(ctx context.Context, locked *model.LockedRate) error
 
@@ -27,9 +42,54 @@ type RateRepository interface {
 	// DeleteLockedRate removes a locked rate
 	DeleteLockedRate(ctx context.Context, lockID string) error
 
+	// CountActiveLocks returns how many unexpired locks a currency pair has
+	CountActiveLocks(ctx context.Context, source, target string) (int, error)
+
 	// ExtendLockedRate extends the expiration of a locked rate
 	ExtendLockedRate(ctx context.Context, lockID string, newExpiry time.Time) error
 
+	// SaveLockedRates stores several locked rates in a single round trip
+	// Locks that have expired by then are skipped, and their IDs returned
+	SaveLockedRates(ctx context.Context, locked []*model.LockedRate) ([]string, error)
+
+	// GetLockIdempotencyKey returns the lock an idempotency key is bound to
+	// Returns nil, nil if no lock was created for the key
+	GetLockIdempotencyKey(ctx context.Context, key string) (*LockIdempotencyRecord, error)
+
+	// ClaimLockIdempotencyKey binds an idempotency key to record's lock if the key is unused
+	// Returns the record bound to the key, which differs from record if another request claimed it first
+	ClaimLockIdempotencyKey(ctx context.Context, key string, record LockIdempotencyRecord, ttl time.Duration) (*LockIdempotencyRecord, error)
+
+	// DeleteLockIdempotencyKey removes a key whose lock no longer exists
+	DeleteLockIdempotencyKey(ctx context.Context, key string) error
+
+	// SaveRateSnapshot stores the market snapshot for a lock for ttl. An
+	// existing snapshot for the lock is never overwritten.
+	SaveRateSnapshot(ctx context.Context, snapshot *model.RateSnapshot, ttl time.Duration) error
+
+	// GetRateSnapshot retrieves the market snapshot taken when a rate was locked
+	// Returns nil, nil if not found
+	GetRateSnapshot(ctx context.Context, lockID string) (*model.RateSnapshot, error)
+
+	// DeleteRateSnapshot removes the snapshot of a lock that was discarded
+	// before it was handed out
+	DeleteRateSnapshot(ctx context.Context, lockID string) error
+
+	// SaveQuote stores a quote, keeping it for a while after it is no
+	// longer valid so it can be told apart from one never issued
+	SaveQuote(ctx context.Context, quote *model.RateQuote) error
+
+	// GetQuote retrieves a stored quote, which may be past its validity
+	// Returns nil, nil if not found
+	GetQuote(ctx context.Context, quoteID string) (*model.RateQuote, error)
+
+	// SaveCorridorOverride stores a corridor's pricing override, replacing
+	// any earlier one for the corridor
+	SaveCorridorOverride(ctx context.Context, override *model.CorridorOverride) error
+
+	// GetCorridorOverrides returns every stored corridor pricing override
+	GetCorridorOverrides(ctx context.Context) ([]model.CorridorOverride, error)
+
 	// Health checks if the repository is healthy
 	Health(ctx context.Context) error
 }
@@ -42,6 +102,14 @@ type CacheStats struct {
 	LastUpdate time.Time
 }
 
+// LockIdempotencyRecord is what an idempotency key is bound to: the lock
+// created for it and a hash of the request that created it, so a key reused
+// for a different request can be told apart from a retry
+type LockIdempotencyRecord struct {
+	LockID      string `json:"lockId"`
+	RequestHash string `json:"requestHash"`
+}
+
 // ErrNotFound is returned when a requested item is not in the repository
 type ErrNotFound struct {
 	Key string
@@ -59,3 +127,41 @@ type ErrExpired struct {
 func (e ErrExpired) Error() string {
 	return "rate lock expired: " + e.LockID
 }
+
+// Write failure kinds reported by ErrWriteFailed
+const (
+	WriteFailureOOM      = "oom"      // Redis is at maxmemory and rejects writes
+	WriteFailureReadOnly = "readonly" // the Redis node is a read-only replica
+	WriteFailureOther    = "error"    // any other write failure, e.g. a lost connection
+)
+
+// ErrWriteFailed is returned when the store rejects a write. Callers treat it
+// as the store being temporarily unavailable rather than a bad request.
+type ErrWriteFailed struct {
+	Operation string
+	Kind      string
+	Err       error
+}
+
+func (e ErrWriteFailed) Error() string {
+	return fmt.Sprintf("failed to %s (%s): %v", e.Operation, e.Kind, e.Err)
+}
+
+func (e ErrWriteFailed) Unwrap() error {
+	return e.Err
+}
+
+// WriteFailureRecorder is notified of every failed write with the operation
+// and the failure kind
+type WriteFailureRecorder func(operation, kind string)
+
+// IsConnectionError reports whether err means the store couldn't be reached
+// at all, as opposed to it rejecting the operation
+func IsConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
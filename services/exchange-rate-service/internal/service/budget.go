@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// gatherWithin calls fn for each of n items concurrently and returns once
+// every call has finished or the budget runs out (0 waits for all calls).
+// done reports which results were collected in time. Calls still running when
+// gatherWithin returns have their context cancelled so they can give up.
+func gatherWithin[T any](ctx context.Context, budget time.Duration, n int, fn func(ctx context.Context, i int) T) (results []T, done []bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i     int
+		value T
+	}
+	finished := make(chan result, n) // buffered so late calls never block
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			finished <- result{i: i, value: fn(ctx, i)}
+		}(i)
+	}
+
+	var expired <-chan time.Time
+	if budget > 0 {
+		timer := time.NewTimer(budget)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	results = make([]T, n)
+	done = make([]bool, n)
+	for remaining := n; remaining > 0; remaining-- {
+		select {
+		case r := <-finished:
+			results[r.i], done[r.i] = r.value, true
+		case <-expired:
+			return results, done
+		case <-ctx.Done():
+			return results, done
+		}
+	}
+	return results, done
+}
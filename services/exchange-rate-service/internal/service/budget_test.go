@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+func TestGetCorridorQuotes_ReturnsPartialResultsWithinBudget(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.AggregationBudgetMs = 100
+	svc.config.ProviderTimeoutMs = 300
+
+	mids := map[string]float64{"PHP": 42.50, "INR": 62.10, "IDR": 11800, "USD": 0.74}
+	cancelled := make(chan struct{})
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		if target == "IDR" {
+			// A provider that hangs until the call times out. The quote
+			// stops waiting at the budget; the fetch, shared with any
+			// other caller, runs until the provider timeout.
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        mids[target],
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+
+	start := time.Now()
+	quotes, warnings := svc.GetCorridorQuotes(context.Background(), CorridorFilter{SourceCurrency: "SGD"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected results within the 100ms budget, took %s", elapsed)
+	}
+
+	if len(quotes) != len(svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD"})) {
+		t.Fatalf("expected an entry per SGD corridor, got %d", len(quotes))
+	}
+	for _, entry := range quotes {
+		if entry.TargetCurrency == "IDR" {
+			if entry.SampleQuote != nil || entry.Error != "timed out" {
+				t.Errorf("expected SGD/IDR to time out, got quote %+v error %q", entry.SampleQuote, entry.Error)
+			}
+			continue
+		}
+		if entry.SampleQuote == nil {
+			t.Errorf("%s/%s: missing sample quote: %s", entry.SourceCurrency, entry.TargetCurrency, entry.Error)
+		}
+	}
+
+	if len(warnings) != 1 || warnings[0] != "sample quote for SGD/IDR timed out" {
+		t.Errorf("expected a timeout warning for SGD/IDR, got %v", warnings)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the slow provider call to be cancelled at the provider timeout")
+	}
+}
+
+func TestGatherWithin_NoBudgetWaitsForAll(t *testing.T) {
+	results, done := gatherWithin(context.Background(), 0, 3, func(ctx context.Context, i int) int {
+		time.Sleep(time.Duration(i) * 10 * time.Millisecond)
+		return i * i
+	})
+
+	for i := range results {
+		if !done[i] || results[i] != i*i {
+			t.Errorf("item %d: expected %d done, got %d (done=%v)", i, i*i, results[i], done[i])
+		}
+	}
+}
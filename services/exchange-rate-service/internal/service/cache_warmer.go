@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// WarmCache fetches the rate of every enabled corridor, so the first
+// requests after startup are served from cache rather than each paying a
+// provider round trip. It stops early once ctx is done; pairs it didn't get
+// to are counted as failed. It returns how many pairs were warmed and how
+// many failed.
+func (s *RateService) WarmCache(ctx context.Context) (warmed, failed int) {
+	start := time.Now()
+	for _, corridor := range s.corridors() {
+		if !corridor.Enabled {
+			continue
+		}
+		if ctx.Err() != nil {
+			failed++
+			continue
+		}
+		if _, err := s.GetRate(ctx, corridor.SourceCurrency, corridor.TargetCurrency); err != nil {
+			failed++
+			s.logger.Warn("Failed to warm rate cache",
+				zap.String("from", corridor.SourceCurrency),
+				zap.String("to", corridor.TargetCurrency),
+				zap.Error(err),
+			)
+			continue
+		}
+		warmed++
+	}
+
+	s.logger.Info("Rate cache warmed",
+		zap.Int("warmed", warmed),
+		zap.Int("failed", failed),
+		zap.Duration("took", time.Since(start)),
+	)
+	return warmed, failed
+}
+
+// RefreshCorridorRates fetches fresh rates for the enabled corridors every
+// interval until ctx is done, so their cached rates never expire and requests
+// don't block on the provider. Set the interval just under the rate
+// validity. Rounds are skipped while the repository is unavailable, since
+// the rates couldn't be cached.
+func (s *RateService) RefreshCorridorRates(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refreshCorridorRates(ctx)
+		}
+	}
+}
+
+// refreshCorridorRates fetches and caches the enabled corridors' rates from
+// the provider, bypassing the cache
+func (s *RateService) refreshCorridorRates(ctx context.Context) {
+	if err := s.repository.Health(ctx); err != nil {
+		s.logger.Warn("Repository unavailable, skipping corridor rate refresh", zap.Error(err))
+		return
+	}
+
+	refreshed := make(map[provider.CurrencyPair]bool)
+	for _, corridor := range s.corridors() {
+		if !corridor.Enabled {
+			continue
+		}
+		// With canonical ordering both directions share one cached rate
+		pair, _ := s.ratePair(corridor.SourceCurrency, corridor.TargetCurrency)
+		if refreshed[pair] {
+			continue
+		}
+		refreshed[pair] = true
+
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.fetchProviderRate(ctx, pair.Source, pair.Target); err != nil {
+			s.logger.Warn("Failed to refresh corridor rate",
+				zap.String("from", pair.Source),
+				zap.String("to", pair.Target),
+				zap.Error(err),
+			)
+		}
+	}
+}
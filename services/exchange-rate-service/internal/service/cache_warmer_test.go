@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+func TestWarmCache_ServesCorridorsFromCache(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		calls.Add(1)
+		return (&MockProvider{}).GetRate(ctx, source, target)
+	}
+
+	enabled := 0
+	for _, c := range model.Corridors {
+		if c.Enabled {
+			enabled++
+		}
+	}
+
+	warmed, failed := svc.WarmCache(ctx)
+	if warmed != enabled || failed != 0 {
+		t.Fatalf("expected %d corridors warmed and none failed, got %d and %d", enabled, warmed, failed)
+	}
+
+	fetched := calls.Load()
+	for _, c := range model.Corridors {
+		if !c.Enabled {
+			continue
+		}
+		_, cacheHit, err := svc.LookupRate(ctx, c.SourceCurrency, c.TargetCurrency)
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", c.SourceCurrency, c.TargetCurrency, err)
+		}
+		if !cacheHit {
+			t.Errorf("%s/%s: expected the warmed rate to be served from cache", c.SourceCurrency, c.TargetCurrency)
+		}
+	}
+	if calls.Load() != fetched {
+		t.Errorf("expected no provider calls after warming, got %d", calls.Load()-fetched)
+	}
+}
+
+func TestWarmCache_CountsFailures(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		if target == "PHP" {
+			return nil, errors.New("provider unavailable")
+		}
+		return (&MockProvider{}).GetRate(ctx, source, target)
+	}
+
+	warmed, failed := svc.WarmCache(context.Background())
+	if failed == 0 || warmed == 0 {
+		t.Errorf("expected the PHP corridors to fail and the rest to warm, got %d warmed and %d failed", warmed, failed)
+	}
+}
+
+func TestWarmCache_StopsWhenContextDone(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	warmed, failed := svc.WarmCache(ctx)
+	if warmed != 0 || failed == 0 {
+		t.Errorf("expected nothing warmed once the context is done, got %d warmed and %d failed", warmed, failed)
+	}
+}
+
+func TestRefreshCorridorRates_KeepsCachedRatesFresh(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	svc.config.RateCacheTTL = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetchedAt := func() time.Time {
+		mockRepo.mu.Lock()
+		defer mockRepo.mu.Unlock()
+		if rate, ok := mockRepo.rates["SGD:PHP"]; ok {
+			return rate.FetchedAt
+		}
+		return time.Time{}
+	}
+
+	go svc.RefreshCorridorRates(ctx, 20*time.Millisecond)
+
+	// No client requests: only the refresher caches the rate
+	var first time.Time
+	deadline := time.Now().Add(time.Second)
+	for first = fetchedAt(); first.IsZero() && time.Now().Before(deadline); first = fetchedAt() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if first.IsZero() {
+		t.Fatal("expected the refresher to cache the SGD/PHP rate")
+	}
+	for time.Now().Before(deadline) && !fetchedAt().After(first) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !fetchedAt().After(first) {
+		t.Errorf("expected the cached SGD/PHP rate to be refreshed after %s", first)
+	}
+}
+
+func TestRefreshCorridorRates_SkipsWhileRepositoryUnavailable(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	mockRepo.HealthFunc = func(ctx context.Context) error {
+		return errors.New("redis unavailable")
+	}
+	var calls atomic.Int32
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		calls.Add(1)
+		return (&MockProvider{}).GetRate(ctx, source, target)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	svc.RefreshCorridorRates(ctx, 10*time.Millisecond)
+
+	if calls.Load() != 0 {
+		t.Errorf("expected no provider calls while the repository is unavailable, got %d", calls.Load())
+	}
+}
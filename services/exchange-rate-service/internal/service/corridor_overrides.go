@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCorridorPricing is returned when a corridor repricing request
+// has no percentages or a percentage that isn't a number in [0, 100)
+type ErrInvalidCorridorPricing struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e ErrInvalidCorridorPricing) Error() string {
+	if e.Field == "" {
+		return "invalid corridor pricing: " + e.Reason
+	}
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// corridorOverrides holds the pricing overrides applied over the compiled-in
+// corridors, so margins and fees can change without a redeploy
+type corridorOverrides struct {
+	mu     sync.RWMutex
+	byPair map[provider.CurrencyPair]model.CorridorOverride
+}
+
+// get returns the override for a corridor, if there is one
+func (o *corridorOverrides) get(from, to string) (model.CorridorOverride, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	override, ok := o.byPair[provider.CurrencyPair{Source: from, Target: to}]
+	return override, ok
+}
+
+// apply returns c with its override's pricing, if it has one
+func (o *corridorOverrides) apply(c model.Corridor) model.Corridor {
+	if override, ok := o.get(c.SourceCurrency, c.TargetCurrency); ok {
+		return override.Apply(c)
+	}
+	return c
+}
+
+func (o *corridorOverrides) set(override model.CorridorOverride) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.byPair == nil {
+		o.byPair = make(map[provider.CurrencyPair]model.CorridorOverride)
+	}
+	o.byPair[provider.CurrencyPair{Source: override.SourceCurrency, Target: override.TargetCurrency}] = override
+}
+
+func (o *corridorOverrides) replace(overrides []model.CorridorOverride) {
+	byPair := make(map[provider.CurrencyPair]model.CorridorOverride, len(overrides))
+	for _, override := range overrides {
+		byPair[provider.CurrencyPair{Source: override.SourceCurrency, Target: override.TargetCurrency}] = override
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.byPair = byPair
+}
+
+// corridors returns every corridor with its pricing overrides applied
+func (s *RateService) corridors() []model.Corridor {
+	corridors := make([]model.Corridor, len(model.Corridors))
+	for i, c := range model.Corridors {
+		corridors[i] = s.overrides.apply(c)
+	}
+	return corridors
+}
+
+// UpdateCorridorPricing overrides a corridor's margin and fee percentages,
+// keeping whichever of them req omits. The override is stored in the
+// repository, so it survives restarts and reaches other instances when they
+// next load overrides, and applies to this instance's quotes straight away.
+func (s *RateService) UpdateCorridorPricing(ctx context.Context, from, to string, req model.UpdateCorridorRequest) (*model.Corridor, error) {
+	if s.baseCorridor(from, to) == nil {
+		return nil, ErrCorridorNotFound{SourceCurrency: from, TargetCurrency: to}
+	}
+	margin, fee := strings.TrimSpace(req.MarginPercentage), strings.TrimSpace(req.FeePercentage)
+	if margin == "" && fee == "" {
+		return nil, ErrInvalidCorridorPricing{Reason: "marginPercentage or feePercentage is required"}
+	}
+	if err := checkPercentage("marginPercentage", margin); err != nil {
+		return nil, err
+	}
+	if err := checkPercentage("feePercentage", fee); err != nil {
+		return nil, err
+	}
+
+	override, _ := s.overrides.get(from, to)
+	override.SourceCurrency, override.TargetCurrency = from, to
+	if margin != "" {
+		override.MarginPercentage = margin
+	}
+	if fee != "" {
+		override.FeePercentage = fee
+	}
+	override.UpdatedAt = time.Now()
+
+	if err := s.repository.SaveCorridorOverride(ctx, &override); err != nil {
+		return nil, fmt.Errorf("failed to save corridor override: %w", err)
+	}
+	s.overrides.set(override)
+
+	s.logger.Info("Corridor pricing updated",
+		zap.String("from", from),
+		zap.String("to", to),
+		zap.String("marginPercentage", override.MarginPercentage),
+		zap.String("feePercentage", override.FeePercentage),
+	)
+	return s.getCorridor(from, to), nil
+}
+
+// checkPercentage returns ErrInvalidCorridorPricing unless value is empty or
+// a percentage in [0, 100)
+func checkPercentage(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	percentage, err := decimal.NewFromString(value)
+	if err != nil {
+		return ErrInvalidCorridorPricing{Field: field, Value: value, Reason: "not a number"}
+	}
+	if percentage.IsNegative() || percentage.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return ErrInvalidCorridorPricing{Field: field, Value: value, Reason: "must be at least 0 and below 100"}
+	}
+	return nil
+}
+
+// LoadCorridorOverrides replaces the pricing overrides in effect with those
+// stored in the repository
+func (s *RateService) LoadCorridorOverrides(ctx context.Context) error {
+	overrides, err := s.repository.GetCorridorOverrides(ctx)
+	if err != nil {
+		return err
+	}
+	s.overrides.replace(overrides)
+	return nil
+}
+
+// WatchCorridorOverrides reloads the stored pricing overrides every interval
+// until ctx is done, so overrides made through other instances take effect
+func (s *RateService) WatchCorridorOverrides(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.LoadCorridorOverrides(ctx); err != nil {
+				s.logger.Warn("Failed to reload corridor overrides", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+)
+
+func TestUpdateCorridorPricing_AppliesToQuotes(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	corridor, err := svc.UpdateCorridorPricing(ctx, "SGD", "PHP", model.UpdateCorridorRequest{
+		MarginPercentage: "1.0",
+		FeePercentage:    "0.8",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if corridor.MarginPercentage != "1.0" || corridor.FeePercentage != "0.8" {
+		t.Errorf("expected margin 1.0 and fee 0.8, got %s and %s", corridor.MarginPercentage, corridor.FeePercentage)
+	}
+
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fee := quote.Fee.StringFixed(2); fee != "8.00" {
+		t.Errorf("expected fee 8.00 at 0.8%%, got %s", fee)
+	}
+	if rate := quote.ExchangeRate.InexactFloat64(); math.Abs(rate-42.29*0.99) > 0.0001 {
+		t.Errorf("expected exchange rate %f with a 1%% margin, got %f", 42.29*0.99, rate)
+	}
+}
+
+func TestUpdateCorridorPricing_KeepsOmittedPercentage(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	if _, err := svc.UpdateCorridorPricing(ctx, "SGD", "PHP", model.UpdateCorridorRequest{MarginPercentage: "1.0"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	corridor, err := svc.UpdateCorridorPricing(ctx, "SGD", "PHP", model.UpdateCorridorRequest{FeePercentage: "0.8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if corridor.MarginPercentage != "1.0" || corridor.FeePercentage != "0.8" {
+		t.Errorf("expected margin 1.0 and fee 0.8, got %s and %s", corridor.MarginPercentage, corridor.FeePercentage)
+	}
+}
+
+func TestUpdateCorridorPricing_RejectsInvalidPricing(t *testing.T) {
+	svc, _, repo := newTestService()
+
+	for _, req := range []model.UpdateCorridorRequest{
+		{},
+		{MarginPercentage: "abc"},
+		{MarginPercentage: "-0.1"},
+		{FeePercentage: "100"},
+	} {
+		_, err := svc.UpdateCorridorPricing(context.Background(), "SGD", "PHP", req)
+		if _, ok := err.(ErrInvalidCorridorPricing); !ok {
+			t.Errorf("%+v: expected ErrInvalidCorridorPricing, got %v", req, err)
+		}
+	}
+	if len(repo.overrides) != 0 {
+		t.Errorf("expected nothing stored, got %d overrides", len(repo.overrides))
+	}
+}
+
+func TestUpdateCorridorPricing_UnknownCorridor(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	_, err := svc.UpdateCorridorPricing(context.Background(), "SGD", "XXX", model.UpdateCorridorRequest{MarginPercentage: "1.0"})
+	if _, ok := err.(ErrCorridorNotFound); !ok {
+		t.Fatalf("expected ErrCorridorNotFound, got %v", err)
+	}
+}
+
+func TestLoadCorridorOverrides_AppliesStoredOverrides(t *testing.T) {
+	svc, _, repo := newTestService()
+	ctx := context.Background()
+
+	if _, err := svc.UpdateCorridorPricing(ctx, "SGD", "PHP", model.UpdateCorridorRequest{FeePercentage: "0.8"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Another instance sharing the repository picks the override up on load
+	other := NewRateService(svc.config, &MockProvider{}, repo, svc.logger)
+	if err := other.LoadCorridorOverrides(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	quote, err := other.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fee := quote.Fee.StringFixed(2); fee != "8.00" {
+		t.Errorf("expected fee 8.00 from the stored override, got %s", fee)
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// errorRateTracker computes a rolling provider error rate over a time window
+// using one bucket per second, so memory stays constant regardless of traffic.
+type errorRateTracker struct {
+	mu      sync.Mutex
+	window  time.Duration
+	buckets []errorRateBucket
+}
+
+type errorRateBucket struct {
+	second   int64
+	requests int
+	errors   int
+}
+
+func newErrorRateTracker(window time.Duration) *errorRateTracker {
+	seconds := int(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &errorRateTracker{
+		window:  time.Duration(seconds) * time.Second,
+		buckets: make([]errorRateBucket, seconds),
+	}
+}
+
+// record adds a provider call outcome to the current bucket
+func (t *errorRateTracker) record(failed bool) {
+	now := time.Now().Unix()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bucket := &t.buckets[now%int64(len(t.buckets))]
+	if bucket.second != now {
+		*bucket = errorRateBucket{second: now}
+	}
+	bucket.requests++
+	if failed {
+		bucket.errors++
+	}
+}
+
+// rate returns the error rate and the number of provider calls in the window
+func (t *errorRateTracker) rate() (float64, int) {
+	cutoff := time.Now().Add(-t.window).Unix()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var requests, errors int
+	for _, bucket := range t.buckets {
+		if bucket.second > cutoff {
+			requests += bucket.requests
+			errors += bucket.errors
+		}
+	}
+
+	if requests == 0 {
+		return 0, 0
+	}
+	return float64(errors) / float64(requests), requests
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"go.uber.org/zap"
+)
+
+// LockRequest is one pair to lock in a batch of rate locks
+type LockRequest struct {
+	SourceCurrency  string
+	TargetCurrency  string
+	DurationSeconds int
+}
+
+// ErrBatchLockFailed is returned when one pair of a batch can't be locked.
+// The locks already taken for the batch have been released by then.
+type ErrBatchLockFailed struct {
+	Index          int
+	SourceCurrency string
+	TargetCurrency string
+	Err            error
+}
+
+func (e ErrBatchLockFailed) Error() string {
+	return fmt.Sprintf("failed to lock %s/%s (pair %d of batch): %v", e.SourceCurrency, e.TargetCurrency, e.Index+1, e.Err)
+}
+
+func (e ErrBatchLockFailed) Unwrap() error {
+	return e.Err
+}
+
+// LockRates locks the rates of several pairs for a transfer split across
+// corridors, returning the locks in request order. Either every pair is
+// locked or none is: if one fails, the locks already taken are released and
+// ErrBatchLockFailed is returned.
+func (s *RateService) LockRates(ctx context.Context, reqs []LockRequest) ([]*model.LockedRate, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("no pairs to lock")
+	}
+
+	locks := make([]*model.LockedRate, 0, len(reqs))
+	for i, req := range reqs {
+		locked, err := s.LockRate(ctx, req.SourceCurrency, req.TargetCurrency, req.DurationSeconds)
+		if err != nil {
+			s.releaseLocks(ctx, locks)
+			return nil, ErrBatchLockFailed{
+				Index:          i,
+				SourceCurrency: req.SourceCurrency,
+				TargetCurrency: req.TargetCurrency,
+				Err:            err,
+			}
+		}
+		locks = append(locks, locked)
+	}
+
+	s.logger.Info("Rate locks batch locked", zap.Int("locks", len(locks)))
+	return locks, nil
+}
+
+// releaseLocks releases the locks of a failed batch. The release goes ahead
+// even if ctx is cancelled, since ctx ending may be why the batch failed;
+// locks that can't be released are left to expire.
+func (s *RateService) releaseLocks(ctx context.Context, locks []*model.LockedRate) {
+	ctx = context.WithoutCancel(ctx)
+	for _, locked := range locks {
+		if err := s.DeleteLockedRate(ctx, locked.LockID); err != nil {
+			s.logger.Warn("Failed to release lock of failed batch, leaving it to expire",
+				zap.String("lockId", locked.LockID),
+				zap.Error(err),
+			)
+		}
+	}
+}
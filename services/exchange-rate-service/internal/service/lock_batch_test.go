@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+func TestLockRates_LocksEveryPair(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+
+	locks, err := svc.LockRates(context.Background(), []LockRequest{
+		{SourceCurrency: "SGD", TargetCurrency: "PHP", DurationSeconds: 60},
+		{SourceCurrency: "USD", TargetCurrency: "PHP"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(locks) != 2 {
+		t.Fatalf("expected 2 locks, got %d", len(locks))
+	}
+	if locks[0].Rate.SourceCurrency != "SGD" || locks[1].Rate.SourceCurrency != "USD" {
+		t.Errorf("expected locks in request order, got %s then %s", locks[0].Rate.SourceCurrency, locks[1].Rate.SourceCurrency)
+	}
+	for _, locked := range locks {
+		if _, ok := mockRepo.lockedRates[locked.LockID]; !ok {
+			t.Errorf("expected lock %s to be stored", locked.LockID)
+		}
+	}
+}
+
+func TestLockRates_ReleasesLocksWhenAPairFails(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	down := provider.ErrProviderUnavailable{Provider: "mock", Reason: "down"}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		if target == "IDR" {
+			return nil, down
+		}
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50, Source: "mock"}, nil
+	}
+
+	_, err := svc.LockRates(context.Background(), []LockRequest{
+		{SourceCurrency: "SGD", TargetCurrency: "PHP"},
+		{SourceCurrency: "SGD", TargetCurrency: "IDR"},
+		{SourceCurrency: "USD", TargetCurrency: "PHP"},
+	})
+
+	var batchErr ErrBatchLockFailed
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected ErrBatchLockFailed, got %v", err)
+	}
+	if batchErr.Index != 1 || batchErr.TargetCurrency != "IDR" {
+		t.Errorf("expected the SGD/IDR pair to be reported, got pair %d %s/%s", batchErr.Index, batchErr.SourceCurrency, batchErr.TargetCurrency)
+	}
+	if !errors.Is(err, down) {
+		t.Errorf("expected the provider error to be wrapped, got %v", err)
+	}
+	if len(mockRepo.lockedRates) != 0 {
+		t.Errorf("expected the SGD/PHP lock to be released, %d locks remain", len(mockRepo.lockedRates))
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+)
+
+// lockFallback keeps locked rates in memory while the repository can't be
+// reached, so transfers can still lock rates during a Redis outage. It is
+// best effort: locks live only in this instance, at most maxEntries are kept
+// (the one closest to expiry is evicted first) and each is dropped once it
+// expires. The idempotency keys claimed for those locks are kept alongside
+// them on the same terms, so retries during the outage still find their lock.
+type lockFallback struct {
+	mu         sync.Mutex
+	maxEntries int
+	locks      map[string]model.LockedRate
+	keys       map[string]fallbackKey // by idempotency key
+}
+
+// fallbackKey is an idempotency key claimed while the repository was unreachable
+type fallbackKey struct {
+	record    repository.LockIdempotencyRecord
+	expiresAt time.Time
+}
+
+func newLockFallback(maxEntries int) *lockFallback {
+	return &lockFallback{
+		maxEntries: maxEntries,
+		locks:      make(map[string]model.LockedRate),
+		keys:       make(map[string]fallbackKey),
+	}
+}
+
+// save stores a copy of locked until it expires
+func (f *lockFallback) save(locked *model.LockedRate) {
+	now := time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removeExpired(now)
+	if _, exists := f.locks[locked.LockID]; !exists && len(f.locks) >= f.maxEntries {
+		var evict string
+		var soonest time.Time
+		for id, l := range f.locks {
+			if evict == "" || l.ExpiresAt.Before(soonest) {
+				evict, soonest = id, l.ExpiresAt
+			}
+		}
+		delete(f.locks, evict)
+	}
+	f.locks[locked.LockID] = *locked
+}
+
+// get returns a copy of the lock, or nil if it isn't held or has expired
+func (f *lockFallback) get(lockID string) *model.LockedRate {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	locked, ok := f.locks[lockID]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(locked.ExpiresAt) {
+		delete(f.locks, lockID)
+		return nil
+	}
+	return &locked
+}
+
+// remove drops the lock and reports whether it was held
+func (f *lockFallback) remove(lockID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.locks[lockID]
+	delete(f.locks, lockID)
+	return ok
+}
+
+// claimKey binds an idempotency key to record until expiresAt if the key is
+// unused, returning the record bound to it
+func (f *lockFallback) claimKey(key string, record repository.LockIdempotencyRecord, expiresAt time.Time) *repository.LockIdempotencyRecord {
+	now := time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removeExpired(now)
+	if existing, ok := f.keys[key]; ok {
+		return &existing.record
+	}
+	if len(f.keys) >= f.maxEntries {
+		var evict string
+		var soonest time.Time
+		for k, held := range f.keys {
+			if evict == "" || held.expiresAt.Before(soonest) {
+				evict, soonest = k, held.expiresAt
+			}
+		}
+		delete(f.keys, evict)
+	}
+	f.keys[key] = fallbackKey{record: record, expiresAt: expiresAt}
+	return &record
+}
+
+// getKey returns the record bound to an idempotency key, or nil if it isn't
+// held or has expired
+func (f *lockFallback) getKey(key string) *repository.LockIdempotencyRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	held, ok := f.keys[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(held.expiresAt) {
+		delete(f.keys, key)
+		return nil
+	}
+	return &held.record
+}
+
+// removeKey drops an idempotency key
+func (f *lockFallback) removeKey(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.keys, key)
+}
+
+// removeExpired drops every lock and key past its expiry. Callers hold mu.
+func (f *lockFallback) removeExpired(now time.Time) {
+	for id, l := range f.locks {
+		if now.After(l.ExpiresAt) {
+			delete(f.locks, id)
+		}
+	}
+	for key, held := range f.keys {
+		if now.After(held.expiresAt) {
+			delete(f.keys, key)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// unreachableLockRepository fails locked rate reads and writes as if Redis
+// were down
+type unreachableLockRepository struct {
+	*MockRepository
+}
+
+func connectionRefused() error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+}
+
+func (r *unreachableLockRepository) SaveLockedRate(ctx context.Context, locked *model.LockedRate) error {
+	return repository.ErrWriteFailed{Operation: "save locked rate", Kind: repository.WriteFailureOther, Err: connectionRefused()}
+}
+
+func (r *unreachableLockRepository) GetLockedRate(ctx context.Context, lockID string) (*model.LockedRate, error) {
+	return nil, connectionRefused()
+}
+
+func (r *unreachableLockRepository) DeleteLockedRate(ctx context.Context, lockID string) error {
+	return connectionRefused()
+}
+
+func (r *unreachableLockRepository) ExtendLockedRate(ctx context.Context, lockID string, newExpiry time.Time) error {
+	return connectionRefused()
+}
+
+func (r *unreachableLockRepository) GetLockIdempotencyKey(ctx context.Context, key string) (*repository.LockIdempotencyRecord, error) {
+	return nil, connectionRefused()
+}
+
+func (r *unreachableLockRepository) ClaimLockIdempotencyKey(ctx context.Context, key string, record repository.LockIdempotencyRecord, ttl time.Duration) (*repository.LockIdempotencyRecord, error) {
+	return nil, repository.ErrWriteFailed{Operation: "claim lock idempotency key", Kind: repository.WriteFailureOther, Err: connectionRefused()}
+}
+
+func (r *unreachableLockRepository) CountActiveLocks(ctx context.Context, source, target string) (int, error) {
+	return 0, connectionRefused()
+}
+
+func newUnreachableLockService(fallbackSize int) *RateService {
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 60, LockFallbackSize: fallbackSize}
+	return NewRateService(cfg, &MockProvider{}, &unreachableLockRepository{NewMockRepository()}, zap.NewNop())
+}
+
+func TestLockRate_FallsBackToMemoryWhenRepositoryUnreachable(t *testing.T) {
+	svc := newUnreachableLockService(10)
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("expected the lock to be kept in memory, got: %v", err)
+	}
+
+	held, err := svc.GetLockedRate(ctx, locked.LockID)
+	if err != nil {
+		t.Fatalf("expected the lock from memory, got: %v", err)
+	}
+	if held.Expired || held.Rate.BuyRate != locked.Rate.BuyRate {
+		t.Errorf("expected the locked rate %s, got %+v", locked.Rate.BuyRate, held)
+	}
+
+	if _, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000); err != nil {
+		t.Errorf("expected quotes from the lock to work, got: %v", err)
+	}
+
+	if _, err := svc.GetLockedRate(ctx, "unknown"); err == nil {
+		t.Error("expected the connection error for a lock that isn't held in memory")
+	}
+}
+
+func TestDeleteLockedRate_ReleasesLockHeldInMemory(t *testing.T) {
+	svc := newUnreachableLockService(10)
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("expected the lock to be kept in memory, got: %v", err)
+	}
+
+	if err := svc.DeleteLockedRate(ctx, locked.LockID); err != nil {
+		t.Fatalf("expected the lock to be released from memory, got: %v", err)
+	}
+	if _, err := svc.GetLockedRate(ctx, locked.LockID); err == nil {
+		t.Error("expected the released lock no longer to be served from memory")
+	}
+
+	if err := svc.DeleteLockedRate(ctx, locked.LockID); err == nil {
+		t.Error("expected the connection error once the lock isn't held in memory")
+	}
+}
+
+func TestLockRateIdempotent_FallsBackToMemoryWhenRepositoryUnreachable(t *testing.T) {
+	svc := newUnreachableLockService(10)
+	ctx := context.Background()
+
+	first, err := svc.LockRateIdempotent(ctx, "retry-key", "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("expected the lock to be kept in memory, got: %v", err)
+	}
+	second, err := svc.LockRateIdempotent(ctx, "retry-key", "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("expected the retry to find the lock in memory, got: %v", err)
+	}
+	if second.LockID != first.LockID {
+		t.Errorf("expected the retry to return lock %s, got %s", first.LockID, second.LockID)
+	}
+}
+
+func TestExtendLockedRate_ExtendsLockHeldInMemory(t *testing.T) {
+	svc := newUnreachableLockService(10)
+	svc.config.MaxLockDuration = 300
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("expected the lock to be kept in memory, got: %v", err)
+	}
+
+	extended, err := svc.ExtendLockedRate(ctx, locked.LockID, 30)
+	if err != nil {
+		t.Fatalf("expected the lock to be extended in memory, got: %v", err)
+	}
+	if want := locked.ExpiresAt.Add(30 * time.Second); !extended.ExpiresAt.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, extended.ExpiresAt)
+	}
+
+	held, err := svc.GetLockedRate(ctx, locked.LockID)
+	if err != nil {
+		t.Fatalf("expected the lock from memory, got: %v", err)
+	}
+	if !held.ExpiresAt.Equal(extended.ExpiresAt) {
+		t.Errorf("expected the extension to be kept in memory, got expiry %v", held.ExpiresAt)
+	}
+}
+
+func TestExtendLockedRates_ExtendsLocksHeldInMemory(t *testing.T) {
+	svc := newUnreachableLockService(10)
+	svc.config.MaxLockDuration = 300
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("expected the lock to be kept in memory, got: %v", err)
+	}
+
+	results, err := svc.ExtendLockedRates(ctx, []string{locked.LockID}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := locked.ExpiresAt.Add(30 * time.Second)
+	if !results[0].Extended || results[0].ExpiresAt == nil || !results[0].ExpiresAt.Equal(want) {
+		t.Fatalf("expected the lock to be extended in memory to %v, got %+v", want, results[0])
+	}
+
+	held, err := svc.GetLockedRate(ctx, locked.LockID)
+	if err != nil {
+		t.Fatalf("expected the lock from memory, got: %v", err)
+	}
+	if !held.ExpiresAt.Equal(want) {
+		t.Errorf("expected the extension to be kept in memory, got expiry %v", held.ExpiresAt)
+	}
+}
+
+func TestLockRate_WithoutFallbackFailsWhenRepositoryUnreachable(t *testing.T) {
+	svc := newUnreachableLockService(0)
+
+	if _, err := svc.LockRate(context.Background(), "SGD", "PHP", 60); err == nil {
+		t.Error("expected an error with the fallback disabled")
+	}
+}
+
+func TestLockFallback_BoundedAndExpiring(t *testing.T) {
+	fallback := newLockFallback(2)
+	now := time.Now()
+
+	fallback.save(&model.LockedRate{LockID: "soonest", ExpiresAt: now.Add(10 * time.Second)})
+	fallback.save(&model.LockedRate{LockID: "later", ExpiresAt: now.Add(time.Minute)})
+	fallback.save(&model.LockedRate{LockID: "latest", ExpiresAt: now.Add(2 * time.Minute)})
+
+	if fallback.get("soonest") != nil {
+		t.Error("expected the lock closest to expiry to be evicted when full")
+	}
+	if fallback.get("later") == nil || fallback.get("latest") == nil {
+		t.Error("expected the other locks to be kept")
+	}
+
+	fallback.save(&model.LockedRate{LockID: "expired", ExpiresAt: now.Add(-time.Second)})
+	if fallback.get("expired") != nil {
+		t.Error("expected an expired lock not to be returned")
+	}
+}
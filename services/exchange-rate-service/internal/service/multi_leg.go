@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// findCorridorPath returns the shortest chain of enabled corridors converting
+// from into to, using at most maxLegs corridors, or nil if there is none.
+// Corridors are tried in the order given, so the path is deterministic.
+func findCorridorPath(corridors []model.Corridor, from, to string, maxLegs int) []model.Corridor {
+	type step struct {
+		currency string
+		path     []model.Corridor
+	}
+
+	visited := map[string]bool{from: true}
+	frontier := []step{{currency: from}}
+	for legs := 0; legs < maxLegs && len(frontier) > 0; legs++ {
+		var next []step
+		for _, current := range frontier {
+			for _, c := range corridors {
+				if !c.Enabled || c.SourceCurrency != current.currency || visited[c.TargetCurrency] {
+					continue
+				}
+				path := append(append([]model.Corridor(nil), current.path...), c)
+				if c.TargetCurrency == to {
+					return path
+				}
+				visited[c.TargetCurrency] = true
+				next = append(next, step{currency: c.TargetCurrency, path: path})
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// getMultiLegQuote quotes a pair without a direct corridor by converting
+// through a path of enabled corridors. Each leg is converted at its own buy
+// rate and charged its own fee; fees are collected up front, so later legs'
+// fees are converted back to the source currency at the rates of the legs
+// before them. The amount each leg converts must be within its corridor's
+// range, as for a direct quote.
+func (s *RateService) getMultiLegQuote(ctx context.Context, from, to string, sourceAmount float64) (*model.RateQuote, error) {
+	path := findCorridorPath(s.corridors(), from, to, s.config.MaxQuoteLegs)
+	if len(path) < 2 {
+		return nil, ErrCorridorNotFound{SourceCurrency: from, TargetCurrency: to}
+	}
+
+	legs := make([]model.QuoteLeg, 0, len(path))
+	amount := model.AmountFromFloat(sourceAmount, from)
+	compositeRate, compositeMid := decimal.NewFromInt(1), decimal.NewFromInt(1)
+	totalFee := decimal.Zero
+	var validUntil time.Time
+	for i := range path {
+		corridor := &path[i]
+		rate, err := s.quoteRate(ctx, corridor.SourceCurrency, corridor.TargetCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkSourceAmount(corridor, amount); err != nil {
+			return nil, err
+		}
+		leg, err := s.priceConversion(rate, corridor, amount)
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, model.QuoteLeg{
+			SourceCurrency:   leg.SourceCurrency,
+			TargetCurrency:   leg.TargetCurrency,
+			SourceAmount:     leg.SourceAmount,
+			TargetAmount:     leg.TargetAmount,
+			ExchangeRate:     leg.ExchangeRate,
+			MidMarketRate:    leg.MidMarketRate,
+			MarginPercentage: rate.MarginPercentage,
+			Fee:              leg.Fee,
+		})
+
+		totalFee = totalFee.Add(leg.Fee.Div(compositeRate))
+		compositeRate = compositeRate.Mul(leg.ExchangeRate.Decimal)
+		compositeMid = compositeMid.Mul(leg.MidMarketRate.Decimal)
+		amount = leg.TargetAmount
+		if validUntil.IsZero() || leg.ValidUntil.Before(validUntil) {
+			validUntil = leg.ValidUntil
+		}
+	}
+
+	source := model.AmountFromFloat(sourceAmount, from)
+	fee := model.NewAmount(totalFee, from)
+	totalCost := model.Amount{Decimal: source.Add(fee.Decimal)}
+	if err := s.checkQuoteTotal(totalCost, from); err != nil {
+		return nil, err
+	}
+
+	margin := decimal.NewFromInt(1).Sub(compositeRate.Div(compositeMid)).Mul(decimal.NewFromInt(100))
+	quote := &model.RateQuote{
+		SourceCurrency:   from,
+		TargetCurrency:   to,
+		SourceAmount:     source,
+		TargetAmount:     amount,
+		ExchangeRate:     model.NewRateValue(compositeRate),
+		MidMarketRate:    model.NewRateValue(compositeMid),
+		Fee:              fee,
+		TotalCost:        totalCost,
+		EffectiveRate:    effectiveRate(amount, totalCost),
+		ValidUntil:       validUntil,
+		QuoteID:          uuid.New().String(),
+		Legs:             legs,
+		MarginPercentage: margin.StringFixed(2),
+		SourcePrecision:  model.Precision(from),
+		TargetPrecision:  model.Precision(to),
+	}
+	if err := s.recordQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+// addCorridor adds a corridor for the duration of the test
+func addCorridor(t *testing.T, corridor model.Corridor) {
+	t.Helper()
+	original := model.Corridors
+	model.Corridors = append(append([]model.Corridor(nil), original...), corridor)
+	t.Cleanup(func() { model.Corridors = original })
+}
+
+func newMultiLegTestService(t *testing.T, maxLegs int) *RateService {
+	t.Helper()
+	addCorridor(t, model.Corridor{
+		SourceCurrency:   "GBP",
+		TargetCurrency:   "USD",
+		Enabled:          true,
+		FeePercentage:    "0.5",
+		FeeMinimum:       model.Money{Currency: "GBP", Amount: "2.00"},
+		MarginPercentage: "0.1",
+		MinTargetAmount:  model.Money{Currency: "USD", Amount: "1.00"},
+		PayoutMethods:    []string{"BANK_ACCOUNT"},
+	})
+
+	svc, mockProvider, _ := newTestService()
+	svc.config.MaxQuoteLegs = maxLegs
+	mids := map[string]float64{"GBP/USD": 1.25, "USD/PHP": 56}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        mids[source+"/"+target],
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+	return svc
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestGetQuote_MultiLegThroughIntermediateCurrency(t *testing.T) {
+	svc := newMultiLegTestService(t, 2)
+
+	quote, err := svc.GetQuote(context.Background(), "GBP", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(quote.Legs) != 2 {
+		t.Fatalf("expected 2 legs, got %+v", quote.Legs)
+	}
+	first, second := quote.Legs[0], quote.Legs[1]
+	if first.SourceCurrency != "GBP" || first.TargetCurrency != "USD" || second.SourceCurrency != "USD" || second.TargetCurrency != "PHP" {
+		t.Fatalf("expected GBP->USD->PHP, got %s->%s, %s->%s",
+			first.SourceCurrency, first.TargetCurrency, second.SourceCurrency, second.TargetCurrency)
+	}
+
+	// GBP/USD: 1.25 less 0.1% margin; USD/PHP: 56 less 0.25% margin
+	if !approxEqual(first.ExchangeRate.InexactFloat64(), 1.24875) || !approxEqual(second.ExchangeRate.InexactFloat64(), 55.86) {
+		t.Errorf("unexpected leg rates %f and %f", first.ExchangeRate.InexactFloat64(), second.ExchangeRate.InexactFloat64())
+	}
+	if !approxEqual(second.SourceAmount.InexactFloat64(), first.TargetAmount.InexactFloat64()) {
+		t.Errorf("expected the second leg to convert the first leg's %f, got %f", first.TargetAmount.InexactFloat64(), second.SourceAmount.InexactFloat64())
+	}
+
+	if !approxEqual(quote.ExchangeRate.InexactFloat64(), 1.24875*55.86) {
+		t.Errorf("expected composite rate %f, got %f", 1.24875*55.86, quote.ExchangeRate.InexactFloat64())
+	}
+	if !approxEqual(quote.MidMarketRate.InexactFloat64(), 70) {
+		t.Errorf("expected composite mid rate 70, got %f", quote.MidMarketRate.InexactFloat64())
+	}
+	// USD 1248.75 at 55.86 is PHP 69755.175, rounded half-up to the cent
+	if !approxEqual(quote.TargetAmount.InexactFloat64(), 69755.18) {
+		t.Errorf("expected target amount 69755.18, got %s", quote.TargetAmount)
+	}
+
+	// 0.5% of GBP 1000, plus 0.4% of USD 1248.75 (rounded to USD 5.00)
+	// converted back at 1.24875
+	if !approxEqual(first.Fee.InexactFloat64(), 5) || !approxEqual(second.Fee.InexactFloat64(), 5) {
+		t.Errorf("unexpected leg fees %f and %f", first.Fee.InexactFloat64(), second.Fee.InexactFloat64())
+	}
+	if !approxEqual(quote.Fee.InexactFloat64(), 9) || !approxEqual(quote.TotalCost.InexactFloat64(), 1009) {
+		t.Errorf("expected fee 9 and total 1009, got %f and %f", quote.Fee.InexactFloat64(), quote.TotalCost.InexactFloat64())
+	}
+
+	if math.Abs(quote.EffectiveRate.InexactFloat64()-quote.TargetAmount.InexactFloat64()/1009) > 1e-6 {
+		t.Errorf("expected effective rate %f, got %f", quote.TargetAmount.InexactFloat64()/1009, quote.EffectiveRate.InexactFloat64())
+	}
+
+	// 1 - (1 - 0.1%)(1 - 0.25%)
+	if quote.MarginPercentage != "0.35" {
+		t.Errorf("expected combined margin 0.35, got %s", quote.MarginPercentage)
+	}
+}
+
+func TestGetQuote_DirectCorridorHasNoLegs(t *testing.T) {
+	svc := newMultiLegTestService(t, 2)
+
+	quote, err := svc.GetQuote(context.Background(), "USD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(quote.Legs) != 0 {
+		t.Errorf("expected a direct quote, got legs %+v", quote.Legs)
+	}
+	if quote.MarginPercentage != "0.25" {
+		t.Errorf("expected the corridor's margin 0.25, got %s", quote.MarginPercentage)
+	}
+}
+
+func TestGetQuote_MultiLegChecksEachLegsRange(t *testing.T) {
+	svc := newMultiLegTestService(t, 2)
+	// GBP 1000 converts to about USD 1248 for the USD/PHP leg
+	overrideCorridor(t, "USD", "PHP", func(c *model.Corridor) {
+		c.MaxAmount = model.Money{Currency: "USD", Amount: "500.00"}
+	})
+
+	_, err := svc.GetQuote(context.Background(), "GBP", "PHP", 1000)
+	outOfRange, ok := err.(ErrAmountOutOfRange)
+	if !ok {
+		t.Fatalf("expected ErrAmountOutOfRange, got %v", err)
+	}
+	if outOfRange.SourceCurrency != "USD" || outOfRange.TargetCurrency != "PHP" {
+		t.Errorf("expected the USD/PHP leg to be out of range, got %s/%s", outOfRange.SourceCurrency, outOfRange.TargetCurrency)
+	}
+
+	if _, err := svc.GetQuote(context.Background(), "GBP", "PHP", 300); err != nil {
+		t.Errorf("expected an amount within every leg's range to be quoted, got %v", err)
+	}
+}
+
+func TestGetQuote_MultiLegDisabled(t *testing.T) {
+	svc := newMultiLegTestService(t, 1)
+
+	if _, err := svc.GetQuote(context.Background(), "GBP", "PHP", 1000); err == nil {
+		t.Error("expected pairs without a corridor to be rejected")
+	}
+}
+
+func TestFindCorridorPath_SkipsDisabledCorridors(t *testing.T) {
+	addCorridor(t, model.Corridor{SourceCurrency: "GBP", TargetCurrency: "USD", Enabled: false})
+
+	if path := findCorridorPath(model.Corridors, "GBP", "PHP", 3); path != nil {
+		t.Errorf("expected no path through a disabled corridor, got %+v", path)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+// callWithTimeout calls a provider with ctx bounded by timeout (0 leaves it
+// unbounded). It returns as soon as the deadline passes, even if the provider
+// ignores its context, with ErrProviderUnavailable and a "timeout" reason.
+// If ctx itself is cancelled first, its error is returned instead.
+func callWithTimeout[T any](ctx context.Context, timeout time.Duration, providerName string, call func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return call(ctx)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	finished := make(chan result, 1) // buffered so an abandoned call never blocks
+	go func() {
+		value, err := call(callCtx)
+		finished <- result{value: value, err: err}
+	}()
+
+	var zero T
+	select {
+	case r := <-finished:
+		if r.err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+			return zero, provider.ErrProviderUnavailable{Provider: providerName, Reason: "timeout"}
+		}
+		return r.value, r.err
+	case <-callCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, provider.ErrProviderUnavailable{Provider: providerName, Reason: "timeout"}
+	}
+}
+
+// providerTimeout is how long a single provider call may take
+func (s *RateService) providerTimeout() time.Duration {
+	return time.Duration(s.config.ProviderTimeoutMs) * time.Millisecond
+}
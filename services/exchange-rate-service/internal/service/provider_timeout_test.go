@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+)
+
+func TestGetRate_ProviderTimeout(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.ProviderTimeoutMs = 50
+
+	// The provider ignores its context, like a hung upstream connection
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		return nil, errors.New("too late")
+	}
+
+	start := time.Now()
+	_, err := svc.GetRate(context.Background(), "SGD", "PHP")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the call to give up after the timeout, took %v", elapsed)
+	}
+
+	var unavailable provider.ErrProviderUnavailable
+	if !errors.As(err, &unavailable) || unavailable.Reason != "timeout" {
+		t.Fatalf("expected ErrProviderUnavailable with a timeout reason, got %v", err)
+	}
+}
+
+func TestGetRate_ProviderTimeoutKeepsClientCancellation(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.ProviderTimeoutMs = 5000
+
+	called := make(chan struct{})
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		close(called)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-called
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := svc.GetRate(ctx, "SGD", "PHP")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the client's cancellation to stop the call, took %v", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestGetRate_FastProviderWithinTimeout(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.ProviderTimeoutMs = 1000
+
+	if _, err := svc.GetRate(context.Background(), "SGD", "PHP"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
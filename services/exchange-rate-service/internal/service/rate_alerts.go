@@ -0,0 +1,359 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// alertWebhookTimeout bounds each webhook call, so a slow receiver can't hold
+// up the other alerts' checks
+const alertWebhookTimeout = 5 * time.Second
+
+// A failed webhook is retried after alertRetryBaseDelay, doubled for each
+// consecutive failure up to alertRetryMaxDelay
+const (
+	alertRetryBaseDelay = 30 * time.Second
+	alertRetryMaxDelay  = 30 * time.Minute
+)
+
+// ErrInvalidAlert is returned when a rate alert request has an invalid field
+type ErrInvalidAlert struct {
+	Field  string
+	Reason string
+}
+
+func (e ErrInvalidAlert) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ErrAlertNotFound is returned for an alert that doesn't exist
+type ErrAlertNotFound struct {
+	AlertID string
+}
+
+func (e ErrAlertNotFound) Error() string {
+	return "rate alert not found: " + e.AlertID
+}
+
+// ErrAlertLimitExceeded is returned when a client, or the service as a
+// whole, already has as many alerts as the configured limit allows
+type ErrAlertLimitExceeded struct {
+	Limit     int
+	PerClient bool
+}
+
+func (e ErrAlertLimitExceeded) Error() string {
+	if e.PerClient {
+		return fmt.Sprintf("client already has the maximum of %d rate alerts", e.Limit)
+	}
+	return fmt.Sprintf("the service already has the maximum of %d rate alerts", e.Limit)
+}
+
+// rateAlerts is the in-memory registry of rate alerts
+type rateAlerts struct {
+	mu   sync.Mutex
+	byID map[string]*model.RateAlert
+
+	client  *http.Client       // posts to webhooks, connecting only to addresses checkIP allows
+	checkIP func(net.IP) error // rejects webhook addresses the service must not reach
+}
+
+// newAlertClient returns the client webhooks are posted with. It doesn't
+// follow redirects or use a proxy, and checks every address it dials after
+// DNS resolution, so a webhook can't be pointed at an internal address.
+func (s *RateService) newAlertClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: alertWebhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return s.alerts.checkIP(net.ParseIP(host))
+		},
+	}
+	return &http.Client{
+		Timeout:   alertWebhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkWebhookIP rejects loopback, private, link-local and other addresses
+// that aren't publicly routable
+func checkWebhookIP(ip net.IP) error {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not publicly routable", ip)
+	}
+	return nil
+}
+
+// CreateAlert registers an alert for clientID that POSTs to webhookURL when
+// pair's mid rate crosses threshold in direction. The webhook's host must
+// resolve only to publicly routable addresses.
+func (s *RateService) CreateAlert(ctx context.Context, clientID string, pair provider.CurrencyPair, direction string, threshold float64, webhookURL string) (*model.RateAlert, error) {
+	if direction != model.AlertAbove && direction != model.AlertBelow {
+		return nil, ErrInvalidAlert{Field: "direction", Reason: fmt.Sprintf("must be %q or %q", model.AlertAbove, model.AlertBelow)}
+	}
+	if !(threshold > 0) || math.IsInf(threshold, 1) {
+		return nil, ErrInvalidAlert{Field: "threshold", Reason: "must be a positive rate"}
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrInvalidAlert{Field: "webhookUrl", Reason: "must be an http or https URL"}
+	}
+	if err := s.checkWebhookHost(ctx, u.Hostname()); err != nil {
+		return nil, ErrInvalidAlert{Field: "webhookUrl", Reason: err.Error()}
+	}
+
+	alert := &model.RateAlert{
+		AlertID:        uuid.New().String(),
+		ClientID:       clientID,
+		SourceCurrency: pair.Source,
+		TargetCurrency: pair.Target,
+		Direction:      direction,
+		Threshold:      threshold,
+		WebhookURL:     webhookURL,
+		CreatedAt:      time.Now(),
+	}
+
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+	if limit := s.config.MaxAlerts; limit > 0 && len(s.alerts.byID) >= limit {
+		return nil, ErrAlertLimitExceeded{Limit: limit}
+	}
+	if limit := s.config.MaxAlertsPerClient; limit > 0 {
+		count := 0
+		for _, existing := range s.alerts.byID {
+			if existing.ClientID == clientID {
+				count++
+			}
+		}
+		if count >= limit {
+			return nil, ErrAlertLimitExceeded{Limit: limit, PerClient: true}
+		}
+	}
+	if s.alerts.byID == nil {
+		s.alerts.byID = make(map[string]*model.RateAlert)
+	}
+	s.alerts.byID[alert.AlertID] = alert
+
+	created := *alert
+	return &created, nil
+}
+
+// checkWebhookHost resolves host and rejects it if any of its addresses is
+// one the service must not reach. The addresses are checked again when the
+// webhook is called, in case the name has been rebound since.
+func (s *RateService) checkWebhookHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return s.alerts.checkIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("host %s could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if err := s.alerts.checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAlert removes an alert
+func (s *RateService) DeleteAlert(alertID string) error {
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+	if _, ok := s.alerts.byID[alertID]; !ok {
+		return ErrAlertNotFound{AlertID: alertID}
+	}
+	delete(s.alerts.byID, alertID)
+	return nil
+}
+
+// EvaluateAlerts checks the alerts against current rates every interval
+// until ctx is done
+func (s *RateService) EvaluateAlerts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.evaluateAlerts(ctx)
+		}
+	}
+}
+
+// evaluateAlerts notifies the alerts whose pair's rate has crossed their
+// threshold, and rearms the triggered ones whose rate has crossed back. An
+// alert whose webhook fails stays armed and is retried with backoff, until
+// MaxAlertFailures consecutive failures disable it.
+func (s *RateService) evaluateAlerts(ctx context.Context) {
+	s.alerts.mu.Lock()
+	alerts := make([]model.RateAlert, 0, len(s.alerts.byID))
+	for _, alert := range s.alerts.byID {
+		alerts = append(alerts, *alert)
+	}
+	s.alerts.mu.Unlock()
+
+	// Alerts on the same pair share one fresh rate
+	rates := make(map[provider.CurrencyPair]*provider.Rate)
+	for _, alert := range alerts {
+		if ctx.Err() != nil {
+			return
+		}
+		if alert.Disabled {
+			continue
+		}
+		pair := provider.CurrencyPair{Source: alert.SourceCurrency, Target: alert.TargetCurrency}
+		rate, ok := rates[pair]
+		if !ok {
+			var err error
+			if rate, err = s.currentRate(ctx, pair); err != nil {
+				s.logger.Warn("Failed to get rate for alerts",
+					zap.String("from", pair.Source),
+					zap.String("to", pair.Target),
+					zap.Error(err),
+				)
+			}
+			rates[pair] = rate
+		}
+		if rate == nil {
+			continue
+		}
+
+		crossed := rate.MidRate > alert.Threshold
+		if alert.Direction == model.AlertBelow {
+			crossed = rate.MidRate < alert.Threshold
+		}
+		switch {
+		case crossed && !alert.Triggered:
+			if time.Now().Before(alert.RetryAt) {
+				continue
+			}
+			if err := s.notifyAlert(ctx, alert, rate.MidRate); err != nil {
+				s.logger.Warn("Failed to notify rate alert", zap.String("alertId", alert.AlertID), zap.Error(err))
+				s.recordAlertFailure(alert.AlertID)
+				continue
+			}
+			s.setAlertTriggered(alert.AlertID, true)
+		case !crossed && alert.Triggered:
+			s.setAlertTriggered(alert.AlertID, false)
+		}
+	}
+}
+
+// currentRate fetches pair's rate from the provider rather than the cache,
+// so alerts see the rate as it moves
+func (s *RateService) currentRate(ctx context.Context, pair provider.CurrencyPair) (*provider.Rate, error) {
+	ratePair, inverted := s.ratePair(pair.Source, pair.Target)
+	rate, err := s.fetchProviderRate(ctx, ratePair.Source, ratePair.Target)
+	if err != nil {
+		return nil, err
+	}
+	if inverted {
+		rate = rate.Inverse()
+	}
+	return rate, nil
+}
+
+// setAlertTriggered records whether an alert has fired, unless it has been
+// deleted meanwhile. Firing clears its webhook failures.
+func (s *RateService) setAlertTriggered(alertID string, triggered bool) {
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+	if alert, ok := s.alerts.byID[alertID]; ok {
+		alert.Triggered = triggered
+		if triggered {
+			alert.ConsecutiveFailures = 0
+			alert.RetryAt = time.Time{}
+		}
+	}
+}
+
+// recordAlertFailure counts a failed webhook call against an alert, backing
+// off its next attempt or disabling it once MaxAlertFailures is reached
+func (s *RateService) recordAlertFailure(alertID string) {
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+	alert, ok := s.alerts.byID[alertID]
+	if !ok {
+		return
+	}
+
+	alert.ConsecutiveFailures++
+	if limit := s.config.MaxAlertFailures; limit > 0 && alert.ConsecutiveFailures >= limit {
+		alert.Disabled = true
+		s.logger.Warn("Disabled rate alert after repeated webhook failures",
+			zap.String("alertId", alertID),
+			zap.Int("failures", alert.ConsecutiveFailures),
+		)
+		return
+	}
+	delay := alertRetryMaxDelay
+	if shift := alert.ConsecutiveFailures - 1; shift < 16 {
+		delay = min(alertRetryBaseDelay<<shift, alertRetryMaxDelay)
+	}
+	alert.RetryAt = time.Now().Add(delay)
+}
+
+// notifyAlert POSTs the alert's notification to its webhook
+func (s *RateService) notifyAlert(ctx context.Context, alert model.RateAlert, rate float64) error {
+	body, err := json.Marshal(model.RateAlertNotification{
+		AlertID:        alert.AlertID,
+		SourceCurrency: alert.SourceCurrency,
+		TargetCurrency: alert.TargetCurrency,
+		Direction:      alert.Direction,
+		Threshold:      alert.Threshold,
+		Rate:           rate,
+		TriggeredAt:    time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, alertWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, alert.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.alerts.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	s.logger.Info("Rate alert triggered",
+		zap.String("alertId", alert.AlertID),
+		zap.String("from", alert.SourceCurrency),
+		zap.String("to", alert.TargetCurrency),
+		zap.Float64("rate", rate),
+	)
+	return nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// newAlertTestService returns a service on a simulated provider whose rates
+// only move when drift is forced. Its webhooks may be on loopback, where the
+// test servers listen.
+func newAlertTestService() (*RateService, *provider.SimulatedProvider) {
+	providerCfg := provider.DefaultSimulatedConfig()
+	providerCfg.MaxDrift = 0
+	providerCfg.DriftInterval = time.Hour
+	simulated := provider.NewSimulatedProvider(providerCfg)
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 60}
+	svc := NewRateService(cfg, simulated, NewMockRepository(), zap.NewNop())
+	svc.alerts.checkIP = func(net.IP) error { return nil }
+	return svc, simulated
+}
+
+// newWebhook returns a webhook server that records the notifications it
+// receives, answering with status
+func newWebhook(t *testing.T, status int) (*httptest.Server, chan model.RateAlertNotification) {
+	t.Helper()
+	received := make(chan model.RateAlertNotification, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification model.RateAlertNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("invalid notification: %v", err)
+		}
+		received <- notification
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestEvaluateAlerts_NotifiesWhenDriftCrossesThreshold(t *testing.T) {
+	svc, simulated := newAlertTestService()
+	server, received := newWebhook(t, http.StatusOK)
+	ctx := context.Background()
+
+	alert, err := svc.CreateAlert(context.Background(), "client", provider.CurrencyPair{Source: "SGD", Target: "PHP"}, model.AlertAbove, 43.0, server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 42.50 is below the threshold
+	svc.evaluateAlerts(ctx)
+	if len(received) != 0 {
+		t.Fatalf("expected no notification at 42.50, got %d", len(received))
+	}
+
+	// A 2% drift takes SGD/PHP to 43.35
+	simulated.SetDrift("SGD", "PHP", 0.02)
+	svc.evaluateAlerts(ctx)
+	if len(received) != 1 {
+		t.Fatalf("expected 1 notification after the drift, got %d", len(received))
+	}
+	notification := <-received
+	if notification.AlertID != alert.AlertID || notification.Direction != model.AlertAbove {
+		t.Errorf("expected a notification for alert %s above, got %+v", alert.AlertID, notification)
+	}
+	if notification.Rate <= 43.0 {
+		t.Errorf("expected the rate above 43.0 in the notification, got %f", notification.Rate)
+	}
+
+	// Still above: notified once until the rate crosses back
+	svc.evaluateAlerts(ctx)
+	if len(received) != 0 {
+		t.Fatalf("expected no repeat notification, got %d", len(received))
+	}
+
+	simulated.ResetDrift()
+	svc.evaluateAlerts(ctx)
+	simulated.SetDrift("SGD", "PHP", 0.02)
+	svc.evaluateAlerts(ctx)
+	if len(received) != 1 {
+		t.Errorf("expected the rearmed alert to notify again, got %d", len(received))
+	}
+}
+
+func TestEvaluateAlerts_RetriesFailedWebhookWithBackoff(t *testing.T) {
+	svc, _ := newAlertTestService()
+	svc.config.MaxAlertFailures = 2
+	server, received := newWebhook(t, http.StatusInternalServerError)
+	ctx := context.Background()
+
+	alert, err := svc.CreateAlert(ctx, "client", provider.CurrencyPair{Source: "SGD", Target: "PHP"}, model.AlertBelow, 43.0, server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc.evaluateAlerts(ctx)
+	svc.evaluateAlerts(ctx)
+	if len(received) != 1 {
+		t.Fatalf("expected the failed notification to wait out its backoff, got %d calls", len(received))
+	}
+
+	// Once the backoff has passed it is retried, and the second failure
+	// disables it
+	svc.alerts.mu.Lock()
+	svc.alerts.byID[alert.AlertID].RetryAt = time.Now()
+	svc.alerts.mu.Unlock()
+	svc.evaluateAlerts(ctx)
+	if len(received) != 2 {
+		t.Fatalf("expected the failed notification to be retried, got %d calls", len(received))
+	}
+
+	svc.alerts.mu.Lock()
+	disabled := svc.alerts.byID[alert.AlertID].Disabled
+	svc.alerts.byID[alert.AlertID].RetryAt = time.Now()
+	svc.alerts.mu.Unlock()
+	if !disabled {
+		t.Fatal("expected the alert to be disabled after 2 consecutive failures")
+	}
+	svc.evaluateAlerts(ctx)
+	if len(received) != 2 {
+		t.Errorf("expected a disabled alert not to notify, got %d calls", len(received))
+	}
+}
+
+func TestEvaluateAlerts_DoesNotFollowRedirects(t *testing.T) {
+	svc, _ := newAlertTestService()
+	target, received := newWebhook(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirect.Close)
+
+	alert, err := svc.CreateAlert(context.Background(), "client", provider.CurrencyPair{Source: "SGD", Target: "PHP"}, model.AlertBelow, 43.0, redirect.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc.evaluateAlerts(context.Background())
+	if len(received) != 0 {
+		t.Error("expected the redirect not to be followed")
+	}
+	svc.alerts.mu.Lock()
+	failures := svc.alerts.byID[alert.AlertID].ConsecutiveFailures
+	svc.alerts.mu.Unlock()
+	if failures != 1 {
+		t.Errorf("expected the redirect to count as a failure, got %d failures", failures)
+	}
+}
+
+func TestEvaluateAlerts_ChecksAddressWhenCalling(t *testing.T) {
+	svc, _ := newAlertTestService()
+	server, received := newWebhook(t, http.StatusOK)
+
+	if _, err := svc.CreateAlert(context.Background(), "client", provider.CurrencyPair{Source: "SGD", Target: "PHP"}, model.AlertBelow, 43.0, server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// As if the webhook's name had been rebound to loopback since it was
+	// created
+	svc.alerts.checkIP = checkWebhookIP
+	svc.evaluateAlerts(context.Background())
+	if len(received) != 0 {
+		t.Error("expected the loopback webhook not to be called")
+	}
+}
+
+func TestCreateAlert_RejectsInternalWebhooks(t *testing.T) {
+	svc := NewRateService(&config.Config{RateCacheTTL: 30, LockDuration: 60}, provider.NewSimulatedProvider(provider.DefaultSimulatedConfig()), NewMockRepository(), zap.NewNop())
+	pair := provider.CurrencyPair{Source: "SGD", Target: "PHP"}
+
+	for _, webhookURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := svc.CreateAlert(context.Background(), "client", pair, model.AlertAbove, 43.0, webhookURL)
+		invalid, ok := err.(ErrInvalidAlert)
+		if !ok || invalid.Field != "webhookUrl" {
+			t.Errorf("%s: expected ErrInvalidAlert on webhookUrl, got %v", webhookURL, err)
+		}
+	}
+}
+
+func TestCreateAlert_EnforcesLimits(t *testing.T) {
+	svc, _ := newAlertTestService()
+	svc.config.MaxAlertsPerClient = 2
+	svc.config.MaxAlerts = 3
+	pair := provider.CurrencyPair{Source: "SGD", Target: "PHP"}
+	create := func(clientID string) error {
+		_, err := svc.CreateAlert(context.Background(), clientID, pair, model.AlertAbove, 43.0, "http://127.0.0.1/hook")
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := create("a"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if limit, ok := create("a").(ErrAlertLimitExceeded); !ok || !limit.PerClient {
+		t.Errorf("expected the per-client limit for a third alert, got %v", limit)
+	}
+
+	if err := create("b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if limit, ok := create("c").(ErrAlertLimitExceeded); !ok || limit.PerClient {
+		t.Errorf("expected the total limit for a fourth alert, got %v", limit)
+	}
+}
+
+func TestDeleteAlert(t *testing.T) {
+	svc, simulated := newAlertTestService()
+	server, received := newWebhook(t, http.StatusOK)
+
+	alert, err := svc.CreateAlert(context.Background(), "client", provider.CurrencyPair{Source: "SGD", Target: "PHP"}, model.AlertAbove, 43.0, server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.DeleteAlert(alert.AlertID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	simulated.SetDrift("SGD", "PHP", 0.02)
+	svc.evaluateAlerts(context.Background())
+	if len(received) != 0 {
+		t.Errorf("expected a deleted alert not to notify, got %d", len(received))
+	}
+
+	if _, ok := svc.DeleteAlert(alert.AlertID).(ErrAlertNotFound); !ok {
+		t.Error("expected ErrAlertNotFound deleting the alert again")
+	}
+}
+
+func TestCreateAlert_Validates(t *testing.T) {
+	svc, _ := newAlertTestService()
+	pair := provider.CurrencyPair{Source: "SGD", Target: "PHP"}
+
+	tests := []struct {
+		direction  string
+		threshold  float64
+		webhookURL string
+		field      string
+	}{
+		{"sideways", 43.0, "http://example.com/hook", "direction"},
+		{model.AlertAbove, 0, "http://example.com/hook", "threshold"},
+		{model.AlertAbove, 43.0, "ftp://example.com/hook", "webhookUrl"},
+		{model.AlertAbove, 43.0, "not a url", "webhookUrl"},
+	}
+	for _, tt := range tests {
+		_, err := svc.CreateAlert(context.Background(), "client", pair, tt.direction, tt.threshold, tt.webhookURL)
+		invalid, ok := err.(ErrInvalidAlert)
+		if !ok || invalid.Field != tt.field {
+			t.Errorf("%+v: expected ErrInvalidAlert on %s, got %v", tt, tt.field, err)
+		}
+	}
+}
@@ -2,24 +2,195 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/metrics"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/tracing"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrBelowMinTargetAmount is returned when a quote converts to less than the
+// corridor's minimum target amount
+type ErrBelowMinTargetAmount struct {
+	SourceCurrency string
+	TargetCurrency string
+	TargetAmount   string
+	MinimumAmount  string
+}
+
+func (e ErrBelowMinTargetAmount) Error() string {
+	return fmt.Sprintf("target amount %s %s is below the minimum of %s %s for %s/%s",
+		e.TargetAmount, e.TargetCurrency, e.MinimumAmount, e.TargetCurrency, e.SourceCurrency, e.TargetCurrency)
+}
+
+// ErrAmountOutOfRange is returned when a quote's source amount is outside
+// the corridor's allowed range. An empty limit leaves that side unbounded.
+type ErrAmountOutOfRange struct {
+	SourceCurrency string
+	TargetCurrency string
+	SourceAmount   string
+	MinimumAmount  string
+	MaximumAmount  string
+}
+
+func (e ErrAmountOutOfRange) Error() string {
+	limit := func(amount, none string) string {
+		if amount == "" {
+			return none
+		}
+		return amount + " " + e.SourceCurrency
+	}
+	return fmt.Sprintf("source amount %s %s is outside the allowed range for %s/%s (minimum %s, maximum %s)",
+		e.SourceAmount, e.SourceCurrency, e.SourceCurrency, e.TargetCurrency,
+		limit(e.MinimumAmount, "none"), limit(e.MaximumAmount, "none"))
+}
+
+// ErrCorridorNotFound is returned when quoting a pair with no corridor, or
+// no path of corridors for a multi-leg quote
+type ErrCorridorNotFound struct {
+	SourceCurrency string
+	TargetCurrency string
+}
+
+func (e ErrCorridorNotFound) Error() string {
+	return fmt.Sprintf("corridor not found: %s/%s", e.SourceCurrency, e.TargetCurrency)
+}
+
+// ErrCorridorDisabled is returned when quoting a corridor that has been
+// turned off
+type ErrCorridorDisabled struct {
+	SourceCurrency string
+	TargetCurrency string
+}
+
+func (e ErrCorridorDisabled) Error() string {
+	return fmt.Sprintf("corridor %s/%s is disabled", e.SourceCurrency, e.TargetCurrency)
+}
+
+// ErrReverseQuoteUnstable is returned when a corridor's fee or margin makes
+// solving for the source amount of a target-amount quote diverge
+type ErrReverseQuoteUnstable struct {
+	SourceCurrency string
+	TargetCurrency string
+	Reason         string
+}
+
+func (e ErrReverseQuoteUnstable) Error() string {
+	return fmt.Sprintf("cannot quote a target amount for %s/%s: %s", e.SourceCurrency, e.TargetCurrency, e.Reason)
+}
+
+// ErrQuoteAmountTooLarge is returned when a quote's total cost exceeds the
+// configured maximum
+type ErrQuoteAmountTooLarge struct {
+	SourceCurrency string
+	TotalCost      string
+	MaximumAmount  string
+}
+
+func (e ErrQuoteAmountTooLarge) Error() string {
+	return fmt.Sprintf("quote total %s %s exceeds the maximum of %s %s",
+		e.TotalCost, e.SourceCurrency, e.MaximumAmount, e.SourceCurrency)
+}
+
+// ErrLockDurationExceeded is returned when an extension would keep a lock
+// longer than MaxLockDuration in total
+type ErrLockDurationExceeded struct {
+	LockID     string
+	MaxSeconds int
+}
+
+func (e ErrLockDurationExceeded) Error() string {
+	return fmt.Sprintf("extension exceeds maximum lock duration of %ds", e.MaxSeconds)
+}
+
+// ErrLockLimitExceeded is returned when a pair already has as many active
+// locks as MaxActiveLocksPerPair allows
+type ErrLockLimitExceeded struct {
+	SourceCurrency string
+	TargetCurrency string
+	Limit          int
+}
+
+func (e ErrLockLimitExceeded) Error() string {
+	return fmt.Sprintf("%s/%s already has the maximum of %d active rate locks", e.SourceCurrency, e.TargetCurrency, e.Limit)
+}
+
+// ErrQuoteExpired is returned for a quote that is past its validity
+type ErrQuoteExpired struct {
+	QuoteID string
+}
+
+func (e ErrQuoteExpired) Error() string {
+	return fmt.Sprintf("quote %s has expired", e.QuoteID)
+}
+
+// ErrQuoteNotFound is returned for a quote that was never issued, or whose
+// record is no longer kept
+type ErrQuoteNotFound struct {
+	QuoteID string
+}
+
+func (e ErrQuoteNotFound) Error() string {
+	return fmt.Sprintf("quote %s not found", e.QuoteID)
+}
+
+// ErrIdempotencyKeyConflict is returned when an idempotency key is reused
+// for a different lock request than the one it was first used for
+type ErrIdempotencyKeyConflict struct {
+	Key string
+}
+
+func (e ErrIdempotencyKeyConflict) Error() string {
+	return fmt.Sprintf("idempotency key %s was already used for a different request", e.Key)
+}
+
+// ErrIdempotentRequestInProgress is returned when another request holds the
+// idempotency key but its lock can't be read yet. The client should retry.
+type ErrIdempotentRequestInProgress struct {
+	Key string
+}
+
+func (e ErrIdempotentRequestInProgress) Error() string {
+	return fmt.Sprintf("a request with idempotency key %s is still in progress", e.Key)
+}
+
+// MarginRecorder is told the margin each lock captures for a corridor,
+// converted to the reporting currency
+type MarginRecorder func(corridor, currency string, amount float64)
+
 // RateService handles exchange rate operations
 type RateService struct {
 	config     *config.Config
 	provider   provider.RateProvider
 	repository repository.RateRepository
 	logger     *zap.Logger
+	errorRate  *errorRateTracker
+	onMargin   MarginRecorder
+	metrics    *metrics.Metrics
+
+	fetches singleflight.Group // coalesces concurrent provider fetches of a pair
+
+	refreshMu  sync.Mutex
+	refreshing map[provider.CurrencyPair]bool // pairs with a background refresh of a stale rate in flight
+
+	lockFallback *lockFallback // locks kept in memory while the repository is unreachable (nil disables)
+
+	overrides corridorOverrides // runtime pricing overrides applied over model.Corridors
+
+	alerts rateAlerts // rate alerts notified by EvaluateAlerts
 }
 
 // NewRateService creates a new RateService with dependency injection
@@ -29,18 +200,101 @@ func NewRateService(
 	rateRepo repository.RateRepository,
 	logger *zap.Logger,
 ) *RateService {
-	return &RateService{
+	s := &RateService{
 		config:     cfg,
 		provider:   rateProvider,
 		repository: rateRepo,
 		logger:     logger,
+		errorRate:  newErrorRateTracker(time.Duration(cfg.ErrorRateWindow) * time.Second),
+		refreshing: make(map[provider.CurrencyPair]bool),
 	}
+	if cfg.LockFallbackSize > 0 {
+		s.lockFallback = newLockFallback(cfg.LockFallbackSize)
+	}
+	s.alerts.checkIP = checkWebhookIP
+	s.alerts.client = s.newAlertClient()
+	return s
+}
+
+// SetMetrics records cache lookups and generated quotes to m
+func (s *RateService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// SetMarginRecorder registers the recorder told about the margin captured by
+// every lock: when a quote is locked, or when a rate lock is priced into a
+// quote, since only then is its amount known
+func (s *RateService) SetMarginRecorder(recorder MarginRecorder) {
+	s.onMargin = recorder
 }
 
-// GetRate retrieves the current exchange rate for a currency pair
+// GetRate retrieves the current exchange rate for a currency pair, for
+// display. Within the stale grace period an expired rate may be served while
+// it is refreshed; quotes and locks price from quoteRate instead.
 func (s *RateService) GetRate(ctx context.Context, from, to string) (*model.ExchangeRate, error) {
+	rate, _, err := s.LookupRate(ctx, from, to)
+	return rate, err
+}
+
+// LookupRate is GetRate, also reporting whether the rate was served from the
+// cache
+func (s *RateService) LookupRate(ctx context.Context, from, to string) (*model.ExchangeRate, bool, error) {
+	ctx, span := tracing.Start(ctx, "RateService.GetRate", tracing.PairAttributes(from, to)...)
+	rate, cacheHit, err := s.getProviderRate(ctx, from, to, true)
+	span.SetAttributes(tracing.AttrCacheHit.Bool(cacheHit))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, false, err
+	}
+	return s.withCacheState(s.providerRateToModel(rate, from, to), cacheHit), cacheHit, nil
+}
+
+// withCacheState records on rate whether it came from the cache and how long
+// ago it was fetched
+func (s *RateService) withCacheState(rate *model.ExchangeRate, fromCache bool) *model.ExchangeRate {
+	rate.FromCache = fromCache
+	if !rate.FetchedAt.IsZero() {
+		rate.Age = math.Max(time.Since(rate.FetchedAt).Seconds(), 0)
+	}
+	return rate
+}
+
+// quoteRate retrieves the current rate for a quote or lock the customer is
+// held to. Unlike GetRate it never serves a stale rate: once the cached rate
+// has expired, a provider failure fails the quote or lock.
+func (s *RateService) quoteRate(ctx context.Context, from, to string) (*model.ExchangeRate, error) {
+	rate, _, err := s.getProviderRate(ctx, from, to, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.providerRateToModel(rate, from, to), nil
+}
+
+// getProviderRate returns the raw provider rate for from/to, inverting the
+// canonical pair's rate when needed. Stale rates are only served with
+// allowStale.
+func (s *RateService) getProviderRate(ctx context.Context, from, to string, allowStale bool) (*provider.Rate, bool, error) {
+	pair, inverted := s.ratePair(from, to)
+	rate, cacheHit, err := s.fetchRate(ctx, pair.Source, pair.Target, allowStale)
+	if err != nil {
+		return nil, false, err
+	}
+	if inverted {
+		rate = rate.Inverse()
+	}
+	return rate, cacheHit, nil
+}
+
+// fetchRate returns the provider rate for a pair from the cache, falling back
+// to the provider and caching the result. With allowStale, an expired rate
+// within the stale grace period is served instead of waiting on the provider.
+// It reports whether the cache was hit.
+func (s *RateService) fetchRate(ctx context.Context, from, to string, allowStale bool) (*provider.Rate, bool, error) {
 	// Try to get from cache first
-	cachedRate, err := s.repository.GetRate(ctx, from, to)
+	cacheCtx, span := tracing.Start(ctx, "cache.GetRate", tracing.PairAttributes(from, to)...)
+	cachedRate, err := s.repository.GetRate(cacheCtx, from, to)
+	span.SetAttributes(tracing.AttrCacheHit.Bool(cachedRate != nil))
+	tracing.End(span, err)
 	if err != nil {
 		s.logger.Warn("Cache lookup failed", zap.Error(err))
 		// Continue to fetch from provider
@@ -52,91 +306,450 @@ func (s *RateService) GetRate(ctx context.Context, from, to string) (*model.Exch
 			zap.String("to", to),
 			zap.String("source", cachedRate.Source),
 		)
-		return s.providerRateToModel(cachedRate, from, to), nil
+		s.recordCacheLookup(true)
+		return cachedRate, true, nil
+	}
+	if allowStale {
+		if stale := s.staleRate(ctx, from, to); stale != nil {
+			s.recordCacheLookup(true)
+			return stale, true, nil
+		}
 	}
+	s.recordCacheLookup(false)
 
-	// Fetch from provider
-	rate, err := s.provider.GetRate(ctx, from, to)
+	rate, err := s.fetchProviderRate(ctx, from, to)
 	if err != nil {
-		s.logger.Error("Failed to fetch rate from provider",
+		return nil, false, err
+	}
+	return rate, false, nil
+}
+
+// fetchProviderRate fetches a pair's rate from the provider and caches it.
+// Concurrent fetches of the same pair share one provider call and its result,
+// so a burst of cache misses doesn't multiply upstream requests. The shared
+// call runs detached from its callers, bounded by the provider timeout, and
+// each caller stops waiting when its own ctx is done.
+func (s *RateService) fetchProviderRate(ctx context.Context, from, to string) (*provider.Rate, error) {
+	ctx, span := tracing.Start(ctx, "provider.GetRate",
+		append(tracing.PairAttributes(from, to), tracing.AttrProvider.String(s.provider.Name()))...)
+	fetches := s.fetches.DoChan(from+":"+to, func() (interface{}, error) {
+		// The fetch is shared by every caller waiting on the pair, so it
+		// outlives the one that started it being cancelled
+		ctx := context.WithoutCancel(ctx)
+		timeout := s.providerTimeout()
+		if timeout <= 0 {
+			timeout = sharedFetchTimeout
+		}
+		rate, err := callWithTimeout(ctx, timeout, s.provider.Name(), func(ctx context.Context) (*provider.Rate, error) {
+			return s.provider.GetRate(ctx, from, to)
+		})
+		s.recordProviderResult(err)
+		if err != nil {
+			s.logger.Error("Failed to fetch rate from provider",
+				zap.String("from", from),
+				zap.String("to", to),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("failed to get rate for %s/%s: %w", from, to, err)
+		}
+
+		// Cache the rate
+		if err := s.cacheRate(ctx, rate); err != nil {
+			if s.config.FailOnCacheWriteError {
+				return nil, fmt.Errorf("failed to cache rate for %s/%s: %w", from, to, err)
+			}
+			// Don't fail the request, serve the rate uncached
+			s.logger.Warn("Failed to cache rate", zap.Error(err))
+		}
+
+		s.logger.Info("Fetched rate from provider",
 			zap.String("from", from),
 			zap.String("to", to),
-			zap.Error(err),
+			zap.Float64("midRate", rate.MidRate),
+			zap.String("source", rate.Source),
 		)
-		return nil, fmt.Errorf("failed to get rate for %s/%s: %w", from, to, err)
+		return rate, nil
+	})
+
+	var result singleflight.Result
+	select {
+	case result = <-fetches:
+	case <-ctx.Done():
+		result = singleflight.Result{Err: ctx.Err()}
+	}
+	span.SetAttributes(tracing.AttrSharedFetch.Bool(result.Shared))
+	tracing.End(span, result.Err)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result.Val.(*provider.Rate), nil
+}
+
+// sharedFetchTimeout bounds a shared provider fetch when no provider timeout
+// is configured, since it no longer ends with its callers
+const sharedFetchTimeout = 10 * time.Second
+
+// staleRateRefreshTimeout bounds a background refresh of a stale rate
+const staleRateRefreshTimeout = 10 * time.Second
+
+// staleRate returns the pair's expired cached rate if it is still within the
+// stale grace period, starting a background refresh so later requests get a
+// fresh rate. It returns nil when the caller should fetch synchronously.
+func (s *RateService) staleRate(ctx context.Context, from, to string) *provider.Rate {
+	grace := time.Duration(s.config.StaleGracePeriod) * time.Second
+	if grace <= 0 {
+		return nil
 	}
 
-	// Cache the rate
-	cacheTTL := time.Duration(s.config.RateCacheTTL) * time.Second
-	if err := s.repository.SaveRate(ctx, rate, cacheTTL); err != nil {
-		s.logger.Warn("Failed to cache rate", zap.Error(err))
-		// Don't fail the request, just log
+	stale, err := s.repository.GetStaleRate(ctx, from, to)
+	if err != nil || stale == nil || time.Now().After(stale.ValidUntil.Add(grace)) {
+		return nil
 	}
 
-	s.logger.Info("Fetched rate from provider",
+	s.logger.Debug("Serving stale rate while refreshing",
 		zap.String("from", from),
 		zap.String("to", to),
-		zap.Float64("midRate", rate.MidRate),
-		zap.String("source", rate.Source),
+		zap.Time("validUntil", stale.ValidUntil),
 	)
+	s.refreshRate(from, to)
+	return stale
+}
 
-	return s.providerRateToModel(rate, from, to), nil
+// refreshRate fetches and caches a fresh rate for the pair in the background.
+// At most one refresh per pair runs at a time, so a burst of requests for a
+// stale rate reaches the provider once.
+func (s *RateService) refreshRate(from, to string) {
+	pair := provider.CurrencyPair{Source: from, Target: to}
+	s.refreshMu.Lock()
+	if s.refreshing[pair] {
+		s.refreshMu.Unlock()
+		return
+	}
+	s.refreshing[pair] = true
+	s.refreshMu.Unlock()
+
+	go func() {
+		defer func() {
+			s.refreshMu.Lock()
+			delete(s.refreshing, pair)
+			s.refreshMu.Unlock()
+		}()
+
+		// The request that found the stale rate may already be done
+		ctx, cancel := context.WithTimeout(context.Background(), staleRateRefreshTimeout)
+		defer cancel()
+
+		if _, err := s.fetchProviderRate(ctx, from, to); err != nil {
+			s.logger.Warn("Failed to refresh stale rate",
+				zap.String("from", from),
+				zap.String("to", to),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// recordCacheLookup counts a rate cache hit or miss
+func (s *RateService) recordCacheLookup(hit bool) {
+	if s.metrics == nil {
+		return
+	}
+	if hit {
+		s.metrics.RecordCacheHit("rate")
+	} else {
+		s.metrics.RecordCacheMiss("rate")
+	}
+}
+
+// ratePair returns the direction a pair's rate is fetched and cached in, and
+// whether that is the inverse of the requested direction. With canonical
+// ordering, A/B and B/A share one cached rate and are exact inverses.
+func (s *RateService) ratePair(from, to string) (provider.CurrencyPair, bool) {
+	if !s.config.CanonicalPairOrdering {
+		return provider.CurrencyPair{Source: from, Target: to}, false
+	}
+	return provider.CanonicalPair(from, to)
+}
+
+// CompareProviders queries every configured provider concurrently for a pair
+// and reports each provider's rate and latency side by side, and which of
+// them GetRate would serve given those answers. Provider failures are
+// reported per entry rather than failing the whole comparison.
+func (s *RateService) CompareProviders(ctx context.Context, from, to string) *model.ProviderRateComparison {
+	providers := []provider.RateProvider{s.provider}
+	composite, isComposite := s.provider.(provider.CompositeProvider)
+	if isComposite {
+		providers = composite.Providers()
+	}
+
+	entries := make([]model.ProviderRateEntry, len(providers))
+	outcomes := make([]provider.ProviderOutcome, len(providers))
+	var wg sync.WaitGroup
+	for i, prov := range providers {
+		wg.Add(1)
+		go func(i int, prov provider.RateProvider) {
+			defer wg.Done()
+
+			start := time.Now()
+			rate, err := callWithTimeout(ctx, s.providerTimeout(), prov.Name(), func(ctx context.Context) (*provider.Rate, error) {
+				return prov.GetRate(ctx, from, to)
+			})
+			latency := time.Since(start)
+			outcomes[i] = provider.ProviderOutcome{Latency: latency, Err: err}
+			entry := model.ProviderRateEntry{
+				Provider:  prov.Name(),
+				LatencyMs: float64(latency.Microseconds()) / 1000,
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.MidRate = rate.MidRate
+				entry.BidRate = rate.BidRate
+				entry.AskRate = rate.AskRate
+			}
+			entries[i] = entry
+		}(i, prov)
+	}
+	wg.Wait()
+
+	comparison := &model.ProviderRateComparison{
+		SourceCurrency: from,
+		TargetCurrency: to,
+		Providers:      entries,
+		ComparedAt:     time.Now(),
+	}
+
+	// The provider GetRate would serve, by the composite provider's own rule
+	selected := 0
+	if isComposite {
+		selected = composite.Selected(outcomes)
+	} else if outcomes[0].Err != nil {
+		selected = -1
+	}
+	if selected >= 0 {
+		comparison.Selected = entries[selected].Provider
+	}
+
+	return comparison
 }
 
-// GetRates retrieves exchange rates for multiple currency pairs
-func (s *RateService) GetRates(ctx context.Context, pairs []provider.CurrencyPair) ([]*model.ExchangeRate, error) {
-	results := make([]*model.ExchangeRate, 0, len(pairs))
+// GetRates retrieves exchange rates for multiple currency pairs, in request
+// order. Pairs without a rate are reported in the result's errors rather than
+// failing the batch: if the provider fails the batch as a whole, each
+// uncached pair is fetched on its own so the failure is pinned to its pairs.
+func (s *RateService) GetRates(ctx context.Context, pairs []provider.CurrencyPair) *model.BatchRates {
+	ratePairs := make([]provider.CurrencyPair, len(pairs))
+	inverted := make([]bool, len(pairs))
+	rates := make(map[provider.CurrencyPair]*provider.Rate, len(pairs))
+	failures := make(map[provider.CurrencyPair]error)
+	cached := make(map[provider.CurrencyPair]bool, len(pairs))
 	uncachedPairs := make([]provider.CurrencyPair, 0)
 
-	// Check cache for each pair
-	for _, pair := range pairs {
-		cachedRate, err := s.repository.GetRate(ctx, pair.Source, pair.Target)
+	// Check cache for each pair, looking up pairs sharing a direction once
+	for i, pair := range pairs {
+		ratePairs[i], inverted[i] = s.ratePair(pair.Source, pair.Target)
+		if _, seen := rates[ratePairs[i]]; seen {
+			continue
+		}
+		cachedRate, err := s.repository.GetRate(ctx, ratePairs[i].Source, ratePairs[i].Target)
+		if err == nil && cachedRate == nil {
+			cachedRate = s.staleRate(ctx, ratePairs[i].Source, ratePairs[i].Target)
+		}
+		s.recordCacheLookup(err == nil && cachedRate != nil)
 		if err == nil && cachedRate != nil {
-			results = append(results, s.providerRateToModel(cachedRate, pair.Source, pair.Target))
+			rates[ratePairs[i]] = cachedRate
+			cached[ratePairs[i]] = true
 		} else {
-			uncachedPairs = append(uncachedPairs, pair)
+			rates[ratePairs[i]] = nil
+			uncachedPairs = append(uncachedPairs, ratePairs[i])
 		}
 	}
 
 	// Fetch uncached rates from provider
 	if len(uncachedPairs) > 0 {
-		rates, err := s.provider.GetRates(ctx, uncachedPairs)
+		fetched, err := callWithTimeout(ctx, s.providerTimeout(), s.provider.Name(), func(ctx context.Context) ([]*provider.Rate, error) {
+			return s.provider.GetRates(ctx, uncachedPairs)
+		})
+		s.recordProviderResult(err)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get rates: %w", err)
+			s.logger.Warn("Failed to get rates, fetching pairs one by one",
+				zap.Int("pairs", len(uncachedPairs)),
+				zap.Error(err),
+			)
+			for _, pair := range uncachedPairs {
+				rate, err := s.fetchProviderRate(ctx, pair.Source, pair.Target)
+				if err != nil {
+					failures[pair] = err
+					continue
+				}
+				rates[pair] = rate
+			}
 		}
 
-		cacheTTL := time.Duration(s.config.RateCacheTTL) * time.Second
-		for _, rate := range rates {
+		for _, rate := range fetched {
+			pair := provider.CurrencyPair{Source: rate.SourceCurrency, Target: rate.TargetCurrency}
 			// Cache each rate
-			if err := s.repository.SaveRate(ctx, rate, cacheTTL); err != nil {
+			if err := s.cacheRate(ctx, rate); err != nil {
+				if s.config.FailOnCacheWriteError {
+					failures[pair] = fmt.Errorf("failed to cache rate for %s/%s: %w", rate.SourceCurrency, rate.TargetCurrency, err)
+					continue
+				}
 				s.logger.Warn("Failed to cache rate", zap.Error(err))
 			}
-			results = append(results, s.providerRateToModel(rate, rate.SourceCurrency, rate.TargetCurrency))
+			rates[pair] = rate
 		}
 	}
 
-	return results, nil
+	result := &model.BatchRates{
+		Rates:  make([]*model.ExchangeRate, 0, len(pairs)),
+		Errors: make([]model.PairError, 0),
+	}
+	for i, pair := range pairs {
+		rate := rates[ratePairs[i]]
+		if rate == nil {
+			err, ok := failures[ratePairs[i]]
+			if !ok {
+				// The provider skipped the pair
+				err = fmt.Errorf("unsupported currency pair: %s/%s", pair.Source, pair.Target)
+			}
+			result.Errors = append(result.Errors, model.PairError{
+				SourceCurrency: pair.Source,
+				TargetCurrency: pair.Target,
+				Error:          err.Error(),
+			})
+			continue
+		}
+		if inverted[i] {
+			rate = rate.Inverse()
+		}
+		result.Rates = append(result.Rates, s.withCacheState(s.providerRateToModel(rate, pair.Source, pair.Target), cached[ratePairs[i]]))
+	}
+
+	return result
 }
 
 // LockRate locks a rate for a specified duration
 func (s *RateService) LockRate(ctx context.Context, from, to string, durationSeconds int) (*model.LockedRate, error) {
+	return s.LockRateIdempotent(ctx, "", from, to, durationSeconds)
+}
+
+// LockRateIdempotent locks a rate, returning the existing lock if one was
+// already created for the idempotency key. This lets clients safely retry
+// after a network timeout without creating duplicate locks. An empty key
+// always creates a new lock, and a key reused for a different pair or
+// duration returns ErrIdempotencyKeyConflict.
+func (s *RateService) LockRateIdempotent(ctx context.Context, idempotencyKey, from, to string, durationSeconds int) (*model.LockedRate, error) {
+	ctx, span := tracing.Start(ctx, "RateService.LockRate", tracing.PairAttributes(from, to)...)
+	locked, err := s.lockRate(ctx, idempotencyKey, from, to, durationSeconds)
+	tracing.End(span, err)
+	return locked, err
+}
+
+// lockRate is LockRateIdempotent without its span
+func (s *RateService) lockRate(ctx context.Context, idempotencyKey, from, to string, durationSeconds int) (*model.LockedRate, error) {
+	requestHash := lockRequestHash("rate", from, to, strconv.Itoa(durationSeconds))
+	if idempotencyKey != "" {
+		existing, err := s.lockForIdempotencyKey(ctx, idempotencyKey, requestHash)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
+	// Get current rate
+	providerRate, _, err := s.getProviderRate(ctx, from, to, false)
+	if err != nil {
+		return nil, err
+	}
+	rate := s.providerRateToModel(providerRate, from, to)
+
+	return s.createLock(ctx, idempotencyKey, requestHash, rate, providerRate, nil, durationSeconds)
+}
+
+// LockRateFromQuote locks the rate of a previously issued quote, so the
+// customer gets exactly the rate they were quoted rather than whatever the
+// market has moved to since
+func (s *RateService) LockRateFromQuote(ctx context.Context, quoteID string, durationSeconds int) (*model.LockedRate, error) {
+	return s.LockRateFromQuoteIdempotent(ctx, "", quoteID, durationSeconds)
+}
+
+// LockRateFromQuoteIdempotent locks a quote's rate, returning the existing
+// lock if one was already created for the idempotency key. The quote must
+// still be valid; otherwise ErrQuoteExpired is returned, or ErrQuoteNotFound
+// if it was never issued.
+func (s *RateService) LockRateFromQuoteIdempotent(ctx context.Context, idempotencyKey, quoteID string, durationSeconds int) (*model.LockedRate, error) {
+	requestHash := lockRequestHash("quote", quoteID, strconv.Itoa(durationSeconds))
+	if idempotencyKey != "" {
+		existing, err := s.lockForIdempotencyKey(ctx, idempotencyKey, requestHash)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
+	quote, err := s.GetQuoteByID(ctx, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The quoted rates and margin are locked as the quote has them. Quotes
+	// without a bid, such as multi-leg ones, are priced from the mid rate.
+	midRate := quote.MidMarketRate.InexactFloat64()
+	bidRate := quote.BidRate
+	if bidRate <= 0 {
+		bidRate = midRate
+	}
+	rate := &model.ExchangeRate{
+		SourceCurrency:   quote.SourceCurrency,
+		TargetCurrency:   quote.TargetCurrency,
+		MidRate:          midRate,
+		Rate:             quote.MidMarketRate.StringFixed(model.RatePlaces),
+		BuyRate:          quote.ExchangeRate.StringFixed(model.RatePlaces),
+		BidRate:          bidRate,
+		MarginPercentage: quote.MarginPercentage,
+		Source:           "quote:" + quote.QuoteID,
+		ExpiresAt:        quote.ValidUntil,
+	}
+	providerRate := &provider.Rate{
+		SourceCurrency: quote.SourceCurrency,
+		TargetCurrency: quote.TargetCurrency,
+		MidRate:        midRate,
+		BidRate:        bidRate,
+		Source:         rate.Source,
+	}
+
+	return s.createLock(ctx, idempotencyKey, requestHash, rate, providerRate, quote, durationSeconds)
+}
+
+// createLock locks rate for durationSeconds (the configured default when 0,
+// at most MaxLockDuration) with a snapshot of providerRate, and claims the
+// idempotency key for it, bound to requestHash, when one is given. The
+// corridor's terms are frozen with the lock, or the quote's when the lock is
+// taken on one, in which case the margin the quote captures is recorded once
+// the lock exists.
+func (s *RateService) createLock(ctx context.Context, idempotencyKey, requestHash string, rate *model.ExchangeRate, providerRate *provider.Rate, quote *model.RateQuote, durationSeconds int) (*model.LockedRate, error) {
 	// Validate and cap duration
 	if durationSeconds <= 0 {
 		durationSeconds = s.config.LockDuration
 	}
-	if durationSeconds > 120 {
-		durationSeconds = 120 // Max 2 minutes
+	if max := s.config.MaxLockDuration; max > 0 && durationSeconds > max {
+		durationSeconds = max
 	}
 
-	// Get current rate
-	rate, err := s.GetRate(ctx, from, to)
-	if err != nil {
+	from, to := rate.SourceCurrency, rate.TargetCurrency
+	if err := s.checkLockLimit(ctx, from, to); err != nil {
 		return nil, err
 	}
 
 	lockID := uuid.New().String()
+	// Round the expiry up so the lock is never shorter than requested
 	lockedAt := time.Now()
-	expiresAt := lockedAt.Add(time.Duration(durationSeconds) * time.Second)
+	duration := time.Duration(durationSeconds) * time.Second
+	expiresAt := roundUpTime(lockedAt.Add(duration), s.expiryGranularity())
 
 	locked := &model.LockedRate{
 		LockID:    lockID,
@@ -145,10 +758,78 @@ func (s *RateService) LockRate(ctx context.Context, from, to string, durationSec
 		ExpiresAt: expiresAt,
 		Expired:   false,
 	}
+	if quote != nil {
+		locked.QuoteID = quote.QuoteID
+		locked.MarginPercentage = quote.MarginPercentage
+		locked.FeePercentage = quote.FeePercentage
+		locked.FeeMinimum = quote.FeeMinimum
+	} else if corridor := s.getCorridor(from, to); corridor != nil {
+		feeMinimum := corridor.FeeMinimum
+		locked.MarginPercentage = corridor.MarginPercentage
+		locked.FeePercentage = corridor.FeePercentage
+		locked.FeeMinimum = &feeMinimum
+	}
+
+	// Keep the market conditions behind the lock for disputes. The snapshot
+	// is written first so no lock exists without one.
+	if retention := time.Duration(s.config.RateSnapshotRetentionHours) * time.Hour; retention > 0 {
+		snapshot := rateSnapshot(locked, providerRate)
+		if err := s.repository.SaveRateSnapshot(ctx, snapshot, time.Until(expiresAt)+retention); err != nil {
+			if !s.useLockFallback(err) {
+				return nil, fmt.Errorf("failed to save rate snapshot: %w", err)
+			}
+			s.logger.Warn("Repository unreachable, locking without a rate snapshot (degraded mode)",
+				zap.String("lockId", lockID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	// Store in repository, or in memory while it can't be reached
+	saveCtx, span := tracing.Start(ctx, "repository.SaveLockedRate", tracing.PairAttributes(from, to)...)
+	err := s.repository.SaveLockedRate(saveCtx, locked)
+	tracing.End(span, err)
+	if err != nil {
+		if !s.useLockFallback(err) {
+			return nil, fmt.Errorf("failed to lock rate: %w", err)
+		}
+		s.lockFallback.save(locked)
+		s.recordLockFallback("save")
+		s.logger.Warn("Repository unreachable, keeping rate lock in memory (degraded mode)",
+			zap.String("lockId", lockID),
+			zap.Error(err),
+		)
+	}
+
+	if idempotencyKey != "" {
+		record := repository.LockIdempotencyRecord{LockID: lockID, RequestHash: requestHash}
+		claimed, err := s.repository.ClaimLockIdempotencyKey(ctx, idempotencyKey, record, time.Until(expiresAt))
+		if err != nil {
+			if !s.useLockFallback(err) {
+				return nil, fmt.Errorf("failed to record idempotency key: %w", err)
+			}
+			claimed = s.lockFallback.claimKey(idempotencyKey, record, expiresAt)
+			s.recordLockFallback("claim_key")
+		}
+		if claimed.LockID != lockID {
+			// A concurrent request won the race; drop our lock and return theirs
+			s.discardLock(ctx, lockID)
+			existing, err := s.lockForIdempotencyKey(ctx, idempotencyKey, requestHash)
+			if err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				return nil, ErrIdempotentRequestInProgress{Key: idempotencyKey}
+			}
+			return existing, nil
+		}
+	}
 
-	// Store in repository
-	if err := s.repository.SaveLockedRate(ctx, locked); err != nil {
-		return nil, fmt.Errorf("failed to lock rate: %w", err)
+	if s.metrics != nil {
+		s.metrics.RecordRateLock(from, to, float64(durationSeconds))
+	}
+	if quote != nil {
+		s.recordCapturedMargin(ctx, quote)
 	}
 
 	s.logger.Info("Rate locked",
@@ -161,99 +842,1022 @@ func (s *RateService) LockRate(ctx context.Context, from, to string, durationSec
 	return locked, nil
 }
 
-// GetLockedRate retrieves a previously locked rate
-func (s *RateService) GetLockedRate(ctx context.Context, lockID string) (*model.LockedRate, error) {
-	locked, err := s.repository.GetLockedRate(ctx, lockID)
-	if err != nil {
-		if _, ok := err.(repository.ErrExpired); ok {
-			return &model.LockedRate{
-				LockID:  lockID,
-				Expired: true,
-			}, nil
+// discardLock removes a lock that was never handed out, along with its rate
+// snapshot and any copy held in memory. Failures are only logged: whatever
+// is left behind expires with the lock.
+func (s *RateService) discardLock(ctx context.Context, lockID string) {
+	inMemory := s.lockFallback != nil && s.lockFallback.remove(lockID)
+	if err := s.repository.DeleteLockedRate(ctx, lockID); err != nil {
+		_, notFound := err.(repository.ErrNotFound)
+		if !notFound && !(inMemory && repository.IsConnectionError(err)) {
+			s.logger.Warn("Failed to delete discarded rate lock", zap.String("lockId", lockID), zap.Error(err))
 		}
-		return nil, err
 	}
-
-	if locked == nil {
-		return &model.LockedRate{
-			LockID:  lockID,
-			Expired: true,
-		}, nil
+	if err := s.repository.DeleteRateSnapshot(ctx, lockID); err != nil && !(inMemory && repository.IsConnectionError(err)) {
+		s.logger.Warn("Failed to delete discarded lock's rate snapshot", zap.String("lockId", lockID), zap.Error(err))
 	}
-
-	return locked, nil
 }
 
-// DeleteLockedRate removes a locked rate (e.g., after transfer is complete)
-func (s *RateService) DeleteLockedRate(ctx context.Context, lockID string) error {
-	if err := s.repository.DeleteLockedRate(ctx, lockID); err != nil {
-		if _, ok := err.(repository.ErrNotFound); ok {
-			return nil // Already deleted or expired
+// checkLockLimit returns ErrLockLimitExceeded if the pair has no room for
+// another lock. The count and the save aren't atomic, so concurrent locks may
+// briefly overshoot the limit; it guards against runaway clients rather than
+// being an exact quota. While the repository is unreachable and locks fall
+// back to memory, the limit isn't enforced.
+func (s *RateService) checkLockLimit(ctx context.Context, from, to string) error {
+	limit := s.config.MaxActiveLocksPerPair
+	if limit <= 0 {
+		return nil
+	}
+
+	active, err := s.repository.CountActiveLocks(ctx, from, to)
+	if err != nil {
+		if s.useLockFallback(err) {
+			return nil
 		}
-		return err
+		return fmt.Errorf("failed to count active locks: %w", err)
+	}
+	if active >= limit {
+		s.logger.Warn("Rate lock limit reached",
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("limit", limit),
+		)
+		return ErrLockLimitExceeded{SourceCurrency: from, TargetCurrency: to, Limit: limit}
 	}
 	return nil
 }
 
-// GetCorridors returns all available corridors
-func (s *RateService) GetCorridors(sourceCurrency string) []model.Corridor {
-	if sourceCurrency == "" {
-		return model.Corridors
+// rateSnapshot records the provider rate and corridor terms behind a lock
+func rateSnapshot(locked *model.LockedRate, rate *provider.Rate) *model.RateSnapshot {
+	return &model.RateSnapshot{
+		LockID:           locked.LockID,
+		SourceCurrency:   locked.Rate.SourceCurrency,
+		TargetCurrency:   locked.Rate.TargetCurrency,
+		MidRate:          rate.MidRate,
+		BidRate:          rate.BidRate,
+		AskRate:          rate.AskRate,
+		Spread:           rate.Spread,
+		Drift:            rate.Drift,
+		BuyRate:          locked.Rate.BuyRate,
+		MarginPercentage: locked.Rate.MarginPercentage,
+		FeePercentage:    locked.FeePercentage,
+		FeeMinimum:       locked.FeeMinimum,
+		Provider:         rate.Source,
+		FetchedAt:        rate.FetchedAt,
+		LockedAt:         locked.LockedAt,
+		ExpiresAt:        locked.ExpiresAt,
 	}
+}
 
-	var filtered []model.Corridor
-	for _, c := range model.Corridors {
-		if c.SourceCurrency == sourceCurrency {
-			filtered = append(filtered, c)
-		}
+// GetRateSnapshot returns the market snapshot taken when a rate was locked.
+// Snapshots outlive their locks for the configured retention window.
+func (s *RateService) GetRateSnapshot(ctx context.Context, lockID string) (*model.RateSnapshot, error) {
+	snapshot, err := s.repository.GetRateSnapshot(ctx, lockID)
+	if err != nil {
+		return nil, err
 	}
-	return filtered
+	if snapshot == nil {
+		return nil, repository.ErrNotFound{Key: lockID}
+	}
+	return snapshot, nil
 }
 
-// GetQuote generates a customer-facing rate quote
-func (s *RateService) GetQuote(ctx context.Context, from, to string, sourceAmount float64) (*model.RateQuote, error) {
-	rate, err := s.GetRate(ctx, from, to)
+// GetRateHistory returns the rates recorded for a pair between start and end,
+// oldest first. With canonical ordering the history is kept for the canonical
+// direction and inverted for the other.
+func (s *RateService) GetRateHistory(ctx context.Context, from, to string, start, end time.Time) (*model.RateHistory, error) {
+	pair, inverted := s.ratePair(from, to)
+	points, err := s.repository.GetRateHistory(ctx, pair.Source, pair.Target, start, end)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get corridor for fee calculation
-	corridor := s.getCorridor(from, to)
-	if corridor == nil {
-		return nil, fmt.Errorf("corridor not found: %s/%s", from, to)
+	if inverted {
+		for i := range points {
+			points[i] = invertHistoryPoint(points[i])
+		}
 	}
+	return &model.RateHistory{
+		SourceCurrency: from,
+		TargetCurrency: to,
+		From:           start,
+		To:             end,
+		Points:         points,
+	}, nil
+}
 
-	// Calculate fee
-	feePercent, _ := strconv.ParseFloat(corridor.FeePercentage, 64)
-	feeMinAmount, _ := strconv.ParseFloat(corridor.FeeMinimum.Amount, 64)
-
-	fee := sourceAmount * (feePercent / 100)
-	if fee < feeMinAmount {
-		fee = feeMinAmount
+// invertHistoryPoint returns the point for the opposite direction, swapping
+// bid and ask as provider.Rate.Inverse does
+func invertHistoryPoint(point model.RateHistoryPoint) model.RateHistoryPoint {
+	inverse := model.RateHistoryPoint{Timestamp: point.Timestamp, MidRate: 1 / point.MidRate}
+	if point.AskRate != 0 {
+		inverse.BidRate = 1 / point.AskRate
 	}
+	if point.BidRate != 0 {
+		inverse.AskRate = 1 / point.BidRate
+	}
+	return inverse
+}
 
-	// Calculate conversion
-	buyRate, _ := strconv.ParseFloat(rate.BuyRate, 64)
-	targetAmount := sourceAmount * buyRate
+// lockForIdempotencyKey returns the still-valid lock created for a key, or
+// nil. It returns ErrIdempotencyKeyConflict if the key was used for a request
+// other than the one hashing to requestHash.
+func (s *RateService) lockForIdempotencyKey(ctx context.Context, idempotencyKey, requestHash string) (*model.LockedRate, error) {
+	record, err := s.repository.GetLockIdempotencyKey(ctx, idempotencyKey)
+	if err != nil && !s.useLockFallback(err) {
+		return nil, fmt.Errorf("failed to check idempotency key: %w", err)
+	}
+	// Keys claimed during an outage stay in memory after it ends
+	if record == nil && s.lockFallback != nil {
+		record = s.lockFallback.getKey(idempotencyKey)
+	}
+	if record == nil {
+		return nil, nil
+	}
+	if record.RequestHash != "" && record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyConflict{Key: idempotencyKey}
+	}
+	lockID := record.LockID
+
+	locked, err := s.GetLockedRate(ctx, lockID)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked.Expired {
+		// The lock expired or was released, so the key no longer protects anything
+		if s.lockFallback != nil {
+			s.lockFallback.removeKey(idempotencyKey)
+		}
+		if err := s.repository.DeleteLockIdempotencyKey(ctx, idempotencyKey); err != nil && !s.useLockFallback(err) {
+			return nil, fmt.Errorf("failed to release idempotency key: %w", err)
+		}
+		return nil, nil
+	}
+
+	s.logger.Info("Returning existing lock for idempotency key",
+		zap.String("lockId", lockID),
+	)
+	return locked, nil
+}
+
+// lockRequestHash identifies a lock request by its parameters, so a retry can
+// be told apart from a different request reusing the idempotency key
+func lockRequestHash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ExtendLockedRates extends several locks by additionalSeconds in one call.
+// Expired or missing locks, and extensions that would exceed MaxLockDuration,
+// are reported per lock rather than failing the batch. The new expiries are
+// written in a single pipelined round trip.
+func (s *RateService) ExtendLockedRates(ctx context.Context, lockIDs []string, additionalSeconds int) ([]model.LockExtensionResult, error) {
+	if additionalSeconds <= 0 {
+		return nil, fmt.Errorf("additional seconds must be positive")
+	}
+
+	results := make([]model.LockExtensionResult, len(lockIDs))
+	toSave := make([]*model.LockedRate, 0, len(lockIDs))
+	saveIdx := make([]int, 0, len(lockIDs))
+
+	for i, lockID := range lockIDs {
+		results[i].LockID = lockID
+
+		locked, inMemory, err := s.findLockToExtend(ctx, lockID)
+		if err != nil {
+			switch err.(type) {
+			case repository.ErrExpired:
+				results[i].Error = "lock expired"
+			case repository.ErrNotFound:
+				results[i].Error = "lock not found"
+			default:
+				results[i].Error = err.Error()
+			}
+			continue
+		}
+		newExpiry, err := s.lockExtension(locked, additionalSeconds)
+		if err != nil {
+			if _, ok := err.(repository.ErrExpired); ok {
+				results[i].Error = "lock expired"
+			} else {
+				results[i].Error = err.Error()
+			}
+			continue
+		}
+
+		extended := *locked
+		extended.ExpiresAt = newExpiry
+		if inMemory {
+			s.lockFallback.save(&extended)
+			s.recordLockFallback("extend")
+			s.logger.Warn("Extending rate lock held in memory (degraded mode)", zap.String("lockId", lockID))
+			results[i].Extended = true
+			results[i].ExpiresAt = &newExpiry
+			continue
+		}
+		toSave = append(toSave, &extended)
+		saveIdx = append(saveIdx, i)
+	}
+
+	var expired []string
+	if len(toSave) > 0 {
+		var err error
+		if expired, err = s.repository.SaveLockedRates(ctx, toSave); err != nil {
+			return nil, fmt.Errorf("failed to extend locks: %w", err)
+		}
+	}
+	expiredIDs := make(map[string]bool, len(expired))
+	for _, lockID := range expired {
+		expiredIDs[lockID] = true
+	}
+
+	for j, i := range saveIdx {
+		if expiredIDs[toSave[j].LockID] {
+			// Lapsed between being read and written
+			results[i].Error = "lock expired"
+			continue
+		}
+		expiresAt := toSave[j].ExpiresAt
+		results[i].Extended = true
+		results[i].ExpiresAt = &expiresAt
+	}
+
+	extended := 0
+	for _, result := range results {
+		if result.Extended {
+			extended++
+		}
+	}
+
+	s.logger.Info("Extended rate locks",
+		zap.Int("requested", len(lockIDs)),
+		zap.Int("extended", extended),
+		zap.Int("additionalSeconds", additionalSeconds),
+	)
+
+	return results, nil
+}
+
+// ExtendLockedRate extends a single rate lock by additionalSeconds. Locks
+// that have expired can't be extended, and no lock is kept longer than
+// MaxLockDuration in total.
+func (s *RateService) ExtendLockedRate(ctx context.Context, lockID string, additionalSeconds int) (*model.LockedRate, error) {
+	if additionalSeconds <= 0 {
+		return nil, fmt.Errorf("additional seconds must be positive, got %d", additionalSeconds)
+	}
+
+	locked, inMemory, err := s.findLockToExtend(ctx, lockID)
+	if err != nil {
+		return nil, err
+	}
+
+	newExpiry, err := s.lockExtension(locked, additionalSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	extended := *locked
+	extended.ExpiresAt = newExpiry
+	if inMemory {
+		s.lockFallback.save(&extended)
+		s.recordLockFallback("extend")
+		s.logger.Warn("Extending rate lock held in memory (degraded mode)", zap.String("lockId", lockID))
+	} else if err := s.repository.ExtendLockedRate(ctx, lockID, newExpiry); err != nil {
+		return nil, fmt.Errorf("failed to extend lock: %w", err)
+	}
+
+	s.logger.Info("Extended rate lock",
+		zap.String("lockId", lockID),
+		zap.Int("additionalSeconds", additionalSeconds),
+		zap.Time("expiresAt", newExpiry),
+	)
+
+	return &extended, nil
+}
+
+// findLockToExtend loads a lock for extension, reporting whether it is held
+// in memory since an outage, in which case it is extended there. A lock that
+// can't be found is reported as repository.ErrNotFound.
+func (s *RateService) findLockToExtend(ctx context.Context, lockID string) (*model.LockedRate, bool, error) {
+	locked, err := s.repository.GetLockedRate(ctx, lockID)
+	if err != nil {
+		if !s.useLockFallback(err) {
+			return nil, false, err
+		}
+		if locked = s.lockFallback.get(lockID); locked == nil {
+			return nil, false, err
+		}
+		return locked, true, nil
+	}
+	if locked == nil && s.lockFallback != nil {
+		if locked = s.lockFallback.get(lockID); locked != nil {
+			return locked, true, nil
+		}
+	}
+	if locked == nil {
+		return nil, false, repository.ErrNotFound{Key: lockID}
+	}
+	return locked, false, nil
+}
+
+// lockExtension returns the lock's expiry once extended by
+// additionalSeconds, or why it can't be extended
+func (s *RateService) lockExtension(locked *model.LockedRate, additionalSeconds int) (time.Time, error) {
+	if time.Now().After(locked.ExpiresAt) {
+		return time.Time{}, repository.ErrExpired{LockID: locked.LockID}
+	}
+
+	newExpiry := locked.ExpiresAt.Add(time.Duration(additionalSeconds) * time.Second)
+	maxDuration := time.Duration(s.config.MaxLockDuration) * time.Second
+	// The expiry was rounded up when locked, by less than the granularity
+	if maxDuration > 0 && newExpiry.Sub(locked.LockedAt) > maxDuration+s.expiryGranularity() {
+		return time.Time{}, ErrLockDurationExceeded{LockID: locked.LockID, MaxSeconds: s.config.MaxLockDuration}
+	}
+	return newExpiry, nil
+}
+
+// GetLockedRate retrieves a previously locked rate
+func (s *RateService) GetLockedRate(ctx context.Context, lockID string) (*model.LockedRate, error) {
+	locked, err := s.repository.GetLockedRate(ctx, lockID)
+	if err != nil {
+		if _, ok := err.(repository.ErrExpired); ok {
+			return &model.LockedRate{
+				LockID:  lockID,
+				Expired: true,
+			}, nil
+		}
+		if !s.useLockFallback(err) {
+			return nil, err
+		}
+		held := s.lockFallback.get(lockID)
+		if held == nil {
+			return nil, err
+		}
+		s.recordLockFallback("get")
+		s.logger.Warn("Repository unreachable, serving rate lock from memory (degraded mode)",
+			zap.String("lockId", lockID),
+			zap.Error(err),
+		)
+		return held, nil
+	}
+
+	// Locks created during an outage stay in memory after it ends
+	if locked == nil && s.lockFallback != nil {
+		if held := s.lockFallback.get(lockID); held != nil {
+			s.recordLockFallback("get")
+			return held, nil
+		}
+	}
+
+	if locked == nil {
+		return &model.LockedRate{
+			LockID:  lockID,
+			Expired: true,
+		}, nil
+	}
+
+	return locked, nil
+}
+
+// useLockFallback reports whether a failed lock read or write should fall
+// back to memory: the fallback is enabled and the repository is unreachable
+func (s *RateService) useLockFallback(err error) bool {
+	return s.lockFallback != nil && repository.IsConnectionError(err)
+}
+
+// recordLockFallback counts a lock operation served from memory
+func (s *RateService) recordLockFallback(operation string) {
+	if s.metrics != nil {
+		s.metrics.RecordLockFallback(operation)
+	}
+}
+
+// DeleteLockedRate removes a locked rate (e.g., after transfer is complete).
+// Releasing a lock that is already gone succeeds, so callers can retry.
+func (s *RateService) DeleteLockedRate(ctx context.Context, lockID string) error {
+	released := s.lockFallback != nil && s.lockFallback.remove(lockID)
+	if err := s.repository.DeleteLockedRate(ctx, lockID); err != nil {
+		_, notFound := err.(repository.ErrNotFound)
+		if !notFound && !(released && repository.IsConnectionError(err)) {
+			return err
+		}
+		// Already deleted or expired, or only ever held in memory
+	} else {
+		released = true
+	}
+
+	if released {
+		s.logger.Info("Rate lock released", zap.String("lockId", lockID))
+		if s.metrics != nil {
+			s.metrics.RecordRateLockExpired()
+		}
+	}
+	return nil
+}
+
+// CorridorFilter narrows a corridor listing. Empty fields match any corridor.
+type CorridorFilter struct {
+	SourceCurrency  string
+	TargetCurrency  string
+	PayoutMethod    string // matches corridors offering the method, e.g. "CASH_PICKUP"
+	IncludeDisabled bool   // also list disabled corridors
+}
+
+// Matches reports whether the corridor passes the filter
+func (f CorridorFilter) Matches(c *model.Corridor) bool {
+	if f.SourceCurrency != "" && c.SourceCurrency != f.SourceCurrency {
+		return false
+	}
+	if f.TargetCurrency != "" && c.TargetCurrency != f.TargetCurrency {
+		return false
+	}
+	if !c.Enabled && !f.IncludeDisabled {
+		return false
+	}
+	if f.PayoutMethod == "" {
+		return true
+	}
+	for _, method := range c.PayoutMethods {
+		if method == f.PayoutMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCorridors returns the corridors matching filter. Disabled corridors are
+// only listed with IncludeDisabled.
+func (s *RateService) GetCorridors(filter CorridorFilter) []model.Corridor {
+	var filtered []model.Corridor
+	for _, c := range s.corridors() {
+		if filter.Matches(&c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// GetCorridorQuotes returns the enabled corridors matching filter, each with
+// an illustrative quote for its sample amount. Sample quotes are not
+// customer quotes, so they don't count towards captured margin. Quotes still
+// pending when AggregationBudgetMs runs out are left off and reported in
+// warnings.
+func (s *RateService) GetCorridorQuotes(ctx context.Context, filter CorridorFilter) ([]model.CorridorQuote, []string) {
+	filter.IncludeDisabled = false
+	corridors := s.GetCorridors(filter)
+	budget := time.Duration(s.config.AggregationBudgetMs) * time.Millisecond
+	quotes, done := gatherWithin(ctx, budget, len(corridors), func(ctx context.Context, i int) model.CorridorQuote {
+		entry := model.CorridorQuote{Corridor: corridors[i]}
+		quote, err := s.sampleQuote(ctx, &corridors[i])
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.SampleQuote = quote
+		}
+		return entry
+	})
+
+	var warnings []string
+	for i := range quotes {
+		if !done[i] {
+			quotes[i] = model.CorridorQuote{Corridor: corridors[i], Error: "timed out"}
+			warnings = append(warnings, fmt.Sprintf("sample quote for %s/%s timed out",
+				corridors[i].SourceCurrency, corridors[i].TargetCurrency))
+		}
+	}
+	return quotes, warnings
+}
+
+// sampleQuote prices the corridor's sample amount at the current rate
+func (s *RateService) sampleQuote(ctx context.Context, corridor *model.Corridor) (*model.RateQuote, error) {
+	rate, err := s.GetRate(ctx, corridor.SourceCurrency, corridor.TargetCurrency)
+	if err != nil {
+		return nil, err
+	}
+	return s.priceQuote(rate, corridor, model.AmountFromFloat(corridor.SampleSourceAmount(), corridor.SourceCurrency))
+}
+
+// GetQuote generates a customer-facing rate quote. Pairs without a corridor
+// are quoted through intermediate currencies when MaxQuoteLegs allows it, and
+// disabled corridors are rejected with ErrCorridorDisabled.
+func (s *RateService) GetQuote(ctx context.Context, from, to string, sourceAmount float64) (*model.RateQuote, error) {
+	ctx, span := tracing.Start(ctx, "RateService.GetQuote", tracing.PairAttributes(from, to)...)
+	quote, err := s.getQuote(ctx, from, to, sourceAmount)
+	tracing.End(span, err)
+	return quote, err
+}
+
+// getQuote is GetQuote without its span
+func (s *RateService) getQuote(ctx context.Context, from, to string, sourceAmount float64) (*model.RateQuote, error) {
+	if s.reversibleCorridor(from, to) != nil {
+		return s.getReverseQuote(ctx, from, to, sourceAmount)
+	}
+	if s.config.MaxQuoteLegs > 1 && s.getCorridor(from, to) == nil {
+		return s.getMultiLegQuote(ctx, from, to, sourceAmount)
+	}
+	if corridor := s.getCorridor(from, to); corridor != nil && !corridor.Enabled {
+		return nil, ErrCorridorDisabled{SourceCurrency: from, TargetCurrency: to}
+	}
+
+	rate, err := s.quoteRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get corridor for fee calculation
+	corridor := s.getCorridor(from, to)
+	if corridor == nil {
+		return nil, ErrCorridorNotFound{SourceCurrency: from, TargetCurrency: to}
+	}
+	amount := model.AmountFromFloat(sourceAmount, from)
+	if err := checkSourceAmount(corridor, amount); err != nil {
+		return nil, err
+	}
+
+	return s.buildQuote(ctx, rate, corridor, amount)
+}
+
+// checkSourceAmount returns ErrAmountOutOfRange unless sourceAmount is within
+// the corridor's minimum and maximum amounts
+func checkSourceAmount(corridor *model.Corridor, sourceAmount model.Amount) error {
+	outOfRange := false
+	if min, err := decimal.NewFromString(corridor.MinAmount.Amount); err == nil && sourceAmount.LessThan(min) {
+		outOfRange = true
+	}
+	if max, err := decimal.NewFromString(corridor.MaxAmount.Amount); err == nil && sourceAmount.GreaterThan(max) {
+		outOfRange = true
+	}
+	if !outOfRange {
+		return nil
+	}
+	return ErrAmountOutOfRange{
+		SourceCurrency: corridor.SourceCurrency,
+		TargetCurrency: corridor.TargetCurrency,
+		SourceAmount:   sourceAmount.String(),
+		MinimumAmount:  corridor.MinAmount.Amount,
+		MaximumAmount:  corridor.MaxAmount.Amount,
+	}
+}
+
+// GetQuoteByID returns a previously issued quote while it is still valid, so
+// a transfer can be made at exactly the fee and rate the customer saw
+func (s *RateService) GetQuoteByID(ctx context.Context, quoteID string) (*model.RateQuote, error) {
+	quote, err := s.repository.GetQuote(ctx, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, ErrQuoteNotFound{QuoteID: quoteID}
+	}
+	if time.Now().After(quote.ValidUntil) {
+		return nil, ErrQuoteExpired{QuoteID: quoteID}
+	}
+	return quote, nil
+}
+
+// GetQuoteForTarget generates a quote for the source amount that converts to
+// targetAmount, solving the forward fee math in reverse. The recipient gets
+// exactly targetAmount. Corridors whose fee or margin would make the solve
+// diverge are rejected, the solved source amount must be within the
+// corridor's range, and the solved total is capped at MaxQuoteAmount.
+func (s *RateService) GetQuoteForTarget(ctx context.Context, from, to string, targetAmount float64) (*model.RateQuote, error) {
+	if corridor := s.getCorridor(from, to); corridor != nil && !corridor.Enabled {
+		return nil, ErrCorridorDisabled{SourceCurrency: from, TargetCurrency: to}
+	}
+
+	rate, err := s.quoteRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	corridor := s.getCorridor(from, to)
+	if corridor == nil {
+		return nil, ErrCorridorNotFound{SourceCurrency: from, TargetCurrency: to}
+	}
+
+	feePercent, err := strconv.ParseFloat(corridor.FeePercentage, 64)
+	if err != nil || feePercent < 0 {
+		return nil, ErrReverseQuoteUnstable{
+			SourceCurrency: from,
+			TargetCurrency: to,
+			Reason:         fmt.Sprintf("invalid fee percentage %q", corridor.FeePercentage),
+		}
+	}
+	if max := s.config.MaxReverseFeePercentage; max > 0 && feePercent >= max {
+		return nil, ErrReverseQuoteUnstable{
+			SourceCurrency: from,
+			TargetCurrency: to,
+			Reason:         fmt.Sprintf("fee percentage %s%% is at or above the limit of %g%%", corridor.FeePercentage, max),
+		}
+	}
+
+	// A margin at or near 100% drives the buy rate to zero, and the solved
+	// source amount towards infinity
+	buyRate, _ := strconv.ParseFloat(rate.BuyRate, 64)
+	if buyRate <= 0 || math.IsInf(targetAmount/buyRate, 0) {
+		return nil, ErrReverseQuoteUnstable{
+			SourceCurrency: from,
+			TargetCurrency: to,
+			Reason:         fmt.Sprintf("buy rate %s leaves nothing to convert", rate.BuyRate),
+		}
+	}
+
+	// Round the source amount up to the source currency's smallest unit so it
+	// always covers the target amount, which the recipient then gets exactly
+	target := model.AmountFromFloat(targetAmount, to)
+	exactBuyRate, _ := decimal.NewFromString(rate.BuyRate)
+	sourceAmount := model.Amount{Decimal: target.Div(exactBuyRate).RoundCeil(model.Precision(from))}
+	if err := checkSourceAmount(corridor, sourceAmount); err != nil {
+		return nil, err
+	}
+
+	quote, err := s.priceQuote(rate, corridor, sourceAmount)
+	if err != nil {
+		return nil, err
+	}
+	quote.TargetAmount = target
+	quote.EffectiveRate = effectiveRate(target, quote.TotalCost)
+	if err := s.recordQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// GetQuoteFromLock prices sourceAmount against a locked rate. With
+// SnapshotLockTerms the margin and fees frozen at lock time are used, so the
+// customer keeps the locked terms even if the corridor config has changed.
+// A lock taken on a quote always keeps the quote's rate and fees, and had its
+// margin recorded when it was locked; any other lock's margin is recorded
+// against the amount quoted here.
+func (s *RateService) GetQuoteFromLock(ctx context.Context, lockID string, sourceAmount float64) (*model.RateQuote, error) {
+	locked, err := s.GetLockedRate(ctx, lockID)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := s.quoteFromLock(ctx, locked, sourceAmount)
+	if err != nil {
+		return nil, err
+	}
+	if locked.QuoteID == "" {
+		s.recordCapturedMargin(ctx, quote)
+	}
+	return quote, nil
+}
+
+// quoteFromLock is GetQuoteFromLock without recording the captured margin
+func (s *RateService) quoteFromLock(ctx context.Context, locked *model.LockedRate, sourceAmount float64) (*model.RateQuote, error) {
+	if locked.Expired {
+		return nil, repository.ErrExpired{LockID: locked.LockID}
+	}
+
+	from, to := locked.Rate.SourceCurrency, locked.Rate.TargetCurrency
+	rate := locked.Rate
+	rate.ExpiresAt = locked.ExpiresAt
+
+	corridor := s.getCorridor(from, to)
+	if (s.config.SnapshotLockTerms || locked.QuoteID != "") && locked.FeeMinimum != nil {
+		terms := model.Corridor{SourceCurrency: from, TargetCurrency: to}
+		if corridor != nil {
+			terms = *corridor
+		}
+		terms.MarginPercentage = locked.MarginPercentage
+		terms.FeePercentage = locked.FeePercentage
+		terms.FeeMinimum = *locked.FeeMinimum
+
+		// A quote's buy rate is kept as quoted rather than re-priced
+		if locked.QuoteID == "" {
+			margin, _ := decimal.NewFromString(locked.MarginPercentage)
+			applyMargin(&rate, margin.Div(decimal.NewFromInt(100)))
+		}
+		return s.buildQuote(ctx, &rate, &terms, model.AmountFromFloat(sourceAmount, from))
+	}
+
+	if corridor == nil {
+		return nil, ErrCorridorNotFound{SourceCurrency: from, TargetCurrency: to}
+	}
+	applyMargin(&rate, s.getMargin(from, to))
+	return s.buildQuote(ctx, &rate, corridor, model.AmountFromFloat(sourceAmount, from))
+}
+
+// VerifyQuoteFromLock prices sourceAmount at the terms a rate was locked at,
+// to check a transfer against its lock after the fact. Unlike
+// GetQuoteFromLock it keeps working once the lock has expired, pricing from
+// the lock's snapshot, which is kept for RateSnapshotRetentionHours. Without
+// a snapshot only a live lock can be verified. The quote isn't stored.
+func (s *RateService) VerifyQuoteFromLock(ctx context.Context, lockID string, sourceAmount float64) (*model.RateQuote, error) {
+	snapshot, err := s.repository.GetRateSnapshot(ctx, lockID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		// Verifying isn't a new use of the lock, so no margin is recorded
+		locked, err := s.GetLockedRate(ctx, lockID)
+		if err != nil {
+			return nil, err
+		}
+		return s.quoteFromLock(ctx, locked, sourceAmount)
+	}
+
+	from, to := snapshot.SourceCurrency, snapshot.TargetCurrency
+	terms := model.Corridor{SourceCurrency: from, TargetCurrency: to}
+	if corridor := s.getCorridor(from, to); corridor != nil {
+		terms = *corridor
+	}
+	if snapshot.FeeMinimum != nil {
+		terms.FeePercentage = snapshot.FeePercentage
+		terms.FeeMinimum = *snapshot.FeeMinimum
+	}
+	// The amount was accepted when locked, whatever the minimum is now
+	terms.MinTargetAmount = model.Money{}
+
+	rate := &model.ExchangeRate{
+		SourceCurrency:   from,
+		TargetCurrency:   to,
+		MidRate:          snapshot.MidRate,
+		BuyRate:          snapshot.BuyRate,
+		BidRate:          snapshot.BidRate,
+		AskRate:          snapshot.AskRate,
+		Spread:           snapshot.Spread,
+		MarginPercentage: snapshot.MarginPercentage,
+		Source:           snapshot.Provider,
+		FetchedAt:        snapshot.FetchedAt,
+		ExpiresAt:        snapshot.ExpiresAt,
+	}
+	return s.priceConversion(rate, &terms, model.AmountFromFloat(sourceAmount, from))
+}
+
+// applyMargin sets the rate's buy rate and margin. Rates are quoted as target
+// currency per unit of source, so the customer gets the provider's bid, less
+// the margin, and a wider spread prices worse; rates without a bid fall back
+// to the mid rate.
+func applyMargin(rate *model.ExchangeRate, margin decimal.Decimal) {
+	basis := rate.BidRate
+	if basis <= 0 {
+		basis = rate.MidRate
+	}
+	buyRate := decimal.NewFromFloat(basis).Mul(decimal.NewFromInt(1).Sub(margin))
+	rate.BuyRate = buyRate.StringFixed(model.RatePlaces)
+	rate.MarginPercentage = margin.Mul(decimal.NewFromInt(100)).StringFixed(2)
+}
+
+// buildQuote prices sourceAmount against rate with the corridor's fees and
+// records the quote
+func (s *RateService) buildQuote(ctx context.Context, rate *model.ExchangeRate, corridor *model.Corridor, sourceAmount model.Amount) (*model.RateQuote, error) {
+	quote, err := s.priceQuote(rate, corridor, sourceAmount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// recordQuote stores a quote given to a customer so it can be redeemed
+// later, and counts it. A quote that can't be stored isn't given out, since
+// its ID couldn't be redeemed.
+func (s *RateService) recordQuote(ctx context.Context, quote *model.RateQuote) error {
+	if err := s.repository.SaveQuote(ctx, quote); err != nil {
+		return fmt.Errorf("failed to store quote: %w", err)
+	}
+	if s.metrics != nil {
+		s.metrics.RecordQuoteGenerated(quote.SourceCurrency, quote.TargetCurrency)
+	}
+	return nil
+}
+
+// priceQuote prices sourceAmount against rate with the corridor's fees
+func (s *RateService) priceQuote(rate *model.ExchangeRate, corridor *model.Corridor, sourceAmount model.Amount) (*model.RateQuote, error) {
+	quote, err := s.priceConversion(rate, corridor, sourceAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkQuoteTotal(quote.TotalCost, quote.SourceCurrency); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// checkQuoteTotal returns ErrQuoteAmountTooLarge if a quote's total cost
+// exceeds MaxQuoteAmount
+func (s *RateService) checkQuoteTotal(totalCost model.Amount, currency string) error {
+	if s.config.MaxQuoteAmount <= 0 {
+		return nil
+	}
+	max := model.AmountFromFloat(s.config.MaxQuoteAmount, currency)
+	if !totalCost.GreaterThan(max.Decimal) {
+		return nil
+	}
+	return ErrQuoteAmountTooLarge{
+		SourceCurrency: currency,
+		TotalCost:      totalCost.String(),
+		MaximumAmount:  max.String(),
+	}
+}
+
+// priceConversion prices sourceAmount against rate with the corridor's fees,
+// without capping the total. Multi-leg quotes cap the total once, in the
+// transfer's source currency, rather than per leg. The math is done in
+// decimal: the fee is rounded to the source currency's precision by the
+// FeeRounding policy and the target amount half-up to its currency's, so the
+// total cost is exactly the source amount plus the fee.
+func (s *RateService) priceConversion(rate *model.ExchangeRate, corridor *model.Corridor, sourceAmount model.Amount) (*model.RateQuote, error) {
+	from, to := corridor.SourceCurrency, corridor.TargetCurrency
+
+	// Calculate fee
+	feePercent, _ := decimal.NewFromString(corridor.FeePercentage)
+	feeMinAmount, _ := decimal.NewFromString(corridor.FeeMinimum.Amount)
+
+	fee := s.roundFee(sourceAmount.Mul(feePercent).Div(decimal.NewFromInt(100)), from)
+	if fee.LessThan(feeMinAmount) {
+		fee = s.roundFee(feeMinAmount, from)
+	}
+
+	// Calculate conversion
+	buyRate, _ := decimal.NewFromString(rate.BuyRate)
+	targetAmount := model.NewAmount(sourceAmount.Mul(buyRate), to)
+
+	// Reject amounts that convert below what payout networks will deliver
+	if minTarget, err := decimal.NewFromString(corridor.MinTargetAmount.Amount); err == nil && targetAmount.LessThan(minTarget) {
+		return nil, ErrBelowMinTargetAmount{
+			SourceCurrency: from,
+			TargetCurrency: to,
+			TargetAmount:   targetAmount.String(),
+			MinimumAmount:  corridor.MinTargetAmount.Amount,
+		}
+	}
+
+	totalCost := model.Amount{Decimal: sourceAmount.Add(fee.Decimal)}
+	feeMinimum := corridor.FeeMinimum
 
 	quote := &model.RateQuote{
 		SourceCurrency: from,
 		TargetCurrency: to,
 		SourceAmount:   sourceAmount,
 		TargetAmount:   targetAmount,
-		ExchangeRate:   buyRate,
-		MidMarketRate:  rate.MidRate,
+		ExchangeRate:   model.NewRateValue(buyRate),
+		MidMarketRate:  model.RateValueFromFloat(rate.MidRate),
 		Fee:            fee,
-		TotalCost:      sourceAmount + fee,
-		ValidUntil:     rate.ExpiresAt,
+		TotalCost:      totalCost,
+		EffectiveRate:  effectiveRate(targetAmount, totalCost),
+		ValidUntil:     roundDownTime(rate.ExpiresAt, s.expiryGranularity()),
 		QuoteID:        uuid.New().String(),
+
+		MarginPercentage: rate.MarginPercentage,
+		BidRate:          rate.BidRate,
+		FeePercentage:    corridor.FeePercentage,
+		FeeMinimum:       &feeMinimum,
+
+		SourcePrecision: model.Precision(from),
+		TargetPrecision: model.Precision(to),
 	}
 
 	return quote, nil
 }
 
-// getCorridor finds the corridor for a currency pair
+// roundFee rounds a fee to currency's precision by the FeeRounding policy.
+// Fees round up unless configured otherwise, so fractions of the smallest
+// unit go to the house.
+func (s *RateService) roundFee(fee decimal.Decimal, currency string) model.Amount {
+	places := model.Precision(currency)
+	switch s.config.FeeRounding {
+	case "nearest":
+		return model.NewAmount(fee, currency)
+	case "down":
+		return model.NewAmount(fee.RoundFloor(places), currency)
+	default:
+		return model.NewAmount(fee.RoundCeil(places), currency)
+	}
+}
+
+// effectiveRate is the rate the customer gets once fees are counted: target
+// amount per unit of source currency paid in total
+func effectiveRate(targetAmount, totalCost model.Amount) model.RateValue {
+	if !totalCost.IsPositive() {
+		return model.RateValue{}
+	}
+	return model.NewRateValue(targetAmount.Div(totalCost.Decimal))
+}
+
+// recordCapturedMargin reports the margin a quote from a lock captures: the
+// difference between the mid and buy rate times the amount, converted from
+// the target currency to the reporting currency. The conversion uses the
+// quote's own mid rate when the reporting currency is the source, and
+// otherwise the cached rate, so locking never waits on the provider; without
+// a cached rate the margin isn't recorded.
+func (s *RateService) recordCapturedMargin(ctx context.Context, quote *model.RateQuote) {
+	currency := s.config.ReportingCurrency
+	if s.onMargin == nil || currency == "" {
+		return
+	}
+
+	margin := quote.SourceAmount.Mul(quote.MidMarketRate.Sub(quote.ExchangeRate.Decimal)).InexactFloat64()
+	if margin <= 0 {
+		return
+	}
+	switch currency {
+	case quote.TargetCurrency:
+	case quote.SourceCurrency:
+		margin /= quote.MidMarketRate.InexactFloat64()
+	default:
+		mid, ok := s.cachedMidRate(ctx, quote.TargetCurrency, currency)
+		if !ok {
+			s.logger.Debug("No cached rate to convert captured margin to reporting currency",
+				zap.String("from", quote.TargetCurrency),
+				zap.String("to", currency),
+			)
+			return
+		}
+		margin *= mid
+	}
+
+	s.onMargin(quote.SourceCurrency+"/"+quote.TargetCurrency, currency, margin)
+}
+
+// cachedMidRate returns the pair's mid rate from the cache, in either
+// direction and even if stale, without calling the provider
+func (s *RateService) cachedMidRate(ctx context.Context, from, to string) (float64, bool) {
+	if rate, err := s.repository.GetStaleRate(ctx, from, to); err == nil && rate != nil && rate.MidRate > 0 {
+		return rate.MidRate, true
+	}
+	if rate, err := s.repository.GetStaleRate(ctx, to, from); err == nil && rate != nil && rate.MidRate > 0 {
+		return 1 / rate.MidRate, true
+	}
+	return 0, false
+}
+
+// rateCacheTTL returns how long a rate for the pair may be cached, using the
+// corridor's RateValiditySeconds override when set. The corridor is matched in
+// either direction since rates may be cached in the canonical direction.
+func (s *RateService) rateCacheTTL(from, to string) time.Duration {
+	corridor := s.getCorridor(from, to)
+	if corridor == nil {
+		corridor = s.getCorridor(to, from)
+	}
+	if corridor != nil && corridor.RateValiditySeconds > 0 {
+		return time.Duration(corridor.RateValiditySeconds) * time.Second
+	}
+	return time.Duration(s.config.RateCacheTTL) * time.Second
+}
+
+// cacheRate stores a provider rate with the pair's cache TTL. ValidUntil is
+// aligned with the TTL so the cache's validity check and Redis expiry agree,
+// except that rates are kept for the stale grace period beyond it.
+func (s *RateService) cacheRate(ctx context.Context, rate *provider.Rate) error {
+	ttl := s.rateCacheTTL(rate.SourceCurrency, rate.TargetCurrency)
+	rate.ValidUntil = time.Now().Add(ttl)
+
+	ctx, span := tracing.Start(ctx, "cache.SaveRate", tracing.PairAttributes(rate.SourceCurrency, rate.TargetCurrency)...)
+	err := s.repository.SaveRate(ctx, rate, ttl+time.Duration(s.config.StaleGracePeriod)*time.Second)
+	tracing.End(span, err)
+	return err
+}
+
+// expiryGranularity returns the precision lock and quote expiries are rounded to
+func (s *RateService) expiryGranularity() time.Duration {
+	return time.Duration(s.config.ExpiryGranularityMs) * time.Millisecond
+}
+
+// roundUpTime rounds t up to a multiple of granularity (no-op when granularity <= 0)
+func roundUpTime(t time.Time, granularity time.Duration) time.Time {
+	if granularity <= 0 {
+		return t
+	}
+	rounded := t.Truncate(granularity)
+	if rounded.Before(t) {
+		rounded = rounded.Add(granularity)
+	}
+	return rounded
+}
+
+// roundDownTime rounds t down to a multiple of granularity, so a quote never
+// outlives the rate it was priced from (no-op when granularity <= 0)
+func roundDownTime(t time.Time, granularity time.Duration) time.Time {
+	if granularity <= 0 {
+		return t
+	}
+	return t.Truncate(granularity)
+}
+
+// getCorridor finds the corridor for a currency pair, with any pricing
+// override applied
 func (s *RateService) getCorridor(from, to string) *model.Corridor {
+	c := s.baseCorridor(from, to)
+	if c == nil {
+		return nil
+	}
+	overridden := s.overrides.apply(*c)
+	return &overridden
+}
+
+// baseCorridor finds the compiled-in corridor for a currency pair
+func (s *RateService) baseCorridor(from, to string) *model.Corridor {
 	for _, c := range model.Corridors {
 		if c.SourceCurrency == from && c.TargetCurrency == to {
 			return &c
@@ -264,40 +1868,120 @@ func (s *RateService) getCorridor(from, to string) *model.Corridor {
 
 // providerRateToModel converts a provider.Rate to model.ExchangeRate
 func (s *RateService) providerRateToModel(rate *provider.Rate, from, to string) *model.ExchangeRate {
-	// Get margin from corridor config
-	margin := s.getMargin(from, to)
+	converted := &model.ExchangeRate{
+		SourceCurrency: from,
+		TargetCurrency: to,
+		MidRate:        rate.MidRate,
+		Rate:           fmt.Sprintf("%.6f", rate.MidRate),
+		BidRate:        rate.BidRate,
+		AskRate:        rate.AskRate,
+		Spread:         rate.Spread,
+		Source:         rate.Source,
+		FetchedAt:      rate.FetchedAt,
+		ExpiresAt:      rate.ValidUntil,
+	}
 
-	// Calculate buy rate (rate offered to customer, includes margin)
-	buyRate := rate.MidRate * (1 - margin)
+	// The buy rate offered to the customer includes the corridor's margin
+	applyMargin(converted, s.getMargin(from, to))
+	return converted
+}
 
-	return &model.ExchangeRate{
-		SourceCurrency:   from,
-		TargetCurrency:   to,
-		MidRate:          rate.MidRate,
-		Rate:             fmt.Sprintf("%.6f", rate.MidRate),
-		BuyRate:          fmt.Sprintf("%.6f", buyRate),
-		BidRate:          rate.BidRate,
-		AskRate:          rate.AskRate,
-		Spread:           rate.Spread,
-		MarginPercentage: fmt.Sprintf("%.2f", margin*100),
-		Source:           rate.Source,
-		FetchedAt:        rate.FetchedAt,
-		ExpiresAt:        rate.ValidUntil,
+// getMargin returns the margin for a currency pair from corridor config,
+// taking a reversible corridor's margin for its reverse direction
+func (s *RateService) getMargin(from, to string) decimal.Decimal {
+	c := s.getCorridor(from, to)
+	if c == nil {
+		c = s.reversibleCorridor(from, to)
+	}
+	if c != nil {
+		margin, _ := decimal.NewFromString(c.MarginPercentage)
+		return margin.Div(decimal.NewFromInt(100))
 	}
+	return decimal.New(3, -3) // Default 0.3%
 }
 
-// getMargin returns the margin for a currency pair from corridor config
-func (s *RateService) getMargin(from, to string) float64 {
-	for _, c := range model.Corridors {
-		if c.SourceCurrency == from && c.TargetCurrency == to {
-			margin, _ := strconv.ParseFloat(c.MarginPercentage, 64)
-			return margin / 100
-		}
+// Health checks if the service and its dependencies are healthy: the
+// repository (Redis) and the rate provider
+func (s *RateService) Health(ctx context.Context) error {
+	if err := s.repository.Health(ctx); err != nil {
+		return fmt.Errorf("redis unhealthy: %w", err)
 	}
-	return 0.003 // Default 0.3%
+	if err := s.checkProvider(ctx); err != nil {
+		return fmt.Errorf("provider unhealthy: %w", err)
+	}
+	return nil
 }
 
-// Health checks if the service and its dependencies are healthy
-func (s *RateService) Health(ctx context.Context) error {
-	return s.repository.Health(ctx)
+// HealthReport reports healthy, degraded or unhealthy. The service is
+// unhealthy when Redis or the provider fails its check, and degraded when
+// both pass but the recent provider error rate exceeds the configured
+// threshold, so load balancers can shed traffic from it.
+func (s *RateService) HealthReport(ctx context.Context) *model.HealthReport {
+	errorRate, requests := s.errorRate.rate()
+	report := &model.HealthReport{
+		State:             model.HealthStateHealthy,
+		ProviderErrorRate: errorRate,
+		ProviderRequests:  requests,
+	}
+
+	// Both dependencies are checked so the report shows which one is down
+	redisErr := s.repository.Health(ctx)
+	providerErr := s.checkProvider(ctx)
+	report.Redis = dependencyHealth(redisErr)
+	report.Provider = dependencyHealth(providerErr)
+	switch {
+	case redisErr != nil:
+		report.State = model.HealthStateUnhealthy
+		report.Error = "redis unhealthy: " + redisErr.Error()
+		return report
+	case providerErr != nil:
+		report.State = model.HealthStateUnhealthy
+		report.Error = "provider unhealthy: " + providerErr.Error()
+		return report
+	}
+
+	if s.IsDegraded() {
+		report.State = model.HealthStateDegraded
+	}
+
+	return report
+}
+
+// checkProvider runs the provider's health check within the provider timeout
+func (s *RateService) checkProvider(ctx context.Context) error {
+	_, err := callWithTimeout(ctx, s.providerTimeout(), s.provider.Name(), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.provider.HealthCheck(ctx)
+	})
+	return err
+}
+
+func dependencyHealth(err error) model.DependencyHealth {
+	if err != nil {
+		return model.DependencyHealth{Error: err.Error()}
+	}
+	return model.DependencyHealth{Healthy: true}
+}
+
+// ProviderErrorRate returns the rolling provider error rate (0-1)
+func (s *RateService) ProviderErrorRate() float64 {
+	errorRate, _ := s.errorRate.rate()
+	return errorRate
+}
+
+// IsDegraded returns true when the provider error rate exceeds the threshold
+func (s *RateService) IsDegraded() bool {
+	if s.config.DegradedErrorRate <= 0 {
+		return false
+	}
+	errorRate, requests := s.errorRate.rate()
+	return requests >= s.config.ErrorRateMinSamples && errorRate > s.config.DegradedErrorRate
+}
+
+// recordProviderResult tracks provider call outcomes for the error rate.
+// Unsupported pairs are caller errors and don't count against the provider.
+func (s *RateService) recordProviderResult(err error) {
+	if _, ok := err.(provider.ErrUnsupportedPair); ok {
+		return
+	}
+	s.errorRate.record(err != nil)
 }
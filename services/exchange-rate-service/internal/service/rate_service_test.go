@@ -2,20 +2,30 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/config"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
 	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/repository"
 	"go.uber.org/zap"
 )
 
 // MockProvider implements provider.RateProvider for testing
 type MockProvider struct {
+	ProviderName string
 	GetRateFunc  func(ctx context.Context, source, target string) (*provider.Rate, error)
 	GetRatesFunc func(ctx context.Context, pairs []provider.CurrencyPair) ([]*provider.Rate, error)
+	HealthFunc   func(ctx context.Context) error
 }
 
 func (m *MockProvider) GetRate(ctx context.Context, source, target string) (*provider.Rate, error) {
@@ -51,6 +61,9 @@ func (m *MockProvider) GetRates(ctx context.Context, pairs []provider.CurrencyPa
 }
 
 func (m *MockProvider) Name() string {
+	if m.ProviderName != "" {
+		return m.ProviderName
+	}
 	return "mock"
 }
 
@@ -58,23 +71,52 @@ func (m *MockProvider) SupportsInverse() bool {
 	return true
 }
 
+// GetSupportedPairs returns the corridors' pairs, though the mock quotes any pair
+func (m *MockProvider) GetSupportedPairs(ctx context.Context) ([]provider.CurrencyPair, error) {
+	pairs := make([]provider.CurrencyPair, len(model.Corridors))
+	for i, c := range model.Corridors {
+		pairs[i] = provider.CurrencyPair{Source: c.SourceCurrency, Target: c.TargetCurrency}
+	}
+	return pairs, nil
+}
+
+func (m *MockProvider) HealthCheck(ctx context.Context) error {
+	if m.HealthFunc != nil {
+		return m.HealthFunc(ctx)
+	}
+	return nil
+}
+
 // MockRepository implements repository.RateRepository for testing
 type MockRepository struct {
-	rates       map[string]*provider.Rate
-	lockedRates map[string]*model.LockedRate
-	SaveRateFunc      func(ctx context.Context, rate *provider.Rate, ttl time.Duration) error
-	GetRateFunc       func(ctx context.Context, source, target string) (*provider.Rate, error)
-	SaveLockedFunc    func(ctx context.Context, locked *model.LockedRate) error
-	GetLockedFunc     func(ctx context.Context, lockID string) (*model.LockedRate, error)
-	DeleteLockedFunc  func(ctx context.Context, lockID string) error
-	ExtendLockedFunc  func(ctx context.Context, lockID string, newExpiry time.Time) error
-	HealthFunc        func(ctx context.Context) error
+	mu               sync.Mutex // guards rates and quotes, which concurrent quotes write
+	rates            map[string]*provider.Rate
+	history          map[string][]model.RateHistoryPoint
+	lockedRates      map[string]*model.LockedRate
+	lockKeys         map[string]repository.LockIdempotencyRecord
+	snapshots        map[string]*model.RateSnapshot
+	snapshotExpiry   map[string]time.Time
+	quotes           map[string]*model.RateQuote
+	overrides        map[string]model.CorridorOverride
+	SaveRateFunc     func(ctx context.Context, rate *provider.Rate, ttl time.Duration) error
+	GetRateFunc      func(ctx context.Context, source, target string) (*provider.Rate, error)
+	SaveLockedFunc   func(ctx context.Context, locked *model.LockedRate) error
+	GetLockedFunc    func(ctx context.Context, lockID string) (*model.LockedRate, error)
+	DeleteLockedFunc func(ctx context.Context, lockID string) error
+	ExtendLockedFunc func(ctx context.Context, lockID string, newExpiry time.Time) error
+	HealthFunc       func(ctx context.Context) error
 }
 
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
-		rates:       make(map[string]*provider.Rate),
-		lockedRates: make(map[string]*model.LockedRate),
+		rates:          make(map[string]*provider.Rate),
+		history:        make(map[string][]model.RateHistoryPoint),
+		lockedRates:    make(map[string]*model.LockedRate),
+		lockKeys:       make(map[string]repository.LockIdempotencyRecord),
+		snapshots:      make(map[string]*model.RateSnapshot),
+		snapshotExpiry: make(map[string]time.Time),
+		quotes:         make(map[string]*model.RateQuote),
+		overrides:      make(map[string]model.CorridorOverride),
 	}
 }
 
@@ -83,22 +125,47 @@ func (m *MockRepository) SaveRate(ctx context.Context, rate *provider.Rate, ttl
 		return m.SaveRateFunc(ctx, rate, ttl)
 	}
 	key := rate.SourceCurrency + ":" + rate.TargetCurrency
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.rates[key] = rate
+	m.history[key] = append(m.history[key], model.RateHistoryPoint{
+		Timestamp: rate.FetchedAt, MidRate: rate.MidRate, BidRate: rate.BidRate, AskRate: rate.AskRate,
+	})
 	return nil
 }
 
+func (m *MockRepository) GetRateHistory(ctx context.Context, source, target string, from, to time.Time) ([]model.RateHistoryPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var points []model.RateHistoryPoint
+	for _, point := range m.history[source+":"+target] {
+		if !point.Timestamp.Before(from) && !point.Timestamp.After(to) {
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
 func (m *MockRepository) GetRate(ctx context.Context, source, target string) (*provider.Rate, error) {
 	if m.GetRateFunc != nil {
 		return m.GetRateFunc(ctx, source, target)
 	}
 	key := source + ":" + target
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	rate, ok := m.rates[key]
-	if !ok {
+	if !ok || (!rate.ValidUntil.IsZero() && time.Now().After(rate.ValidUntil)) {
 		return nil, nil // Cache miss
 	}
 	return rate, nil
 }
 
+func (m *MockRepository) GetStaleRate(ctx context.Context, source, target string) (*provider.Rate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rates[source+":"+target], nil
+}
+
 func (m *MockRepository) SaveLockedRate(ctx context.Context, locked *model.LockedRate) error {
 	if m.SaveLockedFunc != nil {
 		return m.SaveLockedFunc(ctx, locked)
@@ -126,6 +193,16 @@ func (m *MockRepository) DeleteLockedRate(ctx context.Context, lockID string) er
 	return nil
 }
 
+func (m *MockRepository) CountActiveLocks(ctx context.Context, source, target string) (int, error) {
+	active := 0
+	for _, locked := range m.lockedRates {
+		if locked.Rate.SourceCurrency == source && locked.Rate.TargetCurrency == target && time.Now().Before(locked.ExpiresAt) {
+			active++
+		}
+	}
+	return active, nil
+}
+
 func (m *MockRepository) ExtendLockedRate(ctx context.Context, lockID string, newExpiry time.Time) error {
 	if m.ExtendLockedFunc != nil {
 		return m.ExtendLockedFunc(ctx, lockID, newExpiry)
@@ -137,6 +214,88 @@ func (m *MockRepository) ExtendLockedRate(ctx context.Context, lockID string, ne
 	return errors.New("not found")
 }
 
+func (m *MockRepository) SaveLockedRates(ctx context.Context, locked []*model.LockedRate) ([]string, error) {
+	var expired []string
+	for _, l := range locked {
+		if !l.ExpiresAt.After(time.Now()) {
+			expired = append(expired, l.LockID)
+			continue
+		}
+		if err := m.SaveLockedRate(ctx, l); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
+
+func (m *MockRepository) GetLockIdempotencyKey(ctx context.Context, key string) (*repository.LockIdempotencyRecord, error) {
+	if record, ok := m.lockKeys[key]; ok {
+		return &record, nil
+	}
+	return nil, nil
+}
+
+func (m *MockRepository) ClaimLockIdempotencyKey(ctx context.Context, key string, record repository.LockIdempotencyRecord, ttl time.Duration) (*repository.LockIdempotencyRecord, error) {
+	if existing, ok := m.lockKeys[key]; ok {
+		return &existing, nil
+	}
+	m.lockKeys[key] = record
+	return &record, nil
+}
+
+func (m *MockRepository) DeleteLockIdempotencyKey(ctx context.Context, key string) error {
+	delete(m.lockKeys, key)
+	return nil
+}
+
+func (m *MockRepository) SaveRateSnapshot(ctx context.Context, snapshot *model.RateSnapshot, ttl time.Duration) error {
+	if _, ok := m.snapshots[snapshot.LockID]; !ok {
+		m.snapshots[snapshot.LockID] = snapshot
+		m.snapshotExpiry[snapshot.LockID] = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (m *MockRepository) GetRateSnapshot(ctx context.Context, lockID string) (*model.RateSnapshot, error) {
+	return m.snapshots[lockID], nil
+}
+
+func (m *MockRepository) DeleteRateSnapshot(ctx context.Context, lockID string) error {
+	delete(m.snapshots, lockID)
+	delete(m.snapshotExpiry, lockID)
+	return nil
+}
+
+func (m *MockRepository) SaveQuote(ctx context.Context, quote *model.RateQuote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotes[quote.QuoteID] = quote
+	return nil
+}
+
+func (m *MockRepository) GetQuote(ctx context.Context, quoteID string) (*model.RateQuote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quotes[quoteID], nil
+}
+
+func (m *MockRepository) SaveCorridorOverride(ctx context.Context, override *model.CorridorOverride) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overrides[override.SourceCurrency+":"+override.TargetCurrency] = *override
+	return nil
+}
+
+func (m *MockRepository) GetCorridorOverrides(ctx context.Context) ([]model.CorridorOverride, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	overrides := make([]model.CorridorOverride, 0, len(m.overrides))
+	for _, override := range m.overrides {
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
 func (m *MockRepository) Health(ctx context.Context) error {
 	if m.HealthFunc != nil {
 		return m.HealthFunc(ctx)
@@ -144,6 +303,19 @@ func (m *MockRepository) Health(ctx context.Context) error {
 	return nil
 }
 
+// overrideCorridor modifies a corridor's config for the duration of the test
+func overrideCorridor(t *testing.T, from, to string, modify func(*model.Corridor)) {
+	t.Helper()
+	original := model.Corridors
+	model.Corridors = append([]model.Corridor(nil), original...)
+	t.Cleanup(func() { model.Corridors = original })
+	for i := range model.Corridors {
+		if model.Corridors[i].SourceCurrency == from && model.Corridors[i].TargetCurrency == to {
+			modify(&model.Corridors[i])
+		}
+	}
+}
+
 func newTestService() (*RateService, *MockProvider, *MockRepository) {
 	cfg := &config.Config{
 		RateCacheTTL: 30,
@@ -239,205 +411,2115 @@ func TestGetRate_CacheHit_ReturnsFromCache(t *testing.T) {
 	}
 }
 
-func TestGetRate_ProviderError_ReturnsError(t *testing.T) {
+func TestGetRate_ReportsCacheStateAndAge(t *testing.T) {
 	svc, mockProvider, _ := newTestService()
 
+	fetchedAt := time.Now().Add(-5 * time.Second)
 	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
-		return nil, errors.New("provider unavailable")
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        42.50,
+			Source:         "mock",
+			FetchedAt:      fetchedAt,
+		}, nil
 	}
 
 	ctx := context.Background()
-	_, err := svc.GetRate(ctx, "SGD", "PHP")
-
-	if err == nil {
-		t.Fatal("expected error when provider fails")
+	first, err := svc.GetRate(ctx, "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.FromCache {
+		t.Error("expected the first lookup to be fetched, not cached")
 	}
-}
-
-func TestLockRate_CreatesLock(t *testing.T) {
-	svc, _, mockRepo := newTestService()
-
-	ctx := context.Background()
-	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
 
+	second, err := svc.GetRate(ctx, "SGD", "PHP")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if !second.FromCache {
+		t.Error("expected the second lookup within the TTL to come from the cache")
+	}
 
-	if locked.LockID == "" {
-		t.Error("expected lock ID to be generated")
+	for _, rate := range []*model.ExchangeRate{first, second} {
+		if rate.Age < 5 || rate.Age > 10 {
+			t.Errorf("expected an age of about 5 seconds, got %f", rate.Age)
+		}
 	}
+}
 
-	if locked.Expired {
-		t.Error("expected lock to not be expired")
+func TestGetRate_ConcurrentMissesShareOneProviderCall(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+
+	var calls int32
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond) // hold the fetch open while the others arrive
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50, Source: "mock", FetchedAt: time.Now()}, nil
 	}
 
-	// Verify lock is stored in repository
-	stored, _ := mockRepo.GetLockedRate(ctx, locked.LockID)
-	if stored == nil {
-		t.Error("expected lock to be stored in repository")
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if rate.MidRate != 42.50 {
+				t.Errorf("expected mid rate 42.50, got %f", rate.MidRate)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected 1 provider call for 50 concurrent misses, got %d", n)
 	}
 }
 
-func TestLockRate_CapsMaxDuration(t *testing.T) {
-	svc, _, _ := newTestService()
+func TestGetRate_SharedFetchOutlivesCancelledCaller(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
 
-	ctx := context.Background()
-	locked, err := svc.LockRate(ctx, "SGD", "PHP", 300) // Request 5 minutes
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50, Source: "mock", FetchedAt: time.Now()}, nil
+	}
 
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	// The first caller starts the fetch and gives up on it
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := svc.GetRate(ctx, "SGD", "PHP")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
+		if err == nil && rate.MidRate != 42.50 {
+			err = fmt.Errorf("expected mid rate 42.50, got %f", rate.MidRate)
+		}
+		second <- err
+	}()
+
+	cancel()
+	select {
+	case err := <-first:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the cancelled caller to get its own error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the cancelled caller to stop waiting on the shared fetch")
 	}
 
-	// Should be capped at 120 seconds (2 minutes)
-	duration := locked.ExpiresAt.Sub(locked.LockedAt)
-	if duration > 121*time.Second {
-		t.Errorf("expected duration to be capped at 120s, got %v", duration)
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the other caller to get the rate, got %v", err)
 	}
 }
 
-func TestGetLockedRate_ReturnsLock(t *testing.T) {
-	svc, _, mockRepo := newTestService()
+func TestGetRate_ServesStaleRateAndRefreshesOnce(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	svc.config.StaleGracePeriod = 60
+
+	mockRepo.rates["SGD:PHP"] = &provider.Rate{
+		SourceCurrency: "SGD",
+		TargetCurrency: "PHP",
+		MidRate:        42.00,
+		Source:         "mock",
+		FetchedAt:      time.Now().Add(-35 * time.Second),
+		ValidUntil:     time.Now().Add(-5 * time.Second),
+	}
+
+	var calls int32
+	release := make(chan struct{})
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 43.00, Source: "mock", FetchedAt: time.Now()}, nil
+	}
 
-	// Create a lock first
 	ctx := context.Background()
-	created, _ := svc.LockRate(ctx, "SGD", "PHP", 60)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rate, err := svc.GetRate(ctx, "SGD", "PHP")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if rate.MidRate != 42.00 || !rate.FromCache {
+				t.Errorf("expected the stale cached rate 42.00, got %f (fromCache %v)", rate.MidRate, rate.FromCache)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mockRepo.mu.Lock()
+		refreshed := mockRepo.rates["SGD:PHP"].MidRate == 43.00
+		mockRepo.mu.Unlock()
+		if refreshed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale rate was never refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
 
-	// Retrieve it
-	retrieved, err := svc.GetLockedRate(ctx, created.LockID)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected a single background refresh, got %d provider calls", n)
+	}
 
+	rate, err := svc.GetRate(ctx, "SGD", "PHP")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-
-	if retrieved.LockID != created.LockID {
-		t.Errorf("expected lock ID %s, got %s", created.LockID, retrieved.LockID)
+	if rate.MidRate != 43.00 {
+		t.Errorf("expected the refreshed rate 43.00, got %f", rate.MidRate)
 	}
-
-	// Also verify via mock
-	_ = mockRepo
 }
 
-func TestGetLockedRate_ExpiredLock_ReturnsExpired(t *testing.T) {
-	svc, _, mockRepo := newTestService()
+func TestStaleRate_ServedForDisplayButNotQuotesOrLocks(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	svc.config.StaleGracePeriod = 60
 
-	// Don't create a lock - simulate not found
-	ctx := context.Background()
-	retrieved, err := svc.GetLockedRate(ctx, "nonexistent-lock-id")
+	mockRepo.rates["SGD:PHP"] = &provider.Rate{
+		SourceCurrency: "SGD",
+		TargetCurrency: "PHP",
+		MidRate:        42.00,
+		Source:         "mock",
+		FetchedAt:      time.Now().Add(-35 * time.Second),
+		ValidUntil:     time.Now().Add(-5 * time.Second),
+	}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return nil, errors.New("provider unavailable")
+	}
 
+	ctx := context.Background()
+	rate, err := svc.GetRate(ctx, "SGD", "PHP")
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatalf("expected the stale rate for display, got error: %v", err)
 	}
-
-	if !retrieved.Expired {
-		t.Error("expected non-existent lock to be marked as expired")
+	if rate.MidRate != 42.00 {
+		t.Errorf("expected the stale rate 42.00, got %f", rate.MidRate)
 	}
 
-	_ = mockRepo
+	if _, err := svc.GetQuote(ctx, "SGD", "PHP", 1000); err == nil {
+		t.Error("expected the quote to fail with the provider error, not price from the stale rate")
+	}
+	if _, err := svc.LockRate(ctx, "SGD", "PHP", 60); err == nil {
+		t.Error("expected the lock to fail with the provider error, not lock the stale rate")
+	}
 }
 
-func TestDeleteLockedRate_RemovesLock(t *testing.T) {
-	svc, _, mockRepo := newTestService()
-
-	ctx := context.Background()
-
-	// Create a lock
-	created, _ := svc.LockRate(ctx, "SGD", "PHP", 60)
+func TestGetRate_StaleRatePastGracePeriodIsFetched(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	svc.config.StaleGracePeriod = 60
 
-	// Verify it exists
-	_, exists := mockRepo.lockedRates[created.LockID]
-	if !exists {
-		t.Fatal("expected lock to exist before deletion")
+	mockRepo.rates["SGD:PHP"] = &provider.Rate{
+		SourceCurrency: "SGD",
+		TargetCurrency: "PHP",
+		MidRate:        42.00,
+		ValidUntil:     time.Now().Add(-2 * time.Minute),
+	}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 43.00, FetchedAt: time.Now()}, nil
 	}
 
-	// Delete it
-	err := svc.DeleteLockedRate(ctx, created.LockID)
+	rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-
-	// Verify it's gone
-	_, exists = mockRepo.lockedRates[created.LockID]
-	if exists {
-		t.Error("expected lock to be deleted")
+	if rate.MidRate != 43.00 || rate.FromCache {
+		t.Errorf("expected a freshly fetched 43.00, got %f (fromCache %v)", rate.MidRate, rate.FromCache)
 	}
 }
 
-func TestGetCorridors_All(t *testing.T) {
-	svc, _, _ := newTestService()
+func TestGetRate_ProviderError_ReturnsError(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
 
-	corridors := svc.GetCorridors("")
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return nil, errors.New("provider unavailable")
+	}
 
-	if len(corridors) == 0 {
-		t.Error("expected at least some corridors")
+	ctx := context.Background()
+	_, err := svc.GetRate(ctx, "SGD", "PHP")
+
+	if err == nil {
+		t.Fatal("expected error when provider fails")
 	}
 }
 
-func TestGetCorridors_FilteredBySource(t *testing.T) {
-	svc, _, _ := newTestService()
+func TestGetRate_BuyRateIsBidLessMargin(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
 
-	corridors := svc.GetCorridors("SGD")
+	// A 10% spread around the mid rate
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        40,
+			BidRate:        38,
+			AskRate:        42,
+			Spread:         10,
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
 
-	if len(corridors) == 0 {
-		t.Error("expected SGD corridors")
+	ctx := context.Background()
+	rate, err := svc.GetRate(ctx, "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The customer receives PHP, so a wider spread must price worse than the mid
+	if want := fmt.Sprintf("%.6f", 38*(1-0.003)); rate.BuyRate != want {
+		t.Errorf("expected buy rate %s from the bid less margin, not %.6f from the mid, got %s", want, 40*(1-0.003), rate.BuyRate)
 	}
 
-	for _, c := range corridors {
-		if c.SourceCurrency != "SGD" {
-			t.Errorf("expected all corridors to have SGD source, got %s", c.SourceCurrency)
-		}
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := quote.ExchangeRate.InexactFloat64(); math.Abs(got-38*(1-0.003)) > 1e-6 {
+		t.Errorf("expected quoted rate %f from the bid less margin, got %f", 38*(1-0.003), got)
+	}
+	if got := quote.ExchangeRate.InexactFloat64(); got >= 40 {
+		t.Errorf("expected the customer to be priced below the mid 40, got %f", got)
+	}
+	if got := quote.MidMarketRate.InexactFloat64(); got != 40 {
+		t.Errorf("expected the mid-market rate to stay the mid 40, got %f", got)
 	}
 }
 
-func TestGetQuote_CalculatesFees(t *testing.T) {
-	svc, _, _ := newTestService()
-
-	ctx := context.Background()
-	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 100.0)
+func TestGetRate_BuyRateAppliesMarginInDecimal(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 1.34, BidRate: 1.3375, Source: "mock"}, nil
+	}
 
+	rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-
-	if quote.SourceAmount != 100.0 {
-		t.Errorf("expected source amount 100.0, got %f", quote.SourceAmount)
+	// 1.3375 * 0.997 is exactly 1.3334875, which float math rounds down to 1.333487
+	if rate.BuyRate != "1.333488" {
+		t.Errorf("expected buy rate 1.333488, got %s", rate.BuyRate)
 	}
-
-	// Fee should be at least the minimum (SGD 3.00 for SGD/PHP)
-	if quote.Fee < 3.0 {
-		t.Errorf("expected fee >= 3.0, got %f", quote.Fee)
+	if rate.MarginPercentage != "0.30" {
+		t.Errorf("expected margin 0.30, got %s", rate.MarginPercentage)
 	}
+}
 
-	// Total cost should be source + fee
-	expectedTotal := quote.SourceAmount + quote.Fee
-	if quote.TotalCost != expectedTotal {
-		t.Errorf("expected total cost %f, got %f", expectedTotal, quote.TotalCost)
+func TestGetRate_BuyRateFallsBackToMidWithoutBid(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 40, Source: "mock"}, nil
 	}
 
-	// Target amount should be positive
-	if quote.TargetAmount <= 0 {
-		t.Errorf("expected positive target amount, got %f", quote.TargetAmount)
+	rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := fmt.Sprintf("%.6f", 40*(1-0.003)); rate.BuyRate != want {
+		t.Errorf("expected buy rate %s from the mid less margin, got %s", want, rate.BuyRate)
 	}
 }
 
-func TestHealth_ChecksRepository(t *testing.T) {
+func TestLockRate_CreatesLock(t *testing.T) {
 	svc, _, mockRepo := newTestService()
 
 	ctx := context.Background()
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
 
-	// Healthy case
-	err := svc.Health(ctx)
 	if err != nil {
-		t.Errorf("expected healthy service, got error: %v", err)
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// Unhealthy case
-	mockRepo.HealthFunc = func(ctx context.Context) error {
-		return errors.New("redis connection failed")
-	}
+	if locked.LockID == "" {
+		t.Error("expected lock ID to be generated")
+	}
+
+	if locked.Expired {
+		t.Error("expected lock to not be expired")
+	}
+
+	// Verify lock is stored in repository
+	stored, _ := mockRepo.GetLockedRate(ctx, locked.LockID)
+	if stored == nil {
+		t.Error("expected lock to be stored in repository")
+	}
+}
+
+func TestLockRate_CapsMaxDuration(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.ExpiryGranularityMs = 1000
+	svc.config.MaxLockDuration = 90
+
+	ctx := context.Background()
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 300) // Request 5 minutes
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Should be capped at MaxLockDuration, plus the expiry rounding
+	duration := locked.ExpiresAt.Sub(locked.LockedAt)
+	if duration < 90*time.Second || duration >= 91*time.Second {
+		t.Errorf("expected duration to be capped at 90s, got %v", duration)
+	}
+}
+
+func TestLockRate_LimitsActiveLocksPerPair(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.MaxActiveLocksPerPair = 2
+	ctx := context.Background()
+
+	first, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.LockRate(ctx, "SGD", "PHP", 60); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = svc.LockRate(ctx, "SGD", "PHP", 60)
+	if limitErr, ok := err.(ErrLockLimitExceeded); !ok || limitErr.Limit != 2 {
+		t.Fatalf("expected ErrLockLimitExceeded with limit 2, got %v", err)
+	}
+	if _, err := svc.LockRate(ctx, "USD", "PHP", 60); err != nil {
+		t.Errorf("expected other pairs to be unaffected, got %v", err)
+	}
+
+	// Releasing a lock makes room for another
+	if err := svc.DeleteLockedRate(ctx, first.LockID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.LockRate(ctx, "SGD", "PHP", 60); err != nil {
+		t.Errorf("expected a lock after releasing one, got %v", err)
+	}
+}
+
+func TestLockRate_RoundsExpiryToGranularity(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.ExpiryGranularityMs = 1000
+
+	before := time.Now()
+	locked, err := svc.LockRate(context.Background(), "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after := time.Now()
+
+	if locked.ExpiresAt.Nanosecond() != 0 {
+		t.Errorf("expected expiry on a whole second, got %v", locked.ExpiresAt)
+	}
+	// The lock time is when the lock was taken, not derived from the expiry
+	if locked.LockedAt.Before(before) || locked.LockedAt.After(after) {
+		t.Errorf("expected the lock time between %v and %v, got %v", before, after, locked.LockedAt)
+	}
+	if held := locked.ExpiresAt.Sub(locked.LockedAt); held < 30*time.Second || held >= 31*time.Second {
+		t.Errorf("expected 30s between lock and expiry, less than a second of rounding, got %v", held)
+	}
+	// Rounding must never shorten the lock below what was requested
+	if locked.ExpiresAt.Before(before.Add(30 * time.Second)) {
+		t.Errorf("expected expiry at least 30s after the request, got %v", locked.ExpiresAt.Sub(before))
+	}
+	if locked.ExpiresAt.After(time.Now().Add(31 * time.Second)) {
+		t.Errorf("expected expiry rounded by less than a second, got %v", locked.ExpiresAt.Sub(before))
+	}
+}
+
+func TestLockRateFromQuote_LocksQuotedRate(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	ctx := context.Background()
+
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The market moves between quoting and locking
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 50, Source: "mock"}, nil
+	}
+	mockRepo.rates = make(map[string]*provider.Rate)
+
+	locked, err := svc.LockRateFromQuote(ctx, quote.QuoteID, 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buyRate, _ := strconv.ParseFloat(locked.Rate.BuyRate, 64)
+	if locked.Rate.MidRate != quote.MidMarketRate.InexactFloat64() || math.Abs(buyRate-quote.ExchangeRate.InexactFloat64()) > 1e-6 {
+		t.Errorf("expected the quoted rates %s/%s, got %f/%s",
+			quote.MidMarketRate, quote.ExchangeRate, locked.Rate.MidRate, locked.Rate.BuyRate)
+	}
+	if stored, _ := mockRepo.GetLockedRate(ctx, locked.LockID); stored == nil {
+		t.Error("expected lock to be stored in repository")
+	}
+
+	fromLock, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(fromLock.TargetAmount.InexactFloat64()-quote.TargetAmount.InexactFloat64()) > 0.01 {
+		t.Errorf("expected the locked rate to convert to the quoted %f, got %f", quote.TargetAmount.InexactFloat64(), fromLock.TargetAmount.InexactFloat64())
+	}
+}
+
+func TestLockRateFromQuote_KeepsQuotedMargin(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.SnapshotLockTerms = true
+	ctx := context.Background()
+
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The corridor's margin changes between quoting and locking
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) { c.MarginPercentage = "2.0" })
+
+	locked, err := svc.LockRateFromQuote(ctx, quote.QuoteID, 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(locked.Rate.BidRate-42.29) > 1e-6 {
+		t.Errorf("expected the mock's bid 42.29 recovered with the quoted margin, got %f", locked.Rate.BidRate)
+	}
+	if locked.Rate.MarginPercentage != "0.30" {
+		t.Errorf("expected the quoted margin 0.30 on the lock, got %s", locked.Rate.MarginPercentage)
+	}
+}
+
+func TestLockRateFromQuote_KeepsQuotedTerms(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	// A margin with more places than the quote shows
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) { c.MarginPercentage = "0.125" })
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The corridor's fees change between quoting and locking
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.FeePercentage = "10.0"
+		c.FeeMinimum = model.Money{Amount: "50.00", Currency: "SGD"}
+	})
+
+	locked, err := svc.LockRateFromQuote(ctx, quote.QuoteID, 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if locked.QuoteID != quote.QuoteID || locked.FeePercentage != quote.FeePercentage || locked.MarginPercentage != quote.MarginPercentage {
+		t.Errorf("expected the quote's terms on the lock, got margin %q fee %q", locked.MarginPercentage, locked.FeePercentage)
+	}
+	if locked.Rate.BuyRate != quote.ExchangeRate.StringFixed(model.RatePlaces) {
+		t.Errorf("expected the quoted rate %s, got %s", quote.ExchangeRate, locked.Rate.BuyRate)
+	}
+
+	fromLock, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fromLock.ExchangeRate.Equal(quote.ExchangeRate.Decimal) || !fromLock.Fee.Equal(quote.Fee.Decimal) {
+		t.Errorf("expected the quoted rate %s and fee %s, got %s and %s",
+			quote.ExchangeRate, quote.Fee, fromLock.ExchangeRate, fromLock.Fee)
+	}
+}
+
+func TestLockRateFromQuote_ExpiredOrUnknownQuote(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	ctx := context.Background()
+
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mockRepo.quotes[quote.QuoteID].ValidUntil = time.Now().Add(-time.Second)
+
+	if _, err := svc.LockRateFromQuote(ctx, quote.QuoteID, 60); err == nil {
+		t.Error("expected error for an expired quote")
+	} else if _, ok := err.(ErrQuoteExpired); !ok {
+		t.Errorf("expected ErrQuoteExpired, got %v", err)
+	}
+	if _, err := svc.LockRateFromQuote(ctx, "unknown", 60); err == nil {
+		t.Error("expected error for an unknown quote")
+	} else if _, ok := err.(ErrQuoteNotFound); !ok {
+		t.Errorf("expected ErrQuoteNotFound, got %v", err)
+	}
+	if len(mockRepo.lockedRates) != 0 {
+		t.Errorf("expected no locks, got %d", len(mockRepo.lockedRates))
+	}
+}
+
+func TestGetQuote_RoundsValidUntilDown(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.ExpiryGranularityMs = 1000
+
+	rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	quote, err := svc.GetQuote(context.Background(), "SGD", "PHP", 100.0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.ValidUntil.Nanosecond() != 0 {
+		t.Errorf("expected validity on a whole second, got %v", quote.ValidUntil)
+	}
+	if quote.ValidUntil.After(rate.ExpiresAt) {
+		t.Errorf("expected quote validity %v not to outlive the rate %v", quote.ValidUntil, rate.ExpiresAt)
+	}
+}
+
+func TestGetLockedRate_ReturnsLock(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+
+	// Create a lock first
+	ctx := context.Background()
+	created, _ := svc.LockRate(ctx, "SGD", "PHP", 60)
+
+	// Retrieve it
+	retrieved, err := svc.GetLockedRate(ctx, created.LockID)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if retrieved.LockID != created.LockID {
+		t.Errorf("expected lock ID %s, got %s", created.LockID, retrieved.LockID)
+	}
+
+	// Also verify via mock
+	_ = mockRepo
+}
+
+func TestGetLockedRate_ExpiredLock_ReturnsExpired(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+
+	// Don't create a lock - simulate not found
+	ctx := context.Background()
+	retrieved, err := svc.GetLockedRate(ctx, "nonexistent-lock-id")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !retrieved.Expired {
+		t.Error("expected non-existent lock to be marked as expired")
+	}
+
+	_ = mockRepo
+}
+
+func TestDeleteLockedRate_RemovesLock(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+
+	ctx := context.Background()
+
+	// Create a lock
+	created, _ := svc.LockRate(ctx, "SGD", "PHP", 60)
+
+	// Verify it exists
+	_, exists := mockRepo.lockedRates[created.LockID]
+	if !exists {
+		t.Fatal("expected lock to exist before deletion")
+	}
+
+	// Delete it
+	err := svc.DeleteLockedRate(ctx, created.LockID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify it's gone
+	_, exists = mockRepo.lockedRates[created.LockID]
+	if exists {
+		t.Error("expected lock to be deleted")
+	}
+}
+
+func TestGetCorridors_All(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	corridors := svc.GetCorridors(CorridorFilter{})
+
+	if len(corridors) == 0 {
+		t.Error("expected at least some corridors")
+	}
+}
+
+func TestGetCorridors_FilteredBySource(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	corridors := svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD"})
+
+	if len(corridors) == 0 {
+		t.Error("expected SGD corridors")
+	}
+
+	for _, c := range corridors {
+		if c.SourceCurrency != "SGD" {
+			t.Errorf("expected all corridors to have SGD source, got %s", c.SourceCurrency)
+		}
+	}
+}
+
+func TestGetCorridors_Filters(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	tests := []struct {
+		name   string
+		filter CorridorFilter
+		want   []string
+	}{
+		{"target", CorridorFilter{TargetCurrency: "PHP"}, []string{"SGD/PHP", "USD/PHP"}},
+		{"method", CorridorFilter{PayoutMethod: "CASH_PICKUP"}, []string{"SGD/PHP", "USD/PHP"}},
+		{"source and target", CorridorFilter{SourceCurrency: "SGD", TargetCurrency: "PHP"}, []string{"SGD/PHP"}},
+		{"source and method", CorridorFilter{SourceCurrency: "SGD", PayoutMethod: "MOBILE_WALLET"}, []string{"SGD/PHP", "SGD/INR", "SGD/IDR"}},
+		{"target and method", CorridorFilter{TargetCurrency: "PHP", PayoutMethod: "CASH_PICKUP"}, []string{"SGD/PHP", "USD/PHP"}},
+		{"all three", CorridorFilter{SourceCurrency: "USD", TargetCurrency: "PHP", PayoutMethod: "CASH_PICKUP"}, []string{"USD/PHP"}},
+		{"method not offered", CorridorFilter{TargetCurrency: "INR", PayoutMethod: "CASH_PICKUP"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range svc.GetCorridors(tt.filter) {
+				got = append(got, c.SourceCurrency+"/"+c.TargetCurrency)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected corridors %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetCorridors_ExcludesDisabledByDefault(t *testing.T) {
+	svc, _, _ := newTestService()
+	overrideCorridor(t, "SGD", "IDR", func(c *model.Corridor) {
+		c.Enabled = false
+	})
+
+	listed := func(corridors []model.Corridor) bool {
+		for _, c := range corridors {
+			if c.SourceCurrency == "SGD" && c.TargetCurrency == "IDR" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if listed(svc.GetCorridors(CorridorFilter{})) || listed(svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD"})) {
+		t.Error("expected the disabled corridor to be left out by default")
+	}
+	if !listed(svc.GetCorridors(CorridorFilter{IncludeDisabled: true})) || !listed(svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD", IncludeDisabled: true})) {
+		t.Error("expected the disabled corridor when disabled corridors are requested")
+	}
+	if len(svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD"})) != len(svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD", IncludeDisabled: true}))-1 {
+		t.Error("expected only the disabled corridor to be left out")
+	}
+
+	_, err := svc.GetQuote(context.Background(), "SGD", "IDR", 100)
+	if _, ok := err.(ErrCorridorDisabled); !ok {
+		t.Fatalf("expected ErrCorridorDisabled, got %T: %v", err, err)
+	}
+}
+func TestGetQuote_CalculatesFees(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	ctx := context.Background()
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 100.0)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.SourceAmount.InexactFloat64() != 100.0 {
+		t.Errorf("expected source amount 100.0, got %f", quote.SourceAmount.InexactFloat64())
+	}
+
+	// Fee should be at least the minimum (SGD 3.00 for SGD/PHP)
+	if quote.Fee.InexactFloat64() < 3.0 {
+		t.Errorf("expected fee >= 3.0, got %f", quote.Fee.InexactFloat64())
+	}
+
+	// Total cost should be source + fee
+	if expectedTotal := quote.SourceAmount.Add(quote.Fee.Decimal); !quote.TotalCost.Equal(expectedTotal) {
+		t.Errorf("expected total cost %s, got %s", expectedTotal, quote.TotalCost)
+	}
+
+	// Target amount should be positive
+	if quote.TargetAmount.InexactFloat64() <= 0 {
+		t.Errorf("expected positive target amount, got %f", quote.TargetAmount.InexactFloat64())
+	}
+}
+
+func TestGetQuote_RoundsAmountsHalfUp(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.FeeRounding = "nearest"
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.FeePercentage = "0.5"
+		c.FeeMinimum.Amount = "0"
+	})
+
+	// As a float64, 100.005 falls just below the half-cent, so only decimal
+	// math rounds it up
+	tests := []struct {
+		amount    float64
+		source    string
+		fee       string
+		totalCost string
+	}{
+		{100.005, "100.01", "0.50", "100.51"},
+		{101, "101.00", "0.51", "101.51"},
+	}
+	for _, tt := range tests {
+		quote, err := svc.GetQuote(context.Background(), "SGD", "PHP", tt.amount)
+		if err != nil {
+			t.Fatalf("amount %g: unexpected error: %v", tt.amount, err)
+		}
+		got := [3]string{quote.SourceAmount.StringFixed(2), quote.Fee.StringFixed(2), quote.TotalCost.StringFixed(2)}
+		if got != [3]string{tt.source, tt.fee, tt.totalCost} {
+			t.Errorf("amount %g: expected source/fee/total %s/%s/%s, got %s/%s/%s",
+				tt.amount, tt.source, tt.fee, tt.totalCost, got[0], got[1], got[2])
+		}
+
+		body, err := json.Marshal(quote)
+		if err != nil {
+			t.Fatalf("marshal quote: %v", err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatalf("unmarshal quote: %v", err)
+		}
+		if fields["sourceAmount"] != tt.source || fields["totalCost"] != tt.totalCost {
+			t.Errorf("amount %g: expected JSON amounts %q and %q, got %v and %v",
+				tt.amount, tt.source, tt.totalCost, fields["sourceAmount"], fields["totalCost"])
+		}
+		if rate, _ := fields["exchangeRate"].(string); len(rate) < 7 || rate[len(rate)-7] != '.' {
+			t.Errorf("amount %g: expected a 6 decimal exchange rate string, got %v", tt.amount, fields["exchangeRate"])
+		}
+	}
+}
+
+func TestGetQuote_FeeRounding(t *testing.T) {
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.FeePercentage = "0.5"
+		c.FeeMinimum.Amount = "0"
+	})
+
+	// At 0.5%, 99.50 SGD carries a 0.4975 fee and 98.10 SGD a 0.4905 one
+	tests := []struct {
+		policy string
+		amount float64
+		fee    string
+	}{
+		{"", 99.50, "0.50"},
+		{"up", 98.10, "0.50"},
+		{"nearest", 99.50, "0.50"},
+		{"nearest", 98.10, "0.49"},
+		{"down", 99.50, "0.49"},
+	}
+	for _, tt := range tests {
+		svc, _, _ := newTestService()
+		svc.config.FeeRounding = tt.policy
+
+		quote, err := svc.GetQuote(context.Background(), "SGD", "PHP", tt.amount)
+		if err != nil {
+			t.Fatalf("%q %g: unexpected error: %v", tt.policy, tt.amount, err)
+		}
+		if fee := quote.Fee.String(); fee != tt.fee {
+			t.Errorf("%q %g: expected fee %s, got %s", tt.policy, tt.amount, tt.fee, fee)
+		}
+		if !quote.TotalCost.Equal(quote.SourceAmount.Add(quote.Fee.Decimal)) {
+			t.Errorf("%q %g: expected total cost %s + %s, got %s", tt.policy, tt.amount, quote.SourceAmount, quote.Fee, quote.TotalCost)
+		}
+	}
+}
+
+func TestGetQuote_RoundsToCurrencyPrecision(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	tests := []struct {
+		target string
+		places int32
+	}{
+		{"IDR", 0}, // whole-number currency
+		{"PHP", 2},
+	}
+	for _, tt := range tests {
+		quote, err := svc.GetQuote(ctx, "SGD", tt.target, 1234.56)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.target, err)
+		}
+
+		if quote.TargetPrecision != tt.places || quote.SourcePrecision != 2 {
+			t.Errorf("%s: expected source/target precision 2/%d, got %d/%d",
+				tt.target, tt.places, quote.SourcePrecision, quote.TargetPrecision)
+		}
+		if places := quote.TargetAmount.Places(); places != tt.places {
+			t.Errorf("%s: expected the target amount rounded to %d places, got %s", tt.target, tt.places, quote.TargetAmount)
+		}
+		if places := quote.Fee.Places(); places != 2 {
+			t.Errorf("%s: expected the SGD fee rounded to 2 places, got %s", tt.target, quote.Fee)
+		}
+
+		body, err := json.Marshal(quote)
+		if err != nil {
+			t.Fatalf("marshal quote: %v", err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatalf("unmarshal quote: %v", err)
+		}
+		want := quote.TargetAmount.StringFixed(tt.places)
+		if fields["targetAmount"] != want {
+			t.Errorf("%s: expected JSON target amount %q, got %v", tt.target, want, fields["targetAmount"])
+		}
+
+		// Quotes read back from storage keep their precision
+		var stored model.RateQuote
+		if err := json.Unmarshal(body, &stored); err != nil {
+			t.Fatalf("unmarshal quote: %v", err)
+		}
+		if stored.TargetAmount.String() != want {
+			t.Errorf("%s: expected stored target amount %q, got %s", tt.target, want, stored.TargetAmount)
+		}
+	}
+}
+
+func TestGetQuoteByID(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	ctx := context.Background()
+
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 100.0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, err := svc.GetQuoteByID(ctx, quote.QuoteID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Fee != quote.Fee || stored.ExchangeRate != quote.ExchangeRate {
+		t.Errorf("expected the stored quote to match the issued one, got %+v", stored)
+	}
+
+	if _, err := svc.GetQuoteByID(ctx, "unknown"); err == nil {
+		t.Error("expected error for an unknown quote")
+	} else if _, ok := err.(ErrQuoteNotFound); !ok {
+		t.Errorf("expected ErrQuoteNotFound, got %v", err)
+	}
+
+	mockRepo.quotes[quote.QuoteID].ValidUntil = time.Now().Add(-time.Second)
+	if _, err := svc.GetQuoteByID(ctx, quote.QuoteID); err == nil {
+		t.Error("expected error for a quote past its validity")
+	} else if _, ok := err.(ErrQuoteExpired); !ok {
+		t.Errorf("expected ErrQuoteExpired, got %v", err)
+	}
+}
+
+func TestGetQuote_EffectiveRateIncludesFees(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	quote, err := svc.GetQuote(context.Background(), "SGD", "PHP", 1000.0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.Fee.InexactFloat64() <= 0 {
+		t.Fatalf("expected the SGD/PHP corridor to charge a fee, got %f", quote.Fee.InexactFloat64())
+	}
+	if want := quote.TargetAmount.InexactFloat64() / quote.TotalCost.InexactFloat64(); math.Abs(quote.EffectiveRate.InexactFloat64()-want) > 1e-6 {
+		t.Errorf("expected effective rate %f, got %f", want, quote.EffectiveRate.InexactFloat64())
+	}
+	if quote.EffectiveRate.InexactFloat64() >= quote.ExchangeRate.InexactFloat64() {
+		t.Errorf("expected effective rate %f below the buy rate %f", quote.EffectiveRate.InexactFloat64(), quote.ExchangeRate.InexactFloat64())
+	}
+}
+
+func TestGetQuote_EffectiveRateWithoutFeesIsBuyRate(t *testing.T) {
+	svc, _, _ := newTestService()
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.FeePercentage = "0"
+		c.FeeMinimum.Amount = "0"
+	})
+
+	quote, err := svc.GetQuote(context.Background(), "SGD", "PHP", 1000.0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(quote.EffectiveRate.InexactFloat64()-quote.ExchangeRate.InexactFloat64()) > 1e-9 {
+		t.Errorf("expected effective rate to equal the buy rate %f without fees, got %f", quote.ExchangeRate.InexactFloat64(), quote.EffectiveRate.InexactFloat64())
+	}
+}
+
+func TestGetQuote_BelowMinTargetAmount_ReturnsError(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	// SGD 1.00 converts to roughly PHP 42, below the PHP 50.00 floor
+	_, err := svc.GetQuote(context.Background(), "SGD", "PHP", 1.0)
+	if err == nil {
+		t.Fatal("expected error for amount below minimum target amount")
+	}
+
+	minErr, ok := err.(ErrBelowMinTargetAmount)
+	if !ok {
+		t.Fatalf("expected ErrBelowMinTargetAmount, got %T: %v", err, err)
+	}
+	if minErr.MinimumAmount != "50.00" || minErr.TargetCurrency != "PHP" {
+		t.Errorf("unexpected minimum in error: %+v", minErr)
+	}
+}
+
+func TestGetQuote_AboveMinTargetAmount_Succeeds(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	// SGD 2.00 converts to roughly PHP 84, above the PHP 50.00 floor
+	if _, err := svc.GetQuote(context.Background(), "SGD", "PHP", 2.0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGetQuote_SourceAmountRange(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	// SGD/PHP quotes from 1.00 to 50000.00 SGD
+	for _, amount := range []float64{0.01, 10000000} {
+		_, err := svc.GetQuote(context.Background(), "SGD", "PHP", amount)
+		rangeErr, ok := err.(ErrAmountOutOfRange)
+		if !ok {
+			t.Fatalf("amount %.2f: expected ErrAmountOutOfRange, got %T: %v", amount, err, err)
+		}
+		if rangeErr.MinimumAmount != "1.00" || rangeErr.MaximumAmount != "50000.00" || rangeErr.SourceCurrency != "SGD" {
+			t.Errorf("amount %.2f: unexpected limits in error: %+v", amount, rangeErr)
+		}
+	}
+
+	for _, amount := range []float64{2, 1000, 50000} {
+		if _, err := svc.GetQuote(context.Background(), "SGD", "PHP", amount); err != nil {
+			t.Errorf("amount %.2f: unexpected error: %v", amount, err)
+		}
+	}
+}
+
+func TestGetQuoteForTarget_SolvesSourceAmount(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	quote, err := svc.GetQuoteForTarget(context.Background(), "SGD", "PHP", 5000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.TargetAmount.StringFixed(2) != "5000.00" {
+		t.Errorf("expected target amount exactly 5000.00, got %s", quote.TargetAmount)
+	}
+	if expectedTotal := quote.SourceAmount.Add(quote.Fee.Decimal); !quote.TotalCost.Equal(expectedTotal) {
+		t.Errorf("expected total cost %s, got %s", expectedTotal, quote.TotalCost)
+	}
+
+	// The source amount is rounded up, so it always covers the target amount
+	buyRate := quote.ExchangeRate.InexactFloat64()
+	if quote.SourceAmount.InexactFloat64()*buyRate < 5000-buyRate*1e-6 {
+		t.Errorf("expected source amount %s to cover PHP 5000 at %s", quote.SourceAmount, quote.ExchangeRate)
+	}
+}
+
+func TestGetQuoteForTarget_HighFeeCorridorReturnsError(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.MaxReverseFeePercentage = 50
+
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.FeePercentage = "99.99"
+	})
+
+	quote, err := svc.GetQuoteForTarget(context.Background(), "SGD", "PHP", 5000)
+	if _, ok := err.(ErrReverseQuoteUnstable); !ok {
+		t.Fatalf("expected ErrReverseQuoteUnstable, got %v (quote %+v)", err, quote)
+	}
+}
+
+func TestGetQuoteForTarget_NearTotalMarginReturnsError(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 1e-12, Source: "mock"}, nil
+	}
+
+	_, err := svc.GetQuoteForTarget(context.Background(), "SGD", "PHP", 5000)
+	if _, ok := err.(ErrReverseQuoteUnstable); !ok {
+		t.Fatalf("expected ErrReverseQuoteUnstable for a zero buy rate, got %v", err)
+	}
+}
+
+func TestGetQuoteForTarget_CapsSourceAmount(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.MaxQuoteAmount = 10000
+
+	// PHP 1,000,000 needs roughly SGD 23,500
+	_, err := svc.GetQuoteForTarget(context.Background(), "SGD", "PHP", 1000000)
+	capErr, ok := err.(ErrQuoteAmountTooLarge)
+	if !ok {
+		t.Fatalf("expected ErrQuoteAmountTooLarge, got %v", err)
+	}
+	if capErr.MaximumAmount != "10000.00" || capErr.SourceCurrency != "SGD" {
+		t.Errorf("unexpected cap in error: %+v", capErr)
+	}
+}
+
+func TestGetRate_CacheTTLUsesCorridorOverride(t *testing.T) {
+	tests := []struct {
+		target string
+		want   time.Duration
+	}{
+		{"USD", 120 * time.Second}, // corridor override
+		{"IDR", 15 * time.Second},  // corridor override
+		{"PHP", 30 * time.Second},  // global RateCacheTTL
+	}
+
+	for _, tt := range tests {
+		t.Run("SGD/"+tt.target, func(t *testing.T) {
+			svc, _, mockRepo := newTestService()
+
+			var savedTTL time.Duration
+			var savedRate *provider.Rate
+			mockRepo.SaveRateFunc = func(ctx context.Context, rate *provider.Rate, ttl time.Duration) error {
+				savedTTL = ttl
+				savedRate = rate
+				return nil
+			}
+
+			rate, err := svc.GetRate(context.Background(), "SGD", tt.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if savedTTL != tt.want {
+				t.Errorf("expected cache TTL %v, got %v", tt.want, savedTTL)
+			}
+
+			// The cached rate's validity must expire with the Redis key
+			validFor := time.Until(savedRate.ValidUntil)
+			if validFor > tt.want || validFor < tt.want-time.Second {
+				t.Errorf("expected ValidUntil about %v from now, got %v", tt.want, validFor)
+			}
+			if !rate.ExpiresAt.Equal(savedRate.ValidUntil) {
+				t.Errorf("expected ExpiresAt %v to match cached ValidUntil %v", rate.ExpiresAt, savedRate.ValidUntil)
+			}
+		})
+	}
+}
+
+func TestHealth_ChecksRepository(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+
+	ctx := context.Background()
+
+	// Healthy case
+	err := svc.Health(ctx)
+	if err != nil {
+		t.Errorf("expected healthy service, got error: %v", err)
+	}
+
+	// Unhealthy case
+	mockRepo.HealthFunc = func(ctx context.Context) error {
+		return errors.New("redis connection failed")
+	}
+
+	err = svc.Health(ctx)
+	if err == nil {
+		t.Error("expected error when repository is unhealthy")
+	}
+}
+
+func TestHealth_ChecksProvider(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.HealthFunc = func(ctx context.Context) error {
+		return provider.ErrProviderUnavailable{Provider: "mock", Reason: "connection refused"}
+	}
+
+	var unavailable provider.ErrProviderUnavailable
+	if err := svc.Health(context.Background()); !errors.As(err, &unavailable) {
+		t.Errorf("expected the provider's error when it is unhealthy, got %v", err)
+	}
+}
+
+func TestCompareProviders_ReportsEachProvider(t *testing.T) {
+	primary := &MockProvider{
+		ProviderName: "primary",
+		GetRateFunc: func(ctx context.Context, source, target string) (*provider.Rate, error) {
+			return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50, BidRate: 42.40, AskRate: 42.60}, nil
+		},
+	}
+	secondary := &MockProvider{
+		ProviderName: "secondary",
+		GetRateFunc: func(ctx context.Context, source, target string) (*provider.Rate, error) {
+			return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.80, BidRate: 42.70, AskRate: 42.90}, nil
+		},
+	}
+
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 60}
+	chain := provider.NewChainProvider(primary, secondary)
+	svc := NewRateService(cfg, chain, NewMockRepository(), zap.NewNop())
+
+	comparison := svc.CompareProviders(context.Background(), "SGD", "PHP")
+
+	if len(comparison.Providers) != 2 {
+		t.Fatalf("expected 2 provider entries, got %d", len(comparison.Providers))
+	}
+
+	if comparison.Providers[0].Provider != "primary" || comparison.Providers[0].MidRate != 42.50 {
+		t.Errorf("unexpected primary entry: %+v", comparison.Providers[0])
+	}
+	if comparison.Providers[1].Provider != "secondary" || comparison.Providers[1].MidRate != 42.80 {
+		t.Errorf("unexpected secondary entry: %+v", comparison.Providers[1])
+	}
+
+	if comparison.Selected != "primary" {
+		t.Errorf("expected primary to be selected, got %s", comparison.Selected)
+	}
+}
+
+func TestCompareProviders_ProviderFailureReportedPerEntry(t *testing.T) {
+	failing := &MockProvider{
+		ProviderName: "failing",
+		GetRateFunc: func(ctx context.Context, source, target string) (*provider.Rate, error) {
+			return nil, provider.ErrProviderUnavailable{Provider: "failing", Reason: "timeout"}
+		},
+	}
+	healthy := &MockProvider{ProviderName: "healthy"}
+
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 60}
+	svc := NewRateService(cfg, provider.NewChainProvider(failing, healthy), NewMockRepository(), zap.NewNop())
+
+	comparison := svc.CompareProviders(context.Background(), "SGD", "PHP")
+
+	if comparison.Providers[0].Error == "" {
+		t.Error("expected failing provider entry to report an error")
+	}
+	if comparison.Providers[1].Error != "" || comparison.Providers[1].MidRate == 0 {
+		t.Errorf("expected healthy provider entry to have a rate, got %+v", comparison.Providers[1])
+	}
+	if comparison.Selected != "healthy" {
+		t.Errorf("expected healthy provider to be selected, got %s", comparison.Selected)
+	}
+}
+
+func TestCompareProviders_SelectsHedgeWinner(t *testing.T) {
+	slow := &MockProvider{
+		ProviderName: "slow",
+		GetRateFunc: func(ctx context.Context, source, target string) (*provider.Rate, error) {
+			time.Sleep(200 * time.Millisecond)
+			return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50}, nil
+		},
+	}
+	fast := &MockProvider{ProviderName: "fast"}
+
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 60}
+	chain := provider.NewChainProvider(slow, fast).WithHedging(20*time.Millisecond, nil)
+	svc := NewRateService(cfg, chain, NewMockRepository(), zap.NewNop())
+
+	comparison := svc.CompareProviders(context.Background(), "SGD", "PHP")
+
+	// Both answer, but the chain serves the hedge that answers first
+	if comparison.Providers[0].Error != "" || comparison.Providers[1].Error != "" {
+		t.Fatalf("expected both providers to answer, got %+v", comparison.Providers)
+	}
+	if comparison.Selected != "fast" {
+		t.Errorf("expected the hedged fast provider to be selected, got %s", comparison.Selected)
+	}
+}
+
+func newDegradationTestService(failures, successes int) *RateService {
+	cfg := &config.Config{
+		RateCacheTTL:        30,
+		LockDuration:        60,
+		DegradedErrorRate:   0.5,
+		ErrorRateWindow:     60,
+		ErrorRateMinSamples: 4,
+	}
+
+	calls := 0
+	mockProvider := &MockProvider{
+		GetRateFunc: func(ctx context.Context, source, target string) (*provider.Rate, error) {
+			calls++
+			if calls <= failures {
+				return nil, provider.ErrProviderUnavailable{Provider: "mock", Reason: "timeout"}
+			}
+			return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50}, nil
+		},
+	}
+	// Never cache so every call reaches the provider
+	mockRepo := NewMockRepository()
+	mockRepo.SaveRateFunc = func(ctx context.Context, rate *provider.Rate, ttl time.Duration) error {
+		return nil
+	}
+
+	svc := NewRateService(cfg, mockProvider, mockRepo, zap.NewNop())
+	for i := 0; i < failures+successes; i++ {
+		_, _ = svc.GetRate(context.Background(), "SGD", "PHP")
+	}
+	return svc
+}
+
+func TestHealthReport_DegradedAboveErrorRateThreshold(t *testing.T) {
+	svc := newDegradationTestService(6, 2)
+
+	report := svc.HealthReport(context.Background())
+
+	if report.State != model.HealthStateDegraded {
+		t.Errorf("expected degraded state, got %s", report.State)
+	}
+	if report.ProviderErrorRate != 0.75 {
+		t.Errorf("expected error rate 0.75, got %f", report.ProviderErrorRate)
+	}
+}
+
+func TestHealthReport_HealthyBelowErrorRateThreshold(t *testing.T) {
+	svc := newDegradationTestService(2, 6)
+
+	report := svc.HealthReport(context.Background())
+
+	if report.State != model.HealthStateHealthy {
+		t.Errorf("expected healthy state, got %s", report.State)
+	}
+	if report.ProviderErrorRate != 0.25 {
+		t.Errorf("expected error rate 0.25, got %f", report.ProviderErrorRate)
+	}
+}
+
+func TestHealthReport_NotDegradedBelowMinSamples(t *testing.T) {
+	svc := newDegradationTestService(2, 0)
+
+	if report := svc.HealthReport(context.Background()); report.State != model.HealthStateHealthy {
+		t.Errorf("expected healthy state with too few samples, got %s", report.State)
+	}
+}
+
+func TestHealthReport_UnhealthyWhenRepositoryDown(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	mockRepo.HealthFunc = func(ctx context.Context) error {
+		return errors.New("redis connection failed")
+	}
+
+	report := svc.HealthReport(context.Background())
+
+	if report.State != model.HealthStateUnhealthy {
+		t.Errorf("expected unhealthy state, got %s", report.State)
+	}
+	if report.Redis.Healthy || !report.Provider.Healthy {
+		t.Errorf("expected only redis to be reported down, got redis %+v and provider %+v", report.Redis, report.Provider)
+	}
+	if !strings.HasPrefix(report.Error, "redis unhealthy") {
+		t.Errorf("expected a redis error, got %q", report.Error)
+	}
+}
+
+func TestHealthReport_UnhealthyWhenProviderDown(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.HealthFunc = func(ctx context.Context) error {
+		return provider.ErrProviderUnavailable{Provider: "mock", Reason: "connection refused"}
+	}
+
+	report := svc.HealthReport(context.Background())
+
+	if report.State != model.HealthStateUnhealthy {
+		t.Errorf("expected unhealthy state, got %s", report.State)
+	}
+	if !report.Redis.Healthy || report.Provider.Healthy {
+		t.Errorf("expected only the provider to be reported down, got redis %+v and provider %+v", report.Redis, report.Provider)
+	}
+	if !strings.HasPrefix(report.Error, "provider unhealthy") {
+		t.Errorf("expected a provider error, got %q", report.Error)
+	}
+}
+
+func TestLockRateIdempotent_SameKeyReturnsSameLock(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	ctx := context.Background()
+
+	first, err := svc.LockRateIdempotent(ctx, "retry-key-1", "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second, err := svc.LockRateIdempotent(ctx, "retry-key-1", "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first.LockID != second.LockID {
+		t.Errorf("expected same lock ID for repeated key, got %s and %s", first.LockID, second.LockID)
+	}
+
+	if len(mockRepo.lockedRates) != 1 {
+		t.Errorf("expected exactly 1 stored lock, got %d", len(mockRepo.lockedRates))
+	}
+}
+
+func TestLockRateIdempotent_DifferentKeysCreateDifferentLocks(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+
+	first, _ := svc.LockRateIdempotent(ctx, "key-a", "SGD", "PHP", 60)
+	second, _ := svc.LockRateIdempotent(ctx, "key-b", "SGD", "PHP", 60)
+
+	if first.LockID == second.LockID {
+		t.Error("expected different lock IDs for different keys")
+	}
+}
+
+func TestLockRateIdempotent_ExpiredLockCreatesNewLock(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	ctx := context.Background()
+
+	first, _ := svc.LockRateIdempotent(ctx, "retry-key-2", "SGD", "PHP", 60)
+
+	// Simulate the original lock expiring
+	mockRepo.GetLockedFunc = func(ctx context.Context, lockID string) (*model.LockedRate, error) {
+		if lockID == first.LockID {
+			return nil, repository.ErrExpired{LockID: lockID}
+		}
+		return mockRepo.lockedRates[lockID], nil
+	}
+
+	second, err := svc.LockRateIdempotent(ctx, "retry-key-2", "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if second == nil || second.LockID == first.LockID {
+		t.Fatal("expected a new lock once the original expired")
+	}
+
+	if mockRepo.lockKeys["retry-key-2"].LockID != second.LockID {
+		t.Errorf("expected key to be rebound to the new lock, got %s", mockRepo.lockKeys["retry-key-2"].LockID)
+	}
+}
+
+func TestLockRateIdempotent_KeyReusedForDifferentRequest(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	ctx := context.Background()
+
+	if _, err := svc.LockRateIdempotent(ctx, "retry-key-3", "SGD", "PHP", 60); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := svc.LockRateIdempotent(ctx, "retry-key-3", "SGD", "IDR", 60)
+	if _, ok := err.(ErrIdempotencyKeyConflict); !ok {
+		t.Fatalf("expected ErrIdempotencyKeyConflict for a different pair, got %v", err)
+	}
+	_, err = svc.LockRateFromQuoteIdempotent(ctx, "retry-key-3", "quote-1", 60)
+	if _, ok := err.(ErrIdempotencyKeyConflict); !ok {
+		t.Fatalf("expected ErrIdempotencyKeyConflict for a quote lock, got %v", err)
+	}
+	if len(mockRepo.lockedRates) != 1 {
+		t.Errorf("expected exactly 1 stored lock, got %d", len(mockRepo.lockedRates))
+	}
+}
+
+// racingClaimRepository loses every idempotency key claim to another
+// request whose lock can't be read
+type racingClaimRepository struct {
+	*MockRepository
+}
+
+func (r *racingClaimRepository) ClaimLockIdempotencyKey(ctx context.Context, key string, record repository.LockIdempotencyRecord, ttl time.Duration) (*repository.LockIdempotencyRecord, error) {
+	winner := repository.LockIdempotencyRecord{LockID: "winner-lock", RequestHash: record.RequestHash}
+	r.lockKeys[key] = winner
+	return &winner, nil
+}
+
+func TestLockRateIdempotent_LostClaimIsInProgress(t *testing.T) {
+	mockRepo := NewMockRepository()
+	cfg := &config.Config{RateCacheTTL: 30, LockDuration: 60, RateSnapshotRetentionHours: 24}
+	svc := NewRateService(cfg, &MockProvider{}, &racingClaimRepository{mockRepo}, zap.NewNop())
+
+	locked, err := svc.LockRateIdempotent(context.Background(), "retry-key-4", "SGD", "PHP", 60)
+	if _, ok := err.(ErrIdempotentRequestInProgress); !ok {
+		t.Fatalf("expected ErrIdempotentRequestInProgress, got %v (lock %v)", err, locked)
+	}
+	if len(mockRepo.lockedRates) != 0 {
+		t.Errorf("expected the losing lock to be dropped, got %d stored", len(mockRepo.lockedRates))
+	}
+	if len(mockRepo.snapshots) != 0 {
+		t.Errorf("expected the losing lock's rate snapshot to be dropped, got %d stored", len(mockRepo.snapshots))
+	}
+}
+
+func TestExtendLockedRates_PartialSuccess(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	svc.config.MaxLockDuration = 120
+	ctx := context.Background()
+
+	now := time.Now()
+	mockRepo.lockedRates["valid"] = &model.LockedRate{LockID: "valid", LockedAt: now, ExpiresAt: now.Add(30 * time.Second)}
+	mockRepo.lockedRates["expired"] = &model.LockedRate{LockID: "expired", LockedAt: now.Add(-time.Minute), ExpiresAt: now.Add(-time.Second)}
+	mockRepo.lockedRates["near-cap"] = &model.LockedRate{LockID: "near-cap", LockedAt: now, ExpiresAt: now.Add(100 * time.Second)}
+
+	results, err := svc.ExtendLockedRates(ctx, []string{"valid", "expired", "missing", "near-cap"}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	if !results[0].Extended || results[0].ExpiresAt == nil {
+		t.Errorf("expected valid lock to be extended, got %+v", results[0])
+	} else if !results[0].ExpiresAt.Equal(now.Add(60 * time.Second)) {
+		t.Errorf("expected new expiry %v, got %v", now.Add(60*time.Second), *results[0].ExpiresAt)
+	}
+	if !mockRepo.lockedRates["valid"].ExpiresAt.Equal(now.Add(60 * time.Second)) {
+		t.Error("expected extended expiry to be persisted")
+	}
+
+	for i, want := range map[int]string{1: "lock expired", 2: "lock not found"} {
+		if results[i].Extended || results[i].Error != want {
+			t.Errorf("result %d: expected error %q, got %+v", i, want, results[i])
+		}
+	}
+
+	if results[3].Extended || results[3].Error == "" {
+		t.Errorf("expected extension past MaxLockDuration to be rejected, got %+v", results[3])
+	}
+	if !mockRepo.lockedRates["near-cap"].ExpiresAt.Equal(now.Add(100 * time.Second)) {
+		t.Error("expected rejected lock to keep its original expiry")
+	}
+}
+
+func TestExtendLockedRates_RejectsNonPositiveDuration(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	if _, err := svc.ExtendLockedRates(context.Background(), []string{"lock"}, 0); err == nil {
+		t.Error("expected error for non-positive additional seconds")
+	}
+}
+
+func TestExtendLockedRate(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	svc.config.MaxLockDuration = 120
+	ctx := context.Background()
+
+	now := time.Now()
+	mockRepo.lockedRates["valid"] = &model.LockedRate{LockID: "valid", LockedAt: now, ExpiresAt: now.Add(30 * time.Second)}
+	mockRepo.lockedRates["expired"] = &model.LockedRate{LockID: "expired", LockedAt: now.Add(-time.Minute), ExpiresAt: now.Add(-time.Second)}
+	mockRepo.lockedRates["near-cap"] = &model.LockedRate{LockID: "near-cap", LockedAt: now, ExpiresAt: now.Add(100 * time.Second)}
+
+	locked, err := svc.ExtendLockedRate(ctx, "valid", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !locked.ExpiresAt.Equal(now.Add(60 * time.Second)) {
+		t.Errorf("expected new expiry %v, got %v", now.Add(60*time.Second), locked.ExpiresAt)
+	}
+	if !mockRepo.lockedRates["valid"].ExpiresAt.Equal(now.Add(60 * time.Second)) {
+		t.Error("expected extended expiry to be persisted")
+	}
+
+	if _, err := svc.ExtendLockedRate(ctx, "expired", 30); err == nil {
+		t.Error("expected error extending an expired lock")
+	} else if _, ok := err.(repository.ErrExpired); !ok {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+	if _, err := svc.ExtendLockedRate(ctx, "missing", 30); err == nil {
+		t.Error("expected error extending a missing lock")
+	} else if _, ok := err.(repository.ErrNotFound); !ok {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	_, err = svc.ExtendLockedRate(ctx, "near-cap", 30)
+	if capErr, ok := err.(ErrLockDurationExceeded); !ok || capErr.MaxSeconds != 120 {
+		t.Errorf("expected ErrLockDurationExceeded with a 120s cap, got %v", err)
+	}
+	if !mockRepo.lockedRates["near-cap"].ExpiresAt.Equal(now.Add(100 * time.Second)) {
+		t.Error("expected rejected lock to keep its original expiry")
+	}
+}
+
+func TestGetRate_CanonicalOrderingSharesCache(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	svc.config.CanonicalPairOrdering = true
+
+	var calls []string
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		calls = append(calls, source+"/"+target)
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        1.34,
+			BidRate:        1.33,
+			AskRate:        1.35,
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+
+	ctx := context.Background()
+	sgdUSD, err := svc.GetRate(ctx, "SGD", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usdSGD, err := svc.GetRate(ctx, "USD", "SGD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(calls) != 1 || calls[0] != "USD/SGD" {
+		t.Fatalf("expected one provider call for canonical USD/SGD, got %v", calls)
+	}
+	if _, ok := mockRepo.rates["USD:SGD"]; !ok || len(mockRepo.rates) != 1 {
+		t.Errorf("expected only the canonical USD:SGD rate to be cached, got %v", mockRepo.rates)
+	}
+
+	if sgdUSD.SourceCurrency != "SGD" || sgdUSD.TargetCurrency != "USD" {
+		t.Errorf("unexpected currencies: %s/%s", sgdUSD.SourceCurrency, sgdUSD.TargetCurrency)
+	}
+	if sgdUSD.MidRate != 1/usdSGD.MidRate {
+		t.Errorf("expected SGD/USD %v to be the exact inverse of USD/SGD %v", sgdUSD.MidRate, usdSGD.MidRate)
+	}
+	if sgdUSD.BidRate != 1/usdSGD.AskRate || sgdUSD.AskRate != 1/usdSGD.BidRate {
+		t.Errorf("expected bid and ask to swap when inverted, got bid %v ask %v", sgdUSD.BidRate, sgdUSD.AskRate)
+	}
+}
+
+func TestGetRateHistory_CanonicalOrderingInvertsPoints(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	svc.config.CanonicalPairOrdering = true
+
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_ = mockRepo.SaveRate(context.Background(), &provider.Rate{
+		SourceCurrency: "USD", TargetCurrency: "SGD",
+		MidRate: 1.25, BidRate: 1.24, AskRate: 1.26, FetchedAt: fetchedAt,
+	}, time.Minute)
+
+	history, err := svc.GetRateHistory(context.Background(), "SGD", "USD", fetchedAt.Add(-time.Hour), fetchedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if history.SourceCurrency != "SGD" || history.TargetCurrency != "USD" || len(history.Points) != 1 {
+		t.Fatalf("expected one SGD/USD point, got %+v", history)
+	}
+	point := history.Points[0]
+	if math.Abs(point.MidRate-0.8) > 1e-9 || math.Abs(point.BidRate-1/1.26) > 1e-9 || math.Abs(point.AskRate-1/1.24) > 1e-9 {
+		t.Errorf("expected the inverse of USD/SGD with bid and ask swapped, got %+v", point)
+	}
+}
 
-	err = svc.Health(ctx)
-	if err == nil {
-		t.Error("expected error when repository is unhealthy")
+func TestGetRates_CanonicalOrderingFetchesPairOnce(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.CanonicalPairOrdering = true
+
+	var requested []provider.CurrencyPair
+	mockProvider.GetRatesFunc = func(ctx context.Context, pairs []provider.CurrencyPair) ([]*provider.Rate, error) {
+		requested = append(requested, pairs...)
+		rates := make([]*provider.Rate, 0, len(pairs))
+		for _, pair := range pairs {
+			rate, _ := mockProvider.GetRate(ctx, pair.Source, pair.Target)
+			rates = append(rates, rate)
+		}
+		return rates, nil
+	}
+
+	result := svc.GetRates(context.Background(), []provider.CurrencyPair{
+		{Source: "SGD", Target: "USD"},
+		{Source: "USD", Target: "SGD"},
+	})
+	if len(result.Errors) != 0 {
+		t.Fatalf("unexpected errors: %+v", result.Errors)
+	}
+	rates := result.Rates
+
+	if len(requested) != 1 || requested[0] != (provider.CurrencyPair{Source: "USD", Target: "SGD"}) {
+		t.Fatalf("expected a single canonical USD/SGD fetch, got %v", requested)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates, got %d", len(rates))
+	}
+	if rates[0].SourceCurrency != "SGD" || rates[1].SourceCurrency != "USD" {
+		t.Errorf("expected results in request order, got %s then %s", rates[0].SourceCurrency, rates[1].SourceCurrency)
+	}
+	if rates[0].MidRate != 1/rates[1].MidRate {
+		t.Errorf("expected exact inverses, got %v and %v", rates[0].MidRate, rates[1].MidRate)
+	}
+}
+
+func TestGetRates_ReportsSkippedPairs(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	// Like the simulated provider, skip the pairs it doesn't support
+	mockProvider.GetRatesFunc = func(ctx context.Context, pairs []provider.CurrencyPair) ([]*provider.Rate, error) {
+		var rates []*provider.Rate
+		for _, pair := range pairs {
+			if pair.Target == "XXX" {
+				continue
+			}
+			rate, _ := mockProvider.GetRate(ctx, pair.Source, pair.Target)
+			rates = append(rates, rate)
+		}
+		return rates, nil
+	}
+
+	result := svc.GetRates(context.Background(), []provider.CurrencyPair{
+		{Source: "SGD", Target: "PHP"},
+		{Source: "SGD", Target: "XXX"},
+		{Source: "USD", Target: "PHP"},
+	})
+
+	if len(result.Rates) != 2 || result.Rates[0].TargetCurrency != "PHP" || result.Rates[1].SourceCurrency != "USD" {
+		t.Fatalf("expected the SGD/PHP and USD/PHP rates, got %+v", result.Rates)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %+v", result.Errors)
+	}
+	if failure := result.Errors[0]; failure.SourceCurrency != "SGD" || failure.TargetCurrency != "XXX" || failure.Error == "" {
+		t.Errorf("expected SGD/XXX to be reported, got %+v", failure)
+	}
+}
+
+func TestGetRates_BatchFailureIsPinnedToFailingPairs(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRatesFunc = func(ctx context.Context, pairs []provider.CurrencyPair) ([]*provider.Rate, error) {
+		return nil, errors.New("invalid rate for SGD/IDR")
+	}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		if target == "IDR" {
+			return nil, errors.New("invalid rate")
+		}
+		return (&MockProvider{}).GetRate(ctx, source, target)
+	}
+
+	result := svc.GetRates(context.Background(), []provider.CurrencyPair{
+		{Source: "SGD", Target: "PHP"},
+		{Source: "SGD", Target: "IDR"},
+	})
+
+	if len(result.Rates) != 1 || result.Rates[0].TargetCurrency != "PHP" {
+		t.Fatalf("expected the SGD/PHP rate, got %+v", result.Rates)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].TargetCurrency != "IDR" {
+		t.Fatalf("expected SGD/IDR to be reported, got %+v", result.Errors)
+	}
+	if !strings.Contains(result.Errors[0].Error, "invalid rate") {
+		t.Errorf("expected the provider error, got %q", result.Errors[0].Error)
+	}
+}
+
+func TestGetRate_CacheWriteFailureServesUncached(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	mockRepo.SaveRateFunc = func(ctx context.Context, rate *provider.Rate, ttl time.Duration) error {
+		return repository.ErrWriteFailed{Operation: "save rate", Kind: repository.WriteFailureOOM, Err: errors.New("OOM")}
+	}
+
+	rate, err := svc.GetRate(context.Background(), "SGD", "PHP")
+	if err != nil {
+		t.Fatalf("expected rate to be served uncached, got %v", err)
+	}
+	if rate.MidRate != 42.50 {
+		t.Errorf("expected mid rate 42.50, got %f", rate.MidRate)
+	}
+
+	svc.config.FailOnCacheWriteError = true
+	_, err = svc.GetRate(context.Background(), "SGD", "PHP")
+	var writeErr repository.ErrWriteFailed
+	if !errors.As(err, &writeErr) {
+		t.Fatalf("expected ErrWriteFailed with FailOnCacheWriteError, got %v", err)
+	}
+}
+
+func TestLockRate_WriteFailureReturnsErrWriteFailed(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	mockRepo.SaveLockedFunc = func(ctx context.Context, locked *model.LockedRate) error {
+		return repository.ErrWriteFailed{Operation: "save locked rate", Kind: repository.WriteFailureOOM, Err: errors.New("OOM")}
+	}
+
+	_, err := svc.LockRate(context.Background(), "SGD", "PHP", 30)
+	var writeErr repository.ErrWriteFailed
+	if !errors.As(err, &writeErr) || writeErr.Kind != repository.WriteFailureOOM {
+		t.Fatalf("expected OOM ErrWriteFailed, got %v", err)
+	}
+}
+
+func TestGetQuoteFromLock_UsesTermsAtLockTime(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.SnapshotLockTerms = true
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if locked.MarginPercentage != "0.3" || locked.FeePercentage != "0.5" {
+		t.Fatalf("expected corridor terms to be snapshotted, got margin %q fee %q", locked.MarginPercentage, locked.FeePercentage)
+	}
+
+	// Corridor terms change after the rate is locked
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.MarginPercentage = "5.0"
+		c.FeePercentage = "10.0"
+	})
+
+	quote, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantRate := 42.29 * (1 - 0.003) // The mock's bid less the locked margin
+	if math.Abs(quote.ExchangeRate.InexactFloat64()-wantRate) > 1e-6 {
+		t.Errorf("expected locked buy rate %f, got %f", wantRate, quote.ExchangeRate.InexactFloat64())
+	}
+	if quote.Fee.InexactFloat64() != 5.0 {
+		t.Errorf("expected locked 0.5%% fee of 5.00, got %f", quote.Fee.InexactFloat64())
+	}
+	if !quote.ValidUntil.Equal(locked.ExpiresAt) {
+		t.Errorf("expected quote to be valid until the lock expires, got %v", quote.ValidUntil)
+	}
+
+	// Without snapshots the current corridor terms apply
+	svc.config.SnapshotLockTerms = false
+	quote, err = svc.GetQuoteFromLock(ctx, locked.LockID, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.Fee.InexactFloat64() != 100.0 {
+		t.Errorf("expected current 10%% fee of 100.00, got %f", quote.Fee.InexactFloat64())
+	}
+}
+
+func TestGetQuoteFromLock_ExpiredLock(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	_, err := svc.GetQuoteFromLock(context.Background(), "missing-lock", 1000)
+	if _, ok := err.(repository.ErrExpired); !ok {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestVerifyQuoteFromLock_AfterLockExpires(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+	svc.config.RateSnapshotRetentionHours = 24
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	atLock, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The lock expires out of the store and the corridor is repriced
+	delete(mockRepo.lockedRates, locked.LockID)
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.MarginPercentage = "5.0"
+		c.FeePercentage = "10.0"
+	})
+	if _, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000); err == nil {
+		t.Fatal("expected the expired lock to give no live quote")
+	}
+
+	verified, err := svc.VerifyQuoteFromLock(ctx, locked.LockID, 1000)
+	if err != nil {
+		t.Fatalf("expected the expired lock to be verified from its snapshot, got %v", err)
+	}
+	if !verified.TargetAmount.Equal(atLock.TargetAmount.Decimal) || !verified.Fee.Equal(atLock.Fee.Decimal) {
+		t.Errorf("expected the terms at lock time, %s with fee %s, got %s with fee %s",
+			atLock.TargetAmount, atLock.Fee, verified.TargetAmount, verified.Fee)
+	}
+	if _, ok := mockRepo.quotes[verified.QuoteID]; ok {
+		t.Error("expected the verification quote not to be stored")
+	}
+
+	if _, err := svc.VerifyQuoteFromLock(ctx, "missing-lock", 1000); err == nil {
+		t.Error("expected an error for a lock without a snapshot")
+	}
+}
+
+func TestLockRateFromQuote_RecordsCapturedMarginInReportingCurrency(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.ReportingCurrency = "USD"
+
+	mids := map[string]float64{"SGD/PHP": 42.50, "PHP/USD": 0.0175}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        mids[source+"/"+target],
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+
+	var corridor, currency string
+	var captured float64
+	svc.SetMarginRecorder(func(c, cur string, amount float64) {
+		corridor, currency = c, cur
+		captured += amount
+	})
+
+	ctx := context.Background()
+	// The conversion uses the cached PHP/USD rate rather than the provider
+	if _, err := svc.GetRate(ctx, "PHP", "USD"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		if source != "SGD" || target != "PHP" {
+			t.Errorf("unexpected provider call for %s/%s", source, target)
+		}
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        mids[source+"/"+target],
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if captured != 0 {
+		t.Fatalf("expected no margin recorded for a quote that isn't locked, got %f", captured)
+	}
+	if _, err := svc.LockRateFromQuote(ctx, quote.QuoteID, 60); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// SGD 1000 at a 0.3% margin captures PHP 127.50, or USD 2.23125
+	want := 1000 * 42.50 * 0.003 * 0.0175
+	if corridor != "SGD/PHP" || currency != "USD" {
+		t.Errorf("unexpected labels %s %s", corridor, currency)
+	}
+	if math.Abs(captured-want) > 1e-4 {
+		t.Errorf("expected captured margin %f, got %f", want, captured)
+	}
+}
+
+func TestGetQuoteFromLock_RecordsCapturedMarginOfRateLock(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.ReportingCurrency = "PHP"
+
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        42.50,
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+
+	var captured float64
+	var calls int
+	svc.SetMarginRecorder(func(corridor, currency string, amount float64) {
+		captured += amount
+		calls++
+	})
+
+	ctx := context.Background()
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no margin recorded before the lock has an amount, got %d records", calls)
+	}
+	if _, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// SGD 1000 at the lock's 0.3% margin captures PHP 127.50
+	if want := 1000 * 42.50 * 0.003; calls != 1 || math.Abs(captured-want) > 1e-4 {
+		t.Errorf("expected one record of %f, got %d totalling %f", want, calls, captured)
+	}
+
+	// Verifying a transfer against the lock isn't a new use of it
+	if _, err := svc.VerifyQuoteFromLock(ctx, locked.LockID, 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected verifying not to record margin, got %d records", calls)
+	}
+}
+
+func TestLockRateFromQuote_RecordsCapturedMarginOnce(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.ReportingCurrency = "PHP"
+
+	var calls int
+	svc.SetMarginRecorder(func(corridor, currency string, amount float64) { calls++ })
+
+	ctx := context.Background()
+	quote, err := svc.GetQuote(ctx, "SGD", "PHP", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	locked, err := svc.LockRateFromQuote(ctx, quote.QuoteID, 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GetQuoteFromLock(ctx, locked.LockID, 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the quote's margin recorded once when locked, got %d records", calls)
+	}
+}
+
+func TestGetCorridorQuotes_UsesEachCorridorsSampleAmount(t *testing.T) {
+	svc, mockProvider, _ := newTestService()
+	svc.config.ReportingCurrency = "USD"
+
+	mids := map[string]float64{"PHP": 42.50, "INR": 62.10, "IDR": 11800, "USD": 0.74}
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        mids[target],
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) { c.SampleAmount = "750" })
+	overrideCorridor(t, "SGD", "INR", func(c *model.Corridor) { c.SampleAmount = "" })
+
+	recorded := false
+	svc.SetMarginRecorder(func(string, string, float64) { recorded = true })
+
+	quotes, warnings := svc.GetCorridorQuotes(context.Background(), CorridorFilter{SourceCurrency: "SGD"})
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+	if len(quotes) != len(svc.GetCorridors(CorridorFilter{SourceCurrency: "SGD"})) {
+		t.Fatalf("expected a quote entry per SGD corridor, got %d", len(quotes))
+	}
+
+	for _, entry := range quotes {
+		if entry.SampleQuote == nil {
+			t.Errorf("%s/%s: missing sample quote: %s", entry.SourceCurrency, entry.TargetCurrency, entry.Error)
+			continue
+		}
+		if want := entry.SampleSourceAmount(); entry.SampleQuote.SourceAmount.InexactFloat64() != want {
+			t.Errorf("%s/%s: expected sample amount %g, got %s",
+				entry.SourceCurrency, entry.TargetCurrency, want, entry.SampleQuote.SourceAmount)
+		}
+
+		switch entry.TargetCurrency {
+		case "PHP":
+			if entry.SampleQuote.SourceAmount.InexactFloat64() != 750 {
+				t.Errorf("expected configured SGD/PHP sample amount 750, got %g", entry.SampleQuote.SourceAmount.InexactFloat64())
+			}
+		case "INR":
+			if entry.SampleQuote.SourceAmount.InexactFloat64() != model.DefaultSampleAmount("SGD") {
+				t.Errorf("expected default SGD sample amount, got %g", entry.SampleQuote.SourceAmount.InexactFloat64())
+			}
+		}
+	}
+
+	if recorded {
+		t.Error("expected sample quotes not to record captured margin")
+	}
+}
+
+func TestDefaultSampleAmount_ScalesWithCurrencyMagnitude(t *testing.T) {
+	if got := model.DefaultSampleAmount("SGD"); got != 100 {
+		t.Errorf("expected SGD default 100, got %g", got)
+	}
+	if got := model.DefaultSampleAmount("IDR"); got != 1000000 {
+		t.Errorf("expected IDR default 1000000, got %g", got)
+	}
+}
+
+func TestLockRate_SnapshotOutlivesLock(t *testing.T) {
+	svc, mockProvider, mockRepo := newTestService()
+	svc.config.RateSnapshotRetentionHours = 24
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{
+			SourceCurrency: source,
+			TargetCurrency: target,
+			MidRate:        42.50,
+			BidRate:        42.29,
+			AskRate:        42.71,
+			Spread:         0.5,
+			Drift:          0.012,
+			Source:         "mock",
+			FetchedAt:      time.Now(),
+			ValidUntil:     time.Now().Add(30 * time.Second),
+		}, nil
+	}
+	ctx := context.Background()
+
+	locked, err := svc.LockRate(ctx, "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The snapshot is kept for the retention window past the lock's expiry
+	if expiry := mockRepo.snapshotExpiry[locked.LockID]; expiry.Before(locked.ExpiresAt.Add(24*time.Hour - time.Second)) {
+		t.Errorf("expected snapshot retained until %v, expires %v", locked.ExpiresAt.Add(24*time.Hour), expiry)
+	}
+
+	// Simulate the lock expiring out of the store
+	delete(mockRepo.lockedRates, locked.LockID)
+
+	snapshot, err := svc.GetRateSnapshot(ctx, locked.LockID)
+	if err != nil {
+		t.Fatalf("expected snapshot after lock expiry, got %v", err)
+	}
+	if snapshot.MidRate != 42.50 || snapshot.BidRate != 42.29 || snapshot.AskRate != 42.71 ||
+		snapshot.Spread != 0.5 || snapshot.Drift != 0.012 || snapshot.Provider != "mock" {
+		t.Errorf("unexpected market conditions in snapshot: %+v", snapshot)
+	}
+	if snapshot.BuyRate != locked.Rate.BuyRate || snapshot.FeePercentage != "0.5" || !snapshot.LockedAt.Equal(locked.LockedAt) {
+		t.Errorf("expected snapshot to record the locked terms, got %+v", snapshot)
+	}
+}
+
+func TestLockRate_SnapshotsDisabled(t *testing.T) {
+	svc, _, mockRepo := newTestService()
+
+	locked, err := svc.LockRate(context.Background(), "SGD", "PHP", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := mockRepo.snapshots[locked.LockID]; ok {
+		t.Error("expected no snapshot with a zero retention window")
+	}
+	if _, err := svc.GetRateSnapshot(context.Background(), locked.LockID); err == nil {
+		t.Error("expected ErrNotFound")
 	}
 }
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// reversibleCorridor returns the to→from corridor when it allows quotes in
+// the from→to direction and no from→to corridor is defined
+func (s *RateService) reversibleCorridor(from, to string) *model.Corridor {
+	if s.baseCorridor(from, to) != nil {
+		return nil
+	}
+	if c := s.getCorridor(to, from); c != nil && c.ReverseQuotes {
+		return c
+	}
+	return nil
+}
+
+// reverseCorridor derives the from→to corridor from a reversible to→from
+// one. The fee and margin percentages carry over. The forward minimums swap
+// ends, staying in their currencies, while the fee minimum and maximum
+// amount are converted into the new source currency at midRate, the from→to
+// mid rate.
+func (s *RateService) reverseCorridor(from, to string, midRate float64) *model.Corridor {
+	forward := s.reversibleCorridor(from, to)
+	if forward == nil || midRate <= 0 {
+		return nil
+	}
+	rate := decimal.NewFromFloat(midRate)
+
+	return &model.Corridor{
+		SourceCurrency:      from,
+		TargetCurrency:      to,
+		Enabled:             forward.Enabled,
+		FeePercentage:       forward.FeePercentage,
+		FeeMinimum:          convertMoney(forward.FeeMinimum, from, rate),
+		MarginPercentage:    forward.MarginPercentage,
+		PayoutMethods:       forward.PayoutMethods,
+		MinTargetAmount:     forward.MinAmount,
+		MinAmount:           forward.MinTargetAmount,
+		MaxAmount:           convertMoney(forward.MaxAmount, from, rate),
+		RateValiditySeconds: forward.RateValiditySeconds,
+	}
+}
+
+// convertMoney converts m into currency, where currency→m.Currency trades at
+// rate. Amounts that aren't set are left as they are.
+func convertMoney(m model.Money, currency string, rate decimal.Decimal) model.Money {
+	amount, err := decimal.NewFromString(m.Amount)
+	if err != nil {
+		return model.Money{Currency: currency, Amount: m.Amount}
+	}
+	return model.Money{Currency: currency, Amount: model.NewAmount(amount.Div(rate), currency).String()}
+}
+
+// getReverseQuote quotes from→to on the corridor derived from the reversible
+// to→from one
+func (s *RateService) getReverseQuote(ctx context.Context, from, to string, sourceAmount float64) (*model.RateQuote, error) {
+	if forward := s.reversibleCorridor(from, to); !forward.Enabled {
+		return nil, ErrCorridorDisabled{SourceCurrency: from, TargetCurrency: to}
+	}
+
+	rate, err := s.quoteRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	corridor := s.reverseCorridor(from, to, rate.MidRate)
+	if corridor == nil {
+		return nil, ErrCorridorNotFound{SourceCurrency: from, TargetCurrency: to}
+	}
+	amount := model.AmountFromFloat(sourceAmount, from)
+	if err := checkSourceAmount(corridor, amount); err != nil {
+		return nil, err
+	}
+
+	return s.buildQuote(ctx, rate, corridor, amount)
+}
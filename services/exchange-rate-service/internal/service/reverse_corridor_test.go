@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/model"
+)
+
+func TestGetQuote_ReverseCorridor(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.CanonicalPairOrdering = true
+
+	// Only SGD/PHP is defined; PHP/SGD is quoted on it at the inverted rate
+	quote, err := svc.GetQuote(context.Background(), "PHP", "SGD", 50000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if quote.SourceCurrency != "PHP" || quote.TargetCurrency != "SGD" {
+		t.Errorf("expected a PHP/SGD quote, got %s/%s", quote.SourceCurrency, quote.TargetCurrency)
+	}
+	// The buy rate is the PHP/SGD bid, the inverted SGD/PHP ask (1/42.71),
+	// less the corridor's 0.3% margin
+	if rate := quote.ExchangeRate.String(); rate != "0.023343" {
+		t.Errorf("expected exchange rate 0.023343, got %s", rate)
+	}
+	if target := quote.TargetAmount.String(); target != "1167.15" {
+		t.Errorf("expected target amount 1167.15 SGD, got %s", target)
+	}
+	if fee := quote.Fee.String(); fee != "250.00" {
+		t.Errorf("expected the corridor's 0.5%% fee of 250.00 PHP, got %s", fee)
+	}
+	if total := quote.TotalCost.String(); total != "50250.00" {
+		t.Errorf("expected total cost 50250.00 PHP, got %s", total)
+	}
+}
+
+func TestGetQuote_ReverseCorridorConvertsFeeMinimum(t *testing.T) {
+	svc, _, _ := newTestService()
+	svc.config.CanonicalPairOrdering = true
+
+	// 0.5% of 5000 PHP is below the 3 SGD minimum, 127.50 PHP at the mid rate
+	quote, err := svc.GetQuote(context.Background(), "PHP", "SGD", 5000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fee := quote.Fee.String(); fee != "127.50" {
+		t.Errorf("expected the fee minimum of 127.50 PHP, got %s", fee)
+	}
+}
+
+func TestGetQuote_ReverseCorridorMustBeAllowed(t *testing.T) {
+	svc, _, _ := newTestService()
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.ReverseQuotes = false
+	})
+
+	_, err := svc.GetQuote(context.Background(), "PHP", "SGD", 5000)
+	if _, ok := err.(ErrCorridorNotFound); !ok {
+		t.Fatalf("expected ErrCorridorNotFound, got %v", err)
+	}
+}
+
+func TestGetQuote_ReverseCorridorFollowsForwardEnabled(t *testing.T) {
+	svc, _, _ := newTestService()
+	overrideCorridor(t, "SGD", "PHP", func(c *model.Corridor) {
+		c.Enabled = false
+	})
+
+	_, err := svc.GetQuote(context.Background(), "PHP", "SGD", 5000)
+	if _, ok := err.(ErrCorridorDisabled); !ok {
+		t.Fatalf("expected ErrCorridorDisabled, got %v", err)
+	}
+}
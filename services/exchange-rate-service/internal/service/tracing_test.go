@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patteeraL/movra/services/exchange-rate-service/internal/provider"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+// for the duration of the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	original := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(original)
+		_ = tp.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// spanNamed returns the recorded span with the given name
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("expected a %q span, got %d spans", name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+func TestGetRate_TracesCacheLookupAndProviderFetch(t *testing.T) {
+	exporter := recordSpans(t)
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50, Source: "mock", FetchedAt: time.Now()}, nil
+	}
+
+	if _, err := svc.GetRate(context.Background(), "SGD", "PHP"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	root := spanNamed(t, spans, "RateService.GetRate")
+	if root.Parent.IsValid() {
+		t.Errorf("expected RateService.GetRate to be the root span")
+	}
+	for _, name := range []string{"cache.GetRate", "provider.GetRate"} {
+		child := spanNamed(t, spans, name)
+		if child.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("expected %s to be a child of RateService.GetRate", name)
+		}
+	}
+	if save := spanNamed(t, spans, "cache.SaveRate"); save.Parent.SpanID() != spanNamed(t, spans, "provider.GetRate").SpanContext.SpanID() {
+		t.Errorf("expected cache.SaveRate to be a child of provider.GetRate")
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range root.Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["currency.source"] != "SGD" || attrs["currency.target"] != "PHP" {
+		t.Errorf("expected the currency pair on the span, got %v", attrs)
+	}
+	if attrs["cache.hit"] != "false" {
+		t.Errorf("expected cache.hit=false on a cache miss, got %q", attrs["cache.hit"])
+	}
+}
+
+func TestGetRate_TracesCacheHit(t *testing.T) {
+	exporter := recordSpans(t)
+	svc, mockProvider, _ := newTestService()
+	mockProvider.GetRateFunc = func(ctx context.Context, source, target string) (*provider.Rate, error) {
+		return &provider.Rate{SourceCurrency: source, TargetCurrency: target, MidRate: 42.50, Source: "mock", FetchedAt: time.Now()}, nil
+	}
+	ctx := context.Background()
+	if _, err := svc.GetRate(ctx, "SGD", "PHP"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exporter.Reset()
+
+	if _, err := svc.GetRate(ctx, "SGD", "PHP"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	for _, span := range spans {
+		if span.Name == "provider.GetRate" {
+			t.Error("expected no provider span on a cache hit")
+		}
+	}
+	for _, attr := range spanNamed(t, spans, "RateService.GetRate").Attributes {
+		if attr.Key == "cache.hit" && !attr.Value.AsBool() {
+			t.Error("expected cache.hit=true on a cache hit")
+		}
+	}
+}
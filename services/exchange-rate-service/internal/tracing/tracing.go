@@ -0,0 +1,66 @@
+// Package tracing sets up OpenTelemetry tracing and carries trace context
+// across the HTTP and gRPC entry points
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer the service's spans come from
+const instrumentationName = "github.com/patteeraL/movra/services/exchange-rate-service"
+
+// Span attribute keys
+const (
+	AttrSourceCurrency = attribute.Key("currency.source")
+	AttrTargetCurrency = attribute.Key("currency.target")
+	AttrCacheHit       = attribute.Key("cache.hit")
+	AttrProvider       = attribute.Key("provider.name")
+	AttrSharedFetch    = attribute.Key("provider.shared_fetch")
+)
+
+// Setup exports spans to the Jaeger collector at collectorURL and installs the
+// tracer provider and W3C trace context propagation globally. The returned
+// function flushes pending spans and shuts the exporter down.
+func Setup(serviceName, collectorURL string) (func(context.Context) error, error) {
+	exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(collectorURL)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of any span in ctx. Until Setup is called
+// spans are no-ops.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// PairAttributes returns the attributes identifying a currency pair
+func PairAttributes(source, target string) []attribute.KeyValue {
+	return []attribute.KeyValue{AttrSourceCurrency.String(source), AttrTargetCurrency.String(target)}
+}
+
+// End ends span, marking it failed when err is set
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -12,12 +12,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/movra/settlement-service/internal/config"
+	"github.com/movra/settlement-service/internal/exchangerate"
 	settlementgrpc "github.com/movra/settlement-service/internal/grpc"
+	"github.com/movra/settlement-service/internal/handler"
 	"github.com/movra/settlement-service/internal/kafka"
+	"github.com/movra/settlement-service/internal/metrics"
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/pb/settlementpb"
 	"github.com/movra/settlement-service/internal/provider"
 	"github.com/movra/settlement-service/internal/repository"
 	"github.com/movra/settlement-service/internal/service"
+	"github.com/movra/settlement-service/internal/webhook"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	kafkago "github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
@@ -32,6 +40,9 @@ func main() {
 
 	// Load config
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
 
 	// Setup Redis
 	redisClient := redis.NewClient(&redis.Options{
@@ -47,20 +58,96 @@ func main() {
 		logger.Warn("Redis not available, service will work without persistence", zap.Error(err))
 	}
 
+	appMetrics := metrics.NewMetrics("settlement_service")
+
 	// Create repository
-	repo := repository.NewRedisRepository(redisClient)
+	repo := repository.NewRedisRepository(redisClient).
+		WithWriteFailureRecorder(appMetrics.RecordRedisWriteFailure)
+
+	// Index payouts stored before the listing indexes existed
+	if indexed, err := repo.BackfillIndexes(ctx); err != nil {
+		logger.Warn("Failed to backfill payout indexes; older payouts may be missing from listings", zap.Error(err))
+	} else {
+		logger.Info("Payout indexes backfilled", zap.Int("payouts", indexed))
+	}
 
 	// Create provider
 	var payoutProvider provider.PayoutProvider
 	switch cfg.ProviderType {
 	case "simulated":
-		payoutProvider = provider.NewSimulatedProvider(cfg.ProviderFailureRate, cfg.ProviderProcessingTime)
+		var simulated *provider.SimulatedProvider
+		if cfg.ProviderCompletionDelay > 0 {
+			simulated = provider.NewAsyncSimulatedProvider(cfg.ProviderFailureRate, cfg.ProviderProcessingTime, cfg.ProviderCompletionDelay)
+		} else {
+			simulated = provider.NewSimulatedProvider(cfg.ProviderFailureRate, cfg.ProviderProcessingTime)
+		}
+		simulated.WithPickupCodeFormat(cfg.PickupCodeLength, cfg.PickupCodeAlphabet)
+		if cfg.PickupCodeMaxAttempts > 0 {
+			simulated.WithPickupCodeRegistry(repo, cfg.PickupCodeMaxAttempts)
+		}
+		for _, route := range cfg.ProviderFailRoutes {
+			rule, err := provider.ParseFailureRoute(route)
+			if err != nil {
+				logger.Fatal("Invalid PROVIDER_FAIL_ROUTES", zap.Error(err))
+			}
+			simulated.WithFailureRules(rule)
+		}
+		if cfg.ProviderFailAbove > 0 {
+			simulated.WithFailureRules(provider.FailureRule{AmountAbove: cfg.ProviderFailAbove})
+		}
+		payoutProvider = simulated
 	default:
 		payoutProvider = provider.NewSimulatedProvider(10, 2*time.Second)
 	}
 
 	// Create service
 	payoutService := service.NewPayoutService(repo, payoutProvider, logger, cfg.MaxRetries)
+	payoutService.SetFailureClassification(cfg.ClassifyFailures)
+	payoutService.SetStatusBroadcaster(repo)
+	if cfg.PayoutLockTTL > 0 {
+		payoutService.SetPayoutLocker(repo, cfg.PayoutLockTTL)
+	}
+	if cfg.PickupLockoutAttempts > 0 {
+		payoutService.SetPickupAttemptLimit(repo, cfg.PickupLockoutAttempts, cfg.PickupLockoutWindow)
+	}
+	denominations := cfg.PickupDenominations
+	if len(denominations) == 0 {
+		denominations = model.PickupDenominations
+	}
+	payoutService.SetPickupDenominations(denominations, cfg.PickupResidualPolicy)
+	rates := exchangerate.NewClient(cfg.ExchangeRateURL, cfg.ExchangeRateTimeout)
+	if len(cfg.MinPayoutAmounts) > 0 {
+		payoutService.SetMinPayoutAmounts(cfg.MinPayoutAmounts)
+	} else if err := payoutService.RefreshMinPayoutAmounts(ctx, rates); err != nil {
+		logger.Warn("Payouts have no minimums until they load from the exchange rate service", zap.Error(err))
+	}
+	if cfg.QuoteCheckMode != service.QuoteCheckOff {
+		payoutService.SetQuoteCheck(rates, cfg.QuoteCheckMode, cfg.QuoteCheckTolerance)
+	}
+
+	payoutService.SetMetrics(appMetrics)
+	payoutService.SetSettlementSLAs(cfg.SettlementSLAs)
+	payoutService.SetSLABreachRecorder(func(method model.PayoutMethod, currency string) {
+		appMetrics.RecordSLABreach(string(method), currency)
+	})
+
+	// Status notification channels are called one after another in
+	// cfg.StatusNotifyOrder, each debounced within its own window after
+	// ordering so coalescing never reorders them
+	statusChannels := map[string]service.StatusNotifier{}
+	var statusWriter *kafkago.Writer
+	if cfg.KafkaTopicStatus != "" {
+		statusWriter = kafka.NewTopicWriter(cfg.KafkaBrokers, cfg.KafkaTopicStatus)
+		statusChannels[service.ChannelKafka] = kafka.NewProducer(statusWriter, cfg.KafkaWriteTimeout)
+	}
+	if cfg.StatusWebhookURL != "" {
+		statusChannels[service.ChannelWebhook] = webhook.NewNotifier(cfg.StatusWebhookURL, cfg.StatusWebhookTimeout)
+	}
+	var statusNotifier service.StatusNotifier
+	if len(statusChannels) > 0 {
+		statusNotifier = service.NewOrderedDebouncer(cfg.StatusNotifyOrder, statusChannels, cfg.StatusChannelDebounces, cfg.StatusDebounce, logger)
+		payoutService.SetStatusNotifier(statusNotifier)
+	}
 
 	// Setup Gin router for HTTP
 	gin.SetMode(gin.ReleaseMode)
@@ -75,12 +162,12 @@ func main() {
 		})
 	})
 
-	router.GET("/ready", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "ready",
-			"service": "settlement-service",
-		})
-	})
+	// Metrics endpoint
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// API endpoints
+	httpHandler := handler.NewHTTPHandler(cfg, payoutService, logger)
+	httpHandler.SetupRoutes(router)
 
 	// Create HTTP server
 	httpServer := &http.Server{
@@ -93,7 +180,7 @@ func main() {
 	// Create gRPC server
 	grpcServer := grpc.NewServer()
 	settlementServer := settlementgrpc.NewSettlementServer(payoutService, logger)
-	settlementgrpc.RegisterSettlementServiceServer(grpcServer, settlementServer)
+	settlementpb.RegisterSettlementServiceServer(grpcServer, settlementServer)
 
 	// Register health check
 	healthServer := health.NewServer()
@@ -111,6 +198,24 @@ func main() {
 		payoutService,
 		logger,
 	)
+	kafkaConsumer.SetRetry(cfg.KafkaRetryAttempts, cfg.KafkaRetryDelay)
+	var reviewWriter *kafkago.Writer
+	if cfg.KafkaTopicReview != "" {
+		reviewWriter = kafka.NewTopicWriter(cfg.KafkaBrokers, cfg.KafkaTopicReview)
+		kafkaConsumer.SetReviewQueue(reviewWriter)
+	}
+	var deadLetterWriter *kafkago.Writer
+	if cfg.KafkaTopicDLQ != "" {
+		deadLetterWriter = kafka.NewTopicWriter(cfg.KafkaBrokers, cfg.KafkaTopicDLQ)
+		kafkaConsumer.SetDeadLetterQueue(deadLetterWriter)
+	} else {
+		logger.Warn("No dead-letter queue configured; transfer.funded events failing transiently are retried until handled and hold up their partition, and the rest are dropped")
+	}
+	if cfg.KafkaStallThreshold > 0 {
+		httpHandler.SetReadinessCheck(func() error {
+			return kafkaConsumer.CheckProgress(cfg.KafkaStallThreshold)
+		})
+	}
 
 	// Start HTTP server
 	go func() {
@@ -140,6 +245,30 @@ func main() {
 		}
 	}()
 
+	// Start SLA monitor
+	monitorCtx, cancelMonitor := context.WithCancel(context.Background())
+	if cfg.SLACheckInterval > 0 {
+		go payoutService.MonitorSLAs(monitorCtx, cfg.SLACheckInterval)
+	}
+
+	// Start reconciler for payouts the provider completes asynchronously
+	reconcilerCtx, cancelReconciler := context.WithCancel(context.Background())
+	if cfg.ReconcileInterval > 0 {
+		go payoutService.RunReconciler(reconcilerCtx, cfg.ReconcileInterval)
+	}
+
+	// Start sweeper failing cash pickups that were never collected
+	expiryCtx, cancelExpiry := context.WithCancel(context.Background())
+	if cfg.PickupExpiryInterval > 0 {
+		go payoutService.RunPickupExpiry(expiryCtx, cfg.PickupExpiryInterval)
+	}
+
+	// Start reloading payout minimums from the exchange rate service's corridors
+	minPayoutCtx, cancelMinPayout := context.WithCancel(context.Background())
+	if len(cfg.MinPayoutAmounts) == 0 && cfg.MinPayoutRefreshInterval > 0 {
+		go payoutService.RunMinPayoutRefresh(minPayoutCtx, rates, cfg.MinPayoutRefreshInterval)
+	}
+
 	logger.Info("Settlement Service started",
 		zap.String("httpPort", cfg.HTTPPort),
 		zap.String("grpcPort", cfg.GRPCPort),
@@ -152,9 +281,22 @@ func main() {
 
 	logger.Info("Shutting down...")
 
-	// Graceful shutdown
+	// Graceful shutdown, letting the payout in hand finish before the
+	// consumer stops
+	if err := kafkaConsumer.DrainAndStop(cfg.KafkaDrainTimeout); err != nil {
+		logger.Warn("Kafka consumer did not drain cleanly", zap.Error(err))
+	}
 	cancelConsumer()
-	kafkaConsumer.Close()
+	cancelMonitor()
+	cancelReconciler()
+	cancelExpiry()
+	cancelMinPayout()
+	if reviewWriter != nil {
+		reviewWriter.Close()
+	}
+	if deadLetterWriter != nil {
+		deadLetterWriter.Close()
+	}
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -165,5 +307,14 @@ func main() {
 		logger.Error("HTTP shutdown error", zap.Error(err))
 	}
 
+	// Payouts have stopped changing, so send status changes still held back
+	// by the debouncer before the status writer goes away
+	if debouncer, ok := statusNotifier.(*service.StatusDebouncer); ok {
+		debouncer.Flush()
+	}
+	if statusWriter != nil {
+		statusWriter.Close()
+	}
+
 	logger.Info("Settlement Service stopped")
 }
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"github.com/shopspring/decimal"
 )
 
 // Config holds all configuration for the settlement service
@@ -18,19 +24,68 @@ type Config struct {
 	RedisDB       int
 
 	// Kafka
-	KafkaBrokers       string
-	KafkaConsumerGroup string
-	KafkaTopicFunded   string
-	KafkaTopicStatus   string
+	KafkaBrokers        string
+	KafkaConsumerGroup  string
+	KafkaTopicFunded    string
+	KafkaTopicStatus    string        // payout status events are published here (empty disables them)
+	KafkaTopicReview    string        // transfer.funded events conflicting with an existing payout go here (empty only logs them)
+	KafkaTopicDLQ       string        // transfer.funded events that fail processing go here (empty retries transient failures until handled and logs and drops the rest)
+	KafkaWriteTimeout   time.Duration // longest a status event write may hold up a payout
+	KafkaRetryAttempts  int           // attempts at a transfer.funded event failing transiently before it is dead-lettered
+	KafkaRetryDelay     time.Duration // wait between those attempts
+	KafkaDrainTimeout   time.Duration // longest shutdown waits for the transfer.funded event in hand to finish
+	KafkaStallThreshold time.Duration // /ready fails once the consumer makes no progress for this long with messages waiting (0 disables)
 
 	// Provider
-	ProviderType           string // "simulated" or future real providers
-	ProviderFailureRate    int
-	ProviderProcessingTime time.Duration
+	ProviderType            string // "simulated" or future real providers
+	ProviderFailureRate     int
+	ProviderProcessingTime  time.Duration
+	ProviderCompletionDelay time.Duration // >0 makes the simulated provider complete payouts asynchronously
+	PickupCodeMaxAttempts   int           // pickup codes colliding with an active code are regenerated up to this many attempts (0 disables the check)
+	PickupCodeLength        int           // characters in the simulated provider's pickup codes
+	PickupCodeAlphabet      string        // characters the simulated provider's pickup codes are drawn from, e.g. "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	ProviderFailRoutes      []string      // "METHOD:COUNTRY" routes the simulated provider always fails, e.g. "MOBILE_WALLET:XX" ("*" matches any)
+	ProviderFailAbove       float64       // the simulated provider always fails payouts above this amount (0 disables)
+
+	// Payout limits
+	MinPayoutAmounts         map[string]decimal.Decimal // smallest payout accepted per currency, e.g. "PHP=50,INR=100" (empty loads them from the exchange rate service's corridors)
+	MinPayoutRefreshInterval time.Duration              // how often minimums loaded from the corridors are reloaded (0 disables)
+
+	// Cash pickup
+	PickupDenominations   map[string]decimal.Decimal // smallest dispensable amount per currency (empty uses the built-in defaults)
+	PickupResidualPolicy  string                     // "refund" or "donate" the amount below the smallest denomination
+	PickupExpiryInterval  time.Duration              // how often uncollected pickups past their code's expiry are failed (0 disables)
+	PickupLockoutAttempts int                        // wrong pickup codes after which a payout can't be redeemed (0 disables the lockout)
+	PickupLockoutWindow   time.Duration              // how long wrong pickup codes are counted after the last one
 
 	// Retry
-	MaxRetries    int
-	RetryInterval time.Duration
+	MaxRetries       int
+	RetryInterval    time.Duration
+	ClassifyFailures bool          // permanent provider failures become PERMANENTLY_FAILED and are never retried
+	PayoutLockTTL    time.Duration // how long a payout's Redis lock is held while it is processed or retried (0 disables locking)
+
+	// Reconciliation
+	ReconcileInterval time.Duration // how often processing payouts are checked with the provider (0 disables)
+
+	// Status notifications
+	StatusDebounce         time.Duration            // window in which a payout's intermediate status changes are coalesced before a channel is notified
+	StatusChannelDebounces map[string]time.Duration // per-channel windows overriding StatusDebounce, e.g. "kafka=0s,webhook=500ms"
+	StatusNotifyOrder      []string                 // channels ("kafka", "webhook") are notified one after another in this order
+	StatusWebhookURL       string                   // status events are POSTed here; empty disables the webhook channel
+	StatusWebhookTimeout   time.Duration
+
+	// Settlement SLAs
+	SettlementSLAs   map[string]time.Duration // per method ("BANK_ACCOUNT") or method and currency ("BANK_ACCOUNT:IDR") time in flight before a payout breaches its SLA
+	SLACheckInterval time.Duration            // how often in-flight payouts are checked against their SLA (0 disables)
+
+	// Exchange rate service
+	ExchangeRateURL     string
+	ExchangeRateTimeout time.Duration
+	QuoteCheckMode      string          // "off", "warn" or "enforce" checking of payouts against their rate lock's quote
+	QuoteCheckTolerance decimal.Decimal // largest allowed difference from the quoted amount, in the payout currency
+
+	// Admin
+	AdminToken string // required for admin-scoped requests such as unmasked exports; empty disables them
 }
 
 // Load loads configuration from environment variables
@@ -43,20 +98,75 @@ func Load() *Config {
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       getEnvInt("REDIS_DB", 0),
 
-		KafkaBrokers:       getEnv("KAFKA_BROKERS", "localhost:9092"),
-		KafkaConsumerGroup: getEnv("KAFKA_CONSUMER_GROUP", "settlement-service"),
-		KafkaTopicFunded:   getEnv("KAFKA_TOPIC_FUNDED", "transfer.funded"),
-		KafkaTopicStatus:   getEnv("KAFKA_TOPIC_STATUS", "payout.status"),
+		KafkaBrokers:        getEnv("KAFKA_BROKERS", "localhost:9092"),
+		KafkaConsumerGroup:  getEnv("KAFKA_CONSUMER_GROUP", "settlement-service"),
+		KafkaTopicFunded:    getEnv("KAFKA_TOPIC_FUNDED", "transfer.funded"),
+		KafkaTopicStatus:    getEnv("KAFKA_TOPIC_STATUS", "payout.status"),
+		KafkaTopicReview:    getEnv("KAFKA_TOPIC_REVIEW", "transfer.funded.review"),
+		KafkaTopicDLQ:       getEnv("KAFKA_TOPIC_DLQ", "transfer.funded.dlq"),
+		KafkaWriteTimeout:   getEnvDuration("KAFKA_WRITE_TIMEOUT", 2*time.Second),
+		KafkaRetryAttempts:  getEnvInt("KAFKA_RETRY_ATTEMPTS", 3),
+		KafkaRetryDelay:     getEnvDuration("KAFKA_RETRY_DELAY", 500*time.Millisecond),
+		KafkaDrainTimeout:   getEnvDuration("KAFKA_DRAIN_TIMEOUT", 10*time.Second),
+		KafkaStallThreshold: getEnvDuration("KAFKA_STALL_THRESHOLD", 2*time.Minute),
+
+		ProviderType:            getEnv("PROVIDER_TYPE", "simulated"),
+		ProviderFailureRate:     getEnvInt("PROVIDER_FAILURE_RATE", 10),
+		ProviderProcessingTime:  getEnvDuration("PROVIDER_PROCESSING_TIME", 2*time.Second),
+		ProviderCompletionDelay: getEnvDuration("PROVIDER_COMPLETION_DELAY", 0),
+		PickupCodeMaxAttempts:   getEnvInt("PICKUP_CODE_MAX_ATTEMPTS", 5),
+		PickupCodeLength:        getEnvInt("PICKUP_CODE_LENGTH", 8),
+		PickupCodeAlphabet:      getEnv("PICKUP_CODE_ALPHABET", "0123456789"),
+		ProviderFailRoutes:      getEnvList("PROVIDER_FAIL_ROUTES", nil),
+		ProviderFailAbove:       getEnvFloat("PROVIDER_FAIL_ABOVE", 0),
+
+		MinPayoutAmounts:         getEnvDecimalMap("MIN_PAYOUT_AMOUNTS", nil),
+		MinPayoutRefreshInterval: getEnvDuration("MIN_PAYOUT_REFRESH_INTERVAL", 5*time.Minute),
+
+		PickupDenominations:   getEnvDecimalMap("PICKUP_DENOMINATIONS", nil),
+		PickupResidualPolicy:  getEnv("PICKUP_RESIDUAL_POLICY", "refund"),
+		PickupExpiryInterval:  getEnvDuration("PICKUP_EXPIRY_INTERVAL", time.Minute),
+		PickupLockoutAttempts: getEnvInt("PICKUP_LOCKOUT_ATTEMPTS", 5),
+		PickupLockoutWindow:   getEnvDuration("PICKUP_LOCKOUT_WINDOW", 24*time.Hour),
+
+		MaxRetries:       getEnvInt("MAX_RETRIES", 3),
+		RetryInterval:    getEnvDuration("RETRY_INTERVAL", 5*time.Second),
+		ClassifyFailures: getEnvBool("CLASSIFY_FAILURES", true),
+		PayoutLockTTL:    getEnvDuration("PAYOUT_LOCK_TTL", 30*time.Second),
+
+		ReconcileInterval: getEnvDuration("RECONCILE_INTERVAL", 30*time.Second),
+
+		StatusDebounce:         getEnvDuration("STATUS_DEBOUNCE", 0),
+		StatusChannelDebounces: getEnvDurationMap("STATUS_CHANNEL_DEBOUNCES", nil),
+		StatusNotifyOrder:      getEnvList("STATUS_NOTIFY_ORDER", []string{"kafka", "webhook"}),
+		StatusWebhookURL:       getEnv("STATUS_WEBHOOK_URL", ""),
+		StatusWebhookTimeout:   getEnvDuration("STATUS_WEBHOOK_TIMEOUT", 5*time.Second),
+
+		SettlementSLAs:   getEnvDurationMap("SETTLEMENT_SLAS", map[string]time.Duration{}),
+		SLACheckInterval: getEnvDuration("SLA_CHECK_INTERVAL", time.Minute),
 
-		ProviderType:           getEnv("PROVIDER_TYPE", "simulated"),
-		ProviderFailureRate:    getEnvInt("PROVIDER_FAILURE_RATE", 10),
-		ProviderProcessingTime: getEnvDuration("PROVIDER_PROCESSING_TIME", 2*time.Second),
+		ExchangeRateURL:     getEnv("EXCHANGE_RATE_URL", "http://localhost:8082"),
+		ExchangeRateTimeout: getEnvDuration("EXCHANGE_RATE_TIMEOUT", 3*time.Second),
+		QuoteCheckMode:      getEnv("QUOTE_CHECK_MODE", "off"),
+		QuoteCheckTolerance: getEnvDecimal("QUOTE_CHECK_TOLERANCE", decimal.RequireFromString("0.01")),
 
-		MaxRetries:    getEnvInt("MAX_RETRIES", 3),
-		RetryInterval: getEnvDuration("RETRY_INTERVAL", 5*time.Second),
+		AdminToken: getEnv("ADMIN_API_TOKEN", ""),
 	}
 }
 
+// Validate rejects settings whose values the service doesn't recognise, so a
+// typo fails startup instead of silently changing behaviour
+func (c *Config) Validate() error {
+	if !model.ValidResidualPolicy(c.PickupResidualPolicy) {
+		return fmt.Errorf("PICKUP_RESIDUAL_POLICY must be %q or %q, got %q",
+			model.ResidualPolicyRefund, model.ResidualPolicyDonate, c.PickupResidualPolicy)
+	}
+	if err := provider.ValidatePickupCodeFormat(c.PickupCodeLength, c.PickupCodeAlphabet); err != nil {
+		return fmt.Errorf("PICKUP_CODE_LENGTH and PICKUP_CODE_ALPHABET: %w", err)
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -73,6 +183,33 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDecimal(key string, defaultValue decimal.Decimal) decimal.Decimal {
+	if value := os.Getenv(key); value != "" {
+		if d, err := decimal.NewFromString(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if d, err := time.ParseDuration(value); err == nil {
@@ -81,3 +218,61 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getEnvDecimalMap parses "key=number" pairs separated by commas,
+// e.g. "PHP=20,IDR=1000". Malformed entries are skipped.
+func getEnvDecimalMap(key string, defaultValue map[string]decimal.Decimal) map[string]decimal.Decimal {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]decimal.Decimal)
+	for _, entry := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		if d, err := decimal.NewFromString(strings.TrimSpace(raw)); err == nil {
+			result[strings.TrimSpace(name)] = d
+		}
+	}
+	return result
+}
+
+// getEnvList parses a comma-separated list, dropping empty entries
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// getEnvDurationMap parses "key=duration" pairs separated by commas,
+// e.g. "BANK_ACCOUNT=24h,MOBILE_WALLET=1h". Malformed entries are skipped.
+func getEnvDurationMap(key string, defaultValue map[string]time.Duration) map[string]time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		if d, err := time.ParseDuration(strings.TrimSpace(raw)); err == nil {
+			result[strings.TrimSpace(name)] = d
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package exchangerate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/movra/settlement-service/internal/service"
+	"github.com/shopspring/decimal"
+)
+
+// Client calls the exchange rate service's HTTP API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the exchange rate service at baseURL
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// QuoteFromLock quotes sourceAmount against a rate lock, using the terms in
+// effect when the rate was locked. Transfers are often paid out after their
+// lock has expired, so it prices from the lock's snapshot, which the exchange
+// rate service keeps long after the lock.
+func (c *Client) QuoteFromLock(ctx context.Context, lockID string, sourceAmount decimal.Decimal) (*service.LockQuote, error) {
+	endpoint := fmt.Sprintf("%s/api/rates/locked/%s/verify?amount=%s",
+		c.baseURL, url.PathEscape(lockID), sourceAmount.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request locked quote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("rate lock %s not found", lockID)
+	case http.StatusGone:
+		return nil, fmt.Errorf("rate lock %s expired and its snapshot is no longer kept", lockID)
+	default:
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("locked quote returned %d %s: %s", resp.StatusCode, body.Error.Code, body.Error.Message)
+	}
+
+	var quote struct {
+		SourceCurrency string          `json:"sourceCurrency"`
+		TargetCurrency string          `json:"targetCurrency"`
+		TargetAmount   decimal.Decimal `json:"targetAmount"` // Fixed-scale decimal string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
+		return nil, fmt.Errorf("decode locked quote: %w", err)
+	}
+
+	return &service.LockQuote{
+		SourceCurrency: quote.SourceCurrency,
+		TargetCurrency: quote.TargetCurrency,
+		TargetAmount:   quote.TargetAmount,
+	}, nil
+}
+
+// MinPayoutAmounts returns the smallest payout accepted per target currency,
+// taken from the minimum target amounts of the enabled corridors. Payouts
+// don't carry the transfer's source currency, so a currency reached by
+// several corridors gets the smallest of their minimums.
+func (c *Client) MinPayoutAmounts(ctx context.Context) (map[string]decimal.Decimal, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/corridors", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request corridors: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("corridors returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Corridors []struct {
+			MinTargetAmount struct {
+				Currency string `json:"currency"`
+				Amount   string `json:"amount"`
+			} `json:"minTargetAmount"`
+		} `json:"corridors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode corridors: %w", err)
+	}
+
+	minimums := make(map[string]decimal.Decimal)
+	for _, corridor := range body.Corridors {
+		minimum := corridor.MinTargetAmount
+		if minimum.Currency == "" || minimum.Amount == "" {
+			continue
+		}
+		amount, err := decimal.NewFromString(minimum.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minimum target amount %q for %s", minimum.Amount, minimum.Currency)
+		}
+		if current, ok := minimums[minimum.Currency]; !ok || amount.LessThan(current) {
+			minimums[minimum.Currency] = amount
+		}
+	}
+	return minimums, nil
+}
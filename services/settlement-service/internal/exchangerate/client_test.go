@@ -0,0 +1,40 @@
+package exchangerate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_MinPayoutAmounts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/corridors" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"corridors": [
+			{"sourceCurrency": "SGD", "targetCurrency": "PHP", "minTargetAmount": {"currency": "PHP", "amount": "50.00"}},
+			{"sourceCurrency": "USD", "targetCurrency": "PHP", "minTargetAmount": {"currency": "PHP", "amount": "40.00"}},
+			{"sourceCurrency": "SGD", "targetCurrency": "IDR", "minTargetAmount": {"currency": "IDR", "amount": "20000"}},
+			{"sourceCurrency": "SGD", "targetCurrency": "USD", "minTargetAmount": {"currency": "", "amount": ""}}
+		]}`))
+	}))
+	defer server.Close()
+
+	minimums, err := NewClient(server.URL, time.Second).MinPayoutAmounts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(minimums) != 2 {
+		t.Fatalf("expected minimums for PHP and IDR, got %v", minimums)
+	}
+	if php := minimums["PHP"]; php.String() != "40" {
+		t.Errorf("expected the smallest PHP corridor minimum 40, got %s", php)
+	}
+	if idr := minimums["IDR"]; idr.String() != "20000" {
+		t.Errorf("expected IDR minimum 20000, got %s", idr)
+	}
+}
@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/movra/settlement-service/internal/pb/settlementpb"
+	"github.com/movra/settlement-service/internal/provider"
+	"github.com/movra/settlement-service/internal/repository"
+	"github.com/movra/settlement-service/internal/service"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// dialTestServer serves a settlement server on a local port and returns a
+// client for it. The generated client speaks the default proto codec, as the
+// API gateway's proto-loader clients do.
+func dialTestServer(t *testing.T) settlementpb.SettlementServiceClient {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	svc := service.NewPayoutService(repository.NewRedisRepository(client), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	settlementpb.RegisterSettlementServiceServer(grpcServer, NewSettlementServer(svc, zap.NewNop()))
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return settlementpb.NewSettlementServiceClient(conn)
+}
+
+func TestIntegration_InitiateAndListPayoutsOverConnection(t *testing.T) {
+	client := dialTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	initResp, err := client.InitiatePayout(ctx, &InitiatePayoutRequest{
+		TransferId: "transfer_grpc",
+		Amount:     &Money{Currency: "PHP", Amount: "100.00"},
+		Method:     settlementpb.PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT,
+		Recipient: &RecipientDetails{
+			Type:          settlementpb.PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT,
+			BankName:      "Test Bank",
+			AccountNumber: "1234567890",
+		},
+		Metadata: map[string]string{"orderId": "order_1"},
+	})
+	if err != nil {
+		t.Fatalf("InitiatePayout call failed: %v", err)
+	}
+	if initResp.Error != nil {
+		t.Fatalf("unexpected error response: %+v", initResp.Error)
+	}
+	payout := initResp.Payout
+	if payout.GetTransferId() != "transfer_grpc" || payout.GetAmount().GetAmount() != "100.00" || payout.GetMetadata()["orderId"] != "order_1" {
+		t.Errorf("expected the payout to survive the round trip, got %+v", payout)
+	}
+
+	getResp, err := client.GetPayout(ctx, &GetPayoutRequest{PayoutId: payout.GetId()})
+	if err != nil {
+		t.Fatalf("GetPayout call failed: %v", err)
+	}
+	if getResp.Payout.GetId() != payout.GetId() || getResp.Payout.GetRecipient().GetAccountNumber() != "1234567890" {
+		t.Errorf("expected payout %s back, got %+v", payout.GetId(), getResp.Payout)
+	}
+
+	listResp, err := client.ListPayouts(ctx, &ListPayoutsRequest{Pagination: &PaginationRequest{Page: 1, PageSize: 10}})
+	if err != nil {
+		t.Fatalf("ListPayouts call failed: %v", err)
+	}
+	if len(listResp.Payouts) != 1 || listResp.Pagination.GetTotalItems() != 1 || listResp.Pagination.GetTotalPages() != 1 {
+		t.Errorf("expected one payout on one page, got %d payouts and %+v", len(listResp.Payouts), listResp.Pagination)
+	}
+}
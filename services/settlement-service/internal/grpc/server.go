@@ -2,15 +2,15 @@ package grpc
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/pb/commonpb"
+	"github.com/movra/settlement-service/internal/pb/settlementpb"
 	"github.com/movra/settlement-service/internal/repository"
 	"github.com/movra/settlement-service/internal/service"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // SettlementServer implements the gRPC SettlementService
@@ -37,16 +37,19 @@ func (s *SettlementServer) InitiatePayout(ctx context.Context, req *InitiatePayo
 	}
 
 	payout, err := s.service.InitiatePayout(ctx, &service.InitiatePayoutRequest{
-		TransferID: req.TransferId,
-		Method:     protoMethodToModel(req.Method),
-		Amount:     req.Amount.Amount,
-		Currency:   req.Amount.Currency,
-		Recipient:  protoRecipientToModel(req.Recipient),
+		TransferID:   req.TransferId,
+		Method:       protoMethodToModel(req.Method),
+		Amount:       req.Amount.GetAmount(),
+		Currency:     req.Amount.GetCurrency(),
+		Recipient:    protoRecipientToModel(req.Recipient),
+		Metadata:     req.Metadata,
+		LockID:       req.LockId,
+		SourceAmount: req.SourceAmount,
 	})
 	if err != nil {
 		s.logger.Error("Failed to initiate payout", zap.Error(err))
 		return &InitiatePayoutResponse{
-			Error: &Error{Code: "INITIATE_FAILED", Message: err.Error()},
+			Error: &Error{Code: errorCode(err, "INITIATE_FAILED"), Message: err.Error()},
 		}, nil
 	}
 
@@ -77,37 +80,47 @@ func (s *SettlementServer) GetPayout(ctx context.Context, req *GetPayoutRequest)
 
 // ListPayouts lists payouts with optional filters
 func (s *SettlementServer) ListPayouts(ctx context.Context, req *ListPayoutsRequest) (*ListPayoutsResponse, error) {
+	pageSize := int(req.Pagination.GetPageSize())
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	pageNumber := max(int(req.Pagination.GetPage()), 1)
+
 	filter := repository.PayoutFilter{
 		Status:  protoStatusToModel(req.StatusFilter),
 		Method:  protoMethodToModel(req.MethodFilter),
 		BatchID: req.BatchId,
-		Limit:   int(req.Pagination.GetLimit()),
-		Offset:  int(req.Pagination.GetOffset()),
-	}
-
-	if filter.Limit == 0 {
-		filter.Limit = 20
+		Limit:   pageSize,
+		Offset:  (pageNumber - 1) * pageSize,
+		Cursor:  req.Cursor,
 	}
 
-	payouts, err := s.service.ListPayouts(ctx, filter)
+	page, err := s.service.ListPayoutsPage(ctx, filter)
 	if err != nil {
+		code := errorCode(err, "LIST_FAILED")
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			code = "INVALID_ARGUMENT"
+		}
 		return &ListPayoutsResponse{
-			Error: &Error{Code: "LIST_FAILED", Message: err.Error()},
+			Error: &Error{Code: code, Message: err.Error()},
 		}, nil
 	}
 
-	protoPayouts := make([]*Payout, len(payouts))
-	for i, p := range payouts {
+	protoPayouts := make([]*Payout, len(page.Payouts))
+	for i, p := range page.Payouts {
 		protoPayouts[i] = modelPayoutToProto(p)
 	}
 
 	return &ListPayoutsResponse{
 		Payouts: protoPayouts,
 		Pagination: &PaginationResponse{
-			Total:  int32(len(payouts)),
-			Limit:  int32(filter.Limit),
-			Offset: int32(filter.Offset),
+			Page:       int32(pageNumber),
+			PageSize:   int32(pageSize),
+			TotalPages: int32((page.Total + pageSize - 1) / pageSize),
+			TotalItems: int64(page.Total),
 		},
+		NextCursor: page.NextCursor,
+		TotalExact: page.TotalExact,
 	}, nil
 }
 
@@ -122,7 +135,7 @@ func (s *SettlementServer) RetryPayout(ctx context.Context, req *RetryPayoutRequ
 	payout, err := s.service.RetryPayout(ctx, req.PayoutId)
 	if err != nil {
 		return &RetryPayoutResponse{
-			Error: &Error{Code: "RETRY_FAILED", Message: err.Error()},
+			Error: &Error{Code: errorCode(err, "RETRY_FAILED"), Message: err.Error()},
 		}, nil
 	}
 
@@ -142,7 +155,7 @@ func (s *SettlementServer) CancelPayout(ctx context.Context, req *CancelPayoutRe
 	payout, err := s.service.CancelPayout(ctx, req.PayoutId, req.Reason)
 	if err != nil {
 		return &CancelPayoutResponse{
-			Error: &Error{Code: "CANCEL_FAILED", Message: err.Error()},
+			Error: &Error{Code: errorCode(err, "CANCEL_FAILED"), Message: err.Error()},
 		}, nil
 	}
 
@@ -177,8 +190,98 @@ func (s *SettlementServer) GetPickupCode(ctx context.Context, req *GetPickupCode
 	return resp, nil
 }
 
+// RedeemPickup marks a cash pickup payout as picked up on its pickup code
+func (s *SettlementServer) RedeemPickup(ctx context.Context, req *RedeemPickupRequest) (*RedeemPickupResponse, error) {
+	if req.PayoutId == "" || req.PickupCode == "" {
+		return &RedeemPickupResponse{
+			Error: &Error{Code: "INVALID_ARGUMENT", Message: "payout_id and pickup_code are required"},
+		}, nil
+	}
+
+	payout, err := s.service.RedeemPickup(ctx, req.PayoutId, req.PickupCode)
+	if err != nil {
+		return &RedeemPickupResponse{
+			Error: &Error{Code: errorCode(err, "REDEEM_FAILED"), Message: err.Error()},
+		}, nil
+	}
+
+	return &RedeemPickupResponse{
+		Payout: modelPayoutToProto(payout),
+	}, nil
+}
+
+// WatchPayout streams a payout's current status, then each status change
+// until the payout reaches a terminal status
+func (s *SettlementServer) WatchPayout(req *WatchPayoutRequest, stream SettlementService_WatchPayoutServer) error {
+	if req.PayoutId == "" {
+		return stream.Send(&WatchPayoutResponse{
+			Error: &Error{Code: "INVALID_ARGUMENT", Message: "payout_id is required"},
+		})
+	}
+
+	updates, err := s.service.WatchPayout(stream.Context(), req.PayoutId)
+	if err != nil {
+		return stream.Send(&WatchPayoutResponse{
+			Error: &Error{Code: errorCode(err, "WATCH_FAILED"), Message: err.Error()},
+		})
+	}
+
+	for payout := range updates {
+		if err := stream.Send(&WatchPayoutResponse{Payout: modelPayoutToProto(payout)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Helper functions
 
+// errorCode returns UNAVAILABLE when err is a rejected store write (e.g. Redis
+// out of memory), so callers know to retry, and fallback otherwise
+func errorCode(err error, fallback string) string {
+	var writeErr repository.ErrWriteFailed
+	if errors.As(err, &writeErr) {
+		return "UNAVAILABLE"
+	}
+	var mismatch service.ErrQuoteMismatch
+	if errors.As(err, &mismatch) {
+		return "QUOTE_MISMATCH"
+	}
+	var invalidAmount model.ErrInvalidAmount
+	if errors.As(err, &invalidAmount) {
+		return "INVALID_ARGUMENT"
+	}
+	var invalidMetadata model.ErrInvalidMetadata
+	if errors.As(err, &invalidMetadata) {
+		return "INVALID_ARGUMENT"
+	}
+	var invalidCode service.ErrInvalidPickupCode
+	if errors.As(err, &invalidCode) {
+		return "INVALID_PICKUP_CODE"
+	}
+	var expiredCode service.ErrPickupCodeExpired
+	if errors.As(err, &expiredCode) {
+		return "PICKUP_CODE_EXPIRED"
+	}
+	var pickupLocked service.ErrPickupLocked
+	if errors.As(err, &pickupLocked) {
+		return "PICKUP_LOCKED"
+	}
+	var notCashPickup service.ErrNotCashPickup
+	if errors.As(err, &notCashPickup) {
+		return "INVALID_ARGUMENT"
+	}
+	var busy service.ErrPayoutBusy
+	if errors.As(err, &busy) {
+		return "PAYOUT_BUSY"
+	}
+	var notFound service.ErrPayoutNotFound
+	if errors.As(err, &notFound) {
+		return "NOT_FOUND"
+	}
+	return fallback
+}
+
 func modelPayoutToProto(p *model.Payout) *Payout {
 	payout := &Payout{
 		Id:                p.ID,
@@ -195,13 +298,29 @@ func modelPayoutToProto(p *model.Payout) *Payout {
 		RetryCount:        int32(p.RetryCount),
 		CreatedAt:         timeToProtoTimestamp(p.CreatedAt),
 		UpdatedAt:         timeToProtoTimestamp(p.UpdatedAt),
+		Metadata:          p.Metadata,
+		LockId:            p.LockID,
+		QuoteMismatch:     p.QuoteMismatch,
 	}
 	if p.PickupExpiresAt != nil {
 		payout.PickupExpiresAt = timeToProtoTimestamp(*p.PickupExpiresAt)
 	}
+	if p.PayableAmount != "" {
+		payout.PayableAmount = &Money{Currency: p.Currency, Amount: p.PayableAmount}
+	}
+	if p.ResidualAmount != "" {
+		payout.ResidualAmount = &Money{Currency: p.Currency, Amount: p.ResidualAmount}
+		payout.ResidualPolicy = p.ResidualPolicy
+	}
 	if p.CompletedAt != nil {
 		payout.CompletedAt = timeToProtoTimestamp(*p.CompletedAt)
 	}
+	if p.SLABreachedAt != nil {
+		payout.SlaBreachedAt = timeToProtoTimestamp(*p.SLABreachedAt)
+	}
+	if p.ProviderFee != "" {
+		payout.ProviderFee = &Money{Currency: p.ProviderFeeCurrency, Amount: p.ProviderFee}
+	}
 	return payout
 }
 
@@ -241,40 +360,44 @@ func protoRecipientToModel(r *RecipientDetails) model.Recipient {
 func modelStatusToProto(s model.PayoutStatus) PayoutStatus {
 	switch s {
 	case model.PayoutStatusPending:
-		return PayoutStatus_PAYOUT_STATUS_PENDING
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_PENDING
 	case model.PayoutStatusProcessing:
-		return PayoutStatus_PAYOUT_STATUS_PROCESSING
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_PROCESSING
 	case model.PayoutStatusCompleted:
-		return PayoutStatus_PAYOUT_STATUS_COMPLETED
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_COMPLETED
 	case model.PayoutStatusFailed:
-		return PayoutStatus_PAYOUT_STATUS_FAILED
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_FAILED
 	case model.PayoutStatusCancelled:
-		return PayoutStatus_PAYOUT_STATUS_CANCELLED
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_CANCELLED
 	case model.PayoutStatusReadyForPickup:
-		return PayoutStatus_PAYOUT_STATUS_READY_FOR_PICKUP
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_READY_FOR_PICKUP
 	case model.PayoutStatusPickedUp:
-		return PayoutStatus_PAYOUT_STATUS_PICKED_UP
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_PICKED_UP
+	case model.PayoutStatusPermanentlyFailed:
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_PERMANENTLY_FAILED
 	default:
-		return PayoutStatus_PAYOUT_STATUS_UNSPECIFIED
+		return settlementpb.PayoutStatus_PAYOUT_STATUS_UNSPECIFIED
 	}
 }
 
 func protoStatusToModel(s PayoutStatus) model.PayoutStatus {
 	switch s {
-	case PayoutStatus_PAYOUT_STATUS_PENDING:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_PENDING:
 		return model.PayoutStatusPending
-	case PayoutStatus_PAYOUT_STATUS_PROCESSING:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_PROCESSING:
 		return model.PayoutStatusProcessing
-	case PayoutStatus_PAYOUT_STATUS_COMPLETED:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_COMPLETED:
 		return model.PayoutStatusCompleted
-	case PayoutStatus_PAYOUT_STATUS_FAILED:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_FAILED:
 		return model.PayoutStatusFailed
-	case PayoutStatus_PAYOUT_STATUS_CANCELLED:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_CANCELLED:
 		return model.PayoutStatusCancelled
-	case PayoutStatus_PAYOUT_STATUS_READY_FOR_PICKUP:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_READY_FOR_PICKUP:
 		return model.PayoutStatusReadyForPickup
-	case PayoutStatus_PAYOUT_STATUS_PICKED_UP:
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_PICKED_UP:
 		return model.PayoutStatusPickedUp
+	case settlementpb.PayoutStatus_PAYOUT_STATUS_PERMANENTLY_FAILED:
+		return model.PayoutStatusPermanentlyFailed
 	default:
 		return ""
 	}
@@ -283,23 +406,23 @@ func protoStatusToModel(s PayoutStatus) model.PayoutStatus {
 func modelMethodToProto(m model.PayoutMethod) PayoutMethod {
 	switch m {
 	case model.PayoutMethodBankAccount:
-		return PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT
+		return settlementpb.PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT
 	case model.PayoutMethodMobileWallet:
-		return PayoutMethod_PAYOUT_METHOD_MOBILE_WALLET
+		return settlementpb.PayoutMethod_PAYOUT_METHOD_MOBILE_WALLET
 	case model.PayoutMethodCashPickup:
-		return PayoutMethod_PAYOUT_METHOD_CASH_PICKUP
+		return settlementpb.PayoutMethod_PAYOUT_METHOD_CASH_PICKUP
 	default:
-		return PayoutMethod_PAYOUT_METHOD_UNSPECIFIED
+		return settlementpb.PayoutMethod_PAYOUT_METHOD_UNSPECIFIED
 	}
 }
 
 func protoMethodToModel(m PayoutMethod) model.PayoutMethod {
 	switch m {
-	case PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT:
+	case settlementpb.PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT:
 		return model.PayoutMethodBankAccount
-	case PayoutMethod_PAYOUT_METHOD_MOBILE_WALLET:
+	case settlementpb.PayoutMethod_PAYOUT_METHOD_MOBILE_WALLET:
 		return model.PayoutMethodMobileWallet
-	case PayoutMethod_PAYOUT_METHOD_CASH_PICKUP:
+	case settlementpb.PayoutMethod_PAYOUT_METHOD_CASH_PICKUP:
 		return model.PayoutMethodCashPickup
 	default:
 		return ""
@@ -313,192 +436,42 @@ func timeToProtoTimestamp(t time.Time) *Timestamp {
 	}
 }
 
-// Placeholder types for generated proto code
-
-type UnimplementedSettlementServiceServer struct{}
-
-func (UnimplementedSettlementServiceServer) InitiatePayout(context.Context, *InitiatePayoutRequest) (*InitiatePayoutResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method InitiatePayout not implemented")
-}
-func (UnimplementedSettlementServiceServer) GetPayout(context.Context, *GetPayoutRequest) (*GetPayoutResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetPayout not implemented")
-}
-func (UnimplementedSettlementServiceServer) ListPayouts(context.Context, *ListPayoutsRequest) (*ListPayoutsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListPayouts not implemented")
-}
-func (UnimplementedSettlementServiceServer) RetryPayout(context.Context, *RetryPayoutRequest) (*RetryPayoutResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RetryPayout not implemented")
-}
-func (UnimplementedSettlementServiceServer) CancelPayout(context.Context, *CancelPayoutRequest) (*CancelPayoutResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CancelPayout not implemented")
-}
-func (UnimplementedSettlementServiceServer) GetPickupCode(context.Context, *GetPickupCodeRequest) (*GetPickupCodeResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetPickupCode not implemented")
-}
-func (UnimplementedSettlementServiceServer) mustEmbedUnimplementedSettlementServiceServer() {}
-
-// Proto message types (placeholders)
-
-type PayoutStatus int32
+// The types below are generated from proto/settlement.proto into internal/pb;
+// the server refers to them by their unqualified names.
 
-const (
-	PayoutStatus_PAYOUT_STATUS_UNSPECIFIED      PayoutStatus = 0
-	PayoutStatus_PAYOUT_STATUS_PENDING          PayoutStatus = 1
-	PayoutStatus_PAYOUT_STATUS_PROCESSING       PayoutStatus = 2
-	PayoutStatus_PAYOUT_STATUS_COMPLETED        PayoutStatus = 3
-	PayoutStatus_PAYOUT_STATUS_FAILED           PayoutStatus = 4
-	PayoutStatus_PAYOUT_STATUS_CANCELLED        PayoutStatus = 5
-	PayoutStatus_PAYOUT_STATUS_READY_FOR_PICKUP PayoutStatus = 6
-	PayoutStatus_PAYOUT_STATUS_PICKED_UP        PayoutStatus = 7
+type (
+	UnimplementedSettlementServiceServer = settlementpb.UnimplementedSettlementServiceServer
+	SettlementService_WatchPayoutServer  = settlementpb.SettlementService_WatchPayoutServer
 )
 
-type PayoutMethod int32
-
-const (
-	PayoutMethod_PAYOUT_METHOD_UNSPECIFIED   PayoutMethod = 0
-	PayoutMethod_PAYOUT_METHOD_BANK_ACCOUNT  PayoutMethod = 1
-	PayoutMethod_PAYOUT_METHOD_MOBILE_WALLET PayoutMethod = 2
-	PayoutMethod_PAYOUT_METHOD_CASH_PICKUP   PayoutMethod = 3
+// Proto message types
+
+type (
+	PayoutStatus = settlementpb.PayoutStatus
+	PayoutMethod = settlementpb.PayoutMethod
+
+	Payout                 = settlementpb.Payout
+	RecipientDetails       = settlementpb.RecipientDetails
+	InitiatePayoutRequest  = settlementpb.InitiatePayoutRequest
+	InitiatePayoutResponse = settlementpb.InitiatePayoutResponse
+	GetPayoutRequest       = settlementpb.GetPayoutRequest
+	GetPayoutResponse      = settlementpb.GetPayoutResponse
+	ListPayoutsRequest     = settlementpb.ListPayoutsRequest
+	ListPayoutsResponse    = settlementpb.ListPayoutsResponse
+	RetryPayoutRequest     = settlementpb.RetryPayoutRequest
+	RetryPayoutResponse    = settlementpb.RetryPayoutResponse
+	CancelPayoutRequest    = settlementpb.CancelPayoutRequest
+	CancelPayoutResponse   = settlementpb.CancelPayoutResponse
+	GetPickupCodeRequest   = settlementpb.GetPickupCodeRequest
+	GetPickupCodeResponse  = settlementpb.GetPickupCodeResponse
+	RedeemPickupRequest    = settlementpb.RedeemPickupRequest
+	RedeemPickupResponse   = settlementpb.RedeemPickupResponse
+	WatchPayoutRequest     = settlementpb.WatchPayoutRequest
+	WatchPayoutResponse    = settlementpb.WatchPayoutResponse
+
+	Money              = commonpb.Money
+	Timestamp          = commonpb.Timestamp
+	Error              = commonpb.Error
+	PaginationRequest  = commonpb.PaginationRequest
+	PaginationResponse = commonpb.PaginationResponse
 )
-
-type Money struct {
-	Currency string
-	Amount   string
-}
-
-type Timestamp struct {
-	Seconds int64
-	Nanos   int32
-}
-
-type Error struct {
-	Code    string
-	Message string
-	Details map[string]string
-}
-
-type Payout struct {
-	Id                string
-	TransferId        string
-	Status            PayoutStatus
-	Method            PayoutMethod
-	Amount            *Money
-	Currency          string
-	Recipient         *RecipientDetails
-	ProviderReference string
-	BatchId           string
-	PickupCode        string
-	PickupExpiresAt   *Timestamp
-	FailureReason     string
-	RetryCount        int32
-	CreatedAt         *Timestamp
-	UpdatedAt         *Timestamp
-	CompletedAt       *Timestamp
-}
-
-type RecipientDetails struct {
-	Type           PayoutMethod
-	BankName       string
-	BankCode       string
-	AccountNumber  string
-	AccountName    string
-	WalletProvider string
-	MobileNumber   string
-	FirstName      string
-	LastName       string
-	Country        string
-}
-
-type PaginationRequest struct {
-	Limit  int32
-	Offset int32
-}
-
-func (p *PaginationRequest) GetLimit() int32 {
-	if p == nil {
-		return 0
-	}
-	return p.Limit
-}
-
-func (p *PaginationRequest) GetOffset() int32 {
-	if p == nil {
-		return 0
-	}
-	return p.Offset
-}
-
-type PaginationResponse struct {
-	Total  int32
-	Limit  int32
-	Offset int32
-}
-
-type InitiatePayoutRequest struct {
-	TransferId string
-	Amount     *Money
-	Method     PayoutMethod
-	Recipient  *RecipientDetails
-}
-
-type InitiatePayoutResponse struct {
-	Payout *Payout
-	Error  *Error
-}
-
-type GetPayoutRequest struct {
-	PayoutId string
-}
-
-type GetPayoutResponse struct {
-	Payout *Payout
-	Error  *Error
-}
-
-type ListPayoutsRequest struct {
-	Pagination   *PaginationRequest
-	StatusFilter PayoutStatus
-	MethodFilter PayoutMethod
-	BatchId      string
-}
-
-type ListPayoutsResponse struct {
-	Payouts    []*Payout
-	Pagination *PaginationResponse
-	Error      *Error
-}
-
-type RetryPayoutRequest struct {
-	PayoutId string
-}
-
-type RetryPayoutResponse struct {
-	Payout *Payout
-	Error  *Error
-}
-
-type CancelPayoutRequest struct {
-	PayoutId string
-	Reason   string
-}
-
-type CancelPayoutResponse struct {
-	Payout *Payout
-	Error  *Error
-}
-
-type GetPickupCodeRequest struct {
-	PayoutId string
-}
-
-type GetPickupCodeResponse struct {
-	PickupCode         string
-	ExpiresAt          *Timestamp
-	PickupLocationInfo string
-	Error              *Error
-}
-
-// RegisterSettlementServiceServer registers the server
-func RegisterSettlementServiceServer(s interface{}, srv *SettlementServer) {
-	fmt.Printf("Registered SettlementServiceServer: %v\n", srv)
-}
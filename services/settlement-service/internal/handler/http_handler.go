@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/movra/settlement-service/internal/config"
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	// exportFlushEvery is how many rows are written between flushes to the client
+	exportFlushEvery = 100
+
+	// exportWriteTimeout is how long each chunk of an export may take to write
+	exportWriteTimeout = 30 * time.Second
+)
+
+// exportColumns is the stable CSV column order for payout exports
+var exportColumns = []string{
+	"id", "transferId", "status", "method", "amount", "currency",
+	"recipientName", "accountNumber", "mobileNumber", "bankName",
+	"providerReference", "failureReason", "createdAt", "completedAt",
+}
+
+// HTTPHandler handles HTTP requests
+type HTTPHandler struct {
+	config        *config.Config
+	payoutService *service.PayoutService
+	logger        *zap.Logger
+
+	// readiness reports why the service can't take work, if it can't
+	readiness func() error
+}
+
+// NewHTTPHandler creates a new HTTPHandler
+func NewHTTPHandler(cfg *config.Config, payoutService *service.PayoutService, logger *zap.Logger) *HTTPHandler {
+	return &HTTPHandler{
+		config:        cfg,
+		payoutService: payoutService,
+		logger:        logger,
+	}
+}
+
+// SetReadinessCheck makes /ready report not ready while check returns an
+// error, such as the Kafka consumer stalling
+func (h *HTTPHandler) SetReadinessCheck(check func() error) {
+	h.readiness = check
+}
+
+// SetupRoutes configures the HTTP routes
+func (h *HTTPHandler) SetupRoutes(r *gin.Engine) {
+	r.GET("/ready", h.Ready)
+
+	api := r.Group("/api")
+	{
+		api.GET("/payouts/export", h.ExportPayouts)
+	}
+}
+
+// Ready returns the readiness status
+func (h *HTTPHandler) Ready(c *gin.Context) {
+	if h.readiness != nil {
+		if err := h.readiness(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "not ready",
+				"service": "settlement-service",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ready",
+		"service": "settlement-service",
+	})
+}
+
+// ExportPayouts streams payouts created within ?from= and ?to= as CSV or JSON
+// lines. Dates may be RFC 3339 timestamps or YYYY-MM-DD days; a day given for
+// ?to= includes the whole day. Recipient PII is masked unless ?unmasked=true
+// is sent with a valid X-Admin-Token header.
+func (h *HTTPHandler) ExportPayouts(c *gin.Context) {
+	from, _, err := parseExportTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp or YYYY-MM-DD date"})
+		return
+	}
+	to, dateOnly, err := parseExportTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp or YYYY-MM-DD date"})
+		return
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	unmasked := c.Query("unmasked") == "true"
+	if unmasked && !h.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "unmasked exports require an admin token"})
+		return
+	}
+
+	filename := fmt.Sprintf("payouts_%s_%s", from.Format("20060102T150405"), to.Format("20060102T150405"))
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".jsonl"))
+	}
+	c.Status(http.StatusOK)
+
+	controller := http.NewResponseController(c.Writer)
+	_ = controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	csvWriter := csv.NewWriter(c.Writer)
+	jsonEncoder := json.NewEncoder(c.Writer)
+	if format == "csv" {
+		_ = csvWriter.Write(exportColumns)
+	}
+
+	rows := 0
+	err = h.payoutService.ExportPayouts(c.Request.Context(), from, to, unmasked, func(payout *model.Payout) error {
+		if format == "csv" {
+			if err := csvWriter.Write(payoutToCSVRow(payout)); err != nil {
+				return err
+			}
+		} else if err := jsonEncoder.Encode(payout); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushEvery == 0 {
+			csvWriter.Flush()
+			c.Writer.Flush()
+			_ = controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		}
+		return csvWriter.Error()
+	})
+	csvWriter.Flush()
+	c.Writer.Flush()
+
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated export
+		h.logger.Error("Payout export failed",
+			zap.Time("from", from),
+			zap.Time("to", to),
+			zap.Int("rows", rows),
+			zap.Error(err),
+		)
+		return
+	}
+
+	h.logger.Info("Payouts exported",
+		zap.Time("from", from),
+		zap.Time("to", to),
+		zap.String("format", format),
+		zap.Bool("unmasked", unmasked),
+		zap.Int("rows", rows),
+	)
+}
+
+// isAdmin checks the X-Admin-Token header against the configured admin token
+func (h *HTTPHandler) isAdmin(c *gin.Context) bool {
+	if h.config.AdminToken == "" {
+		return false
+	}
+	token := c.GetHeader("X-Admin-Token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.config.AdminToken)) == 1
+}
+
+// parseExportTime parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC),
+// reporting whether the value was a date
+func parseExportTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+func payoutToCSVRow(p *model.Payout) []string {
+	recipientName := p.Recipient.AccountName
+	if recipientName == "" && (p.Recipient.FirstName != "" || p.Recipient.LastName != "") {
+		recipientName = p.Recipient.FirstName + " " + p.Recipient.LastName
+	}
+
+	completedAt := ""
+	if p.CompletedAt != nil {
+		completedAt = p.CompletedAt.UTC().Format(time.RFC3339)
+	}
+
+	return []string{
+		p.ID,
+		p.TransferID,
+		string(p.Status),
+		string(p.Method),
+		p.Amount,
+		p.Currency,
+		recipientName,
+		p.Recipient.AccountNumber,
+		p.Recipient.MobileNumber,
+		p.Recipient.BankName,
+		p.ProviderReference,
+		p.FailureReason,
+		p.CreatedAt.UTC().Format(time.RFC3339),
+		completedAt,
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/movra/settlement-service/internal/config"
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"github.com/movra/settlement-service/internal/repository"
+	"github.com/movra/settlement-service/internal/service"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const testAdminToken = "test-admin-token"
+
+// exportTestDays are three consecutive recent days, within the payout retention window
+var exportTestDays = func() []string {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return []string{
+		today.AddDate(0, 0, -3).Format("2006-01-02"),
+		today.AddDate(0, 0, -2).Format("2006-01-02"),
+		today.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+}()
+
+// newExportTestRouter seeds one payout at noon on each of exportTestDays
+func newExportTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	repo := repository.NewRedisRepository(client)
+
+	for i, day := range exportTestDays {
+		createdAt, _ := time.Parse("2006-01-02", day)
+		payout := &model.Payout{
+			ID:         "payout_" + day,
+			TransferID: "transfer_" + day,
+			Status:     model.PayoutStatusCompleted,
+			Method:     model.PayoutMethodBankAccount,
+			Amount:     "100.00",
+			Currency:   "PHP",
+			Recipient: model.Recipient{
+				Type:          model.PayoutMethodBankAccount,
+				BankName:      "BDO",
+				AccountNumber: "123456789" + string(rune('0'+i)),
+				AccountName:   "Juan Dela Cruz",
+			},
+			CreatedAt: createdAt.Add(12 * time.Hour),
+		}
+		if err := repo.SavePayout(context.Background(), payout); err != nil {
+			t.Fatalf("seed payout: %v", err)
+		}
+	}
+
+	svc := service.NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	router := gin.New()
+	NewHTTPHandler(&config.Config{AdminToken: testAdminToken}, svc, zap.NewNop()).SetupRoutes(router)
+	return router
+}
+
+func performRequest(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportPayouts_CSVMasksPII(t *testing.T) {
+	router := newExportTestRouter(t)
+
+	w := performRequest(router, "/api/payouts/export?from="+exportTestDays[0]+"&to="+exportTestDays[1]+"&format=csv", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected text/csv, got %q", ct)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d records", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Errorf("unexpected header: %v", records[0])
+	}
+
+	row := records[1]
+	if row[0] != "payout_"+exportTestDays[0] || records[2][0] != "payout_"+exportTestDays[1] {
+		t.Errorf("expected payouts for the first two days in order, got %s, %s", row[0], records[2][0])
+	}
+	if row[6] != "J*************" {
+		t.Errorf("expected masked recipient name, got %q", row[6])
+	}
+	if row[7] != "******7890" {
+		t.Errorf("expected masked account number, got %q", row[7])
+	}
+}
+
+func TestExportPayouts_JSONLines(t *testing.T) {
+	router := newExportTestRouter(t)
+
+	w := performRequest(router, "/api/payouts/export?from="+exportTestDays[1]+"&to="+exportTestDays[2]+"&format=json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var payout model.Payout
+		if err := json.Unmarshal(scanner.Bytes(), &payout); err != nil {
+			t.Fatalf("invalid JSON line: %v", err)
+		}
+		if !strings.HasPrefix(payout.Recipient.AccountNumber, "******") {
+			t.Errorf("expected masked account number, got %q", payout.Recipient.AccountNumber)
+		}
+		ids = append(ids, payout.ID)
+	}
+
+	if len(ids) != 2 || ids[0] != "payout_"+exportTestDays[1] || ids[1] != "payout_"+exportTestDays[2] {
+		t.Errorf("expected payouts for the last two days, got %v", ids)
+	}
+}
+
+func TestExportPayouts_UnmaskedRequiresAdminToken(t *testing.T) {
+	router := newExportTestRouter(t)
+	path := "/api/payouts/export?from=" + exportTestDays[0] + "&to=" + exportTestDays[0] + "&unmasked=true"
+
+	if w := performRequest(router, path, nil); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without admin token, got %d", w.Code)
+	}
+	if w := performRequest(router, path, map[string]string{"X-Admin-Token": "wrong"}); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 with wrong admin token, got %d", w.Code)
+	}
+
+	w := performRequest(router, path, map[string]string{"X-Admin-Token": testAdminToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with admin token, got %d: %s", w.Code, w.Body.String())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][7] != "1234567890" || records[1][6] != "Juan Dela Cruz" {
+		t.Errorf("expected unmasked recipient details, got %v", records)
+	}
+}
+
+func TestExportPayouts_InvalidRange(t *testing.T) {
+	router := newExportTestRouter(t)
+
+	for _, path := range []string{
+		"/api/payouts/export?from=yesterday&to=2024-03-02",
+		"/api/payouts/export?from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"/api/payouts/export?from=2024-03-01&to=2024-03-02&format=xml",
+	} {
+		if w := performRequest(router, path, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestReady_ReflectsReadinessCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHTTPHandler(&config.Config{}, nil, zap.NewNop())
+	router := gin.New()
+	h.SetupRoutes(router)
+
+	if w := performRequest(router, "/ready", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 without a readiness check, got %d", w.Code)
+	}
+
+	var stalled error
+	h.SetReadinessCheck(func() error { return stalled })
+	if w := performRequest(router, "/ready", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 while the check passes, got %d", w.Code)
+	}
+
+	stalled = errors.New("kafka consumer made no progress for 2m0s with 5 messages waiting")
+	w := performRequest(router, "/ready", nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once the check fails, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "no progress") {
+		t.Errorf("expected the reason in the response, got %s", w.Body.String())
+	}
+}
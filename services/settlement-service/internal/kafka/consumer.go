@@ -3,9 +3,17 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/repository"
 	"github.com/movra/settlement-service/internal/service"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
@@ -13,11 +21,14 @@ import (
 
 // TransferFundedEvent represents the event when a transfer is funded
 type TransferFundedEvent struct {
-	TransferID   string         `json:"transferId"`
-	Amount       string         `json:"amount"`
-	Currency     string         `json:"currency"`
-	PayoutMethod string         `json:"payoutMethod"`
-	Recipient    RecipientEvent `json:"recipient"`
+	TransferID   string            `json:"transferId"`
+	Amount       string            `json:"amount"`
+	Currency     string            `json:"currency"`
+	PayoutMethod string            `json:"payoutMethod"`
+	Recipient    RecipientEvent    `json:"recipient"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	LockID       string            `json:"lockId,omitempty"`       // Exchange rate lock the transfer was quoted against
+	SourceAmount string            `json:"sourceAmount,omitempty"` // Amount the sender paid, in the lock's source currency
 }
 
 // RecipientEvent represents recipient details in the event
@@ -34,11 +45,67 @@ type RecipientEvent struct {
 	Country        string `json:"country,omitempty"`
 }
 
+// MessageWriter publishes messages to a topic
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// MessageReader reads messages from a topic, committing their offsets only
+// when told to
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// statsReader is a MessageReader that reports how far behind the topic it
+// is, how many fetched messages it holds and how many fetches failed, as
+// kafka-go's Reader does
+type statsReader interface {
+	Stats() kafka.ReaderStats
+}
+
+// deadLetterRetryDelay is how long the consumer waits before publishing a
+// message to the dead-letter queue again after a failed attempt
+const deadLetterRetryDelay = time.Second
+
+// maxUnsettledRetryDelay caps the backoff between attempts at a failed
+// message that has no dead-letter queue to go to
+const maxUnsettledRetryDelay = time.Minute
+
 // Consumer consumes transfer.funded events and initiates payouts
 type Consumer struct {
-	reader  *kafka.Reader
+	reader  MessageReader
 	service *service.PayoutService
 	logger  *zap.Logger
+
+	// review receives events for a transfer that already has a payout but
+	// whose details differ from it, for an operator to resolve
+	review MessageWriter
+
+	// deadLetter receives events that couldn't be processed, with the
+	// reason, so they aren't lost
+	deadLetter      MessageWriter
+	deadLetterDelay time.Duration
+
+	// Transient failures are retried up to retryAttempts times in all,
+	// retryDelay apart, before the event is given up on
+	retryAttempts int
+	retryDelay    time.Duration
+
+	// The running Start's controls: stopFetching ends reading new messages,
+	// abort cuts short the one being handled, and stopped is closed once
+	// Start has returned
+	mu           sync.Mutex
+	stopFetching context.CancelFunc
+	abort        context.CancelFunc
+	stopped      chan struct{}
+
+	// lastProgress is when the consumer started or last read or settled a
+	// message, and lastFetchError when a fetch was last seen failing, in Unix
+	// nanoseconds
+	lastProgress   atomic.Int64
+	lastFetchError atomic.Int64
 }
 
 // NewConsumer creates a new Kafka consumer
@@ -52,41 +119,220 @@ func NewConsumer(brokers string, topic string, groupID string, svc *service.Payo
 	})
 
 	return &Consumer{
-		reader:  reader,
-		service: svc,
-		logger:  logger,
+		reader:          reader,
+		service:         svc,
+		logger:          logger,
+		deadLetterDelay: deadLetterRetryDelay,
+		retryAttempts:   1,
 	}
 }
 
-// Start starts consuming messages
+// NewTopicWriter creates a writer publishing to topic, keyed by transfer.
+// Messages are written one at a time, so batches are sent without waiting
+// for more messages to fill them.
+func NewTopicWriter(brokers string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: 10 * time.Millisecond,
+	}
+}
+
+// SetReviewQueue routes conflicting duplicate events to writer. Without a
+// review queue they are only logged.
+func (c *Consumer) SetReviewQueue(writer MessageWriter) {
+	c.review = writer
+}
+
+// SetDeadLetterQueue routes events that fail processing to writer. Without
+// a dead-letter queue an event failing transiently is retried until it is
+// handled, holding up its partition, and one failing permanently is logged
+// and dropped.
+func (c *Consumer) SetDeadLetterQueue(writer MessageWriter) {
+	c.deadLetter = writer
+}
+
+// SetRetry retries events that fail with a transient error, such as the
+// store being briefly unreachable, up to attempts times in all with delay
+// between attempts. Malformed events are never retried.
+func (c *Consumer) SetRetry(attempts int, delay time.Duration) {
+	c.retryAttempts = attempts
+	c.retryDelay = delay
+}
+
+// Start starts consuming messages. It returns when ctx is cancelled, or
+// once the message in hand is settled after DrainAndStop is called.
+//
+// A message's offset is committed only once it has been handled or
+// dead-lettered, so one that is still unsettled when the consumer stops is
+// redelivered: delivery is at least once, and handleEvent deduplicates
+// redelivered events against their transfer's payout.
 func (c *Consumer) Start(ctx context.Context) error {
 	c.logger.Info("Starting Kafka consumer")
 
+	fetchCtx, stopFetching := context.WithCancel(ctx)
+	handleCtx, abort := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	c.mu.Lock()
+	c.stopFetching, c.abort, c.stopped = stopFetching, abort, stopped
+	c.mu.Unlock()
+	c.markProgress()
+	defer func() {
+		stopFetching()
+		abort()
+		close(stopped)
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return c.reader.Close()
 		default:
-			msg, err := c.reader.ReadMessage(ctx)
+			msg, err := c.reader.FetchMessage(fetchCtx)
 			if err != nil {
-				if ctx.Err() != nil {
+				if fetchCtx.Err() != nil {
+					// Cancelled, or drained: DrainAndStop closes the reader
 					return nil
 				}
+				c.lastFetchError.Store(time.Now().UnixNano())
 				c.logger.Error("Failed to read message", zap.Error(err))
 				continue
 			}
+			c.markProgress()
 
-			if err := c.handleMessage(ctx, msg); err != nil {
-				c.logger.Error("Failed to handle message",
+			if !c.processMessage(handleCtx, msg) {
+				// Shutting down before the message was settled; it is
+				// redelivered once the consumer restarts
+				return c.reader.Close()
+			}
+			if err := c.reader.CommitMessages(handleCtx, msg); err != nil && handleCtx.Err() == nil {
+				c.logger.Error("Failed to commit message",
 					zap.String("topic", msg.Topic),
 					zap.Int64("offset", msg.Offset),
 					zap.Error(err),
 				)
+				continue
 			}
+			c.markProgress()
+		}
+	}
+}
+
+// processMessage handles msg, forwarding it to the dead-letter queue if that
+// fails. When there is none, a transient failure is handled again until it
+// succeeds and a permanent one is logged and dropped. It reports whether the
+// message is settled and its offset can be committed: false means ctx ended
+// before a failed message was settled.
+func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) bool {
+	err := c.handleMessage(ctx, msg)
+	if err == nil {
+		return true
+	}
+	if ctx.Err() != nil {
+		// Cut short by shutdown rather than failed; leave it for redelivery
+		return false
+	}
+
+	c.logger.Error("Failed to handle message",
+		zap.String("topic", msg.Topic),
+		zap.Int64("offset", msg.Offset),
+		zap.Bool("deadLettered", c.deadLetter != nil),
+		zap.Error(err),
+	)
+	if c.deadLetter == nil {
+		if !isTransient(err) {
+			c.dropFailed(msg, err)
+			return true
+		}
+		return c.handleUntilSettled(ctx, msg)
+	}
+
+	// Keep trying: committing without the message in the dead-letter queue
+	// would lose it
+	for {
+		dlqErr := c.sendToDeadLetter(ctx, msg, err)
+		if dlqErr == nil {
+			return true
+		}
+		c.logger.Error("Failed to dead-letter message, retrying",
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(dlqErr),
+		)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(c.deadLetterDelay):
 		}
 	}
 }
 
+// handleUntilSettled handles a transiently failed message again, backing off
+// between attempts, until it succeeds, fails permanently or ctx ends. With no
+// dead-letter queue to hold it, committing a message that may yet succeed
+// would lose it, so it holds up its partition instead.
+func (c *Consumer) handleUntilSettled(ctx context.Context, msg kafka.Message) bool {
+	delay := c.deadLetterDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		err := c.handleMessage(ctx, msg)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if !isTransient(err) {
+			c.dropFailed(msg, err)
+			return true
+		}
+		c.logger.Error("Failed to handle message with no dead-letter queue, retrying",
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.Duration("retryIn", delay),
+			zap.Error(err),
+		)
+		delay = min(delay*2, maxUnsettledRetryDelay)
+	}
+}
+
+// dropFailed logs a message that failed permanently with no dead-letter queue
+// to hold it; retrying it would only block its partition
+func (c *Consumer) dropFailed(msg kafka.Message, reason error) {
+	c.logger.Error("Dropping message that can't be handled, no dead-letter queue configured",
+		zap.String("topic", msg.Topic),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.ByteString("key", msg.Key),
+		zap.ByteString("value", msg.Value),
+		zap.Error(reason),
+	)
+}
+
+// sendToDeadLetter forwards the raw message to the dead-letter queue with
+// the reason it failed and where it came from
+func (c *Consumer) sendToDeadLetter(ctx context.Context, msg kafka.Message, reason error) error {
+	err := c.deadLetter.WriteMessages(ctx, kafka.Message{
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: []kafka.Header{
+			{Key: "dlq-reason", Value: []byte(reason.Error())},
+			{Key: "source-topic", Value: []byte(msg.Topic)},
+			{Key: "source-partition", Value: []byte(strconv.Itoa(msg.Partition))},
+			{Key: "source-offset", Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("publish to dead-letter queue: %w", err)
+	}
+	return nil
+}
+
 func (c *Consumer) handleMessage(ctx context.Context, msg kafka.Message) error {
 	var event TransferFundedEvent
 	if err := json.Unmarshal(msg.Value, &event); err != nil {
@@ -99,25 +345,181 @@ func (c *Consumer) handleMessage(ctx context.Context, msg kafka.Message) error {
 		zap.String("currency", event.Currency),
 	)
 
-	_, err := c.service.InitiatePayout(ctx, &service.InitiatePayoutRequest{
-		TransferID: event.TransferID,
-		Method:     parsePayoutMethod(event.PayoutMethod),
-		Amount:     event.Amount,
-		Currency:   event.Currency,
-		Recipient:  eventRecipientToModel(event.Recipient),
-	})
+	for attempt := 1; ; attempt++ {
+		err := c.handleEvent(ctx, msg, &event)
+		if err == nil || !isTransient(err) || attempt >= c.retryAttempts {
+			return err
+		}
+
+		c.logger.Warn("Transient failure handling transfer.funded event, retrying",
+			zap.String("transferId", event.TransferID),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// isTransient reports whether err looks like a temporary outage, such as a
+// rejected store write or a network failure, or a payout another replica is
+// initiating, that a retry may get past
+func isTransient(err error) bool {
+	var writeErr repository.ErrWriteFailed
+	var netErr net.Error
+	var busy service.ErrPayoutBusy
+	return errors.As(err, &writeErr) || errors.As(err, &netErr) || errors.As(err, &busy) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// handleEvent initiates the payout for a decoded event. It is safe to call
+// again after a failure: a payout saved by an earlier attempt is found and
+// the event deduplicated against it.
+func (c *Consumer) handleEvent(ctx context.Context, msg kafka.Message, event *TransferFundedEvent) error {
+	req := &service.InitiatePayoutRequest{
+		TransferID:   event.TransferID,
+		Method:       parsePayoutMethod(event.PayoutMethod),
+		Amount:       event.Amount,
+		Currency:     event.Currency,
+		Recipient:    eventRecipientToModel(event.Recipient),
+		Metadata:     event.Metadata,
+		LockID:       event.LockID,
+		SourceAmount: event.SourceAmount,
+	}
+
+	// Redelivered events are dropped, but a repeat with different details may
+	// be an upstream correction, so it is neither dropped nor applied
+	existing, err := c.service.GetPayoutByTransferID(ctx, event.TransferID)
 	if err != nil {
+		return fmt.Errorf("look up existing payout: %w", err)
+	}
+	if existing != nil {
+		conflicts := service.PayoutConflicts(existing, req)
+		if len(conflicts) == 0 {
+			c.logger.Info("Skipping duplicate transfer.funded event",
+				zap.String("transferId", event.TransferID),
+				zap.String("payoutId", existing.ID),
+			)
+			return nil
+		}
+		return c.sendForReview(ctx, msg, existing, conflicts)
+	}
+
+	if _, err := c.service.InitiatePayout(ctx, req); err != nil {
 		return fmt.Errorf("initiate payout: %w", err)
 	}
 
 	return nil
 }
 
+// sendForReview alerts on an event conflicting with the transfer's existing
+// payout and forwards it to the review queue, if one is configured
+func (c *Consumer) sendForReview(ctx context.Context, msg kafka.Message, existing *model.Payout, conflicts []string) error {
+	reason := "conflicting duplicate: " + strings.Join(conflicts, ", ")
+	c.logger.Error("transfer.funded event conflicts with existing payout",
+		zap.String("transferId", existing.TransferID),
+		zap.String("payoutId", existing.ID),
+		zap.Strings("conflicts", conflicts),
+		zap.Bool("queuedForReview", c.review != nil),
+	)
+	if c.review == nil {
+		return nil
+	}
+
+	err := c.review.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(existing.TransferID),
+		Value: msg.Value,
+		Headers: []kafka.Header{
+			{Key: "review-reason", Value: []byte(reason)},
+			{Key: "existing-payout-id", Value: []byte(existing.ID)},
+			{Key: "source-topic", Value: []byte(msg.Topic)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("queue event for review: %w", err)
+	}
+	return nil
+}
+
+// markProgress records that the consumer is making progress
+func (c *Consumer) markProgress() {
+	c.lastProgress.Store(time.Now().UnixNano())
+}
+
+// CheckProgress returns an error if the consumer isn't running, or has made
+// no progress for longer than threshold while messages are waiting on the
+// topic or in the reader's queue, or while its fetches are failing. A
+// consumer with nothing to read isn't stalled, however long it has been idle.
+//
+// The reader's lag is only updated by successful fetches, so it goes stale
+// while the broker is unreachable; the failed fetches it counts are what
+// show that case.
+func (c *Consumer) CheckProgress(threshold time.Duration) error {
+	c.mu.Lock()
+	stopped := c.stopped
+	c.mu.Unlock()
+	if stopped == nil {
+		return errors.New("kafka consumer not started")
+	}
+	select {
+	case <-stopped:
+		return errors.New("kafka consumer stopped")
+	default:
+	}
+
+	idle := time.Since(time.Unix(0, c.lastProgress.Load()))
+	if idle <= threshold {
+		return nil
+	}
+	if reader, ok := c.reader.(statsReader); ok {
+		// Counters such as Errors cover the time since Stats was last called
+		stats := reader.Stats()
+		if stats.Errors > 0 {
+			c.lastFetchError.Store(time.Now().UnixNano())
+		}
+		if waiting := stats.Lag + stats.QueueLength; waiting > 0 {
+			return fmt.Errorf("kafka consumer made no progress for %s with %d messages waiting", idle.Round(time.Second), waiting)
+		}
+	}
+	if c.lastFetchError.Load() > c.lastProgress.Load() {
+		return fmt.Errorf("kafka consumer made no progress for %s while fetches are failing", idle.Round(time.Second))
+	}
+	return nil
+}
+
 // Close closes the consumer
 func (c *Consumer) Close() error {
 	return c.reader.Close()
 }
 
+// DrainAndStop stops the consumer reading new messages, waits up to timeout
+// for the message being handled to be processed and committed, then closes
+// the consumer. A message still in hand at the timeout is cut short and left
+// uncommitted, to be redelivered.
+func (c *Consumer) DrainAndStop(timeout time.Duration) error {
+	c.mu.Lock()
+	stopFetching, abort, stopped := c.stopFetching, c.abort, c.stopped
+	c.mu.Unlock()
+	if stopped == nil {
+		// Never started
+		return c.Close()
+	}
+
+	stopFetching()
+	var err error
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		abort()
+		<-stopped
+		err = fmt.Errorf("message still in hand after %s, left for redelivery", timeout)
+	}
+	return errors.Join(err, c.Close())
+}
+
 func parsePayoutMethod(s string) model.PayoutMethod {
 	switch s {
 	case "BANK_ACCOUNT":
@@ -0,0 +1,642 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"github.com/movra/settlement-service/internal/repository"
+	"github.com/movra/settlement-service/internal/service"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// recordingWriter keeps the messages written to it
+type recordingWriter struct {
+	messages []kafka.Message
+}
+
+func (w *recordingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func newTestConsumer(t *testing.T) (*Consumer, *repository.RedisRepository, *recordingWriter) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	repo := repository.NewRedisRepository(client)
+	svc := service.NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	review := &recordingWriter{}
+	consumer := &Consumer{service: svc, logger: zap.NewNop()}
+	consumer.SetReviewQueue(review)
+	return consumer, repo, review
+}
+
+func fundedMessage(t *testing.T, event TransferFundedEvent) kafka.Message {
+	t.Helper()
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return kafka.Message{Topic: "transfer.funded", Value: value}
+}
+
+func fundedEvent() TransferFundedEvent {
+	return TransferFundedEvent{
+		TransferID:   "transfer_dup",
+		Amount:       "100.00",
+		Currency:     "PHP",
+		PayoutMethod: "BANK_ACCOUNT",
+		Recipient: RecipientEvent{
+			Type:          "BANK_ACCOUNT",
+			BankName:      "Test Bank",
+			AccountNumber: "1234567890",
+		},
+	}
+}
+
+func countPayouts(t *testing.T, repo *repository.RedisRepository) int {
+	t.Helper()
+	payouts, err := repo.ListPayouts(context.Background(), repository.PayoutFilter{})
+	if err != nil {
+		t.Fatalf("list payouts: %v", err)
+	}
+	return len(payouts)
+}
+
+func TestConsumer_IdenticalDuplicateIsDeduped(t *testing.T) {
+	consumer, repo, review := newTestConsumer(t)
+	ctx := context.Background()
+
+	first := fundedEvent()
+	if err := consumer.handleMessage(ctx, fundedMessage(t, first)); err != nil {
+		t.Fatalf("first event: %v", err)
+	}
+
+	redelivered := fundedEvent()
+	redelivered.Amount = "100" // Same amount, formatted differently
+	if err := consumer.handleMessage(ctx, fundedMessage(t, redelivered)); err != nil {
+		t.Fatalf("duplicate event: %v", err)
+	}
+
+	if n := countPayouts(t, repo); n != 1 {
+		t.Errorf("expected 1 payout, got %d", n)
+	}
+	if len(review.messages) != 0 {
+		t.Errorf("expected no events queued for review, got %d", len(review.messages))
+	}
+}
+
+func TestConsumer_ConflictingDuplicateIsQueuedForReview(t *testing.T) {
+	consumer, repo, review := newTestConsumer(t)
+	ctx := context.Background()
+
+	if err := consumer.handleMessage(ctx, fundedMessage(t, fundedEvent())); err != nil {
+		t.Fatalf("first event: %v", err)
+	}
+	existing, _ := repo.GetPayoutByTransferID(ctx, "transfer_dup")
+
+	corrected := fundedEvent()
+	corrected.Amount = "150.00"
+	corrected.Recipient.AccountNumber = "0987654321"
+	if err := consumer.handleMessage(ctx, fundedMessage(t, corrected)); err != nil {
+		t.Fatalf("conflicting event: %v", err)
+	}
+
+	if n := countPayouts(t, repo); n != 1 {
+		t.Errorf("expected the conflicting event not to create a payout, got %d payouts", n)
+	}
+	current, _ := repo.GetPayoutByTransferID(ctx, "transfer_dup")
+	if current.Amount != "100.00" || current.Recipient.AccountNumber != "1234567890" {
+		t.Errorf("expected the existing payout to be left alone, got %+v", current)
+	}
+
+	if len(review.messages) != 1 {
+		t.Fatalf("expected 1 event queued for review, got %d", len(review.messages))
+	}
+	queued := review.messages[0]
+	if string(queued.Key) != "transfer_dup" {
+		t.Errorf("expected review message keyed by transfer, got %q", queued.Key)
+	}
+	headers := map[string]string{}
+	for _, h := range queued.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers["review-reason"] != "conflicting duplicate: amount, recipient" {
+		t.Errorf("unexpected review reason %q", headers["review-reason"])
+	}
+	if headers["existing-payout-id"] != existing.ID {
+		t.Errorf("expected existing payout %s, got %q", existing.ID, headers["existing-payout-id"])
+	}
+}
+
+// fakeReader serves queued messages and records the ones committed
+type fakeReader struct {
+	messages chan kafka.Message
+
+	mu        sync.Mutex
+	committed []kafka.Message
+}
+
+func newFakeReader(msgs ...kafka.Message) *fakeReader {
+	r := &fakeReader{messages: make(chan kafka.Message, len(msgs))}
+	for _, msg := range msgs {
+		r.messages <- msg
+	}
+	return r
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+func (r *fakeReader) committedCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.committed)
+}
+
+// countingFailingWriter fails every write, counting the attempts
+type countingFailingWriter struct {
+	attempts atomic.Int32
+}
+
+func (w *countingFailingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.attempts.Add(1)
+	return errors.New("broker unavailable")
+}
+
+func malformedMessage() kafka.Message {
+	return kafka.Message{Topic: "transfer.funded", Partition: 2, Offset: 41, Key: []byte("transfer_bad"), Value: []byte(`{"transferId":`)}
+}
+
+// runConsumer starts the consumer and returns a function that stops it and
+// waits for it to return
+func runConsumer(t *testing.T, consumer *Consumer) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Start(ctx)
+	}()
+	return func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("consumer did not stop")
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsumer_MalformedMessageIsDeadLettered(t *testing.T) {
+	consumer, repo, _ := newTestConsumer(t)
+	dlq := &recordingWriter{}
+	consumer.SetDeadLetterQueue(dlq)
+	reader := newFakeReader(malformedMessage())
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	waitFor(t, "the message to be committed", func() bool { return reader.committedCount() == 1 })
+	stop()
+
+	if n := countPayouts(t, repo); n != 0 {
+		t.Errorf("expected no payout from a malformed event, got %d", n)
+	}
+	if len(dlq.messages) != 1 {
+		t.Fatalf("expected 1 dead-lettered message, got %d", len(dlq.messages))
+	}
+	dead := dlq.messages[0]
+	if string(dead.Value) != `{"transferId":` || string(dead.Key) != "transfer_bad" {
+		t.Errorf("expected the raw message to be dead-lettered, got key %q value %q", dead.Key, dead.Value)
+	}
+	headers := map[string]string{}
+	for _, h := range dead.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers["source-topic"] != "transfer.funded" || headers["source-partition"] != "2" || headers["source-offset"] != "41" {
+		t.Errorf("expected the original topic, partition and offset, got %v", headers)
+	}
+	if headers["dlq-reason"] == "" {
+		t.Error("expected the failure reason")
+	}
+}
+
+func TestConsumer_NotCommittedUntilDeadLettered(t *testing.T) {
+	consumer, _, _ := newTestConsumer(t)
+	dlq := &countingFailingWriter{}
+	consumer.SetDeadLetterQueue(dlq)
+	consumer.deadLetterDelay = time.Millisecond
+	reader := newFakeReader(malformedMessage())
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	waitFor(t, "dead-letter retries", func() bool { return dlq.attempts.Load() >= 3 })
+	stop()
+
+	if n := reader.committedCount(); n != 0 {
+		t.Errorf("expected the message not to be committed while the dead-letter queue is down, got %d commits", n)
+	}
+}
+
+func TestConsumer_ProcessedMessageIsCommitted(t *testing.T) {
+	consumer, repo, _ := newTestConsumer(t)
+	dlq := &recordingWriter{}
+	consumer.SetDeadLetterQueue(dlq)
+	reader := newFakeReader(fundedMessage(t, fundedEvent()))
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	waitFor(t, "the message to be committed", func() bool { return reader.committedCount() == 1 })
+	stop()
+
+	if n := countPayouts(t, repo); n != 1 {
+		t.Errorf("expected 1 payout, got %d", n)
+	}
+	if len(dlq.messages) != 0 {
+		t.Errorf("expected nothing dead-lettered, got %d", len(dlq.messages))
+	}
+}
+
+// flakyRepository fails the first failures payout saves as if Redis were
+// rejecting writes
+type flakyRepository struct {
+	*repository.RedisRepository
+	failures int
+	saves    int
+}
+
+func (r *flakyRepository) SavePayout(ctx context.Context, payout *model.Payout) error {
+	r.saves++
+	if r.saves <= r.failures {
+		return repository.ErrWriteFailed{Operation: "save payout", Kind: repository.WriteFailureOther, Err: errors.New("connection reset")}
+	}
+	return r.RedisRepository.SavePayout(ctx, payout)
+}
+
+func newFlakyTestConsumer(t *testing.T, failures int) (*Consumer, *flakyRepository, *recordingWriter) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	repo := &flakyRepository{RedisRepository: repository.NewRedisRepository(client), failures: failures}
+	svc := service.NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	dlq := &recordingWriter{}
+	consumer := &Consumer{service: svc, logger: zap.NewNop()}
+	consumer.SetRetry(3, time.Millisecond)
+	consumer.SetDeadLetterQueue(dlq)
+	return consumer, repo, dlq
+}
+
+func TestConsumer_RetriesTransientFailures(t *testing.T) {
+	consumer, repo, dlq := newFlakyTestConsumer(t, 2)
+
+	if !consumer.processMessage(context.Background(), fundedMessage(t, fundedEvent())) {
+		t.Fatal("expected the message to be settled")
+	}
+
+	if n := countPayouts(t, repo.RedisRepository); n != 1 {
+		t.Errorf("expected 1 payout, got %d", n)
+	}
+	if len(dlq.messages) != 0 {
+		t.Errorf("expected nothing dead-lettered, got %d", len(dlq.messages))
+	}
+}
+
+func TestConsumer_PersistentFailureIsDeadLettered(t *testing.T) {
+	consumer, repo, dlq := newFlakyTestConsumer(t, 100)
+
+	if !consumer.processMessage(context.Background(), fundedMessage(t, fundedEvent())) {
+		t.Fatal("expected the message to be settled")
+	}
+
+	if repo.saves != 3 {
+		t.Errorf("expected 3 attempts, got %d saves", repo.saves)
+	}
+	if n := countPayouts(t, repo.RedisRepository); n != 0 {
+		t.Errorf("expected no payout, got %d", n)
+	}
+	if len(dlq.messages) != 1 {
+		t.Fatalf("expected the event to be dead-lettered, got %d messages", len(dlq.messages))
+	}
+}
+
+func TestConsumer_MalformedMessageIsNotRetried(t *testing.T) {
+	consumer, repo, dlq := newFlakyTestConsumer(t, 0)
+
+	if !consumer.processMessage(context.Background(), malformedMessage()) {
+		t.Fatal("expected the message to be settled")
+	}
+
+	if repo.saves != 0 {
+		t.Errorf("expected no attempt to save a payout, got %d saves", repo.saves)
+	}
+	if len(dlq.messages) != 1 {
+		t.Errorf("expected the event to be dead-lettered straight away, got %d messages", len(dlq.messages))
+	}
+}
+
+func TestConsumer_RetryStopsWhenCancelled(t *testing.T) {
+	consumer, repo, dlq := newFlakyTestConsumer(t, 100)
+	consumer.SetRetry(3, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	if consumer.processMessage(ctx, fundedMessage(t, fundedEvent())) {
+		t.Error("expected the message to be left for redelivery")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the backoff to end with the context, took %v", elapsed)
+	}
+	if repo.saves != 1 || len(dlq.messages) != 0 {
+		t.Errorf("expected a single attempt and nothing dead-lettered, got %d saves and %d messages", repo.saves, len(dlq.messages))
+	}
+}
+
+// recoveringWriter fails every write until it recovers
+type recoveringWriter struct {
+	recovered atomic.Bool
+	attempts  atomic.Int32
+}
+
+func (w *recoveringWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.attempts.Add(1)
+	if !w.recovered.Load() {
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+func TestConsumer_FailedProcessingIsNotCommitted(t *testing.T) {
+	consumer, _, _ := newFlakyTestConsumer(t, 100)
+	dlq := &recoveringWriter{}
+	consumer.SetDeadLetterQueue(dlq)
+	consumer.deadLetterDelay = time.Millisecond
+	reader := newFakeReader(fundedMessage(t, fundedEvent()))
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	defer stop()
+
+	// Handling fails and the event can't be dead-lettered yet, so its offset
+	// stays uncommitted for redelivery
+	waitFor(t, "dead-letter attempts", func() bool { return dlq.attempts.Load() >= 3 })
+	if n := reader.committedCount(); n != 0 {
+		t.Fatalf("expected the failed message not to be committed, got %d commits", n)
+	}
+
+	dlq.recovered.Store(true)
+	waitFor(t, "the message to be committed once dead-lettered", func() bool { return reader.committedCount() == 1 })
+}
+
+func TestConsumer_FailedMessageWithoutDeadLetterQueueIsRetried(t *testing.T) {
+	// Every attempt at the event makes 3 saves, so the first attempt and
+	// the first retry fail
+	consumer, repo, _ := newFlakyTestConsumer(t, 5)
+	consumer.SetDeadLetterQueue(nil)
+	consumer.deadLetterDelay = time.Millisecond
+	reader := newFakeReader(fundedMessage(t, fundedEvent()))
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	waitFor(t, "the message to be committed once handled", func() bool { return reader.committedCount() == 1 })
+	stop()
+
+	if repo.saves <= 5 {
+		t.Errorf("expected the event to be handled again after failing, got %d saves", repo.saves)
+	}
+	if n := countPayouts(t, repo.RedisRepository); n != 1 {
+		t.Errorf("expected 1 payout, got %d", n)
+	}
+}
+
+func TestConsumer_MalformedMessageWithoutDeadLetterQueueIsDropped(t *testing.T) {
+	consumer, repo, _ := newTestConsumer(t)
+	consumer.SetDeadLetterQueue(nil)
+	consumer.deadLetterDelay = time.Millisecond
+	reader := newFakeReader(malformedMessage(), fundedMessage(t, fundedEvent()))
+	consumer.reader = reader
+
+	// The malformed event can never succeed, so it is committed rather than
+	// holding up the event behind it
+	stop := runConsumer(t, consumer)
+	waitFor(t, "both messages to be committed", func() bool { return reader.committedCount() == 2 })
+	stop()
+
+	if n := countPayouts(t, repo); n != 1 {
+		t.Errorf("expected 1 payout from the valid event, got %d", n)
+	}
+}
+
+// newSlowTestConsumer returns a consumer whose payouts each take processing
+// to go through the provider
+func newSlowTestConsumer(t *testing.T, processing time.Duration) (*Consumer, *repository.RedisRepository) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	repo := repository.NewRedisRepository(client)
+	svc := service.NewPayoutService(repo, provider.NewSimulatedProvider(0, processing), zap.NewNop(), 3)
+	return &Consumer{service: svc, logger: zap.NewNop()}, repo
+}
+
+func TestConsumer_DrainAndStopWaitsForMessageInHand(t *testing.T) {
+	consumer, repo := newSlowTestConsumer(t, 200*time.Millisecond)
+	reader := newFakeReader(fundedMessage(t, fundedEvent()))
+	consumer.reader = reader
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Start(context.Background())
+	}()
+	waitFor(t, "the payout to be initiated", func() bool { return countPayouts(t, repo) == 1 })
+
+	if err := consumer.DrainAndStop(2 * time.Second); err != nil {
+		t.Fatalf("expected a clean drain, got: %v", err)
+	}
+	if n := reader.committedCount(); n != 1 {
+		t.Errorf("expected the message in hand to be committed before stopping, got %d commits", n)
+	}
+	payout, _ := repo.GetPayoutByTransferID(context.Background(), "transfer_dup")
+	if payout.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected the payout to finish processing, got %s", payout.Status)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not stop")
+	}
+}
+
+func TestConsumer_DrainAndStopTimesOut(t *testing.T) {
+	consumer, repo := newSlowTestConsumer(t, 5*time.Second)
+	reader := newFakeReader(fundedMessage(t, fundedEvent()))
+	consumer.reader = reader
+
+	go consumer.Start(context.Background())
+	waitFor(t, "the payout to be initiated", func() bool { return countPayouts(t, repo) == 1 })
+
+	started := time.Now()
+	if err := consumer.DrainAndStop(50 * time.Millisecond); err == nil {
+		t.Error("expected an error when the message in hand doesn't finish in time")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the drain to give up after its timeout, took %s", elapsed)
+	}
+	if n := reader.committedCount(); n != 0 {
+		t.Errorf("expected the unfinished message to be left uncommitted, got %d commits", n)
+	}
+
+	// The payout cut short ends FAILED, so it can be retried, rather than
+	// PROCESSING with no provider reference to reconcile
+	payouts, err := repo.ListPayouts(context.Background(), repository.PayoutFilter{})
+	if err != nil || len(payouts) != 1 {
+		t.Fatalf("expected 1 payout, got %d, %v", len(payouts), err)
+	}
+	if payouts[0].Status != model.PayoutStatusFailed {
+		t.Errorf("expected the aborted payout to be FAILED, got %s", payouts[0].Status)
+	}
+}
+
+func TestConsumer_DrainAndStopBeforeStart(t *testing.T) {
+	consumer, _, _ := newTestConsumer(t)
+	consumer.reader = newFakeReader()
+
+	if err := consumer.DrainAndStop(time.Second); err != nil {
+		t.Errorf("expected no error stopping a consumer that never started, got: %v", err)
+	}
+}
+
+// laggingReader is a fakeReader reporting lag messages waiting on the topic,
+// queued messages already fetched, and failed fetches
+type laggingReader struct {
+	*fakeReader
+	lag    atomic.Int64
+	queued atomic.Int64
+	errors atomic.Int64
+}
+
+func (r *laggingReader) Stats() kafka.ReaderStats {
+	return kafka.ReaderStats{Lag: r.lag.Load(), QueueLength: r.queued.Load(), Errors: r.errors.Swap(0)}
+}
+
+func TestConsumer_CheckProgressDetectsStall(t *testing.T) {
+	consumer, _, _ := newTestConsumer(t)
+	reader := &laggingReader{fakeReader: newFakeReader()}
+	consumer.reader = reader
+
+	if err := consumer.CheckProgress(time.Minute); err == nil {
+		t.Error("expected a consumer that hasn't started not to be making progress")
+	}
+
+	stop := runConsumer(t, consumer)
+	defer stop()
+	waitFor(t, "the consumer to start", func() bool { return consumer.CheckProgress(time.Minute) == nil })
+
+	// Idle with nothing to read isn't a stall
+	time.Sleep(30 * time.Millisecond)
+	if err := consumer.CheckProgress(10 * time.Millisecond); err != nil {
+		t.Errorf("expected an idle consumer without lag to be healthy, got: %v", err)
+	}
+
+	// Messages waiting but none read: stalled
+	reader.lag.Store(5)
+	if err := consumer.CheckProgress(10 * time.Millisecond); err == nil {
+		t.Error("expected a consumer making no progress with lag to be stalled")
+	}
+
+	// Reading a message is progress again
+	reader.messages <- fundedMessage(t, fundedEvent())
+	waitFor(t, "the message to be committed", func() bool { return reader.committedCount() == 1 })
+	if err := consumer.CheckProgress(time.Second); err != nil {
+		t.Errorf("expected the consumer to be healthy after progress, got: %v", err)
+	}
+}
+
+func TestConsumer_CheckProgressCountsQueuedMessages(t *testing.T) {
+	consumer, _, _ := newTestConsumer(t)
+	reader := &laggingReader{fakeReader: newFakeReader()}
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	defer stop()
+	waitFor(t, "the consumer to start", func() bool { return consumer.CheckProgress(time.Minute) == nil })
+
+	// Fetched into the reader's queue, so the topic shows no lag
+	reader.queued.Store(3)
+	time.Sleep(30 * time.Millisecond)
+	if err := consumer.CheckProgress(10 * time.Millisecond); err == nil {
+		t.Error("expected a consumer making no progress with queued messages to be stalled")
+	}
+}
+
+func TestConsumer_CheckProgressDetectsFailingFetches(t *testing.T) {
+	consumer, _, _ := newTestConsumer(t)
+	reader := &laggingReader{fakeReader: newFakeReader()}
+	consumer.reader = reader
+
+	stop := runConsumer(t, consumer)
+	defer stop()
+	waitFor(t, "the consumer to start", func() bool { return consumer.CheckProgress(time.Minute) == nil })
+
+	// The broker is unreachable: fetches fail while the stale lag stays 0
+	reader.errors.Store(4)
+	time.Sleep(30 * time.Millisecond)
+	if err := consumer.CheckProgress(10 * time.Millisecond); err == nil {
+		t.Error("expected a consumer whose fetches fail to be stalled")
+	}
+	// Still stalled on the next check, though the reader's count was reset
+	if err := consumer.CheckProgress(10 * time.Millisecond); err == nil {
+		t.Error("expected the consumer to stay stalled until it makes progress")
+	}
+
+	reader.messages <- fundedMessage(t, fundedEvent())
+	waitFor(t, "the message to be committed", func() bool { return reader.committedCount() == 1 })
+	if err := consumer.CheckProgress(time.Second); err != nil {
+		t.Errorf("expected the consumer to be healthy after progress, got: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/segmentio/kafka-go"
+)
+
+// PayoutStatusEvent is published to payout.status whenever a payout changes
+// status
+type PayoutStatusEvent struct {
+	PayoutID      string    `json:"payoutId"`
+	TransferID    string    `json:"transferId"`
+	Status        string    `json:"status"`
+	FailureReason string    `json:"failureReason,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	// Metadata is the integrator-supplied metadata the payout was initiated
+	// with, echoed so consumers can correlate events with their own records
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// A cash pickup rounded to denominations pays out PayableAmount. The
+	// transfer service refunds ResidualAmount to the sender when
+	// ResidualPolicy is "refund".
+	Currency       string `json:"currency,omitempty"`
+	PayableAmount  string `json:"payableAmount,omitempty"`
+	ResidualAmount string `json:"residualAmount,omitempty"`
+	ResidualPolicy string `json:"residualPolicy,omitempty"`
+}
+
+// NewPayoutStatusEvent describes the payout's current status
+func NewPayoutStatusEvent(payout *model.Payout) PayoutStatusEvent {
+	timestamp := payout.UpdatedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return PayoutStatusEvent{
+		PayoutID:       payout.ID,
+		TransferID:     payout.TransferID,
+		Status:         string(payout.Status),
+		FailureReason:  payout.FailureReason,
+		Timestamp:      timestamp,
+		Metadata:       payout.Metadata,
+		Currency:       payout.Currency,
+		PayableAmount:  payout.PayableAmount,
+		ResidualAmount: payout.ResidualAmount,
+		ResidualPolicy: payout.ResidualPolicy,
+	}
+}
+
+// Producer publishes payout status events. It is a service.StatusNotifier, so
+// failures are logged by the payout service and never fail the payout.
+type Producer struct {
+	writer  MessageWriter
+	timeout time.Duration
+}
+
+// NewProducer creates a producer writing status events to writer. Each write
+// gives up after timeout (0 means no limit), so an unavailable broker can
+// only delay a payout by that long.
+func NewProducer(writer MessageWriter, timeout time.Duration) *Producer {
+	return &Producer{writer: writer, timeout: timeout}
+}
+
+// NotifyStatusChange publishes the payout's current status, keyed by transfer
+// so each transfer's events stay in order
+func (p *Producer) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	value, err := json.Marshal(NewPayoutStatusEvent(payout))
+	if err != nil {
+		return fmt.Errorf("marshal status event: %w", err)
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	if err := p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(payout.TransferID),
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("publish status event: %w", err)
+	}
+	return nil
+}
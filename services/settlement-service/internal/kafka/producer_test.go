@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"github.com/movra/settlement-service/internal/repository"
+	"github.com/movra/settlement-service/internal/service"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return errors.New("broker unavailable")
+}
+
+func newStatusTestService(t *testing.T, failureRate int, writer MessageWriter) *service.PayoutService {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	svc := service.NewPayoutService(repository.NewRedisRepository(client), provider.NewSimulatedProvider(failureRate, time.Millisecond), zap.NewNop(), 3)
+	svc.SetStatusNotifier(NewProducer(writer, time.Second))
+	return svc
+}
+
+func initiateTestPayout(t *testing.T, svc *service.PayoutService) *model.Payout {
+	t.Helper()
+	payout, err := svc.InitiatePayout(context.Background(), &service.InitiatePayoutRequest{
+		TransferID: "transfer_status",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "PHP",
+		Recipient: model.Recipient{
+			Type:          model.PayoutMethodBankAccount,
+			BankCode:      "BDO",
+			AccountNumber: "1234567890",
+		},
+		Metadata: map[string]string{"orderId": "ORD-42"},
+	})
+	if err != nil {
+		t.Fatalf("initiate payout: %v", err)
+	}
+	return payout
+}
+
+func statusEvents(t *testing.T, writer *recordingWriter) []PayoutStatusEvent {
+	t.Helper()
+	events := make([]PayoutStatusEvent, len(writer.messages))
+	for i, msg := range writer.messages {
+		if string(msg.Key) != "transfer_status" {
+			t.Errorf("expected status event keyed by transfer, got %q", msg.Key)
+		}
+		if err := json.Unmarshal(msg.Value, &events[i]); err != nil {
+			t.Fatalf("unmarshal status event: %v", err)
+		}
+	}
+	return events
+}
+
+func TestProducer_EmitsStatusOnSuccess(t *testing.T) {
+	writer := &recordingWriter{}
+	payout := initiateTestPayout(t, newStatusTestService(t, 0, writer))
+
+	events := statusEvents(t, writer)
+	var statuses []string
+	for _, event := range events {
+		statuses = append(statuses, event.Status)
+	}
+	want := []string{"PENDING", "PROCESSING", "COMPLETED"}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected statuses %v, got %v", want, statuses)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("expected statuses %v, got %v", want, statuses)
+			break
+		}
+	}
+
+	last := events[len(events)-1]
+	if last.PayoutID != payout.ID || last.TransferID != "transfer_status" || last.Timestamp.IsZero() {
+		t.Errorf("unexpected completed event %+v", last)
+	}
+	for _, event := range events {
+		if event.Metadata["orderId"] != "ORD-42" {
+			t.Errorf("expected the payout's metadata on the %s event, got %v", event.Status, event.Metadata)
+		}
+	}
+}
+
+func TestProducer_EmitsStatusOnFailure(t *testing.T) {
+	writer := &recordingWriter{}
+	initiateTestPayout(t, newStatusTestService(t, 100, writer))
+
+	events := statusEvents(t, writer)
+	if len(events) == 0 {
+		t.Fatal("expected status events")
+	}
+	last := events[len(events)-1]
+	if last.Status != string(model.PayoutStatusFailed) && last.Status != string(model.PayoutStatusPermanentlyFailed) {
+		t.Errorf("expected a failed status, got %s", last.Status)
+	}
+	if last.FailureReason == "" {
+		t.Error("expected the failure reason on the event")
+	}
+}
+
+func TestProducer_EmitsStatusOnCancel(t *testing.T) {
+	writer := &recordingWriter{}
+	svc := newStatusTestService(t, 100, writer)
+	payout := initiateTestPayout(t, svc)
+
+	if _, err := svc.CancelPayout(context.Background(), payout.ID, "customer request"); err != nil {
+		t.Fatalf("cancel payout: %v", err)
+	}
+
+	events := statusEvents(t, writer)
+	last := events[len(events)-1]
+	if last.Status != string(model.PayoutStatusCancelled) || last.FailureReason != "customer request" {
+		t.Errorf("expected a cancelled event with the reason, got %+v", last)
+	}
+}
+
+func TestProducer_WriteFailureDoesNotFailPayout(t *testing.T) {
+	payout := initiateTestPayout(t, newStatusTestService(t, 0, failingWriter{}))
+
+	if payout.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected payout to complete despite status events failing, got %s", payout.Status)
+	}
+}
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics holds all Prometheus metrics for the settlement service
+type Metrics struct {
+	// Payout metrics
+	PayoutsInitiatedTotal *prometheus.CounterVec
+	PayoutsCompletedTotal *prometheus.CounterVec
+	PayoutsFailedTotal    *prometheus.CounterVec
+	PayoutsCancelledTotal *prometheus.CounterVec
+	PayoutsProcessing     prometheus.Gauge
+
+	// Provider metrics
+	ProviderDuration *prometheus.HistogramVec
+
+	// SLA metrics
+	SLABreachesTotal *prometheus.CounterVec
+
+	// Cash pickup metrics
+	PickupResidualTotal *prometheus.CounterVec
+
+	// Store metrics
+	RedisWriteFailuresTotal *prometheus.CounterVec
+}
+
+// NewMetrics creates and registers all metrics
+func NewMetrics(namespace string) *Metrics {
+	if namespace == "" {
+		namespace = "settlement_service"
+	}
+
+	return &Metrics{
+		PayoutsInitiatedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "payouts_initiated_total",
+				Help:      "Total number of payouts created",
+			},
+			[]string{"method"},
+		),
+
+		PayoutsCompletedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "payouts_completed_total",
+				Help:      "Total number of payouts completed, or picked up for cash pickup",
+			},
+			[]string{"method"},
+		),
+
+		PayoutsFailedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "payouts_failed_total",
+				Help:      "Total number of payout attempts that failed, including permanent failures",
+			},
+			[]string{"method"},
+		),
+
+		PayoutsCancelledTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "payouts_cancelled_total",
+				Help:      "Total number of payouts cancelled",
+			},
+			[]string{"method"},
+		),
+
+		PayoutsProcessing: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "payouts_processing",
+				Help:      "Number of payouts currently PROCESSING with the provider",
+			},
+		),
+
+		ProviderDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "provider_processing_duration_seconds",
+				Help:      "Duration of provider payout calls in seconds",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"method"},
+		),
+
+		SLABreachesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "sla_breaches_total",
+				Help:      "Total number of payouts still in flight past their settlement SLA",
+			},
+			[]string{"method", "currency"},
+		),
+
+		PickupResidualTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "pickup_residual_total",
+				Help:      "Total amount below the smallest denomination withheld from cash pickups, by residual policy",
+			},
+			[]string{"currency", "policy"},
+		),
+
+		RedisWriteFailuresTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "redis_write_failures_total",
+				Help:      "Total number of Redis writes that failed",
+			},
+			[]string{"operation", "type"}, // type is "oom", "readonly" or "error"
+		),
+	}
+}
+
+// RecordPickupResidual records the residual withheld from a cash pickup that
+// is ready to collect, to be refunded or donated per policy
+func (m *Metrics) RecordPickupResidual(currency, policy string, amount float64) {
+	m.PickupResidualTotal.WithLabelValues(currency, policy).Add(amount)
+}
+
+// RecordSLABreach records a payout breaching its settlement SLA
+func (m *Metrics) RecordSLABreach(method, currency string) {
+	m.SLABreachesTotal.WithLabelValues(method, currency).Inc()
+}
+
+// RecordPayoutInitiated records a payout being created
+func (m *Metrics) RecordPayoutInitiated(method string) {
+	m.PayoutsInitiatedTotal.WithLabelValues(method).Inc()
+}
+
+// RecordPayoutCompleted records a payout completing
+func (m *Metrics) RecordPayoutCompleted(method string) {
+	m.PayoutsCompletedTotal.WithLabelValues(method).Inc()
+}
+
+// RecordPayoutFailed records a payout attempt failing
+func (m *Metrics) RecordPayoutFailed(method string) {
+	m.PayoutsFailedTotal.WithLabelValues(method).Inc()
+}
+
+// RecordPayoutCancelled records a payout being cancelled
+func (m *Metrics) RecordPayoutCancelled(method string) {
+	m.PayoutsCancelledTotal.WithLabelValues(method).Inc()
+}
+
+// RecordProcessingStarted records a payout moving to PROCESSING
+func (m *Metrics) RecordProcessingStarted() {
+	m.PayoutsProcessing.Inc()
+}
+
+// RecordProcessingEnded records a payout leaving PROCESSING
+func (m *Metrics) RecordProcessingEnded() {
+	m.PayoutsProcessing.Dec()
+}
+
+// SetPayoutsProcessing sets the number of PROCESSING payouts, e.g. from a
+// count of the stored payouts
+func (m *Metrics) SetPayoutsProcessing(count int) {
+	m.PayoutsProcessing.Set(float64(count))
+}
+
+// RecordProviderDuration records how long a provider payout call took
+func (m *Metrics) RecordProviderDuration(method string, duration time.Duration) {
+	m.ProviderDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// RecordRedisWriteFailure records a failed Redis write
+func (m *Metrics) RecordRedisWriteFailure(operation, kind string) {
+	m.RedisWriteFailuresTotal.WithLabelValues(operation, kind).Inc()
+}
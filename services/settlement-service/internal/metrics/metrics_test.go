@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var (
+	testMetricsOnce sync.Once
+	testMetrics     *Metrics
+)
+
+// newTestMetrics returns a shared Metrics, since metrics register with the
+// default registry and can only be created once per process
+func newTestMetrics() *Metrics {
+	testMetricsOnce.Do(func() {
+		testMetrics = NewMetrics("test")
+	})
+	return testMetrics
+}
+
+func TestRecordSLABreach(t *testing.T) {
+	m := newTestMetrics()
+	counter := m.SLABreachesTotal.WithLabelValues("BANK_ACCOUNT", "PHP")
+	before := testutil.ToFloat64(counter)
+
+	m.RecordSLABreach("BANK_ACCOUNT", "PHP")
+	m.RecordSLABreach("BANK_ACCOUNT", "PHP")
+
+	if got := testutil.ToFloat64(counter) - before; got != 2 {
+		t.Errorf("expected SLA breaches to increase by 2, got %f", got)
+	}
+}
+
+func TestPayoutsProcessingGauge(t *testing.T) {
+	m := newTestMetrics()
+	m.SetPayoutsProcessing(3)
+
+	m.RecordProcessingStarted()
+	m.RecordProcessingEnded()
+	m.RecordProcessingEnded()
+
+	if got := testutil.ToFloat64(m.PayoutsProcessing); got != 2 {
+		t.Errorf("expected 2 payouts processing, got %f", got)
+	}
+}
@@ -1,22 +1,71 @@
 package model
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // PayoutStatus represents the status of a payout
 type PayoutStatus string
 
 const (
-	PayoutStatusPending        PayoutStatus = "PENDING"
-	PayoutStatusProcessing     PayoutStatus = "PROCESSING"
-	PayoutStatusCompleted      PayoutStatus = "COMPLETED"
-	PayoutStatusFailed         PayoutStatus = "FAILED"
-	PayoutStatusCancelled      PayoutStatus = "CANCELLED"
-	PayoutStatusReadyForPickup PayoutStatus = "READY_FOR_PICKUP"
-	PayoutStatusPickedUp       PayoutStatus = "PICKED_UP"
+	PayoutStatusPending           PayoutStatus = "PENDING"
+	PayoutStatusProcessing        PayoutStatus = "PROCESSING"
+	PayoutStatusCompleted         PayoutStatus = "COMPLETED"
+	PayoutStatusFailed            PayoutStatus = "FAILED"
+	PayoutStatusPermanentlyFailed PayoutStatus = "PERMANENTLY_FAILED" // Failed in a way retrying can't fix
+	PayoutStatusCancelled         PayoutStatus = "CANCELLED"
+	PayoutStatusReadyForPickup    PayoutStatus = "READY_FOR_PICKUP"
+	PayoutStatusPickedUp          PayoutStatus = "PICKED_UP"
 )
 
+// IsTerminal reports whether a payout in this status has finished processing
+func (s PayoutStatus) IsTerminal() bool {
+	switch s {
+	case PayoutStatusCompleted, PayoutStatusFailed, PayoutStatusPermanentlyFailed, PayoutStatusCancelled, PayoutStatusPickedUp:
+		return true
+	}
+	return false
+}
+
+// payoutTransitions lists the statuses a payout may move to from each status.
+// Completed, picked up and cancelled payouts never change again; a failed
+// payout can only be retried or cancelled. A cash pickup that isn't collected
+// before its code expires fails.
+var payoutTransitions = map[PayoutStatus][]PayoutStatus{
+	PayoutStatusPending:           {PayoutStatusProcessing, PayoutStatusCancelled},
+	PayoutStatusProcessing:        {PayoutStatusCompleted, PayoutStatusFailed, PayoutStatusPermanentlyFailed, PayoutStatusReadyForPickup},
+	PayoutStatusReadyForPickup:    {PayoutStatusPickedUp, PayoutStatusFailed},
+	PayoutStatusFailed:            {PayoutStatusPending, PayoutStatusCancelled},
+	PayoutStatusPermanentlyFailed: {PayoutStatusCancelled},
+}
+
+// CanTransition reports whether a payout may move from one status to another
+func CanTransition(from, to PayoutStatus) bool {
+	for _, allowed := range payoutTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidTransition is returned when a status change isn't allowed from
+// the payout's current status
+type ErrInvalidTransition struct {
+	PayoutID string
+	From     PayoutStatus
+	To       PayoutStatus
+}
+
+func (e ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("payout %s cannot move from %s to %s", e.PayoutID, e.From, e.To)
+}
+
 // PayoutMethod represents the payout method
 type PayoutMethod string
 
@@ -28,22 +77,236 @@ const (
 
 // Payout represents a payout record
 type Payout struct {
-	ID                 string       `json:"id"`
-	TransferID         string       `json:"transferId"`
-	Status             PayoutStatus `json:"status"`
-	Method             PayoutMethod `json:"method"`
-	Amount             string       `json:"amount"`
-	Currency           string       `json:"currency"`
-	Recipient          Recipient    `json:"recipient"`
-	ProviderReference  string       `json:"providerReference,omitempty"`
-	BatchID            string       `json:"batchId,omitempty"`
-	PickupCode         string       `json:"pickupCode,omitempty"`
-	PickupExpiresAt    *time.Time   `json:"pickupExpiresAt,omitempty"`
-	FailureReason      string       `json:"failureReason,omitempty"`
-	RetryCount         int          `json:"retryCount"`
-	CreatedAt          time.Time    `json:"createdAt"`
-	UpdatedAt          time.Time    `json:"updatedAt"`
-	CompletedAt        *time.Time   `json:"completedAt,omitempty"`
+	ID                string            `json:"id"`
+	TransferID        string            `json:"transferId"`
+	Status            PayoutStatus      `json:"status"`
+	Method            PayoutMethod      `json:"method"`
+	Amount            string            `json:"amount"`
+	Currency          string            `json:"currency"`
+	Recipient         Recipient         `json:"recipient"`
+	ProviderReference string            `json:"providerReference,omitempty"`
+	BatchID           string            `json:"batchId,omitempty"`
+	PickupCode        string            `json:"pickupCode,omitempty"`
+	PickupExpiresAt   *time.Time        `json:"pickupExpiresAt,omitempty"`
+	FailureReason     string            `json:"failureReason,omitempty"`
+	RetryCount        int               `json:"retryCount"`
+	CreatedAt         time.Time         `json:"createdAt"`
+	UpdatedAt         time.Time         `json:"updatedAt"`
+	CompletedAt       *time.Time        `json:"completedAt,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+
+	// Cash pickup agents can only dispense whole notes, so the payable
+	// amount is Amount rounded down to a denomination and the residual is
+	// handled per ResidualPolicy
+	PayableAmount  string `json:"payableAmount,omitempty"`
+	ResidualAmount string `json:"residualAmount,omitempty"`
+	ResidualPolicy string `json:"residualPolicy,omitempty"`
+
+	// LockID is the exchange rate lock the transfer was quoted against.
+	// QuoteMismatch explains why the amount didn't match the lock's quote,
+	// when the quote check only warns.
+	LockID        string `json:"lockId,omitempty"`
+	QuoteMismatch string `json:"quoteMismatch,omitempty"`
+
+	// ProviderFee is what the provider charged for the payout, in
+	// ProviderFeeCurrency, as reported when it was processed
+	ProviderFee         string `json:"providerFee,omitempty"`
+	ProviderFeeCurrency string `json:"providerFeeCurrency,omitempty"`
+
+	// SLABreachedAt is when the payout was found still in flight past its
+	// method's settlement SLA
+	SLABreachedAt *time.Time `json:"slaBreachedAt,omitempty"`
+
+	// Version counts the saves of the payout, so a save based on a stale
+	// copy can be rejected
+	Version int `json:"version"`
+}
+
+// EstimatedCompletion is how long payouts typically take to settle per
+// method, and the default settlement SLA. Cash pickup counts until the cash is
+// ready to collect, not until it is collected.
+var EstimatedCompletion = map[PayoutMethod]time.Duration{
+	PayoutMethodBankAccount:  24 * time.Hour,
+	PayoutMethodMobileWallet: 15 * time.Minute,
+	PayoutMethodCashPickup:   time.Hour,
+}
+
+// Residual policies for the part of a cash pickup amount that can't be
+// dispensed: refunded to the sender, or kept as a donation
+const (
+	ResidualPolicyRefund = "refund"
+	ResidualPolicyDonate = "donate"
+)
+
+// ValidResidualPolicy reports whether policy is a known residual policy
+func ValidResidualPolicy(policy string) bool {
+	return policy == ResidualPolicyRefund || policy == ResidualPolicyDonate
+}
+
+// DisbursedAmount is the amount the provider pays out: the payable amount of
+// a cash pickup rounded to denominations, or Amount otherwise
+func (p *Payout) DisbursedAmount() string {
+	if p.PayableAmount != "" {
+		return p.PayableAmount
+	}
+	return p.Amount
+}
+
+// Metadata limits keep integrator-supplied metadata small enough to store
+// and echo on every lookup and status event
+const (
+	MaxMetadataEntries     = 20
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 256
+)
+
+// ErrInvalidMetadata is returned for integrator-supplied metadata that
+// exceeds the size limits
+type ErrInvalidMetadata struct {
+	Reason string
+}
+
+func (e ErrInvalidMetadata) Error() string {
+	return "invalid metadata: " + e.Reason
+}
+
+// ValidateMetadata checks integrator-supplied metadata against the size
+// limits, returning ErrInvalidMetadata if it exceeds them
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return ErrInvalidMetadata{Reason: fmt.Sprintf("%d entries, maximum is %d", len(metadata), MaxMetadataEntries)}
+	}
+	for key, value := range metadata {
+		if key == "" {
+			return ErrInvalidMetadata{Reason: "keys must not be empty"}
+		}
+		if len(key) > MaxMetadataKeyLength {
+			return ErrInvalidMetadata{Reason: fmt.Sprintf("key %q exceeds %d characters", key, MaxMetadataKeyLength)}
+		}
+		if len(value) > MaxMetadataValueLength {
+			return ErrInvalidMetadata{Reason: fmt.Sprintf("value for %q exceeds %d characters", key, MaxMetadataValueLength)}
+		}
+	}
+	return nil
+}
+
+// AmountPlaces is the number of decimal places amounts are kept to in
+// currencies without an entry in CurrencyPrecision
+const AmountPlaces = 2
+
+// CurrencyPrecision is the number of decimal places amounts are kept to in
+// currencies that don't use AmountPlaces. It mirrors the exchange rate
+// service's currency precision.
+var CurrencyPrecision = map[string]int{
+	"IDR": 0,
+	"VND": 0,
+	"JPY": 0,
+	"KRW": 0,
+}
+
+// Precision returns the number of decimal places amounts in currency are kept to
+func Precision(currency string) int {
+	if places, ok := CurrencyPrecision[currency]; ok {
+		return places
+	}
+	return AmountPlaces
+}
+
+// FormatAmount formats value to currency's precision
+func FormatAmount(value decimal.Decimal, currency string) string {
+	return value.StringFixed(int32(Precision(currency)))
+}
+
+// ErrInvalidAmount is returned for a payout amount that isn't a positive
+// decimal number within its currency's precision
+type ErrInvalidAmount struct {
+	Amount   string
+	Currency string
+	Reason   string
+}
+
+func (e ErrInvalidAmount) Error() string {
+	return fmt.Sprintf("invalid amount %q %s: %s", e.Amount, e.Currency, e.Reason)
+}
+
+// amountPattern matches plain decimal numbers, without a sign or exponent
+var amountPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// NormalizeAmount parses a payout amount and returns it in canonical form:
+// no leading zeros and exactly the currency's number of decimal places, e.g.
+// "0100.5" SGD becomes "100.50". Amounts that aren't positive numbers, or
+// need more decimal places than the currency has, are rejected with
+// ErrInvalidAmount rather than rounded.
+func NormalizeAmount(amount, currency string) (string, error) {
+	if !amountPattern.MatchString(amount) {
+		reason := "not a decimal number"
+		if strings.HasPrefix(amount, "-") {
+			reason = "must be positive"
+		}
+		return "", ErrInvalidAmount{Amount: amount, Currency: currency, Reason: reason}
+	}
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	whole = strings.TrimLeft(whole, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if whole == "" && fraction == "" {
+		return "", ErrInvalidAmount{Amount: amount, Currency: currency, Reason: "must be positive"}
+	}
+
+	places := Precision(currency)
+	if len(fraction) > places {
+		return "", ErrInvalidAmount{Amount: amount, Currency: currency, Reason: fmt.Sprintf("%s amounts have at most %d decimal places", currency, places)}
+	}
+
+	if whole == "" {
+		whole = "0"
+	}
+	if places == 0 {
+		return whole, nil
+	}
+	return whole + "." + fraction + strings.Repeat("0", places-len(fraction)), nil
+}
+
+// ValidateMinPayoutAmount checks that a payout meets the minimum for its
+// currency in minimums. Currencies without a minimum are accepted.
+func ValidateMinPayoutAmount(amount, currency string, minimums map[string]decimal.Decimal) error {
+	minimum, ok := minimums[currency]
+	if !ok {
+		return nil
+	}
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", amount)
+	}
+	if value.LessThan(minimum) {
+		return fmt.Errorf("amount %s %s is below the minimum payout of %s %s", amount, currency, FormatAmount(minimum, currency), currency)
+	}
+	return nil
+}
+
+// PickupDenominations is the smallest amount cash pickup agents can dispense
+// per currency, e.g. the smallest note in circulation. Cash pickup amounts are
+// rounded down to a multiple of it.
+var PickupDenominations = map[string]decimal.Decimal{
+	"PHP": decimal.NewFromInt(10),
+	"INR": decimal.NewFromInt(10),
+	"IDR": decimal.NewFromInt(1000),
+	"USD": decimal.NewFromInt(1),
+}
+
+// RoundToDenomination splits amount into the largest multiple of denomination
+// not above it and the residual. A denomination <= 0 leaves the amount whole.
+func RoundToDenomination(amount string, denomination decimal.Decimal) (payable, residual decimal.Decimal, err error) {
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("invalid amount %q", amount)
+	}
+	if !denomination.IsPositive() {
+		return value, decimal.Zero, nil
+	}
+
+	residual = value.Mod(denomination)
+	return value.Sub(residual), residual, nil
 }
 
 // Recipient holds recipient details
@@ -60,15 +323,71 @@ type Recipient struct {
 	Country        string       `json:"country,omitempty"`
 }
 
+// TransitionTo moves the payout to status, returning ErrInvalidTransition if
+// CanTransition doesn't allow it. Staying in the same status is a no-op.
+func (p *Payout) TransitionTo(status PayoutStatus) error {
+	if p.Status == status {
+		return nil
+	}
+	if !CanTransition(p.Status, status) {
+		return ErrInvalidTransition{PayoutID: p.ID, From: p.Status, To: status}
+	}
+	p.Status = status
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// MaskPII returns a copy of the payout with recipient details and the pickup
+// code masked, for exports shared outside operations
+func (p *Payout) MaskPII() *Payout {
+	masked := *p
+	masked.Recipient = Recipient{
+		Type:           p.Recipient.Type,
+		BankName:       p.Recipient.BankName,
+		BankCode:       p.Recipient.BankCode,
+		AccountNumber:  maskValue(p.Recipient.AccountNumber, 4),
+		AccountName:    maskName(p.Recipient.AccountName),
+		WalletProvider: p.Recipient.WalletProvider,
+		MobileNumber:   maskValue(p.Recipient.MobileNumber, 4),
+		FirstName:      maskName(p.Recipient.FirstName),
+		LastName:       maskName(p.Recipient.LastName),
+		Country:        p.Recipient.Country,
+	}
+	masked.PickupCode = maskValue(p.PickupCode, 0)
+	return &masked
+}
+
+// maskValue replaces all but the last keepLast characters with '*'
+func maskValue(value string, keepLast int) string {
+	runes := []rune(value)
+	if len(runes) <= keepLast {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-keepLast) + string(runes[len(runes)-keepLast:])
+}
+
+// maskName keeps only the first character of a name
+func maskName(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+}
+
 // PayoutBatch represents a batch of payouts
 type PayoutBatch struct {
-	ID              string       `json:"id"`
-	Method          PayoutMethod `json:"method"`
-	Currency        string       `json:"currency"`
-	TotalPayouts    int          `json:"totalPayouts"`
-	CompletedPayouts int         `json:"completedPayouts"`
-	FailedPayouts   int          `json:"failedPayouts"`
-	TotalAmount     string       `json:"totalAmount"`
-	CreatedAt       time.Time    `json:"createdAt"`
-	CompletedAt     *time.Time   `json:"completedAt,omitempty"`
+	ID               string       `json:"id"`
+	Method           PayoutMethod `json:"method"`
+	Currency         string       `json:"currency"`
+	TotalPayouts     int          `json:"totalPayouts"`
+	CompletedPayouts int          `json:"completedPayouts"`
+	FailedPayouts    int          `json:"failedPayouts"`
+	// FailedInitiations counts the requests that never became a payout in
+	// the batch, either because initiating failed or because the transfer
+	// already had a payout
+	FailedInitiations int        `json:"failedInitiations"`
+	TotalAmount       string     `json:"totalAmount"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	CompletedAt       *time.Time `json:"completedAt,omitempty"`
 }
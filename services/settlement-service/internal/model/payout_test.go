@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPayout_TransitionTo_LegalPath(t *testing.T) {
+	payout := &Payout{ID: "payout_1", Status: PayoutStatusPending}
+
+	for _, status := range []PayoutStatus{PayoutStatusProcessing, PayoutStatusCompleted} {
+		if err := payout.TransitionTo(status); err != nil {
+			t.Fatalf("expected %s to be allowed, got %v", status, err)
+		}
+		if payout.Status != status {
+			t.Errorf("expected status %s, got %s", status, payout.Status)
+		}
+	}
+}
+
+func TestPayout_TransitionTo_Rejected(t *testing.T) {
+	tests := []struct {
+		from PayoutStatus
+		to   PayoutStatus
+	}{
+		{PayoutStatusCompleted, PayoutStatusCancelled},
+		{PayoutStatusCompleted, PayoutStatusProcessing},
+		{PayoutStatusCancelled, PayoutStatusPending},
+		{PayoutStatusPending, PayoutStatusCompleted},
+		{PayoutStatusReadyForPickup, PayoutStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		payout := &Payout{ID: "payout_1", Status: tt.from}
+		err := payout.TransitionTo(tt.to)
+
+		var transitionErr ErrInvalidTransition
+		if !errors.As(err, &transitionErr) {
+			t.Errorf("%s -> %s: expected ErrInvalidTransition, got %v", tt.from, tt.to, err)
+			continue
+		}
+		if payout.Status != tt.from {
+			t.Errorf("%s -> %s: expected the status to be unchanged, got %s", tt.from, tt.to, payout.Status)
+		}
+	}
+}
+
+func TestRoundToDenomination(t *testing.T) {
+	tests := []struct {
+		amount, denomination string
+		payable, residual    string
+	}{
+		{"55.75", "10", "50", "5.75"},
+		{"0.3", "0.1", "0.3", "0"}, // Exact multiple of a fractional denomination
+		{"1234.56", "0.25", "1234.5", "0.06"},
+		{"25000", "1000", "25000", "0"},
+		{"42.5", "0", "42.5", "0"}, // No denomination
+	}
+
+	for _, tt := range tests {
+		payable, residual, err := RoundToDenomination(tt.amount, decimal.RequireFromString(tt.denomination))
+		if err != nil {
+			t.Fatalf("%s in %s: unexpected error: %v", tt.amount, tt.denomination, err)
+		}
+		if payable.String() != tt.payable || residual.String() != tt.residual {
+			t.Errorf("%s in %s: expected %s + %s, got %s + %s", tt.amount, tt.denomination, tt.payable, tt.residual, payable, residual)
+		}
+	}
+}
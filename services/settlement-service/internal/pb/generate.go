@@ -0,0 +1,5 @@
+// Package pb holds the Go code protoc generates from proto/common.proto and
+// proto/settlement.proto, one package per proto file.
+package pb
+
+//go:generate protoc --proto_path=../../../../proto --go_out=../.. --go_opt=module=github.com/movra/settlement-service,Mcommon.proto=github.com/movra/settlement-service/internal/pb/commonpb,Msettlement.proto=github.com/movra/settlement-service/internal/pb/settlementpb --go-grpc_out=../.. --go-grpc_opt=module=github.com/movra/settlement-service,Mcommon.proto=github.com/movra/settlement-service/internal/pb/commonpb,Msettlement.proto=github.com/movra/settlement-service/internal/pb/settlementpb common.proto settlement.proto
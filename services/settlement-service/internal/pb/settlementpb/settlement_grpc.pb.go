@@ -0,0 +1,412 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: settlement.proto
+
+package settlementpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SettlementService_InitiatePayout_FullMethodName = "/movra.settlement.SettlementService/InitiatePayout"
+	SettlementService_GetPayout_FullMethodName      = "/movra.settlement.SettlementService/GetPayout"
+	SettlementService_ListPayouts_FullMethodName    = "/movra.settlement.SettlementService/ListPayouts"
+	SettlementService_RetryPayout_FullMethodName    = "/movra.settlement.SettlementService/RetryPayout"
+	SettlementService_CancelPayout_FullMethodName   = "/movra.settlement.SettlementService/CancelPayout"
+	SettlementService_GetPickupCode_FullMethodName  = "/movra.settlement.SettlementService/GetPickupCode"
+	SettlementService_RedeemPickup_FullMethodName   = "/movra.settlement.SettlementService/RedeemPickup"
+	SettlementService_WatchPayout_FullMethodName    = "/movra.settlement.SettlementService/WatchPayout"
+)
+
+// SettlementServiceClient is the client API for SettlementService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SettlementServiceClient interface {
+	// Initiate payout (typically triggered via Kafka, but available via gRPC)
+	InitiatePayout(ctx context.Context, in *InitiatePayoutRequest, opts ...grpc.CallOption) (*InitiatePayoutResponse, error)
+	// Get payout status
+	GetPayout(ctx context.Context, in *GetPayoutRequest, opts ...grpc.CallOption) (*GetPayoutResponse, error)
+	// List payouts (admin)
+	ListPayouts(ctx context.Context, in *ListPayoutsRequest, opts ...grpc.CallOption) (*ListPayoutsResponse, error)
+	// Retry failed payout (admin)
+	RetryPayout(ctx context.Context, in *RetryPayoutRequest, opts ...grpc.CallOption) (*RetryPayoutResponse, error)
+	// Cancel payout (before processing)
+	CancelPayout(ctx context.Context, in *CancelPayoutRequest, opts ...grpc.CallOption) (*CancelPayoutResponse, error)
+	// Get cash pickup code
+	GetPickupCode(ctx context.Context, in *GetPickupCodeRequest, opts ...grpc.CallOption) (*GetPickupCodeResponse, error)
+	// Mark a cash pickup as collected on its pickup code
+	RedeemPickup(ctx context.Context, in *RedeemPickupRequest, opts ...grpc.CallOption) (*RedeemPickupResponse, error)
+	// Stream a payout's status changes until it reaches a terminal status
+	WatchPayout(ctx context.Context, in *WatchPayoutRequest, opts ...grpc.CallOption) (SettlementService_WatchPayoutClient, error)
+}
+
+type settlementServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSettlementServiceClient(cc grpc.ClientConnInterface) SettlementServiceClient {
+	return &settlementServiceClient{cc}
+}
+
+func (c *settlementServiceClient) InitiatePayout(ctx context.Context, in *InitiatePayoutRequest, opts ...grpc.CallOption) (*InitiatePayoutResponse, error) {
+	out := new(InitiatePayoutResponse)
+	err := c.cc.Invoke(ctx, SettlementService_InitiatePayout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) GetPayout(ctx context.Context, in *GetPayoutRequest, opts ...grpc.CallOption) (*GetPayoutResponse, error) {
+	out := new(GetPayoutResponse)
+	err := c.cc.Invoke(ctx, SettlementService_GetPayout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) ListPayouts(ctx context.Context, in *ListPayoutsRequest, opts ...grpc.CallOption) (*ListPayoutsResponse, error) {
+	out := new(ListPayoutsResponse)
+	err := c.cc.Invoke(ctx, SettlementService_ListPayouts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) RetryPayout(ctx context.Context, in *RetryPayoutRequest, opts ...grpc.CallOption) (*RetryPayoutResponse, error) {
+	out := new(RetryPayoutResponse)
+	err := c.cc.Invoke(ctx, SettlementService_RetryPayout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) CancelPayout(ctx context.Context, in *CancelPayoutRequest, opts ...grpc.CallOption) (*CancelPayoutResponse, error) {
+	out := new(CancelPayoutResponse)
+	err := c.cc.Invoke(ctx, SettlementService_CancelPayout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) GetPickupCode(ctx context.Context, in *GetPickupCodeRequest, opts ...grpc.CallOption) (*GetPickupCodeResponse, error) {
+	out := new(GetPickupCodeResponse)
+	err := c.cc.Invoke(ctx, SettlementService_GetPickupCode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) RedeemPickup(ctx context.Context, in *RedeemPickupRequest, opts ...grpc.CallOption) (*RedeemPickupResponse, error) {
+	out := new(RedeemPickupResponse)
+	err := c.cc.Invoke(ctx, SettlementService_RedeemPickup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *settlementServiceClient) WatchPayout(ctx context.Context, in *WatchPayoutRequest, opts ...grpc.CallOption) (SettlementService_WatchPayoutClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SettlementService_ServiceDesc.Streams[0], SettlementService_WatchPayout_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &settlementServiceWatchPayoutClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SettlementService_WatchPayoutClient interface {
+	Recv() (*WatchPayoutResponse, error)
+	grpc.ClientStream
+}
+
+type settlementServiceWatchPayoutClient struct {
+	grpc.ClientStream
+}
+
+func (x *settlementServiceWatchPayoutClient) Recv() (*WatchPayoutResponse, error) {
+	m := new(WatchPayoutResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SettlementServiceServer is the server API for SettlementService service.
+// All implementations must embed UnimplementedSettlementServiceServer
+// for forward compatibility
+type SettlementServiceServer interface {
+	// Initiate payout (typically triggered via Kafka, but available via gRPC)
+	InitiatePayout(context.Context, *InitiatePayoutRequest) (*InitiatePayoutResponse, error)
+	// Get payout status
+	GetPayout(context.Context, *GetPayoutRequest) (*GetPayoutResponse, error)
+	// List payouts (admin)
+	ListPayouts(context.Context, *ListPayoutsRequest) (*ListPayoutsResponse, error)
+	// Retry failed payout (admin)
+	RetryPayout(context.Context, *RetryPayoutRequest) (*RetryPayoutResponse, error)
+	// Cancel payout (before processing)
+	CancelPayout(context.Context, *CancelPayoutRequest) (*CancelPayoutResponse, error)
+	// Get cash pickup code
+	GetPickupCode(context.Context, *GetPickupCodeRequest) (*GetPickupCodeResponse, error)
+	// Mark a cash pickup as collected on its pickup code
+	RedeemPickup(context.Context, *RedeemPickupRequest) (*RedeemPickupResponse, error)
+	// Stream a payout's status changes until it reaches a terminal status
+	WatchPayout(*WatchPayoutRequest, SettlementService_WatchPayoutServer) error
+	mustEmbedUnimplementedSettlementServiceServer()
+}
+
+// UnimplementedSettlementServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSettlementServiceServer struct {
+}
+
+func (UnimplementedSettlementServiceServer) InitiatePayout(context.Context, *InitiatePayoutRequest) (*InitiatePayoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitiatePayout not implemented")
+}
+func (UnimplementedSettlementServiceServer) GetPayout(context.Context, *GetPayoutRequest) (*GetPayoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPayout not implemented")
+}
+func (UnimplementedSettlementServiceServer) ListPayouts(context.Context, *ListPayoutsRequest) (*ListPayoutsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPayouts not implemented")
+}
+func (UnimplementedSettlementServiceServer) RetryPayout(context.Context, *RetryPayoutRequest) (*RetryPayoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetryPayout not implemented")
+}
+func (UnimplementedSettlementServiceServer) CancelPayout(context.Context, *CancelPayoutRequest) (*CancelPayoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelPayout not implemented")
+}
+func (UnimplementedSettlementServiceServer) GetPickupCode(context.Context, *GetPickupCodeRequest) (*GetPickupCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPickupCode not implemented")
+}
+func (UnimplementedSettlementServiceServer) RedeemPickup(context.Context, *RedeemPickupRequest) (*RedeemPickupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeemPickup not implemented")
+}
+func (UnimplementedSettlementServiceServer) WatchPayout(*WatchPayoutRequest, SettlementService_WatchPayoutServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPayout not implemented")
+}
+func (UnimplementedSettlementServiceServer) mustEmbedUnimplementedSettlementServiceServer() {}
+
+// UnsafeSettlementServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SettlementServiceServer will
+// result in compilation errors.
+type UnsafeSettlementServiceServer interface {
+	mustEmbedUnimplementedSettlementServiceServer()
+}
+
+func RegisterSettlementServiceServer(s grpc.ServiceRegistrar, srv SettlementServiceServer) {
+	s.RegisterService(&SettlementService_ServiceDesc, srv)
+}
+
+func _SettlementService_InitiatePayout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitiatePayoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).InitiatePayout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_InitiatePayout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).InitiatePayout(ctx, req.(*InitiatePayoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_GetPayout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPayoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).GetPayout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_GetPayout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).GetPayout(ctx, req.(*GetPayoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_ListPayouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPayoutsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).ListPayouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_ListPayouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).ListPayouts(ctx, req.(*ListPayoutsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_RetryPayout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetryPayoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).RetryPayout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_RetryPayout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).RetryPayout(ctx, req.(*RetryPayoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_CancelPayout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelPayoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).CancelPayout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_CancelPayout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).CancelPayout(ctx, req.(*CancelPayoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_GetPickupCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPickupCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).GetPickupCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_GetPickupCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).GetPickupCode(ctx, req.(*GetPickupCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_RedeemPickup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemPickupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SettlementServiceServer).RedeemPickup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SettlementService_RedeemPickup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SettlementServiceServer).RedeemPickup(ctx, req.(*RedeemPickupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SettlementService_WatchPayout_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPayoutRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SettlementServiceServer).WatchPayout(m, &settlementServiceWatchPayoutServer{stream})
+}
+
+type SettlementService_WatchPayoutServer interface {
+	Send(*WatchPayoutResponse) error
+	grpc.ServerStream
+}
+
+type settlementServiceWatchPayoutServer struct {
+	grpc.ServerStream
+}
+
+func (x *settlementServiceWatchPayoutServer) Send(m *WatchPayoutResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SettlementService_ServiceDesc is the grpc.ServiceDesc for SettlementService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SettlementService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "movra.settlement.SettlementService",
+	HandlerType: (*SettlementServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InitiatePayout",
+			Handler:    _SettlementService_InitiatePayout_Handler,
+		},
+		{
+			MethodName: "GetPayout",
+			Handler:    _SettlementService_GetPayout_Handler,
+		},
+		{
+			MethodName: "ListPayouts",
+			Handler:    _SettlementService_ListPayouts_Handler,
+		},
+		{
+			MethodName: "RetryPayout",
+			Handler:    _SettlementService_RetryPayout_Handler,
+		},
+		{
+			MethodName: "CancelPayout",
+			Handler:    _SettlementService_CancelPayout_Handler,
+		},
+		{
+			MethodName: "GetPickupCode",
+			Handler:    _SettlementService_GetPickupCode_Handler,
+		},
+		{
+			MethodName: "RedeemPickup",
+			Handler:    _SettlementService_RedeemPickup_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPayout",
+			Handler:       _SettlementService_WatchPayout_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "settlement.proto",
+}
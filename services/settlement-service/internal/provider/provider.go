@@ -7,20 +7,40 @@ import (
 	"github.com/movra/settlement-service/internal/model"
 )
 
+// FailureCategory classifies a failed payout for retry decisions
+type FailureCategory string
+
+const (
+	// FailureTransient failures may succeed on retry, e.g. a provider timeout.
+	// Failures without a category are treated as transient.
+	FailureTransient FailureCategory = "TRANSIENT"
+
+	// FailurePermanent failures will fail again on retry, e.g. an invalid account
+	FailurePermanent FailureCategory = "PERMANENT"
+)
+
 // ProviderResult represents the result of a payout operation
 type ProviderResult struct {
 	ProviderReference string
 	Status            model.PayoutStatus
 	FailureReason     string
+	FailureCategory   FailureCategory
 	PickupCode        string
 	PickupExpiresAt   *time.Time
+
+	// ProviderFee is what the provider charged for the payout, in
+	// ProviderFeeCurrency, so reconciliation can compare it with the
+	// expected cost. Empty when the provider doesn't report one.
+	ProviderFee         string
+	ProviderFeeCurrency string
 }
 
 // ProviderStatus represents the status from a provider check
 type ProviderStatus struct {
-	Status        model.PayoutStatus
-	FailureReason string
-	CompletedAt   *time.Time
+	Status          model.PayoutStatus
+	FailureReason   string
+	FailureCategory FailureCategory
+	CompletedAt     *time.Time
 }
 
 // PayoutProvider defines the interface for payout providers
@@ -37,3 +57,11 @@ type PayoutProvider interface {
 	// Name returns the provider name
 	Name() string
 }
+
+// PickupCodeRegistry tracks the pickup codes of payouts that can currently be
+// redeemed
+type PickupCodeRegistry interface {
+	// ReservePickupCode marks code active until expiresAt. It returns false if
+	// the code is already active.
+	ReservePickupCode(ctx context.Context, code string, expiresAt time.Time) (bool, error)
+}
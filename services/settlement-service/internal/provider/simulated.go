@@ -4,8 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"math"
 	"math/big"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/movra/settlement-service/internal/model"
 )
@@ -14,6 +19,103 @@ import (
 type SimulatedProvider struct {
 	failureRate    int // percentage 0-100
 	processingTime time.Duration
+
+	// completionDelay enables async mode: payouts stay PROCESSING until
+	// CheckStatus is called after the delay has passed
+	completionDelay time.Duration
+	mu              sync.Mutex
+	pending         map[string]time.Time // provider reference -> completion time
+
+	// Pickup codes are checked against the registry, when set, and
+	// regenerated on collision up to maxCodeAttempts times
+	generateCode    func() string
+	codes           PickupCodeRegistry
+	maxCodeAttempts int
+
+	// failureRules fail matching payouts every time, before the random
+	// failure rate is applied
+	failureRules []FailureRule
+}
+
+// FailureRule makes the simulated provider fail every matching payout, so
+// tests and demos can reproduce a failure deterministically. Empty fields
+// match any payout.
+type FailureRule struct {
+	Method      model.PayoutMethod
+	Country     string  // recipient country
+	AmountAbove float64 // payouts of more than this amount (0 matches any amount)
+
+	Reason   string          // defaults to a description of the rule
+	Category FailureCategory // defaults to FailurePermanent
+}
+
+// Matches reports whether the rule applies to the payout
+func (r FailureRule) Matches(payout *model.Payout) bool {
+	if r.Method != "" && payout.Method != r.Method {
+		return false
+	}
+	if r.Country != "" && payout.Recipient.Country != r.Country {
+		return false
+	}
+	if r.AmountAbove > 0 {
+		amount, err := strconv.ParseFloat(payout.DisbursedAmount(), 64)
+		if err != nil || amount <= r.AmountAbove {
+			return false
+		}
+	}
+	return true
+}
+
+// result returns the failed result for a payout matching the rule
+func (r FailureRule) result(providerRef string) *ProviderResult {
+	reason := r.Reason
+	if reason == "" {
+		reason = "Simulated failure: " + r.describe()
+	}
+	category := r.Category
+	if category == "" {
+		category = FailurePermanent
+	}
+	return &ProviderResult{
+		ProviderReference: providerRef,
+		Status:            model.PayoutStatusFailed,
+		FailureReason:     reason,
+		FailureCategory:   category,
+	}
+}
+
+// describe names what the rule matches, e.g. "MOBILE_WALLET payouts to XX"
+func (r FailureRule) describe() string {
+	description := "payouts"
+	if r.Method != "" {
+		description = string(r.Method) + " " + description
+	}
+	if r.Country != "" {
+		description += " to " + r.Country
+	}
+	if r.AmountAbove > 0 {
+		description += " above " + strconv.FormatFloat(r.AmountAbove, 'f', -1, 64)
+	}
+	return description
+}
+
+// ParseFailureRoute parses "METHOD:COUNTRY", e.g. "MOBILE_WALLET:XX", into a
+// rule failing payouts of that method to that country. Either part may be
+// "*" to match any.
+func ParseFailureRoute(route string) (FailureRule, error) {
+	method, country, ok := strings.Cut(strings.TrimSpace(route), ":")
+	if !ok || method == "" || country == "" {
+		return FailureRule{}, fmt.Errorf("invalid failure route %q, expected METHOD:COUNTRY", route)
+	}
+
+	var rule FailureRule
+	if method != "*" {
+		rule.Method = model.PayoutMethod(method)
+	}
+	if country != "*" {
+		rule.Country = country
+	}
+	return rule, nil
 }
 
 // NewSimulatedProvider creates a new simulated provider
@@ -21,9 +123,54 @@ func NewSimulatedProvider(failureRate int, processingTime time.Duration) *Simula
 	return &SimulatedProvider{
 		failureRate:    failureRate,
 		processingTime: processingTime,
+		generateCode:   pickupCodeGenerator(DefaultPickupCodeLength, DefaultPickupCodeAlphabet),
+	}
+}
+
+// NewAsyncSimulatedProvider creates a simulated provider that accepts bank and
+// wallet payouts as PROCESSING and only reports them COMPLETED on a CheckStatus
+// call made after completionDelay
+func NewAsyncSimulatedProvider(failureRate int, processingTime, completionDelay time.Duration) *SimulatedProvider {
+	return &SimulatedProvider{
+		failureRate:     failureRate,
+		processingTime:  processingTime,
+		completionDelay: completionDelay,
+		pending:         make(map[string]time.Time),
+		generateCode:    pickupCodeGenerator(DefaultPickupCodeLength, DefaultPickupCodeAlphabet),
 	}
 }
 
+// WithPickupCodeRegistry makes pickup codes unique among active codes by
+// reserving each in registry, regenerating a colliding code up to maxAttempts
+// times in total
+func (p *SimulatedProvider) WithPickupCodeRegistry(registry PickupCodeRegistry, maxAttempts int) *SimulatedProvider {
+	p.codes = registry
+	p.maxCodeAttempts = maxAttempts
+	return p
+}
+
+// WithFailureRules makes the provider fail every payout matching one of the
+// rules, whatever its failure rate
+func (p *SimulatedProvider) WithFailureRules(rules ...FailureRule) *SimulatedProvider {
+	p.failureRules = append(p.failureRules, rules...)
+	return p
+}
+
+// WithPickupCodeFormat makes the provider generate random pickup codes of
+// length characters drawn from alphabet to match a target provider's format.
+// A length <= 0 or empty alphabet keeps the default. The format should have
+// passed ValidatePickupCodeFormat.
+func (p *SimulatedProvider) WithPickupCodeFormat(length int, alphabet string) *SimulatedProvider {
+	p.generateCode = pickupCodeGenerator(length, alphabet)
+	return p
+}
+
+// WithPickupCodeGenerator replaces the random pickup code generator
+func (p *SimulatedProvider) WithPickupCodeGenerator(generate func() string) *SimulatedProvider {
+	p.generateCode = generate
+	return p
+}
+
 func (p *SimulatedProvider) Name() string {
 	return "simulated"
 }
@@ -39,12 +186,20 @@ func (p *SimulatedProvider) ProcessPayout(ctx context.Context, payout *model.Pay
 	// Generate provider reference
 	providerRef := fmt.Sprintf("SIM_%d", time.Now().UnixNano())
 
+	// Fail payouts matching a rule every time
+	for _, rule := range p.failureRules {
+		if rule.Matches(payout) {
+			return rule.result(providerRef), nil
+		}
+	}
+
 	// Simulate random failures
 	if p.shouldFail() {
 		return &ProviderResult{
 			ProviderReference: providerRef,
 			Status:            model.PayoutStatusFailed,
 			FailureReason:     "Simulated failure: recipient account not found",
+			FailureCategory:   FailurePermanent,
 		}, nil
 	}
 
@@ -52,21 +207,49 @@ func (p *SimulatedProvider) ProcessPayout(ctx context.Context, payout *model.Pay
 		ProviderReference: providerRef,
 		Status:            model.PayoutStatusCompleted,
 	}
+	if fee, ok := simulatedFee(payout); ok {
+		result.ProviderFee = fee
+		result.ProviderFeeCurrency = payout.Currency
+	}
 
 	// For cash pickup, generate pickup code
 	if payout.Method == model.PayoutMethodCashPickup {
-		result.Status = model.PayoutStatusReadyForPickup
-		result.PickupCode = p.generatePickupCode()
 		expiresAt := time.Now().Add(72 * time.Hour)
+		code, err := p.pickupCode(ctx, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		result.Status = model.PayoutStatusReadyForPickup
+		result.PickupCode = code
 		result.PickupExpiresAt = &expiresAt
+		return result, nil
+	}
+
+	if p.completionDelay > 0 {
+		p.mu.Lock()
+		p.pending[providerRef] = time.Now().Add(p.completionDelay)
+		p.mu.Unlock()
+		result.Status = model.PayoutStatusProcessing
 	}
 
 	return result, nil
 }
 
 func (p *SimulatedProvider) CheckStatus(ctx context.Context, providerReference string) (*ProviderStatus, error) {
-	// Simulated provider always returns completed for status checks
 	now := time.Now()
+
+	if p.completionDelay > 0 {
+		p.mu.Lock()
+		completesAt, ok := p.pending[providerReference]
+		if ok && now.Before(completesAt) {
+			p.mu.Unlock()
+			return &ProviderStatus{Status: model.PayoutStatusProcessing}, nil
+		}
+		delete(p.pending, providerReference)
+		p.mu.Unlock()
+	}
+
+	// Unknown references (and everything in sync mode) report completed
 	return &ProviderStatus{
 		Status:      model.PayoutStatusCompleted,
 		CompletedAt: &now,
@@ -75,6 +258,11 @@ func (p *SimulatedProvider) CheckStatus(ctx context.Context, providerReference s
 
 func (p *SimulatedProvider) CancelPayout(ctx context.Context, providerReference string) error {
 	// Simulated cancellation always succeeds
+	if p.completionDelay > 0 {
+		p.mu.Lock()
+		delete(p.pending, providerReference)
+		p.mu.Unlock()
+	}
 	return nil
 }
 
@@ -86,12 +274,100 @@ func (p *SimulatedProvider) shouldFail() bool {
 	return int(n.Int64()) < p.failureRate
 }
 
-func (p *SimulatedProvider) generatePickupCode() string {
-	const digits = "0123456789"
-	code := make([]byte, 8)
-	for i := range code {
-		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
-		code[i] = digits[n.Int64()]
+// simulatedFeeRate is the share of the amount the simulated provider charges
+// for a payout it accepts
+const simulatedFeeRate = 0.005
+
+// simulatedFee returns the simulated provider's fee for the payout, rounded
+// to its currency's precision, or false if the amount isn't a number
+func simulatedFee(payout *model.Payout) (string, bool) {
+	amount, err := strconv.ParseFloat(payout.DisbursedAmount(), 64)
+	if err != nil {
+		return "", false
+	}
+	places := model.Precision(payout.Currency)
+	scale := math.Pow10(places)
+	return strconv.FormatFloat(math.Round(amount*simulatedFeeRate*scale)/scale, 'f', places, 64), true
+}
+
+// pickupCode generates a pickup code, reserving it in the registry if one is
+// set so no two redeemable payouts share a code
+func (p *SimulatedProvider) pickupCode(ctx context.Context, expiresAt time.Time) (string, error) {
+	if p.codes == nil {
+		return p.generateCode(), nil
+	}
+
+	attempts := p.maxCodeAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	for i := 0; i < attempts; i++ {
+		code := p.generateCode()
+		reserved, err := p.codes.ReservePickupCode(ctx, code, expiresAt)
+		if err != nil {
+			return "", fmt.Errorf("reserve pickup code: %w", err)
+		}
+		if reserved {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("no unique pickup code after %d attempts", attempts)
+}
+
+// Pickup codes are 8 digits unless WithPickupCodeFormat says otherwise
+const (
+	DefaultPickupCodeLength   = 8
+	DefaultPickupCodeAlphabet = "0123456789"
+)
+
+// MinPickupCodeBits is the least randomness a pickup code format may carry,
+// its length times log2 of its alphabet's size
+const MinPickupCodeBits = 26
+
+// ValidatePickupCodeFormat rejects a pickup code format that can't be drawn
+// from evenly or is too easy to guess: the alphabet must be ASCII without
+// repeated characters, and codes must carry MinPickupCodeBits. Unset values
+// are checked as their defaults.
+func ValidatePickupCodeFormat(length int, alphabet string) error {
+	if length <= 0 {
+		length = DefaultPickupCodeLength
+	}
+	if alphabet == "" {
+		alphabet = DefaultPickupCodeAlphabet
+	}
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c > unicode.MaxASCII {
+			return fmt.Errorf("pickup code alphabet %q must be ASCII", alphabet)
+		}
+		if seen[c] {
+			return fmt.Errorf("pickup code alphabet %q repeats %q", alphabet, c)
+		}
+		seen[c] = true
+	}
+	if bits := float64(length) * math.Log2(float64(len(alphabet))); bits < MinPickupCodeBits {
+		return fmt.Errorf("pickup codes of %d characters from %d carry %.1f bits, want at least %d",
+			length, len(alphabet), bits, MinPickupCodeBits)
+	}
+	return nil
+}
+
+// pickupCodeGenerator returns a generator of random codes of length
+// characters from alphabet, using the defaults for unset values
+func pickupCodeGenerator(length int, alphabet string) func() string {
+	if length <= 0 {
+		length = DefaultPickupCodeLength
+	}
+	if alphabet == "" {
+		alphabet = DefaultPickupCodeAlphabet
+	}
+	return func() string {
+		code := make([]byte, length)
+		for i := range code {
+			n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+			code[i] = alphabet[n.Int64()]
+		}
+		return string(code)
 	}
-	return string(code)
 }
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -82,6 +83,11 @@ func TestSimulatedProvider_ProcessPayout_Failure(t *testing.T) {
 	if result.FailureReason == "" {
 		t.Error("expected failure reason to be set")
 	}
+
+	// Account not found won't succeed on retry
+	if result.FailureCategory != FailurePermanent {
+		t.Errorf("expected PERMANENT failure category, got: %s", result.FailureCategory)
+	}
 }
 
 func TestSimulatedProvider_ProcessPayout_ContextCancellation(t *testing.T) {
@@ -130,3 +136,265 @@ func TestSimulatedProvider_Name(t *testing.T) {
 		t.Errorf("expected name 'simulated', got: %s", provider.Name())
 	}
 }
+
+func TestSimulatedProvider_AsyncCompletion(t *testing.T) {
+	provider := NewAsyncSimulatedProvider(0, 10*time.Millisecond, 50*time.Millisecond)
+
+	payout := &model.Payout{
+		ID:     "test_payout_async",
+		Method: model.PayoutMethodBankAccount,
+	}
+
+	result, err := provider.ProcessPayout(context.Background(), payout)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if result.Status != model.PayoutStatusProcessing {
+		t.Fatalf("expected status PROCESSING, got: %s", result.Status)
+	}
+
+	status, err := provider.CheckStatus(context.Background(), result.ProviderReference)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if status.Status != model.PayoutStatusProcessing {
+		t.Errorf("expected status PROCESSING before delay, got: %s", status.Status)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	status, err = provider.CheckStatus(context.Background(), result.ProviderReference)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if status.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected status COMPLETED after delay, got: %s", status.Status)
+	}
+	if status.CompletedAt == nil {
+		t.Error("expected completion time to be set")
+	}
+}
+
+// memoryCodeRegistry is an in-memory PickupCodeRegistry
+type memoryCodeRegistry struct {
+	active map[string]bool
+}
+
+func (r *memoryCodeRegistry) ReservePickupCode(ctx context.Context, code string, expiresAt time.Time) (bool, error) {
+	if r.active[code] {
+		return false, nil
+	}
+	r.active[code] = true
+	return true, nil
+}
+
+// sequenceGenerator returns codes in order, repeating the last one
+func sequenceGenerator(codes ...string) (func() string, *int) {
+	calls := 0
+	return func() string {
+		code := codes[len(codes)-1]
+		if calls < len(codes) {
+			code = codes[calls]
+		}
+		calls++
+		return code
+	}, &calls
+}
+
+func TestSimulatedProvider_PickupCodeCollisionIsRegenerated(t *testing.T) {
+	registry := &memoryCodeRegistry{active: map[string]bool{"11111111": true}}
+	generate, calls := sequenceGenerator("11111111", "11111111", "22222222")
+	provider := NewSimulatedProvider(0, time.Millisecond).
+		WithPickupCodeGenerator(generate).
+		WithPickupCodeRegistry(registry, 5)
+
+	result, err := provider.ProcessPayout(context.Background(), &model.Payout{ID: "payout_1", Method: model.PayoutMethodCashPickup})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if result.PickupCode != "22222222" {
+		t.Errorf("expected the colliding code to be regenerated, got %s", result.PickupCode)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 generated codes, got %d", *calls)
+	}
+	if !registry.active["22222222"] {
+		t.Error("expected the issued code to be reserved")
+	}
+}
+
+func TestSimulatedProvider_PickupCodeAttemptsAreBounded(t *testing.T) {
+	registry := &memoryCodeRegistry{active: map[string]bool{"11111111": true}}
+	generate, calls := sequenceGenerator("11111111")
+	provider := NewSimulatedProvider(0, time.Millisecond).
+		WithPickupCodeGenerator(generate).
+		WithPickupCodeRegistry(registry, 3)
+
+	_, err := provider.ProcessPayout(context.Background(), &model.Payout{ID: "payout_1", Method: model.PayoutMethodCashPickup})
+	if err == nil {
+		t.Fatal("expected an error when every attempt collides")
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", *calls)
+	}
+}
+
+func TestSimulatedProvider_PickupCodeFormat(t *testing.T) {
+	tests := []struct {
+		length   int
+		alphabet string
+		wantLen  int
+		want     string // characters codes may contain
+	}{
+		{6, "ABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6, "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"},
+		{10, "XY", 10, "XY"},
+		{0, "", DefaultPickupCodeLength, DefaultPickupCodeAlphabet},
+	}
+
+	for _, tt := range tests {
+		provider := NewSimulatedProvider(0, time.Millisecond).WithPickupCodeFormat(tt.length, tt.alphabet)
+		for i := 0; i < 20; i++ {
+			result, err := provider.ProcessPayout(context.Background(), &model.Payout{ID: "payout_1", Method: model.PayoutMethodCashPickup})
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			code := result.PickupCode
+			if len(code) != tt.wantLen {
+				t.Errorf("length %d: expected a %d character code, got %q", tt.length, tt.wantLen, code)
+			}
+			if strings.Trim(code, tt.want) != "" {
+				t.Errorf("alphabet %q: expected code drawn from %q, got %q", tt.alphabet, tt.want, code)
+			}
+		}
+	}
+}
+
+func TestValidatePickupCodeFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		alphabet string
+		wantErr  bool
+	}{
+		{"default", 0, "", false},
+		{"8 digits", 8, "0123456789", false},
+		{"6 from 32", 6, "ABCDEFGHJKLMNPQRSTUVWXYZ23456789", false},
+		{"too short", 6, "0123456789", true},
+		{"one character", 30, "A", true},
+		{"repeated character", 8, "01234567890", true},
+		{"non-ASCII", 8, "0123456789é", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePickupCodeFormat(tt.length, tt.alphabet)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got: %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestSimulatedProvider_FailureRules(t *testing.T) {
+	// A 0% failure rate shows the rules fail payouts on their own
+	provider := NewSimulatedProvider(0, time.Millisecond).WithFailureRules(
+		FailureRule{Method: model.PayoutMethodMobileWallet, Country: "XX"},
+		FailureRule{AmountAbove: 5000, Reason: "Amount over limit", Category: FailureTransient},
+	)
+
+	tests := []struct {
+		name     string
+		payout   *model.Payout
+		reason   string
+		category FailureCategory
+	}{
+		{
+			name:     "wallet to XX",
+			payout:   &model.Payout{Method: model.PayoutMethodMobileWallet, Amount: "10.00", Recipient: model.Recipient{Country: "XX"}},
+			reason:   "Simulated failure: MOBILE_WALLET payouts to XX",
+			category: FailurePermanent,
+		},
+		{
+			name:     "over threshold",
+			payout:   &model.Payout{Method: model.PayoutMethodBankAccount, Amount: "5000.01", Recipient: model.Recipient{Country: "PH"}},
+			reason:   "Amount over limit",
+			category: FailureTransient,
+		},
+		{
+			name:   "wallet elsewhere",
+			payout: &model.Payout{Method: model.PayoutMethodMobileWallet, Amount: "10.00", Recipient: model.Recipient{Country: "PH"}},
+		},
+		{
+			name:   "at threshold",
+			payout: &model.Payout{Method: model.PayoutMethodBankAccount, Amount: "5000.00", Recipient: model.Recipient{Country: "XX"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				result, err := provider.ProcessPayout(context.Background(), tt.payout)
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+
+				if tt.reason == "" {
+					if result.Status != model.PayoutStatusCompleted {
+						t.Fatalf("expected status COMPLETED, got: %s", result.Status)
+					}
+					continue
+				}
+				if result.Status != model.PayoutStatusFailed {
+					t.Fatalf("attempt %d: expected status FAILED, got: %s", i, result.Status)
+				}
+				if result.FailureReason != tt.reason {
+					t.Errorf("expected reason %q, got: %q", tt.reason, result.FailureReason)
+				}
+				if result.FailureCategory != tt.category {
+					t.Errorf("expected category %s, got: %s", tt.category, result.FailureCategory)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFailureRoute(t *testing.T) {
+	rule, err := ParseFailureRoute("MOBILE_WALLET:*")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rule.Method != model.PayoutMethodMobileWallet || rule.Country != "" {
+		t.Errorf("expected a MOBILE_WALLET rule for any country, got: %+v", rule)
+	}
+
+	if _, err := ParseFailureRoute("MOBILE_WALLET"); err == nil {
+		t.Error("expected an error for a route without a country")
+	}
+}
+
+func TestSimulatedProvider_ReportsFee(t *testing.T) {
+	provider := NewSimulatedProvider(0, time.Millisecond)
+
+	tests := []struct {
+		amount   string
+		currency string
+		fee      string
+	}{
+		{"100.00", "SGD", "0.50"},
+		{"2500.00", "PHP", "12.50"},
+		{"1000000", "IDR", "5000"},
+	}
+	for _, tt := range tests {
+		result, err := provider.ProcessPayout(context.Background(), &model.Payout{
+			ID:       "payout_fee",
+			Method:   model.PayoutMethodBankAccount,
+			Amount:   tt.amount,
+			Currency: tt.currency,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if result.ProviderFee != tt.fee || result.ProviderFeeCurrency != tt.currency {
+			t.Errorf("%s %s: expected fee %s %s, got %s %s", tt.amount, tt.currency, tt.fee, tt.currency, result.ProviderFee, result.ProviderFeeCurrency)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/movra/settlement-service/internal/model"
@@ -14,12 +17,24 @@ import (
 const (
 	payoutKeyPrefix   = "payout:"
 	transferKeyPrefix = "payout:transfer:"
-	payoutTTL         = 7 * 24 * time.Hour // 7 days
+	batchKeyPrefix    = "payout:batch:"
+	lockKeyPrefix     = "payout:lock:"
+	pickupAttemptsKey = "payout:pickup_attempts:"
+	statusChannel     = "payout:status:"       // pub/sub channel of each payout's status changes
+	createdIndexKey   = "payouts:by_created"   // sorted set of payout IDs scored by creation time (ms)
+	statusIndexPrefix = "payouts:by_status:"   // per-status sorted sets of payout IDs scored by creation time (ms)
+	methodIndexPrefix = "payouts:by_method:"   // per-method sorted sets of payout IDs scored by creation time (ms)
+	pickupCodesKey    = "payouts:pickup_codes" // sorted set of active pickup codes scored by expiry (ms)
+	payoutTTL         = 7 * 24 * time.Hour     // 7 days
+
+	// exportChunkSize is how many payouts are loaded per round trip when iterating the index
+	exportChunkSize = 100
 )
 
 // RedisRepository implements PayoutRepository using Redis
 type RedisRepository struct {
-	client *redis.Client
+	client         *redis.Client
+	onWriteFailure WriteFailureRecorder
 }
 
 // NewRedisRepository creates a new Redis repository
@@ -27,32 +42,255 @@ func NewRedisRepository(client *redis.Client) *RedisRepository {
 	return &RedisRepository{client: client}
 }
 
+// WithWriteFailureRecorder reports failed writes to recorder, e.g. for metrics
+func (r *RedisRepository) WithWriteFailureRecorder(recorder WriteFailureRecorder) *RedisRepository {
+	r.onWriteFailure = recorder
+	return r
+}
+
+// writeError classifies a failed write as ErrWriteFailed and reports it to
+// the write failure recorder. The Redis error code may follow the client's
+// own prefix when a transaction fails.
+func (r *RedisRepository) writeError(operation string, err error) error {
+	kind := WriteFailureOther
+	switch msg := err.Error(); {
+	case strings.HasPrefix(msg, "OOM "), strings.Contains(msg, ": OOM "):
+		kind = WriteFailureOOM
+	case strings.HasPrefix(msg, "READONLY "), strings.Contains(msg, ": READONLY "):
+		kind = WriteFailureReadOnly
+	}
+	if r.onWriteFailure != nil {
+		r.onWriteFailure(operation, kind)
+	}
+	return ErrWriteFailed{Operation: operation, Kind: kind, Err: err}
+}
+
+// SavePayout saves the payout, refusing a status change the stored payout
+// can't make (see model.CanTransition). The payout's Version must match the
+// stored one, or the save fails with ErrConcurrentModification; on success
+// Version is incremented. The stored payout is checked and the new one
+// written in one transaction, so a concurrent retry and cancel can't both win.
+// A new payout claims its transfer in the same transaction, failing with
+// ErrTransferClaimed if another payout has it.
 func (r *RedisRepository) SavePayout(ctx context.Context, payout *model.Payout) error {
-	data, err := json.Marshal(payout)
+	next := *payout
+	next.Version++
+	data, err := json.Marshal(&next)
 	if err != nil {
 		return fmt.Errorf("marshal payout: %w", err)
 	}
 
-	pipe := r.client.Pipeline()
+	key := payoutKeyPrefix + payout.ID
+	transferKey := transferKeyPrefix + payout.TransferID
+	err = r.client.Watch(ctx, func(tx *redis.Tx) error {
+		storedStatus, err := checkStoredPayout(ctx, tx, key, payout)
+		if err != nil {
+			return err
+		}
+		if payout.Version == 0 {
+			if err := checkTransferUnclaimed(ctx, tx, transferKey, payout.ID); err != nil {
+				return err
+			}
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.queuePayoutWrites(ctx, pipe, &next, data, storedStatus)
+			return nil
+		})
+		return err
+	}, key, transferKey)
+
+	var transitionErr model.ErrInvalidTransition
+	switch {
+	case err == nil:
+		payout.Version = next.Version
+		return nil
+	case err == redis.TxFailedErr && payout.Version == 0:
+		// The transfer may have been claimed between the check and the write;
+		// checking again tells a claim from an unrelated write
+		if claimed, _ := r.transferClaimed(ctx, transferKey, payout.ID); claimed {
+			return ErrTransferClaimed
+		}
+		return ErrConcurrentModification
+	case err == redis.TxFailedErr:
+		// Another writer saved the payout between the check and the write
+		return ErrConcurrentModification
+	case errors.Is(err, ErrConcurrentModification), errors.Is(err, ErrTransferClaimed), errors.As(err, &transitionErr):
+		return err
+	default:
+		return r.writeError("save payout", err)
+	}
+}
+
+// checkTransferUnclaimed returns ErrTransferClaimed if the transfer already
+// has a stored payout other than payoutID
+func checkTransferUnclaimed(ctx context.Context, tx *redis.Tx, transferKey, payoutID string) error {
+	owner, err := tx.Get(ctx, transferKey).Result()
+	if err == redis.Nil || owner == payoutID {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// An index left behind by an expired payout doesn't hold the transfer
+	exists, err := tx.Exists(ctx, payoutKeyPrefix+owner).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 1 {
+		return ErrTransferClaimed
+	}
+	return nil
+}
+
+// transferClaimed reports whether the transfer has a stored payout other
+// than payoutID
+func (r *RedisRepository) transferClaimed(ctx context.Context, transferKey, payoutID string) (bool, error) {
+	owner, err := r.client.Get(ctx, transferKey).Result()
+	if err != nil || owner == payoutID {
+		return false, err
+	}
+	exists, err := r.client.Exists(ctx, payoutKeyPrefix+owner).Result()
+	return exists == 1, err
+}
+
+// checkStoredPayout returns the stored payout's status, which is empty for a
+// new payout. It fails with ErrConcurrentModification if the stored payout
+// has moved past the payout's version, or ErrInvalidTransition if its status
+// can't move to the payout's new status.
+func checkStoredPayout(ctx context.Context, tx *redis.Tx, key string, payout *model.Payout) (model.PayoutStatus, error) {
+	data, err := tx.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
 
+	var stored struct {
+		Status  model.PayoutStatus `json:"status"`
+		Version int                `json:"version"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return "", fmt.Errorf("unmarshal stored payout: %w", err)
+	}
+	if stored.Version != payout.Version {
+		return "", ErrConcurrentModification
+	}
+	if stored.Status != payout.Status && !model.CanTransition(stored.Status, payout.Status) {
+		return "", model.ErrInvalidTransition{PayoutID: payout.ID, From: stored.Status, To: payout.Status}
+	}
+	return stored.Status, nil
+}
+
+// queuePayoutWrites queues the writes that store payout and its indexes,
+// moving it out of the index of storedStatus when its status has changed
+func (r *RedisRepository) queuePayoutWrites(ctx context.Context, pipe redis.Pipeliner, payout *model.Payout, data []byte, storedStatus model.PayoutStatus) {
 	// Save payout by ID
 	pipe.Set(ctx, payoutKeyPrefix+payout.ID, data, payoutTTL)
 
 	// Save index by transfer ID
 	pipe.Set(ctx, transferKeyPrefix+payout.TransferID, payout.ID, payoutTTL)
 
-	_, err = pipe.Exec(ctx)
-	if err != nil {
-		return fmt.Errorf("save payout: %w", err)
+	queueIndexWrites(ctx, pipe, payout)
+	if storedStatus != "" && storedStatus != payout.Status {
+		pipe.ZRem(ctx, statusIndexPrefix+string(storedStatus), payout.ID)
 	}
 
-	return nil
+	// A pickup code stops being active once the payout leaves READY_FOR_PICKUP,
+	// e.g. when it is redeemed or cancelled
+	if payout.PickupCode != "" && payout.Status != model.PayoutStatusReadyForPickup {
+		pipe.ZRem(ctx, pickupCodesKey, payout.PickupCode)
+	}
+}
+
+// queueIndexWrites indexes payout by creation time overall, within its
+// status and within its method, dropping entries older than the retention
+// window from each index it writes
+func queueIndexWrites(ctx context.Context, pipe redis.Pipeliner, payout *model.Payout) {
+	keys := []string{createdIndexKey, statusIndexPrefix + string(payout.Status)}
+	if payout.Method != "" {
+		keys = append(keys, methodIndexPrefix+string(payout.Method))
+	}
+	cutoff := fmt.Sprintf("(%d", time.Now().Add(-payoutTTL).UnixMilli())
+	for _, key := range keys {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(payout.CreatedAt.UnixMilli()), Member: payout.ID})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", cutoff)
+	}
+}
+
+// BackfillIndexes indexes every stored payout by creation time, status and
+// method, returning how many it indexed. Payouts saved before an index
+// existed are otherwise invisible to listings and exports; running it again
+// is harmless.
+func (r *RedisRepository) BackfillIndexes(ctx context.Context) (int, error) {
+	indexed := 0
+	iter := r.client.Scan(ctx, 0, payoutKeyPrefix+"*", exportChunkSize).Iterator()
+	var keys []string
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		values, err := r.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return fmt.Errorf("load payouts: %w", err)
+		}
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, value := range values {
+				data, ok := value.(string)
+				if !ok {
+					continue // Expired since the scan
+				}
+				var payout model.Payout
+				if err := json.Unmarshal([]byte(data), &payout); err != nil || payout.ID == "" {
+					continue
+				}
+				queueIndexWrites(ctx, pipe, &payout)
+				indexed++
+			}
+			return nil
+		})
+		keys = keys[:0]
+		if err != nil {
+			return r.writeError("backfill payout indexes", err)
+		}
+		return nil
+	}
+
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if isPayoutIndexKey(key) {
+			continue
+		}
+		keys = append(keys, key)
+		if len(keys) == exportChunkSize {
+			if err := flush(); err != nil {
+				return indexed, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return indexed, fmt.Errorf("scan payouts: %w", err)
+	}
+	if err := flush(); err != nil {
+		return indexed, err
+	}
+	return indexed, nil
+}
+
+// isPayoutIndexKey reports whether a key under the payout prefix holds
+// something other than a payout
+func isPayoutIndexKey(key string) bool {
+	return strings.HasPrefix(key, transferKeyPrefix) ||
+		strings.HasPrefix(key, batchKeyPrefix) ||
+		strings.HasPrefix(key, lockKeyPrefix) ||
+		strings.HasPrefix(key, pickupAttemptsKey)
 }
 
 func (r *RedisRepository) GetPayout(ctx context.Context, id string) (*model.Payout, error) {
 	data, err := r.client.Get(ctx, payoutKeyPrefix+id).Bytes()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("payout not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrPayoutNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get payout: %w", err)
@@ -69,76 +307,224 @@ func (r *RedisRepository) GetPayout(ctx context.Context, id string) (*model.Payo
 func (r *RedisRepository) GetPayoutByTransferID(ctx context.Context, transferID string) (*model.Payout, error) {
 	payoutID, err := r.client.Get(ctx, transferKeyPrefix+transferID).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("payout not found for transfer: %s", transferID)
+		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get payout by transfer: %w", err)
 	}
 
-	return r.GetPayout(ctx, payoutID)
+	data, err := r.client.Get(ctx, payoutKeyPrefix+payoutID).Bytes()
+	if err == redis.Nil {
+		return nil, nil // Index outlived the payout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+
+	var payout model.Payout
+	if err := json.Unmarshal(data, &payout); err != nil {
+		return nil, fmt.Errorf("unmarshal payout: %w", err)
+	}
+	return &payout, nil
 }
 
 func (r *RedisRepository) ListPayouts(ctx context.Context, filter PayoutFilter) ([]*model.Payout, error) {
-	// For Redis, we do a simple scan - in production, use a proper index or database
-	var cursor uint64
-	var payouts []*model.Payout
+	page, err := r.ListPayoutsPage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return page.Payouts, nil
+}
 
-	for {
-		keys, nextCursor, err := r.client.Scan(ctx, cursor, payoutKeyPrefix+"*", 100).Result()
+// errPageFull stops walking the index once a page is complete
+var errPageFull = errors.New("page full")
+
+// ListPayoutsPage walks the creation index, or the status or method index
+// when filtering by one, so pages are stable while new payouts are created.
+// Filtered listings walk every payout in the index to count the total
+// exactly; unfiltered ones stop after the page and take the total from the
+// index size.
+func (r *RedisRepository) ListPayoutsPage(ctx context.Context, filter PayoutFilter) (*PayoutPage, error) {
+	var after *payoutCursor
+	if filter.Cursor != "" {
+		c, err := decodePayoutCursor(filter.Cursor)
 		if err != nil {
-			return nil, fmt.Errorf("scan payouts: %w", err)
+			return nil, err
 		}
+		after = &c
+	}
 
-		for _, key := range keys {
-			// Skip index keys (transfer: prefix)
-			if strings.HasPrefix(key, transferKeyPrefix) {
-				continue
-			}
-
-			data, err := r.client.Get(ctx, key).Bytes()
-			if err != nil {
-				continue
-			}
+	page := &PayoutPage{TotalExact: true}
+	min := "-inf"
+	if filter.Status == "" && filter.Method == "" && filter.BatchID == "" && filter.Limit > 0 {
+		total, err := r.client.ZCard(ctx, createdIndexKey).Result()
+		if err != nil {
+			return nil, fmt.Errorf("count payouts: %w", err)
+		}
+		page.Total = int(total)
+		page.TotalExact = false
+		if after != nil {
+			min = strconv.FormatInt(after.createdMs, 10)
+		}
+	}
 
-			var payout model.Payout
-			if err := json.Unmarshal(data, &payout); err != nil {
-				continue
-			}
+	skip := filter.Offset
+	if after != nil {
+		skip = 0
+	}
+	index := createdIndexKey
+	switch {
+	case filter.Status != "":
+		index = statusIndexPrefix + string(filter.Status)
+	case filter.Method != "":
+		index = methodIndexPrefix + string(filter.Method)
+	}
 
-			// Apply filters
-			if filter.Status != "" && payout.Status != filter.Status {
-				continue
-			}
-			if filter.Method != "" && payout.Method != filter.Method {
-				continue
+	var last *model.Payout
+	err := r.forEachIndexedPayout(ctx, index, min, "+inf", func(payout *model.Payout) error {
+		if !filter.Matches(payout) {
+			return nil
+		}
+		if page.TotalExact {
+			page.Total++
+		}
+		if after != nil && !after.before(payout) {
+			return nil
+		}
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		if filter.Limit > 0 && len(page.Payouts) >= filter.Limit {
+			// Another match means there is a next page
+			if page.NextCursor == "" {
+				page.NextCursor = cursorAt(last).encode()
 			}
-			if filter.BatchID != "" && payout.BatchID != filter.BatchID {
-				continue
+			if !page.TotalExact {
+				return errPageFull
 			}
+			return nil
+		}
+		page.Payouts = append(page.Payouts, payout)
+		last = payout
+		return nil
+	})
+	if err != nil && err != errPageFull {
+		return nil, err
+	}
+	return page, nil
+}
 
-			payouts = append(payouts, &payout)
+func (r *RedisRepository) ForEachPayoutCreatedBetween(ctx context.Context, from, to time.Time, fn func(*model.Payout) error) error {
+	return r.forEachIndexedPayout(ctx, createdIndexKey, fmt.Sprintf("%d", from.UnixMilli()), fmt.Sprintf("(%d", to.UnixMilli()), fn)
+}
 
-			if filter.Limit > 0 && len(payouts) >= filter.Limit {
-				return payouts, nil
-			}
+// forEachIndexedPayout calls fn for every payout whose creation time is in
+// the score range [min, max] of index, oldest first and by ID for payouts
+// created in the same millisecond.
+//
+// Each chunk starts after the last creation time loaded rather than at an
+// offset into the range, so payouts indexed or dropped while iterating don't
+// shift it and cause others to be skipped or repeated.
+func (r *RedisRepository) forEachIndexedPayout(ctx context.Context, index, min, max string, fn func(*model.Payout) error) error {
+	rangeBy := &redis.ZRangeBy{
+		Min:   min,
+		Max:   max,
+		Count: exportChunkSize,
+	}
+
+	for {
+		entries, err := r.client.ZRangeByScoreWithScores(ctx, index, rangeBy).Result()
+		if err != nil {
+			return fmt.Errorf("range payout index: %w", err)
+		}
+		keys := make([]string, len(entries))
+		for i, entry := range entries {
+			keys[i] = payoutKeyPrefix + entry.Member.(string)
+		}
+		if err := r.loadPayouts(ctx, keys, fn); err != nil {
+			return err
+		}
+		if len(entries) < exportChunkSize {
+			return nil
 		}
 
-		cursor = nextCursor
-		if cursor == 0 {
-			break
+		// The chunk may have ended part way through the payouts created in
+		// its last millisecond; load the rest before moving past it
+		last := strconv.FormatFloat(entries[len(entries)-1].Score, 'f', -1, 64)
+		loaded := make(map[string]bool)
+		for _, key := range keys {
+			loaded[key] = true
+		}
+		ids, err := r.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: last, Max: last}).Result()
+		if err != nil {
+			return fmt.Errorf("range payout index: %w", err)
+		}
+		keys = keys[:0]
+		for _, id := range ids {
+			if !loaded[payoutKeyPrefix+id] {
+				keys = append(keys, payoutKeyPrefix+id)
+			}
+		}
+		if err := r.loadPayouts(ctx, keys, fn); err != nil {
+			return err
 		}
+		rangeBy.Min = "(" + last
+	}
+}
+
+// loadPayouts calls fn for each payout stored under keys, skipping any that
+// have expired or can't be decoded
+func (r *RedisRepository) loadPayouts(ctx context.Context, keys []string, fn func(*model.Payout) error) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return fmt.Errorf("load payouts: %w", err)
 	}
 
-	return payouts, nil
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // Payout expired since it was indexed
+		}
+
+		var payout model.Payout
+		if err := json.Unmarshal([]byte(data), &payout); err != nil {
+			continue
+		}
+		if err := fn(&payout); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// UpdatePayoutStatus reloads the payout and applies the new status, starting
+// over if another writer saves the payout in between
 func (r *RedisRepository) UpdatePayoutStatus(ctx context.Context, id string, status model.PayoutStatus, failureReason string) error {
+	for attempt := 1; ; attempt++ {
+		err := r.updatePayoutStatus(ctx, id, status, failureReason)
+		if !errors.Is(err, ErrConcurrentModification) || attempt >= maxUpdateAttempts {
+			return err
+		}
+	}
+}
+
+// maxUpdateAttempts bounds how often UpdatePayoutStatus reloads a payout that
+// keeps changing under it
+const maxUpdateAttempts = 3
+
+func (r *RedisRepository) updatePayoutStatus(ctx context.Context, id string, status model.PayoutStatus, failureReason string) error {
 	payout, err := r.GetPayout(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	payout.Status = status
+	if err := payout.TransitionTo(status); err != nil {
+		return err
+	}
 	payout.FailureReason = failureReason
 	payout.UpdatedAt = time.Now()
 
@@ -149,3 +535,148 @@ func (r *RedisRepository) UpdatePayoutStatus(ctx context.Context, id string, sta
 
 	return r.SavePayout(ctx, payout)
 }
+
+func (r *RedisRepository) SaveBatch(ctx context.Context, batch *model.PayoutBatch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("marshal batch: %w", err)
+	}
+
+	// Batches are kept as long as their payouts
+	if err := r.client.Set(ctx, batchKeyPrefix+batch.ID, data, payoutTTL).Err(); err != nil {
+		return r.writeError("save batch", err)
+	}
+	return nil
+}
+
+func (r *RedisRepository) GetBatch(ctx context.Context, id string) (*model.PayoutBatch, error) {
+	data, err := r.client.Get(ctx, batchKeyPrefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("batch not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get batch: %w", err)
+	}
+
+	var batch model.PayoutBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("unmarshal batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// ReservePickupCode adds code to the active pickup codes until expiresAt,
+// returning false if it is already active. Expired codes are pruned first so
+// they can be issued again.
+func (r *RedisRepository) ReservePickupCode(ctx context.Context, code string, expiresAt time.Time) (bool, error) {
+	pipe := r.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, pickupCodesKey, "-inf", fmt.Sprintf("(%d", time.Now().UnixMilli()))
+	added := pipe.ZAddNX(ctx, pickupCodesKey, redis.Z{Score: float64(expiresAt.UnixMilli()), Member: code})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, r.writeError("reserve pickup code", err)
+	}
+	return added.Val() == 1, nil
+}
+
+// releaseLockScript deletes a lock only while it still holds the releasing
+// token, so an expired lock since taken by another holder is left alone
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquirePayoutLock takes the lock named key under token for ttl, returning
+// false if another holder has it
+func (r *RedisRepository) AcquirePayoutLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	acquired, err := r.client.SetNX(ctx, lockKeyPrefix+key, token, ttl).Result()
+	if err != nil {
+		return false, r.writeError("acquire payout lock", err)
+	}
+	return acquired, nil
+}
+
+// ReleasePayoutLock releases the lock named key if token still holds it
+func (r *RedisRepository) ReleasePayoutLock(ctx context.Context, key, token string) error {
+	if err := releaseLockScript.Run(ctx, r.client, []string{lockKeyPrefix + key}, token).Err(); err != nil {
+		return r.writeError("release payout lock", err)
+	}
+	return nil
+}
+
+// PickupAttempts returns the wrong pickup codes counted against the payout
+func (r *RedisRepository) PickupAttempts(ctx context.Context, payoutID string) (int, error) {
+	attempts, err := r.client.Get(ctx, pickupAttemptsKey+payoutID).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get pickup attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// CountFailedPickupAttempt counts a wrong pickup code against the payout,
+// keeping the count for window after it, and returns the new count
+func (r *RedisRepository) CountFailedPickupAttempt(ctx context.Context, payoutID string, window time.Duration) (int, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, pickupAttemptsKey+payoutID)
+		pipe.Expire(ctx, pickupAttemptsKey+payoutID, window)
+		return nil
+	})
+	if err != nil {
+		return 0, r.writeError("count pickup attempt", err)
+	}
+	return int(incr.Val()), nil
+}
+
+// PublishPayoutStatus publishes a snapshot of the payout on its status
+// channel, reaching the payout's watchers on every replica
+func (r *RedisRepository) PublishPayoutStatus(ctx context.Context, payout *model.Payout) error {
+	data, err := json.Marshal(payout)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payout: %w", err)
+	}
+	if err := r.client.Publish(ctx, statusChannel+payout.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish payout status: %w", err)
+	}
+	return nil
+}
+
+// SubscribePayoutStatus subscribes to the payout's status channel, returning
+// once Redis has confirmed the subscription. The channel is closed when the
+// returned func is called or the subscription is lost.
+func (r *RedisRepository) SubscribePayoutStatus(ctx context.Context, payoutID string) (<-chan model.Payout, func(), error) {
+	pubsub := r.client.Subscribe(ctx, statusChannel+payoutID)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to payout status: %w", err)
+	}
+
+	changes := make(chan model.Payout)
+	done := make(chan struct{})
+	go func() {
+		defer close(changes)
+		for msg := range pubsub.Channel() {
+			var payout model.Payout
+			if err := json.Unmarshal([]byte(msg.Payload), &payout); err != nil {
+				continue
+			}
+			select {
+			case changes <- payout:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return changes, func() {
+		once.Do(func() {
+			close(done)
+			pubsub.Close()
+		})
+	}, nil
+}
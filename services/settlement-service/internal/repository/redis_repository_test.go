@@ -0,0 +1,684 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRepository(t *testing.T) *RedisRepository {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisRepository(client)
+}
+
+func TestRedisRepository_ForEachPayoutCreatedBetween(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	// Enough payouts in range to span several chunks
+	for i := 0; i < exportChunkSize*2+5; i++ {
+		payout := &model.Payout{
+			ID:         fmt.Sprintf("payout_%03d", i),
+			TransferID: fmt.Sprintf("transfer_%03d", i),
+			Status:     model.PayoutStatusCompleted,
+			CreatedAt:  base.Add(time.Duration(i) * time.Second),
+		}
+		if err := repo.SavePayout(ctx, payout); err != nil {
+			t.Fatalf("save payout: %v", err)
+		}
+	}
+
+	from := base.Add(10 * time.Second)
+	to := base.Add(150 * time.Second)
+
+	var ids []string
+	err := repo.ForEachPayoutCreatedBetween(ctx, from, to, func(p *model.Payout) error {
+		ids = append(ids, p.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ids) != 140 {
+		t.Fatalf("expected 140 payouts in range, got %d", len(ids))
+	}
+	if ids[0] != "payout_010" || ids[len(ids)-1] != "payout_149" {
+		t.Errorf("expected payout_010..payout_149 in order, got %s..%s", ids[0], ids[len(ids)-1])
+	}
+}
+
+func TestRedisRepository_ForEachPayoutCreatedBetween_StableWhileIndexChanges(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	saveAt := func(i int, createdAt time.Time) {
+		t.Helper()
+		payout := &model.Payout{
+			ID:         fmt.Sprintf("payout_%03d", i),
+			TransferID: fmt.Sprintf("transfer_%03d", i),
+			Status:     model.PayoutStatusCompleted,
+			CreatedAt:  createdAt,
+		}
+		if err := repo.SavePayout(ctx, payout); err != nil {
+			t.Fatalf("save payout: %v", err)
+		}
+	}
+	// More payouts created in one millisecond than fit in a chunk, then
+	// one a millisecond
+	for i := 0; i < exportChunkSize+10; i++ {
+		saveAt(i, base)
+	}
+	for i := exportChunkSize + 10; i < exportChunkSize*3; i++ {
+		saveAt(i, base.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	// Payouts already exported leave the index as they would on expiry
+	seen := make(map[string]int)
+	err := repo.ForEachPayoutCreatedBetween(ctx, base, base.Add(time.Hour), func(p *model.Payout) error {
+		seen[p.ID]++
+		return repo.client.ZRem(ctx, createdIndexKey, p.ID).Err()
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(seen) != exportChunkSize*3 {
+		t.Errorf("expected all %d payouts, got %d", exportChunkSize*3, len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("expected %s once, got %d times", id, n)
+		}
+	}
+}
+
+func TestRedisRepository_ForEachPayoutCreatedBetween_ResavedPayoutIndexedOnce(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1", TransferID: "transfer_1", Status: model.PayoutStatusPending, CreatedAt: time.Now()}
+	for _, status := range []model.PayoutStatus{model.PayoutStatusPending, model.PayoutStatusProcessing, model.PayoutStatusCompleted} {
+		payout.Status = status
+		if err := repo.SavePayout(ctx, payout); err != nil {
+			t.Fatalf("save payout: %v", err)
+		}
+	}
+
+	var seen []*model.Payout
+	err := repo.ForEachPayoutCreatedBetween(ctx, time.Now().Add(-time.Minute), time.Now().Add(time.Minute), func(p *model.Payout) error {
+		seen = append(seen, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(seen) != 1 || seen[0].Status != model.PayoutStatusCompleted {
+		t.Errorf("expected the latest version of the payout once, got %+v", seen)
+	}
+}
+
+func TestRedisRepository_SavePayoutWriteFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		respErr string
+		kind    string
+	}{
+		{"oom", "OOM command not allowed when used memory > 'maxmemory'.", WriteFailureOOM},
+		{"readonly", "READONLY You can't write against a read only replica.", WriteFailureReadOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { client.Close() })
+			var recorded []string
+			repo := NewRedisRepository(client).WithWriteFailureRecorder(func(operation, kind string) {
+				recorded = append(recorded, operation+"/"+kind)
+			})
+
+			mr.SetError(tt.respErr)
+			err := repo.SavePayout(context.Background(), &model.Payout{ID: "payout_1", TransferID: "transfer_1", CreatedAt: time.Now()})
+
+			var writeErr ErrWriteFailed
+			if !errors.As(err, &writeErr) {
+				t.Fatalf("expected ErrWriteFailed, got %v", err)
+			}
+			if writeErr.Kind != tt.kind {
+				t.Errorf("expected kind %s, got %s", tt.kind, writeErr.Kind)
+			}
+			if len(recorded) != 1 || recorded[0] != "save payout/"+tt.kind {
+				t.Errorf("expected the failure to be recorded once, got %v", recorded)
+			}
+		})
+	}
+}
+
+func TestRedisRepository_SavePayout_RejectsInvalidTransition(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1", TransferID: "transfer_1", Status: model.PayoutStatusPending, CreatedAt: time.Now()}
+	for _, status := range []model.PayoutStatus{model.PayoutStatusPending, model.PayoutStatusProcessing, model.PayoutStatusCompleted} {
+		payout.Status = status
+		if err := repo.SavePayout(ctx, payout); err != nil {
+			t.Fatalf("save %s payout: %v", status, err)
+		}
+	}
+
+	// A stale copy can't move the completed payout anywhere
+	stale := *payout
+	stale.Status = model.PayoutStatusCancelled
+	err := repo.SavePayout(ctx, &stale)
+
+	var transitionErr model.ErrInvalidTransition
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	if transitionErr.From != model.PayoutStatusCompleted || transitionErr.To != model.PayoutStatusCancelled {
+		t.Errorf("expected COMPLETED -> CANCELLED, got %s -> %s", transitionErr.From, transitionErr.To)
+	}
+
+	stored, err := repo.GetPayout(ctx, payout.ID)
+	if err != nil {
+		t.Fatalf("get payout: %v", err)
+	}
+	if stored.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected the stored payout to stay COMPLETED, got %s", stored.Status)
+	}
+}
+
+func TestRedisRepository_UpdatePayoutStatus_RejectsInvalidTransition(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1", TransferID: "transfer_1", Status: model.PayoutStatusCancelled, CreatedAt: time.Now()}
+	if err := repo.SavePayout(ctx, payout); err != nil {
+		t.Fatalf("save payout: %v", err)
+	}
+
+	err := repo.UpdatePayoutStatus(ctx, payout.ID, model.PayoutStatusCompleted, "")
+	var transitionErr model.ErrInvalidTransition
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestRedisRepository_SavePayout_RejectsStaleWrite(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1", TransferID: "transfer_1", Status: model.PayoutStatusFailed, CreatedAt: time.Now()}
+	if err := repo.SavePayout(ctx, payout); err != nil {
+		t.Fatalf("save payout: %v", err)
+	}
+	if payout.Version != 1 {
+		t.Fatalf("expected version 1 after the first save, got %d", payout.Version)
+	}
+
+	// A manual retry and the reconciler both load the payout
+	retry, _ := repo.GetPayout(ctx, payout.ID)
+	reconcile, _ := repo.GetPayout(ctx, payout.ID)
+
+	retry.Status = model.PayoutStatusPending
+	retry.RetryCount++
+	if err := repo.SavePayout(ctx, retry); err != nil {
+		t.Fatalf("save retry: %v", err)
+	}
+
+	reconcile.Status = model.PayoutStatusCancelled
+	if err := repo.SavePayout(ctx, reconcile); !errors.Is(err, ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification for the stale write, got %v", err)
+	}
+
+	stored, err := repo.GetPayout(ctx, payout.ID)
+	if err != nil {
+		t.Fatalf("get payout: %v", err)
+	}
+	if stored.Status != model.PayoutStatusPending || stored.Version != 2 {
+		t.Errorf("expected the retry to win at version 2, got %s at version %d", stored.Status, stored.Version)
+	}
+}
+
+func TestRedisRepository_SavePayout_ClaimsTransfer(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	first := &model.Payout{ID: "payout_1", TransferID: "transfer_1", Status: model.PayoutStatusPending, CreatedAt: time.Now()}
+	if err := repo.SavePayout(ctx, first); err != nil {
+		t.Fatalf("save payout: %v", err)
+	}
+
+	second := &model.Payout{ID: "payout_2", TransferID: "transfer_1", Status: model.PayoutStatusPending, CreatedAt: time.Now()}
+	if err := repo.SavePayout(ctx, second); !errors.Is(err, ErrTransferClaimed) {
+		t.Fatalf("expected ErrTransferClaimed for a second payout of the transfer, got %v", err)
+	}
+	if stored, _ := repo.GetPayoutByTransferID(ctx, "transfer_1"); stored == nil || stored.ID != first.ID {
+		t.Fatalf("expected the transfer to keep its first payout, got %+v", stored)
+	}
+
+	// Updating the transfer's own payout doesn't conflict with its claim
+	first.Status = model.PayoutStatusProcessing
+	if err := repo.SavePayout(ctx, first); err != nil {
+		t.Errorf("expected the claiming payout to save, got %v", err)
+	}
+}
+
+func TestRedisRepository_PickupCodes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	if ok, err := repo.ReservePickupCode(ctx, "12345678", expiresAt); err != nil || !ok {
+		t.Fatalf("expected first reservation to succeed, got %v, %v", ok, err)
+	}
+	if ok, _ := repo.ReservePickupCode(ctx, "12345678", expiresAt); ok {
+		t.Error("expected an active code to be rejected")
+	}
+
+	// Redeeming the payout releases its code
+	payout := &model.Payout{
+		ID:         "payout_1",
+		TransferID: "transfer_1",
+		Status:     model.PayoutStatusPickedUp,
+		PickupCode: "12345678",
+		CreatedAt:  time.Now(),
+	}
+	if err := repo.SavePayout(ctx, payout); err != nil {
+		t.Fatalf("save payout: %v", err)
+	}
+	if ok, _ := repo.ReservePickupCode(ctx, "12345678", expiresAt); !ok {
+		t.Error("expected a redeemed code to be reusable")
+	}
+}
+
+func TestRedisRepository_ExpiredPickupCodeIsReusable(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	if ok, _ := repo.ReservePickupCode(ctx, "12345678", time.Now().Add(-time.Minute)); !ok {
+		t.Fatal("expected reservation to succeed")
+	}
+	if ok, _ := repo.ReservePickupCode(ctx, "12345678", time.Now().Add(time.Hour)); !ok {
+		t.Error("expected an expired code to be reusable")
+	}
+}
+
+func savePagingPayouts(t *testing.T, repo *RedisRepository, n int) time.Time {
+	t.Helper()
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	for i := 0; i < n; i++ {
+		status := model.PayoutStatusCompleted
+		if i%2 == 1 {
+			status = model.PayoutStatusFailed
+		}
+		payout := &model.Payout{
+			ID:         fmt.Sprintf("payout_%03d", i),
+			TransferID: fmt.Sprintf("transfer_%03d", i),
+			Status:     status,
+			// Pairs share a creation time, so ties are ordered by ID
+			CreatedAt: base.Add(time.Duration(i/2) * time.Second),
+		}
+		if err := repo.SavePayout(context.Background(), payout); err != nil {
+			t.Fatalf("save payout: %v", err)
+		}
+	}
+	return base
+}
+
+// pageThrough lists every page of filter, returning the payout IDs in order
+// and the first page's totals
+func pageThrough(t *testing.T, repo *RedisRepository, filter PayoutFilter, onPage func(int)) ([]string, *PayoutPage) {
+	t.Helper()
+	var ids []string
+	var first *PayoutPage
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("expected paging to finish")
+		}
+		page, err := repo.ListPayoutsPage(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("list page %d: %v", pages, err)
+		}
+		if first == nil {
+			first = page
+		}
+		for _, p := range page.Payouts {
+			ids = append(ids, p.ID)
+		}
+		if onPage != nil {
+			onPage(pages)
+		}
+		if page.NextCursor == "" {
+			return ids, first
+		}
+		if len(page.Payouts) != filter.Limit {
+			t.Errorf("expected a full page before the last, got %d payouts", len(page.Payouts))
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func assertSequence(t *testing.T, ids []string, want []string) {
+	t.Helper()
+	if len(ids) != len(want) {
+		t.Fatalf("expected %d payouts across pages, got %d: %v", len(want), len(ids), ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected payouts %v across pages, got %v", want, ids)
+		}
+	}
+}
+
+func TestRedisRepository_ListPayoutsPage_Cursor(t *testing.T) {
+	repo := newTestRepository(t)
+	savePagingPayouts(t, repo, 25)
+
+	var want []string
+	for i := 0; i < 25; i++ {
+		want = append(want, fmt.Sprintf("payout_%03d", i))
+	}
+
+	ids, first := pageThrough(t, repo, PayoutFilter{Limit: 10}, nil)
+	assertSequence(t, ids, want)
+	if first.Total != 25 || first.TotalExact {
+		t.Errorf("expected an estimated total of 25, got %d (exact %v)", first.Total, first.TotalExact)
+	}
+}
+
+func TestRedisRepository_ListPayoutsPage_FilteredTotalIsExact(t *testing.T) {
+	repo := newTestRepository(t)
+	savePagingPayouts(t, repo, 25)
+
+	var want []string
+	for i := 0; i < 25; i += 2 {
+		want = append(want, fmt.Sprintf("payout_%03d", i))
+	}
+
+	ids, first := pageThrough(t, repo, PayoutFilter{Status: model.PayoutStatusCompleted, Limit: 10}, nil)
+	assertSequence(t, ids, want)
+	if first.Total != 13 || !first.TotalExact {
+		t.Errorf("expected an exact total of 13, got %d (exact %v)", first.Total, first.TotalExact)
+	}
+}
+
+func TestRedisRepository_ListPayoutsPage_StableWhilePayoutsAreCreated(t *testing.T) {
+	repo := newTestRepository(t)
+	base := savePagingPayouts(t, repo, 25)
+
+	ids, _ := pageThrough(t, repo, PayoutFilter{Limit: 10}, func(page int) {
+		if page != 0 {
+			return
+		}
+		// A payout created mid-listing lands after the existing ones
+		late := &model.Payout{ID: "payout_late", TransferID: "transfer_late", CreatedAt: base.Add(time.Minute)}
+		if err := repo.SavePayout(context.Background(), late); err != nil {
+			t.Fatalf("save payout: %v", err)
+		}
+	})
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("payout %s listed twice", id)
+		}
+		seen[id] = true
+	}
+	if len(ids) != 26 || ids[25] != "payout_late" {
+		t.Errorf("expected all 25 payouts then the late one, got %v", ids)
+	}
+}
+
+func TestRedisRepository_ListPayoutsPage_Offset(t *testing.T) {
+	repo := newTestRepository(t)
+	savePagingPayouts(t, repo, 25)
+
+	var ids []string
+	for offset := 0; offset < 30; offset += 10 {
+		payouts, err := repo.ListPayouts(context.Background(), PayoutFilter{Limit: 10, Offset: offset})
+		if err != nil {
+			t.Fatalf("list payouts: %v", err)
+		}
+		for _, p := range payouts {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	var want []string
+	for i := 0; i < 25; i++ {
+		want = append(want, fmt.Sprintf("payout_%03d", i))
+	}
+	assertSequence(t, ids, want)
+}
+
+func TestRedisRepository_ListPayoutsPage_InvalidCursor(t *testing.T) {
+	repo := newTestRepository(t)
+
+	if _, err := repo.ListPayoutsPage(context.Background(), PayoutFilter{Limit: 10, Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestRedisRepository_ListPayoutsPage_StatusAndMethodIndexes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	payout := &model.Payout{ID: "payout_moving", TransferID: "transfer_moving", Method: model.PayoutMethodMobileWallet, Status: model.PayoutStatusPending, CreatedAt: now}
+	other := &model.Payout{ID: "payout_other", TransferID: "transfer_other", Method: model.PayoutMethodBankAccount, Status: model.PayoutStatusPending, CreatedAt: now}
+	for _, p := range []*model.Payout{payout, other} {
+		if err := repo.SavePayout(ctx, p); err != nil {
+			t.Fatalf("save payout: %v", err)
+		}
+	}
+	payout.Status = model.PayoutStatusProcessing
+	if err := repo.SavePayout(ctx, payout); err != nil {
+		t.Fatalf("save payout: %v", err)
+	}
+
+	for _, tc := range []struct {
+		filter PayoutFilter
+		want   []string
+	}{
+		{PayoutFilter{Status: model.PayoutStatusPending}, []string{"payout_other"}},
+		{PayoutFilter{Status: model.PayoutStatusProcessing}, []string{"payout_moving"}},
+		{PayoutFilter{Method: model.PayoutMethodMobileWallet}, []string{"payout_moving"}},
+	} {
+		page, err := repo.ListPayoutsPage(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("list payouts: %v", err)
+		}
+		var ids []string
+		for _, p := range page.Payouts {
+			ids = append(ids, p.ID)
+		}
+		assertSequence(t, ids, tc.want)
+	}
+
+	if n, _ := repo.client.ZCard(ctx, statusIndexPrefix+string(model.PayoutStatusPending)).Result(); n != 1 {
+		t.Errorf("expected the payout to leave the PENDING index, got %d entries", n)
+	}
+}
+
+func TestRedisRepository_BackfillIndexes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// A payout written before the indexes existed, next to a transfer index
+	// key under the same prefix
+	legacy := model.Payout{ID: "payout_legacy", TransferID: "transfer_legacy", Method: model.PayoutMethodBankAccount, Status: model.PayoutStatusCompleted, CreatedAt: time.Now()}
+	data, _ := json.Marshal(legacy)
+	repo.client.Set(ctx, payoutKeyPrefix+legacy.ID, data, time.Hour)
+	repo.client.Set(ctx, transferKeyPrefix+legacy.TransferID, legacy.ID, time.Hour)
+
+	if payouts, _ := repo.ListPayouts(ctx, PayoutFilter{}); len(payouts) != 0 {
+		t.Fatalf("expected the legacy payout to be unindexed, got %d payouts", len(payouts))
+	}
+
+	indexed, err := repo.BackfillIndexes(ctx)
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if indexed != 1 {
+		t.Errorf("expected 1 payout indexed, got %d", indexed)
+	}
+
+	for _, filter := range []PayoutFilter{{}, {Status: model.PayoutStatusCompleted}, {Method: model.PayoutMethodBankAccount}} {
+		payouts, err := repo.ListPayouts(ctx, filter)
+		if err != nil {
+			t.Fatalf("list payouts: %v", err)
+		}
+		if len(payouts) != 1 || payouts[0].ID != legacy.ID {
+			t.Errorf("expected the legacy payout listed for %+v, got %v", filter, payouts)
+		}
+	}
+}
+
+func TestRedisRepository_Batches(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	batch := &model.PayoutBatch{
+		ID:           "batch_1",
+		Method:       model.PayoutMethodBankAccount,
+		Currency:     "PHP",
+		TotalPayouts: 2,
+		TotalAmount:  "200.00",
+		CreatedAt:    time.Now().Truncate(time.Millisecond),
+	}
+	if err := repo.SaveBatch(ctx, batch); err != nil {
+		t.Fatalf("save batch: %v", err)
+	}
+
+	got, err := repo.GetBatch(ctx, "batch_1")
+	if err != nil {
+		t.Fatalf("get batch: %v", err)
+	}
+	if got.Method != batch.Method || got.Currency != batch.Currency || got.TotalPayouts != 2 ||
+		got.TotalAmount != "200.00" || !got.CreatedAt.Equal(batch.CreatedAt) {
+		t.Errorf("expected %+v, got %+v", batch, got)
+	}
+
+	if _, err := repo.GetBatch(ctx, "batch_missing"); err == nil {
+		t.Error("expected an error for an unknown batch")
+	}
+}
+
+func TestRedisRepository_PayoutLock(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	acquired, err := repo.AcquirePayoutLock(ctx, "payout_1", "token_a", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("expected to acquire the lock, got %v, %v", acquired, err)
+	}
+	if acquired, _ := repo.AcquirePayoutLock(ctx, "payout_1", "token_b", time.Minute); acquired {
+		t.Fatal("expected a held lock not to be acquired again")
+	}
+	if acquired, _ := repo.AcquirePayoutLock(ctx, "payout_2", "token_b", time.Minute); !acquired {
+		t.Error("expected another payout's lock to be independent")
+	}
+
+	// Only the holder's token releases the lock
+	if err := repo.ReleasePayoutLock(ctx, "payout_1", "token_b"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if acquired, _ := repo.AcquirePayoutLock(ctx, "payout_1", "token_b", time.Minute); acquired {
+		t.Fatal("expected a release with the wrong token to keep the lock")
+	}
+	if err := repo.ReleasePayoutLock(ctx, "payout_1", "token_a"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if acquired, _ := repo.AcquirePayoutLock(ctx, "payout_1", "token_b", time.Minute); !acquired {
+		t.Error("expected the released lock to be acquired")
+	}
+}
+
+func TestRedisRepository_PickupAttempts(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	if attempts, err := repo.PickupAttempts(ctx, "payout_1"); err != nil || attempts != 0 {
+		t.Fatalf("expected no attempts counted, got %d, %v", attempts, err)
+	}
+	for want := 1; want <= 3; want++ {
+		attempts, err := repo.CountFailedPickupAttempt(ctx, "payout_1", time.Hour)
+		if err != nil || attempts != want {
+			t.Fatalf("expected attempt %d to be counted, got %d, %v", want, attempts, err)
+		}
+	}
+	if attempts, _ := repo.PickupAttempts(ctx, "payout_1"); attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if attempts, _ := repo.PickupAttempts(ctx, "payout_2"); attempts != 0 {
+		t.Errorf("expected another payout's attempts to be independent, got %d", attempts)
+	}
+	if ttl := repo.client.TTL(ctx, pickupAttemptsKey+"payout_1").Val(); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected the count to expire within the window, got TTL %s", ttl)
+	}
+}
+
+func TestRedisRepository_PayoutStatusPubSub(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	changes, unsubscribe, err := repo.SubscribePayoutStatus(ctx, "payout_1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	// Only the subscribed payout's changes are received
+	for _, payout := range []*model.Payout{
+		{ID: "payout_2", Status: model.PayoutStatusCompleted},
+		{ID: "payout_1", Status: model.PayoutStatusCompleted},
+	} {
+		if err := repo.PublishPayoutStatus(ctx, payout); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	select {
+	case change := <-changes:
+		if change.ID != "payout_1" || change.Status != model.PayoutStatusCompleted {
+			t.Errorf("expected payout_1 COMPLETED, got %s %s", change.ID, change.Status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the status change")
+	}
+
+	unsubscribe()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("expected the channel to be closed after unsubscribing")
+		}
+	case <-time.After(time.Second):
+		t.Error("timed out waiting for the channel to close")
+	}
+}
+
+func TestRedisRepository_GetPayout_NotFound(t *testing.T) {
+	repo := newTestRepository(t)
+
+	payout, err := repo.GetPayout(context.Background(), "payout_missing")
+	if !errors.Is(err, ErrPayoutNotFound) {
+		t.Fatalf("expected ErrPayoutNotFound, got %v", err)
+	}
+	if payout != nil {
+		t.Errorf("expected no payout, got %+v", payout)
+	}
+}
@@ -2,26 +2,49 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/movra/settlement-service/internal/model"
 )
 
 // PayoutRepository defines the interface for payout storage
 type PayoutRepository interface {
-	// SavePayout saves or updates a payout
+	// SavePayout saves or updates a payout, failing with
+	// ErrConcurrentModification if its Version is stale, or with
+	// ErrTransferClaimed if it is new and its transfer already has a payout
 	SavePayout(ctx context.Context, payout *model.Payout) error
 
 	// GetPayout retrieves a payout by ID
 	GetPayout(ctx context.Context, id string) (*model.Payout, error)
 
-	// GetPayoutByTransferID retrieves a payout by transfer ID
+	// GetPayoutByTransferID retrieves a payout by transfer ID, returning nil
+	// if the transfer has no payout
 	GetPayoutByTransferID(ctx context.Context, transferID string) (*model.Payout, error)
 
 	// ListPayouts retrieves payouts with optional filters
 	ListPayouts(ctx context.Context, filter PayoutFilter) ([]*model.Payout, error)
 
+	// ListPayoutsPage retrieves one page of payouts matching the filters,
+	// oldest first, with the total across all pages and a cursor for the next
+	ListPayoutsPage(ctx context.Context, filter PayoutFilter) (*PayoutPage, error)
+
+	// ForEachPayoutCreatedBetween calls fn for every payout created in [from, to),
+	// oldest first, loading payouts in chunks rather than all at once
+	ForEachPayoutCreatedBetween(ctx context.Context, from, to time.Time, fn func(*model.Payout) error) error
+
 	// UpdatePayoutStatus updates only the status and related fields
 	UpdatePayoutStatus(ctx context.Context, id string, status model.PayoutStatus, failureReason string) error
+
+	// SaveBatch saves or updates a payout batch
+	SaveBatch(ctx context.Context, batch *model.PayoutBatch) error
+
+	// GetBatch retrieves a payout batch by ID
+	GetBatch(ctx context.Context, id string) (*model.PayoutBatch, error)
 }
 
 // PayoutFilter defines filters for listing payouts
@@ -31,4 +54,108 @@ type PayoutFilter struct {
 	BatchID string
 	Limit   int
 	Offset  int
+	Cursor  string // NextCursor of the previous page; takes precedence over Offset
+}
+
+// Matches reports whether the payout passes the filter's status, method and
+// batch filters
+func (f PayoutFilter) Matches(payout *model.Payout) bool {
+	return (f.Status == "" || payout.Status == f.Status) &&
+		(f.Method == "" || payout.Method == f.Method) &&
+		(f.BatchID == "" || payout.BatchID == f.BatchID)
+}
+
+// PayoutPage is one page of a payout listing
+type PayoutPage struct {
+	Payouts []*model.Payout
+
+	// Total is the number of payouts matching the filters across all pages.
+	// It is exact when TotalExact is set, and otherwise estimated from an
+	// index that may still count payouts that have since expired.
+	Total      int
+	TotalExact bool
+
+	// NextCursor resumes the listing after this page, and is empty on the
+	// last page. Payouts created since the listing began appear on later
+	// pages; none are skipped or repeated.
+	NextCursor string
+}
+
+// ErrInvalidCursor is returned for a pagination cursor that wasn't issued
+// by a previous page
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// payoutCursor is a position in the listing order: creation time in
+// milliseconds, then payout ID
+type payoutCursor struct {
+	createdMs int64
+	id        string
+}
+
+func cursorAt(payout *model.Payout) payoutCursor {
+	return payoutCursor{createdMs: payout.CreatedAt.UnixMilli(), id: payout.ID}
+}
+
+// before reports whether the payout comes after the cursor
+func (c payoutCursor) before(payout *model.Payout) bool {
+	at := cursorAt(payout)
+	return at.createdMs > c.createdMs || (at.createdMs == c.createdMs && at.id > c.id)
+}
+
+func (c payoutCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.createdMs, 10) + ":" + c.id))
+}
+
+func decodePayoutCursor(token string) (payoutCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return payoutCursor{}, ErrInvalidCursor
+	}
+	ms, id, ok := strings.Cut(string(raw), ":")
+	createdMs, err := strconv.ParseInt(ms, 10, 64)
+	if !ok || err != nil || id == "" {
+		return payoutCursor{}, ErrInvalidCursor
+	}
+	return payoutCursor{createdMs: createdMs, id: id}, nil
+}
+
+// Write failure kinds reported by ErrWriteFailed
+const (
+	WriteFailureOOM      = "oom"      // Redis is at maxmemory and rejects writes
+	WriteFailureReadOnly = "readonly" // the Redis node is a read-only replica
+	WriteFailureOther    = "error"    // any other write failure, e.g. a lost connection
+)
+
+// WriteFailureRecorder is notified of every failed write with the operation
+// and the failure kind
+type WriteFailureRecorder func(operation, kind string)
+
+// ErrPayoutNotFound is returned by GetPayout when no payout has the ID
+var ErrPayoutNotFound = errors.New("payout not found")
+
+// ErrConcurrentModification is returned by SavePayout when the payout was
+// saved by another writer since it was loaded. Callers should reload the
+// payout and apply their change again.
+var ErrConcurrentModification = errors.New("payout was modified concurrently")
+
+// ErrTransferClaimed is returned by SavePayout when a new payout's transfer
+// already has a payout, e.g. when a funded event is redelivered while the
+// first delivery is still being handled. The transfer's payout is the one
+// already stored.
+var ErrTransferClaimed = errors.New("transfer already has a payout")
+
+// ErrWriteFailed is returned when the store rejects a write, e.g. because
+// Redis is out of memory. It means the store is temporarily unavailable.
+type ErrWriteFailed struct {
+	Operation string
+	Kind      string
+	Err       error
+}
+
+func (e ErrWriteFailed) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Operation, e.Kind, e.Err)
+}
+
+func (e ErrWriteFailed) Unwrap() error {
+	return e.Err
 }
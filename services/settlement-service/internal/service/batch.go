@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/repository"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidBatch is returned for a batch that can't be created; none of its
+// payouts are initiated
+type ErrInvalidBatch struct {
+	Reason string
+}
+
+func (e ErrInvalidBatch) Error() string {
+	return "invalid batch: " + e.Reason
+}
+
+// CreateBatch initiates a payout for every request as members of one batch.
+// A batch pays out in a single method and currency, and every request is
+// validated before any payout is created. Once the batch exists, a payout
+// that can't be initiated is logged and left out rather than failing the
+// rest, as is a transfer that already has a payout, which keeps it; both are
+// counted in the batch's FailedInitiations.
+func (s *PayoutService) CreateBatch(ctx context.Context, reqs []InitiatePayoutRequest) (*model.PayoutBatch, error) {
+	if len(reqs) == 0 {
+		return nil, ErrInvalidBatch{Reason: "no payouts"}
+	}
+
+	total := decimal.Zero
+	for i := range reqs {
+		req := &reqs[i]
+		if req.Method != reqs[0].Method {
+			return nil, ErrInvalidBatch{Reason: fmt.Sprintf("transfer %s pays out by %s, not %s", req.TransferID, req.Method, reqs[0].Method)}
+		}
+		if req.Currency != reqs[0].Currency {
+			return nil, ErrInvalidBatch{Reason: fmt.Sprintf("transfer %s pays out in %s, not %s", req.TransferID, req.Currency, reqs[0].Currency)}
+		}
+		if err := s.validateInitiateRequest(req); err != nil {
+			return nil, ErrInvalidBatch{Reason: fmt.Sprintf("transfer %s: %v", req.TransferID, err)}
+		}
+		amount, err := decimal.NewFromString(req.Amount)
+		if err != nil {
+			return nil, ErrInvalidBatch{Reason: fmt.Sprintf("transfer %s: invalid amount %q", req.TransferID, req.Amount)}
+		}
+		total = total.Add(amount)
+	}
+
+	now := time.Now()
+	batch := &model.PayoutBatch{
+		ID:           fmt.Sprintf("batch_%d", now.UnixNano()),
+		Method:       reqs[0].Method,
+		Currency:     reqs[0].Currency,
+		TotalPayouts: len(reqs),
+		TotalAmount:  total.StringFixed(int32(model.Precision(reqs[0].Currency))),
+		CreatedAt:    now,
+	}
+	if err := s.repo.SaveBatch(ctx, batch); err != nil {
+		return nil, fmt.Errorf("save batch: %w", err)
+	}
+
+	for i := range reqs {
+		payout, err := s.initiatePayout(ctx, &reqs[i], batch.ID)
+		if err != nil {
+			s.logger.Error("Failed to initiate batch payout",
+				zap.String("batchId", batch.ID),
+				zap.String("transferId", reqs[i].TransferID),
+				zap.Error(err),
+			)
+			batch.FailedInitiations++
+			continue
+		}
+		if payout.BatchID != batch.ID {
+			s.logger.Warn("Transfer already has a payout outside the batch",
+				zap.String("batchId", batch.ID),
+				zap.String("transferId", reqs[i].TransferID),
+				zap.String("payoutId", payout.ID),
+			)
+			batch.FailedInitiations++
+		}
+	}
+
+	if batch.FailedInitiations > 0 {
+		if err := s.repo.SaveBatch(ctx, batch); err != nil {
+			return nil, fmt.Errorf("save batch: %w", err)
+		}
+	}
+
+	return s.GetBatch(ctx, batch.ID)
+}
+
+// GetBatch retrieves a batch with its completed and failed counts recomputed
+// from the payouts currently in it. TotalPayouts and TotalAmount stay as the
+// batch was requested; the requests that never became payouts are reported
+// in FailedInitiations.
+func (s *PayoutService) GetBatch(ctx context.Context, batchID string) (*model.PayoutBatch, error) {
+	batch, err := s.repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	payouts, err := s.repo.ListPayouts(ctx, repository.PayoutFilter{BatchID: batchID})
+	if err != nil {
+		return nil, fmt.Errorf("list batch payouts: %w", err)
+	}
+
+	batch.CompletedPayouts = 0
+	batch.FailedPayouts = 0
+	batch.CompletedAt = nil
+
+	var lastSettled time.Time
+	settled := 0
+	for _, payout := range payouts {
+		switch payout.Status {
+		case model.PayoutStatusCompleted, model.PayoutStatusPickedUp:
+			batch.CompletedPayouts++
+		case model.PayoutStatusFailed, model.PayoutStatusPermanentlyFailed, model.PayoutStatusCancelled:
+			batch.FailedPayouts++
+		}
+
+		if payout.Status.IsTerminal() {
+			settled++
+			at := payout.UpdatedAt
+			if payout.CompletedAt != nil {
+				at = *payout.CompletedAt
+			}
+			if at.After(lastSettled) {
+				lastSettled = at
+			}
+		}
+	}
+
+	// A batch is complete once every payout in it has settled either way
+	if len(payouts) > 0 && settled == len(payouts) {
+		batch.CompletedAt = &lastSettled
+	}
+
+	return batch, nil
+}
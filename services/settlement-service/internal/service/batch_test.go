@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// transferFailingProvider fails the payouts of the given transfers and
+// completes the rest
+type transferFailingProvider struct {
+	fail map[string]bool
+}
+
+func (p *transferFailingProvider) ProcessPayout(ctx context.Context, payout *model.Payout) (*provider.ProviderResult, error) {
+	if p.fail[payout.TransferID] {
+		return &provider.ProviderResult{
+			ProviderReference: "FAIL_" + payout.ID,
+			Status:            model.PayoutStatusFailed,
+			FailureReason:     "account closed",
+			FailureCategory:   provider.FailurePermanent,
+		}, nil
+	}
+	return &provider.ProviderResult{
+		ProviderReference: "OK_" + payout.ID,
+		Status:            model.PayoutStatusCompleted,
+	}, nil
+}
+
+func (p *transferFailingProvider) CheckStatus(ctx context.Context, providerReference string) (*provider.ProviderStatus, error) {
+	return &provider.ProviderStatus{Status: model.PayoutStatusCompleted}, nil
+}
+
+func (p *transferFailingProvider) CancelPayout(ctx context.Context, providerReference string) error {
+	return nil
+}
+
+func (p *transferFailingProvider) Name() string {
+	return "transfer-failing"
+}
+
+func batchRequest(transferID, amount string) InitiatePayoutRequest {
+	return InitiatePayoutRequest{
+		TransferID: transferID,
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     amount,
+		Currency:   "PHP",
+		Recipient: model.Recipient{
+			Type:          model.PayoutMethodBankAccount,
+			BankCode:      "BDO",
+			AccountNumber: "1234567890",
+		},
+	}
+}
+
+func TestPayoutService_CreateBatch_PartialFailure(t *testing.T) {
+	repo := NewMockRepository()
+	prov := &transferFailingProvider{fail: map[string]bool{"transfer_b": true}}
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	batch, err := svc.CreateBatch(context.Background(), []InitiatePayoutRequest{
+		batchRequest("transfer_a", "100.00"),
+		batchRequest("transfer_b", "250.50"),
+		batchRequest("transfer_c", "149.50"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if batch.TotalPayouts != 3 || batch.CompletedPayouts != 2 || batch.FailedPayouts != 1 {
+		t.Errorf("expected 3 payouts with 2 completed and 1 failed, got %d/%d/%d",
+			batch.TotalPayouts, batch.CompletedPayouts, batch.FailedPayouts)
+	}
+	if batch.TotalAmount != "500.00" {
+		t.Errorf("expected total amount 500.00, got %s", batch.TotalAmount)
+	}
+	if batch.Method != model.PayoutMethodBankAccount || batch.Currency != "PHP" {
+		t.Errorf("expected a BANK_ACCOUNT PHP batch, got %s %s", batch.Method, batch.Currency)
+	}
+	if batch.CompletedAt == nil {
+		t.Error("expected a batch whose payouts have all settled to be complete")
+	}
+
+	for _, p := range repo.payouts {
+		if p.BatchID != batch.ID {
+			t.Errorf("expected payout for %s in batch %s, got %q", p.TransferID, batch.ID, p.BatchID)
+		}
+	}
+}
+
+func TestPayoutService_CreateBatch_ReportsFailedInitiations(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	ctx := context.Background()
+
+	// transfer_a already has a payout, so it stays outside the batch
+	existing := batchRequest("transfer_a", "100.00")
+	if _, err := svc.InitiatePayout(ctx, &existing); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	created, err := svc.CreateBatch(ctx, []InitiatePayoutRequest{
+		batchRequest("transfer_a", "100.00"),
+		batchRequest("transfer_b", "100.10"),
+		batchRequest("transfer_c", "100.20"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	batch, err := svc.GetBatch(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if batch.TotalPayouts != 3 || batch.FailedInitiations != 1 {
+		t.Errorf("expected 3 payouts with 1 failed initiation, got %d/%d", batch.TotalPayouts, batch.FailedInitiations)
+	}
+	if batch.CompletedPayouts != 2 || batch.FailedPayouts != 0 {
+		t.Errorf("expected the 2 batched payouts completed, got %d/%d", batch.CompletedPayouts, batch.FailedPayouts)
+	}
+	if batch.TotalAmount != "300.30" {
+		t.Errorf("expected the requested total 300.30, got %s", batch.TotalAmount)
+	}
+	if batch.CompletedAt == nil {
+		t.Error("expected a batch whose payouts have all settled to be complete")
+	}
+}
+
+func TestPayoutService_GetBatch_RecomputesFromPayouts(t *testing.T) {
+	repo := NewMockRepository()
+	prov := &pendingProvider{checked: make(chan struct{}, 1)}
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	created, err := svc.CreateBatch(context.Background(), []InitiatePayoutRequest{
+		batchRequest("transfer_a", "100.00"),
+		batchRequest("transfer_b", "100.00"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if created.CompletedPayouts != 0 || created.FailedPayouts != 0 || created.CompletedAt != nil {
+		t.Errorf("expected an in-flight batch, got %+v", created)
+	}
+
+	prov.complete()
+	if _, err := svc.ReconcilePayouts(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	batch, err := svc.GetBatch(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if batch.TotalPayouts != 2 || batch.CompletedPayouts != 2 || batch.FailedPayouts != 0 {
+		t.Errorf("expected both payouts completed, got %d/%d/%d",
+			batch.TotalPayouts, batch.CompletedPayouts, batch.FailedPayouts)
+	}
+	if batch.CompletedAt == nil {
+		t.Error("expected the batch to be complete")
+	}
+}
+
+func TestPayoutService_CreateBatch_RejectsMixedCurrencies(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+
+	mixed := batchRequest("transfer_b", "100.00")
+	mixed.Currency = "IDR"
+	_, err := svc.CreateBatch(context.Background(), []InitiatePayoutRequest{
+		batchRequest("transfer_a", "100.00"),
+		mixed,
+	})
+
+	var invalid ErrInvalidBatch
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidBatch, got: %v", err)
+	}
+	if len(repo.payouts) != 0 || len(repo.batches) != 0 {
+		t.Errorf("expected nothing to be saved, got %d payouts and %d batches", len(repo.payouts), len(repo.batches))
+	}
+}
+
+func TestPayoutService_CreateBatch_TotalInCurrencyPrecision(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+
+	first, second := batchRequest("transfer_a", "20000"), batchRequest("transfer_b", "30000")
+	first.Currency, second.Currency = "IDR", "IDR"
+	batch, err := svc.CreateBatch(context.Background(), []InitiatePayoutRequest{first, second})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if batch.TotalAmount != "50000" {
+		t.Errorf("expected an IDR total with no decimal places, got %q", batch.TotalAmount)
+	}
+}
+
+func TestPayoutService_GetBatch_NotFound(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+
+	if _, err := svc.GetBatch(context.Background(), "batch_missing"); err == nil {
+		t.Error("expected an error for an unknown batch")
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// GetPayoutByTransferID retrieves the payout for a transfer, or nil if the
+// transfer has no payout yet
+func (s *PayoutService) GetPayoutByTransferID(ctx context.Context, transferID string) (*model.Payout, error) {
+	return s.repo.GetPayoutByTransferID(ctx, transferID)
+}
+
+// PayoutConflicts returns the fields in which a repeated request for a
+// transfer differs from the payout already created for it. An empty result
+// means the request is a plain duplicate.
+func PayoutConflicts(existing *model.Payout, req *InitiatePayoutRequest) []string {
+	var conflicts []string
+	if !sameAmount(existing.Amount, req.Amount) {
+		conflicts = append(conflicts, "amount")
+	}
+	if existing.Currency != req.Currency {
+		conflicts = append(conflicts, "currency")
+	}
+	if existing.Method != req.Method {
+		conflicts = append(conflicts, "method")
+	}
+	if existing.Recipient != req.Recipient {
+		conflicts = append(conflicts, "recipient")
+	}
+	return conflicts
+}
+
+// sameAmount compares amounts numerically, so "100" and "100.00" match
+func sameAmount(a, b string) bool {
+	x, errA := decimal.NewFromString(a)
+	y, errB := decimal.NewFromString(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return x.Equal(y)
+}
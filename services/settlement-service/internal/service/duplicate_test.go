@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/movra/settlement-service/internal/model"
+)
+
+func TestPayoutConflicts_Amount(t *testing.T) {
+	tests := []struct {
+		existing string
+		repeated string
+		conflict bool
+	}{
+		{"100.10", "100.1", false},
+		{"100", "100.00", false},
+		{"100.10", "100.11", true},
+		// Equal as float64, but not the same amount
+		{"0.3", "0.30000000000000001", true},
+	}
+
+	for _, tt := range tests {
+		existing := &model.Payout{Amount: tt.existing, Currency: "SGD", Method: model.PayoutMethodBankAccount}
+		req := &InitiatePayoutRequest{Amount: tt.repeated, Currency: "SGD", Method: model.PayoutMethodBankAccount}
+
+		conflicts := PayoutConflicts(existing, req)
+		if got := len(conflicts) == 1 && conflicts[0] == "amount"; got != tt.conflict {
+			t.Errorf("%s vs %s: expected conflict %v, got %v", tt.existing, tt.repeated, tt.conflict, conflicts)
+		}
+	}
+}
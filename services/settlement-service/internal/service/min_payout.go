@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// MinPayoutSource provides the smallest payout accepted per currency, e.g.
+// from the corridors of the exchange rate service
+type MinPayoutSource interface {
+	MinPayoutAmounts(ctx context.Context) (map[string]decimal.Decimal, error)
+}
+
+// SetMinPayoutAmounts sets the smallest payout accepted per currency.
+// Currencies not in minimums have no minimum.
+func (s *PayoutService) SetMinPayoutAmounts(minimums map[string]decimal.Decimal) {
+	s.minPayoutMu.Lock()
+	defer s.minPayoutMu.Unlock()
+	s.minPayoutAmounts = minimums
+}
+
+// minPayoutTable returns the current minimums. The table is replaced rather
+// than changed, so it can be read without the lock.
+func (s *PayoutService) minPayoutTable() map[string]decimal.Decimal {
+	s.minPayoutMu.RLock()
+	defer s.minPayoutMu.RUnlock()
+	return s.minPayoutAmounts
+}
+
+// RefreshMinPayoutAmounts loads the minimums from source. On error the
+// current minimums are kept.
+func (s *PayoutService) RefreshMinPayoutAmounts(ctx context.Context, source MinPayoutSource) error {
+	minimums, err := source.MinPayoutAmounts(ctx)
+	if err != nil {
+		return fmt.Errorf("load payout minimums: %w", err)
+	}
+	s.SetMinPayoutAmounts(minimums)
+	return nil
+}
+
+// RunMinPayoutRefresh reloads the minimums from source every interval until
+// ctx is cancelled, so corridor changes reach payout validation
+func (s *PayoutService) RunMinPayoutRefresh(ctx context.Context, source MinPayoutSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RefreshMinPayoutAmounts(ctx, source); err != nil {
+				s.logger.Warn("Payout minimum refresh failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PayoutLocker holds short-lived named locks shared by every replica, so the
+// same payout is never sent to the provider twice at once
+type PayoutLocker interface {
+	// AcquirePayoutLock takes the lock named key under token for ttl,
+	// returning false if it is already held
+	AcquirePayoutLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// ReleasePayoutLock releases the lock named key if token still holds it
+	ReleasePayoutLock(ctx context.Context, key, token string) error
+}
+
+// ErrPayoutBusy is returned when another operation, possibly on another
+// replica, holds the lock of the payout or of the transfer it pays out
+type ErrPayoutBusy struct {
+	PayoutID   string
+	TransferID string // Set when the transfer's payout is being initiated
+}
+
+func (e ErrPayoutBusy) Error() string {
+	if e.PayoutID == "" {
+		return fmt.Sprintf("payout for transfer %s is being initiated by another operation", e.TransferID)
+	}
+	return fmt.Sprintf("payout %s is being processed by another operation", e.PayoutID)
+}
+
+// SetPayoutLocker makes initiation, processing and retries hold a lock. ttl
+// should outlast a provider call; a lock left by a crashed replica expires
+// after it.
+func (s *PayoutService) SetPayoutLocker(locker PayoutLocker, ttl time.Duration) {
+	s.locker = locker
+	s.lockTTL = ttl
+}
+
+// lockPayout takes the payout's lock and returns the func releasing it
+func (s *PayoutService) lockPayout(ctx context.Context, id string) (func(), error) {
+	return s.lock(ctx, id, ErrPayoutBusy{PayoutID: id})
+}
+
+// lockTransfer takes the lock on initiating the transfer's payout and returns
+// the func releasing it. It is taken before the transfer is checked for an
+// existing payout, so nothing is saved unless it is held.
+func (s *PayoutService) lockTransfer(ctx context.Context, transferID string) (func(), error) {
+	return s.lock(ctx, "transfer:"+transferID, ErrPayoutBusy{TransferID: transferID})
+}
+
+// lock takes the lock named key, failing with busy if it is held, and
+// returns the func releasing it. Without a locker it does nothing.
+func (s *PayoutService) lock(ctx context.Context, key string, busy ErrPayoutBusy) (func(), error) {
+	if s.locker == nil {
+		return func() {}, nil
+	}
+
+	token, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+	acquired, err := s.locker.AcquirePayoutLock(ctx, key, token, s.lockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("lock payout: %w", err)
+	}
+	if !acquired {
+		return nil, busy
+	}
+
+	return func() {
+		// Released even if the caller's context has been cancelled
+		if err := s.locker.ReleasePayoutLock(context.WithoutCancel(ctx), key, token); err != nil {
+			s.logger.Warn("Failed to release payout lock; it expires with its TTL",
+				zap.String("lock", key),
+				zap.Error(err),
+			)
+		}
+	}, nil
+}
+
+// lockToken returns a random token identifying one holder of a payout lock
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate lock token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// memoryLocker is an in-memory PayoutLocker
+type memoryLocker struct {
+	mu    sync.Mutex
+	held  map[string]string // lock name to holder's token
+	taken chan string       // sent each acquired lock name
+	err   error             // returned by AcquirePayoutLock when set
+}
+
+func newMemoryLocker() *memoryLocker {
+	return &memoryLocker{held: make(map[string]string), taken: make(chan string, 10)}
+}
+
+func (l *memoryLocker) AcquirePayoutLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return false, l.err
+	}
+	if _, ok := l.held[key]; ok {
+		return false, nil
+	}
+	l.held[key] = token
+	l.taken <- key
+	return true, nil
+}
+
+func (l *memoryLocker) ReleasePayoutLock(ctx context.Context, key, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] == token {
+		delete(l.held, key)
+	}
+	return nil
+}
+
+func (l *memoryLocker) isHeld(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.held[key]
+	return ok
+}
+
+func (l *memoryLocker) setErr(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err
+}
+
+func TestPayoutService_ConcurrentRetryIsRejected(t *testing.T) {
+	// Payouts to XX fail transiently and the provider is slow enough for a
+	// second retry to arrive while the first is in flight
+	prov := provider.NewSimulatedProvider(0, 100*time.Millisecond).
+		WithFailureRules(provider.FailureRule{Country: "XX", Category: provider.FailureTransient})
+	svc := NewPayoutService(NewMockRepository(), prov, zap.NewNop(), 3)
+	locker := newMemoryLocker()
+	svc.SetPayoutLocker(locker, time.Minute)
+	ctx := context.Background()
+
+	payout, err := svc.InitiatePayout(ctx, &InitiatePayoutRequest{
+		TransferID: "transfer_lock",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+		Recipient:  model.Recipient{Country: "XX"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusFailed {
+		t.Fatalf("expected status FAILED, got: %s", payout.Status)
+	}
+	if locker.isHeld("transfer:transfer_lock") {
+		t.Fatal("expected the transfer's lock to be released after initiating")
+	}
+	<-locker.taken
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.RetryPayout(ctx, payout.ID)
+		done <- err
+	}()
+	<-locker.taken
+
+	_, err = svc.RetryPayout(ctx, payout.ID)
+	busy, ok := err.(ErrPayoutBusy)
+	if !ok || busy.PayoutID != payout.ID {
+		t.Fatalf("expected ErrPayoutBusy for the second retry, got %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected the first retry to succeed, got: %v", err)
+	}
+	if locker.isHeld(payout.ID) {
+		t.Error("expected the lock to be released after the retry")
+	}
+
+	retried, _ := svc.GetPayout(ctx, payout.ID)
+	if retried.RetryCount != 1 {
+		t.Errorf("expected the payout to be retried once, got %d retries", retried.RetryCount)
+	}
+}
+
+func TestPayoutService_ConcurrentInitiationIsRejected(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, 100*time.Millisecond), zap.NewNop(), 3)
+	locker := newMemoryLocker()
+	svc.SetPayoutLocker(locker, time.Minute)
+	ctx := context.Background()
+	req := func() *InitiatePayoutRequest {
+		return &InitiatePayoutRequest{
+			TransferID: "transfer_lock",
+			Method:     model.PayoutMethodBankAccount,
+			Amount:     "100.00",
+			Currency:   "SGD",
+		}
+	}
+
+	done := make(chan *model.Payout, 1)
+	go func() {
+		payout, err := svc.InitiatePayout(ctx, req())
+		if err != nil {
+			t.Errorf("expected the first initiation to succeed, got: %v", err)
+		}
+		done <- payout
+	}()
+	<-locker.taken
+
+	_, err := svc.InitiatePayout(ctx, req())
+	busy, ok := err.(ErrPayoutBusy)
+	if !ok || busy.TransferID != "transfer_lock" {
+		t.Fatalf("expected ErrPayoutBusy for the transfer, got %v", err)
+	}
+
+	first := <-done
+	if first == nil {
+		t.FailNow()
+	}
+
+	// Once the first is done, a redelivery finds its payout
+	again, err := svc.InitiatePayout(ctx, req())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("expected payout %s, got %s", first.ID, again.ID)
+	}
+}
+
+func TestPayoutService_InitiateLockErrorSavesNothing(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	locker := newMemoryLocker()
+	locker.setErr(errors.New("connection reset"))
+	svc.SetPayoutLocker(locker, time.Minute)
+	ctx := context.Background()
+	req := func() *InitiatePayoutRequest {
+		return &InitiatePayoutRequest{
+			TransferID: "transfer_blip",
+			Method:     model.PayoutMethodBankAccount,
+			Amount:     "100.00",
+			Currency:   "SGD",
+		}
+	}
+
+	if _, err := svc.InitiatePayout(ctx, req()); err == nil {
+		t.Fatal("expected an error when the lock can't be taken")
+	}
+	if existing, _ := repo.GetPayoutByTransferID(ctx, "transfer_blip"); existing != nil {
+		t.Fatalf("expected no payout to be saved, got one %s", existing.Status)
+	}
+
+	// The redelivery after the blip creates and processes the payout
+	locker.setErr(nil)
+	payout, err := svc.InitiatePayout(ctx, req())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected status COMPLETED, got: %s", payout.Status)
+	}
+}
+
+func TestPayoutService_ReconcileSkipsLockedPayout(t *testing.T) {
+	prov := &pendingProvider{checked: make(chan struct{}, 1)}
+	svc := NewPayoutService(NewMockRepository(), prov, zap.NewNop(), 3)
+	locker := newMemoryLocker()
+	svc.SetPayoutLocker(locker, time.Minute)
+	recorder := &recordingNotifier{}
+	svc.SetStatusNotifier(recorder)
+	ctx := context.Background()
+
+	payout, err := svc.InitiatePayout(ctx, &InitiatePayoutRequest{
+		TransferID: "transfer_reconcile_lock",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	prov.complete()
+
+	// Another operation holds the payout, so reconciliation leaves it alone
+	locker.AcquirePayoutLock(ctx, payout.ID, "other", time.Minute)
+	if updated, err := svc.ReconcilePayouts(ctx); err != nil || updated != 0 {
+		t.Fatalf("expected the locked payout to be skipped, got %d updated, err %v", updated, err)
+	}
+	locker.ReleasePayoutLock(ctx, payout.ID, "other")
+
+	if updated, err := svc.ReconcilePayouts(ctx); err != nil || updated != 1 {
+		t.Fatalf("expected the payout to be reconciled, got %d updated, err %v", updated, err)
+	}
+	saved, _ := svc.GetPayout(ctx, payout.ID)
+	if saved.Status != model.PayoutStatusCompleted || saved.CompletedAt == nil {
+		t.Fatalf("expected a completed payout with its completion time, got %s", saved.Status)
+	}
+	if locker.isHeld(payout.ID) {
+		t.Error("expected the payout lock to be released")
+	}
+
+	// The notification carries the payout as saved
+	recorder.mu.Lock()
+	last := recorder.events[len(recorder.events)-1]
+	recorder.mu.Unlock()
+	if last.Status != model.PayoutStatusCompleted || last.Version != saved.Version || !last.CompletedAt.Equal(*saved.CompletedAt) {
+		t.Errorf("expected the saved payout to be notified, got version %d status %s", last.Version, last.Status)
+	}
+}
@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/movra/settlement-service/internal/metrics"
 	"github.com/movra/settlement-service/internal/model"
 	"github.com/movra/settlement-service/internal/provider"
 	"github.com/movra/settlement-service/internal/repository"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
@@ -17,6 +23,46 @@ type PayoutService struct {
 	provider   provider.PayoutProvider
 	logger     *zap.Logger
 	maxRetries int
+	notifier   StatusNotifier
+
+	// classifyFailures moves failures the provider marks permanent to
+	// PERMANENTLY_FAILED so they are never retried
+	classifyFailures bool
+
+	// Cash pickup amounts are rounded down to these per-currency
+	// denominations, with the residual handled per residualPolicy
+	denominations  map[string]decimal.Decimal
+	residualPolicy string
+
+	// Payouts below the minimum for their currency are rejected. The
+	// minimums may be refreshed while payouts are validated.
+	minPayoutMu      sync.RWMutex
+	minPayoutAmounts map[string]decimal.Decimal
+
+	// Payouts carrying a rate lock are checked against the lock's quote
+	rates          ExchangeRateClient
+	quoteCheckMode string
+	quoteTolerance decimal.Decimal
+
+	// Payouts in flight longer than their settlement SLA are flagged
+	slas        map[string]time.Duration
+	onSLABreach SLABreachRecorder
+
+	// Clients watching payouts are sent every status change, through the
+	// broadcaster when set so changes on other replicas reach them
+	watchers    payoutWatchers
+	broadcaster StatusBroadcaster
+
+	// Processing and retries hold the payout's lock across replicas
+	locker  PayoutLocker
+	lockTTL time.Duration
+
+	// Wrong pickup codes are counted and lock the pickup past a limit
+	pickupAttempts    PickupAttemptCounter
+	maxPickupAttempts int
+	pickupLockout     time.Duration
+
+	metrics *metrics.Metrics
 }
 
 // NewPayoutService creates a new payout service
@@ -27,15 +73,88 @@ func NewPayoutService(
 	maxRetries int,
 ) *PayoutService {
 	return &PayoutService{
-		repo:       repo,
-		provider:   prov,
-		logger:     logger,
-		maxRetries: maxRetries,
+		repo:             repo,
+		provider:         prov,
+		logger:           logger,
+		maxRetries:       maxRetries,
+		classifyFailures: true,
+		denominations:    model.PickupDenominations,
+		residualPolicy:   model.ResidualPolicyRefund,
 	}
 }
 
-// InitiatePayout creates and processes a new payout
+// SetPickupDenominations sets the smallest dispensable amount per currency
+// for cash pickup payouts and the policy for the residual. Currencies not in
+// denominations are paid out exactly.
+func (s *PayoutService) SetPickupDenominations(denominations map[string]decimal.Decimal, residualPolicy string) {
+	s.denominations = denominations
+	s.residualPolicy = residualPolicy
+}
+
+// SetFailureClassification enables or disables moving permanent provider
+// failures to PERMANENTLY_FAILED. When disabled every failure is retryable.
+func (s *PayoutService) SetFailureClassification(enabled bool) {
+	s.classifyFailures = enabled
+}
+
+// SetMetrics sets the metrics payout throughput is recorded in
+func (s *PayoutService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// SetStatusNotifier registers the notifier told about every status change
+func (s *PayoutService) SetStatusNotifier(notifier StatusNotifier) {
+	s.notifier = notifier
+}
+
+// InitiatePayout creates and processes a new payout, or returns the payout
+// already created for the transfer
 func (s *PayoutService) InitiatePayout(ctx context.Context, req *InitiatePayoutRequest) (*model.Payout, error) {
+	return s.initiatePayout(ctx, req, "")
+}
+
+// validateInitiateRequest checks the parts of a payout request that don't
+// depend on stored state, and normalizes its amount to the currency's
+// precision
+func (s *PayoutService) validateInitiateRequest(req *InitiatePayoutRequest) error {
+	if err := model.ValidateMetadata(req.Metadata); err != nil {
+		return err
+	}
+	amount, err := model.NormalizeAmount(req.Amount, req.Currency)
+	if err != nil {
+		return err
+	}
+	req.Amount = amount
+	return model.ValidateMinPayoutAmount(req.Amount, req.Currency, s.minPayoutTable())
+}
+
+// initiatePayout creates and processes a payout, as a member of batchID when
+// it is set
+func (s *PayoutService) initiatePayout(ctx context.Context, req *InitiatePayoutRequest, batchID string) (*model.Payout, error) {
+	if err := s.validateInitiateRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Transfers are paid out once: funded events are delivered at least
+	// once, so a repeat returns the transfer's existing payout
+	release, err := s.lockTransfer(ctx, req.TransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	existing, err := s.repo.GetPayoutByTransferID(ctx, req.TransferID)
+	if err != nil {
+		return nil, fmt.Errorf("look up existing payout: %w", err)
+	}
+	if existing != nil {
+		s.logger.Info("Payout already initiated for transfer",
+			zap.String("transferId", req.TransferID),
+			zap.String("payoutId", existing.ID),
+		)
+		return existing, nil
+	}
+
 	now := time.Now()
 
 	payout := &model.Payout{
@@ -46,14 +165,33 @@ func (s *PayoutService) InitiatePayout(ctx context.Context, req *InitiatePayoutR
 		Amount:     req.Amount,
 		Currency:   req.Currency,
 		Recipient:  req.Recipient,
+		Metadata:   req.Metadata,
+		LockID:     req.LockID,
+		BatchID:    batchID,
 		CreatedAt:  now,
 		UpdatedAt:  now,
 	}
+	if err := s.checkQuote(ctx, req, payout); err != nil {
+		return nil, err
+	}
+	if payout.Method == model.PayoutMethodCashPickup {
+		if err := s.applyPickupDenomination(payout); err != nil {
+			return nil, err
+		}
+	}
 
-	// Save initial payout
+	// Save initial payout. The save claims the transfer, so of two requests
+	// for the same transfer passing the check above only one creates a payout.
 	if err := s.repo.SavePayout(ctx, payout); err != nil {
+		if errors.Is(err, repository.ErrTransferClaimed) {
+			return s.claimedPayout(ctx, req.TransferID)
+		}
 		return nil, fmt.Errorf("save payout: %w", err)
 	}
+	if s.metrics != nil {
+		s.metrics.RecordPayoutInitiated(string(payout.Method))
+	}
+	s.notifyStatus(ctx, payout)
 
 	// Process payout
 	if err := s.processPayout(ctx, payout); err != nil {
@@ -68,6 +206,23 @@ func (s *PayoutService) InitiatePayout(ctx context.Context, req *InitiatePayoutR
 	return s.repo.GetPayout(ctx, payout.ID)
 }
 
+// claimedPayout returns the payout another request created for the transfer
+// while this one was preparing its own
+func (s *PayoutService) claimedPayout(ctx context.Context, transferID string) (*model.Payout, error) {
+	existing, err := s.repo.GetPayoutByTransferID(ctx, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("look up existing payout: %w", err)
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("transfer %s claimed by a payout that no longer exists", transferID)
+	}
+	s.logger.Info("Payout initiated concurrently for transfer",
+		zap.String("transferId", transferID),
+		zap.String("payoutId", existing.ID),
+	)
+	return existing, nil
+}
+
 // GetPayout retrieves a payout by ID
 func (s *PayoutService) GetPayout(ctx context.Context, id string) (*model.Payout, error) {
 	return s.repo.GetPayout(ctx, id)
@@ -78,30 +233,62 @@ func (s *PayoutService) ListPayouts(ctx context.Context, filter repository.Payou
 	return s.repo.ListPayouts(ctx, filter)
 }
 
+// ListPayoutsPage retrieves one page of payouts with filters
+func (s *PayoutService) ListPayoutsPage(ctx context.Context, filter repository.PayoutFilter) (*repository.PayoutPage, error) {
+	return s.repo.ListPayoutsPage(ctx, filter)
+}
+
+// ExportPayouts calls fn for every payout created in [from, to), oldest first.
+// Recipient PII is masked unless unmasked is set.
+func (s *PayoutService) ExportPayouts(ctx context.Context, from, to time.Time, unmasked bool, fn func(*model.Payout) error) error {
+	if !to.After(from) {
+		return fmt.Errorf("export range end must be after start")
+	}
+
+	return s.repo.ForEachPayoutCreatedBetween(ctx, from, to, func(payout *model.Payout) error {
+		if !unmasked {
+			payout = payout.MaskPII()
+		}
+		return fn(payout)
+	})
+}
+
 // RetryPayout retries a failed payout
 func (s *PayoutService) RetryPayout(ctx context.Context, id string) (*model.Payout, error) {
-	payout, err := s.repo.GetPayout(ctx, id)
+	release, err := s.lockPayout(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
-	if payout.Status != model.PayoutStatusFailed {
-		return nil, fmt.Errorf("can only retry failed payouts, current status: %s", payout.Status)
-	}
-
-	if payout.RetryCount >= s.maxRetries {
-		return nil, fmt.Errorf("max retries (%d) exceeded", s.maxRetries)
+	payout, err := s.repo.GetPayout(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
 	// Reset for retry
-	payout.Status = model.PayoutStatusPending
-	payout.RetryCount++
-	payout.FailureReason = ""
-	payout.UpdatedAt = time.Now()
+	err = s.updatePayout(ctx, payout, "save payout for retry", func(payout *model.Payout) error {
+		if payout.Status == model.PayoutStatusPermanentlyFailed {
+			return fmt.Errorf("payout failed permanently and cannot be retried: %s", payout.FailureReason)
+		}
+		if payout.Status != model.PayoutStatusFailed {
+			return fmt.Errorf("can only retry failed payouts, current status: %s", payout.Status)
+		}
+		if payout.RetryCount >= s.maxRetries {
+			return fmt.Errorf("max retries (%d) exceeded", s.maxRetries)
+		}
 
-	if err := s.repo.SavePayout(ctx, payout); err != nil {
-		return nil, fmt.Errorf("save payout for retry: %w", err)
+		if err := payout.TransitionTo(model.PayoutStatusPending); err != nil {
+			return err
+		}
+		payout.RetryCount++
+		payout.FailureReason = ""
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.notifyStatus(ctx, payout)
 
 	// Process again
 	if err := s.processPayout(ctx, payout); err != nil {
@@ -122,7 +309,7 @@ func (s *PayoutService) CancelPayout(ctx context.Context, id string, reason stri
 		return nil, err
 	}
 
-	if payout.Status != model.PayoutStatusPending && payout.Status != model.PayoutStatusFailed {
+	if !model.CanTransition(payout.Status, model.PayoutStatusCancelled) {
 		return nil, fmt.Errorf("can only cancel pending or failed payouts, current status: %s", payout.Status)
 	}
 
@@ -136,13 +323,20 @@ func (s *PayoutService) CancelPayout(ctx context.Context, id string, reason stri
 		}
 	}
 
-	payout.Status = model.PayoutStatusCancelled
-	payout.FailureReason = reason
-	payout.UpdatedAt = time.Now()
-
-	if err := s.repo.SavePayout(ctx, payout); err != nil {
-		return nil, fmt.Errorf("save cancelled payout: %w", err)
+	var from model.PayoutStatus
+	err = s.updatePayout(ctx, payout, "save cancelled payout", func(payout *model.Payout) error {
+		from = payout.Status
+		if err := payout.TransitionTo(model.PayoutStatusCancelled); err != nil {
+			return err
+		}
+		payout.FailureReason = reason
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.recordStatusChange(payout, from)
+	s.notifyStatus(ctx, payout)
 
 	return payout, nil
 }
@@ -155,7 +349,7 @@ func (s *PayoutService) GetPickupCode(ctx context.Context, id string) (string, *
 	}
 
 	if payout.Method != model.PayoutMethodCashPickup {
-		return "", nil, fmt.Errorf("not a cash pickup payout")
+		return "", nil, ErrNotCashPickup{PayoutID: payout.ID}
 	}
 
 	if payout.PickupCode == "" {
@@ -165,40 +359,272 @@ func (s *PayoutService) GetPickupCode(ctx context.Context, id string) (string, *
 	return payout.PickupCode, payout.PickupExpiresAt, nil
 }
 
+// ErrInvalidPickupCode is returned when a pickup is redeemed with a code that
+// isn't the payout's
+type ErrInvalidPickupCode struct {
+	PayoutID string
+}
+
+func (e ErrInvalidPickupCode) Error() string {
+	return "invalid pickup code for payout " + e.PayoutID
+}
+
+// ErrPickupCodeExpired is returned when a pickup is redeemed after its code
+// expired
+type ErrPickupCodeExpired struct {
+	PayoutID  string
+	ExpiredAt time.Time
+}
+
+func (e ErrPickupCodeExpired) Error() string {
+	return fmt.Sprintf("pickup code for payout %s expired at %s", e.PayoutID, e.ExpiredAt.Format(time.RFC3339))
+}
+
+// RedeemPickup marks a cash pickup payout as picked up once the recipient
+// presents its pickup code, completing the payout. The code is checked before
+// anything else about the payout is revealed, and wrong codes count towards
+// locking the pickup.
+func (s *PayoutService) RedeemPickup(ctx context.Context, id string, code string) (*model.Payout, error) {
+	payout, err := s.repo.GetPayout(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payout.Method != model.PayoutMethodCashPickup {
+		return nil, ErrNotCashPickup{PayoutID: payout.ID}
+	}
+	if err := s.checkPickupAttempts(ctx, payout.ID); err != nil {
+		return nil, err
+	}
+	if payout.PickupCode == "" || subtle.ConstantTimeCompare([]byte(code), []byte(payout.PickupCode)) != 1 {
+		return nil, s.failPickupAttempt(ctx, payout.ID)
+	}
+
+	var from model.PayoutStatus
+	err = s.updatePayout(ctx, payout, "save picked up payout", func(payout *model.Payout) error {
+		from = payout.Status
+		if payout.Status != model.PayoutStatusReadyForPickup {
+			return fmt.Errorf("payout is not ready for pickup, current status: %s", payout.Status)
+		}
+		now := time.Now()
+		if payout.PickupExpiresAt != nil && !now.Before(*payout.PickupExpiresAt) {
+			return ErrPickupCodeExpired{PayoutID: payout.ID, ExpiredAt: *payout.PickupExpiresAt}
+		}
+		if err := payout.TransitionTo(model.PayoutStatusPickedUp); err != nil {
+			return err
+		}
+		payout.CompletedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordStatusChange(payout, from)
+	s.notifyStatus(ctx, payout)
+
+	s.logger.Info("Payout picked up", zap.String("payoutId", payout.ID))
+
+	return payout, nil
+}
+
+// ReconcilePayouts polls the provider for every PROCESSING payout with a
+// provider reference and applies any status change. It returns the number
+// of payouts that were updated.
+func (s *PayoutService) ReconcilePayouts(ctx context.Context) (int, error) {
+	payouts, err := s.repo.ListPayouts(ctx, repository.PayoutFilter{Status: model.PayoutStatusProcessing})
+	if err != nil {
+		return 0, fmt.Errorf("list processing payouts: %w", err)
+	}
+	if s.metrics != nil {
+		// Resync the gauge, which starts at zero after a restart
+		s.metrics.SetPayoutsProcessing(len(payouts))
+	}
+
+	updated := 0
+	for _, payout := range payouts {
+		if payout.ProviderReference == "" {
+			continue
+		}
+		reconciled, err := s.reconcilePayout(ctx, payout.ID)
+		if err != nil {
+			var busy ErrPayoutBusy
+			if errors.As(err, &busy) {
+				continue // Being processed; picked up on the next pass
+			}
+			s.logger.Error("Failed to reconcile payout",
+				zap.String("payoutId", payout.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if reconciled {
+			updated++
+		}
+	}
+
+	return updated, nil
+}
+
+// errNotReconciled is returned by the reconciled status's change when the
+// reloaded payout already has the provider's status or can't move to it
+var errNotReconciled = errors.New("payout needs no reconciliation")
+
+// reconcilePayout checks a processing payout with the provider under the
+// payout's lock and applies any status change. It reports whether the payout
+// was updated.
+func (s *PayoutService) reconcilePayout(ctx context.Context, id string) (bool, error) {
+	release, err := s.lockPayout(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	payout, err := s.repo.GetPayout(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if payout.Status != model.PayoutStatusProcessing || payout.ProviderReference == "" {
+		return false, nil
+	}
+
+	status, err := s.provider.CheckStatus(ctx, payout.ProviderReference)
+	if err != nil {
+		s.logger.Warn("Failed to check payout status",
+			zap.String("payoutId", payout.ID),
+			zap.String("providerRef", payout.ProviderReference),
+			zap.Error(err),
+		)
+		return false, nil
+	}
+
+	newStatus := s.failureStatus(status.Status, status.FailureCategory)
+	var from model.PayoutStatus
+	err = s.updatePayout(ctx, payout, "save reconciled payout", func(payout *model.Payout) error {
+		from = payout.Status
+		if payout.Status == newStatus {
+			return errNotReconciled
+		}
+		if !model.CanTransition(payout.Status, newStatus) {
+			s.logger.Warn("Ignoring provider status the payout can't move to",
+				zap.String("payoutId", payout.ID),
+				zap.String("status", string(payout.Status)),
+				zap.String("providerStatus", string(newStatus)),
+			)
+			return errNotReconciled
+		}
+		if err := payout.TransitionTo(newStatus); err != nil {
+			return err
+		}
+		payout.FailureReason = status.FailureReason
+		if newStatus == model.PayoutStatusCompleted {
+			completedAt := time.Now()
+			if status.CompletedAt != nil {
+				completedAt = *status.CompletedAt
+			}
+			payout.CompletedAt = &completedAt
+		}
+		return nil
+	})
+	if errors.Is(err, errNotReconciled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.recordStatusChange(payout, from)
+	s.notifyStatus(ctx, payout)
+
+	s.logger.Info("Payout reconciled",
+		zap.String("payoutId", payout.ID),
+		zap.String("status", string(payout.Status)),
+	)
+	return true, nil
+}
+
+// RunReconciler reconciles processing payouts with the provider every
+// interval until ctx is cancelled
+func (s *PayoutService) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ReconcilePayouts(ctx); err != nil {
+				s.logger.Error("Payout reconciliation failed", zap.Error(err))
+			}
+		}
+	}
+}
+
 func (s *PayoutService) processPayout(ctx context.Context, payout *model.Payout) error {
 	// Update to processing
-	payout.Status = model.PayoutStatusProcessing
-	payout.UpdatedAt = time.Now()
-	if err := s.repo.SavePayout(ctx, payout); err != nil {
-		return fmt.Errorf("update to processing: %w", err)
+	var from model.PayoutStatus
+	err := s.updatePayout(ctx, payout, "update to processing", func(payout *model.Payout) error {
+		from = payout.Status
+		return payout.TransitionTo(model.PayoutStatusProcessing)
+	})
+	if err != nil {
+		return err
 	}
+	s.recordStatusChange(payout, from)
+	s.notifyStatus(ctx, payout)
 
 	// Call provider
+	started := time.Now()
 	result, err := s.provider.ProcessPayout(ctx, payout)
+	if s.metrics != nil {
+		s.metrics.RecordProviderDuration(string(payout.Method), time.Since(started))
+	}
+
+	// The outcome is saved even if ctx ended during the call, such as on a
+	// shutdown cutting it short: a payout left PROCESSING with no provider
+	// reference would never be reconciled or retried
+	ctx = context.WithoutCancel(ctx)
 	if err != nil {
-		payout.Status = model.PayoutStatusFailed
-		payout.FailureReason = err.Error()
-		payout.UpdatedAt = time.Now()
-		s.repo.SavePayout(ctx, payout)
-		return fmt.Errorf("provider error: %w", err)
+		providerErr := err
+		err := s.updatePayout(ctx, payout, "save failure", func(payout *model.Payout) error {
+			from = payout.Status
+			if err := payout.TransitionTo(model.PayoutStatusFailed); err != nil {
+				return err
+			}
+			payout.FailureReason = providerErr.Error()
+			return nil
+		})
+		if err == nil {
+			s.recordStatusChange(payout, from)
+		}
+		s.notifyStatus(ctx, payout)
+		return fmt.Errorf("provider error: %w", providerErr)
 	}
 
 	// Update with result
-	payout.ProviderReference = result.ProviderReference
-	payout.Status = result.Status
-	payout.FailureReason = result.FailureReason
-	payout.PickupCode = result.PickupCode
-	payout.PickupExpiresAt = result.PickupExpiresAt
-	payout.UpdatedAt = time.Now()
-
-	if result.Status == model.PayoutStatusCompleted {
-		now := time.Now()
-		payout.CompletedAt = &now
-	}
+	err = s.updatePayout(ctx, payout, "save result", func(payout *model.Payout) error {
+		from = payout.Status
+		if err := payout.TransitionTo(s.failureStatus(result.Status, result.FailureCategory)); err != nil {
+			return fmt.Errorf("apply provider result: %w", err)
+		}
+		payout.ProviderReference = result.ProviderReference
+		payout.FailureReason = result.FailureReason
+		payout.PickupCode = result.PickupCode
+		payout.PickupExpiresAt = result.PickupExpiresAt
+		payout.ProviderFee = result.ProviderFee
+		payout.ProviderFeeCurrency = result.ProviderFeeCurrency
+		payout.UpdatedAt = time.Now()
 
-	if err := s.repo.SavePayout(ctx, payout); err != nil {
-		return fmt.Errorf("save result: %w", err)
+		if result.Status == model.PayoutStatusCompleted {
+			now := time.Now()
+			payout.CompletedAt = &now
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.recordStatusChange(payout, from)
+	s.notifyStatus(ctx, payout)
 
 	s.logger.Info("Payout processed",
 		zap.String("payoutId", payout.ID),
@@ -209,6 +635,118 @@ func (s *PayoutService) processPayout(ctx context.Context, payout *model.Payout)
 	return nil
 }
 
+// recordStatusChange records a payout's move from one status to another in
+// the metrics. Cash pickup payouts count as completed once picked up, so one
+// that expires uncollected is counted only as failed.
+func (s *PayoutService) recordStatusChange(payout *model.Payout, from model.PayoutStatus) {
+	if s.metrics == nil || payout.Status == from {
+		return
+	}
+
+	method := string(payout.Method)
+	switch payout.Status {
+	case model.PayoutStatusCompleted, model.PayoutStatusPickedUp:
+		s.metrics.RecordPayoutCompleted(method)
+	case model.PayoutStatusReadyForPickup:
+		if residual, err := strconv.ParseFloat(payout.ResidualAmount, 64); err == nil && residual > 0 {
+			s.metrics.RecordPickupResidual(payout.Currency, payout.ResidualPolicy, residual)
+		}
+	case model.PayoutStatusFailed, model.PayoutStatusPermanentlyFailed:
+		s.metrics.RecordPayoutFailed(method)
+	case model.PayoutStatusCancelled:
+		s.metrics.RecordPayoutCancelled(method)
+	}
+
+	if payout.Status == model.PayoutStatusProcessing {
+		s.metrics.RecordProcessingStarted()
+	} else if from == model.PayoutStatusProcessing {
+		s.metrics.RecordProcessingEnded()
+	}
+}
+
+// updatePayout applies change to payout and saves it. If another writer saved
+// the payout since it was loaded, the payout is reloaded and change applied
+// again. Errors from change are returned as is; save errors are wrapped with
+// operation.
+func (s *PayoutService) updatePayout(ctx context.Context, payout *model.Payout, operation string, change func(*model.Payout) error) error {
+	for attempt := 1; ; attempt++ {
+		if err := change(payout); err != nil {
+			return err
+		}
+		err := s.repo.SavePayout(ctx, payout)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrConcurrentModification) || attempt >= maxUpdateAttempts {
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+
+		reloaded, err := s.repo.GetPayout(ctx, payout.ID)
+		if err != nil {
+			return fmt.Errorf("%s: reload payout: %w", operation, err)
+		}
+		*payout = *reloaded
+	}
+}
+
+// maxUpdateAttempts bounds how often updatePayout reloads a payout that keeps
+// changing under it
+const maxUpdateAttempts = 3
+
+// applyPickupDenomination sets the payable and residual amounts of a cash
+// pickup payout, rejecting it if the payable amount falls below the minimum
+func (s *PayoutService) applyPickupDenomination(payout *model.Payout) error {
+	denomination, ok := s.denominations[payout.Currency]
+	if !ok {
+		return nil
+	}
+
+	payable, residual, err := model.RoundToDenomination(payout.Amount, denomination)
+	if err != nil {
+		return err
+	}
+	if !payable.IsPositive() {
+		return fmt.Errorf("amount %s %s is below the smallest dispensable denomination of %s %s",
+			payout.Amount, payout.Currency, denomination, payout.Currency)
+	}
+
+	payout.PayableAmount = model.FormatAmount(payable, payout.Currency)
+	if err := model.ValidateMinPayoutAmount(payout.PayableAmount, payout.Currency, s.minPayoutTable()); err != nil {
+		return fmt.Errorf("payable amount after rounding to denominations: %w", err)
+	}
+	if residual.IsPositive() {
+		payout.ResidualAmount = model.FormatAmount(residual, payout.Currency)
+		payout.ResidualPolicy = s.residualPolicy
+	}
+	return nil
+}
+
+// failureStatus maps a FAILED provider status to PERMANENTLY_FAILED when the
+// provider classified the failure as permanent
+func (s *PayoutService) failureStatus(status model.PayoutStatus, category provider.FailureCategory) model.PayoutStatus {
+	if s.classifyFailures && status == model.PayoutStatusFailed && category == provider.FailurePermanent {
+		return model.PayoutStatusPermanentlyFailed
+	}
+	return status
+}
+
+// notifyStatus reports a status change to the payout's watchers and the
+// notifier. Notification failures are logged and never fail the payout
+// operation.
+func (s *PayoutService) notifyStatus(ctx context.Context, payout *model.Payout) {
+	s.publishStatus(ctx, payout)
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.NotifyStatusChange(ctx, payout); err != nil {
+		s.logger.Warn("Failed to notify payout status change",
+			zap.String("payoutId", payout.ID),
+			zap.String("status", string(payout.Status)),
+			zap.Error(err),
+		)
+	}
+}
+
 // InitiatePayoutRequest represents a request to initiate a payout
 type InitiatePayoutRequest struct {
 	TransferID string
@@ -216,4 +754,10 @@ type InitiatePayoutRequest struct {
 	Amount     string
 	Currency   string
 	Recipient  model.Recipient
+	Metadata   map[string]string // Integrator-supplied, echoed on lookups and events
+
+	// LockID is the exchange rate lock the transfer was quoted against, and
+	// SourceAmount the amount the sender paid, used to verify Amount
+	LockID       string
+	SourceAmount string
 }
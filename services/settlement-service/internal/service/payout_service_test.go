@@ -2,27 +2,43 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/movra/settlement-service/internal/metrics"
 	"github.com/movra/settlement-service/internal/model"
 	"github.com/movra/settlement-service/internal/provider"
 	"github.com/movra/settlement-service/internal/repository"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 // MockRepository is a simple in-memory repository for testing
 type MockRepository struct {
 	payouts map[string]*model.Payout
+	batches map[string]*model.PayoutBatch
+	saveErr error // returned by SavePayout when set
 }
 
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
 		payouts: make(map[string]*model.Payout),
+		batches: make(map[string]*model.PayoutBatch),
 	}
 }
 
 func (r *MockRepository) SavePayout(ctx context.Context, payout *model.Payout) error {
+	if r.saveErr != nil {
+		return r.saveErr
+	}
 	r.payouts[payout.ID] = payout
 	return nil
 }
@@ -31,7 +47,7 @@ func (r *MockRepository) GetPayout(ctx context.Context, id string) (*model.Payou
 	if p, ok := r.payouts[id]; ok {
 		return p, nil
 	}
-	return nil, nil
+	return nil, fmt.Errorf("%w: %s", repository.ErrPayoutNotFound, id)
 }
 
 func (r *MockRepository) GetPayoutByTransferID(ctx context.Context, transferID string) (*model.Payout, error) {
@@ -46,7 +62,7 @@ func (r *MockRepository) GetPayoutByTransferID(ctx context.Context, transferID s
 func (r *MockRepository) ListPayouts(ctx context.Context, filter repository.PayoutFilter) ([]*model.Payout, error) {
 	var result []*model.Payout
 	for _, p := range r.payouts {
-		if filter.Status != "" && p.Status != filter.Status {
+		if !filter.Matches(p) {
 			continue
 		}
 		result = append(result, p)
@@ -54,6 +70,30 @@ func (r *MockRepository) ListPayouts(ctx context.Context, filter repository.Payo
 	return result, nil
 }
 
+func (r *MockRepository) ListPayoutsPage(ctx context.Context, filter repository.PayoutFilter) (*repository.PayoutPage, error) {
+	payouts, err := r.ListPayouts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.PayoutPage{Payouts: payouts, Total: len(payouts), TotalExact: true}, nil
+}
+
+func (r *MockRepository) ForEachPayoutCreatedBetween(ctx context.Context, from, to time.Time, fn func(*model.Payout) error) error {
+	var matched []*model.Payout
+	for _, p := range r.payouts {
+		if !p.CreatedAt.Before(from) && p.CreatedAt.Before(to) {
+			matched = append(matched, p)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.Before(matched[j].CreatedAt) })
+	for _, p := range matched {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *MockRepository) UpdatePayoutStatus(ctx context.Context, id string, status model.PayoutStatus, failureReason string) error {
 	if p, ok := r.payouts[id]; ok {
 		p.Status = status
@@ -62,6 +102,20 @@ func (r *MockRepository) UpdatePayoutStatus(ctx context.Context, id string, stat
 	return nil
 }
 
+func (r *MockRepository) SaveBatch(ctx context.Context, batch *model.PayoutBatch) error {
+	saved := *batch
+	r.batches[batch.ID] = &saved
+	return nil
+}
+
+func (r *MockRepository) GetBatch(ctx context.Context, id string) (*model.PayoutBatch, error) {
+	if b, ok := r.batches[id]; ok {
+		batch := *b
+		return &batch, nil
+	}
+	return nil, fmt.Errorf("batch not found: %s", id)
+}
+
 func TestPayoutService_InitiatePayout_Success(t *testing.T) {
 	repo := NewMockRepository()
 	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
@@ -94,6 +148,63 @@ func TestPayoutService_InitiatePayout_Success(t *testing.T) {
 	}
 }
 
+func TestPayoutService_InitiatePayout_IdempotentByTransferID(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	req := &InitiatePayoutRequest{
+		TransferID: "transfer_123",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+		Recipient: model.Recipient{
+			Type:          model.PayoutMethodBankAccount,
+			BankName:      "Test Bank",
+			AccountNumber: "1234567890",
+		},
+	}
+
+	first, err := svc.InitiatePayout(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	second, err := svc.InitiatePayout(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error on redelivery, got: %v", err)
+	}
+
+	if second.ID != first.ID {
+		t.Errorf("expected the existing payout %s, got %s", first.ID, second.ID)
+	}
+	if len(repo.payouts) != 1 {
+		t.Errorf("expected 1 payout for the transfer, got %d", len(repo.payouts))
+	}
+}
+
+func TestPayoutService_InitiatePayout_WriteFailure(t *testing.T) {
+	repo := NewMockRepository()
+	repo.saveErr = repository.ErrWriteFailed{Operation: "save payout", Kind: repository.WriteFailureOOM, Err: errors.New("OOM")}
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	_, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_123",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+
+	var writeErr repository.ErrWriteFailed
+	if !errors.As(err, &writeErr) || writeErr.Kind != repository.WriteFailureOOM {
+		t.Fatalf("expected OOM ErrWriteFailed, got %v", err)
+	}
+}
+
 func TestPayoutService_InitiatePayout_CashPickup(t *testing.T) {
 	repo := NewMockRepository()
 	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
@@ -179,6 +290,251 @@ func TestPayoutService_CancelPayout(t *testing.T) {
 	}
 }
 
+func TestPayoutService_CancelPayout_RejectsCompletedPayout(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	created, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_cancel_completed",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if created.Status != model.PayoutStatusCompleted {
+		t.Fatalf("expected status COMPLETED, got: %s", created.Status)
+	}
+
+	if _, err := svc.CancelPayout(context.Background(), created.ID, "Too late"); err == nil {
+		t.Fatal("expected cancelling a completed payout to fail")
+	}
+	if stored := repo.payouts[created.ID]; stored.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected the payout to stay COMPLETED, got: %s", stored.Status)
+	}
+}
+
+// conflictingRepository simulates another writer saving a payout just before
+// the next conflicts saves, which fail with ErrConcurrentModification
+type conflictingRepository struct {
+	*MockRepository
+	conflicts  int
+	concurrent func(*model.Payout) // the other writer's change
+}
+
+func (r *conflictingRepository) SavePayout(ctx context.Context, payout *model.Payout) error {
+	if r.conflicts > 0 {
+		if stored, ok := r.payouts[payout.ID]; ok {
+			r.conflicts--
+			changed := *stored
+			r.concurrent(&changed)
+			r.payouts[payout.ID] = &changed
+			return repository.ErrConcurrentModification
+		}
+	}
+	return r.MockRepository.SavePayout(ctx, payout)
+}
+
+func TestPayoutService_CancelPayout_ReloadsAfterConcurrentModification(t *testing.T) {
+	repo := &conflictingRepository{MockRepository: NewMockRepository()}
+	prov := provider.NewSimulatedProvider(100, 10*time.Millisecond) // 100% failure to get a failed payout
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	created, _ := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_cancel_conflict",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+
+	// A retry lands between loading the payout and saving the cancellation
+	repo.conflicts = 1
+	repo.concurrent = func(p *model.Payout) {
+		p.Status = model.PayoutStatusPending
+		p.RetryCount++
+	}
+
+	cancelled, err := svc.CancelPayout(context.Background(), created.ID, "Customer requested")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cancelled.Status != model.PayoutStatusCancelled {
+		t.Errorf("expected status CANCELLED, got: %s", cancelled.Status)
+	}
+	if cancelled.RetryCount != 1 {
+		t.Errorf("expected the cancellation to keep the concurrent retry count, got: %d", cancelled.RetryCount)
+	}
+}
+
+var (
+	testMetricsOnce sync.Once
+	testMetrics     *metrics.Metrics
+)
+
+// newTestMetrics returns a shared Metrics, since metrics register with the
+// default registry and can only be created once per process
+func newTestMetrics() *metrics.Metrics {
+	testMetricsOnce.Do(func() {
+		testMetrics = metrics.NewMetrics("service_test")
+	})
+	return testMetrics
+}
+
+func TestPayoutService_MetricsRecordedOnInitiate(t *testing.T) {
+	m := newTestMetrics()
+	initiated := m.PayoutsInitiatedTotal.WithLabelValues("MOBILE_WALLET")
+	completed := m.PayoutsCompletedTotal.WithLabelValues("MOBILE_WALLET")
+	failed := m.PayoutsFailedTotal.WithLabelValues("MOBILE_WALLET")
+	cancelled := m.PayoutsCancelledTotal.WithLabelValues("MOBILE_WALLET")
+	before := []float64{testutil.ToFloat64(initiated), testutil.ToFloat64(completed), testutil.ToFloat64(failed), testutil.ToFloat64(cancelled)}
+	processing := testutil.ToFloat64(m.PayoutsProcessing)
+
+	logger, _ := zap.NewDevelopment()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond).WithFailureRules(provider.FailureRule{Country: "XX"})
+	svc := NewPayoutService(NewMockRepository(), prov, logger, 3)
+	svc.SetMetrics(m)
+
+	initiate := func(transferID, country string) *model.Payout {
+		payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+			TransferID: transferID,
+			Method:     model.PayoutMethodMobileWallet,
+			Amount:     "100.00",
+			Currency:   "SGD",
+			Recipient:  model.Recipient{Country: country},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		return payout
+	}
+	initiate("transfer_metrics_ok", "PH")
+	failedPayout := initiate("transfer_metrics_failed", "XX")
+	if _, err := svc.CancelPayout(context.Background(), failedPayout.ID, "Customer requested"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	after := []float64{testutil.ToFloat64(initiated), testutil.ToFloat64(completed), testutil.ToFloat64(failed), testutil.ToFloat64(cancelled)}
+	for i, name := range []string{"initiated", "completed", "failed", "cancelled"} {
+		want := 1.0
+		if name == "initiated" {
+			want = 2
+		}
+		if got := after[i] - before[i]; got != want {
+			t.Errorf("expected %s payouts to increase by %.0f, got %f", name, want, got)
+		}
+	}
+	if got := testutil.ToFloat64(m.PayoutsProcessing); got != processing {
+		t.Errorf("expected no payouts left processing, gauge moved from %f to %f", processing, got)
+	}
+	if count := testutil.CollectAndCount(m.ProviderDuration); count == 0 {
+		t.Error("expected provider durations to be observed")
+	}
+}
+
+func TestPayoutService_MetricsCountPickupsOnceSettled(t *testing.T) {
+	m := newTestMetrics()
+	completed := m.PayoutsCompletedTotal.WithLabelValues("CASH_PICKUP")
+	failed := m.PayoutsFailedTotal.WithLabelValues("CASH_PICKUP")
+	completedBefore, failedBefore := testutil.ToFloat64(completed), testutil.ToFloat64(failed)
+
+	prov := provider.NewSimulatedProvider(0, time.Millisecond).
+		WithPickupCodeGenerator(func() string { return "ABC12345" })
+	svc := NewPayoutService(NewMockRepository(), prov, zap.NewNop(), 3)
+	svc.SetMetrics(m)
+	ctx := context.Background()
+
+	initiate := func(transferID string) *model.Payout {
+		payout, err := svc.InitiatePayout(ctx, &InitiatePayoutRequest{
+			TransferID: transferID,
+			Method:     model.PayoutMethodCashPickup,
+			Amount:     "100.00",
+			Currency:   "PHP",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		return payout
+	}
+	collected := initiate("transfer_metrics_collected")
+	uncollected := initiate("transfer_metrics_uncollected")
+
+	// Ready to collect isn't completed yet
+	if got := testutil.ToFloat64(completed) - completedBefore; got != 0 {
+		t.Fatalf("expected no completions before pickup, got %f", got)
+	}
+
+	if _, err := svc.RedeemPickup(ctx, collected.ID, "ABC12345"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expired := time.Now().Add(-time.Minute)
+	uncollected.PickupExpiresAt = &expired
+	if _, err := svc.ExpirePickups(ctx); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if got := testutil.ToFloat64(completed) - completedBefore; got != 1 {
+		t.Errorf("expected only the collected pickup to count as completed, got %f", got)
+	}
+	if got := testutil.ToFloat64(failed) - failedBefore; got != 1 {
+		t.Errorf("expected the expired pickup to count as failed, got %f", got)
+	}
+}
+
+func TestPayoutService_ConcurrentInitiationCreatesOnePayout(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	repo := repository.NewRedisRepository(client)
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, 20*time.Millisecond), zap.NewNop(), 3)
+
+	// A gRPC request and a redelivered funded event for the same transfer
+	const requests = 4
+	var wg sync.WaitGroup
+	ids := make(chan string, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+				TransferID: "transfer_concurrent",
+				Method:     model.PayoutMethodBankAccount,
+				Amount:     "100.00",
+				Currency:   "SGD",
+			})
+			if err != nil {
+				t.Errorf("expected no error, got: %v", err)
+				return
+			}
+			ids <- payout.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	var first string
+	for id := range ids {
+		if first == "" {
+			first = id
+		}
+		if id != first {
+			t.Errorf("expected every request to return payout %s, got %s", first, id)
+		}
+	}
+	payouts, err := repo.ListPayouts(context.Background(), repository.PayoutFilter{})
+	if err != nil {
+		t.Fatalf("list payouts: %v", err)
+	}
+	if len(payouts) != 1 {
+		t.Errorf("expected one payout for the transfer, got %d", len(payouts))
+	}
+}
+
 func TestPayoutService_GetPickupCode(t *testing.T) {
 	repo := NewMockRepository()
 	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
@@ -208,3 +564,649 @@ func TestPayoutService_GetPickupCode(t *testing.T) {
 		t.Error("expected expiry time")
 	}
 }
+
+// newPickupPayout returns a service and a cash pickup payout ready to collect
+// with pickup code ABC12345
+func newPickupPayout(t *testing.T) (*PayoutService, *model.Payout) {
+	t.Helper()
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond).
+		WithPickupCodeGenerator(func() string { return "ABC12345" })
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_redeem",
+		Method:     model.PayoutMethodCashPickup,
+		Amount:     "100.00",
+		Currency:   "PHP",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusReadyForPickup {
+		t.Fatalf("expected status READY_FOR_PICKUP, got %s", payout.Status)
+	}
+	return svc, payout
+}
+
+func TestPayoutService_RedeemPickup(t *testing.T) {
+	svc, created := newPickupPayout(t)
+
+	payout, err := svc.RedeemPickup(context.Background(), created.ID, "ABC12345")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusPickedUp {
+		t.Errorf("expected status PICKED_UP, got %s", payout.Status)
+	}
+	if payout.CompletedAt == nil {
+		t.Error("expected completion time to be set")
+	}
+
+	// A pickup is redeemed once
+	if _, err := svc.RedeemPickup(context.Background(), created.ID, "ABC12345"); err == nil {
+		t.Error("expected error redeeming a picked up payout again")
+	}
+}
+
+func TestPayoutService_RedeemPickup_WrongCode(t *testing.T) {
+	svc, created := newPickupPayout(t)
+
+	_, err := svc.RedeemPickup(context.Background(), created.ID, "XYZ98765")
+	var invalid ErrInvalidPickupCode
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidPickupCode, got: %v", err)
+	}
+
+	payout, _ := svc.GetPayout(context.Background(), created.ID)
+	if payout.Status != model.PayoutStatusReadyForPickup || payout.CompletedAt != nil {
+		t.Errorf("expected payout to stay ready for pickup, got %s", payout.Status)
+	}
+}
+
+func TestPayoutService_RedeemPickup_ExpiredCode(t *testing.T) {
+	svc, created := newPickupPayout(t)
+	expired := time.Now().Add(-time.Minute)
+	created.PickupExpiresAt = &expired
+
+	_, err := svc.RedeemPickup(context.Background(), created.ID, "ABC12345")
+	var expiredErr ErrPickupCodeExpired
+	if !errors.As(err, &expiredErr) {
+		t.Fatalf("expected ErrPickupCodeExpired, got: %v", err)
+	}
+
+	payout, _ := svc.GetPayout(context.Background(), created.ID)
+	if payout.Status != model.PayoutStatusReadyForPickup {
+		t.Errorf("expected payout to stay ready for pickup, got %s", payout.Status)
+	}
+}
+
+func TestPayoutService_InitiatePayout_StoresProviderFee(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	created, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_fee",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	payout, err := svc.GetPayout(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.ProviderFee != "0.50" || payout.ProviderFeeCurrency != "SGD" {
+		t.Errorf("expected provider fee 0.50 SGD, got %s %s", payout.ProviderFee, payout.ProviderFeeCurrency)
+	}
+}
+
+func TestPayoutService_InitiatePayout_MetadataRoundTrip(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	metadata := map[string]string{"orderId": "ORD-42", "customerTier": "gold"}
+	created, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_metadata",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+		Metadata:   metadata,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Persisted payouts are stored as JSON, so round-trip through it
+	data, err := json.Marshal(repo.payouts[created.ID])
+	if err != nil {
+		t.Fatalf("marshal payout: %v", err)
+	}
+	var stored model.Payout
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("unmarshal payout: %v", err)
+	}
+
+	for key, value := range metadata {
+		if stored.Metadata[key] != value {
+			t.Errorf("expected stored metadata %s=%s, got: %s", key, value, stored.Metadata[key])
+		}
+	}
+
+	fetched, _ := svc.GetPayout(context.Background(), created.ID)
+	if fetched.Metadata["orderId"] != "ORD-42" {
+		t.Errorf("expected metadata on lookup, got: %v", fetched.Metadata)
+	}
+}
+
+func TestPayoutService_InitiatePayout_MetadataTooLarge(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	metadata := make(map[string]string)
+	for i := 0; i <= model.MaxMetadataEntries; i++ {
+		metadata[fmt.Sprintf("key%d", i)] = "value"
+	}
+
+	_, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_big_metadata",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+		Metadata:   metadata,
+	})
+	var invalid model.ErrInvalidMetadata
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidMetadata for oversized metadata, got: %v", err)
+	}
+
+	if len(repo.payouts) != 0 {
+		t.Errorf("expected no payout to be saved, got %d", len(repo.payouts))
+	}
+}
+
+func TestPayoutService_InitiatePayout_BelowMinimumAmount(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+	svc.SetMinPayoutAmounts(map[string]decimal.Decimal{"PHP": decimal.NewFromInt(50)})
+
+	_, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_small",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "42.00",
+		Currency:   "PHP",
+	})
+	if err == nil {
+		t.Fatal("expected error for amount below the PHP minimum")
+	}
+
+	if len(repo.payouts) != 0 {
+		t.Errorf("expected no payout to be saved, got %d", len(repo.payouts))
+	}
+}
+
+func TestPayoutService_SetMinPayoutAmounts(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, 10*time.Millisecond), zap.NewNop(), 3)
+	svc.SetMinPayoutAmounts(map[string]decimal.Decimal{"PHP": decimal.NewFromInt(20), "SGD": decimal.NewFromInt(10)})
+	ctx := context.Background()
+
+	// 42 PHP meets the configured minimum
+	if _, err := svc.InitiatePayout(ctx, &InitiatePayoutRequest{
+		TransferID: "transfer_php",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "42.00",
+		Currency:   "PHP",
+	}); err != nil {
+		t.Errorf("expected 42 PHP to meet the configured minimum, got: %v", err)
+	}
+	if _, err := svc.InitiatePayout(ctx, &InitiatePayoutRequest{
+		TransferID: "transfer_sgd",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "5.00",
+		Currency:   "SGD",
+	}); err == nil {
+		t.Error("expected 5 SGD to be below the configured minimum")
+	}
+}
+
+// staticMinPayoutSource serves fixed minimums, or err when set
+type staticMinPayoutSource struct {
+	minimums map[string]decimal.Decimal
+	err      error
+}
+
+func (s *staticMinPayoutSource) MinPayoutAmounts(ctx context.Context) (map[string]decimal.Decimal, error) {
+	return s.minimums, s.err
+}
+
+func TestPayoutService_RefreshMinPayoutAmounts(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	ctx := context.Background()
+	source := &staticMinPayoutSource{minimums: map[string]decimal.Decimal{"PHP": decimal.NewFromInt(50)}}
+
+	if err := svc.RefreshMinPayoutAmounts(ctx, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	small := &InitiatePayoutRequest{TransferID: "transfer_small", Method: model.PayoutMethodBankAccount, Amount: "42.00", Currency: "PHP"}
+	if _, err := svc.InitiatePayout(ctx, small); err == nil {
+		t.Fatal("expected 42 PHP to be below the loaded minimum")
+	}
+
+	// A failed refresh keeps the minimums already loaded
+	source.err = errors.New("exchange rate service unavailable")
+	if err := svc.RefreshMinPayoutAmounts(ctx, source); err == nil {
+		t.Fatal("expected the refresh error to be returned")
+	}
+	if _, err := svc.InitiatePayout(ctx, small); err == nil {
+		t.Error("expected the loaded minimum to still apply")
+	}
+}
+
+func TestPayoutService_InitiatePayout_ValidatesAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		stored   string // empty when the amount is rejected
+	}{
+		{"100.00", "SGD", "100.00"},
+		{"0100.5", "SGD", "100.50"},
+		{"25000.00", "IDR", "25000"},
+		{"-5", "SGD", ""},
+		{"abc", "SGD", ""},
+		{"0.00", "SGD", ""},
+		{"100.999", "SGD", ""},
+		{"25000.5", "IDR", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			repo := NewMockRepository()
+			prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+			logger, _ := zap.NewDevelopment()
+
+			svc := NewPayoutService(repo, prov, logger, 3)
+
+			payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+				TransferID: "transfer_amount",
+				Method:     model.PayoutMethodBankAccount,
+				Amount:     tt.amount,
+				Currency:   tt.currency,
+			})
+
+			if tt.stored == "" {
+				var invalid model.ErrInvalidAmount
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected ErrInvalidAmount, got: %v", err)
+				}
+				if len(repo.payouts) != 0 {
+					t.Errorf("expected no payout to be saved, got %d", len(repo.payouts))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if payout.Amount != tt.stored {
+				t.Errorf("expected amount %s to be stored, got: %s", tt.stored, payout.Amount)
+			}
+		})
+	}
+}
+
+func TestPayoutService_ReconcilePayouts_AsyncCompletion(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewAsyncSimulatedProvider(0, 10*time.Millisecond, 50*time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, prov, logger, 3)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_async",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusProcessing {
+		t.Fatalf("expected status PROCESSING, got: %s", payout.Status)
+	}
+
+	// Polling before the delay leaves the payout processing
+	updated, err := svc.ReconcilePayouts(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if updated != 0 {
+		t.Errorf("expected no updates before delay, got %d", updated)
+	}
+	if p, _ := svc.GetPayout(context.Background(), payout.ID); p.Status != model.PayoutStatusProcessing {
+		t.Errorf("expected status PROCESSING before delay, got: %s", p.Status)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	updated, err = svc.ReconcilePayouts(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("expected 1 update after delay, got %d", updated)
+	}
+	if p, _ := svc.GetPayout(context.Background(), payout.ID); p.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected status COMPLETED after delay, got: %s", p.Status)
+	}
+}
+
+// pendingProvider leaves payouts processing until complete is called, and
+// signals checked whenever CheckStatus reports a completion
+type pendingProvider struct {
+	mu        sync.Mutex
+	completed bool
+	checked   chan struct{}
+}
+
+func (p *pendingProvider) ProcessPayout(ctx context.Context, payout *model.Payout) (*provider.ProviderResult, error) {
+	return &provider.ProviderResult{
+		ProviderReference: "PENDING_" + payout.ID,
+		Status:            model.PayoutStatusProcessing,
+	}, nil
+}
+
+func (p *pendingProvider) CheckStatus(ctx context.Context, providerReference string) (*provider.ProviderStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.completed {
+		return &provider.ProviderStatus{Status: model.PayoutStatusProcessing}, nil
+	}
+	select {
+	case p.checked <- struct{}{}:
+	default:
+	}
+	now := time.Now()
+	return &provider.ProviderStatus{Status: model.PayoutStatusCompleted, CompletedAt: &now}, nil
+}
+
+func (p *pendingProvider) CancelPayout(ctx context.Context, providerReference string) error {
+	return nil
+}
+
+func (p *pendingProvider) Name() string {
+	return "pending"
+}
+
+func (p *pendingProvider) complete() {
+	p.mu.Lock()
+	p.completed = true
+	p.mu.Unlock()
+}
+
+func TestPayoutService_RunReconciler_CompletesProcessingPayout(t *testing.T) {
+	repo := NewMockRepository()
+	prov := &pendingProvider{checked: make(chan struct{}, 1)}
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_reconcile",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusProcessing {
+		t.Fatalf("expected status PROCESSING, got: %s", payout.Status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.RunReconciler(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	prov.complete()
+	select {
+	case <-prov.checked:
+	case <-time.After(time.Second):
+		t.Fatal("reconciler never checked the payout's status")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reconciler did not stop after cancellation")
+	}
+
+	p, _ := svc.GetPayout(context.Background(), payout.ID)
+	if p.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected status COMPLETED after reconciliation, got: %s", p.Status)
+	}
+}
+
+// failingProvider fails every payout with the given failure category
+type failingProvider struct {
+	category provider.FailureCategory
+}
+
+func (p *failingProvider) ProcessPayout(ctx context.Context, payout *model.Payout) (*provider.ProviderResult, error) {
+	return &provider.ProviderResult{
+		ProviderReference: "FAIL_" + payout.ID,
+		Status:            model.PayoutStatusFailed,
+		FailureReason:     "failed with category " + string(p.category),
+		FailureCategory:   p.category,
+	}, nil
+}
+
+func (p *failingProvider) CheckStatus(ctx context.Context, providerReference string) (*provider.ProviderStatus, error) {
+	return &provider.ProviderStatus{Status: model.PayoutStatusFailed, FailureCategory: p.category}, nil
+}
+
+func (p *failingProvider) CancelPayout(ctx context.Context, providerReference string) error {
+	return nil
+}
+
+func (p *failingProvider) Name() string {
+	return "failing"
+}
+
+func TestPayoutService_PermanentFailureIsNotRetried(t *testing.T) {
+	repo := NewMockRepository()
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, &failingProvider{category: provider.FailurePermanent}, logger, 3)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_permanent",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusPermanentlyFailed {
+		t.Fatalf("expected status PERMANENTLY_FAILED, got: %s", payout.Status)
+	}
+
+	if _, err := svc.RetryPayout(context.Background(), payout.ID); err == nil {
+		t.Error("expected retry of a permanently failed payout to be rejected")
+	}
+	if payout.RetryCount != 0 {
+		t.Errorf("expected no retries, got: %d", payout.RetryCount)
+	}
+}
+
+func TestPayoutService_TransientFailureIsRetried(t *testing.T) {
+	repo := NewMockRepository()
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, &failingProvider{category: provider.FailureTransient}, logger, 3)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_transient",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusFailed {
+		t.Fatalf("expected status FAILED, got: %s", payout.Status)
+	}
+
+	retried, err := svc.RetryPayout(context.Background(), payout.ID)
+	if err != nil {
+		t.Fatalf("expected transient failure to be retried, got: %v", err)
+	}
+	if retried.RetryCount != 1 {
+		t.Errorf("expected retry count 1, got: %d", retried.RetryCount)
+	}
+}
+
+func TestPayoutService_FailureClassificationDisabled(t *testing.T) {
+	repo := NewMockRepository()
+	logger, _ := zap.NewDevelopment()
+
+	svc := NewPayoutService(repo, &failingProvider{category: provider.FailurePermanent}, logger, 3)
+	svc.SetFailureClassification(false)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_unclassified",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusFailed {
+		t.Errorf("expected status FAILED with classification disabled, got: %s", payout.Status)
+	}
+}
+
+func TestPayoutService_CashPickupRoundsToDenomination(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		payable  string
+		residual string
+	}{
+		{"4237.00", "PHP", "4230.00", "7.00"},
+		{"4237.75", "PHP", "4230.00", "7.75"},
+		{"125000", "IDR", "125000", ""},
+		{"20500", "IDR", "20000", "500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			repo := NewMockRepository()
+			prov := provider.NewSimulatedProvider(0, time.Millisecond)
+			svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+			svc.SetPickupDenominations(model.PickupDenominations, model.ResidualPolicyDonate)
+
+			payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+				TransferID: "transfer_pickup_" + tt.amount,
+				Method:     model.PayoutMethodCashPickup,
+				Amount:     tt.amount,
+				Currency:   tt.currency,
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+
+			if payout.Amount != tt.amount {
+				t.Errorf("expected requested amount %s to be kept, got %s", tt.amount, payout.Amount)
+			}
+			if payout.PayableAmount != tt.payable {
+				t.Errorf("expected payable %s, got %s", tt.payable, payout.PayableAmount)
+			}
+			if payout.ResidualAmount != tt.residual {
+				t.Errorf("expected residual %q, got %q", tt.residual, payout.ResidualAmount)
+			}
+			if tt.residual != "" && payout.ResidualPolicy != model.ResidualPolicyDonate {
+				t.Errorf("expected residual policy donate, got %q", payout.ResidualPolicy)
+			}
+		})
+	}
+}
+
+func TestPayoutService_CashPickupPaysOutPayableAmount(t *testing.T) {
+	// The provider fails anything above 4235, which the requested amount is
+	// and the amount rounded to denominations isn't
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, time.Millisecond).
+		WithFailureRules(provider.FailureRule{AmountAbove: 4235})
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+	svc.SetPickupDenominations(model.PickupDenominations, model.ResidualPolicyRefund)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_pickup_payable",
+		Method:     model.PayoutMethodCashPickup,
+		Amount:     "4237.75",
+		Currency:   "PHP",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.Status != model.PayoutStatusReadyForPickup {
+		t.Fatalf("expected the provider to be sent the payable 4230.00, got status %s (%s)", payout.Status, payout.FailureReason)
+	}
+	if payout.ProviderFee != "21.15" {
+		t.Errorf("expected the fee on the payable amount 21.15, got %s", payout.ProviderFee)
+	}
+}
+
+func TestPayoutService_CashPickupBelowMinimumAfterRounding(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, time.Millisecond)
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+	svc.SetPickupDenominations(map[string]decimal.Decimal{"PHP": decimal.NewFromInt(20)}, model.ResidualPolicyRefund)
+	svc.SetMinPayoutAmounts(map[string]decimal.Decimal{"PHP": decimal.NewFromInt(50)})
+
+	_, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_pickup_min",
+		Method:     model.PayoutMethodCashPickup,
+		Amount:     "55.00",
+		Currency:   "PHP",
+	})
+	if err == nil {
+		t.Error("expected an error when the payable amount falls below the minimum")
+	}
+}
+
+func TestPayoutService_BankPayoutIsNotRounded(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, time.Millisecond)
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_bank_exact",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "4237.75",
+		Currency:   "PHP",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if payout.PayableAmount != "" || payout.ResidualAmount != "" {
+		t.Errorf("expected bank payouts to be paid exactly, got payable %q residual %q", payout.PayableAmount, payout.ResidualAmount)
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// watchBuffer is how many status changes a watcher may fall behind by before
+// older ones are dropped for newer ones
+const watchBuffer = 16
+
+// StatusBroadcaster carries payout status changes between replicas, so a
+// payout can be watched on any of them whichever replica processes it
+type StatusBroadcaster interface {
+	// PublishPayoutStatus broadcasts a snapshot of the payout to its
+	// subscribers on every replica
+	PublishPayoutStatus(ctx context.Context, payout *model.Payout) error
+	// SubscribePayoutStatus returns the payout's broadcast snapshots once
+	// the subscription is active, and the func ending it
+	SubscribePayoutStatus(ctx context.Context, payoutID string) (<-chan model.Payout, func(), error)
+}
+
+// ErrPayoutNotFound is returned when watching a payout that doesn't exist
+type ErrPayoutNotFound struct {
+	PayoutID string
+}
+
+func (e ErrPayoutNotFound) Error() string {
+	return fmt.Sprintf("payout not found: %s", e.PayoutID)
+}
+
+// SetStatusBroadcaster makes status changes reach watchers on every replica.
+// Without one only changes made by this instance are seen.
+func (s *PayoutService) SetStatusBroadcaster(broadcaster StatusBroadcaster) {
+	s.broadcaster = broadcaster
+}
+
+// payoutWatchers fans the status changes made by this instance out to the
+// clients watching each payout
+type payoutWatchers struct {
+	mu   sync.Mutex
+	byID map[string]map[chan model.Payout]struct{}
+}
+
+// subscribe registers a watcher of the payout's status changes. The returned
+// func unregisters it.
+func (w *payoutWatchers) subscribe(payoutID string) (<-chan model.Payout, func()) {
+	ch := make(chan model.Payout, watchBuffer)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.byID == nil {
+		w.byID = make(map[string]map[chan model.Payout]struct{})
+	}
+	if w.byID[payoutID] == nil {
+		w.byID[payoutID] = make(map[chan model.Payout]struct{})
+	}
+	w.byID[payoutID][ch] = struct{}{}
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.byID[payoutID], ch)
+		if len(w.byID[payoutID]) == 0 {
+			delete(w.byID, payoutID)
+		}
+	}
+}
+
+// publish sends a snapshot of the payout to its watchers without blocking. A
+// watcher whose buffer is full loses its oldest status change, so the latest
+// (and any terminal) status always gets through.
+func (w *payoutWatchers) publish(payout *model.Payout) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.byID[payout.ID] {
+		select {
+		case ch <- *payout:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- *payout:
+			default:
+			}
+		}
+	}
+}
+
+// publishStatus sends a status change to the payout's watchers, through the
+// broadcaster when there is one
+func (s *PayoutService) publishStatus(ctx context.Context, payout *model.Payout) {
+	if s.broadcaster == nil {
+		s.watchers.publish(payout)
+		return
+	}
+	if err := s.broadcaster.PublishPayoutStatus(ctx, payout); err != nil {
+		s.logger.Warn("Failed to broadcast payout status change",
+			zap.String("payoutId", payout.ID),
+			zap.String("status", string(payout.Status)),
+			zap.Error(err),
+		)
+	}
+}
+
+// subscribeStatus subscribes to the payout's status changes, through the
+// broadcaster when there is one
+func (s *PayoutService) subscribeStatus(ctx context.Context, id string) (<-chan model.Payout, func(), error) {
+	if s.broadcaster == nil {
+		changes, unsubscribe := s.watchers.subscribe(id)
+		return changes, unsubscribe, nil
+	}
+	return s.broadcaster.SubscribePayoutStatus(ctx, id)
+}
+
+// WatchPayout streams the payout's status: its current state first, then
+// every status change until it reaches a terminal status or ctx is done, when
+// the channel is closed. Without a broadcaster only changes made by this
+// instance are seen.
+func (s *PayoutService) WatchPayout(ctx context.Context, id string) (<-chan *model.Payout, error) {
+	// Subscribe before loading, so no change can slip in between
+	changes, unsubscribe, err := s.subscribeStatus(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("watch payout: %w", err)
+	}
+
+	payout, err := s.repo.GetPayout(ctx, id)
+	if errors.Is(err, repository.ErrPayoutNotFound) {
+		unsubscribe()
+		return nil, ErrPayoutNotFound{PayoutID: id}
+	}
+	if err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	updates := make(chan *model.Payout)
+	go func() {
+		defer close(updates)
+		defer unsubscribe()
+
+		send := func(p *model.Payout) bool {
+			select {
+			case updates <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		last := payout.Status
+		if !send(payout) || last.IsTerminal() {
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				// Changes saved before the payout was loaded repeat its status
+				if change.Status == last {
+					continue
+				}
+				last = change.Status
+				if !send(&change) || last.IsTerminal() {
+					return
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
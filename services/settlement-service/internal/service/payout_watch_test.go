@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// memoryBroadcaster is an in-memory StatusBroadcaster shared by services
+// standing in for replicas
+type memoryBroadcaster struct {
+	watchers payoutWatchers
+}
+
+func (b *memoryBroadcaster) PublishPayoutStatus(ctx context.Context, payout *model.Payout) error {
+	b.watchers.publish(payout)
+	return nil
+}
+
+func (b *memoryBroadcaster) SubscribePayoutStatus(ctx context.Context, payoutID string) (<-chan model.Payout, func(), error) {
+	changes, unsubscribe := b.watchers.subscribe(payoutID)
+	return changes, unsubscribe, nil
+}
+
+// nextStatus returns the next status sent on updates, failing the test if
+// none arrives in time
+func nextStatus(t *testing.T, updates <-chan *model.Payout) model.PayoutStatus {
+	t.Helper()
+	select {
+	case payout, ok := <-updates:
+		if !ok {
+			t.Fatal("expected a status update, the watch ended")
+		}
+		return payout.Status
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a status update")
+	}
+	return ""
+}
+
+func TestPayoutService_WatchPayout(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewAsyncSimulatedProvider(0, 10*time.Millisecond, 50*time.Millisecond)
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	created, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_watch",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := svc.WatchPayout(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// The current status is sent straight away
+	if status := nextStatus(t, updates); status != model.PayoutStatusProcessing {
+		t.Fatalf("expected PROCESSING first, got %s", status)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := svc.ReconcilePayouts(context.Background()); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if status := nextStatus(t, updates); status != model.PayoutStatusCompleted {
+		t.Fatalf("expected COMPLETED next, got %s", status)
+	}
+
+	// The watch ends once the payout is terminal
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("expected the watch to end after COMPLETED")
+		}
+	case <-time.After(time.Second):
+		t.Error("timed out waiting for the watch to end")
+	}
+}
+
+func TestPayoutService_WatchPayout_Terminal(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, 10*time.Millisecond)
+	svc := NewPayoutService(repo, prov, zap.NewNop(), 3)
+
+	created, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_watch_done",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	updates, err := svc.WatchPayout(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if status := nextStatus(t, updates); status != model.PayoutStatusCompleted {
+		t.Fatalf("expected COMPLETED, got %s", status)
+	}
+	if _, ok := <-updates; ok {
+		t.Error("expected the watch of a completed payout to end")
+	}
+}
+
+func TestPayoutService_WatchPayout_SeesOtherReplicasChanges(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewAsyncSimulatedProvider(0, 10*time.Millisecond, 50*time.Millisecond)
+	broadcaster := &memoryBroadcaster{}
+	watching := NewPayoutService(repo, prov, zap.NewNop(), 3)
+	watching.SetStatusBroadcaster(broadcaster)
+	processing := NewPayoutService(repo, prov, zap.NewNop(), 3)
+	processing.SetStatusBroadcaster(broadcaster)
+
+	created, err := processing.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_watch_replica",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := watching.WatchPayout(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if status := nextStatus(t, updates); status != model.PayoutStatusProcessing {
+		t.Fatalf("expected PROCESSING first, got %s", status)
+	}
+
+	// The other replica completes the payout
+	time.Sleep(60 * time.Millisecond)
+	if _, err := processing.ReconcilePayouts(context.Background()); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if status := nextStatus(t, updates); status != model.PayoutStatusCompleted {
+		t.Fatalf("expected COMPLETED next, got %s", status)
+	}
+}
+
+func TestPayoutService_WatchPayout_NotFound(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+
+	_, err := svc.WatchPayout(context.Background(), "missing")
+	var notFound ErrPayoutNotFound
+	if !errors.As(err, &notFound) || notFound.PayoutID != "missing" {
+		t.Errorf("expected ErrPayoutNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PickupAttemptCounter counts the wrong pickup codes presented for each
+// payout, shared by every replica so a code can't be guessed by spreading
+// attempts across them
+type PickupAttemptCounter interface {
+	// PickupAttempts returns the wrong codes counted against the payout
+	PickupAttempts(ctx context.Context, payoutID string) (int, error)
+	// CountFailedPickupAttempt counts a wrong code against the payout, keeping
+	// the count for window after it, and returns the new count
+	CountFailedPickupAttempt(ctx context.Context, payoutID string, window time.Duration) (int, error)
+}
+
+// ErrNotCashPickup is returned when a pickup operation targets a payout
+// that isn't a cash pickup
+type ErrNotCashPickup struct {
+	PayoutID string
+}
+
+func (e ErrNotCashPickup) Error() string {
+	return fmt.Sprintf("payout %s is not a cash pickup payout", e.PayoutID)
+}
+
+// ErrPickupLocked is returned when a pickup is redeemed after too many wrong
+// codes were presented for it
+type ErrPickupLocked struct {
+	PayoutID string
+	Attempts int
+}
+
+func (e ErrPickupLocked) Error() string {
+	return fmt.Sprintf("pickup for payout %s is locked after %d wrong codes", e.PayoutID, e.Attempts)
+}
+
+// SetPickupAttemptLimit locks a payout's pickup once maxAttempts wrong codes
+// have been presented for it, until window has passed since the last one.
+// Without a counter codes can be tried without limit.
+func (s *PayoutService) SetPickupAttemptLimit(counter PickupAttemptCounter, maxAttempts int, window time.Duration) {
+	s.pickupAttempts = counter
+	s.maxPickupAttempts = maxAttempts
+	s.pickupLockout = window
+}
+
+// checkPickupAttempts fails with ErrPickupLocked if the payout's pickup is
+// locked. It fails closed when the count can't be read.
+func (s *PayoutService) checkPickupAttempts(ctx context.Context, payoutID string) error {
+	if s.pickupAttempts == nil {
+		return nil
+	}
+	attempts, err := s.pickupAttempts.PickupAttempts(ctx, payoutID)
+	if err != nil {
+		return fmt.Errorf("check pickup attempts: %w", err)
+	}
+	if attempts >= s.maxPickupAttempts {
+		return ErrPickupLocked{PayoutID: payoutID, Attempts: attempts}
+	}
+	return nil
+}
+
+// failPickupAttempt counts a wrong code against the payout and returns the
+// error for it, ErrPickupLocked once it is the last one allowed
+func (s *PayoutService) failPickupAttempt(ctx context.Context, payoutID string) error {
+	if s.pickupAttempts == nil {
+		return ErrInvalidPickupCode{PayoutID: payoutID}
+	}
+	attempts, err := s.pickupAttempts.CountFailedPickupAttempt(ctx, payoutID, s.pickupLockout)
+	if err != nil {
+		s.logger.Warn("Failed to count wrong pickup code",
+			zap.String("payoutId", payoutID),
+			zap.Error(err),
+		)
+		return ErrInvalidPickupCode{PayoutID: payoutID}
+	}
+	if attempts >= s.maxPickupAttempts {
+		s.logger.Warn("Pickup locked after too many wrong codes",
+			zap.String("payoutId", payoutID),
+			zap.Int("attempts", attempts),
+		)
+		return ErrPickupLocked{PayoutID: payoutID, Attempts: attempts}
+	}
+	return ErrInvalidPickupCode{PayoutID: payoutID}
+}
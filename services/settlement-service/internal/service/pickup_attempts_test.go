@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// memoryPickupAttempts is an in-memory PickupAttemptCounter
+type memoryPickupAttempts struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func newMemoryPickupAttempts() *memoryPickupAttempts {
+	return &memoryPickupAttempts{attempts: make(map[string]int)}
+}
+
+func (m *memoryPickupAttempts) PickupAttempts(ctx context.Context, payoutID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.attempts[payoutID], nil
+}
+
+func (m *memoryPickupAttempts) CountFailedPickupAttempt(ctx context.Context, payoutID string, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts[payoutID]++
+	return m.attempts[payoutID], nil
+}
+
+func TestPayoutService_RedeemPickup_LocksAfterWrongCodes(t *testing.T) {
+	svc, created := newPickupPayout(t)
+	svc.SetPickupAttemptLimit(newMemoryPickupAttempts(), 3, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.RedeemPickup(ctx, created.ID, "XYZ98765")
+		var invalid ErrInvalidPickupCode
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expected ErrInvalidPickupCode for wrong code %d, got: %v", i+1, err)
+		}
+	}
+	_, err := svc.RedeemPickup(ctx, created.ID, "XYZ98765")
+	var locked ErrPickupLocked
+	if !errors.As(err, &locked) || locked.Attempts != 3 {
+		t.Fatalf("expected ErrPickupLocked after 3 wrong codes, got: %v", err)
+	}
+
+	// Once locked, even the right code is refused
+	if _, err := svc.RedeemPickup(ctx, created.ID, "ABC12345"); !errors.As(err, &locked) {
+		t.Fatalf("expected ErrPickupLocked for the right code, got: %v", err)
+	}
+	payout, _ := svc.GetPayout(ctx, created.ID)
+	if payout.Status != model.PayoutStatusReadyForPickup {
+		t.Errorf("expected payout to stay ready for pickup, got %s", payout.Status)
+	}
+}
+
+func TestPayoutService_RedeemPickup_WrongCodeRevealsNoStatus(t *testing.T) {
+	svc, created := newPickupPayout(t)
+	ctx := context.Background()
+	if _, err := svc.RedeemPickup(ctx, created.ID, "ABC12345"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	_, err := svc.RedeemPickup(ctx, created.ID, "XYZ98765")
+	var invalid ErrInvalidPickupCode
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidPickupCode for a picked up payout, got: %v", err)
+	}
+}
+
+func TestPayoutService_RedeemPickup_NotCashPickup(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	ctx := context.Background()
+	payout, err := svc.InitiatePayout(ctx, &InitiatePayoutRequest{
+		TransferID: "transfer_bank",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	_, err = svc.RedeemPickup(ctx, payout.ID, "ABC12345")
+	var notPickup ErrNotCashPickup
+	if !errors.As(err, &notPickup) || notPickup.PayoutID != payout.ID {
+		t.Fatalf("expected ErrNotCashPickup, got: %v", err)
+	}
+}
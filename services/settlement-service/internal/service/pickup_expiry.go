@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// pickupExpiredReason is the failure reason of a cash pickup whose code
+// expired before it was collected
+const pickupExpiredReason = "pickup code expired before the cash was collected"
+
+// errPickupNotExpirable is returned by the expiry's change when the reloaded
+// payout is no longer ready for pickup, e.g. because it was collected meanwhile
+var errPickupNotExpirable = errors.New("payout is no longer ready for pickup")
+
+// ExpirePickups fails every READY_FOR_PICKUP payout whose pickup code has
+// expired, so uncollected cash can be retried with a new code or cancelled.
+// It returns the number of payouts expired.
+func (s *PayoutService) ExpirePickups(ctx context.Context) (int, error) {
+	payouts, err := s.repo.ListPayouts(ctx, repository.PayoutFilter{Status: model.PayoutStatusReadyForPickup})
+	if err != nil {
+		return 0, fmt.Errorf("list ready for pickup payouts: %w", err)
+	}
+
+	expired := 0
+	for _, payout := range payouts {
+		if payout.PickupExpiresAt == nil || time.Now().Before(*payout.PickupExpiresAt) {
+			continue
+		}
+
+		var from model.PayoutStatus
+		err := s.updatePayout(ctx, payout, "save expired pickup", func(payout *model.Payout) error {
+			from = payout.Status
+			if payout.Status != model.PayoutStatusReadyForPickup {
+				return errPickupNotExpirable
+			}
+			if err := payout.TransitionTo(model.PayoutStatusFailed); err != nil {
+				return err
+			}
+			payout.FailureReason = pickupExpiredReason
+			return nil
+		})
+		if errors.Is(err, errPickupNotExpirable) {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to expire pickup",
+				zap.String("payoutId", payout.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		expired++
+		s.recordStatusChange(payout, from)
+		s.notifyStatus(ctx, payout)
+
+		s.logger.Info("Cash pickup expired",
+			zap.String("payoutId", payout.ID),
+			zap.Timep("expiredAt", payout.PickupExpiresAt),
+		)
+	}
+	return expired, nil
+}
+
+// RunPickupExpiry expires uncollected pickups every interval until ctx is
+// cancelled
+func (s *PayoutService) RunPickupExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ExpirePickups(ctx); err != nil {
+				s.logger.Error("Pickup expiry sweep failed", zap.Error(err))
+			}
+		}
+	}
+}
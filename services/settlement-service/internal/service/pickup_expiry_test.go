@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+func TestPayoutService_ExpirePickups(t *testing.T) {
+	svc, expiring := newPickupPayout(t)
+	expired := time.Now().Add(-time.Minute)
+	expiring.PickupExpiresAt = &expired
+
+	// A pickup whose code is still valid is left ready to collect
+	valid, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_valid_pickup",
+		Method:     model.PayoutMethodCashPickup,
+		Amount:     "100.00",
+		Currency:   "PHP",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	recorder := &recordingNotifier{}
+	svc.SetStatusNotifier(recorder)
+
+	count, err := svc.ExpirePickups(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 pickup expired, got %d", count)
+	}
+
+	payout, _ := svc.GetPayout(context.Background(), expiring.ID)
+	if payout.Status != model.PayoutStatusFailed {
+		t.Errorf("expected status FAILED, got %s", payout.Status)
+	}
+	if payout.FailureReason != pickupExpiredReason {
+		t.Errorf("expected failure reason %q, got %q", pickupExpiredReason, payout.FailureReason)
+	}
+	if statuses := recorder.statuses(); len(statuses) != 1 || statuses[0] != model.PayoutStatusFailed {
+		t.Errorf("expected one FAILED status event, got %v", statuses)
+	}
+
+	payout, _ = svc.GetPayout(context.Background(), valid.ID)
+	if payout.Status != model.PayoutStatusReadyForPickup {
+		t.Errorf("expected the unexpired pickup to stay READY_FOR_PICKUP, got %s", payout.Status)
+	}
+
+	// Swept payouts aren't expired again
+	if count, _ := svc.ExpirePickups(context.Background()); count != 0 {
+		t.Errorf("expected no pickups expired on the second sweep, got %d", count)
+	}
+}
+
+// countingRepository counts the payout saves that reach the repository
+type countingRepository struct {
+	*conflictingRepository
+	saves int
+}
+
+func (r *countingRepository) SavePayout(ctx context.Context, payout *model.Payout) error {
+	r.saves++
+	return r.conflictingRepository.SavePayout(ctx, payout)
+}
+
+func TestPayoutService_ExpirePickups_SkipsPickupCollectedMeanwhile(t *testing.T) {
+	repo := &countingRepository{conflictingRepository: &conflictingRepository{MockRepository: NewMockRepository()}}
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	expired := time.Now().Add(-time.Minute)
+	repo.payouts["collected"] = &model.Payout{
+		ID: "collected", Status: model.PayoutStatusReadyForPickup, Method: model.PayoutMethodCashPickup,
+		Currency: "PHP", PickupExpiresAt: &expired,
+	}
+
+	// The cash is collected between listing the pickup and expiring it
+	repo.conflicts = 1
+	repo.concurrent = func(p *model.Payout) {
+		p.Status = model.PayoutStatusPickedUp
+	}
+
+	count, err := svc.ExpirePickups(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no pickups expired, got %d", count)
+	}
+	if payout := repo.payouts["collected"]; payout.Status != model.PayoutStatusPickedUp {
+		t.Errorf("expected the payout to stay PICKED_UP, got %s", payout.Status)
+	}
+	if repo.saves != 1 {
+		t.Errorf("expected only the conflicting save, got %d saves", repo.saves)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Quote consistency check modes
+const (
+	QuoteCheckOff     = "off"     // payouts are not checked against their rate lock
+	QuoteCheckWarn    = "warn"    // mismatches are logged and flagged on the payout
+	QuoteCheckEnforce = "enforce" // mismatching payouts are rejected before any money moves
+)
+
+// LockQuote is the exchange rate service's quote for an amount against a
+// rate lock
+type LockQuote struct {
+	SourceCurrency string
+	TargetCurrency string
+	TargetAmount   decimal.Decimal
+}
+
+// ExchangeRateClient prices amounts against rate locks held by the exchange
+// rate service
+type ExchangeRateClient interface {
+	// QuoteFromLock quotes sourceAmount at the lock's rate and terms, also
+	// once the lock has expired
+	QuoteFromLock(ctx context.Context, lockID string, sourceAmount decimal.Decimal) (*LockQuote, error)
+}
+
+// ErrQuoteMismatch is returned when a payout's amount doesn't match what its
+// rate lock quotes for the transfer's source amount
+type ErrQuoteMismatch struct {
+	LockID   string
+	Expected string // Amount and currency the lock quotes
+	Actual   string // Amount and currency of the payout
+}
+
+func (e ErrQuoteMismatch) Error() string {
+	return fmt.Sprintf("payout of %s does not match %s quoted by rate lock %s", e.Actual, e.Expected, e.LockID)
+}
+
+// SetQuoteCheck verifies payouts carrying a rate lock against the exchange
+// rate service's quote for that lock. Amounts may differ by up to tolerance
+// in the payout currency.
+func (s *PayoutService) SetQuoteCheck(client ExchangeRateClient, mode string, tolerance decimal.Decimal) {
+	s.rates = client
+	s.quoteCheckMode = mode
+	s.quoteTolerance = tolerance
+}
+
+// checkQuote compares a new payout with the quote for its rate lock. In warn
+// mode problems are flagged on the payout; in enforce mode they are returned.
+func (s *PayoutService) checkQuote(ctx context.Context, req *InitiatePayoutRequest, payout *model.Payout) error {
+	if s.rates == nil || req.LockID == "" || (s.quoteCheckMode != QuoteCheckWarn && s.quoteCheckMode != QuoteCheckEnforce) {
+		return nil
+	}
+
+	err := s.compareWithQuote(ctx, req)
+	if err == nil {
+		return nil
+	}
+	if s.quoteCheckMode == QuoteCheckEnforce {
+		return err
+	}
+
+	s.logger.Warn("Payout does not match its rate lock quote",
+		zap.String("transferId", req.TransferID),
+		zap.String("lockId", req.LockID),
+		zap.Error(err),
+	)
+	payout.QuoteMismatch = err.Error()
+	return nil
+}
+
+func (s *PayoutService) compareWithQuote(ctx context.Context, req *InitiatePayoutRequest) error {
+	sourceAmount, err := decimal.NewFromString(req.SourceAmount)
+	if err != nil {
+		return fmt.Errorf("invalid source amount %q for quote check", req.SourceAmount)
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", req.Amount)
+	}
+
+	quote, err := s.rates.QuoteFromLock(ctx, req.LockID, sourceAmount)
+	if err != nil {
+		return fmt.Errorf("verify rate lock %s: %w", req.LockID, err)
+	}
+
+	if quote.TargetCurrency != req.Currency || quote.TargetAmount.Sub(amount).Abs().GreaterThan(s.quoteTolerance) {
+		return ErrQuoteMismatch{
+			LockID:   req.LockID,
+			Expected: fmt.Sprintf("%s %s", model.FormatAmount(quote.TargetAmount, quote.TargetCurrency), quote.TargetCurrency),
+			Actual:   fmt.Sprintf("%s %s", req.Amount, req.Currency),
+		}
+	}
+	return nil
+}
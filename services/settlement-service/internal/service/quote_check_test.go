@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// mockExchangeRateClient quotes a fixed rate for every lock it knows
+type mockExchangeRateClient struct {
+	rates    map[string]decimal.Decimal // lock ID -> rate into currency
+	currency string                     // target currency, PHP when empty
+	err      error
+	calls    int
+}
+
+func (m *mockExchangeRateClient) QuoteFromLock(ctx context.Context, lockID string, sourceAmount decimal.Decimal) (*LockQuote, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	rate, ok := m.rates[lockID]
+	if !ok {
+		return nil, errors.New("rate lock not found")
+	}
+	currency := m.currency
+	if currency == "" {
+		currency = "PHP"
+	}
+	return &LockQuote{SourceCurrency: "SGD", TargetCurrency: currency, TargetAmount: sourceAmount.Mul(rate)}, nil
+}
+
+func newQuoteCheckedService(mode string, client ExchangeRateClient) (*PayoutService, *MockRepository) {
+	repo := NewMockRepository()
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	svc.SetQuoteCheck(client, mode, decimal.RequireFromString("0.01"))
+	return svc, repo
+}
+
+func lockedPayoutRequest(amount string) *InitiatePayoutRequest {
+	return &InitiatePayoutRequest{
+		TransferID:   "transfer_locked",
+		Method:       model.PayoutMethodBankAccount,
+		Amount:       amount,
+		Currency:     "PHP",
+		LockID:       "lock_123",
+		SourceAmount: "100.00",
+	}
+}
+
+func TestPayoutService_QuoteCheck_Matching(t *testing.T) {
+	client := &mockExchangeRateClient{rates: map[string]decimal.Decimal{"lock_123": decimal.RequireFromString("42.5")}}
+	svc, _ := newQuoteCheckedService(QuoteCheckEnforce, client)
+
+	payout, err := svc.InitiatePayout(context.Background(), lockedPayoutRequest("4250.00"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if client.calls != 1 {
+		t.Errorf("expected the lock to be quoted once, got %d calls", client.calls)
+	}
+	if payout.LockID != "lock_123" || payout.QuoteMismatch != "" {
+		t.Errorf("expected an unflagged payout for lock_123, got lock %q mismatch %q", payout.LockID, payout.QuoteMismatch)
+	}
+}
+
+func TestPayoutService_QuoteCheck_EnforceRejectsMismatch(t *testing.T) {
+	client := &mockExchangeRateClient{rates: map[string]decimal.Decimal{"lock_123": decimal.RequireFromString("42.5")}}
+	svc, repo := newQuoteCheckedService(QuoteCheckEnforce, client)
+
+	_, err := svc.InitiatePayout(context.Background(), lockedPayoutRequest("4300.00"))
+	var mismatch ErrQuoteMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected ErrQuoteMismatch, got %v", err)
+	}
+	if mismatch.Expected != "4250.00 PHP" || mismatch.Actual != "4300.00 PHP" {
+		t.Errorf("unexpected mismatch details: %+v", mismatch)
+	}
+	if len(repo.payouts) != 0 {
+		t.Errorf("expected no payout to be saved, got %d", len(repo.payouts))
+	}
+}
+
+func TestPayoutService_QuoteCheck_EnforceRejectsUnverifiableLock(t *testing.T) {
+	client := &mockExchangeRateClient{err: errors.New("rate lock lock_123 expired")}
+	svc, repo := newQuoteCheckedService(QuoteCheckEnforce, client)
+
+	if _, err := svc.InitiatePayout(context.Background(), lockedPayoutRequest("4250.00")); err == nil {
+		t.Fatal("expected an error when the lock can't be quoted")
+	}
+	if len(repo.payouts) != 0 {
+		t.Errorf("expected no payout to be saved, got %d", len(repo.payouts))
+	}
+}
+
+func TestPayoutService_QuoteCheck_WarnFlagsMismatch(t *testing.T) {
+	client := &mockExchangeRateClient{rates: map[string]decimal.Decimal{"lock_123": decimal.RequireFromString("42.5")}}
+	svc, _ := newQuoteCheckedService(QuoteCheckWarn, client)
+
+	payout, err := svc.InitiatePayout(context.Background(), lockedPayoutRequest("4300.00"))
+	if err != nil {
+		t.Fatalf("expected warn mode to proceed, got: %v", err)
+	}
+	if payout.QuoteMismatch == "" {
+		t.Error("expected the payout to be flagged")
+	}
+	if payout.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected status COMPLETED, got: %s", payout.Status)
+	}
+}
+
+func TestPayoutService_QuoteCheck_Skipped(t *testing.T) {
+	client := &mockExchangeRateClient{rates: map[string]decimal.Decimal{"lock_123": decimal.RequireFromString("42.5")}}
+
+	svc, _ := newQuoteCheckedService(QuoteCheckOff, client)
+	if _, err := svc.InitiatePayout(context.Background(), lockedPayoutRequest("4300.00")); err != nil {
+		t.Fatalf("expected the check to be off, got: %v", err)
+	}
+
+	svc, _ = newQuoteCheckedService(QuoteCheckEnforce, client)
+	req := lockedPayoutRequest("4300.00")
+	req.LockID = ""
+	if _, err := svc.InitiatePayout(context.Background(), req); err != nil {
+		t.Fatalf("expected payouts without a lock to skip the check, got: %v", err)
+	}
+
+	if client.calls != 0 {
+		t.Errorf("expected no quotes, got %d calls", client.calls)
+	}
+}
+
+func TestPayoutService_QuoteCheck_ExpectedAmountInCurrencyPrecision(t *testing.T) {
+	client := &mockExchangeRateClient{rates: map[string]decimal.Decimal{"lock_123": decimal.RequireFromString("11650.5")}, currency: "IDR"}
+	svc, _ := newQuoteCheckedService(QuoteCheckEnforce, client)
+	req := lockedPayoutRequest("1165000")
+	req.Currency = "IDR"
+
+	_, err := svc.InitiatePayout(context.Background(), req)
+	var mismatch ErrQuoteMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected ErrQuoteMismatch, got %v", err)
+	}
+	if mismatch.Expected != "1165050 IDR" {
+		t.Errorf("expected the quoted amount without decimals, got %q", mismatch.Expected)
+	}
+}
+
+func TestPayoutService_QuoteCheck_ToleranceIsExact(t *testing.T) {
+	client := &mockExchangeRateClient{rates: map[string]decimal.Decimal{"lock_123": decimal.RequireFromString("42.5")}}
+	svc, _ := newQuoteCheckedService(QuoteCheckEnforce, client)
+
+	// 0.01 off is within the tolerance; float math puts 4250.01 - 4250 above it
+	if _, err := svc.InitiatePayout(context.Background(), lockedPayoutRequest("4250.01")); err != nil {
+		t.Errorf("expected a difference of exactly the tolerance to pass, got: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// SLABreachRecorder is told about every payout found past its settlement SLA
+type SLABreachRecorder func(method model.PayoutMethod, currency string)
+
+// inFlightStatuses are the statuses a payout can breach its SLA in. Cash
+// pickups waiting to be collected have settled, so READY_FOR_PICKUP isn't one.
+var inFlightStatuses = []model.PayoutStatus{model.PayoutStatusPending, model.PayoutStatusProcessing}
+
+// errNoSLABreach is returned by the SLA flag's change when the reloaded
+// payout has settled or been flagged since it was listed
+var errNoSLABreach = errors.New("payout no longer breaches its SLA")
+
+// SetSettlementSLAs overrides how long payouts may stay in flight before they
+// breach their SLA. Keys are a method ("BANK_ACCOUNT") or a method and payout
+// currency ("BANK_ACCOUNT:IDR"), which takes precedence. Methods without an
+// override use model.EstimatedCompletion.
+func (s *PayoutService) SetSettlementSLAs(slas map[string]time.Duration) {
+	s.slas = slas
+}
+
+// SetSLABreachRecorder registers the recorder told about every SLA breach
+func (s *PayoutService) SetSLABreachRecorder(recorder SLABreachRecorder) {
+	s.onSLABreach = recorder
+}
+
+// settlementSLA returns how long the payout may stay in flight, or 0 if its
+// method has no SLA
+func (s *PayoutService) settlementSLA(payout *model.Payout) time.Duration {
+	if sla, ok := s.slas[string(payout.Method)+":"+payout.Currency]; ok {
+		return sla
+	}
+	if sla, ok := s.slas[string(payout.Method)]; ok {
+		return sla
+	}
+	return model.EstimatedCompletion[payout.Method]
+}
+
+// CheckSLABreaches flags every in-flight payout older than its SLA that
+// hasn't been flagged yet, so support can intervene. It returns the number of
+// payouts newly flagged.
+func (s *PayoutService) CheckSLABreaches(ctx context.Context) (int, error) {
+	now := time.Now()
+	breached := 0
+	for _, status := range inFlightStatuses {
+		payouts, err := s.repo.ListPayouts(ctx, repository.PayoutFilter{Status: status})
+		if err != nil {
+			return breached, fmt.Errorf("list %s payouts: %w", status, err)
+		}
+
+		for _, payout := range payouts {
+			sla := s.settlementSLA(payout)
+			if payout.SLABreachedAt != nil || sla <= 0 || now.Sub(payout.CreatedAt) <= sla {
+				continue
+			}
+
+			err := s.updatePayout(ctx, payout, "save SLA breach", func(p *model.Payout) error {
+				if p.SLABreachedAt != nil || (p.Status != model.PayoutStatusPending && p.Status != model.PayoutStatusProcessing) {
+					return errNoSLABreach
+				}
+				p.SLABreachedAt = &now
+				return nil
+			})
+			if errors.Is(err, errNoSLABreach) {
+				continue
+			}
+			if err != nil {
+				s.logger.Error("Failed to flag SLA breach",
+					zap.String("payoutId", payout.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+			breached++
+
+			if s.onSLABreach != nil {
+				s.onSLABreach(payout.Method, payout.Currency)
+			}
+			s.logger.Warn("Payout breached its settlement SLA",
+				zap.String("payoutId", payout.ID),
+				zap.String("method", string(payout.Method)),
+				zap.String("status", string(payout.Status)),
+				zap.Duration("sla", sla),
+				zap.Duration("age", now.Sub(payout.CreatedAt)),
+			)
+		}
+	}
+	return breached, nil
+}
+
+// MonitorSLAs checks for SLA breaches every interval until ctx is cancelled
+func (s *PayoutService) MonitorSLAs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CheckSLABreaches(ctx); err != nil {
+				s.logger.Error("SLA breach check failed", zap.Error(err))
+			}
+		}
+	}
+}
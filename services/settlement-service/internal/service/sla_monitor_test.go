@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+func TestPayoutService_CheckSLABreaches_FlagsStuckPayout(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+
+	var breaches []string
+	svc.SetSLABreachRecorder(func(method model.PayoutMethod, currency string) {
+		breaches = append(breaches, string(method)+":"+currency)
+	})
+
+	now := time.Now()
+	repo.payouts["stuck"] = &model.Payout{
+		ID: "stuck", Status: model.PayoutStatusProcessing, Method: model.PayoutMethodMobileWallet,
+		Currency: "PHP", CreatedAt: now.Add(-time.Hour),
+	}
+	repo.payouts["on_time"] = &model.Payout{
+		ID: "on_time", Status: model.PayoutStatusProcessing, Method: model.PayoutMethodBankAccount,
+		Currency: "PHP", CreatedAt: now.Add(-time.Hour),
+	}
+	repo.payouts["completed"] = &model.Payout{
+		ID: "completed", Status: model.PayoutStatusCompleted, Method: model.PayoutMethodMobileWallet,
+		Currency: "PHP", CreatedAt: now.Add(-time.Hour),
+	}
+
+	breached, err := svc.CheckSLABreaches(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if breached != 1 {
+		t.Fatalf("expected 1 breach, got %d", breached)
+	}
+	if repo.payouts["stuck"].SLABreachedAt == nil {
+		t.Error("expected the stuck mobile wallet payout to be flagged")
+	}
+	if repo.payouts["on_time"].SLABreachedAt != nil || repo.payouts["completed"].SLABreachedAt != nil {
+		t.Error("expected payouts within their SLA or settled not to be flagged")
+	}
+	if len(breaches) != 1 || breaches[0] != "MOBILE_WALLET:PHP" {
+		t.Errorf("expected one MOBILE_WALLET:PHP breach recorded, got %v", breaches)
+	}
+
+	// Flagged payouts are only reported once
+	if breached, _ := svc.CheckSLABreaches(context.Background()); breached != 0 || len(breaches) != 1 {
+		t.Errorf("expected no new breaches, got %d", breached)
+	}
+}
+
+func TestPayoutService_SettlementSLA_CurrencyOverride(t *testing.T) {
+	svc := NewPayoutService(NewMockRepository(), provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	svc.SetSettlementSLAs(map[string]time.Duration{
+		"BANK_ACCOUNT":     2 * time.Hour,
+		"BANK_ACCOUNT:IDR": 48 * time.Hour,
+	})
+
+	tests := []struct {
+		method   model.PayoutMethod
+		currency string
+		want     time.Duration
+	}{
+		{model.PayoutMethodBankAccount, "PHP", 2 * time.Hour},
+		{model.PayoutMethodBankAccount, "IDR", 48 * time.Hour},
+		{model.PayoutMethodMobileWallet, "PHP", model.EstimatedCompletion[model.PayoutMethodMobileWallet]},
+	}
+	for _, tt := range tests {
+		payout := &model.Payout{Method: tt.method, Currency: tt.currency}
+		if got := svc.settlementSLA(payout); got != tt.want {
+			t.Errorf("%s %s: expected SLA %s, got %s", tt.method, tt.currency, tt.want, got)
+		}
+	}
+}
+
+func TestPayoutService_CheckSLABreaches_ReloadsAfterConcurrentModification(t *testing.T) {
+	repo := &conflictingRepository{MockRepository: NewMockRepository()}
+	svc := NewPayoutService(repo, provider.NewSimulatedProvider(0, time.Millisecond), zap.NewNop(), 3)
+	stuckPayout := func(id string) *model.Payout {
+		return &model.Payout{
+			ID: id, Status: model.PayoutStatusProcessing, Method: model.PayoutMethodMobileWallet,
+			Currency: "PHP", CreatedAt: time.Now().Add(-time.Hour),
+		}
+	}
+
+	// Another writer saves the payout between listing and flagging it. Its
+	// save never carried the flag.
+	repo.payouts["stuck"] = stuckPayout("stuck")
+	repo.conflicts = 1
+	repo.concurrent = func(p *model.Payout) {
+		p.SLABreachedAt = nil
+		p.ProviderReference = "ref_stuck"
+	}
+	breached, err := svc.CheckSLABreaches(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stuck := repo.payouts["stuck"]
+	if breached != 1 || stuck.SLABreachedAt == nil || stuck.ProviderReference != "ref_stuck" {
+		t.Errorf("expected the breach flagged on top of the concurrent change, got %d breaches and %+v", breached, stuck)
+	}
+
+	// A payout that completes meanwhile no longer breaches its SLA
+	repo.payouts["settling"] = stuckPayout("settling")
+	repo.conflicts = 1
+	repo.concurrent = func(p *model.Payout) {
+		p.SLABreachedAt = nil
+		p.Status = model.PayoutStatusCompleted
+	}
+	breached, err = svc.CheckSLABreaches(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settled := repo.payouts["settling"]; breached != 0 || settled.SLABreachedAt != nil {
+		t.Errorf("expected the payout completed meanwhile not to be flagged, got %d breaches and %+v", breached, settled)
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"go.uber.org/zap"
+)
+
+// StatusNotifier is told about every payout status change, e.g. to publish
+// status events or call webhooks
+type StatusNotifier interface {
+	NotifyStatusChange(ctx context.Context, payout *model.Payout) error
+}
+
+// Status notification channels
+const (
+	ChannelKafka   = "kafka"
+	ChannelWebhook = "webhook"
+)
+
+// OrderedNotifier sends each status change to its channels one at a time, in
+// a fixed order. A channel is only called after the previous one has
+// returned, so with a Kafka notifier that returns once the broker has
+// acknowledged the event, ordering "kafka" before "webhook" guarantees a
+// webhook never fires before the status event is durably produced; ordering
+// "webhook" first guarantees the webhook was attempted before consumers see
+// the event. A failing channel doesn't stop the ones after it (the guarantee
+// is then only that it was attempted first), and errors are returned for
+// logging without affecting the payout's own status.
+type OrderedNotifier struct {
+	names     []string
+	notifiers []StatusNotifier
+}
+
+// NewOrderedNotifier creates a notifier calling channels in the given order.
+// Unknown names in order are ignored; channels missing from order are called
+// last, sorted by name.
+func NewOrderedNotifier(order []string, channels map[string]StatusNotifier) *OrderedNotifier {
+	n := &OrderedNotifier{}
+	added := make(map[string]bool, len(channels))
+	add := func(name string) {
+		if notifier, ok := channels[name]; ok && !added[name] {
+			added[name] = true
+			n.names = append(n.names, name)
+			n.notifiers = append(n.notifiers, notifier)
+		}
+	}
+
+	for _, name := range order {
+		add(name)
+	}
+	remaining := make([]string, 0, len(channels))
+	for name := range channels {
+		if !added[name] {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Strings(remaining)
+	for _, name := range remaining {
+		add(name)
+	}
+	return n
+}
+
+// NotifyStatusChange sends the status change to every channel in order
+func (n *OrderedNotifier) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	var errs []error
+	for i, notifier := range n.notifiers {
+		if err := notifier.NotifyStatusChange(ctx, payout); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StatusDebouncer coalesces rapid status changes for the same payout, with a
+// debounce window per channel. Intermediate statuses are held for a channel's
+// window and replaced by any later status; terminal statuses are sent to every
+// channel immediately and discard pending intermediate ones, so they are never
+// dropped.
+//
+// Channels are debounced after ordering: a channel's window is at least that
+// of the channels before it, and sending a channel's pending status first
+// sends the same status to any earlier channel still holding it, so no
+// channel sees a status before the channels ordered ahead of it.
+type StatusDebouncer struct {
+	names    []string
+	channels []StatusNotifier
+	windows  []time.Duration
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	pending map[string][]*pendingStatus // by payout ID, then channel; nil when nothing is pending
+
+	// sendMu serializes sends so a delayed intermediate status can never be
+	// delivered after the terminal status that superseded it
+	sendMu sync.Mutex
+}
+
+type pendingStatus struct {
+	payout *model.Payout
+	timer  *time.Timer
+}
+
+// NewStatusDebouncer creates a debouncing notifier with a single channel. A
+// window <= 0 disables debouncing and returns next unchanged.
+func NewStatusDebouncer(next StatusNotifier, window time.Duration, logger *zap.Logger) StatusNotifier {
+	if window <= 0 {
+		return next
+	}
+	return newStatusDebouncer([]string{""}, []StatusNotifier{next}, []time.Duration{window}, logger)
+}
+
+// NewOrderedDebouncer creates a notifier calling channels in the given order,
+// as NewOrderedNotifier does, with each channel's intermediate statuses
+// coalesced within its window in windows. Channels missing from windows use
+// defaultWindow. If no channel has a window > 0 the OrderedNotifier is
+// returned unchanged.
+func NewOrderedDebouncer(order []string, channels map[string]StatusNotifier, windows map[string]time.Duration, defaultWindow time.Duration, logger *zap.Logger) StatusNotifier {
+	ordered := NewOrderedNotifier(order, channels)
+
+	channelWindows := make([]time.Duration, len(ordered.names))
+	var longest time.Duration
+	for i, name := range ordered.names {
+		window, ok := windows[name]
+		if !ok {
+			window = defaultWindow
+		}
+		// A later channel never sends before an earlier one
+		longest = max(longest, window)
+		channelWindows[i] = longest
+	}
+	if longest <= 0 {
+		return ordered
+	}
+	return newStatusDebouncer(ordered.names, ordered.notifiers, channelWindows, logger)
+}
+
+func newStatusDebouncer(names []string, channels []StatusNotifier, windows []time.Duration, logger *zap.Logger) *StatusDebouncer {
+	return &StatusDebouncer{
+		names:    names,
+		channels: channels,
+		windows:  windows,
+		logger:   logger,
+		pending:  make(map[string][]*pendingStatus),
+	}
+}
+
+// NotifyStatusChange sends a status change to the channels without a window
+// and queues it for the rest
+func (d *StatusDebouncer) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	snapshot := *payout
+	terminal := snapshot.Status.IsTerminal()
+
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+
+	d.mu.Lock()
+	pending := d.pending[snapshot.ID]
+	if pending == nil {
+		pending = make([]*pendingStatus, len(d.channels))
+	}
+	var immediate []int
+	for i := range d.channels {
+		if terminal || d.windows[i] <= 0 {
+			if p := pending[i]; p != nil {
+				p.timer.Stop()
+				pending[i] = nil
+			}
+			immediate = append(immediate, i)
+			continue
+		}
+		if p := pending[i]; p != nil {
+			p.payout = &snapshot
+			continue
+		}
+		channel := i
+		pending[i] = &pendingStatus{
+			payout: &snapshot,
+			timer:  time.AfterFunc(d.windows[i], func() { d.flush(snapshot.ID, channel) }),
+		}
+	}
+	d.setPending(snapshot.ID, pending)
+	d.mu.Unlock()
+
+	var errs []error
+	for _, i := range immediate {
+		if err := d.send(ctx, i, &snapshot); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush immediately sends every pending status, e.g. during shutdown
+func (d *StatusDebouncer) Flush() {
+	d.mu.Lock()
+	ids := make([]string, 0, len(d.pending))
+	for id := range d.pending {
+		ids = append(ids, id)
+	}
+	d.mu.Unlock()
+
+	for _, id := range ids {
+		d.flush(id, len(d.channels)-1)
+	}
+}
+
+// flush sends a payout's pending status to channel and to any channel ordered
+// before it that still holds it
+func (d *StatusDebouncer) flush(payoutID string, channel int) {
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+
+	d.mu.Lock()
+	pending := d.pending[payoutID]
+	due := make(map[int]*model.Payout)
+	for i := 0; i < len(pending) && i <= channel; i++ {
+		if p := pending[i]; p != nil {
+			p.timer.Stop()
+			due[i] = p.payout
+			pending[i] = nil
+		}
+	}
+	d.setPending(payoutID, pending)
+	d.mu.Unlock()
+
+	for i := 0; i <= channel; i++ {
+		payout, ok := due[i]
+		if !ok {
+			continue // Already sent, or superseded by a terminal status
+		}
+		if err := d.send(context.Background(), i, payout); err != nil {
+			d.logger.Error("Failed to send debounced status change",
+				zap.String("payoutId", payoutID),
+				zap.String("status", string(payout.Status)),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// setPending stores a payout's pending statuses, dropping the entry once
+// nothing is pending. d.mu must be held.
+func (d *StatusDebouncer) setPending(payoutID string, pending []*pendingStatus) {
+	for _, p := range pending {
+		if p != nil {
+			d.pending[payoutID] = pending
+			return
+		}
+	}
+	delete(d.pending, payoutID)
+}
+
+// send sends a status change to one channel, naming the channel in its error
+func (d *StatusDebouncer) send(ctx context.Context, channel int, payout *model.Payout) error {
+	err := d.channels[channel].NotifyStatusChange(ctx, payout)
+	if err != nil && d.names[channel] != "" {
+		return fmt.Errorf("%s: %w", d.names[channel], err)
+	}
+	return err
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/model"
+	"github.com/movra/settlement-service/internal/provider"
+	"go.uber.org/zap"
+)
+
+// recordingNotifier records every status change it is sent
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []model.Payout
+}
+
+func (n *recordingNotifier) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, *payout)
+	return nil
+}
+
+func (n *recordingNotifier) statuses() []model.PayoutStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	statuses := make([]model.PayoutStatus, len(n.events))
+	for i, e := range n.events {
+		statuses[i] = e.Status
+	}
+	return statuses
+}
+
+func TestStatusDebouncer_CoalescesRapidTransitions(t *testing.T) {
+	recorder := &recordingNotifier{}
+	debouncer := NewStatusDebouncer(recorder, 50*time.Millisecond, zap.NewNop())
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1"}
+	for _, status := range []model.PayoutStatus{model.PayoutStatusPending, model.PayoutStatusProcessing, model.PayoutStatusCompleted} {
+		payout.Status = status
+		if err := debouncer.NotifyStatusChange(ctx, payout); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Wait past the window to make sure nothing stale is flushed later
+	time.Sleep(100 * time.Millisecond)
+
+	statuses := recorder.statuses()
+	if len(statuses) != 1 || statuses[0] != model.PayoutStatusCompleted {
+		t.Errorf("expected a single COMPLETED event, got %v", statuses)
+	}
+}
+
+func TestStatusDebouncer_SendsIntermediateStatusAfterWindow(t *testing.T) {
+	recorder := &recordingNotifier{}
+	debouncer := NewStatusDebouncer(recorder, 20*time.Millisecond, zap.NewNop())
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1", Status: model.PayoutStatusPending}
+	debouncer.NotifyStatusChange(ctx, payout)
+	payout.Status = model.PayoutStatusProcessing
+	debouncer.NotifyStatusChange(ctx, payout)
+
+	if statuses := recorder.statuses(); len(statuses) != 0 {
+		t.Fatalf("expected no events inside the window, got %v", statuses)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	statuses := recorder.statuses()
+	if len(statuses) != 1 || statuses[0] != model.PayoutStatusProcessing {
+		t.Errorf("expected the latest intermediate status once, got %v", statuses)
+	}
+}
+
+func TestStatusDebouncer_TerminalStatusesNeverDropped(t *testing.T) {
+	recorder := &recordingNotifier{}
+	debouncer := NewStatusDebouncer(recorder, time.Minute, zap.NewNop())
+	ctx := context.Background()
+
+	// A retried payout fails, then completes: both terminal events are kept
+	payout := &model.Payout{ID: "payout_1", Status: model.PayoutStatusFailed}
+	debouncer.NotifyStatusChange(ctx, payout)
+	payout.Status = model.PayoutStatusPending
+	debouncer.NotifyStatusChange(ctx, payout)
+	payout.Status = model.PayoutStatusCompleted
+	debouncer.NotifyStatusChange(ctx, payout)
+
+	statuses := recorder.statuses()
+	if len(statuses) != 2 || statuses[0] != model.PayoutStatusFailed || statuses[1] != model.PayoutStatusCompleted {
+		t.Errorf("expected FAILED then COMPLETED, got %v", statuses)
+	}
+}
+
+func TestStatusDebouncer_ZeroWindowDisablesDebounce(t *testing.T) {
+	recorder := &recordingNotifier{}
+	if notifier := NewStatusDebouncer(recorder, 0, zap.NewNop()); notifier != StatusNotifier(recorder) {
+		t.Error("expected a zero window to return the wrapped notifier")
+	}
+}
+
+func TestPayoutService_InitiatePayout_DebouncedStatusEvents(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	recorder := &recordingNotifier{}
+	svc := NewPayoutService(repo, prov, logger, 3)
+	svc.SetStatusNotifier(NewStatusDebouncer(recorder, 50*time.Millisecond, logger))
+
+	_, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_debounce",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	statuses := recorder.statuses()
+	if len(statuses) != 1 || statuses[0] != model.PayoutStatusCompleted {
+		t.Errorf("expected a single COMPLETED event, got %v", statuses)
+	}
+}
+
+// channelNotifier appends its name to a shared log and returns err
+type channelNotifier struct {
+	name string
+	log  *[]string
+	err  error
+}
+
+func (n *channelNotifier) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	*n.log = append(*n.log, n.name+":"+string(payout.Status))
+	return n.err
+}
+
+func TestOrderedNotifier_CallsChannelsInConfiguredOrder(t *testing.T) {
+	tests := []struct {
+		order    []string
+		expected []string
+	}{
+		{[]string{ChannelKafka, ChannelWebhook}, []string{"kafka:COMPLETED", "webhook:COMPLETED"}},
+		{[]string{ChannelWebhook, ChannelKafka}, []string{"webhook:COMPLETED", "kafka:COMPLETED"}},
+		{[]string{ChannelWebhook}, []string{"webhook:COMPLETED", "kafka:COMPLETED"}},
+	}
+
+	for _, tt := range tests {
+		var log []string
+		notifier := NewOrderedNotifier(tt.order, map[string]StatusNotifier{
+			ChannelKafka:   &channelNotifier{name: "kafka", log: &log},
+			ChannelWebhook: &channelNotifier{name: "webhook", log: &log},
+		})
+
+		payout := &model.Payout{ID: "payout_1", Status: model.PayoutStatusCompleted}
+		if err := notifier.NotifyStatusChange(context.Background(), payout); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(log) != len(tt.expected) || log[0] != tt.expected[0] || log[1] != tt.expected[1] {
+			t.Errorf("order %v: expected %v, got %v", tt.order, tt.expected, log)
+		}
+	}
+}
+
+func TestOrderedNotifier_FailuresAreIsolated(t *testing.T) {
+	var log []string
+	produceErr := errors.New("broker unavailable")
+	notifier := NewOrderedNotifier([]string{ChannelKafka, ChannelWebhook}, map[string]StatusNotifier{
+		ChannelKafka:   &channelNotifier{name: "kafka", log: &log, err: produceErr},
+		ChannelWebhook: &channelNotifier{name: "webhook", log: &log},
+	})
+
+	err := notifier.NotifyStatusChange(context.Background(), &model.Payout{ID: "payout_1", Status: model.PayoutStatusCompleted})
+	if !errors.Is(err, produceErr) {
+		t.Errorf("expected the Kafka error to be returned, got %v", err)
+	}
+	if len(log) != 2 || log[1] != "webhook:COMPLETED" {
+		t.Errorf("expected the webhook to be sent after the failed produce, got %v", log)
+	}
+}
+
+func TestPayoutService_NotificationFailureDoesNotBlockCompletion(t *testing.T) {
+	repo := NewMockRepository()
+	prov := provider.NewSimulatedProvider(0, time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+
+	var log []string
+	svc := NewPayoutService(repo, prov, logger, 3)
+	svc.SetStatusNotifier(NewOrderedNotifier([]string{ChannelKafka, ChannelWebhook}, map[string]StatusNotifier{
+		ChannelKafka:   &channelNotifier{name: "kafka", log: &log, err: errors.New("broker unavailable")},
+		ChannelWebhook: &channelNotifier{name: "webhook", log: &log, err: errors.New("endpoint returned 500")},
+	}))
+
+	payout, err := svc.InitiatePayout(context.Background(), &InitiatePayoutRequest{
+		TransferID: "transfer_notify_failure",
+		Method:     model.PayoutMethodBankAccount,
+		Amount:     "100.00",
+		Currency:   "SGD",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if payout.Status != model.PayoutStatusCompleted {
+		t.Errorf("expected COMPLETED despite notification failures, got %s", payout.Status)
+	}
+	if last := log[len(log)-2:]; last[0] != "kafka:COMPLETED" || last[1] != "webhook:COMPLETED" {
+		t.Errorf("expected completion sent to kafka then webhook, got %v", log)
+	}
+}
+
+func TestStatusDebouncer_KeepsChannelOrder(t *testing.T) {
+	var log []string
+	ordered := NewOrderedNotifier([]string{ChannelKafka, ChannelWebhook}, map[string]StatusNotifier{
+		ChannelKafka:   &channelNotifier{name: "kafka", log: &log},
+		ChannelWebhook: &channelNotifier{name: "webhook", log: &log},
+	})
+	debouncer := NewStatusDebouncer(ordered, time.Hour, zap.NewNop()).(*StatusDebouncer)
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1", Status: model.PayoutStatusPending}
+	debouncer.NotifyStatusChange(ctx, payout)
+	payout.Status = model.PayoutStatusProcessing
+	debouncer.NotifyStatusChange(ctx, payout)
+	debouncer.Flush()
+	payout.Status = model.PayoutStatusCompleted
+	debouncer.NotifyStatusChange(ctx, payout)
+
+	expected := []string{"kafka:PROCESSING", "webhook:PROCESSING", "kafka:COMPLETED", "webhook:COMPLETED"}
+	if len(log) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, log)
+	}
+	for i := range expected {
+		if log[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, log)
+		}
+	}
+}
+
+// lockedLog is a channel log shared by notifiers called from debounce timers
+type lockedLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *lockedLog) notifier(name string) StatusNotifier {
+	return notifierFunc(func(ctx context.Context, payout *model.Payout) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.entries = append(l.entries, name+":"+string(payout.Status))
+		return nil
+	})
+}
+
+func (l *lockedLog) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.entries...)
+}
+
+type notifierFunc func(ctx context.Context, payout *model.Payout) error
+
+func (f notifierFunc) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	return f(ctx, payout)
+}
+
+func TestOrderedDebouncer_PerChannelWindows(t *testing.T) {
+	log := &lockedLog{}
+	notifier := NewOrderedDebouncer([]string{ChannelKafka, ChannelWebhook}, map[string]StatusNotifier{
+		ChannelKafka:   log.notifier("kafka"),
+		ChannelWebhook: log.notifier("webhook"),
+	}, map[string]time.Duration{ChannelKafka: 0, ChannelWebhook: 30 * time.Millisecond}, time.Hour, zap.NewNop())
+	ctx := context.Background()
+
+	payout := &model.Payout{ID: "payout_1"}
+	for _, status := range []model.PayoutStatus{model.PayoutStatusPending, model.PayoutStatusProcessing} {
+		payout.Status = status
+		notifier.NotifyStatusChange(ctx, payout)
+	}
+
+	// Kafka has no window and gets every status; the webhook's are coalesced
+	time.Sleep(80 * time.Millisecond)
+	payout.Status = model.PayoutStatusCompleted
+	notifier.NotifyStatusChange(ctx, payout)
+
+	expected := []string{"kafka:PENDING", "kafka:PROCESSING", "webhook:PROCESSING", "kafka:COMPLETED", "webhook:COMPLETED"}
+	if got := log.snapshot(); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestOrderedDebouncer_ShorterLaterWindowKeepsOrder(t *testing.T) {
+	log := &lockedLog{}
+	notifier := NewOrderedDebouncer([]string{ChannelKafka, ChannelWebhook}, map[string]StatusNotifier{
+		ChannelKafka:   log.notifier("kafka"),
+		ChannelWebhook: log.notifier("webhook"),
+	}, map[string]time.Duration{ChannelKafka: 50 * time.Millisecond, ChannelWebhook: 10 * time.Millisecond}, 0, zap.NewNop())
+
+	notifier.NotifyStatusChange(context.Background(), &model.Payout{ID: "payout_1", Status: model.PayoutStatusProcessing})
+
+	// The webhook's shorter window doesn't let it overtake Kafka
+	time.Sleep(25 * time.Millisecond)
+	if got := log.snapshot(); len(got) != 0 {
+		t.Fatalf("expected no events before Kafka's window ends, got %v", got)
+	}
+	time.Sleep(75 * time.Millisecond)
+
+	expected := []string{"kafka:PROCESSING", "webhook:PROCESSING"}
+	if got := log.snapshot(); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestOrderedDebouncer_NoWindowsReturnsOrderedNotifier(t *testing.T) {
+	notifier := NewOrderedDebouncer(nil, map[string]StatusNotifier{ChannelKafka: &recordingNotifier{}}, nil, 0, zap.NewNop())
+	if _, ok := notifier.(*OrderedNotifier); !ok {
+		t.Errorf("expected an OrderedNotifier when no channel is debounced, got %T", notifier)
+	}
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/movra/settlement-service/internal/kafka"
+	"github.com/movra/settlement-service/internal/model"
+)
+
+// Notifier POSTs payout status events to a webhook. It is a
+// service.StatusNotifier, so failures are logged by the payout service and
+// never fail the payout.
+type Notifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewNotifier creates a notifier POSTing to url. Each call gives up after
+// timeout, so an unresponsive endpoint can only delay a payout by that long.
+func NewNotifier(url string, timeout time.Duration) *Notifier {
+	return &Notifier{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// NotifyStatusChange POSTs the payout's current status as the same event
+// published to Kafka, returning once the webhook has answered with a 2xx
+func (n *Notifier) NotifyStatusChange(ctx context.Context, payout *model.Payout) error {
+	body, err := json.Marshal(kafka.NewPayoutStatusEvent(payout))
+	if err != nil {
+		return fmt.Errorf("marshal status event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("call status webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/movra/settlement-service/internal/kafka"
+	"github.com/movra/settlement-service/internal/model"
+)
+
+func TestNotifier_PostsStatusEvent(t *testing.T) {
+	received := make(chan kafka.PayoutStatusEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event kafka.PayoutStatusEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		received <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, time.Second)
+	payout := &model.Payout{ID: "payout_1", TransferID: "transfer_1", Status: model.PayoutStatusCompleted}
+	if err := notifier.NotifyStatusChange(context.Background(), payout); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	event := <-received
+	if event.PayoutID != "payout_1" || event.TransferID != "transfer_1" || event.Status != string(model.PayoutStatusCompleted) {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestNotifier_ErrorStatusFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, time.Second)
+	if err := notifier.NotifyStatusChange(context.Background(), &model.Payout{ID: "payout_1"}); err == nil {
+		t.Error("expected an error for a 502 response")
+	}
+}